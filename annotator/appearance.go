@@ -0,0 +1,155 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// newAppearanceForm returns an appearance XObject form with a graphics state named gs1 setting the
+// opacity to `opacity` if less than 1. Returns the form and the name of the graphics state, empty if not
+// needed. The annotations are given appearance streams so that they look the same in all viewers.
+func newAppearanceForm(opacity float64) (*pdf.XObjectForm, string, error) {
+	return newBlendedAppearanceForm(opacity, "")
+}
+
+// newBlendedAppearanceForm returns an appearance XObject form as newAppearanceForm, with the graphics
+// state also setting the blend mode to `blendMode` if not empty.
+func newBlendedAppearanceForm(opacity float64, blendMode string) (*pdf.XObjectForm, string, error) {
+	form := pdf.NewXObjectForm()
+	form.Resources = pdf.NewPdfPageResources()
+	if opacity >= 1.0 && blendMode == "" {
+		return form, "", nil
+	}
+
+	// Create graphics state with right opacity and blend mode.
+	gsState := pdfcore.MakeDict()
+	if opacity < 1.0 {
+		gsState.Set("ca", pdfcore.MakeFloat(opacity))
+		gsState.Set("CA", pdfcore.MakeFloat(opacity))
+	}
+	if blendMode != "" {
+		gsState.Set("BM", pdfcore.MakeName(blendMode))
+	}
+	err := form.Resources.AddExtGState("gs1", gsState)
+	if err != nil {
+		common.Log.Debug("Unable to add extgstate gs1")
+		return nil, "", err
+	}
+	return form, "gs1", nil
+}
+
+// makeAppearanceDict sets the content of appearance `form` drawn in the page coordinate system to `content`,
+// with bounding box `bbox`, and returns the appearance dictionary with `form` as normal appearance.
+func makeAppearanceDict(form *pdf.XObjectForm, content []byte, bbox *pdf.PdfRectangle) (*pdfcore.PdfObjectDictionary, error) {
+	err := form.SetContentStream(content, defStreamEncoder())
+	if err != nil {
+		return nil, err
+	}
+	form.BBox = bbox.ToPdfObject()
+
+	apDict := pdfcore.MakeDict()
+	apDict.Set("N", form.ToPdfObject())
+	return apDict, nil
+}
+
+// setRoundCapsAndJoins sets the line cap and join styles of `cc` to round.
+func setRoundCapsAndJoins(cc *contentstream.ContentCreator) {
+	cc.AddOperand(contentstream.ContentStreamOperation{
+		Operand: "J", Params: []pdfcore.PdfObject{pdfcore.MakeInteger(1)},
+	})
+	cc.AddOperand(contentstream.ContentStreamOperation{
+		Operand: "j", Params: []pdfcore.PdfObject{pdfcore.MakeInteger(1)},
+	})
+}
+
+// colorComponents returns the components of `color`, or nil if `color` is nil.
+func colorComponents(color *pdf.PdfColorDeviceRGB) []float64 {
+	if color == nil {
+		return nil
+	}
+	return []float64{color.R(), color.G(), color.B()}
+}
+
+// pointsBBox is the bounding box of a set of points.
+type pointsBBox struct {
+	pdf.PdfRectangle
+	empty bool
+}
+
+// newPointsBBox returns an empty bounding box.
+func newPointsBBox() *pointsBBox {
+	return &pointsBBox{empty: true}
+}
+
+// add adds the square of half side `margin` centered at (`x`,`y`) to `b`.
+func (b *pointsBBox) add(x, y, margin float64) {
+	if b.empty {
+		b.PdfRectangle = pdf.PdfRectangle{Llx: x - margin, Lly: y - margin, Urx: x + margin, Ury: y + margin}
+		b.empty = false
+		return
+	}
+	b.Llx = math.Min(b.Llx, x-margin)
+	b.Lly = math.Min(b.Lly, y-margin)
+	b.Urx = math.Max(b.Urx, x+margin)
+	b.Ury = math.Max(b.Ury, y+margin)
+}
+
+// addPoints adds the squares of half side `margin` centered at `points` to `b`.
+func (b *pointsBBox) addPoints(points []draw.Point, margin float64) {
+	for _, p := range points {
+		b.add(p.X, p.Y, margin)
+	}
+}
+
+// rect returns the bounding box as a rectangle.
+func (b *pointsBBox) rect() *pdf.PdfRectangle {
+	rect := b.PdfRectangle
+	return &rect
+}
+
+// textWidth returns the width of `text` drawn with `font` of size 1.
+func textWidth(font *pdf.PdfFont, text string) float64 {
+	var width float64
+	for _, r := range text {
+		metrics, has := font.GetRuneMetrics(r)
+		if !has {
+			continue
+		}
+		width += metrics.Wx
+	}
+	return width / 1000.0
+}
+
+// wrapText splits `text` into lines no wider than `maxWidth` when drawn with `font` of size `fontSize`,
+// breaking lines between words where possible and at line breaks.
+func wrapText(font *pdf.PdfFont, fontSize float64, text string, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		var line string
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line == "" || textWidth(font, candidate)*fontSize <= maxWidth {
+				line = candidate
+				continue
+			}
+			lines = append(lines, line)
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,220 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// TextAlignment represents the alignment of the text of FreeText annotations.
+type TextAlignment int
+
+// Text alignments, matching the values of the Q entry of FreeText annotations.
+const (
+	TextAlignmentLeft   TextAlignment = 0
+	TextAlignmentCenter TextAlignment = 1
+	TextAlignmentRight  TextAlignment = 2
+)
+
+// FreeTextAnnotationDef defines a text box with a lower left corner at (X,Y), showing Text wrapped to the box
+// width. The text is drawn with Font (Helvetica if not specified) of size FontSize (12 if not specified).
+// The box can optionally have a border and a filling color, and a callout line pointing at a location of the
+// page: Callout specifies the line by 2 or 3 points, starting at the pointed location and ending at the box
+// (with a knee point in between if 3 points are specified).
+type FreeTextAnnotationDef struct {
+	X                 float64
+	Y                 float64
+	Width             float64
+	Height            float64
+	Text              string
+	Font              *pdf.PdfFont
+	FontSize          float64
+	TextColor         *pdf.PdfColorDeviceRGB
+	TextAlignment     TextAlignment
+	FillEnabled       bool // Show fill?
+	FillColor         *pdf.PdfColorDeviceRGB
+	BorderEnabled     bool // Show border?
+	BorderWidth       float64
+	BorderColor       *pdf.PdfColorDeviceRGB
+	Callout           []draw.Point
+	CalloutLineEnding LineEnding // Line ending style at the pointed location.
+	Opacity           float64    // Alpha value (0-1).
+}
+
+// CreateFreeTextAnnotation creates a free text annotation object that can be added to page PDF annotations.
+func CreateFreeTextAnnotation(freeTextDef FreeTextAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if freeTextDef.Width <= 0 || freeTextDef.Height <= 0 {
+		return nil, errors.New("invalid free text box size")
+	}
+	if n := len(freeTextDef.Callout); n != 0 && n != 2 && n != 3 {
+		return nil, errors.New("callout line requires 2 or 3 points")
+	}
+
+	font := freeTextDef.Font
+	fontName := pdfcore.PdfObjectName("Font1")
+	if font == nil {
+		var err error
+		if font, err = pdf.NewStandard14Font(pdf.HelveticaName); err != nil {
+			return nil, err
+		}
+		fontName = "Helv"
+	}
+	if freeTextDef.FontSize <= 0 {
+		freeTextDef.FontSize = 12
+	}
+	if freeTextDef.TextColor == nil {
+		freeTextDef.TextColor = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+	if !freeTextDef.BorderEnabled || freeTextDef.BorderColor == nil {
+		freeTextDef.BorderEnabled = false
+		freeTextDef.BorderWidth = 0
+	}
+	if freeTextDef.FillColor == nil {
+		freeTextDef.FillEnabled = false
+	}
+
+	freeTextAnnotation := pdf.NewPdfAnnotationFreeText()
+	freeTextAnnotation.Contents = pdfcore.MakeTextString(freeTextDef.Text)
+	tc := freeTextDef.TextColor
+	freeTextAnnotation.DA = pdfcore.MakeString(fmt.Sprintf("/%s %g Tf %.4g %.4g %.4g rg",
+		fontName, freeTextDef.FontSize, tc.R(), tc.G(), tc.B()))
+	freeTextAnnotation.Q = pdfcore.MakeInteger(int64(freeTextDef.TextAlignment))
+	if freeTextDef.FillEnabled {
+		freeTextAnnotation.C = pdfcore.MakeArrayFromFloats(colorComponents(freeTextDef.FillColor))
+	}
+	bs := pdf.NewBorderStyle()
+	bs.SetBorderWidth(freeTextDef.BorderWidth)
+	freeTextAnnotation.BS = bs.ToPdfObject()
+	if len(freeTextDef.Callout) > 0 {
+		freeTextAnnotation.IT = pdfcore.MakeName("FreeTextCallout")
+		freeTextAnnotation.CL = makeVerticesArray(freeTextDef.Callout)
+		freeTextAnnotation.LE = pdfcore.MakeName(freeTextDef.CalloutLineEnding.name())
+	}
+	if freeTextDef.Opacity < 1.0 {
+		freeTextAnnotation.CA = pdfcore.MakeFloat(freeTextDef.Opacity)
+	}
+
+	form, gsName, err := newAppearanceForm(freeTextDef.Opacity)
+	if err != nil {
+		return nil, err
+	}
+	if err := form.Resources.SetFontByName(fontName, font.ToPdfObject()); err != nil {
+		return nil, err
+	}
+	content, bbox := drawFreeText(freeTextDef, font, fontName, gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, err
+	}
+	freeTextAnnotation.AP = apDict
+	freeTextAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	// Differences between the annotation rectangle and the text box.
+	freeTextAnnotation.RD = pdfcore.MakeArrayFromFloats([]float64{
+		freeTextDef.X - bbox.Llx,
+		freeTextDef.Y - bbox.Lly,
+		bbox.Urx - freeTextDef.X - freeTextDef.Width,
+		bbox.Ury - freeTextDef.Y - freeTextDef.Height,
+	})
+
+	return freeTextAnnotation.PdfAnnotation, nil
+}
+
+// drawFreeText returns the content stream drawing the free text annotation of `freeTextDef` in the page
+// coordinate system with `font` named `fontName`, using the graphics state `gsName` if not empty, and its
+// bounding box.
+func drawFreeText(freeTextDef FreeTextAnnotationDef, font *pdf.PdfFont, fontName pdfcore.PdfObjectName,
+	gsName string) ([]byte, *pdf.PdfRectangle) {
+	x, y := freeTextDef.X, freeTextDef.Y
+	width, height := freeTextDef.Width, freeTextDef.Height
+	bw := freeTextDef.BorderWidth
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if gsName != "" {
+		cc.Add_gs(pdfcore.PdfObjectName(gsName))
+	}
+
+	bbox := newPointsBBox()
+	bbox.add(x, y, 0)
+	bbox.add(x+width, y+height, 0)
+
+	// Box.
+	if freeTextDef.FillEnabled {
+		fc := freeTextDef.FillColor
+		cc.Add_rg(fc.R(), fc.G(), fc.B()).Add_re(x, y, width, height).Add_f()
+	}
+	if freeTextDef.BorderEnabled {
+		bc := freeTextDef.BorderColor
+		cc.Add_w(bw).Add_RG(bc.R(), bc.G(), bc.B()).
+			Add_re(x+bw/2, y+bw/2, width-bw, height-bw).Add_S()
+	}
+
+	// Callout line, drawn with the border color (the text color if no border) and width.
+	if callout := freeTextDef.Callout; len(callout) > 0 {
+		lineWidth := math.Max(bw, 1)
+		lineColor := freeTextDef.BorderColor
+		if lineColor == nil {
+			lineColor = freeTextDef.TextColor
+		}
+		cc.Add_w(lineWidth).Add_RG(lineColor.R(), lineColor.G(), lineColor.B())
+		fill := freeTextDef.FillEnabled
+		if fill {
+			fc := freeTextDef.FillColor
+			cc.Add_rg(fc.R(), fc.G(), fc.B())
+		}
+		cc.Add_m(callout[0].X, callout[0].Y)
+		for _, p := range callout[1:] {
+			cc.Add_l(p.X, p.Y)
+		}
+		cc.Add_S()
+		bbox.addPoints(callout, lineWidth)
+		drawLineEnding(cc, freeTextDef.CalloutLineEnding, callout[0],
+			callout[0].X-callout[1].X, callout[0].Y-callout[1].Y, lineWidth, fill, bbox)
+	}
+
+	// Text, clipped to the box.
+	padding := bw + 2
+	fontSize := freeTextDef.FontSize
+	lineHeight := 1.2 * fontSize
+	lines := wrapText(font, fontSize, freeTextDef.Text, width-2*padding)
+
+	tc := freeTextDef.TextColor
+	cc.Add_q().
+		Add_re(x+bw, y+bw, math.Max(width-2*bw, 0), math.Max(height-2*bw, 0)).
+		Add_W().
+		Add_n().
+		Add_BT().
+		Add_rg(tc.R(), tc.G(), tc.B()).
+		Add_Tf(fontName, fontSize)
+	encoder := font.Encoder()
+	baseline := y + height - padding - 0.8*fontSize
+	for _, line := range lines {
+		lineX := x + padding
+		switch freeTextDef.TextAlignment {
+		case TextAlignmentCenter:
+			lineX = x + (width-textWidth(font, line)*fontSize)/2
+		case TextAlignmentRight:
+			lineX = x + width - padding - textWidth(font, line)*fontSize
+		}
+		if line != "" {
+			cc.Add_Tm(1, 0, 0, 1, lineX, baseline).
+				Add_Tj(*pdfcore.MakeStringFromBytes(encoder.Encode(line)))
+		}
+		baseline -= lineHeight
+	}
+	cc.Add_ET().Add_Q()
+	cc.Add_Q()
+
+	return cc.Bytes(), bbox.rect()
+}
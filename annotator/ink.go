@@ -0,0 +1,131 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// InkAnnotationDef defines a freehand drawing made of one or more disjoint Paths, each a list of points.
+// The paths are drawn with round caps and joins, optionally smoothed into curves through the points.
+type InkAnnotationDef struct {
+	Paths     [][]draw.Point
+	LineColor *pdf.PdfColorDeviceRGB
+	LineWidth float64
+	Smooth    bool    // Draw smooth curves through the points?
+	Opacity   float64 // Alpha value (0-1).
+}
+
+// CreateInkAnnotation creates an ink annotation object that can be added to page PDF annotations.
+func CreateInkAnnotation(inkDef InkAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if inkDef.LineColor == nil {
+		return nil, errors.New("line color not specified")
+	}
+
+	inkList := pdfcore.MakeArray()
+	for _, path := range inkDef.Paths {
+		if len(path) > 0 {
+			inkList.Append(makeVerticesArray(path))
+		}
+	}
+	if inkList.Len() == 0 {
+		return nil, errors.New("ink annotation requires at least 1 point")
+	}
+
+	inkAnnotation := pdf.NewPdfAnnotationInk()
+	inkAnnotation.InkList = inkList
+	inkAnnotation.C = pdfcore.MakeArrayFromFloats(colorComponents(inkDef.LineColor))
+	bs := pdf.NewBorderStyle()
+	bs.SetBorderWidth(inkDef.LineWidth)
+	inkAnnotation.BS = bs.ToPdfObject()
+	if inkDef.Opacity < 1.0 {
+		inkAnnotation.CA = pdfcore.MakeFloat(inkDef.Opacity)
+	}
+
+	form, gsName, err := newAppearanceForm(inkDef.Opacity)
+	if err != nil {
+		return nil, err
+	}
+	content, bbox := drawInk(inkDef, gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, err
+	}
+	inkAnnotation.AP = apDict
+	inkAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	return inkAnnotation.PdfAnnotation, nil
+}
+
+// drawInk returns the content stream drawing the paths of `inkDef` in the page coordinate system, using the
+// graphics state `gsName` if not empty, and its bounding box.
+func drawInk(inkDef InkAnnotationDef, gsName string) ([]byte, *pdf.PdfRectangle) {
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if gsName != "" {
+		cc.Add_gs(pdfcore.PdfObjectName(gsName))
+	}
+	color := inkDef.LineColor
+	cc.Add_w(inkDef.LineWidth).Add_RG(color.R(), color.G(), color.B())
+	setRoundCapsAndJoins(cc)
+
+	bbox := newPointsBBox()
+	margin := inkDef.LineWidth / 2
+	for _, path := range inkDef.Paths {
+		if len(path) == 0 {
+			continue
+		}
+		bbox.addPoints(path, margin)
+		cc.Add_m(path[0].X, path[0].Y)
+		if len(path) == 1 {
+			// A dot, drawn by the round cap of a line of length 0.
+			cc.Add_l(path[0].X, path[0].Y).Add_S()
+			continue
+		}
+		if !inkDef.Smooth {
+			for _, p := range path[1:] {
+				cc.Add_l(p.X, p.Y)
+			}
+			cc.Add_S()
+			continue
+		}
+
+		// Catmull-Rom spline through the points, converted to cubic Bezier curves. The curves are inside the
+		// convex hulls of their control points.
+		for i := 0; i+1 < len(path); i++ {
+			p0 := path[maxInt(i-1, 0)]
+			p1, p2 := path[i], path[i+1]
+			p3 := path[minInt(i+2, len(path)-1)]
+			c1 := draw.NewPoint(p1.X+(p2.X-p0.X)/6, p1.Y+(p2.Y-p0.Y)/6)
+			c2 := draw.NewPoint(p2.X-(p3.X-p1.X)/6, p2.Y-(p3.Y-p1.Y)/6)
+			cc.Add_c(c1.X, c1.Y, c2.X, c2.Y, p2.X, p2.Y)
+			bbox.addPoints([]draw.Point{c1, c2}, margin)
+		}
+		cc.Add_S()
+	}
+	cc.Add_Q()
+
+	return cc.Bytes(), bbox.rect()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+)
+
+// LineEnding represents the line ending styles of the PolyLine annotations and FreeText annotation callout
+// lines, as in the LE entry of the annotations.
+type LineEnding string
+
+// Line ending styles.
+const (
+	LineEndingNone         LineEnding = "None"
+	LineEndingSquare       LineEnding = "Square"
+	LineEndingCircle       LineEnding = "Circle"
+	LineEndingDiamond      LineEnding = "Diamond"
+	LineEndingOpenArrow    LineEnding = "OpenArrow"
+	LineEndingClosedArrow  LineEnding = "ClosedArrow"
+	LineEndingButt         LineEnding = "Butt"
+	LineEndingROpenArrow   LineEnding = "ROpenArrow"
+	LineEndingRClosedArrow LineEnding = "RClosedArrow"
+	LineEndingSlash        LineEnding = "Slash"
+)
+
+// name returns the name of line ending `le`, None if not specified.
+func (le LineEnding) name() string {
+	if le == "" {
+		return string(LineEndingNone)
+	}
+	return string(le)
+}
+
+// bezierCircle is the distance of the control points from the on-curve points of the cubic Bezier curves
+// approximating a quarter of a circle of radius 1.
+const bezierCircle = 0.5523
+
+// drawLineEnding adds the operations drawing line ending `le` at the end point `p` of a line ending with
+// direction `dx`,`dy` and width `lineWidth` to `cc`, using the current stroking color and, if `fill` is
+// true, filling closed endings with the current non-stroking color. The extent of the line ending is added
+// to `bbox`.
+func drawLineEnding(cc *contentstream.ContentCreator, le LineEnding, p draw.Point, dx, dy, lineWidth float64,
+	fill bool, bbox *pointsBBox) {
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	// Unit vector along the line and normal unit vector.
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy, ux
+	size := 3*lineWidth + 6
+
+	// Point at distance `s` along the line and `t` across it from `p`.
+	point := func(s, t float64) draw.Point {
+		return draw.NewPoint(p.X+s*ux+t*nx, p.Y+s*uy+t*ny)
+	}
+	polyline := func(points []draw.Point, closed bool) {
+		cc.Add_m(points[0].X, points[0].Y)
+		for _, pt := range points[1:] {
+			cc.Add_l(pt.X, pt.Y)
+		}
+		if closed {
+			cc.Add_h()
+			if fill {
+				cc.Add_B()
+			} else {
+				cc.Add_S()
+			}
+		} else {
+			cc.Add_S()
+		}
+		bbox.addPoints(points, lineWidth)
+	}
+
+	r := size / 2
+	switch le {
+	case LineEndingSquare:
+		polyline([]draw.Point{point(-r, -r), point(r, -r), point(r, r), point(-r, r)}, true)
+	case LineEndingDiamond:
+		polyline([]draw.Point{point(-r, 0), point(0, -r), point(r, 0), point(0, r)}, true)
+	case LineEndingOpenArrow:
+		polyline([]draw.Point{point(-size, r), p, point(-size, -r)}, false)
+	case LineEndingClosedArrow:
+		polyline([]draw.Point{point(-size, r), p, point(-size, -r)}, true)
+	case LineEndingROpenArrow:
+		polyline([]draw.Point{point(size, r), p, point(size, -r)}, false)
+	case LineEndingRClosedArrow:
+		polyline([]draw.Point{point(size, r), p, point(size, -r)}, true)
+	case LineEndingButt:
+		polyline([]draw.Point{point(0, -r), point(0, r)}, false)
+	case LineEndingSlash:
+		// Line at 30 degrees clockwise from the perpendicular to the line.
+		s, c := math.Sincos(math.Pi / 6)
+		polyline([]draw.Point{point(-r*s, -r*c), point(r*s, r*c)}, false)
+	case LineEndingCircle:
+		k := bezierCircle * r
+		start := point(r, 0)
+		cc.Add_m(start.X, start.Y)
+		quarters := [][3]draw.Point{
+			{point(r, k), point(k, r), point(0, r)},
+			{point(-k, r), point(-r, k), point(-r, 0)},
+			{point(-r, -k), point(-k, -r), point(0, -r)},
+			{point(k, -r), point(r, -k), point(r, 0)},
+		}
+		for _, q := range quarters {
+			cc.Add_c(q[0].X, q[0].Y, q[1].X, q[1].Y, q[2].X, q[2].Y)
+		}
+		cc.Add_h()
+		if fill {
+			cc.Add_B()
+		} else {
+			cc.Add_S()
+		}
+		bbox.add(p.X, p.Y, r+lineWidth)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// getAppearance returns the annotation rectangle of `annot`, and the normal appearance form of `annot` with
+// the operands of its content stream.
+func getAppearance(t *testing.T, annot *pdf.PdfAnnotation) (*pdf.PdfRectangle, *pdf.XObjectForm, []string) {
+	arr, ok := pdfcore.GetArray(annot.Rect)
+	require.True(t, ok)
+	rect, err := pdf.NewPdfRectangle(*arr)
+	require.NoError(t, err)
+
+	apDict, ok := pdfcore.GetDict(annot.AP)
+	require.True(t, ok)
+	stream, ok := pdfcore.GetStream(apDict.Get("N"))
+	require.True(t, ok)
+	form, err := pdf.NewXObjectFormFromStream(stream)
+	require.NoError(t, err)
+	bbox, err := pdf.NewPdfRectangle(*form.BBox.(*pdfcore.PdfObjectArray))
+	require.NoError(t, err)
+	require.Equal(t, rect, bbox)
+
+	data, err := form.GetContentStream()
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	require.NoError(t, err)
+	var operands []string
+	for _, op := range *ops {
+		operands = append(operands, op.Operand)
+	}
+	return rect, form, operands
+}
+
+func TestCreateFreeTextAnnotation(t *testing.T) {
+	annot, err := CreateFreeTextAnnotation(FreeTextAnnotationDef{
+		X: 100, Y: 100, Width: 100, Height: 50,
+		Text:              "A comment long enough to be wrapped on several lines",
+		FillEnabled:       true,
+		FillColor:         pdf.NewPdfColorDeviceRGB(1, 1, 0.8),
+		BorderEnabled:     true,
+		BorderWidth:       1,
+		BorderColor:       pdf.NewPdfColorDeviceRGB(1, 0, 0),
+		Callout:           []draw.Point{draw.NewPoint(20, 20), draw.NewPoint(60, 60), draw.NewPoint(100, 120)},
+		CalloutLineEnding: LineEndingClosedArrow,
+		Opacity:           1,
+	})
+	require.NoError(t, err)
+
+	freeText, ok := annot.GetContext().(*pdf.PdfAnnotationFreeText)
+	require.True(t, ok)
+	require.Equal(t, "/Helv 12 Tf 0 0 0 rg", freeText.DA.(*pdfcore.PdfObjectString).Str())
+	require.Equal(t, pdfcore.MakeName("FreeTextCallout"), freeText.IT)
+	require.Equal(t, pdfcore.MakeName("ClosedArrow"), freeText.LE)
+
+	rect, form, operands := getAppearance(t, annot)
+	require.True(t, rect.Llx < 20 && rect.Lly < 20 && rect.Urx >= 200 && rect.Ury >= 150)
+	rd, err := pdfcore.GetNumbersAsFloat(freeText.RD.(*pdfcore.PdfObjectArray).Elements())
+	require.NoError(t, err)
+	require.InDelta(t, 100, rect.Llx+rd[0], 1e-9)
+	require.InDelta(t, 200, rect.Urx-rd[2], 1e-9)
+	require.True(t, form.Resources.HasFontByName("Helv"))
+
+	numLines := strings.Count(strings.Join(operands, " "), "Tj")
+	require.True(t, numLines > 1)
+	require.Contains(t, operands, "B")
+}
+
+func TestCreateInkAnnotation(t *testing.T) {
+	paths := [][]draw.Point{
+		{draw.NewPoint(10, 10), draw.NewPoint(20, 30), draw.NewPoint(30, 10), draw.NewPoint(40, 30)},
+		{draw.NewPoint(50, 50)},
+	}
+	for _, smooth := range []bool{false, true} {
+		annot, err := CreateInkAnnotation(InkAnnotationDef{
+			Paths:     paths,
+			LineColor: pdf.NewPdfColorDeviceRGB(0, 0, 1),
+			LineWidth: 2,
+			Smooth:    smooth,
+			Opacity:   0.5,
+		})
+		require.NoError(t, err)
+
+		ink, ok := annot.GetContext().(*pdf.PdfAnnotationInk)
+		require.True(t, ok)
+		require.Equal(t, 2, ink.InkList.(*pdfcore.PdfObjectArray).Len())
+
+		rect, form, operands := getAppearance(t, annot)
+		require.True(t, rect.Llx <= 9 && rect.Lly <= 9 && rect.Urx >= 51 && rect.Ury >= 51)
+		_, ok = form.Resources.GetExtGState("gs1")
+		require.True(t, ok)
+		require.Equal(t, smooth, strings.Contains(strings.Join(operands, " "), "c"))
+	}
+}
+
+func TestCreatePolyLineAnnotation(t *testing.T) {
+	vertices := []draw.Point{draw.NewPoint(10, 10), draw.NewPoint(50, 10), draw.NewPoint(50, 50)}
+
+	annot, err := CreatePolygonAnnotation(PolygonAnnotationDef{
+		Vertices:    vertices,
+		LineColor:   pdf.NewPdfColorDeviceRGB(0, 0, 0),
+		LineWidth:   1,
+		FillEnabled: true,
+		FillColor:   pdf.NewPdfColorDeviceRGB(0, 1, 0),
+		Opacity:     1,
+	})
+	require.NoError(t, err)
+	rect, _, operands := getAppearance(t, annot)
+	require.Equal(t, pdf.PdfRectangle{Llx: 9, Lly: 9, Urx: 51, Ury: 51}, *rect)
+	require.Equal(t, []string{"q", "w", "RG", "rg", "m", "l", "l", "h", "B", "Q"}, operands)
+
+	for _, le := range []LineEnding{
+		LineEndingSquare, LineEndingCircle, LineEndingDiamond, LineEndingOpenArrow, LineEndingClosedArrow,
+		LineEndingButt, LineEndingROpenArrow, LineEndingRClosedArrow, LineEndingSlash,
+	} {
+		annot, err = CreatePolyLineAnnotation(PolyLineAnnotationDef{
+			Vertices:         vertices,
+			LineColor:        pdf.NewPdfColorDeviceRGB(0, 0, 0),
+			LineWidth:        1,
+			LineEndingStyle1: le,
+			LineEndingStyle2: le,
+			Opacity:          1,
+		})
+		require.NoError(t, err)
+		polyLine, ok := annot.GetContext().(*pdf.PdfAnnotationPolyLine)
+		require.True(t, ok)
+		require.Equal(t, pdfcore.MakeArray(pdfcore.MakeName(string(le)), pdfcore.MakeName(string(le))), polyLine.LE)
+
+		rect, _, operands = getAppearance(t, annot)
+		require.True(t, rect.Llx <= 9 && rect.Lly < 9 && rect.Ury >= 51, le)
+		require.Equal(t, 3, strings.Count(strings.Join(operands, " "), "S")+
+			strings.Count(strings.Join(operands, " "), "B"), le)
+	}
+}
+
+func TestCreateStampAnnotation(t *testing.T) {
+	annot, err := CreateStampAnnotation(StampAnnotationDef{X: 100, Y: 100, Name: StampApproved, Opacity: 1})
+	require.NoError(t, err)
+	stamp, ok := annot.GetContext().(*pdf.PdfAnnotationStamp)
+	require.True(t, ok)
+	require.Equal(t, pdfcore.MakeName("Approved"), stamp.Name)
+	require.Equal(t, "APPROVED", stamp.Contents.(*pdfcore.PdfObjectString).Str())
+
+	rect, form, operands := getAppearance(t, annot)
+	require.Equal(t, 100.0, rect.Llx)
+	require.True(t, rect.Width() > rect.Height())
+	require.True(t, form.Resources.HasFontByName("HeBo"))
+	require.Contains(t, operands, "Tj")
+
+	annot, err = CreateStampAnnotation(StampAnnotationDef{X: 0, Y: 0, Width: 150, Height: 40, Text: "PAID", Opacity: 1})
+	require.NoError(t, err)
+	rect, _, _ = getAppearance(t, annot)
+	require.Equal(t, pdf.PdfRectangle{Urx: 150, Ury: 40}, *rect)
+
+	_, err = CreateStampAnnotation(StampAnnotationDef{})
+	require.Error(t, err)
+}
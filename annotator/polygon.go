@@ -0,0 +1,179 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// PolygonAnnotationDef defines a closed polygon with the specified Vertices. The polygon has a border and can
+// optionally have a filling color.
+type PolygonAnnotationDef struct {
+	Vertices    []draw.Point
+	LineColor   *pdf.PdfColorDeviceRGB
+	LineWidth   float64
+	FillEnabled bool // Show fill?
+	FillColor   *pdf.PdfColorDeviceRGB
+	Opacity     float64 // Alpha value (0-1).
+}
+
+// PolyLineAnnotationDef defines an open polyline with the specified Vertices. The line ending styles of the
+// first and last vertices can be set, and the closed line endings can optionally be filled.
+type PolyLineAnnotationDef struct {
+	Vertices         []draw.Point
+	LineColor        *pdf.PdfColorDeviceRGB
+	LineWidth        float64
+	LineEndingStyle1 LineEnding // Line ending style of the first vertex.
+	LineEndingStyle2 LineEnding // Line ending style of the last vertex.
+	FillEnabled      bool       // Fill closed line endings?
+	FillColor        *pdf.PdfColorDeviceRGB
+	Opacity          float64 // Alpha value (0-1).
+}
+
+// CreatePolygonAnnotation creates a polygon annotation object that can be added to page PDF annotations.
+func CreatePolygonAnnotation(polygonDef PolygonAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if len(polygonDef.Vertices) < 2 {
+		return nil, errors.New("polygon requires at least 2 vertices")
+	}
+	if polygonDef.LineColor == nil {
+		return nil, errors.New("line color not specified")
+	}
+
+	polygonAnnotation := pdf.NewPdfAnnotationPolygon()
+	polygonAnnotation.Vertices = makeVerticesArray(polygonDef.Vertices)
+	polygonAnnotation.C = pdfcore.MakeArrayFromFloats(colorComponents(polygonDef.LineColor))
+	if polygonDef.FillEnabled && polygonDef.FillColor != nil {
+		polygonAnnotation.IC = pdfcore.MakeArrayFromFloats(colorComponents(polygonDef.FillColor))
+	}
+	bs := pdf.NewBorderStyle()
+	bs.SetBorderWidth(polygonDef.LineWidth)
+	polygonAnnotation.BS = bs.ToPdfObject()
+	if polygonDef.Opacity < 1.0 {
+		polygonAnnotation.CA = pdfcore.MakeFloat(polygonDef.Opacity)
+	}
+
+	form, gsName, err := newAppearanceForm(polygonDef.Opacity)
+	if err != nil {
+		return nil, err
+	}
+	fillColor := polygonDef.FillColor
+	if !polygonDef.FillEnabled {
+		fillColor = nil
+	}
+	content, bbox := drawPolyline(polygonDef.Vertices, true, polygonDef.LineColor, fillColor,
+		polygonDef.LineWidth, LineEndingNone, LineEndingNone, gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, err
+	}
+	polygonAnnotation.AP = apDict
+	polygonAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	return polygonAnnotation.PdfAnnotation, nil
+}
+
+// CreatePolyLineAnnotation creates a polyline annotation object that can be added to page PDF annotations.
+func CreatePolyLineAnnotation(polyLineDef PolyLineAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if len(polyLineDef.Vertices) < 2 {
+		return nil, errors.New("polyline requires at least 2 vertices")
+	}
+	if polyLineDef.LineColor == nil {
+		return nil, errors.New("line color not specified")
+	}
+
+	polyLineAnnotation := pdf.NewPdfAnnotationPolyLine()
+	polyLineAnnotation.Vertices = makeVerticesArray(polyLineDef.Vertices)
+	polyLineAnnotation.LE = pdfcore.MakeArray(
+		pdfcore.MakeName(polyLineDef.LineEndingStyle1.name()),
+		pdfcore.MakeName(polyLineDef.LineEndingStyle2.name()),
+	)
+	polyLineAnnotation.C = pdfcore.MakeArrayFromFloats(colorComponents(polyLineDef.LineColor))
+	if polyLineDef.FillEnabled && polyLineDef.FillColor != nil {
+		polyLineAnnotation.IC = pdfcore.MakeArrayFromFloats(colorComponents(polyLineDef.FillColor))
+	}
+	bs := pdf.NewBorderStyle()
+	bs.SetBorderWidth(polyLineDef.LineWidth)
+	polyLineAnnotation.BS = bs.ToPdfObject()
+	if polyLineDef.Opacity < 1.0 {
+		polyLineAnnotation.CA = pdfcore.MakeFloat(polyLineDef.Opacity)
+	}
+
+	form, gsName, err := newAppearanceForm(polyLineDef.Opacity)
+	if err != nil {
+		return nil, err
+	}
+	fillColor := polyLineDef.FillColor
+	if !polyLineDef.FillEnabled {
+		fillColor = nil
+	}
+	content, bbox := drawPolyline(polyLineDef.Vertices, false, polyLineDef.LineColor, fillColor,
+		polyLineDef.LineWidth, polyLineDef.LineEndingStyle1, polyLineDef.LineEndingStyle2, gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, err
+	}
+	polyLineAnnotation.AP = apDict
+	polyLineAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	return polyLineAnnotation.PdfAnnotation, nil
+}
+
+// makeVerticesArray returns the array of the coordinates of `vertices`.
+func makeVerticesArray(vertices []draw.Point) *pdfcore.PdfObjectArray {
+	coords := make([]float64, 0, 2*len(vertices))
+	for _, v := range vertices {
+		coords = append(coords, v.X, v.Y)
+	}
+	return pdfcore.MakeArrayFromFloats(coords)
+}
+
+// drawPolyline returns the content stream drawing the polyline through `vertices` in the page coordinate
+// system, closed if `closed` is true, with line endings `le1` and `le2` otherwise, using the graphics state
+// `gsName` if not empty, and its bounding box. The polygon or the closed line endings are filled with
+// `fillColor` if not nil.
+func drawPolyline(vertices []draw.Point, closed bool, lineColor, fillColor *pdf.PdfColorDeviceRGB,
+	lineWidth float64, le1, le2 LineEnding, gsName string) ([]byte, *pdf.PdfRectangle) {
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if gsName != "" {
+		cc.Add_gs(pdfcore.PdfObjectName(gsName))
+	}
+	cc.Add_w(lineWidth).Add_RG(lineColor.R(), lineColor.G(), lineColor.B())
+	if fillColor != nil {
+		cc.Add_rg(fillColor.R(), fillColor.G(), fillColor.B())
+	}
+
+	bbox := newPointsBBox()
+	bbox.addPoints(vertices, lineWidth)
+	cc.Add_m(vertices[0].X, vertices[0].Y)
+	for _, v := range vertices[1:] {
+		cc.Add_l(v.X, v.Y)
+	}
+	switch {
+	case closed && fillColor != nil:
+		cc.Add_h().Add_B()
+	case closed:
+		cc.Add_h().Add_S()
+	default:
+		cc.Add_S()
+	}
+
+	if !closed {
+		n := len(vertices)
+		first, second := vertices[0], vertices[1]
+		drawLineEnding(cc, le1, first, first.X-second.X, first.Y-second.Y, lineWidth, fillColor != nil, bbox)
+		last, prev := vertices[n-1], vertices[n-2]
+		drawLineEnding(cc, le2, last, last.X-prev.X, last.Y-prev.Y, lineWidth, fillColor != nil, bbox)
+	}
+	cc.Add_Q()
+
+	return cc.Bytes(), bbox.rect()
+}
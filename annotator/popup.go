@@ -0,0 +1,84 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// PopupAnnotationDef defines a pop-up window with a lower left corner at (X,Y), showing the text of its
+// parent markup annotation.
+type PopupAnnotationDef struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	Open   bool // Initially displayed open?
+}
+
+// CreatePopupAnnotation creates a pop-up annotation for the markup annotation `parent`, e.g. a text markup
+// annotation created with CreateTextMarkupAnnotation, which displays the comment text (Contents) of `parent`.
+// Both annotations need to be added to the page PDF annotations.
+func CreatePopupAnnotation(parent *pdf.PdfAnnotation, popupDef PopupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	markup := getMarkup(parent)
+	if markup == nil {
+		return nil, errors.New("parent is not a markup annotation")
+	}
+
+	popupAnnotation := pdf.NewPdfAnnotationPopup()
+	popupAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		popupDef.X, popupDef.Y, popupDef.X + popupDef.Width, popupDef.Y + popupDef.Height,
+	})
+	popupAnnotation.Open = pdfcore.MakeBool(popupDef.Open)
+	popupAnnotation.Parent = parent.GetContainingPdfObject()
+	markup.Popup = popupAnnotation
+
+	return popupAnnotation.PdfAnnotation, nil
+}
+
+// getMarkup returns the markup portion of `annot`, or nil if it is not a markup annotation.
+func getMarkup(annot *pdf.PdfAnnotation) *pdf.PdfAnnotationMarkup {
+	switch t := annot.GetContext().(type) {
+	case *pdf.PdfAnnotationText:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationFreeText:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationLine:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationSquare:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationCircle:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationPolygon:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationPolyLine:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationHighlight:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationUnderline:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationSquiggly:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationStrikeOut:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationCaret:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationStamp:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationInk:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationFileAttachment:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationSound:
+		return t.PdfAnnotationMarkup
+	case *pdf.PdfAnnotationRedact:
+		return t.PdfAnnotationMarkup
+	}
+	return nil
+}
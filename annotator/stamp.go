@@ -0,0 +1,201 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// StampName represents the names of rubber stamp annotations.
+type StampName string
+
+// Standard stamp names.
+const (
+	StampApproved            StampName = "Approved"
+	StampExperimental        StampName = "Experimental"
+	StampNotApproved         StampName = "NotApproved"
+	StampAsIs                StampName = "AsIs"
+	StampExpired             StampName = "Expired"
+	StampNotForPublicRelease StampName = "NotForPublicRelease"
+	StampConfidential        StampName = "Confidential"
+	StampFinal               StampName = "Final"
+	StampSold                StampName = "Sold"
+	StampDepartmental        StampName = "Departmental"
+	StampForComment          StampName = "ForComment"
+	StampTopSecret           StampName = "TopSecret"
+	StampDraft               StampName = "Draft"
+	StampForPublicRelease    StampName = "ForPublicRelease"
+)
+
+// Default colors of the standard stamps.
+var (
+	stampColorGreen = pdf.NewPdfColorDeviceRGB(0.13, 0.55, 0.13)
+	stampColorRed   = pdf.NewPdfColorDeviceRGB(0.8, 0.1, 0.1)
+	stampColorBlue  = pdf.NewPdfColorDeviceRGB(0.1, 0.2, 0.7)
+)
+
+// standardStamps maps the standard stamp names to their labels and default colors.
+var standardStamps = map[StampName]struct {
+	label string
+	color *pdf.PdfColorDeviceRGB
+}{
+	StampApproved:            {"APPROVED", stampColorGreen},
+	StampExperimental:        {"EXPERIMENTAL", stampColorBlue},
+	StampNotApproved:         {"NOT APPROVED", stampColorRed},
+	StampAsIs:                {"AS IS", stampColorBlue},
+	StampExpired:             {"EXPIRED", stampColorRed},
+	StampNotForPublicRelease: {"NOT FOR PUBLIC RELEASE", stampColorRed},
+	StampConfidential:        {"CONFIDENTIAL", stampColorRed},
+	StampFinal:               {"FINAL", stampColorGreen},
+	StampSold:                {"SOLD", stampColorGreen},
+	StampDepartmental:        {"DEPARTMENTAL", stampColorBlue},
+	StampForComment:          {"FOR COMMENT", stampColorBlue},
+	StampTopSecret:           {"TOP SECRET", stampColorRed},
+	StampDraft:               {"DRAFT", stampColorBlue},
+	StampForPublicRelease:    {"FOR PUBLIC RELEASE", stampColorGreen},
+}
+
+// StampAnnotationDef defines a rubber stamp with a lower left corner at (X,Y). The stamp shows its label in a
+// rounded frame: standard stamps (Name) have built-in labels and colors, custom stamps show Text.
+// The Color overrides the color of the stamp if specified. The Width and Height are computed from the label if
+// not specified.
+type StampAnnotationDef struct {
+	X       float64
+	Y       float64
+	Width   float64
+	Height  float64
+	Name    StampName
+	Text    string // Label of custom stamps.
+	Color   *pdf.PdfColorDeviceRGB
+	Opacity float64 // Alpha value (0-1).
+}
+
+// CreateStampAnnotation creates a rubber stamp annotation object that can be added to page PDF annotations.
+func CreateStampAnnotation(stampDef StampAnnotationDef) (*pdf.PdfAnnotation, error) {
+	label := stampDef.Text
+	color := stampDef.Color
+	if std, ok := standardStamps[stampDef.Name]; ok {
+		if label == "" {
+			label = std.label
+		}
+		if color == nil {
+			color = std.color
+		}
+	}
+	if label == "" {
+		return nil, errors.New("stamp label not specified")
+	}
+	if color == nil {
+		color = stampColorRed
+	}
+
+	font, err := pdf.NewStandard14Font(pdf.HelveticaBoldName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Default size: 20 point label with margins of a third of the height.
+	width, height := stampDef.Width, stampDef.Height
+	if width <= 0 || height <= 0 {
+		height = 20 / 0.6
+		width = textWidth(font, label)*20 + 2*height/3
+	}
+
+	stampAnnotation := pdf.NewPdfAnnotationStamp()
+	if stampDef.Name != "" {
+		stampAnnotation.Name = pdfcore.MakeName(string(stampDef.Name))
+	}
+	stampAnnotation.Contents = pdfcore.MakeTextString(label)
+	stampAnnotation.C = pdfcore.MakeArrayFromFloats(colorComponents(color))
+	if stampDef.Opacity < 1.0 {
+		stampAnnotation.CA = pdfcore.MakeFloat(stampDef.Opacity)
+	}
+
+	form, gsName, err := newAppearanceForm(stampDef.Opacity)
+	if err != nil {
+		return nil, err
+	}
+	if err := form.Resources.SetFontByName("HeBo", font.ToPdfObject()); err != nil {
+		return nil, err
+	}
+	bbox := &pdf.PdfRectangle{Llx: stampDef.X, Lly: stampDef.Y, Urx: stampDef.X + width, Ury: stampDef.Y + height}
+	content := drawStamp(bbox, label, color, font, "HeBo", gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, err
+	}
+	stampAnnotation.AP = apDict
+	stampAnnotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	return stampAnnotation.PdfAnnotation, nil
+}
+
+// drawStamp returns the content stream drawing a stamp in `rect` of the page coordinate system showing
+// `label` with `font` named `fontName` in `color`, using the graphics state `gsName` if not empty.
+func drawStamp(rect *pdf.PdfRectangle, label string, color *pdf.PdfColorDeviceRGB, font *pdf.PdfFont,
+	fontName pdfcore.PdfObjectName, gsName string) []byte {
+	width, height := rect.Width(), rect.Height()
+	lineWidth := math.Max(height/15, 0.5)
+	radius := math.Min(height, width) / 5
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if gsName != "" {
+		cc.Add_gs(pdfcore.PdfObjectName(gsName))
+	}
+
+	// Rounded frame filled with a light tint of the stamp color.
+	r, g, b := color.R(), color.G(), color.B()
+	tint := func(c float64) float64 { return 1 - 0.15*(1-c) }
+	cc.Add_w(lineWidth).
+		Add_RG(r, g, b).
+		Add_rg(tint(r), tint(g), tint(b))
+	drawRoundedRect(cc, rect.Llx+lineWidth/2, rect.Lly+lineWidth/2, width-lineWidth, height-lineWidth, radius)
+	cc.Add_B()
+
+	// Label centered in the frame, fitted to its inner size.
+	margin := lineWidth + radius
+	fontSize := 0.6 * height
+	if w := textWidth(font, label); w > 0 {
+		fontSize = math.Min(fontSize, (width-2*margin)/w)
+	}
+	if fontSize > 0 {
+		labelWidth := textWidth(font, label) * fontSize
+		// The cap height of Helvetica-Bold is about 0.72 of the font size.
+		x := rect.Llx + (width-labelWidth)/2
+		y := rect.Lly + (height-0.72*fontSize)/2
+		cc.Add_BT().
+			Add_rg(r, g, b).
+			Add_Tf(fontName, fontSize).
+			Add_Tm(1, 0, 0, 1, x, y).
+			Add_Tj(*pdfcore.MakeStringFromBytes(font.Encoder().Encode(label))).
+			Add_ET()
+	}
+	cc.Add_Q()
+
+	return cc.Bytes()
+}
+
+// drawRoundedRect adds the path of the rectangle with lower left corner (`x`,`y`), width `width`, height
+// `height` and corners rounded with radius `radius` to `cc`.
+func drawRoundedRect(cc *contentstream.ContentCreator, x, y, width, height, radius float64) {
+	k := bezierCircle * radius
+	cc.Add_m(x+radius, y).
+		Add_l(x+width-radius, y).
+		Add_c(x+width-radius+k, y, x+width, y+radius-k, x+width, y+radius).
+		Add_l(x+width, y+height-radius).
+		Add_c(x+width, y+height-radius+k, x+width-radius+k, y+height, x+width-radius, y+height).
+		Add_l(x+radius, y+height).
+		Add_c(x+radius-k, y+height, x, y+height-radius+k, x, y+height-radius).
+		Add_l(x, y+radius).
+		Add_c(x, y+radius-k, x+radius-k, y, x+radius, y).
+		Add_h()
+}
@@ -0,0 +1,253 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// TextMarkupType represents the type of a text markup annotation.
+type TextMarkupType int
+
+// Text markup annotation types.
+const (
+	TextMarkupHighlight TextMarkupType = iota
+	TextMarkupUnderline
+	TextMarkupStrikeOut
+	TextMarkupSquiggly
+)
+
+// TextMarkupAnnotationDef defines a text markup annotation (highlight, underline, strikeout or squiggly
+// underline) of the text in the quadrilaterals QuadPoints. Each quadrilateral is specified by 8 numbers: the
+// coordinates of its upper left, upper right, lower left and lower right corners, as in the QuadPoints
+// entry of text markup annotations. QuadPointsFromTextMarks computes the QuadPoints of extracted text.
+// The annotation can optionally have a comment text (Contents), displayed by a pop-up annotation created
+// with CreatePopupAnnotation.
+type TextMarkupAnnotationDef struct {
+	Type       TextMarkupType
+	QuadPoints []float64
+	Color      *pdf.PdfColorDeviceRGB
+	Opacity    float64 // Alpha value (0-1).
+	Contents   string  // Comment text.
+	Author     string
+	Subject    string
+}
+
+// CreateTextMarkupAnnotation creates a text markup annotation object that can be added to page PDF annotations.
+// Highlights are drawn with the multiply blend mode so that the highlighted text remains visible.
+func CreateTextMarkupAnnotation(markupDef TextMarkupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if len(markupDef.QuadPoints) == 0 || len(markupDef.QuadPoints)%8 != 0 {
+		return nil, errors.New("invalid number of quad points")
+	}
+	if markupDef.Color == nil {
+		return nil, errors.New("color not specified")
+	}
+
+	var annotation *pdf.PdfAnnotation
+	var markup *pdf.PdfAnnotationMarkup
+	quadPoints := pdfcore.MakeArrayFromFloats(markupDef.QuadPoints)
+	switch markupDef.Type {
+	case TextMarkupHighlight:
+		highlight := pdf.NewPdfAnnotationHighlight()
+		highlight.QuadPoints = quadPoints
+		annotation, markup = highlight.PdfAnnotation, highlight.PdfAnnotationMarkup
+	case TextMarkupUnderline:
+		underline := pdf.NewPdfAnnotationUnderline()
+		underline.QuadPoints = quadPoints
+		annotation, markup = underline.PdfAnnotation, underline.PdfAnnotationMarkup
+	case TextMarkupStrikeOut:
+		strikeOut := pdf.NewPdfAnnotationStrikeOut()
+		strikeOut.QuadPoints = quadPoints
+		annotation, markup = strikeOut.PdfAnnotation, strikeOut.PdfAnnotationMarkup
+	case TextMarkupSquiggly:
+		squiggly := pdf.NewPdfAnnotationSquiggly()
+		squiggly.QuadPoints = quadPoints
+		annotation, markup = squiggly.PdfAnnotation, squiggly.PdfAnnotationMarkup
+	default:
+		return nil, errors.New("unsupported text markup type")
+	}
+
+	r, g, b := markupDef.Color.R(), markupDef.Color.G(), markupDef.Color.B()
+	annotation.C = pdfcore.MakeArrayFromFloats([]float64{r, g, b})
+	if markupDef.Opacity < 1.0 {
+		markup.CA = pdfcore.MakeFloat(markupDef.Opacity)
+	}
+	if markupDef.Contents != "" {
+		annotation.Contents = pdfcore.MakeTextString(markupDef.Contents)
+	}
+	if markupDef.Author != "" {
+		markup.T = pdfcore.MakeTextString(markupDef.Author)
+	}
+	if markupDef.Subject != "" {
+		markup.Subj = pdfcore.MakeTextString(markupDef.Subject)
+	}
+
+	apDict, bbox, err := makeTextMarkupAnnotationAppearanceStream(markupDef)
+	if err != nil {
+		return nil, err
+	}
+	annotation.AP = apDict
+	annotation.Rect = pdfcore.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+
+	return annotation, nil
+}
+
+// QuadPointsFromTextMarks returns the QuadPoints of the text of `marks`, e.g. a range of the text marks
+// of a page returned by extractor.TextMarkArray.RangeOffset: one quadrilateral per line of text.
+func QuadPointsFromTextMarks(marks *extractor.TextMarkArray) []float64 {
+	var quadPoints []float64
+	var line *pdf.PdfRectangle
+	addLine := func() {
+		if line != nil {
+			quadPoints = append(quadPoints,
+				line.Llx, line.Ury, line.Urx, line.Ury, line.Llx, line.Lly, line.Urx, line.Lly)
+		}
+	}
+
+	for _, mark := range marks.Elements() {
+		if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+			continue
+		}
+		bbox := mark.BBox
+		if line != nil {
+			// Marks overlapping vertically by at least half of their height are on the same line.
+			overlap := math.Min(line.Ury, bbox.Ury) - math.Max(line.Lly, bbox.Lly)
+			height := math.Min(line.Height(), bbox.Height())
+			if overlap >= height/2 && bbox.Llx >= line.Llx {
+				line.Llx = math.Min(line.Llx, bbox.Llx)
+				line.Lly = math.Min(line.Lly, bbox.Lly)
+				line.Urx = math.Max(line.Urx, bbox.Urx)
+				line.Ury = math.Max(line.Ury, bbox.Ury)
+				continue
+			}
+		}
+		addLine()
+		line = &pdf.PdfRectangle{Llx: bbox.Llx, Lly: bbox.Lly, Urx: bbox.Urx, Ury: bbox.Ury}
+	}
+	addLine()
+	return quadPoints
+}
+
+func makeTextMarkupAnnotationAppearanceStream(markupDef TextMarkupAnnotationDef) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	// The highlights darken the marked up text.
+	blendMode := ""
+	if markupDef.Type == TextMarkupHighlight {
+		blendMode = "Multiply"
+	}
+	form, gsName, err := newBlendedAppearanceForm(markupDef.Opacity, blendMode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, bbox := drawTextMarkup(markupDef, gsName)
+	apDict, err := makeAppearanceDict(form, content, bbox)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, bbox, nil
+}
+
+// drawTextMarkup returns the content stream drawing the text markup of `markupDef` in the page coordinate
+// system, using the graphics state `gsName` if not empty, and its bounding box.
+func drawTextMarkup(markupDef TextMarkupAnnotationDef, gsName string) ([]byte, *pdf.PdfRectangle) {
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if gsName != "" {
+		cc.Add_gs(pdfcore.PdfObjectName(gsName))
+	}
+	r, g, b := markupDef.Color.R(), markupDef.Color.G(), markupDef.Color.B()
+	if markupDef.Type == TextMarkupHighlight {
+		cc.Add_rg(r, g, b)
+	} else {
+		cc.Add_RG(r, g, b)
+	}
+
+	bbox := &pdf.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	addPoint := func(x, y, margin float64) {
+		bbox.Llx = math.Min(bbox.Llx, x-margin)
+		bbox.Lly = math.Min(bbox.Lly, y-margin)
+		bbox.Urx = math.Max(bbox.Urx, x+margin)
+		bbox.Ury = math.Max(bbox.Ury, y+margin)
+	}
+
+	qp := markupDef.QuadPoints
+	for i := 0; i+8 <= len(qp); i += 8 {
+		// Corners: upper left (x1,y1), upper right (x2,y2), lower left (x3,y3), lower right (x4,y4).
+		x1, y1, x2, y2, x3, y3, x4, y4 := qp[i], qp[i+1], qp[i+2], qp[i+3], qp[i+4], qp[i+5], qp[i+6], qp[i+7]
+
+		// Unit vectors along and across the text line, and the line height.
+		ux, uy := x4-x3, y4-y3
+		length := math.Hypot(ux, uy)
+		vx, vy := x1-x3, y1-y3
+		height := math.Hypot(vx, vy)
+		if length == 0 || height == 0 {
+			continue
+		}
+		ux, uy = ux/length, uy/length
+		vx, vy = vx/height, vy/height
+
+		// Point at distance `s` along the line from the lower left corner and `t` above it.
+		point := func(s, t float64) (float64, float64) {
+			return x3 + s*ux + t*vx, y3 + s*uy + t*vy
+		}
+
+		// The annotation rectangle covers the marked up text.
+		for _, p := range [][2]float64{{x1, y1}, {x2, y2}, {x3, y3}, {x4, y4}} {
+			addPoint(p[0], p[1], 0)
+		}
+
+		lineWidth := math.Max(1, height/14)
+		switch markupDef.Type {
+		case TextMarkupHighlight:
+			cc.Add_m(x1, y1).Add_l(x2, y2).Add_l(x4, y4).Add_l(x3, y3).Add_h().Add_f()
+		case TextMarkupUnderline, TextMarkupStrikeOut:
+			t := 0.5*lineWidth + 0.05*height
+			if markupDef.Type == TextMarkupStrikeOut {
+				t = 0.4 * height
+			}
+			sx, sy := point(0, t)
+			ex, ey := point(length, t)
+			cc.Add_w(lineWidth).Add_m(sx, sy).Add_l(ex, ey).Add_S()
+			addPoint(sx, sy, lineWidth)
+			addPoint(ex, ey, lineWidth)
+		case TextMarkupSquiggly:
+			// Zigzag along the bottom of the line.
+			amplitude := height / 12
+			period := 4 * amplitude
+			cc.Add_w(0.5 * lineWidth)
+			for s, up := 0.0, false; ; s, up = s+period/2, !up {
+				t := amplitude
+				if up {
+					t = 2 * amplitude
+				}
+				x, y := point(math.Min(s, length), t)
+				if s == 0 {
+					cc.Add_m(x, y)
+				} else {
+					cc.Add_l(x, y)
+				}
+				addPoint(x, y, lineWidth)
+				if s >= length {
+					break
+				}
+			}
+			cc.Add_S()
+		}
+	}
+	cc.Add_Q()
+
+	if math.IsInf(bbox.Llx, 0) {
+		bbox = &pdf.PdfRectangle{}
+	}
+	return cc.Bytes(), bbox
+}
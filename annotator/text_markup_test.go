@@ -0,0 +1,90 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	pdfcore "github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	pdf "github.com/unidoc/unipdf/v3/model"
+)
+
+func TestQuadPointsFromTextMarks(t *testing.T) {
+	var marks extractor.TextMarkArray
+	marks.Append(extractor.TextMark{Text: "a", BBox: pdf.PdfRectangle{Llx: 10, Lly: 100, Urx: 16, Ury: 112}})
+	marks.Append(extractor.TextMark{Text: "b", BBox: pdf.PdfRectangle{Llx: 16, Lly: 99, Urx: 22, Ury: 111}})
+	marks.Append(extractor.TextMark{Text: "\n", Meta: true})
+	marks.Append(extractor.TextMark{Text: "c", BBox: pdf.PdfRectangle{Llx: 10, Lly: 86, Urx: 16, Ury: 98}})
+
+	require.Equal(t, []float64{
+		10, 112, 22, 112, 10, 99, 22, 99,
+		10, 98, 16, 98, 10, 86, 16, 86,
+	}, QuadPointsFromTextMarks(&marks))
+}
+
+func TestCreateTextMarkupAnnotation(t *testing.T) {
+	quadPoints := []float64{10, 114, 50, 114, 10, 100, 50, 100}
+	for _, markupType := range []TextMarkupType{
+		TextMarkupHighlight, TextMarkupUnderline, TextMarkupStrikeOut, TextMarkupSquiggly,
+	} {
+		annot, err := CreateTextMarkupAnnotation(TextMarkupAnnotationDef{
+			Type:       markupType,
+			QuadPoints: quadPoints,
+			Color:      pdf.NewPdfColorDeviceRGB(1, 1, 0),
+			Opacity:    0.5,
+			Contents:   "Comment",
+		})
+		require.NoError(t, err)
+
+		rect, err := pdf.NewPdfRectangle(*annot.Rect.(*pdfcore.PdfObjectArray))
+		require.NoError(t, err)
+		require.True(t, rect.Llx <= 10 && rect.Lly <= 100 && rect.Urx >= 50 && rect.Ury >= 114)
+
+		apDict, ok := pdfcore.GetDict(annot.AP)
+		require.True(t, ok)
+		stream, ok := pdfcore.GetStream(apDict.Get("N"))
+		require.True(t, ok)
+		form, err := pdf.NewXObjectFormFromStream(stream)
+		require.NoError(t, err)
+		gs, ok := form.Resources.GetExtGState("gs1")
+		require.True(t, ok)
+		gsDict, ok := pdfcore.GetDict(gs)
+		require.True(t, ok)
+		require.Equal(t, pdfcore.MakeFloat(0.5), gsDict.Get("CA"))
+
+		data, err := form.GetContentStream()
+		require.NoError(t, err)
+		ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+		require.NoError(t, err)
+		var operands []string
+		for _, op := range *ops {
+			operands = append(operands, op.Operand)
+		}
+		if markupType == TextMarkupHighlight {
+			require.Equal(t, pdfcore.MakeName("Multiply"), gsDict.Get("BM"))
+			require.Contains(t, operands, "f")
+		} else {
+			require.Nil(t, gsDict.Get("BM"))
+			require.Contains(t, operands, "S")
+		}
+
+		popup, err := CreatePopupAnnotation(annot, PopupAnnotationDef{X: 60, Y: 100, Width: 100, Height: 50})
+		require.NoError(t, err)
+		dict, ok := pdfcore.GetDict(annot.GetContext().ToPdfObject())
+		require.True(t, ok)
+		require.Equal(t, popup.GetContainingPdfObject(), dict.Get("Popup"))
+		popupDict, ok := pdfcore.GetDict(popup.GetContext().ToPdfObject())
+		require.True(t, ok)
+		require.Equal(t, annot.GetContainingPdfObject(), popupDict.Get("Parent"))
+	}
+
+	_, err := CreateTextMarkupAnnotation(TextMarkupAnnotationDef{QuadPoints: []float64{1, 2, 3}})
+	require.Error(t, err)
+}
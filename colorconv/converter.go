@@ -0,0 +1,482 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package colorconv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/pdfutil"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/icc"
+)
+
+// Target is the colorspace the colors are converted to.
+type Target int
+
+// Target colorspaces.
+const (
+	TargetGray Target = iota // DeviceGray.
+	TargetRGB                // DeviceRGB.
+	TargetCMYK               // DeviceCMYK.
+)
+
+// String returns the name of the device colorspace of the target.
+func (t Target) String() string {
+	switch t {
+	case TargetGray:
+		return "DeviceGray"
+	case TargetRGB:
+		return "DeviceRGB"
+	case TargetCMYK:
+		return "DeviceCMYK"
+	}
+	return fmt.Sprintf("Target(%d)", int(t))
+}
+
+// Converter converts the colors of pages to a target colorspace.
+type Converter struct {
+	// PreserveSpotColors keeps the colors of Separation and DeviceN colorspaces, and of Indexed
+	// colorspaces based on them, instead of converting them to the target colorspace.
+	PreserveSpotColors bool
+
+	// Profile is the ICC profile of the output device, whose colorspace must be the target
+	// colorspace. If set, the colors are converted from their sRGB values with the profile and
+	// rendering intent Intent. Otherwise, the colors are converted with the formulas of section
+	// 10.3 of the PDF reference.
+	// The gray colors are converted to the black component of CMYK colors in both cases.
+	Profile *icc.Profile
+	Intent  icc.Intent
+
+	target    Target
+	transform *icc.Transform // sRGB to Profile.
+	profile   *icc.Profile   // Profile of transform.
+
+	// Converted objects, which may be used by several pages.
+	visited pdfutil.ObjectSet
+}
+
+// NewConverter returns a converter of colors to `target`.
+func NewConverter(target Target) *Converter {
+	return &Converter{
+		target:  target,
+		visited: pdfutil.ObjectSet{},
+	}
+}
+
+// ConvertDocument converts the colors of the pages of the document loaded by `reader`. The pages
+// are converted in place, see ConvertPage.
+func (c *Converter) ConvertDocument(reader *model.PdfReader) error {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return err
+		}
+		if err := c.ConvertPage(page); err != nil {
+			return fmt.Errorf("page %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ConvertPage converts the colors of `page`: the colors set by the operators of its content
+// streams, the images, shadings, patterns and forms used by the content streams and the
+// appearances and colors of its annotations. The objects used by the page are modified in place,
+// so the converted page must be written to a new document with a model.PdfWriter.
+func (c *Converter) ConvertPage(page *model.PdfPage) error {
+	if err := c.prepare(); err != nil {
+		return err
+	}
+	if page.Resources == nil {
+		page.Resources = model.NewPdfPageResources()
+	}
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := c.convertContent(contents, page.Resources)
+	if err != nil {
+		return err
+	}
+	err = page.SetContentStreams([]string{string(ops.Bytes())}, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	c.convertGroup(page.Group)
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	for _, annot := range annotations {
+		if err := c.convertAnnotation(annot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare checks the target and creates the transform of the profile.
+func (c *Converter) prepare() error {
+	if c.target < TargetGray || c.target > TargetCMYK {
+		return errors.New("invalid target colorspace")
+	}
+	if c.visited == nil {
+		c.visited = pdfutil.ObjectSet{}
+	}
+	if c.Profile == nil {
+		c.transform, c.profile = nil, nil
+		return nil
+	}
+	if c.Profile == c.profile && c.transform != nil && c.transform.Intent() == c.Intent {
+		return nil
+	}
+	if c.Profile.NumComponents() != c.numComponents() {
+		return errors.New("profile colorspace does not match the target colorspace")
+	}
+	transform, err := icc.NewTransform(icc.SRGB(), c.Profile, c.Intent)
+	if err != nil {
+		return err
+	}
+	c.transform, c.profile = transform, c.Profile
+	return nil
+}
+
+// numComponents returns the number of components of the colors of the target colorspace.
+func (c *Converter) numComponents() int {
+	switch c.target {
+	case TargetRGB:
+		return 3
+	case TargetCMYK:
+		return 4
+	}
+	return 1
+}
+
+// colorspace returns the target colorspace.
+func (c *Converter) colorspace() model.PdfColorspace {
+	switch c.target {
+	case TargetRGB:
+		return model.NewPdfColorspaceDeviceRGB()
+	case TargetCMYK:
+		return model.NewPdfColorspaceDeviceCMYK()
+	}
+	return model.NewPdfColorspaceDeviceGray()
+}
+
+// isTarget returns true if `cs` is the target colorspace, whose colors are not converted.
+func (c *Converter) isTarget(cs model.PdfColorspace) bool {
+	switch cs.(type) {
+	case *model.PdfColorspaceDeviceGray:
+		return c.target == TargetGray
+	case *model.PdfColorspaceDeviceRGB:
+		return c.target == TargetRGB
+	case *model.PdfColorspaceDeviceCMYK:
+		return c.target == TargetCMYK
+	}
+	return false
+}
+
+// isPreserved returns true if the colors of `cs` are spot colors which are preserved.
+func (c *Converter) isPreserved(cs model.PdfColorspace) bool {
+	switch t := cs.(type) {
+	case *model.PdfColorspaceSpecialSeparation, *model.PdfColorspaceDeviceN:
+		return c.PreserveSpotColors
+	case *model.PdfColorspaceSpecialIndexed:
+		return t.Base != nil && c.isPreserved(t.Base)
+	}
+	return false
+}
+
+// isConverted returns true if the colors of `cs` are converted.
+func (c *Converter) isConverted(cs model.PdfColorspace) bool {
+	return cs != nil && !c.isTarget(cs) && !c.isPreserved(cs)
+}
+
+// convertColor returns the components of `color` of colorspace `cs` in the target colorspace.
+func (c *Converter) convertColor(cs model.PdfColorspace, color model.PdfColor) ([]float64, error) {
+	if vals, ok := c.convertDeviceColor(cs, color); ok {
+		return vals, nil
+	}
+	rgbColor, err := cs.ColorToRGB(color)
+	if err != nil {
+		return nil, err
+	}
+	rgb, ok := rgbColor.(*model.PdfColorDeviceRGB)
+	if !ok {
+		common.Log.Debug("ERROR: Invalid RGB color %T of %s", rgbColor, cs)
+		return nil, errors.New("invalid RGB color")
+	}
+	return c.fromRGB(rgb.R(), rgb.G(), rgb.B()), nil
+}
+
+// convertDeviceColor returns the components of `color` of colorspace `cs`, or of an Indexed
+// colorspace based on `cs`, in the target colorspace if the color is not converted through RGB:
+// colors of the target colorspace and gray colors converted to CMYK.
+func (c *Converter) convertDeviceColor(cs model.PdfColorspace, color model.PdfColor) ([]float64, bool) {
+	if indexed, ok := cs.(*model.PdfColorspaceSpecialIndexed); ok {
+		cs = indexed.Base
+	}
+	switch col := color.(type) {
+	case *model.PdfColorDeviceGray:
+		if _, ok := cs.(*model.PdfColorspaceDeviceGray); !ok {
+			return nil, false
+		}
+		switch c.target {
+		case TargetGray:
+			return []float64{col.Val()}, true
+		case TargetCMYK:
+			return []float64{0, 0, 0, 1 - col.Val()}, true
+		}
+	case *model.PdfColorDeviceRGB:
+		if _, ok := cs.(*model.PdfColorspaceDeviceRGB); ok && c.target == TargetRGB {
+			return []float64{col.R(), col.G(), col.B()}, true
+		}
+	case *model.PdfColorDeviceCMYK:
+		if _, ok := cs.(*model.PdfColorspaceDeviceCMYK); ok && c.target == TargetCMYK {
+			return []float64{col.C(), col.M(), col.Y(), col.K()}, true
+		}
+	}
+	return nil, false
+}
+
+// fromRGB returns the components of RGB color (`r`,`g`,`b`) in the target colorspace.
+func (c *Converter) fromRGB(r, g, b float64) []float64 {
+	if c.transform != nil {
+		vals := c.transform.Convert([]float64{r, g, b})
+		for i, v := range vals {
+			vals[i] = math.Min(math.Max(v, 0), 1)
+		}
+		return vals
+	}
+
+	switch c.target {
+	case TargetGray:
+		return []float64{0.3*r + 0.59*g + 0.11*b}
+	case TargetCMYK:
+		// Inverse of the conversion of DeviceCMYK colors to RGB.
+		k := 1 - math.Max(r, math.Max(g, b))
+		if k >= 1 {
+			return []float64{0, 0, 0, 1}
+		}
+		return []float64{(1 - r - k) / (1 - k), (1 - g - k) / (1 - k), (1 - b - k) / (1 - k), k}
+	}
+	return []float64{r, g, b}
+}
+
+// colorOperation returns the operation setting the stroking color if `stroke` is true, or
+// non-stroking color otherwise, to color `vals` of the target colorspace.
+func (c *Converter) colorOperation(vals []float64, stroke bool) *contentstream.ContentStreamOperation {
+	operand := map[Target]string{TargetGray: "g", TargetRGB: "rg", TargetCMYK: "k"}[c.target]
+	if stroke {
+		operand = map[Target]string{TargetGray: "G", TargetRGB: "RG", TargetCMYK: "K"}[c.target]
+	}
+	return &contentstream.ContentStreamOperation{Operand: operand, Params: core.MakeArrayFromFloats(vals).Elements()}
+}
+
+// convertContent returns the operations of content stream `content` drawn with resources
+// `resources` with their colors converted. The XObjects, shadings and patterns used by the
+// content stream are converted in place.
+func (c *Converter) convertContent(content string, resources *model.PdfPageResources) (
+	*contentstream.ContentStreamOperations, error) {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var converted contentstream.ContentStreamOperations
+	processor := contentstream.NewContentStreamProcessor(*ops)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			convertedOps, err := c.convertOperation(op, gs, resources)
+			if err != nil {
+				return err
+			}
+			converted = append(converted, convertedOps...)
+			return nil
+		})
+	if err := processor.Process(resources); err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+// convertOperation returns the operations replacing `op`, processed with graphics state `gs` and
+// resources `resources`.
+func (c *Converter) convertOperation(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+	resources *model.PdfPageResources) ([]*contentstream.ContentStreamOperation, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	switch op.Operand {
+	case "CS", "SC", "SCN", "G", "RG", "K":
+		return c.convertColorOperation(op, gs.ColorspaceStroking, gs.ColorStroking, true, resources)
+	case "cs", "sc", "scn", "g", "rg", "k":
+		return c.convertColorOperation(op, gs.ColorspaceNonStroking, gs.ColorNonStroking, false, resources)
+	case "BI":
+		return c.convertInlineImage(op, resources)
+	case "Do":
+		if len(op.Params) != 1 || resources == nil {
+			break
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			stream, xtype := resources.GetXObjectByName(*name)
+			if err := c.convertXObject(stream, xtype, resources); err != nil {
+				return nil, err
+			}
+		}
+	case "sh":
+		if len(op.Params) != 1 || resources == nil {
+			break
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			if shading, ok := resources.GetShadingByName(*name); ok {
+				if err := c.convertShading(shading); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return keep, nil
+}
+
+// convertColorOperation returns the operations replacing color operation `op`, which sets the
+// stroking colorspace or color if `stroke` is true, or the non-stroking ones otherwise, to
+// colorspace `cs` and color `color`.
+func (c *Converter) convertColorOperation(op *contentstream.ContentStreamOperation, cs model.PdfColorspace,
+	color model.PdfColor, stroke bool, resources *model.PdfPageResources) (
+	[]*contentstream.ContentStreamOperation, error) {
+	if pattern, ok := cs.(*model.PdfColorspaceSpecialPattern); ok {
+		return c.convertPatternOperation(op, pattern, color, resources)
+	}
+	if c.isPreserved(cs) {
+		return []*contentstream.ContentStreamOperation{op}, nil
+	}
+	vals, err := c.convertColor(cs, color)
+	if err != nil {
+		return nil, err
+	}
+	return []*contentstream.ContentStreamOperation{c.colorOperation(vals, stroke)}, nil
+}
+
+// convertPatternOperation returns the operations replacing operation `op` setting Pattern
+// colorspace `cs` or pattern color `color`. The pattern is converted and the colors of uncolored
+// patterns are converted to colors of a Pattern colorspace based on the target colorspace.
+func (c *Converter) convertPatternOperation(op *contentstream.ContentStreamOperation,
+	cs *model.PdfColorspaceSpecialPattern, color model.PdfColor, resources *model.PdfPageResources) (
+	[]*contentstream.ContentStreamOperation, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	convertUnderlying := cs.UnderlyingCS != nil && c.isConverted(cs.UnderlyingCS)
+
+	if op.Operand == "cs" || op.Operand == "CS" {
+		if !convertUnderlying {
+			return keep, nil
+		}
+		name, err := c.patternColorspaceName(resources)
+		if err != nil {
+			return nil, err
+		}
+		return []*contentstream.ContentStreamOperation{{
+			Operand: op.Operand,
+			Params:  []core.PdfObject{core.MakeName(string(name))},
+		}}, nil
+	}
+
+	patternColor, ok := color.(*model.PdfColorPattern)
+	if !ok {
+		return keep, nil
+	}
+	if resources != nil {
+		if pattern, ok := resources.GetPatternByName(patternColor.PatternName); ok {
+			if err := c.convertPattern(pattern, resources); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !convertUnderlying || patternColor.Color == nil {
+		return keep, nil
+	}
+	vals, err := c.convertColor(cs.UnderlyingCS, patternColor.Color)
+	if err != nil {
+		return nil, err
+	}
+	params := core.MakeArrayFromFloats(vals).Elements()
+	params = append(params, core.MakeName(string(patternColor.PatternName)))
+	return []*contentstream.ContentStreamOperation{{Operand: op.Operand, Params: params}}, nil
+}
+
+// patternColorspaceName returns the name of the Pattern colorspace based on the target
+// colorspace in `resources`, which is added if needed.
+func (c *Converter) patternColorspaceName(resources *model.PdfPageResources) (core.PdfObjectName, error) {
+	if resources == nil {
+		return "", errors.New("resources missing")
+	}
+	for i := 1; ; i++ {
+		name := core.PdfObjectName(fmt.Sprintf("Pattern%s%d", c.target, i))
+		cs, ok := resources.GetColorspaceByName(name)
+		if !ok {
+			pattern := model.NewPdfColorspaceSpecialPattern()
+			pattern.UnderlyingCS = c.colorspace()
+			return name, resources.SetColorspaceByName(name, pattern)
+		}
+		if pattern, ok := cs.(*model.PdfColorspaceSpecialPattern); ok && pattern.UnderlyingCS != nil &&
+			c.isTarget(pattern.UnderlyingCS) {
+			return name, nil
+		}
+	}
+}
+
+// convertInlineImage returns the operations replacing inline image operation `op` drawn with
+// resources `resources`: an inline image of the target colorspace if it is converted.
+func (c *Converter) convertInlineImage(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) ([]*contentstream.ContentStreamOperation, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	if len(op.Params) != 1 {
+		return keep, nil
+	}
+	inline, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
+	if !ok {
+		return keep, nil
+	}
+	if isMask, err := inline.IsMask(); err != nil || isMask {
+		return keep, nil
+	}
+	cs, err := inline.GetColorSpace(resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Unsupported inline image colorspace: %v - not converted", err)
+		return keep, nil
+	}
+	if !c.isConverted(cs) {
+		return keep, nil
+	}
+	img, err := inline.ToImage(resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode inline image: %v - not converted", err)
+		return keep, nil
+	}
+
+	converted, err := c.convertImage(img, cs, inline.Decode)
+	if err != nil {
+		return nil, err
+	}
+	convertedInline, err := contentstream.NewInlineImageFromImage(*converted, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	convertedInline.Intent = inline.Intent
+	convertedInline.Interpolate = inline.Interpolate
+	return []*contentstream.ContentStreamOperation{{
+		Operand: "BI",
+		Params:  []core.PdfObject{convertedInline},
+	}}, nil
+}
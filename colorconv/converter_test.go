@@ -0,0 +1,258 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package colorconv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makeTestDocument returns a document with a page filling a red square, stroking a gray square,
+// filling a square with a spot color and drawing an RGB image, an axial shading, a triangle mesh
+// shading and a form XObject, with a Square annotation with an appearance.
+func makeTestDocument(t *testing.T) *model.PdfReader {
+	content := "1 0 0 rg 0 0 10 10 re f\n" +
+		"0.5 G 0 0 10 10 re S\n" +
+		"/CS0 cs 1 scn 0 0 10 10 re f\n" +
+		"q 10 0 0 10 0 0 cm /Im0 Do Q\n" +
+		"/Sh0 sh /Sh1 sh /Fm0 Do\n"
+	page := pdftest.NewPage(t, 100, 100, content)
+
+	// Spot color printed on the cyan plate when not available.
+	tint := core.MakeDict()
+	tint.Set("FunctionType", core.MakeInteger(2))
+	tint.Set("Domain", core.MakeArrayFromFloats([]float64{0, 1}))
+	tint.Set("C0", core.MakeArrayFromFloats([]float64{0, 0, 0, 0}))
+	tint.Set("C1", core.MakeArrayFromFloats([]float64{1, 0, 0, 0}))
+	tint.Set("N", core.MakeInteger(1))
+	spot, err := model.NewPdfColorspaceFromPdfObject(core.MakeArray(core.MakeName("Separation"),
+		core.MakeName("Spot"), core.MakeName("DeviceCMYK"), tint))
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetColorspaceByName("CS0", spot))
+
+	// Red and green pixels.
+	img := &model.Image{Width: 2, Height: 1, BitsPerComponent: 8, ColorComponents: 3,
+		Data: []byte{255, 0, 0, 0, 255, 0}}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceRGB(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im0", ximg))
+
+	// Axial shading from red to blue.
+	function := core.MakeDict()
+	function.Set("FunctionType", core.MakeInteger(2))
+	function.Set("Domain", core.MakeArrayFromFloats([]float64{0, 1}))
+	function.Set("C0", core.MakeArrayFromFloats([]float64{1, 0, 0}))
+	function.Set("C1", core.MakeArrayFromFloats([]float64{0, 0, 1}))
+	function.Set("N", core.MakeInteger(1))
+	axial := core.MakeDict()
+	axial.Set("ShadingType", core.MakeInteger(2))
+	axial.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	axial.Set("Coords", core.MakeArrayFromFloats([]float64{0, 0, 100, 0}))
+	axial.Set("Function", function)
+	require.NoError(t, page.Resources.SetShadingByName("Sh0", core.MakeIndirectObject(axial)))
+
+	// Triangle with red, green and blue vertices at (0,0), (100,0) and (0,100).
+	mesh, err := core.MakeStream([]byte{
+		0, 0, 0, 255, 0, 0,
+		0, 255, 0, 0, 255, 0,
+		0, 0, 255, 0, 0, 255,
+	}, core.NewRawEncoder())
+	require.NoError(t, err)
+	mesh.Set("ShadingType", core.MakeInteger(4))
+	mesh.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	mesh.Set("BitsPerCoordinate", core.MakeInteger(8))
+	mesh.Set("BitsPerComponent", core.MakeInteger(8))
+	mesh.Set("BitsPerFlag", core.MakeInteger(8))
+	mesh.Set("Decode", core.MakeArrayFromFloats([]float64{0, 100, 0, 100, 0, 1, 0, 1, 0, 1}))
+	require.NoError(t, page.Resources.SetShadingByName("Sh1", mesh))
+
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	require.NoError(t, form.SetContentStream([]byte("0 1 0 rg 0 0 10 10 re f"), nil))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm0", form))
+
+	appearance := model.NewXObjectForm()
+	appearance.BBox = core.MakeArrayFromFloats([]float64{0, 0, 10, 10})
+	require.NoError(t, appearance.SetContentStream([]byte("0 0 1 RG 0 0 10 10 re S"), nil))
+	square := model.NewPdfAnnotationSquare()
+	square.Rect = core.MakeArrayFromFloats([]float64{0, 0, 10, 10})
+	square.C = core.MakeArrayFromFloats([]float64{0, 0, 1})
+	square.IC = core.MakeArrayFromFloats([]float64{1, 1, 0})
+	ap := core.MakeDict()
+	ap.Set("N", appearance.ToPdfObject())
+	square.AP = ap
+	page.AddAnnotation(square.PdfAnnotation)
+
+	return pdftest.WriteRead(t, page)
+}
+
+// colorOperations returns the color operations of `content`, one operation per line. The
+// operations appended by unlicensed writers are included.
+func colorOperations(t *testing.T, content []byte) []string {
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	require.NoError(t, err)
+	var colorOps contentstream.ContentStreamOperations
+	for _, op := range *ops {
+		switch op.Operand {
+		case "CS", "cs", "SC", "SCN", "sc", "scn", "G", "g", "RG", "rg", "K", "k":
+			colorOps = append(colorOps, op)
+		}
+	}
+	return splitLines(string(colorOps.Bytes()))
+}
+
+// splitLines returns the non-empty lines of `s`.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range bytes.Split([]byte(s), []byte("\n")) {
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines
+}
+
+// requireColorArray checks that `obj` is an array of color components `expected`.
+func requireColorArray(t *testing.T, expected []float64, obj core.PdfObject) {
+	arr, ok := core.GetArray(obj)
+	require.True(t, ok)
+	vals, err := arr.ToFloat64Array()
+	require.NoError(t, err)
+	require.Len(t, vals, len(expected))
+	for i := range expected {
+		require.InDelta(t, expected[i], vals[i], 1e-6, "component %d of %v", i, vals)
+	}
+}
+
+// requireShadingColor checks that the shading named `name` of `page` paints color `expected` at
+// point (`x`,`y`).
+func requireShadingColor(t *testing.T, page *model.PdfPage, name string, x, y float64, expected []float64) {
+	shading, ok := page.Resources.GetShadingByName(core.PdfObjectName(name))
+	require.True(t, ok)
+	evaluator, err := model.NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	vals, ok := evaluator.Evaluate(x, y)
+	require.True(t, ok)
+	require.Len(t, vals, len(expected))
+	for i := range expected {
+		require.InDelta(t, expected[i], vals[i], 0.01, "component %d of %v", i, vals)
+	}
+}
+
+func TestConvertGray(t *testing.T) {
+	reader := makeTestDocument(t)
+	require.NoError(t, NewConverter(TargetGray).ConvertDocument(reader))
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	reader = pdftest.WriteRead(t, page)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	// The spot color is converted through its alternate colorspace: cyan.
+	expected := []string{"0.3 g", "0.5 G", "0.7 g", "0.7 g"}
+	require.Equal(t, expected, colorOperations(t, []byte(content))[:len(expected)])
+
+	stream, _ := page.Resources.GetXObjectByName("Im0")
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	require.NoError(t, err)
+	require.Equal(t, "DeviceGray", ximg.ColorSpace.String())
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, []byte{77, 150}, img.Data)
+
+	requireShadingColor(t, page, "Sh0", 0, 0, []float64{0.3})
+	requireShadingColor(t, page, "Sh0", 100, 0, []float64{0.11})
+	requireShadingColor(t, page, "Sh1", 100, 0, []float64{0.59})
+
+	stream, _ = page.Resources.GetXObjectByName("Fm0")
+	form, err := model.NewXObjectFormFromStream(stream)
+	require.NoError(t, err)
+	formContent, err := form.GetContentStream()
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.59 g"}, colorOperations(t, formContent))
+
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	square, ok := annotations[0].GetContext().(*model.PdfAnnotationSquare)
+	require.True(t, ok)
+	requireColorArray(t, []float64{0.11}, square.C)
+	requireColorArray(t, []float64{0.89}, square.IC)
+	appearance, ok := core.GetStream(core.ResolveReference(square.AP).(*core.PdfObjectDictionary).Get("N"))
+	require.True(t, ok)
+	data, err := core.DecodeStream(appearance)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.11 G"}, colorOperations(t, data))
+}
+
+func TestConvertCMYK(t *testing.T) {
+	reader := makeTestDocument(t)
+	converter := NewConverter(TargetCMYK)
+	converter.PreserveSpotColors = true
+	require.NoError(t, converter.ConvertDocument(reader))
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	reader = pdftest.WriteRead(t, page)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	// The gray colors are converted to black and the spot color is kept.
+	expected := []string{"0 1 1 0 k", "0 0 0 0.5 K", "/CS0 cs", "1 scn"}
+	require.Equal(t, expected, colorOperations(t, []byte(content))[:len(expected)])
+
+	stream, _ := page.Resources.GetXObjectByName("Im0")
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	require.NoError(t, err)
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 255, 255, 0, 255, 0, 255, 0}, img.Data)
+
+	requireShadingColor(t, page, "Sh0", 100, 0, []float64{1, 1, 0, 0})
+	requireShadingColor(t, page, "Sh1", 0, 100, []float64{1, 1, 0, 0})
+}
+
+func TestConvertUncoloredPattern(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	cs, err := model.NewPdfColorspaceFromPdfObject(core.MakeArray(core.MakeName("Pattern"),
+		core.MakeName("DeviceRGB")))
+	require.NoError(t, err)
+	require.NoError(t, resources.SetColorspaceByName("P0", cs))
+	tiling, err := core.MakeStream([]byte("0 0 10 10 re f"), core.NewRawEncoder())
+	require.NoError(t, err)
+	tiling.Set("PatternType", core.MakeInteger(1))
+	tiling.Set("PaintType", core.MakeInteger(2))
+	tiling.Set("TilingType", core.MakeInteger(1))
+	tiling.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 10, 10}))
+	tiling.Set("XStep", core.MakeFloat(10))
+	tiling.Set("YStep", core.MakeFloat(10))
+	tiling.Set("Resources", core.MakeDict())
+	require.NoError(t, resources.SetPatternByName("Pt0", tiling))
+
+	converter := NewConverter(TargetGray)
+	require.NoError(t, converter.prepare())
+	ops, err := converter.convertContent("/P0 cs 1 0 0 /Pt0 scn 0 0 50 50 re f", resources)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/PatternDeviceGray1 cs", "0.3 /Pt0 scn"}, colorOperations(t, ops.Bytes()))
+
+	// The Pattern colorspace based on the target colorspace is added once.
+	cs, ok := resources.GetColorspaceByName("PatternDeviceGray1")
+	require.True(t, ok)
+	require.Equal(t, "[/Pattern /DeviceGray]", cs.ToPdfObject().WriteString())
+	_, err = converter.convertContent("/P0 CS 0 1 0 /Pt0 SCN", resources)
+	require.NoError(t, err)
+	require.False(t, resources.HasColorspaceByName("PatternDeviceGray2"))
+}
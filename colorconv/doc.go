@@ -0,0 +1,22 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package colorconv converts the colors of documents to a single device colorspace: DeviceGray,
+// e.g. for printing in black and white, DeviceRGB or DeviceCMYK, e.g. for printing presses.
+//
+// The colors set by the operators of the page content streams are replaced with colors of the
+// target colorspace, and the images, shadings, patterns and form XObjects used by the pages and
+// the appearances of their annotations are converted in place. The colors are converted through
+// RGB, or with an ICC profile of the output device (Converter.Profile). Spot colors (Separation
+// and DeviceN colorspaces) can be preserved, for printing on separate plates.
+//
+// The soft masks of the graphics states and the glyphs of Type 3 fonts are not converted.
+//
+// Example:
+//
+//	converter := colorconv.NewConverter(colorconv.TargetGray)
+//	err := converter.ConvertDocument(reader)
+//	// Add the pages of reader to a model.PdfWriter.
+package colorconv
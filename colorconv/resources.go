@@ -0,0 +1,392 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package colorconv
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/pdfutil"
+	"github.com/unidoc/unipdf/v3/internal/sampling"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// convertXObject converts XObject `stream` of type `xtype` drawn with resources `resources`.
+func (c *Converter) convertXObject(stream *core.PdfObjectStream, xtype model.XObjectType,
+	resources *model.PdfPageResources) error {
+	if stream == nil || c.visited.Visit(stream) {
+		return nil
+	}
+	switch xtype {
+	case model.XObjectTypeImage:
+		return c.convertImageXObject(stream)
+	case model.XObjectTypeForm:
+		return c.convertForm(stream, resources)
+	}
+	return nil
+}
+
+// convertForm converts the content stream of form XObject `stream`, drawn with resources
+// `resources` if the form has none, and the colorspace of its transparency group.
+func (c *Converter) convertForm(stream *core.PdfObjectStream, resources *model.PdfPageResources) error {
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid form XObject: %v - not converted", err)
+		return nil
+	}
+	content, err := xform.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form XObject: %v - not converted", err)
+		return nil
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+	if formResources == nil {
+		formResources = model.NewPdfPageResources()
+	}
+
+	ops, err := c.convertContent(string(content), formResources)
+	if err != nil {
+		return err
+	}
+	if err := pdfutil.SetStreamData(stream, ops.Bytes()); err != nil {
+		return err
+	}
+	if xform.Resources != nil {
+		stream.Set("Resources", xform.Resources.ToPdfObject())
+	}
+	c.convertGroup(stream.Get("Group"))
+	return nil
+}
+
+// convertGroup sets the colorspace of transparency group dictionary `obj` to the target
+// colorspace.
+func (c *Converter) convertGroup(obj core.PdfObject) {
+	group, ok := core.GetDict(obj)
+	if !ok || group.Get("CS") == nil {
+		return
+	}
+	group.Set("CS", core.MakeName(c.target.String()))
+}
+
+// convertImageXObject converts the samples of image XObject `stream` to the target colorspace,
+// or the lookup table of images of Indexed colorspaces. Stencil masks and the images that cannot
+// be decoded are not converted.
+func (c *Converter) convertImageXObject(stream *core.PdfObjectStream) error {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid image: %v - not converted", err)
+		return nil
+	}
+	if b, ok := core.GetBool(ximg.ImageMask); ok && bool(*b) {
+		return nil
+	}
+	cs := ximg.ColorSpace
+	if !c.isConverted(cs) {
+		return nil
+	}
+
+	if indexed, ok := cs.(*model.PdfColorspaceSpecialIndexed); ok {
+		convertedCS, err := c.convertIndexed(indexed)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid indexed colorspace: %v - not converted", err)
+			return nil
+		}
+		stream.Set("ColorSpace", convertedCS.ToPdfObject())
+		return nil
+	}
+
+	img, err := ximg.ToImage()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode image: %v - not converted", err)
+		return nil
+	}
+	converted, err := c.convertImage(img, cs, ximg.Decode)
+	if err != nil {
+		return err
+	}
+	// The colors of the color key masks are samples of the original image.
+	if ranges, ok := core.GetArray(ximg.Mask); ok {
+		ximg.Mask = colorKeyMask(img, ranges)
+	}
+	if smask, ok := core.GetStream(ximg.SMask); ok {
+		if matte, ok := core.GetArray(smask.Get("Matte")); ok {
+			c.convertColorArray(matte, cs)
+		}
+	}
+
+	ximg.Filter = core.NewFlateEncoder()
+	if err := ximg.SetImage(converted, c.colorspace()); err != nil {
+		return err
+	}
+	ximg.Decode = nil
+	ximg.ToPdfObject()
+	return nil
+}
+
+// convertIndexed returns the Indexed colorspace based on the target colorspace with the colors of
+// the lookup table of `cs`.
+func (c *Converter) convertIndexed(cs *model.PdfColorspaceSpecialIndexed) (model.PdfColorspace, error) {
+	lookup := make([]byte, 0, (cs.HiVal+1)*c.numComponents())
+	for i := 0; i <= cs.HiVal; i++ {
+		color, err := cs.ColorFromFloats([]float64{float64(i)})
+		if err != nil {
+			return nil, err
+		}
+		vals, err := c.convertColor(cs, color)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			lookup = append(lookup, byte(math.Round(math.Min(math.Max(v, 0), 1)*255)))
+		}
+	}
+	return model.NewPdfColorspaceFromPdfObject(core.MakeArray(core.MakeName("Indexed"),
+		core.MakeName(c.target.String()), core.MakeInteger(int64(cs.HiVal)), core.MakeStringFromBytes(lookup)))
+}
+
+// convertImage returns image `img` of colorspace `cs` and decode array `decodeObj` (nil for the
+// default decode array) converted to the target colorspace, with 8 bits per component.
+func (c *Converter) convertImage(img *model.Image, cs model.PdfColorspace, decodeObj core.PdfObject) (
+	*model.Image, error) {
+	n := cs.GetNumComponents()
+	bpc := int(img.BitsPerComponent)
+	if img.ColorComponents != n || bpc < 1 || bpc > 16 {
+		return nil, errors.New("invalid image")
+	}
+	maxVal := float64(uint32(1)<<uint(bpc) - 1)
+	decode := cs.DecodeArray()
+	if _, ok := cs.(*model.PdfColorspaceSpecialIndexed); ok {
+		decode = []float64{0, maxVal}
+	}
+	if arr, ok := core.GetArray(decodeObj); ok {
+		if d, err := arr.ToFloat64Array(); err == nil && len(d) == 2*n {
+			decode = d
+		}
+	}
+	if len(decode) != 2*n {
+		return nil, errors.New("invalid decode array")
+	}
+
+	width, height := int(img.Width), int(img.Height)
+	rowBytes := (width*n*bpc + 7) / 8
+	if len(img.Data) < rowBytes*height {
+		return nil, errors.New("image data too short")
+	}
+
+	cache := map[uint64][]byte{}
+	vals := make([]float64, n)
+	data := make([]byte, 0, width*height*c.numComponents())
+	for y := 0; y < height; y++ {
+		samples := sampling.ResampleBytes(img.Data[y*rowBytes:(y+1)*rowBytes], bpc)
+		for x := 0; x < width; x++ {
+			pixel := samples[x*n : (x+1)*n]
+			key, cacheable := sampling.ColorKey(pixel)
+			if converted, ok := cache[key]; ok && cacheable {
+				data = append(data, converted...)
+				continue
+			}
+
+			for i, s := range pixel {
+				vals[i] = decode[2*i] + float64(s)*(decode[2*i+1]-decode[2*i])/maxVal
+			}
+			color, err := cs.ColorFromFloats(vals)
+			if err != nil {
+				return nil, err
+			}
+			convertedVals, err := c.convertColor(cs, color)
+			if err != nil {
+				return nil, err
+			}
+			converted := make([]byte, len(convertedVals))
+			for i, v := range convertedVals {
+				converted[i] = byte(math.Round(math.Min(math.Max(v, 0), 1) * 255))
+			}
+			if cacheable && len(cache) < sampling.MaxColorCacheSize {
+				cache[key] = converted
+			}
+			data = append(data, converted...)
+		}
+	}
+
+	return &model.Image{
+		Width:            img.Width,
+		Height:           img.Height,
+		BitsPerComponent: 8,
+		ColorComponents:  c.numComponents(),
+		Data:             data,
+	}, nil
+}
+
+// colorKeyMask returns the stencil mask masking out the pixels of image `img` whose samples are
+// in the ranges of color key mask `ranges`, or nil if the ranges are invalid.
+func colorKeyMask(img *model.Image, ranges *core.PdfObjectArray) core.PdfObject {
+	n := img.ColorComponents
+	bpc := int(img.BitsPerComponent)
+	keys, err := ranges.ToIntegerArray()
+	if err != nil || len(keys) != 2*n || bpc < 1 || bpc > 16 {
+		return nil
+	}
+	width, height := int(img.Width), int(img.Height)
+	rowBytes := (width*n*bpc + 7) / 8
+	maskRowBytes := (width + 7) / 8
+	data := make([]byte, maskRowBytes*height)
+	for y := 0; y < height && (y+1)*rowBytes <= len(img.Data); y++ {
+		samples := sampling.ResampleBytes(img.Data[y*rowBytes:(y+1)*rowBytes], bpc)
+		for x := 0; x < width; x++ {
+			masked := true
+			for i, s := range samples[x*n : (x+1)*n] {
+				if int(s) < keys[2*i] || int(s) > keys[2*i+1] {
+					masked = false
+					break
+				}
+			}
+			if masked {
+				data[y*maskRowBytes+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+
+	mask, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return nil
+	}
+	mask.Set("Type", core.MakeName("XObject"))
+	mask.Set("Subtype", core.MakeName("Image"))
+	mask.Set("Width", core.MakeInteger(img.Width))
+	mask.Set("Height", core.MakeInteger(img.Height))
+	mask.Set("ImageMask", core.MakeBool(true))
+	return mask
+}
+
+// convertPattern converts `pattern` used with resources `resources`: the content stream of tiling
+// patterns, drawn with `resources` if the pattern has none, and the shading of shading patterns.
+func (c *Converter) convertPattern(pattern *model.PdfPattern, resources *model.PdfPageResources) error {
+	if c.visited.Visit(pattern.GetContainingPdfObject()) {
+		return nil
+	}
+	if pattern.IsShading() {
+		return c.convertShading(pattern.GetAsShadingPattern().Shading)
+	}
+	if !pattern.IsTiling() {
+		return nil
+	}
+
+	tiling := pattern.GetAsTilingPattern()
+	stream, ok := core.GetStream(pattern.GetContainingPdfObject())
+	if !ok {
+		return nil
+	}
+	content, err := tiling.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode tiling pattern: %v - not converted", err)
+		return nil
+	}
+	patternResources := tiling.Resources
+	if patternResources == nil {
+		patternResources = resources
+	}
+	ops, err := c.convertContent(string(content), patternResources)
+	if err != nil {
+		return err
+	}
+	if err := pdfutil.SetStreamData(stream, ops.Bytes()); err != nil {
+		return err
+	}
+	if tiling.Resources != nil {
+		stream.Set("Resources", tiling.Resources.ToPdfObject())
+	}
+	return nil
+}
+
+// convertAnnotation converts the appearance streams of `annot` and the colors of its
+// dictionary, which are used to regenerate its appearances.
+func (c *Converter) convertAnnotation(annot *model.PdfAnnotation) error {
+	dict, ok := core.GetDict(annot.GetContainingPdfObject())
+	if !ok {
+		return nil
+	}
+	for _, key := range []core.PdfObjectName{"C", "IC"} {
+		if arr, ok := core.GetArray(dict.Get(key)); ok {
+			c.convertColorArray(arr, nil)
+		}
+	}
+	if mk, ok := core.GetDict(dict.Get("MK")); ok {
+		for _, key := range []core.PdfObjectName{"BC", "BG"} {
+			if arr, ok := core.GetArray(mk.Get(key)); ok {
+				c.convertColorArray(arr, nil)
+			}
+		}
+	}
+
+	ap, ok := core.GetDict(dict.Get("AP"))
+	if !ok {
+		return nil
+	}
+	for _, key := range []core.PdfObjectName{"N", "R", "D"} {
+		obj := ap.Get(key)
+		if stream, ok := core.GetStream(obj); ok {
+			if err := c.convertXObject(stream, model.XObjectTypeForm, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		// Appearances of the states of the annotation.
+		states, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		for _, state := range states.Keys() {
+			if stream, ok := core.GetStream(states.Get(state)); ok {
+				if err := c.convertXObject(stream, model.XObjectTypeForm, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// convertColorArray converts the color components of `arr` in place, from colorspace `cs` or, if
+// nil, from the device colorspace with the same number of components. The arrays of other colors
+// are not changed.
+func (c *Converter) convertColorArray(arr *core.PdfObjectArray, cs model.PdfColorspace) {
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return
+	}
+	if cs == nil {
+		switch len(vals) {
+		case 1:
+			cs = model.NewPdfColorspaceDeviceGray()
+		case 3:
+			cs = model.NewPdfColorspaceDeviceRGB()
+		case 4:
+			cs = model.NewPdfColorspaceDeviceCMYK()
+		default:
+			return
+		}
+	}
+	if !c.isConverted(cs) {
+		return
+	}
+	color, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid color %v: %v - not converted", vals, err)
+		return
+	}
+	converted, err := c.convertColor(cs, color)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to convert color %v: %v", vals, err)
+		return
+	}
+	arr.Clear()
+	arr.Append(core.MakeArrayFromFloats(converted).Elements()...)
+}
@@ -0,0 +1,376 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package colorconv
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/jbig2/reader"
+	"github.com/unidoc/unipdf/v3/internal/pdfutil"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Number of samples per input of the sampled functions replacing the functions of the shadings
+// with 1 input (axial, radial and mesh shadings) and 2 inputs (function-based shadings).
+const (
+	gradientSamples = 256
+	functionSamples = 64
+)
+
+// convertShading converts `shading` in place: its functions are replaced with sampled functions
+// returning colors of the target colorspace and the colors of the vertices of mesh shadings
+// without functions are converted.
+func (c *Converter) convertShading(shading *model.PdfShading) error {
+	if shading == nil || c.visited.Visit(shading.GetContainingPdfObject()) {
+		return nil
+	}
+	cs := shading.ColorSpace
+	if !c.isConverted(cs) {
+		return nil
+	}
+	var dict *core.PdfObjectDictionary
+	stream, isStream := core.GetStream(shading.GetContainingPdfObject())
+	if isStream {
+		dict = stream.PdfObjectDictionary
+	} else if d, ok := core.GetDict(shading.GetContainingPdfObject()); ok {
+		dict = d
+	} else {
+		return nil
+	}
+
+	var funcs []model.PdfFunction
+	var domain []float64
+	var mesh *meshParams
+	switch t := shading.GetContext().(type) {
+	case *model.PdfShadingType1:
+		funcs, domain = t.Function, floats(t.Domain, []float64{0, 1, 0, 1})
+	case *model.PdfShadingType2:
+		funcs, domain = t.Function, floats(t.Domain, []float64{0, 1})
+	case *model.PdfShadingType3:
+		funcs, domain = t.Function, floats(t.Domain, []float64{0, 1})
+	case *model.PdfShadingType4:
+		funcs = t.Function
+		mesh = &meshParams{shadingType: 4, bitsPerCoord: t.BitsPerCoordinate, bitsPerComp: t.BitsPerComponent,
+			bitsPerFlag: t.BitsPerFlag, decode: floats(t.Decode, nil)}
+	case *model.PdfShadingType5:
+		funcs = t.Function
+		mesh = &meshParams{shadingType: 5, bitsPerCoord: t.BitsPerCoordinate, bitsPerComp: t.BitsPerComponent,
+			decode: floats(t.Decode, nil)}
+	case *model.PdfShadingType6:
+		funcs = t.Function
+		mesh = &meshParams{shadingType: 6, bitsPerCoord: t.BitsPerCoordinate, bitsPerComp: t.BitsPerComponent,
+			bitsPerFlag: t.BitsPerFlag, decode: floats(t.Decode, nil)}
+	case *model.PdfShadingType7:
+		funcs = t.Function
+		mesh = &meshParams{shadingType: 7, bitsPerCoord: t.BitsPerCoordinate, bitsPerComp: t.BitsPerComponent,
+			bitsPerFlag: t.BitsPerFlag, decode: floats(t.Decode, nil)}
+	default:
+		return nil
+	}
+
+	if len(funcs) > 0 {
+		// The parametric values of the vertices of mesh shadings are in the range of the decode
+		// array.
+		if mesh != nil {
+			if len(mesh.decode) < 6 {
+				common.Log.Debug("ERROR: Invalid mesh decode array - not converted")
+				return nil
+			}
+			domain = mesh.decode[4:6]
+		}
+		size := []int{gradientSamples}
+		if len(domain) == 4 {
+			size = []int{functionSamples, functionSamples}
+		}
+		function, err := c.sampledFunction(cs, funcs, domain, size)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to evaluate shading functions: %v - not converted", err)
+			return nil
+		}
+		dict.Set("Function", function)
+	} else if mesh != nil && isStream {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode mesh shading: %v - not converted", err)
+			return nil
+		}
+		converted, err := c.convertMesh(data, mesh, cs)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid mesh shading: %v - not converted", err)
+			return nil
+		}
+		decode := append([]float64{}, mesh.decode[:4]...)
+		for i := 0; i < c.numComponents(); i++ {
+			decode = append(decode, 0, 1)
+		}
+		dict.Set("BitsPerComponent", core.MakeInteger(16))
+		dict.Set("Decode", core.MakeArrayFromFloats(decode))
+		if err := pdfutil.SetStreamData(stream, converted); err != nil {
+			return err
+		}
+	} else {
+		return nil
+	}
+
+	if shading.Background != nil {
+		c.convertColorArray(shading.Background, cs)
+	}
+	dict.Set("ColorSpace", core.MakeName(c.target.String()))
+	return nil
+}
+
+// floats returns the numbers of `arr`, or `def` if `arr` is nil or not an array of numbers.
+func floats(arr *core.PdfObjectArray, def []float64) []float64 {
+	if arr == nil {
+		return def
+	}
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return def
+	}
+	return vals
+}
+
+// sampledFunction returns a sampled function (type 0) with `len(size)` inputs over `domain` and
+// `size` samples per input, which returns the components in the target colorspace of the colors
+// of colorspace `cs` returned by shading functions `funcs` at the samples.
+func (c *Converter) sampledFunction(cs model.PdfColorspace, funcs []model.PdfFunction, domain []float64,
+	size []int) (*core.PdfObjectStream, error) {
+	if len(domain) != 2*len(size) {
+		return nil, errors.New("invalid domain")
+	}
+	ranges := cs.DecodeArray()
+	n := cs.GetNumComponents()
+	if len(ranges) != 2*n {
+		return nil, errors.New("invalid colorspace ranges")
+	}
+
+	total := 1
+	for _, s := range size {
+		total *= s
+	}
+	in := make([]float64, len(size))
+	data := make([]byte, 0, 2*c.numComponents()*total)
+	for i := 0; i < total; i++ {
+		// The first input varies fastest.
+		k := i
+		for j, s := range size {
+			in[j] = domain[2*j] + float64(k%s)*(domain[2*j+1]-domain[2*j])/float64(s-1)
+			k /= s
+		}
+		vals, err := evaluateFunctions(funcs, in, n)
+		if err != nil {
+			return nil, err
+		}
+		for j := range vals {
+			vals[j] = math.Max(ranges[2*j], math.Min(ranges[2*j+1], vals[j]))
+		}
+		color, err := cs.ColorFromFloats(vals)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := c.convertColor(cs, color)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range converted {
+			u := uint16(math.Round(math.Min(math.Max(v, 0), 1) * 0xffff))
+			data = append(data, byte(u>>8), byte(u))
+		}
+	}
+
+	stream, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	outRange := make([]float64, 0, 2*c.numComponents())
+	for i := 0; i < c.numComponents(); i++ {
+		outRange = append(outRange, 0, 1)
+	}
+	sizes := make([]int64, len(size))
+	for i, s := range size {
+		sizes[i] = int64(s)
+	}
+	stream.Set("FunctionType", core.MakeInteger(0))
+	stream.Set("Domain", core.MakeArrayFromFloats(domain))
+	stream.Set("Range", core.MakeArrayFromFloats(outRange))
+	stream.Set("Size", core.MakeArrayFromIntegers64(sizes))
+	stream.Set("BitsPerSample", core.MakeInteger(16))
+	return stream, nil
+}
+
+// evaluateFunctions returns the `n` color components returned by shading functions `funcs` for
+// inputs `in`: a single function returns all the components, otherwise there is one function per
+// component.
+func evaluateFunctions(funcs []model.PdfFunction, in []float64, n int) ([]float64, error) {
+	var vals []float64
+	if len(funcs) == 1 {
+		out, err := funcs[0].Evaluate(in)
+		if err != nil {
+			return nil, err
+		}
+		vals = out
+	} else {
+		for _, f := range funcs {
+			out, err := f.Evaluate(in)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) == 0 {
+				return nil, errors.New("function without output")
+			}
+			vals = append(vals, out[0])
+		}
+	}
+	if len(vals) < n {
+		return nil, errors.New("too few function outputs")
+	}
+	return vals[:n], nil
+}
+
+// meshParams are the parameters of the streams of mesh shadings.
+type meshParams struct {
+	shadingType  int
+	bitsPerCoord *core.PdfObjectInteger
+	bitsPerComp  *core.PdfObjectInteger
+	bitsPerFlag  *core.PdfObjectInteger
+	decode       []float64
+}
+
+// convertMesh returns the data of the stream of a mesh shading with parameters `mesh` and color
+// components of colorspace `cs`, `data`, with the color components converted to components of the
+// target colorspace of 16 bits and range [0,1]. The edge flags and coordinates are copied.
+func (c *Converter) convertMesh(data []byte, mesh *meshParams, cs model.PdfColorspace) ([]byte, error) {
+	n := cs.GetNumComponents()
+	if mesh.bitsPerCoord == nil || mesh.bitsPerComp == nil || len(mesh.decode) < 4+2*n {
+		return nil, errors.New("mesh parameters missing")
+	}
+	bitsPerCoord, bitsPerComp := byte(*mesh.bitsPerCoord), byte(*mesh.bitsPerComp)
+	var bitsPerFlag byte
+	if mesh.bitsPerFlag != nil {
+		bitsPerFlag = byte(*mesh.bitsPerFlag)
+	}
+	if bitsPerCoord < 1 || bitsPerCoord > 32 || bitsPerComp < 1 || bitsPerComp > 16 || bitsPerFlag > 8 ||
+		(mesh.shadingType != 5 && bitsPerFlag == 0) {
+		return nil, errors.New("invalid mesh bits")
+	}
+
+	r := reader.New(data)
+	w := &bitWriter{}
+	copyBits := func(bits byte) (uint64, error) {
+		u, err := r.ReadBits(bits)
+		if err != nil {
+			return 0, err
+		}
+		w.writeBits(u, bits)
+		return u, nil
+	}
+	copyPoints := func(count int) error {
+		for i := 0; i < 2*count; i++ {
+			if _, err := copyBits(bitsPerCoord); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	maxComp := float64(uint64(1)<<bitsPerComp - 1)
+	vals := make([]float64, n)
+	convertColors := func(count int) error {
+		for k := 0; k < count; k++ {
+			for i := range vals {
+				u, err := r.ReadBits(bitsPerComp)
+				if err != nil {
+					return err
+				}
+				min, max := mesh.decode[4+2*i], mesh.decode[5+2*i]
+				vals[i] = min + float64(u)*(max-min)/maxComp
+			}
+			color, err := cs.ColorFromFloats(vals)
+			if err != nil {
+				return err
+			}
+			converted, err := c.convertColor(cs, color)
+			if err != nil {
+				return err
+			}
+			for _, v := range converted {
+				w.writeBits(uint64(math.Round(math.Min(math.Max(v, 0), 1)*0xffff)), 16)
+			}
+		}
+		return nil
+	}
+
+	// The vertices of free-form meshes and the patches start at byte boundaries. The streams
+	// ending with a partial vertex or patch are truncated as when they are drawn.
+	for {
+		var err error
+		switch mesh.shadingType {
+		case 4:
+			if _, err = copyBits(bitsPerFlag); err == nil {
+				if err = copyPoints(1); err == nil {
+					err = convertColors(1)
+				}
+			}
+			r.Align()
+			w.align()
+		case 5:
+			if err = copyPoints(1); err == nil {
+				err = convertColors(1)
+			}
+		case 6, 7:
+			var flag uint64
+			if flag, err = copyBits(bitsPerFlag); err != nil {
+				break
+			}
+			points, colors := 12, 4
+			if mesh.shadingType == 7 {
+				points = 16
+			}
+			// Patches sharing an edge with the previous patch omit its points and colors.
+			if flag != 0 {
+				points, colors = points-4, colors-2
+			}
+			if err = copyPoints(points); err == nil {
+				err = convertColors(colors)
+			}
+			r.Align()
+			w.align()
+		default:
+			return nil, errors.New("not a mesh shading")
+		}
+		if err != nil {
+			break
+		}
+	}
+	return w.data, nil
+}
+
+// bitWriter writes values of up to 64 bits, most significant bit first.
+type bitWriter struct {
+	data  []byte
+	nbits uint // Number of bits written in the last byte.
+}
+
+// writeBits writes the `bits` least significant bits of `v`.
+func (w *bitWriter) writeBits(v uint64, bits byte) {
+	for i := int(bits) - 1; i >= 0; i-- {
+		if w.nbits == 0 {
+			w.data = append(w.data, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.data[len(w.data)-1] |= 0x80 >> w.nbits
+		}
+		w.nbits = (w.nbits + 1) % 8
+	}
+}
+
+// align pads the last byte with zeros.
+func (w *bitWriter) align() {
+	w.nbits = 0
+}
@@ -0,0 +1,411 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package contentstream
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// OCFlattenMode specifies how optional content is flattened by FlattenOptionalContent.
+type OCFlattenMode int
+
+// Optional content flattening modes.
+const (
+	// OCFlattenRemoveHidden removes the content which is hidden in the optional content
+	// configuration. The visible optional content becomes part of the base content.
+	OCFlattenRemoveHidden OCFlattenMode = iota
+
+	// OCFlattenMergeAll merges the content of all optional content groups into the base
+	// content, regardless of its visibility.
+	OCFlattenMergeAll
+)
+
+// FlattenOptionalContent rewrites the contents of `page` so that they no longer depend on
+// optional content (layers): marked content sequences tagged OC are unwrapped and the OC entries
+// of the XObjects and annotations are removed. With mode OCFlattenRemoveHidden, the content
+// hidden in configuration `config` of `ocProperties` (the default configuration if nil) is
+// removed. Graphics state changes made by hidden content are kept, as they affect the
+// following content, and hidden text is replaced by positioning adjustments of the same width
+// so that the following text of the text object is not moved.
+// Once all pages are flattened, the optional content properties can be dropped from the
+// document, e.g. by not setting them on the PdfWriter.
+func FlattenOptionalContent(page *model.PdfPage, ocProperties *model.PdfOCProperties, config *model.PdfOCConfig,
+	mode OCFlattenMode) error {
+	f := &ocFlattener{
+		ocProperties: ocProperties,
+		config:       config,
+		mode:         mode,
+		forms:        map[*core.PdfObjectStream]struct{}{},
+		fonts:        map[core.PdfObject]*model.PdfFont{},
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return err
+	}
+	flattened, err := f.flatten(ops, page.Resources)
+	if err != nil {
+		return err
+	}
+	err = page.SetContentStreams([]string{string(flattened.Bytes())}, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	if len(annotations) > 0 {
+		var kept []*model.PdfAnnotation
+		for _, annot := range annotations {
+			if annot.OC != nil {
+				if !f.isVisible(annot.OC) {
+					continue
+				}
+				annot.OC = nil
+				if dict, ok := core.GetDict(annot.GetContainingPdfObject()); ok {
+					dict.Remove("OC")
+				}
+			}
+			kept = append(kept, annot)
+		}
+		page.SetAnnotations(kept)
+	}
+	return nil
+}
+
+// ocFlattener flattens optional content in content streams.
+type ocFlattener struct {
+	ocProperties *model.PdfOCProperties
+	config       *model.PdfOCConfig
+	mode         OCFlattenMode
+
+	// Processed form XObjects.
+	forms map[*core.PdfObjectStream]struct{}
+
+	// Loaded fonts, used to measure the hidden text.
+	fonts map[core.PdfObject]*model.PdfFont
+}
+
+// ocTextState is the part of the text state which determines the displacement of the shown
+// text (section 9.4.4 PDF32000_2008).
+type ocTextState struct {
+	font        *model.PdfFont
+	fontSize    float64
+	charSpacing float64
+	wordSpacing float64
+}
+
+// ocMarkedContent represents a marked content sequence during flattening.
+type ocMarkedContent struct {
+	isOC   bool // Tagged OC: the sequence delimiters are removed.
+	hidden bool // Hidden: the sequence contents are removed.
+}
+
+// isVisible returns true if content controlled by `oc` is kept.
+func (f *ocFlattener) isVisible(oc core.PdfObject) bool {
+	if f.mode == OCFlattenMergeAll {
+		return true
+	}
+	return isOptionalContentVisible(oc, f.ocProperties, f.config)
+}
+
+// flatten returns the flattened operations `ops` using resources `resources`.
+func (f *ocFlattener) flatten(ops *ContentStreamOperations, resources *model.PdfPageResources) (
+	*ContentStreamOperations, error) {
+	var out ContentStreamOperations
+	var stack []ocMarkedContent
+	numHidden := 0
+
+	// Path of hidden content, kept if used for clipping.
+	var path ContentStreamOperations
+	var clip *ContentStreamOperation
+
+	// Text state, used to move the text matrix past the hidden text.
+	var ts ocTextState
+	var tsStack []ocTextState
+
+	for _, op := range *ops {
+		hidden := numHidden > 0
+		f.updateTextState(op, resources, &ts, &tsStack)
+
+		switch op.Operand {
+		case "BMC", "BDC":
+			var mc ocMarkedContent
+			if op.Operand == "BDC" && len(op.Params) == 2 {
+				if tag, ok := core.GetNameVal(op.Params[0]); ok && tag == "OC" {
+					mc.isOC = true
+					if !hidden && !f.isVisible(markedContentOC(op, resources)) {
+						mc.hidden = true
+						numHidden++
+					}
+				}
+			}
+			stack = append(stack, mc)
+			if !mc.isOC && !hidden {
+				out = append(out, op)
+			}
+			continue
+		case "EMC":
+			if len(stack) == 0 {
+				common.Log.Debug("EMC without marked content sequence - keeping")
+				out = append(out, op)
+				continue
+			}
+			mc := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if mc.hidden {
+				numHidden--
+			}
+			if !mc.isOC && !hidden {
+				out = append(out, op)
+			}
+			continue
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				break
+			}
+			name, ok := core.GetName(op.Params[0])
+			if !ok {
+				break
+			}
+			stream, xtype := resources.GetXObjectByName(*name)
+			if stream == nil || hidden {
+				break
+			}
+			if oc := stream.Get("OC"); oc != nil && !f.isVisible(oc) {
+				// Hidden XObject.
+				continue
+			}
+			if xtype == model.XObjectTypeForm {
+				if err := f.flattenForm(stream, resources); err != nil {
+					return nil, err
+				}
+			}
+			stream.PdfObjectDictionary.Remove("OC")
+		}
+
+		if !hidden {
+			out = append(out, op)
+			continue
+		}
+
+		// Hidden content: only keep the operations which affect the graphics state.
+		switch op.Operand {
+		case "q", "Q", "cm", "w", "J", "j", "M", "d", "ri", "i", "gs",
+			"CS", "cs", "SC", "SCN", "sc", "scn", "G", "g", "RG", "rg", "K", "k",
+			"BT", "ET", "Tc", "Tw", "Tz", "TL", "Tf", "Tr", "Ts", "Td", "TD", "Tm", "T*":
+			out = append(out, op)
+		case "Tj":
+			out = f.appendTextDisplacement(out, op.Params, &ts)
+		case "TJ":
+			if len(op.Params) == 1 {
+				if arr, ok := core.GetArray(op.Params[0]); ok {
+					out = f.appendTextDisplacement(out, arr.Elements(), &ts)
+				}
+			}
+		case "'":
+			out = append(out, &ContentStreamOperation{Operand: "T*"})
+			out = f.appendTextDisplacement(out, op.Params, &ts)
+		case "\"":
+			if len(op.Params) == 3 {
+				out = append(out,
+					&ContentStreamOperation{Operand: "Tw", Params: op.Params[:1]},
+					&ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]})
+			}
+			out = append(out, &ContentStreamOperation{Operand: "T*"})
+			if len(op.Params) == 3 {
+				out = f.appendTextDisplacement(out, op.Params[2:], &ts)
+			}
+		case "m", "l", "c", "v", "y", "h", "re":
+			path = append(path, op)
+		case "W", "W*":
+			clip = op
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			if clip != nil {
+				// Keep the clipping without painting the path.
+				out = append(out, path...)
+				out = append(out, clip, &ContentStreamOperation{Operand: "n"})
+			}
+			path = nil
+			clip = nil
+		}
+	}
+
+	return &out, nil
+}
+
+// updateTextState updates text state `ts` and its saved copies `stack` with operation `op`,
+// loading the fonts from `resources`.
+func (f *ocFlattener) updateTextState(op *ContentStreamOperation, resources *model.PdfPageResources,
+	ts *ocTextState, stack *[]ocTextState) {
+	switch op.Operand {
+	case "q":
+		*stack = append(*stack, *ts)
+	case "Q":
+		if n := len(*stack); n > 0 {
+			*ts = (*stack)[n-1]
+			*stack = (*stack)[:n-1]
+		}
+	case "Tf":
+		if len(op.Params) != 2 || resources == nil {
+			return
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			return
+		}
+		size, err := core.GetNumberAsFloat(op.Params[1])
+		if err != nil {
+			return
+		}
+		obj, _ := resources.GetFontByName(*name)
+		ts.font = f.loadFont(obj)
+		ts.fontSize = size
+	case "gs":
+		if len(op.Params) != 1 || resources == nil {
+			return
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			return
+		}
+		obj, _ := resources.GetExtGState(*name)
+		gs, ok := core.GetDict(obj)
+		if !ok {
+			return
+		}
+		if arr, ok := core.GetArray(gs.Get("Font")); ok && arr.Len() == 2 {
+			if size, err := core.GetNumberAsFloat(core.ResolveReference(arr.Get(1))); err == nil {
+				ts.font = f.loadFont(arr.Get(0))
+				ts.fontSize = size
+			}
+		}
+	case "Tc", "Tw":
+		if len(op.Params) != 1 {
+			return
+		}
+		v, err := core.GetNumberAsFloat(op.Params[0])
+		if err != nil {
+			return
+		}
+		if op.Operand == "Tc" {
+			ts.charSpacing = v
+		} else {
+			ts.wordSpacing = v
+		}
+	case "\"":
+		if len(op.Params) != 3 {
+			return
+		}
+		if v, err := core.GetNumbersAsFloat(op.Params[:2]); err == nil {
+			ts.wordSpacing, ts.charSpacing = v[0], v[1]
+		}
+	}
+}
+
+// loadFont returns the font of font dictionary `obj`, or nil if it cannot be loaded.
+func (f *ocFlattener) loadFont(obj core.PdfObject) *model.PdfFont {
+	if obj == nil {
+		return nil
+	}
+	if font, ok := f.fonts[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load font: %v", err)
+		font = nil
+	}
+	f.fonts[obj] = font
+	return font
+}
+
+// appendTextDisplacement appends to `out` a TJ operation moving the text matrix as hidden text
+// operands `args` (strings and positioning adjustments as for TJ) would, without showing any
+// text, so that the following text is not moved. The horizontal scaling applies to both.
+func (f *ocFlattener) appendTextDisplacement(out ContentStreamOperations, args []core.PdfObject,
+	ts *ocTextState) ContentStreamOperations {
+	if ts.fontSize == 0 {
+		return out
+	}
+	// Displacement in unscaled text space units (section 9.4.4 PDF32000_2008).
+	tx := 0.0
+	for _, arg := range args {
+		if n, err := core.GetNumberAsFloat(arg); err == nil {
+			tx -= n / 1000 * ts.fontSize
+			continue
+		}
+		str, ok := core.GetString(arg)
+		if !ok {
+			continue
+		}
+		if ts.font == nil {
+			common.Log.Debug("ERROR: Hidden text without font - text displacement not kept")
+			continue
+		}
+		for _, code := range ts.font.BytesToCharcodes(str.Bytes()) {
+			if m, ok := ts.font.GetCharMetrics(code); ok {
+				tx += m.Wx / 1000 * ts.fontSize
+			}
+			tx += ts.charSpacing
+			if !ts.font.IsCID() && code == 32 {
+				tx += ts.wordSpacing
+			}
+		}
+	}
+	if tx == 0 {
+		return out
+	}
+	adjustment := -tx * 1000 / ts.fontSize
+	return append(out, &ContentStreamOperation{
+		Operand: "TJ",
+		Params:  []core.PdfObject{core.MakeArray(core.MakeFloat(adjustment))},
+	})
+}
+
+// flattenForm flattens the content stream of the form XObject `stream` drawn with resources
+// `resources`.
+func (f *ocFlattener) flattenForm(stream *core.PdfObjectStream, resources *model.PdfPageResources) error {
+	if _, processed := f.forms[stream]; processed {
+		return nil
+	}
+	f.forms[stream] = struct{}{}
+
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return err
+	}
+	content, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	ops, err := NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		return err
+	}
+
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+	flattened, err := f.flatten(ops, formResources)
+	if err != nil {
+		return err
+	}
+	if err := xform.SetContentStream(flattened.Bytes(), core.NewFlateEncoder()); err != nil {
+		return err
+	}
+	xform.ToPdfObject()
+	return nil
+}
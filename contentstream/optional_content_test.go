@@ -0,0 +1,148 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package contentstream
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+const testOCContents = `q 1 0 0 rg
+/OC /OC0 BDC 0 0 10 10 re f EMC
+/OC /OC1 BDC 2 w 0 0 m 5 5 l S 0 0 20 20 re W n BT /F1 12 Tf (hidden) Tj ET EMC
+/Span <</MCID 0>> BDC BT (span) Tj ET EMC
+/Fm0 Do
+Q`
+
+// newTestOCPage returns a page with optional content: OCG A (visible) and B (hidden) referenced
+// by marked content and a form XObject in B.
+func newTestOCPage(t *testing.T) (*model.PdfPage, *model.PdfOCProperties) {
+	ocProperties := model.NewPdfOCProperties()
+	a, b := model.NewPdfOCG("A"), model.NewPdfOCG("B")
+	ocProperties.AddOCG(a, true)
+	ocProperties.AddOCG(b, false)
+
+	page := pdftest.NewPage(t, 200, 200, testOCContents)
+	require.NoError(t, page.Resources.SetPropertiesByName("OC0", a.ToPdfObject()))
+	require.NoError(t, page.Resources.SetPropertiesByName("OC1", b.ToPdfObject()))
+
+	xform := model.NewXObjectForm()
+	xform.OC = b.ToPdfObject()
+	require.NoError(t, xform.SetContentStream([]byte("/OC /OC0 BDC 0 0 1 1 re f EMC"), nil))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm0", xform))
+	return page, ocProperties
+}
+
+func TestProcessorOptionalContent(t *testing.T) {
+	page, ocProperties := newTestOCPage(t)
+
+	ops, err := NewContentStreamParser(testOCContents).Parse()
+	require.NoError(t, err)
+	proc := NewContentStreamProcessor(*ops)
+	proc.SetOptionalContent(ocProperties, nil)
+
+	var visibleRects, hiddenRects int
+	var formVisible bool
+	proc.AddHandler(HandlerConditionEnumAllOperands, "",
+		func(op *ContentStreamOperation, gs GraphicsState, resources *model.PdfPageResources) error {
+			switch op.Operand {
+			case "re":
+				if proc.IsContentVisible() {
+					visibleRects++
+				} else {
+					hiddenRects++
+				}
+			case "Do":
+				stream, _ := resources.GetXObjectByName("Fm0")
+				formVisible = proc.IsOptionalContentVisible(stream.Get("OC"))
+			}
+			return nil
+		})
+	require.NoError(t, proc.Process(page.Resources))
+	require.Equal(t, 1, visibleRects)
+	require.Equal(t, 1, hiddenRects)
+	require.False(t, formVisible)
+	require.True(t, proc.IsContentVisible())
+}
+
+func TestFlattenOptionalContent(t *testing.T) {
+	// Remove hidden content.
+	page, ocProperties := newTestOCPage(t)
+	require.NoError(t, FlattenOptionalContent(page, ocProperties, nil, OCFlattenRemoveHidden))
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+
+	require.NotContains(t, contents, "/OC")
+	require.Contains(t, contents, "0 0 10 10 re")
+	require.NotContains(t, contents, "(hidden)")
+	require.NotContains(t, contents, "5 5 l")
+	require.NotContains(t, contents, "/Fm0 Do")
+	// Graphics state changes and clipping of hidden content are kept.
+	require.Contains(t, contents, "2 w")
+	require.Contains(t, contents, "0 0 20 20 re\nW\nn")
+	// Other marked content is kept.
+	require.Contains(t, contents, "/Span")
+	require.Equal(t, 1, strings.Count(contents, "EMC"))
+
+	// Merge all layers.
+	page, ocProperties = newTestOCPage(t)
+	require.NoError(t, FlattenOptionalContent(page, ocProperties, nil, OCFlattenMergeAll))
+	contents, err = page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, contents, "/OC")
+	require.Contains(t, contents, "(hidden)")
+	require.Contains(t, contents, "/Fm0 Do")
+
+	stream, _ := page.Resources.GetXObjectByName("Fm0")
+	require.Nil(t, stream.Get("OC"))
+	formContent, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+	require.NotContains(t, string(formContent), "BDC")
+	require.Contains(t, string(formContent), "0 0 1 1 re")
+}
+
+func TestFlattenOptionalContentHiddenText(t *testing.T) {
+	// The hidden text is replaced by a positioning adjustment of its width, so that the following
+	// visible text is not moved.
+	ocProperties := model.NewPdfOCProperties()
+	b := model.NewPdfOCG("B")
+	ocProperties.AddOCG(b, false)
+	page := pdftest.NewPage(t, 200, 200,
+		"BT /F1 10 Tf 2 Tc /OC /OC1 BDC (hid) Tj [(den) -500] TJ EMC (shown) Tj ET")
+	require.NoError(t, page.Resources.SetPropertiesByName("OC1", b.ToPdfObject()))
+
+	require.NoError(t, FlattenOptionalContent(page, ocProperties, nil, OCFlattenRemoveHidden))
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, contents, "hid")
+	require.NotContains(t, contents, "den")
+	require.Contains(t, contents, "(shown) Tj")
+
+	ops, err := NewContentStreamParser(contents).Parse()
+	require.NoError(t, err)
+	var adjustments []float64
+	for _, op := range *ops {
+		if op.Operand != "TJ" {
+			continue
+		}
+		arr, ok := core.GetArray(op.Params[0])
+		require.True(t, ok)
+		vals, err := arr.ToFloat64Array()
+		require.NoError(t, err)
+		adjustments = append(adjustments, vals...)
+	}
+	// Helvetica widths: h 556, i 222, d 556, e 556, n 556. The character spacing of 2 is 200
+	// thousandths of the font size of 10 per glyph.
+	require.Len(t, adjustments, 2)
+	require.InDelta(t, -(556 + 222 + 556 + 3*200), adjustments[0], 1e-6)
+	require.InDelta(t, -(556+556+556+3*200)-500, adjustments[1], 1e-6)
+}
@@ -55,6 +55,14 @@ type ContentStreamProcessor struct {
 
 	handlers     []handlerEntry
 	currentIndex int
+
+	// Optional content evaluation (disabled if ocProperties is nil).
+	ocProperties *model.PdfOCProperties
+	ocConfig     *model.PdfOCConfig
+	// Visibility of the marked content sequences enclosing the current operation
+	// and number of hidden ones.
+	markedContent []bool
+	numHidden     int
 }
 
 // HandlerFunc is the function syntax that the ContentStreamProcessor handler must implement.
@@ -192,16 +200,32 @@ func (proc *ContentStreamProcessor) getInitialColor(cs model.PdfColorspace) (mod
 		if cs.Base == nil {
 			return nil, errors.New("indexed base not specified")
 		}
+		// The initial color is the color of index 0.
+		if color, err := cs.ColorFromFloats([]float64{0}); err == nil {
+			return color, nil
+		}
 		return proc.getInitialColor(cs.Base)
 	case *model.PdfColorspaceSpecialSeparation:
 		if cs.AlternateSpace == nil {
 			return nil, errors.New("alternate space not specified")
 		}
+		// The initial color has tint 1.0 (section 8.6.6.4 p. 165 PDF32000_2008).
+		if color, err := cs.ColorFromFloats([]float64{1}); err == nil {
+			return color, nil
+		}
 		return proc.getInitialColor(cs.AlternateSpace)
 	case *model.PdfColorspaceDeviceN:
 		if cs.AlternateSpace == nil {
 			return nil, errors.New("alternate space not specified")
 		}
+		// All the components of the initial color have tint 1.0.
+		tints := make([]float64, cs.GetNumComponents())
+		for i := range tints {
+			tints[i] = 1
+		}
+		if color, err := cs.ColorFromFloats(tints); err == nil {
+			return color, nil
+		}
 		return proc.getInitialColor(cs.AlternateSpace)
 	case *model.PdfColorspaceSpecialPattern:
 		// FIXME/check: A pattern does not have an initial color...
@@ -212,6 +236,92 @@ func (proc *ContentStreamProcessor) getInitialColor(cs model.PdfColorspace) (mod
 	return nil, errors.New("unsupported colorspace")
 }
 
+// SetOptionalContent enables the evaluation of optional content (layers) while processing,
+// with the state of the optional content groups given by configuration `config` of
+// `ocProperties`. If `config` is nil, the default configuration is used.
+// Handlers can check whether the current operation is visible with IsContentVisible.
+func (proc *ContentStreamProcessor) SetOptionalContent(ocProperties *model.PdfOCProperties, config *model.PdfOCConfig) {
+	proc.ocProperties = ocProperties
+	proc.ocConfig = config
+}
+
+// IsContentVisible returns false if the current operation is in a marked content sequence
+// which is hidden by optional content. Always returns true if optional content evaluation
+// is not enabled with SetOptionalContent.
+func (proc *ContentStreamProcessor) IsContentVisible() bool {
+	return proc.numHidden == 0
+}
+
+// IsOptionalContentVisible returns true if content controlled by the optional content group
+// or membership dictionary `oc` is visible, e.g. for the OC entry of an XObject.
+// Returns true if `oc` is nil or optional content evaluation is not enabled.
+func (proc *ContentStreamProcessor) IsOptionalContentVisible(oc core.PdfObject) bool {
+	return isOptionalContentVisible(oc, proc.ocProperties, proc.ocConfig)
+}
+
+// isOptionalContentVisible returns true if content controlled by `oc` is visible in
+// configuration `config` (default configuration if nil) of `ocProperties`.
+func isOptionalContentVisible(oc core.PdfObject, ocProperties *model.PdfOCProperties, config *model.PdfOCConfig) bool {
+	if oc == nil || ocProperties == nil {
+		return true
+	}
+	content, err := ocProperties.GetOptionalContent(oc)
+	if err != nil {
+		common.Log.Debug("Invalid optional content - assuming visible: %v", err)
+		return true
+	}
+	if config == nil {
+		return ocProperties.IsContentVisible(content)
+	}
+	return content.IsVisible(config.IsVisible)
+}
+
+// markedContentOC returns the optional content referenced by the BDC operation `op` or nil if
+// the marked content is not optional content.
+func markedContentOC(op *ContentStreamOperation, resources *model.PdfPageResources) core.PdfObject {
+	if len(op.Params) != 2 {
+		return nil
+	}
+	if tag, ok := core.GetNameVal(op.Params[0]); !ok || tag != "OC" {
+		return nil
+	}
+	if name, ok := core.GetName(op.Params[1]); ok {
+		if resources == nil {
+			return nil
+		}
+		obj, _ := resources.GetPropertiesByName(*name)
+		return obj
+	}
+	return op.Params[1]
+}
+
+// handleMarkedContent tracks the visibility of marked content sequences (BMC, BDC, EMC).
+func (proc *ContentStreamProcessor) handleMarkedContent(op *ContentStreamOperation, resources *model.PdfPageResources) {
+	switch op.Operand {
+	case "BMC":
+		proc.markedContent = append(proc.markedContent, true)
+	case "BDC":
+		visible := true
+		if proc.ocProperties != nil {
+			visible = proc.IsOptionalContentVisible(markedContentOC(op, resources))
+		}
+		proc.markedContent = append(proc.markedContent, visible)
+		if !visible {
+			proc.numHidden++
+		}
+	case "EMC":
+		n := len(proc.markedContent)
+		if n == 0 {
+			common.Log.Debug("EMC without marked content sequence - ignoring")
+			return
+		}
+		if !proc.markedContent[n-1] {
+			proc.numHidden--
+		}
+		proc.markedContent = proc.markedContent[:n-1]
+	}
+}
+
 // Process processes the entire list of operations. Maintains the graphics state that is passed to any
 // handlers that are triggered during processing (either on specific operators or all).
 func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) error {
@@ -221,6 +331,8 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 	proc.graphicsState.ColorStroking = model.NewPdfColorDeviceGray(0)
 	proc.graphicsState.ColorNonStroking = model.NewPdfColorDeviceGray(0)
 	proc.graphicsState.CTM = transform.IdentityMatrix()
+	proc.markedContent = nil
+	proc.numHidden = 0
 
 	for _, op := range proc.operations {
 		var err error
@@ -230,6 +342,10 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 		case "q":
 			proc.graphicsStack.Push(proc.graphicsState)
 		case "Q":
+			if len(proc.graphicsStack) == 0 {
+				common.Log.Debug("WARN: Invalid Q operator - graphics state stack is empty. Skipping")
+				break
+			}
 			proc.graphicsState = proc.graphicsStack.Pop()
 
 		// Color operations (Table 74 p. 179)
@@ -259,6 +375,10 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 			err = proc.handleCommand_k(op, resources)
 		case "cm":
 			err = proc.handleCommand_cm(op, resources)
+
+		// Marked content (section 14.6 p. 550).
+		case "BMC", "BDC":
+			proc.handleMarkedContent(op, resources)
 		}
 		if err != nil {
 			common.Log.Debug("Processor handling error (%s): %v", op.Operand, err)
@@ -279,6 +399,12 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 				return err
 			}
 		}
+
+		// Leave marked content after the handlers are called, so that the EMC operation
+		// has the same visibility as the sequence it closes.
+		if op.Operand == "EMC" {
+			proc.handleMarkedContent(op, resources)
+		}
 	}
 
 	return nil
@@ -0,0 +1,54 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package contentstream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestProcessorInitialColor(t *testing.T) {
+	// Separation with a tint transform to DeviceCMYK and Indexed with 2 RGB colors.
+	tintTransform := core.MakeDict()
+	tintTransform.Set("FunctionType", core.MakeInteger(2))
+	tintTransform.Set("Domain", core.MakeArrayFromFloats([]float64{0, 1}))
+	tintTransform.Set("C0", core.MakeArrayFromFloats([]float64{0, 0, 0, 0}))
+	tintTransform.Set("C1", core.MakeArrayFromFloats([]float64{0, 1, 0, 0}))
+	tintTransform.Set("N", core.MakeFloat(1))
+	separation, err := model.NewPdfColorspaceFromPdfObject(core.MakeArray(core.MakeName("Separation"),
+		core.MakeName("Spot"), core.MakeName("DeviceCMYK"), tintTransform))
+	require.NoError(t, err)
+	indexed, err := model.NewPdfColorspaceFromPdfObject(core.MakeArray(core.MakeName("Indexed"),
+		core.MakeName("DeviceRGB"), core.MakeInteger(1), core.MakeString("\xff\x00\x00\x00\x00\xff")))
+	require.NoError(t, err)
+
+	resources := model.NewPdfPageResources()
+	require.NoError(t, resources.SetColorspaceByName("CS0", separation))
+	require.NoError(t, resources.SetColorspaceByName("CS1", indexed))
+
+	ops, err := NewContentStreamParser("/CS0 cs /CS1 CS 0 0 1 1 re f").Parse()
+	require.NoError(t, err)
+	proc := NewContentStreamProcessor(*ops)
+	var fill, stroke model.PdfColor
+	proc.AddHandler(HandlerConditionEnumOperand, "f",
+		func(op *ContentStreamOperation, gs GraphicsState, resources *model.PdfPageResources) error {
+			fill, stroke = gs.ColorNonStroking, gs.ColorStroking
+			return nil
+		})
+	require.NoError(t, proc.Process(resources))
+
+	// The initial tint of the Separation colorspace is 1.0 and the initial index is 0.
+	cmyk, ok := fill.(*model.PdfColorDeviceCMYK)
+	require.True(t, ok)
+	require.Equal(t, []float64{0, 1, 0, 0}, []float64{cmyk.C(), cmyk.M(), cmyk.Y(), cmyk.K()})
+	rgb, ok := stroke.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, []float64{1, 0, 0}, []float64{rgb.R(), rgb.G(), rgb.B()})
+}
@@ -195,6 +195,17 @@ func MakeEncodedString(s string, utf16BE bool) *PdfObjectString {
 	return &PdfObjectString{val: string(strutils.StringToPDFDocEncoding(s)), isHex: false}
 }
 
+// MakeTextString creates a PdfObjectString for text string `s`, encoded with PDFDocEncoding if
+// `s` is plain ASCII and as UTF-16BE otherwise.
+func MakeTextString(s string) *PdfObjectString {
+	for _, r := range s {
+		if r > 0x7f {
+			return MakeEncodedString(s, true)
+		}
+	}
+	return MakeString(s)
+}
+
 // MakeNull creates an PdfObjectNull.
 func MakeNull() *PdfObjectNull {
 	null := PdfObjectNull{}
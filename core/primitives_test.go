@@ -115,6 +115,17 @@ func TestUTF16StringEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestMakeTextString(t *testing.T) {
+	// Plain ASCII text is kept as is, other text is encoded as UTF-16BE.
+	if str := MakeTextString("Title"); str.Str() != "Title" || str.Decoded() != "Title" {
+		t.Fatalf("ASCII text string: %q", str.Str())
+	}
+	str := MakeTextString("Título")
+	if !strings.HasPrefix(str.Str(), "\xfe\xff") || str.Decoded() != "Título" {
+		t.Fatalf("UTF-16BE text string: % X", str.Str())
+	}
+}
+
 func BenchmarkPdfObjectIntegerWriteString(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		i := MakeInteger(int64(n))
@@ -44,6 +44,9 @@ type Block struct {
 
 	// Block annotations.
 	annotations []*model.PdfAnnotation
+
+	// Optional content (layer) controlling the visibility of the block.
+	oc model.PdfOptionalContent
 }
 
 // NewBlock creates a new Block with specified width and height.
@@ -123,6 +126,19 @@ func (blk *Block) AddAnnotation(annotation *model.PdfAnnotation) {
 	blk.annotations = append(blk.annotations, annotation)
 }
 
+// SetOptionalContent assigns the block to an optional content group (layer) or membership
+// dictionary. The visibility of the block contents is then controlled by `oc` when
+// the block is drawn. Pass nil to make the contents always visible.
+func (blk *Block) SetOptionalContent(oc model.PdfOptionalContent) {
+	blk.oc = oc
+}
+
+// OptionalContent returns the optional content the block is assigned to, or nil if the
+// block contents are always visible.
+func (blk *Block) OptionalContent() model.PdfOptionalContent {
+	return blk.oc
+}
+
 // duplicate duplicates the block with a new copy of the operations list.
 func (blk *Block) duplicate() *Block {
 	dup := &Block{}
@@ -174,9 +190,37 @@ func (blk *Block) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, er
 	contents.WrapIfNeeded()
 	dup.contents = &contents
 
+	if blk.oc != nil {
+		if err := dup.wrapOptionalContent(blk.oc); err != nil {
+			return nil, ctx, err
+		}
+	}
+
 	return []*Block{dup}, ctx, nil
 }
 
+// wrapOptionalContent wraps the block contents in a marked content sequence tagged OC, which
+// makes the contents visible according to `oc`. The optional content is referenced through
+// the Properties resources of the block.
+func (blk *Block) wrapOptionalContent(oc model.PdfOptionalContent) error {
+	obj := oc.ToPdfObject()
+	name := resourcesNextUnusedPropertiesName(obj, blk.resources)
+	if err := blk.resources.SetPropertiesByName(name, obj); err != nil {
+		return err
+	}
+
+	ops := contentstream.ContentStreamOperations{}
+	ops = append(ops, &contentstream.ContentStreamOperation{
+		Operand: "BDC",
+		Params:  []core.PdfObject{core.MakeName("OC"), core.MakeName(string(name))},
+	})
+	ops = append(ops, *blk.contents...)
+	ops = append(ops, &contentstream.ContentStreamOperation{Operand: "EMC"})
+	blk.contents = &ops
+	blk.oc = nil
+	return nil
+}
+
 // Height returns the Block's height.
 func (blk *Block) Height() float64 {
 	return blk.height
@@ -408,6 +452,7 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 	patternMap := map[core.PdfObjectName]core.PdfObjectName{}
 	shadingMap := map[core.PdfObjectName]core.PdfObjectName{}
 	gstateMap := map[core.PdfObjectName]core.PdfObjectName{}
+	propertiesMap := map[core.PdfObjectName]core.PdfObjectName{}
 
 	for _, op := range *contentsToAdd {
 		switch op.Operand {
@@ -587,6 +632,29 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 					op.Params[0] = &useName
 				}
 			}
+		case "BDC", "DP":
+			// Property lists (e.g. optional content).
+			if len(op.Params) == 2 {
+				if name, ok := op.Params[1].(*core.PdfObjectName); ok {
+					if _, processed := propertiesMap[*name]; !processed {
+						useName := *name
+						// Process if not already processed.
+						obj, found := resourcesToAdd.GetPropertiesByName(*name)
+						if found {
+							if obj2, found := resources.GetPropertiesByName(useName); found && obj2 != obj {
+								useName = resourcesNextUnusedPropertiesName(obj, resources)
+							}
+							if err := resources.SetPropertiesByName(useName, obj); err != nil {
+								return err
+							}
+						}
+						propertiesMap[*name] = useName
+					}
+
+					useName := propertiesMap[*name]
+					op.Params[1] = &useName
+				}
+			}
 		}
 
 		*contents = append(*contents, op)
@@ -640,3 +708,24 @@ func resourcesNextUnusedFontName(name string, font core.PdfObject, resources *mo
 
 	return fontName
 }
+
+// resourcesNextUnusedPropertiesName returns the name under which the property list `obj` is
+// available in `resources`, i.e. the name of an existing entry for `obj` or an unused name.
+func resourcesNextUnusedPropertiesName(obj core.PdfObject, resources *model.PdfPageResources) core.PdfObjectName {
+	if dict, ok := core.GetDict(resources.Properties); ok {
+		for _, key := range dict.Keys() {
+			if dict.Get(key) == obj {
+				return key
+			}
+		}
+	}
+
+	num := 0
+	for {
+		name := core.PdfObjectName(fmt.Sprintf("OC%d", num))
+		if _, found := resources.GetPropertiesByName(name); !found {
+			return name
+		}
+		num++
+	}
+}
@@ -6,6 +6,7 @@
 package creator
 
 import (
+	"bytes"
 	"errors"
 	goimage "image"
 	"io"
@@ -59,9 +60,17 @@ type Creator struct {
 	// External outlines.
 	externalOutline *model.PdfOutlineTreeNode
 
+	// Page labels. Generated automatically for the front matter (front page and table of
+	// contents) unless set externally.
+	pageLabels         *model.PdfPageLabels
+	externalPageLabels *model.PdfPageLabels
+
 	// Forms.
 	acroForm *model.PdfAcroForm
 
+	// Optional content (layers).
+	ocProperties *model.PdfOCProperties
+
 	optimizer model.Optimizer
 
 	// Default fonts used by all components instantiated through the creator.
@@ -82,6 +91,38 @@ func (c *Creator) SetOutlineTree(outlineTree *model.PdfOutlineTreeNode) {
 	c.externalOutline = outlineTree
 }
 
+// SetPageLabels sets the page labels of the PDF file generated by the creator.
+// Setting page labels disables the automatic labeling of the front matter pages
+// done by the creator when a front page or table of contents is generated.
+func (c *Creator) SetPageLabels(labels *model.PdfPageLabels) {
+	c.externalPageLabels = labels
+}
+
+// SetOptionalContentProperties sets the optional content properties (layers) of the PDF file
+// generated by the creator.
+func (c *Creator) SetOptionalContentProperties(ocProperties *model.PdfOCProperties) {
+	c.ocProperties = ocProperties
+}
+
+// OptionalContentProperties returns the optional content properties (layers) of the PDF file
+// generated by the creator. They can be used to configure the presentation of the layers,
+// e.g. their order or radio button groups.
+func (c *Creator) OptionalContentProperties() *model.PdfOCProperties {
+	if c.ocProperties == nil {
+		c.ocProperties = model.NewPdfOCProperties()
+	}
+	return c.ocProperties
+}
+
+// AddLayer creates a new optional content group (layer) named `name`, which is initially
+// visible if `visible` is true. Content is assigned to the layer with DrawInLayer or
+// Block.SetOptionalContent.
+func (c *Creator) AddLayer(name string, visible bool) *model.PdfOCG {
+	ocg := model.NewPdfOCG(name)
+	c.OptionalContentProperties().AddOCG(ocg, visible)
+	return ocg
+}
+
 // FrontpageFunctionArgs holds the input arguments to a front page drawing function.
 // It is designed as a struct, so additional parameters can be added in the future with backwards
 // compatibility.
@@ -366,6 +407,7 @@ func (c *Creator) Finalize() error {
 	}
 
 	totPages := len(c.pages)
+	totPagesBody := totPages
 
 	// Estimate number of additional generated pages and update TOC.
 	genpages := 0
@@ -459,6 +501,25 @@ func (c *Creator) Finalize() error {
 		}
 	}
 
+	// Label the front matter pages with lowercase roman numerals. The labels of the
+	// remaining pages match the page numbers of the table of contents.
+	if frontMatter := len(c.pages) - totPagesBody; frontMatter > 0 && frontMatter < len(c.pages) {
+		c.pageLabels = model.NewPdfPageLabels()
+		err := c.pageLabels.Set(1, model.PdfPageLabel{Style: model.PageLabelStyleLowerRoman})
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to set the page labels: %v", err)
+			return err
+		}
+		err = c.pageLabels.Set(frontMatter+1, model.PdfPageLabel{
+			Style: model.PageLabelStyleDecimal,
+			Start: frontMatter + 1,
+		})
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to set the page labels: %v", err)
+			return err
+		}
+	}
+
 	// Account for the front page and the table of content pages.
 	if c.outline != nil && c.AddOutlines {
 		var adjustOutlineDest func(item *model.OutlineItem)
@@ -622,6 +683,34 @@ func (c *Creator) Draw(d Drawable) error {
 	return nil
 }
 
+// DrawInLayer draws the specified Drawable like Draw, with the generated contents assigned
+// to the optional content group (layer) or membership dictionary `oc`.
+func (c *Creator) DrawInLayer(d Drawable, oc model.PdfOptionalContent) error {
+	return c.Draw(&optionalContentDrawable{drawable: d, oc: oc})
+}
+
+// optionalContentDrawable wraps a Drawable, assigning the blocks it generates to optional content.
+type optionalContentDrawable struct {
+	drawable Drawable
+	oc       model.PdfOptionalContent
+}
+
+// GeneratePageBlocks generates the blocks of the wrapped drawable and wraps their contents
+// in optional content sequences.
+// Implements the Drawable interface.
+func (d *optionalContentDrawable) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	blocks, ctx, err := d.drawable.GeneratePageBlocks(ctx)
+	if err != nil {
+		return nil, ctx, err
+	}
+	for _, block := range blocks {
+		if err := block.wrapOptionalContent(d.oc); err != nil {
+			return nil, ctx, err
+		}
+	}
+	return blocks, ctx, nil
+}
+
 // Write output of creator to io.Writer interface.
 func (c *Creator) Write(ws io.Writer) error {
 	if err := c.Finalize(); err != nil {
@@ -647,6 +736,21 @@ func (c *Creator) Write(ws io.Writer) error {
 		pdfWriter.AddOutlineTree(&c.outline.ToPdfOutline().PdfOutlineTreeNode)
 	}
 
+	// Page labels.
+	if c.externalPageLabels != nil {
+		pdfWriter.SetPageLabels(c.externalPageLabels)
+	} else if c.pageLabels != nil {
+		pdfWriter.SetPageLabels(c.pageLabels)
+	}
+
+	// Optional content.
+	if c.ocProperties != nil {
+		if err := pdfWriter.SetOptionalContentProperties(c.ocProperties); err != nil {
+			common.Log.Debug("Failure: %v", err)
+			return err
+		}
+	}
+
 	// Pdf Writer access hook. Can be used to encrypt, etc. via the PdfWriter instance.
 	if c.pdfWriterAccessFunc != nil {
 		err := c.pdfWriterAccessFunc(&pdfWriter)
@@ -833,6 +937,23 @@ func (c *Creator) NewImageFromFile(path string) (*Image, error) {
 	return newImageFromFile(path)
 }
 
+// NewImagesFromTIFF creates Images from the pages of multi-page TIFF file data `data`. The image
+// XObjects are encoded when reading (see model.ReadTIFF): the encoders set with SetEncoder are
+// not used.
+func (c *Creator) NewImagesFromTIFF(data []byte) ([]*Image, error) {
+	return newImagesFromTIFF(bytes.NewReader(data))
+}
+
+// NewImagesFromTIFFFile creates Images from the pages of a multi-page TIFF file.
+func (c *Creator) NewImagesFromTIFFFile(path string) ([]*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return newImagesFromTIFF(f)
+}
+
 // NewImageFromGoImage creates an Image from a go image.Image data structure.
 func (c *Creator) NewImageFromGoImage(goimg goimage.Image) (*Image, error) {
 	return newImageFromGoImage(goimg)
@@ -3147,3 +3147,74 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+// Tests drawing contents in layers and toggling the default visibility of a layer.
+func TestCreatorLayers(t *testing.T) {
+	c := New()
+	dimensions := c.AddLayer("Dimensions", true)
+	notes := c.AddLayer("Notes", false)
+
+	p := c.NewParagraph("Drawn on the notes layer")
+	require.NoError(t, c.DrawInLayer(p, notes))
+
+	block := NewBlock(100, 100)
+	rect := c.NewRectangle(10, 10, 50, 50)
+	require.NoError(t, block.Draw(rect))
+	block.SetOptionalContent(dimensions)
+	block.SetPos(100, 200)
+	require.NoError(t, c.Draw(block))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	ocProps, err := reader.GetOptionalContentProperties()
+	require.NoError(t, err)
+	require.NotNil(t, ocProps)
+	require.Len(t, ocProps.OCGs, 2)
+	require.Len(t, ocProps.D.Order, 2)
+
+	loadedDimensions := ocProps.GetOCGByName("Dimensions")
+	loadedNotes := ocProps.GetOCGByName("Notes")
+	require.NotNil(t, loadedDimensions)
+	require.NotNil(t, loadedNotes)
+	require.True(t, ocProps.IsVisible(loadedDimensions))
+	require.False(t, ocProps.IsVisible(loadedNotes))
+
+	// Check the page contents reference both layers.
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(contents, "/OC /"))
+	require.Equal(t, 2, strings.Count(contents, "EMC"))
+
+	props, ok := core.GetDict(page.Resources.Properties)
+	require.True(t, ok)
+	var layers []string
+	for _, key := range props.Keys() {
+		oc, err := ocProps.GetOptionalContent(props.Get(key))
+		require.NoError(t, err)
+		ocg, ok := oc.(*model.PdfOCG)
+		require.True(t, ok)
+		layers = append(layers, ocg.Name)
+	}
+	require.ElementsMatch(t, []string{"Dimensions", "Notes"}, layers)
+
+	// Make the notes visible by default in an incremental update.
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	ocProps.SetVisible(loadedNotes, true)
+	appender.SetOptionalContentProperties(ocProps)
+
+	var out bytes.Buffer
+	require.NoError(t, appender.Write(&out))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	ocProps, err = reader.GetOptionalContentProperties()
+	require.NoError(t, err)
+	require.True(t, ocProps.IsVisible(ocProps.GetOCGByName("Notes")))
+	require.True(t, ocProps.IsVisible(ocProps.GetOCGByName("Dimensions")))
+}
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"fmt"
 	goimage "image"
+	"io"
 	"os"
 
 	"github.com/unidoc/unipdf/v3/common"
@@ -115,6 +116,40 @@ func newImageFromGoImage(goimg goimage.Image) (*Image, error) {
 	return newImage(img)
 }
 
+// newImagesFromTIFF creates Images from the pages of the TIFF file read from `r`. The images are
+// sized according to the resolution of the pages, 72 pixels per inch if not specified.
+func newImagesFromTIFF(r io.Reader) ([]*Image, error) {
+	pages, err := model.ReadTIFF(r)
+	if err != nil {
+		common.Log.Error("Error loading TIFF image: %s", err)
+		return nil, err
+	}
+
+	images := make([]*Image, 0, len(pages))
+	for _, page := range pages {
+		width := float64(*page.Image.Width)
+		if page.XResolution > 0 {
+			width *= 72 / page.XResolution
+		}
+		height := float64(*page.Image.Height)
+		if page.YResolution > 0 {
+			height *= 72 / page.YResolution
+		}
+
+		images = append(images, &Image{
+			xobj:        page.Image,
+			origWidth:   width,
+			origHeight:  height,
+			width:       width,
+			height:      height,
+			angle:       0,
+			opacity:     1.0,
+			positioning: positionRelative,
+		})
+	}
+	return images, nil
+}
+
 // SetEncoder sets the encoding/compression mechanism for the image.
 func (img *Image) SetEncoder(encoder core.StreamEncoder) {
 	img.encoder = encoder
@@ -5,6 +5,8 @@
 package creator
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/unidoc/unipdf/v3/model"
@@ -107,3 +109,42 @@ func TestTOCAdvanced(t *testing.T) {
 		t.Fatalf("Fail: %v\n", err)
 	}
 }
+
+func TestTOCPageLabels(t *testing.T) {
+	c := New()
+	c.AddTOC = true
+	c.CreateFrontPage(func(args FrontpageFunctionArgs) {
+		c.Draw(c.NewParagraph("Front page"))
+	})
+
+	for i := 0; i < 3; i++ {
+		ch := c.NewChapter(fmt.Sprintf("Chapter %d", i+1))
+		ch.Add(c.NewParagraph("Content"))
+		if err := c.Draw(ch); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		c.NewPage()
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Front page and one TOC page.
+	expected := []string{"i", "ii", "3", "4"}
+	for i, exp := range expected {
+		label, err := reader.GetPageLabel(i + 1)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if label != exp {
+			t.Fatalf("page %d: expected label %q, got %q", i+1, exp, label)
+		}
+	}
+}
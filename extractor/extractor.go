@@ -6,6 +6,8 @@
 package extractor
 
 import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
 	"github.com/unidoc/unipdf/v3/model"
 )
 
@@ -28,6 +30,10 @@ type Extractor struct {
 
 	// textCount is an incrementing number used to identify XYTest objects.
 	textCount int64
+
+	// Optional content properties and configuration used to skip hidden content.
+	ocProperties *model.PdfOCProperties
+	ocConfig     *model.PdfOCConfig
 }
 
 // New returns an Extractor instance for extracting content from the input PDF page.
@@ -42,11 +48,38 @@ func New(page *model.PdfPage) (*Extractor, error) {
 	// fmt.Printf("%s\n", contents)
 	// fmt.Println("========================= ::: =========================")
 
+	// Content hidden in the default optional content configuration is not extracted.
+	ocProperties, err := page.GetOptionalContentProperties()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load optional content properties - ignoring: %v", err)
+		ocProperties = nil
+	}
+
 	e := &Extractor{
-		contents:    contents,
-		resources:   page.Resources,
-		fontCache:   map[string]fontEntry{},
-		formResults: map[string]textResult{},
+		contents:     contents,
+		resources:    page.Resources,
+		fontCache:    map[string]fontEntry{},
+		formResults:  map[string]textResult{},
+		ocProperties: ocProperties,
 	}
 	return e, nil
 }
+
+// SetOptionalContent sets the optional content properties and configuration which determine
+// the visibility of the page contents. Content hidden by optional content (layers) is not
+// extracted. If `config` is nil, the default configuration of `ocProperties` is used.
+// By default, the optional content properties of the document containing the page are used.
+// Pass nil `ocProperties` to extract all content regardless of its visibility.
+func (e *Extractor) SetOptionalContent(ocProperties *model.PdfOCProperties, config *model.PdfOCConfig) {
+	e.ocProperties = ocProperties
+	e.ocConfig = config
+	e.formResults = map[string]textResult{}
+}
+
+// newProcessor returns a content stream processor for `ops`, which evaluates the
+// optional content visibility.
+func (e *Extractor) newProcessor(ops contentstream.ContentStreamOperations) *contentstream.ContentStreamProcessor {
+	processor := contentstream.NewContentStreamProcessor(ops)
+	processor.SetOptionalContent(e.ocProperties, e.ocConfig)
+	return processor
+}
@@ -6,9 +6,12 @@
 package extractor
 
 import (
+	goimage "image"
+
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/contentstream"
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 )
 
@@ -16,6 +19,21 @@ import (
 // PDF pages.
 type ImageExtractOptions struct {
 	IncludeInlineStencilMasks bool
+
+	// Composite sets the Composited images of the image marks: the images with their decode
+	// arrays applied, converted to RGB and composited with their soft masks (SMask), stencil
+	// masks and color key masks (Mask) as the alpha channel.
+	Composite bool
+
+	// RawData sets the RawData of the image marks of the image XObjects encoded with the
+	// DCTDecode, JPXDecode or JBIG2Decode filters, so that the images can be saved without
+	// recompression.
+	RawData bool
+
+	// ColorConversion sets the conversion of the colors of the images to RGB, e.g. with the ICC
+	// profile of DeviceCMYK colors of the output intent of the document. The DeviceCMYK colors
+	// are converted with the formula of the PDF reference if nil.
+	ColorConversion *model.ColorConversion
 }
 
 // ExtractPageImages returns the image contents of the page extractor, including data
@@ -25,7 +43,8 @@ type ImageExtractOptions struct {
 // are not extracted.
 func (e *Extractor) ExtractPageImages(options *ImageExtractOptions) (*PageImages, error) {
 	ctx := &imageExtractContext{
-		options: options,
+		options:   options,
+		extractor: e,
 	}
 
 	err := ctx.extractContentStreamImages(e.contents, e.resources)
@@ -46,9 +65,24 @@ type PageImages struct {
 
 // ImageMark represents an image drawn on a page and its position in device coordinates.
 // All coordinates are in device coordinates.
+// The stencil masks (ImageMask) are extracted as RGB images painted with the current fill color
+// on a white background.
 type ImageMark struct {
 	Image *model.Image
 
+	// Composited is the image composited with its masks, set with the Composite option. The
+	// stencil masks are painted with the current fill color on a transparent background.
+	Composited *goimage.NRGBA
+
+	// RawData is the data of the image encoded with the RawFilter filter (DCTDecode, JPXDecode
+	// or JBIG2Decode), set with the RawData option. The JBIG2 global segments (JBIG2Globals)
+	// are prepended to the JBIG2 data. If the image cannot be decoded, only the raw data is set.
+	RawData   []byte
+	RawFilter string
+
+	// CTM is the placement of the image: it maps the unit square of the image to the page.
+	CTM transform.Matrix
+
 	// Dimensions of the image as displayed in the PDF.
 	Width  float64
 	Height float64
@@ -73,11 +107,19 @@ type imageExtractContext struct {
 
 	// Extract options.
 	options *ImageExtractOptions
+
+	extractor *Extractor
 }
 
 type cachedImage struct {
 	image *model.Image
 	cs    model.PdfColorspace
+
+	// data is the image data to composite, for stencil masks and with the Composite option.
+	data *imageData
+
+	rawData   []byte
+	rawFilter string
 }
 
 func (ctx *imageExtractContext) extractContentStreamImages(contents string, resources *model.PdfPageResources) error {
@@ -94,9 +136,14 @@ func (ctx *imageExtractContext) extractContentStreamImages(contents string, reso
 		ctx.options = &ImageExtractOptions{}
 	}
 
-	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor := ctx.extractor.newProcessor(*operations)
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+			if !isOperationVisible(processor, op, resources) {
+				// Skip content hidden by optional content.
+				return nil
+			}
+			gs.ColorspaceNonStroking = ctx.options.ColorConversion.Colorspace(gs.ColorspaceNonStroking)
 			return ctx.processOperand(op, gs, resources)
 		})
 
@@ -139,32 +186,58 @@ func (ctx *imageExtractContext) processOperand(op *contentstream.ContentStreamOp
 }
 
 func (ctx *imageExtractContext) extractInlineImage(iimg *contentstream.ContentStreamInlineImage, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
-	img, err := iimg.ToImage(resources)
+	isMask, err := iimg.IsMask()
 	if err != nil {
 		return err
 	}
 
-	cs, err := iimg.GetColorSpace(resources)
-	if err != nil {
-		return err
-	}
-	if cs == nil {
-		// Default if not specified?
-		cs = model.NewPdfColorspaceDeviceGray()
+	var data *imageData
+	if isMask || ctx.options.Composite {
+		data, err = newInlineImageData(iimg, resources)
+		if err != nil {
+			return err
+		}
+		data.cs = ctx.options.ColorConversion.Colorspace(data.cs)
 	}
 
-	rgbImg, err := cs.ImageToRGB(*img)
-	if err != nil {
-		return err
-	}
+	imgMark := newImageMark(gs)
+	if isMask {
+		composited, err := data.composite(fillColor(gs))
+		if err != nil {
+			return err
+		}
+		imgMark.Image = stencilImage(composited)
+		if ctx.options.Composite {
+			imgMark.Composited = composited
+		}
+	} else {
+		img, err := iimg.ToImage(resources)
+		if err != nil {
+			return err
+		}
 
-	imgMark := ImageMark{
-		Image:  &rgbImg,
-		Width:  gs.CTM.ScalingFactorX(),
-		Height: gs.CTM.ScalingFactorY(),
-		Angle:  gs.CTM.Angle(),
+		cs, err := iimg.GetColorSpace(resources)
+		if err != nil {
+			return err
+		}
+		if cs == nil {
+			// Default if not specified?
+			cs = model.NewPdfColorspaceDeviceGray()
+		}
+		cs = ctx.options.ColorConversion.Colorspace(cs)
+
+		rgbImg, err := cs.ImageToRGB(*img)
+		if err != nil {
+			return err
+		}
+		imgMark.Image = &rgbImg
+		if ctx.options.Composite {
+			imgMark.Composited, err = data.composite(fillColor(gs))
+			if err != nil {
+				return err
+			}
+		}
 	}
-	imgMark.X, imgMark.Y = gs.CTM.Translation()
 
 	ctx.extractedImages = append(ctx.extractedImages, imgMark)
 	ctx.inlineImages++
@@ -180,45 +253,100 @@ func (ctx *imageExtractContext) extractXObjectImage(name *core.PdfObjectName, gs
 	// Cache on stream pointer so can ensure that it is the same object (better than using name).
 	cimg, cached := ctx.cacheXObjectImages[stream]
 	if !cached {
-		ximg, err := resources.GetXObjectImageByName(*name)
+		var err error
+		cimg, err = ctx.loadXObjectImage(stream, name, resources)
 		if err != nil {
 			return err
 		}
-		if ximg == nil {
+		if cimg == nil {
 			return nil
 		}
+		ctx.cacheXObjectImages[stream] = cimg
+	}
 
-		img, err := ximg.ToImage()
+	common.Log.Debug("@Do CTM: %s", gs.CTM.String())
+	imgMark := newImageMark(gs)
+	imgMark.RawData, imgMark.RawFilter = cimg.rawData, cimg.rawFilter
+	switch {
+	case cimg.data != nil && cimg.data.isMask:
+		composited, err := cimg.data.composite(fillColor(gs))
 		if err != nil {
 			return err
 		}
+		imgMark.Image = stencilImage(composited)
+		if ctx.options.Composite {
+			imgMark.Composited = composited
+		}
+	case cimg.image != nil:
+		rgbImg, err := cimg.cs.ImageToRGB(*cimg.image)
+		if err != nil {
+			return err
+		}
+		imgMark.Image = &rgbImg
+		if ctx.options.Composite {
+			imgMark.Composited, err = cimg.data.composite(fillColor(gs))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx.extractedImages = append(ctx.extractedImages, imgMark)
+	ctx.xObjectImages++
+	return nil
+}
 
-		cimg = &cachedImage{
-			image: img,
-			cs:    ximg.ColorSpace,
+// loadXObjectImage decodes the image XObject `stream` named `name` in `resources`. Returns nil
+// if it is not an image.
+func (ctx *imageExtractContext) loadXObjectImage(stream *core.PdfObjectStream, name *core.PdfObjectName,
+	resources *model.PdfPageResources) (*cachedImage, error) {
+	cimg := &cachedImage{}
+	if ctx.options.RawData {
+		var err error
+		cimg.rawData, cimg.rawFilter, err = rawImageData(stream)
+		if err != nil {
+			return nil, err
 		}
-		ctx.cacheXObjectImages[stream] = cimg
 	}
-	img := cimg.image
-	cs := cimg.cs
 
-	rgbImg, err := cs.ImageToRGB(*img)
+	ximg, err := resources.GetXObjectImageByName(*name)
+	if err == nil && ximg == nil {
+		return nil, nil
+	}
+	if err == nil {
+		cimg.cs = ctx.options.ColorConversion.Colorspace(ximg.ColorSpace)
+		if isMask, _ := core.GetBoolVal(ximg.ImageMask); isMask {
+			cimg.data, err = newXObjectImageData(ximg, nil)
+		} else {
+			cimg.image, err = ximg.ToImage()
+			if err == nil && ctx.options.Composite {
+				cimg.data, err = newXObjectImageData(ximg, cimg.image.Data)
+			}
+		}
+		if err == nil && cimg.data != nil {
+			cimg.data.cs = ctx.options.ColorConversion.Colorspace(cimg.data.cs)
+		}
+	}
 	if err != nil {
-		return err
+		if cimg.rawData == nil {
+			return nil, err
+		}
+		common.Log.Debug("ERROR: Unable to decode image: %v - only raw data extracted", err)
+		cimg.image, cimg.data = nil, nil
 	}
+	return cimg, nil
+}
 
-	common.Log.Debug("@Do CTM: %s", gs.CTM.String())
+// newImageMark returns the image mark of an image drawn with graphics state `gs`.
+func newImageMark(gs contentstream.GraphicsState) ImageMark {
 	imgMark := ImageMark{
-		Image:  &rgbImg,
 		Width:  gs.CTM.ScalingFactorX(),
 		Height: gs.CTM.ScalingFactorY(),
 		Angle:  gs.CTM.Angle(),
+		CTM:    gs.CTM,
 	}
 	imgMark.X, imgMark.Y = gs.CTM.Translation()
-
-	ctx.extractedImages = append(ctx.extractedImages, imgMark)
-	ctx.xObjectImages++
-	return nil
+	return imgMark
 }
 
 // Go through the XObject Form content stream (recursive processing).
@@ -0,0 +1,443 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"errors"
+	goimage "image"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/sampling"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// imageData is the decoded data of an image with the entries of its dictionary needed to
+// composite it.
+type imageData struct {
+	width, height, bpc int
+	cs                 model.PdfColorspace // nil for stencil masks.
+	data               []byte
+	decode             []float64
+
+	isMask bool
+	mask   core.PdfObject // Stencil mask stream or color key mask ranges.
+	smask  core.PdfObject // Soft mask stream.
+	matte  []float64      // Matte color of the image if it is a soft mask.
+}
+
+// newXObjectImageData returns the image data of image XObject `ximg` with decoded data `data`,
+// decoding the stream of `ximg` if `data` is nil.
+func newXObjectImageData(ximg *model.XObjectImage, data []byte) (*imageData, error) {
+	if ximg.Width == nil || ximg.Height == nil {
+		return nil, errors.New("image size missing")
+	}
+	img := &imageData{
+		width:  int(*ximg.Width),
+		height: int(*ximg.Height),
+		cs:     ximg.ColorSpace,
+		data:   data,
+		mask:   ximg.Mask,
+		smask:  ximg.SMask,
+	}
+	img.isMask, _ = core.GetBoolVal(ximg.ImageMask)
+	if ximg.BitsPerComponent != nil {
+		img.bpc = int(*ximg.BitsPerComponent)
+	}
+	if img.isMask {
+		// The samples of stencil masks are 1 bit values.
+		img.bpc = 1
+		img.cs = nil
+		img.mask, img.smask = nil, nil
+	}
+	if arr, ok := core.GetArray(ximg.Decode); ok {
+		img.decode, _ = arr.ToFloat64Array()
+	}
+	if arr, ok := core.GetArray(ximg.Matte); ok {
+		img.matte, _ = arr.ToFloat64Array()
+	}
+	if img.data == nil {
+		stream, ok := core.GetStream(ximg.GetContainingPdfObject())
+		if !ok {
+			return nil, errors.New("image stream missing")
+		}
+		decoded, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		img.data = decoded
+	}
+	return img, nil
+}
+
+// newInlineImageData returns the image data of inline image `iimg` drawn with resources
+// `resources`.
+func newInlineImageData(iimg *contentstream.ContentStreamInlineImage, resources *model.PdfPageResources) (
+	*imageData, error) {
+	decoded, err := iimg.ToImage(resources)
+	if err != nil {
+		return nil, err
+	}
+	img := &imageData{
+		width:  int(decoded.Width),
+		height: int(decoded.Height),
+		bpc:    int(decoded.BitsPerComponent),
+		data:   decoded.Data,
+	}
+	img.isMask, err = iimg.IsMask()
+	if err != nil {
+		return nil, err
+	}
+	if !img.isMask {
+		img.cs, err = iimg.GetColorSpace(resources)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if arr, ok := core.GetArray(iimg.Decode); ok {
+		img.decode, _ = arr.ToFloat64Array()
+	}
+	return img, nil
+}
+
+// numComponents returns the number of color components of the samples of the image.
+func (img *imageData) numComponents() int {
+	if img.cs == nil {
+		return 1
+	}
+	return img.cs.GetNumComponents()
+}
+
+// rows calls `fn` with the samples of each row of the image.
+func (img *imageData) rows(fn func(y int, samples []uint32) error) error {
+	n := img.numComponents()
+	if img.width <= 0 || img.height <= 0 || img.bpc < 1 || img.bpc > 16 {
+		return errors.New("invalid image")
+	}
+	rowBytes := (img.width*n*img.bpc + 7) / 8
+	if len(img.data) < rowBytes*img.height {
+		return errors.New("image data too short")
+	}
+	for y := 0; y < img.height; y++ {
+		samples := sampling.ResampleBytes(img.data[y*rowBytes:(y+1)*rowBytes], img.bpc)
+		if err := fn(y, samples[:img.width*n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeArray returns the decode array of the image, or the default one of its colorspace.
+func (img *imageData) decodeArray() []float64 {
+	n := img.numComponents()
+	if len(img.decode) == 2*n {
+		return img.decode
+	}
+	switch img.cs.(type) {
+	case nil:
+		return []float64{0, 1}
+	case *model.PdfColorspaceSpecialIndexed:
+		return []float64{0, float64(uint32(1)<<uint(img.bpc) - 1)}
+	}
+	return img.cs.DecodeArray()
+}
+
+// alphaPlane returns the opacity of the pixels of the image used as a stencil mask (painted
+// pixels are opaque) or as a soft mask (the gray levels are the opacity).
+func (img *imageData) alphaPlane() ([]byte, error) {
+	if !img.isMask && img.numComponents() != 1 {
+		return nil, errors.New("invalid mask")
+	}
+	decode := img.decodeArray()
+	maxVal := float64(uint32(1)<<uint(img.bpc) - 1)
+	alpha := make([]byte, 0, img.width*img.height)
+	err := img.rows(func(y int, samples []uint32) error {
+		for _, s := range samples {
+			v := decode[0] + float64(s)*(decode[1]-decode[0])/maxVal
+			switch {
+			case img.isMask && v < 0.5:
+				// The samples decoded to 0 of stencil masks are painted.
+				alpha = append(alpha, 0xff)
+			case img.isMask:
+				alpha = append(alpha, 0)
+			default:
+				alpha = append(alpha, toByte(v))
+			}
+		}
+		return nil
+	})
+	return alpha, err
+}
+
+// composite returns the image composited with its masks, the stencil masks being painted with
+// color `fill`.
+func (img *imageData) composite(fill [3]byte) (*goimage.NRGBA, error) {
+	out := goimage.NewNRGBA(goimage.Rect(0, 0, img.width, img.height))
+	if img.isMask {
+		alpha, err := img.alphaPlane()
+		if err != nil {
+			return nil, err
+		}
+		for i, a := range alpha {
+			copy(out.Pix[4*i:], fill[:])
+			out.Pix[4*i+3] = a
+		}
+		return out, nil
+	}
+
+	// The opacity from the stencil or soft mask, scaled to the image size.
+	alpha, matte, err := img.maskAlpha()
+	if err != nil {
+		return nil, err
+	}
+	var colorKey []int
+	if arr, ok := core.GetArray(img.mask); ok {
+		colorKey, _ = arr.ToIntegerArray()
+	}
+
+	n := img.numComponents()
+	decode := img.decodeArray()
+	if len(decode) != 2*n {
+		return nil, errors.New("invalid decode array")
+	}
+	if len(matte) != n {
+		matte = nil
+	}
+	maxVal := float64(uint32(1)<<uint(img.bpc) - 1)
+
+	cache := map[uint64][3]byte{}
+	vals := make([]float64, n)
+	err = img.rows(func(y int, samples []uint32) error {
+		for x := 0; x < img.width; x++ {
+			i := y*img.width + x
+			pixel := samples[x*n : (x+1)*n]
+			a := byte(0xff)
+			if alpha != nil {
+				a = alpha[i]
+			}
+			if len(colorKey) == 2*n && inColorKeyRanges(pixel, colorKey) {
+				a = 0
+			}
+			out.Pix[4*i+3] = a
+
+			key, cacheable := sampling.ColorKey(pixel)
+			cacheable = cacheable && matte == nil
+			if rgb, ok := cache[key]; ok && cacheable {
+				copy(out.Pix[4*i:], rgb[:])
+				continue
+			}
+			for j, s := range pixel {
+				vals[j] = decode[2*j] + float64(s)*(decode[2*j+1]-decode[2*j])/maxVal
+				// The colors pre-blended with the matte color are restored.
+				if matte != nil && a > 0 {
+					v := matte[j] + (vals[j]-matte[j])*255/float64(a)
+					lo, hi := math.Min(decode[2*j], decode[2*j+1]), math.Max(decode[2*j], decode[2*j+1])
+					vals[j] = math.Min(math.Max(v, lo), hi)
+				}
+			}
+			rgb, err := colorToRGBBytes(img.cs, vals)
+			if err != nil {
+				return err
+			}
+			if cacheable && len(cache) < sampling.MaxColorCacheSize {
+				cache[key] = rgb
+			}
+			copy(out.Pix[4*i:], rgb[:])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// maskAlpha returns the opacity of the pixels of the image from its soft mask or stencil mask,
+// or nil if it has none, and the matte color of the soft mask.
+func (img *imageData) maskAlpha() ([]byte, []float64, error) {
+	var obj core.PdfObject
+	if stream, ok := core.GetStream(img.smask); ok {
+		obj = stream
+	} else if stream, ok := core.GetStream(img.mask); ok {
+		obj = stream
+	} else {
+		return nil, nil, nil
+	}
+
+	ximg, err := model.NewXObjectImageFromStream(obj.(*core.PdfObjectStream))
+	if err != nil {
+		return nil, nil, err
+	}
+	mask, err := newXObjectImageData(ximg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if core.ResolveReference(img.mask) == obj {
+		// Stencil masks may omit the ImageMask entry in Mask entries.
+		mask.isMask, mask.bpc, mask.cs = true, 1, nil
+	} else {
+		mask.cs = model.NewPdfColorspaceDeviceGray()
+	}
+	plane, err := mask.alphaPlane()
+	if err != nil {
+		return nil, nil, err
+	}
+	if mask.width == img.width && mask.height == img.height {
+		return plane, mask.matte, nil
+	}
+
+	// The masks can have a different resolution than the image.
+	alpha := make([]byte, img.width*img.height)
+	for y := 0; y < img.height; y++ {
+		my := y * mask.height / img.height
+		for x := 0; x < img.width; x++ {
+			mx := x * mask.width / img.width
+			alpha[y*img.width+x] = plane[my*mask.width+mx]
+		}
+	}
+	// The matte color only applies to soft masks of the image size.
+	return alpha, nil, nil
+}
+
+// inColorKeyRanges returns true if all the samples of `pixel` are in the ranges of color key
+// mask `ranges`.
+func inColorKeyRanges(pixel []uint32, ranges []int) bool {
+	for i, s := range pixel {
+		if int(s) < ranges[2*i] || int(s) > ranges[2*i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// colorToRGBBytes returns the 8 bit RGB components of the color of colorspace `cs` with
+// components `vals`.
+func colorToRGBBytes(cs model.PdfColorspace, vals []float64) ([3]byte, error) {
+	var rgb [3]byte
+	color, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		return rgb, err
+	}
+	converted, err := cs.ColorToRGB(color)
+	if err != nil {
+		return rgb, err
+	}
+	c, ok := converted.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return rgb, errors.New("invalid RGB color")
+	}
+	return [3]byte{toByte(c.R()), toByte(c.G()), toByte(c.B())}, nil
+}
+
+// fillColor returns the 8 bit RGB components of the nonstroking color of `gs`, black if it is
+// not a plain color (e.g. a pattern).
+func fillColor(gs contentstream.GraphicsState) [3]byte {
+	if gs.ColorspaceNonStroking == nil || gs.ColorNonStroking == nil {
+		return [3]byte{}
+	}
+	converted, err := gs.ColorspaceNonStroking.ColorToRGB(gs.ColorNonStroking)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to convert fill color: %v - using black", err)
+		return [3]byte{}
+	}
+	c, ok := converted.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return [3]byte{}
+	}
+	return [3]byte{toByte(c.R()), toByte(c.G()), toByte(c.B())}
+}
+
+// stencilImage returns composited stencil mask `img` as an RGB image, with its unpainted pixels
+// in white.
+func stencilImage(img *goimage.NRGBA) *model.Image {
+	numPixels := len(img.Pix) / 4
+	data := make([]byte, 3*numPixels)
+	for i := 0; i < numPixels; i++ {
+		if img.Pix[4*i+3] == 0 {
+			data[3*i], data[3*i+1], data[3*i+2] = 0xff, 0xff, 0xff
+			continue
+		}
+		copy(data[3*i:3*i+3], img.Pix[4*i:4*i+3])
+	}
+	b := img.Bounds()
+	return &model.Image{
+		Width:            int64(b.Dx()),
+		Height:           int64(b.Dy()),
+		BitsPerComponent: 8,
+		ColorComponents:  3,
+		Data:             data,
+	}
+}
+
+// rawImageData returns the data of image stream `stream` encoded with the image compression
+// filters DCTDecode, JPXDecode or JBIG2Decode, which can be saved without recompression, and the
+// name of the filter. The data is decoded with the other filters the image is encoded with, and
+// the JBIG2 global segments are prepended to the JBIG2 data. Returns nil if the image is not
+// encoded with these filters.
+func rawImageData(stream *core.PdfObjectStream) ([]byte, string, error) {
+	var filters []core.PdfObject
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case *core.PdfObjectName:
+		filters = []core.PdfObject{t}
+	case *core.PdfObjectArray:
+		filters = t.Elements()
+	}
+	if len(filters) == 0 {
+		return nil, "", nil
+	}
+	last, ok := core.GetNameVal(filters[len(filters)-1])
+	if !ok {
+		return nil, "", nil
+	}
+	switch last {
+	case core.StreamEncodingFilterNameDCT, core.StreamEncodingFilterNameJPX, core.StreamEncodingFilterNameJBIG2:
+	default:
+		return nil, "", nil
+	}
+
+	var parms []core.PdfObject
+	switch t := core.TraceToDirectObject(stream.Get("DecodeParms")).(type) {
+	case *core.PdfObjectDictionary:
+		parms = []core.PdfObject{t}
+	case *core.PdfObjectArray:
+		parms = t.Elements()
+	}
+
+	data := stream.Stream
+	if len(filters) > 1 {
+		// Decode the data with the other filters only.
+		dict := core.MakeDict()
+		dict.Set("Filter", core.MakeArray(filters[:len(filters)-1]...))
+		if len(parms) == len(filters) {
+			dict.Set("DecodeParms", core.MakeArray(parms[:len(parms)-1]...))
+		}
+		decoded, err := core.DecodeStream(&core.PdfObjectStream{PdfObjectDictionary: dict, Stream: data})
+		if err != nil {
+			return nil, "", err
+		}
+		data = decoded
+	}
+
+	if last == core.StreamEncodingFilterNameJBIG2 && len(parms) == len(filters) {
+		if dict, ok := core.GetDict(parms[len(parms)-1]); ok {
+			if globals, ok := core.GetStream(dict.Get("JBIG2Globals")); ok {
+				decoded, err := core.DecodeStream(globals)
+				if err != nil {
+					return nil, "", err
+				}
+				data = append(decoded, data...)
+			}
+		}
+	}
+	return data, last, nil
+}
+
+// toByte returns the 8 bit value of the value `v` between 0 and 1.
+func toByte(v float64) byte {
+	return byte(math.Round(math.Min(math.Max(v, 0), 1) * 255))
+}
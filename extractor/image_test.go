@@ -6,6 +6,7 @@
 package extractor
 
 import (
+	"fmt"
 	"math"
 	"os"
 	"path/filepath"
@@ -15,9 +16,21 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 )
 
+// checkImageMarks checks that `images` match `expected`, except for the image data and the
+// placement matrix, which is checked through the position, size and angle.
+func checkImageMarks(t *testing.T, expected, images []ImageMark) {
+	for i, img := range images {
+		img.Image = nil
+		img.CTM = transform.Matrix{}
+		assert.Equalf(t, expected[i], img, "i = %d", i)
+	}
+}
+
 func TestImageExtractionBasic(t *testing.T) {
 	type expectedImage struct {
 		X      float64
@@ -84,10 +97,7 @@ func TestImageExtractionBasic(t *testing.T) {
 
 		assert.Equal(t, len(tcase.Expected), len(pageImages.Images))
 
-		for i, img := range pageImages.Images {
-			img.Image = nil // Discard image data.
-			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
-		}
+		checkImageMarks(t, tcase.Expected, pageImages.Images)
 	}
 }
 
@@ -181,10 +191,7 @@ func TestImageExtractionNestedCM(t *testing.T) {
 
 		assert.Equal(t, len(tcase.Expected), len(pageImages.Images))
 
-		for i, img := range pageImages.Images {
-			img.Image = nil // Discard image data.
-			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
-		}
+		checkImageMarks(t, tcase.Expected, pageImages.Images)
 	}
 }
 
@@ -250,6 +257,89 @@ func TestImageExtractionMulti(t *testing.T) {
 	}
 }
 
+// makeImageStream returns an image XObject stream with data `data` and the entries `entries`
+// (key, value pairs) in its dictionary.
+func makeImageStream(t *testing.T, data []byte, encoder core.StreamEncoder, entries ...core.PdfObject) *core.PdfObjectStream {
+	stream, err := core.MakeStream(data, encoder)
+	require.NoError(t, err)
+	stream.Set("Type", core.MakeName("XObject"))
+	stream.Set("Subtype", core.MakeName("Image"))
+	for i := 0; i+1 < len(entries); i += 2 {
+		stream.Set(*entries[i].(*core.PdfObjectName), entries[i+1])
+	}
+	return stream
+}
+
+// Test the compositing of images with their masks and decode arrays and the raw image data.
+func TestImageExtractionComposite(t *testing.T) {
+	name := core.MakeName
+	integer := core.MakeInteger
+	content := "q 20 0 0 10 5 6 cm /Im0 Do Q 0 1 0 rg /Im1 Do /Im2 Do"
+	page := pdftest.NewPage(t, 100, 100, content)
+
+	// Red and blue RGB image with inverted samples, with a soft mask.
+	smask := makeImageStream(t, []byte{255, 128}, nil,
+		name("Width"), integer(2), name("Height"), integer(1),
+		name("ColorSpace"), name("DeviceGray"), name("BitsPerComponent"), integer(8))
+	im0 := makeImageStream(t, []byte{0, 255, 255, 255, 255, 0}, core.NewFlateEncoder(),
+		name("Width"), integer(2), name("Height"), integer(1),
+		name("ColorSpace"), name("DeviceRGB"), name("BitsPerComponent"), integer(8),
+		name("Decode"), core.MakeArrayFromFloats([]float64{1, 0, 1, 0, 1, 0}),
+		name("SMask"), smask)
+	// 3x2 stencil mask, with padded rows.
+	im1 := makeImageStream(t, []byte{0x40, 0x80}, nil,
+		name("Width"), integer(3), name("Height"), integer(2),
+		name("ImageMask"), core.MakeBool(true))
+	// Gray image with a color key mask.
+	im2 := makeImageStream(t, []byte{10, 200}, nil,
+		name("Width"), integer(2), name("Height"), integer(1),
+		name("ColorSpace"), name("DeviceGray"), name("BitsPerComponent"), integer(8),
+		name("Mask"), core.MakeArrayFromIntegers([]int{0, 20}))
+	// JPEG data (not decodable) encoded with the Flate filter too.
+	jpeg := []byte("\xff\xd8JPEG")
+	im3 := makeImageStream(t, jpeg, core.NewFlateEncoder(),
+		name("Width"), integer(2), name("Height"), integer(1),
+		name("ColorSpace"), name("DeviceGray"), name("BitsPerComponent"), integer(8),
+		name("Filter"), core.MakeArray(name("FlateDecode"), name("DCTDecode")))
+	for i, stream := range []*core.PdfObjectStream{im0, im1, im2, im3} {
+		require.NoError(t, page.Resources.SetXObjectByName(core.PdfObjectName(fmt.Sprintf("Im%d", i)), stream))
+	}
+
+	// The stencil masks are painted with the fill color by default.
+	pageExtractor, err := New(page)
+	require.NoError(t, err)
+	pageImages, err := pageExtractor.ExtractPageImages(nil)
+	require.NoError(t, err)
+	require.Len(t, pageImages.Images, 3)
+	require.Equal(t, []byte{0, 255, 0, 255, 255, 255, 0, 255, 0, 255, 255, 255, 0, 255, 0, 0, 255, 0},
+		pageImages.Images[1].Image.Data)
+	require.Nil(t, pageImages.Images[0].Composited)
+
+	// The images that cannot be decoded are extracted with their raw data only.
+	require.NoError(t, page.SetContentStreams([]string{content + " /Im3 Do"}, core.NewRawEncoder()))
+	pageExtractor, err = New(page)
+	require.NoError(t, err)
+	pageImages, err = pageExtractor.ExtractPageImages(&ImageExtractOptions{Composite: true, RawData: true})
+	require.NoError(t, err)
+	require.Len(t, pageImages.Images, 4)
+
+	mark := pageImages.Images[0]
+	require.Equal(t, []byte{255, 0, 0, 255, 0, 0, 255, 128}, mark.Composited.Pix)
+	ctmX, ctmY := mark.CTM.Transform(1, 1)
+	require.Equal(t, []float64{25, 16}, []float64{ctmX, ctmY})
+	require.Equal(t, []byte{
+		0, 255, 0, 255, 0, 255, 0, 0, 0, 255, 0, 255,
+		0, 255, 0, 0, 0, 255, 0, 255, 0, 255, 0, 255,
+	}, pageImages.Images[1].Composited.Pix)
+	require.Equal(t, []byte{10, 10, 10, 0, 200, 200, 200, 255}, pageImages.Images[2].Composited.Pix)
+
+	mark = pageImages.Images[3]
+	require.Nil(t, mark.Image)
+	require.Nil(t, mark.Composited)
+	require.Equal(t, jpeg, mark.RawData)
+	require.Equal(t, "DCTDecode", mark.RawFilter)
+}
+
 func TestImageExtractionRealWorld(t *testing.T) {
 	if len(corpusFolder) == 0 && !forceTest {
 		t.Log("Corpus folder not set - skipping")
@@ -326,10 +416,7 @@ func TestImageExtractionRealWorld(t *testing.T) {
 		}
 		assert.Equal(t, len(tcase.Expected), len(pageImages.Images))
 
-		for i, img := range pageImages.Images {
-			img.Image = nil // Discard image data.
-			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
-		}
+		checkImageMarks(t, tcase.Expected, pageImages.Images)
 	}
 }
 
@@ -71,7 +71,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 		return pageText, state.numChars, state.numMisses, err
 	}
 
-	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor := e.newProcessor(*operations)
 
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
@@ -79,6 +79,20 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 
 			operand := op.Operand
 
+			// Content hidden by optional content is processed to keep track of the text
+			// state, but the resulting marks are discarded.
+			if !isOperationVisible(processor, op, resources) {
+				if operand == "Do" {
+					return nil
+				}
+				curTo, numMarks := to, len(to.marks)
+				defer func() {
+					if to == curTo && len(to.marks) > numMarks {
+						to.marks = to.marks[:numMarks]
+					}
+				}()
+			}
+
 			switch operand {
 			case "q":
 				if !fontStack.empty() {
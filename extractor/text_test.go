@@ -6,6 +6,7 @@
 package extractor
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -1078,3 +1079,88 @@ func (l *markupList) saveOutputPdf() {
 		l.t.Fatalf("WriteFile failed. metaPath=%q err=%v", metaPath, err)
 	}
 }
+
+// TestTextExtractionOptionalContent tests that text in hidden optional content groups (layers)
+// is not extracted.
+func TestTextExtractionOptionalContent(t *testing.T) {
+	isTesting = true
+
+	c := creator.New()
+	visible := c.AddLayer("Visible", true)
+	hidden := c.AddLayer("Hidden", false)
+
+	for _, l := range []struct {
+		text string
+		ocg  *model.PdfOCG
+	}{
+		{"base text", nil},
+		{"visible layer text", visible},
+		{"hidden layer text", hidden},
+	} {
+		p := c.NewParagraph(l.text)
+		var err error
+		if l.ocg == nil {
+			err = c.Draw(p)
+		} else {
+			err = c.DrawInLayer(p, l.ocg)
+		}
+		if err != nil {
+			t.Fatalf("Draw failed. err=%v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage failed. err=%v", err)
+	}
+
+	extractText := func(configure func(e *Extractor)) string {
+		e, err := New(page)
+		if err != nil {
+			t.Fatalf("New failed. err=%v", err)
+		}
+		if configure != nil {
+			configure(e)
+		}
+		text, err := e.ExtractText()
+		if err != nil {
+			t.Fatalf("ExtractText failed. err=%v", err)
+		}
+		return text
+	}
+
+	// Default configuration.
+	text := extractText(nil)
+	if !strings.Contains(text, "base text") || !strings.Contains(text, "visible layer text") {
+		t.Fatalf("missing visible text: %q", text)
+	}
+	if strings.Contains(text, "hidden layer text") {
+		t.Fatalf("hidden text extracted: %q", text)
+	}
+
+	// Toggled visibility.
+	ocProperties, err := reader.GetOptionalContentProperties()
+	if err != nil {
+		t.Fatalf("GetOptionalContentProperties failed. err=%v", err)
+	}
+	config := model.NewPdfOCConfig()
+	config.SetVisible(ocProperties.GetOCGByName("Visible"), false)
+	text = extractText(func(e *Extractor) { e.SetOptionalContent(ocProperties, config) })
+	if strings.Contains(text, "visible layer text") || !strings.Contains(text, "hidden layer text") {
+		t.Fatalf("unexpected text with alternate configuration: %q", text)
+	}
+
+	// Optional content ignored.
+	text = extractText(func(e *Extractor) { e.SetOptionalContent(nil, nil) })
+	if !strings.Contains(text, "visible layer text") || !strings.Contains(text, "hidden layer text") {
+		t.Fatalf("missing text with optional content ignored: %q", text)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"fmt"
 
 	"github.com/unidoc/unipdf/v3/common/license"
+	"github.com/unidoc/unipdf/v3/contentstream"
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
 )
 
 // RenderMode specifies the text rendering mode (Tmode), which determines whether showing text shall cause
@@ -26,6 +28,28 @@ const (
 	RenderModeClip                          // Clip
 )
 
+// isOperationVisible returns false if the operation `op` processed by `processor` is hidden by
+// optional content, i.e. it is in a hidden marked content sequence or it paints an XObject
+// with hidden optional content.
+func isOperationVisible(processor *contentstream.ContentStreamProcessor, op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) bool {
+	if !processor.IsContentVisible() {
+		return false
+	}
+	if op.Operand != "Do" || len(op.Params) != 1 || resources == nil {
+		return true
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return true
+	}
+	stream, _ := resources.GetXObjectByName(*name)
+	if stream == nil {
+		return true
+	}
+	return processor.IsOptionalContentVisible(stream.Get("OC"))
+}
+
 // toFloatXY returns `objs` as 2 floats, if that's what `objs` is, or an error if it isn't.
 func toFloatXY(objs []core.PdfObject) (x, y float64, err error) {
 	if len(objs) != 2 {
@@ -0,0 +1,116 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package impose
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// BookletOptions defines the layout of saddle-stitched booklet imposition.
+type BookletOptions struct {
+	// Size of the output sheets. Each side of a sheet holds two pages next to each other,
+	// separated by the spine (fold) in the middle of the sheet.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Space between the pages and the outer edges of the sheet.
+	Margin float64
+
+	// Creep compensation per sheet: the pages of the n-th sheet from the outside of the
+	// booklet are shifted toward the spine by n*Creep. The shifted pages are not cut off at
+	// the spine, so that their inner edges extend into the fold.
+	Creep float64
+}
+
+// NewBookletOptions returns the options for booklet imposition on sheets of size
+// `sheetWidth` x `sheetHeight`.
+func NewBookletOptions(sheetWidth, sheetHeight float64) *BookletOptions {
+	return &BookletOptions{
+		SheetWidth:  sheetWidth,
+		SheetHeight: sheetHeight,
+	}
+}
+
+// BookletOrder returns the indices of the pages in a saddle-stitched booklet of `numPages` pages,
+// in the order they are placed on the sheet sides: left then right page of the front of the
+// first sheet, then of its back, and so on. The number of pages is rounded up to a multiple of 4,
+// indices greater or equal to `numPages` stand for blank pages.
+func BookletOrder(numPages int) []int {
+	n := (numPages + 3) / 4 * 4
+	order := make([]int, 0, n)
+	for i := 0; i < n/4; i++ {
+		order = append(order,
+			n-1-2*i, 2*i, // Front.
+			2*i+1, n-2-2*i, // Back.
+		)
+	}
+	return order
+}
+
+// Booklet imposes `pages` for a saddle-stitched booklet: pages are placed two per sheet side so
+// that the folded and nested sheets read in order. Output pages alternate between sheet fronts
+// and backs and are meant to be printed duplex, flipping on the short edge. Blank pages are added
+// as needed so that the number of pages is a multiple of 4.
+func Booklet(pages []*model.PdfPage, opts *BookletOptions) ([]*model.PdfPage, error) {
+	if len(pages) == 0 {
+		return nil, errNoPages
+	}
+	if opts == nil || opts.SheetWidth <= 0 || opts.SheetHeight <= 0 {
+		return nil, errors.New("invalid booklet options")
+	}
+
+	spine := opts.SheetWidth / 2
+	slotWidth := spine - opts.Margin
+	slotHeight := opts.SheetHeight - 2*opts.Margin
+	if slotWidth <= 0 || slotHeight <= 0 {
+		return nil, errors.New("margins exceed the sheet size")
+	}
+
+	imp := newImposer()
+	order := BookletOrder(len(pages))
+	for side := 0; side < len(order)/2; side++ {
+		s := imp.newSheet(opts.SheetWidth, opts.SheetHeight)
+		creep := float64(side/2) * opts.Creep
+
+		for slot := 0; slot < 2; slot++ {
+			idx := order[2*side+slot]
+			if idx >= len(pages) {
+				// Blank page.
+				continue
+			}
+			page := pages[idx]
+			f, err := imp.pageForm(page)
+			if err != nil {
+				return nil, err
+			}
+
+			// Pages are scaled to fit their half of the sheet and aligned to the spine. The
+			// clipping half of the sheet moves with the pages shifted by the creep.
+			w, h := f.size()
+			scale := math.Min(slotWidth/w, slotHeight/h)
+			ty := opts.Margin + (slotHeight-h*scale)/2
+			clip := model.PdfRectangle{Lly: 0, Ury: opts.SheetHeight}
+			var tx float64
+			if slot == 0 {
+				tx = spine - w*scale + creep
+				clip.Llx, clip.Urx = creep, spine+creep
+			} else {
+				tx = spine - creep
+				clip.Llx, clip.Urx = spine-creep, opts.SheetWidth-creep
+			}
+
+			m := transform.NewMatrix(scale, 0, 0, scale, tx, ty).Mult(f.displayMatrix())
+			if err := imp.place(s, page, m, &clip); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return imp.finish()
+}
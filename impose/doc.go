@@ -0,0 +1,15 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package impose provides page imposition: source pages are placed as form XObjects onto
+// new sheets for printing. Supported layouts are N-up grids (NUp), saddle-stitch booklets
+// (Booklet) and tiling of large pages across multiple sheets (Tile).
+//
+// The generated sheets are regular pages which can be added to a model.PdfWriter.
+// Annotations of the source pages are copied onto the sheets with their geometry transformed,
+// and link destinations pointing to imposed pages are remapped to the corresponding sheets.
+// Widget annotations are not copied as the form fields are not carried over to the sheets:
+// flatten the form fields of the source document beforehand to keep their appearance.
+package impose
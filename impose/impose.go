@@ -0,0 +1,341 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package impose
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+var errNoPages = errors.New("no pages to impose")
+
+// pageForm is a source page converted to a form XObject.
+type pageForm struct {
+	xform *model.XObjectForm
+
+	// Visible area of the page (crop box) in the page space and page rotation
+	// (clockwise, multiple of 90 degrees).
+	box    model.PdfRectangle
+	rotate int64
+}
+
+// size returns the displayed size of the page, i.e. accounting for the rotation.
+func (f *pageForm) size() (float64, float64) {
+	w, h := f.box.Width(), f.box.Height()
+	if f.rotate%180 != 0 {
+		return h, w
+	}
+	return w, h
+}
+
+// displayMatrix returns the matrix mapping the page space to the display space, where the
+// displayed (rotated) page spans from (0,0) to size().
+func (f *pageForm) displayMatrix() transform.Matrix {
+	w, h := f.box.Width(), f.box.Height()
+	var rot transform.Matrix
+	switch f.rotate {
+	case 90:
+		rot = transform.NewMatrix(0, -1, 1, 0, 0, w)
+	case 180:
+		rot = transform.NewMatrix(-1, 0, 0, -1, w, h)
+	case 270:
+		rot = transform.NewMatrix(0, 1, -1, 0, h, 0)
+	default:
+		rot = transform.IdentityMatrix()
+	}
+	return rot.Mult(transform.TranslationMatrix(-f.box.Llx, -f.box.Lly))
+}
+
+// fitMatrix returns the matrix mapping the page space to the sheet space so that the displayed
+// page is scaled by `scale` and centered in rectangle `rect` of the sheet. If `scale` is not
+// positive, the page is scaled to fit the rectangle.
+func (f *pageForm) fitMatrix(rect model.PdfRectangle, scale float64) transform.Matrix {
+	w, h := f.size()
+	if scale <= 0 {
+		scale = math.Min(rect.Width()/w, rect.Height()/h)
+	}
+	tx := rect.Llx + (rect.Width()-w*scale)/2
+	ty := rect.Lly + (rect.Height()-h*scale)/2
+
+	m := transform.NewMatrix(scale, 0, 0, scale, tx, ty)
+	return m.Mult(f.displayMatrix())
+}
+
+// sheet is an output page being imposed.
+type sheet struct {
+	page     *model.PdfPage
+	cc       *contentstream.ContentCreator
+	numForms int
+}
+
+// placement is a source page drawn on a sheet.
+type placement struct {
+	sheet *sheet
+	page  *model.PdfPage
+
+	// Transform from the page space to the sheet space and visible area of the page
+	// on the sheet.
+	m    transform.Matrix
+	clip model.PdfRectangle
+}
+
+// imposer places source pages onto sheets.
+type imposer struct {
+	forms      map[*model.PdfPage]*pageForm
+	sheets     []*sheet
+	placements []*placement
+
+	// Placements of each source page by page object, used to remap destinations.
+	pagePlacements map[core.PdfObject][]*placement
+}
+
+func newImposer() *imposer {
+	return &imposer{
+		forms:          map[*model.PdfPage]*pageForm{},
+		pagePlacements: map[core.PdfObject][]*placement{},
+	}
+}
+
+// newSheet appends a new sheet of size `width` x `height`.
+func (imp *imposer) newSheet(width, height float64) *sheet {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: width, Ury: height}
+	s := &sheet{
+		page: page,
+		cc:   contentstream.NewContentCreator(),
+	}
+	imp.sheets = append(imp.sheets, s)
+	return s
+}
+
+// pageForm returns the form XObject of `page`, converting it on first access.
+func (imp *imposer) pageForm(page *model.PdfPage) (*pageForm, error) {
+	if f, ok := imp.forms[page]; ok {
+		return f, nil
+	}
+
+	box, err := page.GetCropBox()
+	if err != nil {
+		return nil, err
+	}
+	rotate, err := page.GetRotate()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	xform := model.NewXObjectForm()
+	xform.BBox = box.ToPdfObject()
+	xform.Resources = page.Resources
+	if xform.Resources == nil {
+		xform.Resources = model.NewPdfPageResources()
+	}
+	xform.Group = page.Group
+	if err := xform.SetContentStream([]byte(contents), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+
+	f := &pageForm{xform: xform, box: *box, rotate: rotate}
+	imp.forms[page] = f
+	return f, nil
+}
+
+// place draws `page` on sheet `s` with transform `m` from the page space to the sheet space.
+// The page is clipped to `clip` (sheet space) if not nil, otherwise to its visible area.
+func (imp *imposer) place(s *sheet, page *model.PdfPage, m transform.Matrix, clip *model.PdfRectangle) error {
+	f, err := imp.pageForm(page)
+	if err != nil {
+		return err
+	}
+
+	name := core.PdfObjectName(fmt.Sprintf("Pg%d", s.numForms))
+	s.numForms++
+	if err := s.page.Resources.SetXObjectFormByName(name, f.xform); err != nil {
+		return err
+	}
+
+	visible := f.box.Transform(m)
+	if clip != nil {
+		visible = visible.Intersect(*clip)
+	}
+	s.cc.Add_q().
+		Add_re(visible.Llx, visible.Lly, visible.Width(), visible.Height()).
+		Add_W().
+		Add_n().
+		Add_cm(m[0], m[1], m[3], m[4], m[6], m[7]).
+		Add_Do(name).
+		Add_Q()
+
+	p := &placement{sheet: s, page: page, m: m, clip: visible}
+	imp.placements = append(imp.placements, p)
+	if obj := page.GetPageAsIndirectObject(); obj != nil {
+		imp.pagePlacements[obj] = append(imp.pagePlacements[obj], p)
+	}
+	return nil
+}
+
+// finish completes the imposition: sets the sheet contents, copies the annotations of the
+// placed pages and returns the sheets.
+func (imp *imposer) finish() ([]*model.PdfPage, error) {
+	for _, s := range imp.sheets {
+		err := s.page.SetContentStreams([]string{s.cc.String()}, core.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := imp.copyAnnotations(); err != nil {
+		return nil, err
+	}
+
+	pages := make([]*model.PdfPage, len(imp.sheets))
+	for i, s := range imp.sheets {
+		pages[i] = s.page
+	}
+	return pages, nil
+}
+
+// copiedAnnotation is a copy of an annotation dictionary placed on a sheet.
+type copiedAnnotation struct {
+	sheet     *sheet
+	m         transform.Matrix
+	container *core.PdfIndirectObject
+}
+
+// copyAnnotations copies the annotations of the placed pages onto the sheets, transforming
+// their geometry. Destinations to placed pages are remapped to the sheets.
+func (imp *imposer) copyAnnotations() error {
+	var copies []copiedAnnotation
+	// Source annotation dictionary -> copy (last one), used to remap references between
+	// annotations (IRT).
+	copyMap := map[core.PdfObject]*core.PdfIndirectObject{}
+
+	for _, p := range imp.placements {
+		annotations, err := p.page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		for _, annot := range annotations {
+			switch annot.GetContext().(type) {
+			case *model.PdfAnnotationWidget, *model.PdfAnnotationPopup:
+				// Form fields are not carried over and popups are recreated by viewers.
+				continue
+			}
+
+			var obj core.PdfObject
+			if ctx := annot.GetContext(); ctx != nil {
+				obj = ctx.ToPdfObject()
+			} else {
+				obj = annot.ToPdfObject()
+			}
+			dict, ok := core.GetDict(obj)
+			if !ok {
+				continue
+			}
+			rect, ok := model.GetPdfRectangle(dict.Get("Rect"))
+			if !ok {
+				continue
+			}
+			rect = rect.Transform(p.m)
+			if !rect.Overlaps(p.clip) {
+				continue
+			}
+
+			d := core.MakeDict()
+			d.Merge(dict)
+			d.Set("P", p.sheet.page.GetPageAsIndirectObject())
+			d.Remove("Popup")
+			d.Remove("Parent")
+			d.Remove("StructParent")
+
+			container := core.MakeIndirectObject(d)
+			copies = append(copies, copiedAnnotation{sheet: p.sheet, m: p.m, container: container})
+			copyMap[obj] = container
+		}
+	}
+
+	for _, c := range copies {
+		d, _ := core.GetDict(c.container)
+		if irt := d.Get("IRT"); irt != nil {
+			if target, ok := copyMap[core.ResolveReference(irt)]; ok {
+				d.Set("IRT", target)
+			} else {
+				d.Remove("IRT")
+			}
+		}
+		if dest := d.Get("Dest"); dest != nil {
+			d.Set("Dest", imp.remapDestination(dest))
+		}
+		if action, ok := core.GetDict(d.Get("A")); ok {
+			if s, _ := core.GetNameVal(action.Get("S")); s == "GoTo" {
+				a := core.MakeDict()
+				a.Merge(action)
+				a.Set("D", imp.remapDestination(action.Get("D")))
+				d.Set("A", a)
+			}
+		}
+
+		annot, err := model.NewPdfAnnotationFromIndirectObject(c.container)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to copy annotation - skipping: %v", err)
+			continue
+		}
+		annot.Transform(c.m)
+		c.sheet.page.AddAnnotation(annot)
+	}
+	return nil
+}
+
+// remapDestination returns the explicit destination `dest` with the target page replaced by
+// the sheet it is placed on. Destinations to pages which are not imposed and named destinations
+// are returned unchanged.
+func (imp *imposer) remapDestination(dest core.PdfObject) core.PdfObject {
+	arr, ok := core.GetArray(dest)
+	if !ok || arr.Len() < 2 {
+		return dest
+	}
+	placements := imp.pagePlacements[core.ResolveReference(arr.Get(0))]
+	if len(placements) == 0 {
+		return dest
+	}
+	p := placements[0]
+	sheetObj := p.sheet.page.GetPageAsIndirectObject()
+
+	nums := func(objs []core.PdfObject) ([]float64, bool) {
+		vals, err := core.GetNumbersAsFloat(objs)
+		return vals, err == nil
+	}
+	fitType, _ := core.GetNameVal(arr.Get(1))
+	elems := arr.Elements()
+	switch fitType {
+	case "XYZ":
+		if len(elems) == 5 {
+			if vals, ok := nums(elems[2:4]); ok {
+				x, y := p.m.Transform(vals[0], vals[1])
+				return core.MakeArray(sheetObj, core.MakeName("XYZ"), core.MakeFloat(x), core.MakeFloat(y), elems[4])
+			}
+		}
+	case "FitR":
+		if len(elems) == 6 {
+			if vals, ok := nums(elems[2:6]); ok {
+				rect := model.PdfRectangle{Llx: vals[0], Lly: vals[1], Urx: vals[2], Ury: vals[3]}
+				r := rect.Transform(p.m)
+				return core.MakeArray(sheetObj, core.MakeName("FitR"), core.MakeFloat(r.Llx),
+					core.MakeFloat(r.Lly), core.MakeFloat(r.Urx), core.MakeFloat(r.Ury))
+			}
+		}
+	}
+	return core.MakeArray(sheetObj, core.MakeName("Fit"))
+}
@@ -0,0 +1,151 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package impose
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// loadTestPages returns `n` pages of size 200x300 read from a generated document. The first page
+// has a link annotation to the last page.
+func loadTestPages(t *testing.T, n int) []*model.PdfPage {
+	var pages []*model.PdfPage
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("0 0 1 rg 10 10 %d 20 re f", 10*(i+1))
+		pages = append(pages, pdftest.NewPage(t, 200, 300, content))
+	}
+
+	link := model.NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 250, 110, 270})
+	link.Dest = core.MakeArray(pages[n-1].GetPageAsIndirectObject(), core.MakeName("XYZ"),
+		core.MakeFloat(0), core.MakeFloat(300), core.MakeNull())
+	pages[0].AddAnnotation(link.PdfAnnotation)
+
+	return pdftest.ReadPages(t, pdftest.WriteRead(t, pages...))
+}
+
+func TestNUp(t *testing.T) {
+	pages := loadTestPages(t, 5)
+	sheets, err := NUp(pages, NewNUpOptions(400, 300, 2, 1))
+	require.NoError(t, err)
+	require.Len(t, sheets, 3)
+
+	r := pdftest.WriteRead(t, sheets...)
+	numPages, err := r.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 3, numPages)
+
+	sheet, err := r.GetPage(1)
+	require.NoError(t, err)
+	content, err := sheet.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(content, " Do"))
+	require.Contains(t, content, "1 0 0 1 200 0 cm")
+
+	// The link is copied in place and targets the sheet holding the last page.
+	annotations, err := sheet.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	link, ok := annotations[0].GetContext().(*model.PdfAnnotationLink)
+	require.True(t, ok)
+	rect, ok := core.GetArray(link.Rect)
+	require.True(t, ok)
+	vals, _ := rect.ToFloat64Array()
+	require.Equal(t, []float64{10, 250, 110, 270}, vals)
+
+	dest, ok := core.GetArray(link.Dest)
+	require.True(t, ok)
+	last, err := r.GetPage(3)
+	require.NoError(t, err)
+	require.Equal(t, last.GetPageAsIndirectObject(), core.ResolveReference(dest.Get(0)))
+
+	_, err = NUp(nil, NewNUpOptions(400, 300, 2, 1))
+	require.Error(t, err)
+}
+
+func TestBookletOrder(t *testing.T) {
+	require.Equal(t, []int{7, 0, 1, 6, 5, 2, 3, 4}, BookletOrder(8))
+	require.Equal(t, []int{3, 0, 1, 2}, BookletOrder(3))
+}
+
+func TestBooklet(t *testing.T) {
+	pages := loadTestPages(t, 6)
+	opts := NewBookletOptions(400, 300)
+	opts.Creep = 2
+	sheets, err := Booklet(pages, opts)
+	require.NoError(t, err)
+	require.Len(t, sheets, 4)
+
+	r := pdftest.WriteRead(t, sheets...)
+	var numForms []int
+	for i := 1; i <= 4; i++ {
+		sheet, err := r.GetPage(i)
+		require.NoError(t, err)
+		content, err := sheet.GetAllContentStreams()
+		require.NoError(t, err)
+		numForms = append(numForms, strings.Count(content, " Do"))
+
+		if i == 3 {
+			// Inner sheet front: pages 5 and 2 shifted toward the spine by the creep.
+			require.Contains(t, content, "1 0 0 1 2 0 cm")
+			require.Contains(t, content, "1 0 0 1 198 0 cm")
+			// The pages are not cut off at the spine: the visible bounds are the whole pages.
+			require.Contains(t, content, "2 0 200 300 re")
+			require.Contains(t, content, "198 0 200 300 re")
+		}
+	}
+	// The first sheet front holds a blank page and page 1, its back page 2 and a blank page.
+	require.Equal(t, []int{1, 1, 2, 2}, numForms)
+}
+
+func TestTile(t *testing.T) {
+	pages := loadTestPages(t, 1)
+	opts := NewTileOptions(120, 120)
+	opts.Margin = 10
+	opts.Overlap = 20
+	opts.CropMarks = true
+	opts.Scale = 2
+	sheets, err := Tile(pages, opts)
+	require.NoError(t, err)
+
+	// Scaled page 400x600, tiles 100x100 with step 80: 5 columns and 8 rows.
+	require.Len(t, sheets, 40)
+
+	r := pdftest.WriteRead(t, sheets...)
+	sheet, err := r.GetPage(1)
+	require.NoError(t, err)
+	content, err := sheet.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "10 10 100 100 re")
+	require.Contains(t, content, "2 0 0 2 10 -490 cm")
+
+	// The link near the top left corner of the page is copied onto the first tile.
+	annotations, err := sheet.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+}
+
+func TestPageFormInherited(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 300, "0 0 1 rg 10 10 100 20 re f")
+	parent := core.MakeDict()
+	parent.Set("CropBox", core.MakeArrayFromFloats([]float64{10, 20, 110, 220}))
+	parent.Set("Rotate", core.MakeInteger(90))
+	page.Parent = core.MakeIndirectObject(parent)
+
+	imp := newImposer()
+	f, err := imp.pageForm(page)
+	require.NoError(t, err)
+	require.Equal(t, model.PdfRectangle{Llx: 10, Lly: 20, Urx: 110, Ury: 220}, f.box)
+	require.Equal(t, int64(90), f.rotate)
+}
@@ -0,0 +1,100 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package impose
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// NUpOrder specifies the order in which pages fill the cells of an N-up sheet.
+type NUpOrder int
+
+// N-up page orders.
+const (
+	// NUpOrderRows fills the cells left to right, then top to bottom.
+	NUpOrderRows NUpOrder = iota
+
+	// NUpOrderColumns fills the cells top to bottom, then left to right.
+	NUpOrderColumns
+)
+
+// NUpOptions defines the layout of N-up imposition.
+type NUpOptions struct {
+	// Size of the output sheets.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Number of columns and rows of pages on each sheet.
+	Columns int
+	Rows    int
+
+	// Space around the grid and between the cells.
+	Margin float64
+	Gutter float64
+
+	// Order in which the pages fill the cells.
+	Order NUpOrder
+}
+
+// NewNUpOptions returns the options for imposing `columns` x `rows` pages per sheet of size
+// `sheetWidth` x `sheetHeight`.
+func NewNUpOptions(sheetWidth, sheetHeight float64, columns, rows int) *NUpOptions {
+	return &NUpOptions{
+		SheetWidth:  sheetWidth,
+		SheetHeight: sheetHeight,
+		Columns:     columns,
+		Rows:        rows,
+	}
+}
+
+// NUp imposes `pages` onto sheets with a grid of opts.Columns x opts.Rows cells. Each page is
+// scaled to fit its cell, preserving its aspect ratio, and centered in it.
+func NUp(pages []*model.PdfPage, opts *NUpOptions) ([]*model.PdfPage, error) {
+	if len(pages) == 0 {
+		return nil, errNoPages
+	}
+	if opts == nil || opts.Columns <= 0 || opts.Rows <= 0 || opts.SheetWidth <= 0 || opts.SheetHeight <= 0 {
+		return nil, errors.New("invalid N-up options")
+	}
+
+	cols, rows := float64(opts.Columns), float64(opts.Rows)
+	cellWidth := (opts.SheetWidth - 2*opts.Margin - (cols-1)*opts.Gutter) / cols
+	cellHeight := (opts.SheetHeight - 2*opts.Margin - (rows-1)*opts.Gutter) / rows
+	if cellWidth <= 0 || cellHeight <= 0 {
+		return nil, errors.New("margins and gutters exceed the sheet size")
+	}
+
+	perSheet := opts.Columns * opts.Rows
+	imp := newImposer()
+	var s *sheet
+	for i, page := range pages {
+		cell := i % perSheet
+		if cell == 0 {
+			s = imp.newSheet(opts.SheetWidth, opts.SheetHeight)
+		}
+
+		var col, row int
+		if opts.Order == NUpOrderColumns {
+			col, row = cell/opts.Rows, cell%opts.Rows
+		} else {
+			col, row = cell%opts.Columns, cell/opts.Columns
+		}
+		llx := opts.Margin + float64(col)*(cellWidth+opts.Gutter)
+		ury := opts.SheetHeight - opts.Margin - float64(row)*(cellHeight+opts.Gutter)
+		rect := model.PdfRectangle{Llx: llx, Lly: ury - cellHeight, Urx: llx + cellWidth, Ury: ury}
+
+		f, err := imp.pageForm(page)
+		if err != nil {
+			return nil, err
+		}
+		if err := imp.place(s, page, f.fitMatrix(rect, 0), &rect); err != nil {
+			return nil, err
+		}
+	}
+	return imp.finish()
+}
@@ -0,0 +1,141 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package impose
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TileOptions defines the layout of tiling imposition.
+type TileOptions struct {
+	// Size of the output sheets.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Space around the tile on each sheet. Crop marks are drawn in the margin.
+	Margin float64
+
+	// Overlap between adjacent tiles, in the sheet space.
+	Overlap float64
+
+	// Draw crop marks at the corners of the tiles.
+	CropMarks bool
+
+	// Scale of the pages. Pages are not scaled if not positive.
+	Scale float64
+}
+
+// NewTileOptions returns the options for tiling pages across sheets of size
+// `sheetWidth` x `sheetHeight`.
+func NewTileOptions(sheetWidth, sheetHeight float64) *TileOptions {
+	return &TileOptions{
+		SheetWidth:  sheetWidth,
+		SheetHeight: sheetHeight,
+		Scale:       1,
+	}
+}
+
+// Tile splits each of `pages` into tiles printed on separate sheets, so that large pages can be
+// printed on smaller sheets and assembled. Adjacent tiles overlap by opts.Overlap. Tiles are
+// output row by row, from the top left corner of each page.
+func Tile(pages []*model.PdfPage, opts *TileOptions) ([]*model.PdfPage, error) {
+	if len(pages) == 0 {
+		return nil, errNoPages
+	}
+	if opts == nil || opts.SheetWidth <= 0 || opts.SheetHeight <= 0 || opts.Overlap < 0 {
+		return nil, errors.New("invalid tile options")
+	}
+
+	tileWidth := opts.SheetWidth - 2*opts.Margin
+	tileHeight := opts.SheetHeight - 2*opts.Margin
+	if tileWidth <= opts.Overlap || tileHeight <= opts.Overlap {
+		return nil, errors.New("margins and overlap exceed the sheet size")
+	}
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	clip := model.PdfRectangle{
+		Llx: opts.Margin,
+		Lly: opts.Margin,
+		Urx: opts.SheetWidth - opts.Margin,
+		Ury: opts.SheetHeight - opts.Margin,
+	}
+
+	imp := newImposer()
+	for _, page := range pages {
+		f, err := imp.pageForm(page)
+		if err != nil {
+			return nil, err
+		}
+		w, h := f.size()
+		w *= scale
+		h *= scale
+		cols := numTiles(w, tileWidth, opts.Overlap)
+		rows := numTiles(h, tileHeight, opts.Overlap)
+
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				s := imp.newSheet(opts.SheetWidth, opts.SheetHeight)
+
+				// Map the top left corner of the tile region of the page to the top left corner
+				// of the sheet clip.
+				x := float64(col) * (tileWidth - opts.Overlap)
+				y := h - float64(row)*(tileHeight-opts.Overlap)
+				m := transform.NewMatrix(scale, 0, 0, scale, clip.Llx-x, clip.Ury-y).Mult(f.displayMatrix())
+				if err := imp.place(s, page, m, &clip); err != nil {
+					return nil, err
+				}
+				if opts.CropMarks {
+					drawCropMarks(s, clip, opts.Margin)
+				}
+			}
+		}
+	}
+	return imp.finish()
+}
+
+// numTiles returns the number of tiles of size `tile` overlapping by `overlap` needed to cover
+// `size`.
+func numTiles(size, tile, overlap float64) int {
+	if size <= tile {
+		return 1
+	}
+	// Tolerate rounding errors when the tiles exactly cover the size.
+	return int(math.Ceil((size-overlap)/(tile-overlap) - 1e-6))
+}
+
+// drawCropMarks draws crop marks at the corners of `rect` on sheet `s`, in the margin of size
+// `margin` around it.
+func drawCropMarks(s *sheet, rect model.PdfRectangle, margin float64) {
+	if margin <= 0 {
+		return
+	}
+	// Marks are offset from the corners so that they are not visible on the trimmed tile.
+	offset := math.Min(3, margin/4)
+	length := margin - offset
+
+	cc := s.cc
+	cc.Add_q().Add_w(0.25).Add_K(1, 1, 1, 1)
+	for _, x := range []float64{rect.Llx, rect.Urx} {
+		for _, y := range []float64{rect.Lly, rect.Ury} {
+			dx, dy := -1.0, -1.0
+			if x == rect.Urx {
+				dx = 1
+			}
+			if y == rect.Ury {
+				dy = 1
+			}
+			cc.Add_m(x+dx*offset, y).Add_l(x+dx*(offset+length), y).Add_S()
+			cc.Add_m(x, y+dy*offset).Add_l(x, y+dy*(offset+length)).Add_S()
+		}
+	}
+	cc.Add_Q()
+}
@@ -0,0 +1,140 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+// qeState is an entry of the probability estimation table (Table E.1).
+type qeState struct {
+	qe         uint32
+	nmps, nlps uint8
+	switchMPS  bool
+}
+
+// qeTable is the probability estimation table of the MQ coder (Table E.1).
+var qeTable = [47]qeState{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false}, {0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false}, {0x0221, 38, 33, false}, {0x5601, 7, 6, true}, {0x5401, 8, 14, false},
+	{0x4801, 9, 14, false}, {0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true}, {0x5401, 16, 14, false},
+	{0x5101, 17, 15, false}, {0x4801, 18, 16, false}, {0x3801, 19, 17, false}, {0x3401, 20, 18, false},
+	{0x3001, 21, 19, false}, {0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false}, {0x1401, 28, 25, false},
+	{0x1201, 29, 26, false}, {0x1101, 30, 27, false}, {0x0AC1, 31, 28, false}, {0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false}, {0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false}, {0x0085, 40, 37, false},
+	{0x0049, 41, 38, false}, {0x0025, 42, 39, false}, {0x0015, 43, 40, false}, {0x0009, 44, 41, false},
+	{0x0005, 45, 42, false}, {0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// arithmeticEncoder is the MQ arithmetic encoder (Annex E.2).
+type arithmeticEncoder struct {
+	a, c uint32
+	ct   int
+	// out are the encoded bytes, preceded by a placeholder byte. Its last byte is the byte B being
+	// output.
+	out []byte
+
+	// index and mps are the states of the contexts.
+	index []uint8
+	mps   []uint8
+}
+
+// newArithmeticEncoder returns an arithmetic encoder of `numContexts` contexts (INITENC, E.2.8).
+func newArithmeticEncoder(numContexts int) *arithmeticEncoder {
+	return &arithmeticEncoder{
+		a:     0x8000,
+		ct:    12,
+		out:   []byte{0},
+		index: make([]uint8, numContexts),
+		mps:   make([]uint8, numContexts),
+	}
+}
+
+// encodeBit encodes bit `d` in context `cx` (ENCODE, E.2.2).
+func (e *arithmeticEncoder) encodeBit(cx int, d uint8) {
+	state := &qeTable[e.index[cx]]
+	qe := state.qe
+	e.a -= qe
+	if d == e.mps[cx] {
+		// CODEMPS (E.2.4).
+		if e.a&0x8000 != 0 {
+			e.c += qe
+			return
+		}
+		if e.a < qe {
+			e.a = qe
+		} else {
+			e.c += qe
+		}
+		e.index[cx] = state.nmps
+	} else {
+		// CODELPS (E.2.3).
+		if e.a < qe {
+			e.c += qe
+		} else {
+			e.a = qe
+		}
+		if state.switchMPS {
+			e.mps[cx] = 1 - e.mps[cx]
+		}
+		e.index[cx] = state.nlps
+	}
+	e.renormalize()
+}
+
+// renormalize is RENORME (E.2.6).
+func (e *arithmeticEncoder) renormalize() {
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+// byteOut is BYTEOUT (E.2.7), with bit stuffing after the 0xFF bytes.
+func (e *arithmeticEncoder) byteOut() {
+	last := len(e.out) - 1
+	if e.out[last] != 0xFF && e.c >= 0x8000000 {
+		// Carry into the byte B.
+		e.out[last]++
+		e.c &= 0x7FFFFFF
+	}
+	if e.out[last] == 0xFF {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xFFFFF
+		e.ct = 7
+		return
+	}
+	e.out = append(e.out, byte(e.c>>19))
+	e.c &= 0x7FFFF
+	e.ct = 8
+}
+
+// flush terminates the encoding (FLUSH, E.2.9) and returns the encoded bytes, terminated with
+// the 0xFFAC marker.
+func (e *arithmeticEncoder) flush() []byte {
+	// SETBITS.
+	temp := e.c + e.a
+	e.c |= 0xFFFF
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	if e.out[len(e.out)-1] != 0xFF {
+		e.out = append(e.out, 0xFF)
+	}
+	e.out = append(e.out, 0xAC)
+	return e.out[1:]
+}
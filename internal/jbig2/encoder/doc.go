@@ -4,4 +4,5 @@
  */
 
 // Package encoder contains jbig2 encoder structures. (WIP)
+// Only the lossless encoding of single images as arithmetically coded generic regions is supported.
 package encoder
@@ -0,0 +1,102 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Segment types (7.3).
+const (
+	segmentImmediateGenericRegion = 38
+	segmentPageInformation        = 48
+)
+
+// template0AT are the nominal adaptive template pixels of generic region template 0 (6.2.5.3).
+var template0AT = [4][2]int{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}
+
+// EncodeGeneric encodes the bilevel image of `width` x `height` pixels `data`, with rows padded to
+// byte boundaries and 1 bits for the black pixels, as a JBIG2 stream embedded in a PDF file:
+// a page information segment followed by an immediate generic region segment, arithmetically
+// coded with template 0. The file header and end of page segment are not written (PDF 7.4.7).
+func EncodeGeneric(data []byte, width, height int) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid image size")
+	}
+	rowStride := (width + 7) / 8
+	if len(data) < rowStride*height {
+		return nil, errors.New("image data too short")
+	}
+
+	// Page information segment (7.4.8): size, unknown resolution, flags and no striping.
+	page := make([]byte, 19)
+	binary.BigEndian.PutUint32(page[0:], uint32(width))
+	binary.BigEndian.PutUint32(page[4:], uint32(height))
+
+	// Generic region segment (7.4.6): region segment information (7.4.1), flags with template 0
+	// and no typical prediction, the adaptive template pixels and the coded data.
+	region := make([]byte, 17, 26)
+	binary.BigEndian.PutUint32(region[0:], uint32(width))
+	binary.BigEndian.PutUint32(region[4:], uint32(height))
+	region = append(region, 0)
+	for _, at := range template0AT {
+		region = append(region, byte(int8(at[0])), byte(int8(at[1])))
+	}
+	region = append(region, encodeGenericRegion(data, width, height, rowStride)...)
+
+	out := appendSegment(nil, 0, segmentPageInformation, page)
+	return appendSegment(out, 1, segmentImmediateGenericRegion, region), nil
+}
+
+// appendSegment appends to `out` the segment of number `number`, type `typ` and data `data`
+// associated with page 1, without referred-to segments (7.2).
+func appendSegment(out []byte, number uint32, typ byte, data []byte) []byte {
+	var header [11]byte
+	binary.BigEndian.PutUint32(header[0:], number)
+	header[4] = typ
+	header[5] = 0 // No referred-to segment.
+	header[6] = 1 // Page association.
+	binary.BigEndian.PutUint32(header[7:], uint32(len(data)))
+	out = append(out, header[:]...)
+	return append(out, data...)
+}
+
+// encodeGenericRegion returns the arithmetic coded pixels of generic region `data` with template 0
+// (6.2.5.7).
+func encodeGenericRegion(data []byte, width, height, rowStride int) []byte {
+	pixel := func(x, y int) int {
+		if x < 0 || x >= width || y < 0 {
+			return 0
+		}
+		return int(data[y*rowStride+x/8]>>uint(7-x%8)) & 1
+	}
+
+	e := newArithmeticEncoder(1 << 16)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Context of template 0 (Figure 3), with the nominal adaptive template pixels.
+			cx := pixel(x-1, y) |
+				pixel(x-2, y)<<1 |
+				pixel(x-3, y)<<2 |
+				pixel(x-4, y)<<3 |
+				pixel(x+template0AT[0][0], y+template0AT[0][1])<<4 |
+				pixel(x+2, y-1)<<5 |
+				pixel(x+1, y-1)<<6 |
+				pixel(x, y-1)<<7 |
+				pixel(x-1, y-1)<<8 |
+				pixel(x-2, y-1)<<9 |
+				pixel(x+template0AT[1][0], y+template0AT[1][1])<<10 |
+				pixel(x+template0AT[2][0], y+template0AT[2][1])<<11 |
+				pixel(x+1, y-2)<<12 |
+				pixel(x, y-2)<<13 |
+				pixel(x-1, y-2)<<14 |
+				pixel(x+template0AT[3][0], y+template0AT[3][1])<<15
+			e.encodeBit(cx, uint8(pixel(x, y)))
+		}
+	}
+	return e.flush()
+}
@@ -0,0 +1,68 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/internal/jbig2"
+)
+
+// decodeGeneric decodes the page of JBIG2 stream `encoded` with the jbig2 decoder.
+func decodeGeneric(t *testing.T, encoded []byte) []byte {
+	doc, err := jbig2.NewDocument(encoded)
+	require.NoError(t, err)
+	page, err := doc.GetPage(1)
+	require.NoError(t, err)
+	bm, err := page.GetBitmap()
+	require.NoError(t, err)
+	return bm.GetChocolateData()
+}
+
+func TestEncodeGeneric(t *testing.T) {
+	// 37x29 image with a frame, a diagonal and a few random pixels.
+	width, height := 37, 29
+	rowStride := (width + 7) / 8
+	data := make([]byte, rowStride*height)
+	setPixel := func(x, y int) {
+		data[y*rowStride+x/8] |= 0x80 >> uint(x%8)
+	}
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		setPixel(0, y)
+		setPixel(width-1, y)
+		setPixel(y, y)
+		setPixel(r.Intn(width), y)
+	}
+	for x := 0; x < width; x++ {
+		setPixel(x, 0)
+		setPixel(x, height-1)
+	}
+
+	encoded, err := EncodeGeneric(data, width, height)
+	require.NoError(t, err)
+	require.Equal(t, data, decodeGeneric(t, encoded))
+
+	// Noise.
+	noise := make([]byte, 25*120)
+	r.Read(noise)
+	encoded, err = EncodeGeneric(noise, 200, 120)
+	require.NoError(t, err)
+	require.Equal(t, noise, decodeGeneric(t, encoded))
+
+	// Blank images are coded in a few bytes.
+	blank := make([]byte, 100*1000)
+	encoded, err = EncodeGeneric(blank, 800, 1000)
+	require.NoError(t, err)
+	require.True(t, len(encoded) < 100, "size: %d", len(encoded))
+	require.Equal(t, blank, decodeGeneric(t, encoded))
+
+	_, err = EncodeGeneric(data, width, height+1)
+	require.Error(t, err)
+}
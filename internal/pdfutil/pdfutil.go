@@ -0,0 +1,44 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pdfutil provides helpers for the packages which modify the objects of documents in
+// place.
+package pdfutil
+
+import (
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// ObjectSet is a set of objects, used to process the objects shared by several pages or
+// resources only once.
+type ObjectSet map[core.PdfObject]struct{}
+
+// Visit returns true if `obj` is already in the set, and adds it to the set otherwise.
+// A nil object is never in the set.
+func (s ObjectSet) Visit(obj core.PdfObject) bool {
+	if obj == nil {
+		return false
+	}
+	if _, ok := s[obj]; ok {
+		return true
+	}
+	s[obj] = struct{}{}
+	return false
+}
+
+// SetStreamData replaces the data of `stream` with `data`, encoded with the Flate filter.
+func SetStreamData(stream *core.PdfObjectStream, data []byte) error {
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(data)
+	if err != nil {
+		return err
+	}
+	stream.Remove("DecodeParms")
+	stream.Remove("DL")
+	stream.Set("Filter", core.MakeName(encoder.GetFilterName()))
+	stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+	stream.Stream = encoded
+	return nil
+}
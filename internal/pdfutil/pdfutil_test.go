@@ -0,0 +1,37 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestObjectSet(t *testing.T) {
+	s := ObjectSet{}
+	obj := core.MakeDict()
+	require.False(t, s.Visit(obj))
+	require.True(t, s.Visit(obj))
+	require.False(t, s.Visit(core.MakeDict()))
+	require.False(t, s.Visit(nil))
+	require.False(t, s.Visit(nil))
+}
+
+func TestSetStreamData(t *testing.T) {
+	stream, err := core.MakeStream([]byte("old"), core.NewRawEncoder())
+	require.NoError(t, err)
+	stream.Set("DecodeParms", core.MakeDict())
+	require.NoError(t, SetStreamData(stream, []byte("0 0 m 10 10 l S")))
+	require.Nil(t, stream.Get("DecodeParms"))
+	require.Equal(t, core.MakeName(core.StreamEncodingFilterNameFlate), stream.Get("Filter"))
+
+	data, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+	require.Equal(t, "0 0 m 10 10 l S", string(data))
+}
@@ -0,0 +1,26 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sampling
+
+// MaxColorCacheSize is the maximum number of entries of the caches of converted colors keyed by
+// ColorKey. The images with few distinct colors, e.g. drawings and scans of documents, have most
+// of their colors cached, while the caches of photographs, whose pixels have mostly distinct
+// colors, stop growing once full.
+const MaxColorCacheSize = 1 << 16
+
+// ColorKey returns the key of the color of the samples `pixel` of an image pixel, of up to 16
+// bits, in the caches of converted colors, which hold at most MaxColorCacheSize colors.
+// The bool return flag is false if the color has more than 4 components and cannot be cached.
+func ColorKey(pixel []uint32) (uint64, bool) {
+	if len(pixel) > 4 {
+		return 0, false
+	}
+	var key uint64
+	for _, s := range pixel {
+		key = key<<16 | uint64(s&0xffff)
+	}
+	return key, true
+}
@@ -164,3 +164,16 @@ func TestResamplingUint32xx(t *testing.T) {
 		}
 	}
 }
+
+func TestColorKey(t *testing.T) {
+	key, ok := ColorKey([]uint32{1, 2, 3})
+	if !ok || key != 0x000100020003 {
+		t.Fatalf("ColorKey: %x %v", key, ok)
+	}
+	if key2, _ := ColorKey([]uint32{1, 2, 4}); key2 == key {
+		t.Fatalf("ColorKey: same key for different colors")
+	}
+	if _, ok := ColorKey([]uint32{1, 2, 3, 4, 5}); ok {
+		t.Fatalf("ColorKey: key for 5 components")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pdftest provides the document fixtures shared by the tests of the packages built on
+// package model. It is separate from package testutils, which the tests of package model import.
+package pdftest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// NewPage returns a page of size `width`x`height` with Helvetica as font F1 and content stream
+// `content`.
+func NewPage(t *testing.T, width, height float64, content string) *model.PdfPage {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: width, Ury: height}
+	page.Resources = model.NewPdfPageResources()
+
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+	return page
+}
+
+// Write writes `pages` to a new document and returns the document data.
+func Write(t *testing.T, pages ...*model.PdfPage) []byte {
+	w := model.NewPdfWriter()
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	return buf.Bytes()
+}
+
+// Read returns the reader of document `data`.
+func Read(t *testing.T, data []byte) *model.PdfReader {
+	r, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	return r
+}
+
+// WriteRead writes `pages` to a new document and returns the reader of the document.
+func WriteRead(t *testing.T, pages ...*model.PdfPage) *model.PdfReader {
+	return Read(t, Write(t, pages...))
+}
+
+// ReadPages returns the pages of the document read by `r`.
+func ReadPages(t *testing.T, r *model.PdfReader) []*model.PdfPage {
+	n, err := r.GetNumPages()
+	require.NoError(t, err)
+	pages := make([]*model.PdfPage, n)
+	for i := range pages {
+		pages[i], err = r.GetPage(i + 1)
+		require.NoError(t, err)
+	}
+	return pages
+}
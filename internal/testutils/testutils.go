@@ -9,6 +9,9 @@ package testutils
 import (
 	"errors"
 	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
@@ -159,3 +162,18 @@ func CompareDictionariesDeep(d1, d2 *core.PdfObjectDictionary) bool {
 
 	return true
 }
+
+// Bounder is implemented by the paths providing their bounding box, such as glyph outlines.
+type Bounder interface {
+	Bounds() (llx, lly, urx, ury float64, ok bool)
+}
+
+// RequireBounds checks that the bounds of `path` are within `delta` of `expected`
+// ([llx lly urx ury]).
+func RequireBounds(t *testing.T, path Bounder, expected [4]float64, delta float64) {
+	llx, lly, urx, ury, ok := path.Bounds()
+	require.True(t, ok)
+	for i, v := range []float64{llx, lly, urx, ury} {
+		require.InDelta(t, expected[i], v, delta, "bounds=%v", []float64{llx, lly, urx, ury})
+	}
+}
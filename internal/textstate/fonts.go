@@ -0,0 +1,61 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package textstate
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// FontCache caches the fonts loaded from font dictionaries, which may be shared by the resources
+// of several content streams.
+type FontCache map[core.PdfObject]*model.PdfFont
+
+// Get returns the font named `name` in `resources`, or nil if it is not found or cannot be
+// loaded.
+func (fc FontCache) Get(resources *model.PdfPageResources, name core.PdfObjectName) *model.PdfFont {
+	var obj core.PdfObject
+	if resources != nil {
+		obj, _ = resources.GetFontByName(name)
+	}
+	if obj == nil {
+		common.Log.Debug("ERROR: Font %s not found", name)
+		return nil
+	}
+	return fc.Load(obj)
+}
+
+// Load returns the font of font dictionary `obj`, or nil if it cannot be loaded.
+func (fc FontCache) Load(obj core.PdfObject) *model.PdfFont {
+	if font, ok := fc[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load font: %v", err)
+		font = nil
+	}
+	fc[obj] = font
+	return font
+}
+
+// SetExtGStateFont sets the font and the font size of the text state from the Font entry of
+// graphics state parameter dictionary `dict`, loading the font with `fonts`. Returns true if the
+// entry is set.
+func (ts *State) SetExtGStateFont(dict *core.PdfObjectDictionary, fonts FontCache) bool {
+	arr, ok := core.GetArray(dict.Get("Font"))
+	if !ok || arr.Len() != 2 {
+		return false
+	}
+	size, err := core.GetNumberAsFloat(core.ResolveReference(arr.Get(1)))
+	if err != nil {
+		return false
+	}
+	ts.Font = fonts.Load(arr.Get(0))
+	ts.FontSize = size
+	return true
+}
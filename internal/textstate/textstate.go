@@ -0,0 +1,255 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package textstate tracks the text state and the text matrices of content streams and lays out
+// the glyphs shown by the text showing operators (section 9.3 and 9.4 PDF32000_2008).
+package textstate
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// glyphSpace maps the glyph space, in thousandths of text space units, to the text space.
+var glyphSpace = transform.NewMatrix(0.001, 0, 0, 0.001, 0, 0)
+
+// State is the text state of the graphics state (section 9.3 p. 243 PDF32000_2008).
+type State struct {
+	Font        *model.PdfFont
+	FontSize    float64
+	CharSpacing float64
+	WordSpacing float64
+	Scaling     float64 // Horizontal scaling in percent.
+	Leading     float64
+	Rise        float64
+	RenderMode  int
+}
+
+// New returns the initial text state.
+func New() State {
+	return State{Scaling: 100}
+}
+
+// PaintModes returns whether the glyphs are filled, stroked and added to the clipping path with
+// the text rendering mode of the text state (Table 106 p. 254 PDF32000_2008).
+func (ts *State) PaintModes() (fill, stroke, clip bool) {
+	switch ts.RenderMode {
+	case 0, 4:
+		fill = true
+	case 1, 5:
+		stroke = true
+	case 2, 6:
+		fill, stroke = true, true
+	}
+	clip = ts.RenderMode >= 4 && ts.RenderMode <= 7
+	return fill, stroke, clip
+}
+
+// Set updates the text state with the text state operation `op` (Tc, Tw, Tz, TL, Ts, Tr or Tf),
+// loading the fonts with `getFont`. Returns false if `op` is not a text state operation.
+func (ts *State) Set(op *contentstream.ContentStreamOperation,
+	getFont func(name core.PdfObjectName) *model.PdfFont) bool {
+	switch op.Operand {
+	case "Tc", "Tw", "Tz", "TL", "Ts", "Tr":
+	case "Tf":
+		if len(op.Params) != 2 {
+			return true
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			return true
+		}
+		size, err := core.GetNumberAsFloat(op.Params[1])
+		if err != nil {
+			return true
+		}
+		ts.Font = getFont(*name)
+		ts.FontSize = size
+		return true
+	default:
+		return false
+	}
+
+	f, ok := FloatParams(op, 1)
+	if !ok {
+		return true
+	}
+	switch op.Operand {
+	case "Tc":
+		ts.CharSpacing = f[0]
+	case "Tw":
+		ts.WordSpacing = f[0]
+	case "Tz":
+		ts.Scaling = f[0]
+	case "TL":
+		ts.Leading = f[0]
+	case "Ts":
+		ts.Rise = f[0]
+	case "Tr":
+		ts.RenderMode = int(f[0])
+	}
+	return true
+}
+
+// Object is the state of a text object: the text matrix and the text line matrix.
+type Object struct {
+	Tm, Tlm transform.Matrix
+}
+
+// Begin starts a text object.
+func (to *Object) Begin() {
+	to.Tm = transform.IdentityMatrix()
+	to.Tlm = transform.IdentityMatrix()
+}
+
+// Handle updates the text state `ts` and the text matrices with the text operation `op`, loading
+// the fonts with `getFont`. It returns the text operands to show (strings and positioning
+// adjustments as for TJ) and true if `op` shows text.
+func (to *Object) Handle(op *contentstream.ContentStreamOperation, ts *State,
+	getFont func(name core.PdfObjectName) *model.PdfFont) ([]core.PdfObject, bool) {
+	if ts.Set(op, getFont) {
+		return nil, false
+	}
+	switch op.Operand {
+	case "Td", "TD":
+		if f, ok := FloatParams(op, 2); ok {
+			if op.Operand == "TD" {
+				ts.Leading = -f[1]
+			}
+			to.Tlm.Concat(transform.TranslationMatrix(f[0], f[1]))
+			to.Tm = to.Tlm
+		}
+	case "Tm":
+		if f, ok := FloatParams(op, 6); ok {
+			to.Tlm = transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5])
+			to.Tm = to.Tlm
+		}
+	case "T*":
+		to.NextLine(ts)
+	case "Tj":
+		if len(op.Params) == 1 {
+			return op.Params, true
+		}
+	case "TJ":
+		if len(op.Params) == 1 {
+			if arr, ok := core.GetArray(op.Params[0]); ok {
+				return arr.Elements(), true
+			}
+		}
+	case "'":
+		if len(op.Params) == 1 {
+			to.NextLine(ts)
+			return op.Params, true
+		}
+	case `"`:
+		if len(op.Params) != 3 {
+			return nil, false
+		}
+		if f, err := core.GetNumbersAsFloat(op.Params[:2]); err == nil {
+			ts.WordSpacing, ts.CharSpacing = f[0], f[1]
+		}
+		to.NextLine(ts)
+		return op.Params[2:], true
+	}
+	return nil, false
+}
+
+// NextLine moves to the start of the next text line.
+func (to *Object) NextLine(ts *State) {
+	to.Tlm.Concat(transform.TranslationMatrix(0, -ts.Leading))
+	to.Tm = to.Tlm
+}
+
+// Glyph is a glyph shown by a text showing operation.
+type Glyph struct {
+	Code textencoding.CharCode
+	// Data are the bytes of the character code in the shown string.
+	Data []byte
+	// Width is the horizontal displacement of the glyph in glyph space.
+	Width float64
+	// Advance is the horizontal displacement of the text matrix in text space, including the
+	// character and word spacing.
+	Advance float64
+	// Matrix maps the glyph space to the user space.
+	Matrix transform.Matrix
+}
+
+// Show shows the text operands `args` (strings and positioning adjustments as for TJ) with text
+// state `ts`, calling `glyph` for each glyph before moving the text matrix past it and `adjust`,
+// if not nil, for each positioning adjustment. Nothing is shown if the font is not set.
+func (to *Object) Show(args []core.PdfObject, ts *State, glyph func(g Glyph), adjust func(n float64)) {
+	font := ts.Font
+	if font == nil {
+		return
+	}
+	fs := ts.FontSize
+	th := ts.Scaling / 100
+	stateMatrix := transform.NewMatrix(fs*th, 0, 0, fs, 0, ts.Rise)
+	codeLen := 1
+	if font.IsCID() {
+		codeLen = 2
+	}
+
+	for _, arg := range args {
+		if n, err := core.GetNumberAsFloat(arg); err == nil {
+			to.Tm.Concat(transform.TranslationMatrix(-n/1000*fs*th, 0))
+			if adjust != nil {
+				adjust(n)
+			}
+			continue
+		}
+		str, ok := core.GetString(arg)
+		if !ok {
+			continue
+		}
+		data := str.Bytes()
+		for i, code := range font.BytesToCharcodes(data) {
+			start := i * codeLen
+			end := start + codeLen
+			if end > len(data) {
+				end = len(data)
+			}
+
+			w0 := 0.0
+			if m, ok := font.GetCharMetrics(code); ok {
+				w0 = m.Wx
+			}
+			wordSpacing := 0.0
+			if codeLen == 1 && code == 32 {
+				wordSpacing = ts.WordSpacing
+			}
+			tx := (w0/1000*fs + ts.CharSpacing + wordSpacing) * th
+
+			// The glyph space is mapped to the user space by the glyph space scaling, the text
+			// state parameters and the text matrix.
+			glyph(Glyph{
+				Code:    code,
+				Data:    data[start:end],
+				Width:   w0,
+				Advance: tx,
+				Matrix:  to.Tm.Mult(stateMatrix).Mult(glyphSpace),
+			})
+			to.Tm.Concat(transform.TranslationMatrix(tx, 0))
+		}
+	}
+}
+
+// FloatParams returns the `n` number parameters of `op`, or false if they are invalid.
+func FloatParams(op *contentstream.ContentStreamOperation, n int) ([]float64, bool) {
+	if len(op.Params) != n {
+		common.Log.Debug("ERROR: Invalid number of parameters for %s: %d", op.Operand, len(op.Params))
+		return nil, false
+	}
+	f, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid parameters for %s: %v", op.Operand, err)
+		return nil, false
+	}
+	return f, true
+}
@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package textstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestShow(t *testing.T) {
+	font, err := model.NewStandard14Font(model.CourierName)
+	require.NoError(t, err)
+	getFont := func(name core.PdfObjectName) *model.PdfFont {
+		return font
+	}
+	ops, err := contentstream.NewContentStreamParser("10 Tf 2 Tc 50 Tz 10 20 Td 5 TL T* [(A) -1000 (B)] TJ").Parse()
+	require.NoError(t, err)
+
+	ts := New()
+	var to Object
+	to.Begin()
+	var glyphs []Glyph
+	var adjustments []float64
+	for _, op := range *ops {
+		if op.Operand == "Tf" {
+			op.Params = append([]core.PdfObject{core.MakeName("F1")}, op.Params...)
+		}
+		args, show := to.Handle(op, &ts, getFont)
+		if show {
+			to.Show(args, &ts, func(g Glyph) {
+				glyphs = append(glyphs, g)
+			}, func(n float64) {
+				adjustments = append(adjustments, n)
+			})
+		}
+	}
+
+	// The advance is (w/1000*10 + 2) * 0.5 and the adjustment moves 1000/1000*10*0.5 = 5 to the
+	// right.
+	metrics, ok := font.GetCharMetrics('A')
+	require.True(t, ok)
+	w := metrics.Wx
+	advance := (w/1000*10 + 2) * 0.5
+	require.Len(t, glyphs, 2)
+	require.Equal(t, []float64{-1000}, adjustments)
+	require.Equal(t, []byte("A"), glyphs[0].Data)
+	require.Equal(t, w, glyphs[0].Width)
+	require.InDelta(t, advance, glyphs[0].Advance, 1e-9)
+	x, y := glyphs[0].Matrix.Transform(0, 0)
+	require.InDelta(t, 10, x, 1e-9)
+	require.InDelta(t, 15, y, 1e-9)
+	x, _ = glyphs[1].Matrix.Transform(0, 0)
+	require.InDelta(t, 10+advance+5, x, 1e-9)
+	x, _ = glyphs[0].Matrix.Transform(1000, 0)
+	require.InDelta(t, 15, x, 1e-9)
+}
+
+func TestSetExtGStateFont(t *testing.T) {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	fontObj := font.ToPdfObject()
+
+	fonts := FontCache{}
+	dict := core.MakeDict()
+	ts := New()
+	require.False(t, ts.SetExtGStateFont(dict, fonts))
+
+	dict.Set("Font", core.MakeArray(fontObj, core.MakeInteger(12)))
+	require.True(t, ts.SetExtGStateFont(dict, fonts))
+	require.NotNil(t, ts.Font)
+	require.Equal(t, 12.0, ts.FontSize)
+	require.Equal(t, ts.Font, fonts.Load(fontObj))
+
+	resources := model.NewPdfPageResources()
+	require.NoError(t, resources.SetFontByName("F1", fontObj))
+	require.Equal(t, ts.Font, fonts.Get(resources, "F1"))
+	require.Nil(t, fonts.Get(resources, "F2"))
+}
+
+func TestPaintModes(t *testing.T) {
+	expected := [][3]bool{
+		{true, false, false}, {false, true, false}, {true, true, false}, {false, false, false},
+		{true, false, true}, {false, true, true}, {true, true, true}, {false, false, true},
+	}
+	for mode, modes := range expected {
+		ts := State{RenderMode: mode}
+		fill, stroke, clip := ts.PaintModes()
+		require.Equal(t, modes, [3]bool{fill, stroke, clip}, "mode %d", mode)
+	}
+}
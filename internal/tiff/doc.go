@@ -0,0 +1,11 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package tiff reads the pages (image file directories) of multi-page TIFF files, as described
+// in the TIFF 6.0 specification. The strips of the pages compressed with the CCITT schemes are
+// kept compressed so that they can be embedded in PDF files as is; the other strips are
+// decompressed (LZW, Deflate and PackBits compression) on demand. Tiled images and JPEG
+// compression are not supported.
+package tiff
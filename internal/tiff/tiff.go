@@ -0,0 +1,470 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package tiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/bits"
+
+	"golang.org/x/image/tiff/lzw"
+)
+
+// Compression schemes.
+const (
+	CompressionNone        = 1
+	CompressionCCITTRLE    = 2 // CCITT modified Huffman run length encoding.
+	CompressionCCITTGroup3 = 3
+	CompressionCCITTGroup4 = 4
+	CompressionLZW         = 5
+	CompressionDeflate     = 8
+	CompressionPackBits    = 32773
+	CompressionDeflateOld  = 32946
+)
+
+// Photometric interpretations.
+const (
+	PhotometricWhiteIsZero = 0
+	PhotometricBlackIsZero = 1
+	PhotometricRGB         = 2
+	PhotometricPalette     = 3
+	PhotometricSeparated   = 5
+)
+
+// Extra sample types.
+const (
+	ExtraSampleUnspecified     = 0
+	ExtraSampleAssociatedAlpha = 1 // Alpha premultiplied with the color samples.
+	ExtraSampleUnassociated    = 2
+)
+
+// T4Options flags.
+const (
+	T4Option2D       = 1
+	T4OptionFillBits = 4
+)
+
+// Tags.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagFillOrder       = 266
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagXResolution     = 282
+	tagYResolution     = 283
+	tagPlanarConfig    = 284
+	tagT4Options       = 292
+	tagResolutionUnit  = 296
+	tagPredictor       = 317
+	tagColorMap        = 320
+	tagTileWidth       = 322
+	tagInkSet          = 332
+	tagExtraSamples    = 338
+)
+
+// Field types.
+const (
+	typeByte     = 1
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// maxPages is the maximum number of pages read, against loops of image file directories.
+const maxPages = 10000
+
+// ErrUnsupported is returned for the TIFF features that are not supported.
+var ErrUnsupported = errors.New("unsupported TIFF image")
+
+// Page is a page (image file directory) of a TIFF file.
+type Page struct {
+	Width, Height   int
+	BitsPerSample   int
+	SamplesPerPixel int
+	Photometric     int
+	Compression     int
+	Predictor       int
+	T4Options       uint32
+	InkSet          int
+	ExtraSamples    []int
+
+	// ColorMap is the palette of the palette color images: the red, then green, then blue
+	// values of the colors, as 16 bit values.
+	ColorMap []uint16
+
+	// XResolution and YResolution are the resolution in pixels per inch, 0 if not specified.
+	XResolution, YResolution float64
+
+	// Strips are the compressed data of the strips of RowsPerStrip rows, with the bits of their
+	// bytes in the most significant bit first fill order.
+	Strips       [][]byte
+	RowsPerStrip int
+
+	bigEndian bool
+}
+
+// Decode returns the pages of TIFF file `data`.
+func Decode(data []byte) ([]*Page, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid TIFF header")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF byte order")
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, errors.New("invalid TIFF version")
+	}
+
+	d := &decoder{data: data, order: order}
+	var pages []*Page
+	visited := map[uint32]bool{}
+	offset := order.Uint32(data[4:8])
+	for offset != 0 && len(pages) < maxPages {
+		if visited[offset] {
+			break
+		}
+		visited[offset] = true
+		page, next, err := d.readPage(offset)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", len(pages)+1, err)
+		}
+		pages = append(pages, page)
+		offset = next
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("no TIFF image")
+	}
+	return pages, nil
+}
+
+// decoder reads the image file directories of a TIFF file.
+type decoder struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// field is an entry of an image file directory.
+type field struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// readPage reads the page of the image file directory at `offset`. Returns the offset of the next
+// image file directory.
+func (d *decoder) readPage(offset uint32) (*Page, uint32, error) {
+	if int64(offset)+2 > int64(len(d.data)) {
+		return nil, 0, errors.New("invalid directory offset")
+	}
+	n := int(d.order.Uint16(d.data[offset:]))
+	start := int(offset) + 2
+	if start+12*n+4 > len(d.data) {
+		return nil, 0, errors.New("invalid directory")
+	}
+	fields := map[uint16]field{}
+	for i := 0; i < n; i++ {
+		entry := d.data[start+12*i : start+12*i+12]
+		tag := d.order.Uint16(entry)
+		f := field{typ: d.order.Uint16(entry[2:]), count: d.order.Uint32(entry[4:])}
+		size := typeSize(f.typ) * int64(f.count)
+		if size <= 4 {
+			f.value = entry[8 : 8+size]
+		} else {
+			valueOffset := int64(d.order.Uint32(entry[8:]))
+			if valueOffset+size > int64(len(d.data)) {
+				return nil, 0, fmt.Errorf("invalid value of tag %d", tag)
+			}
+			f.value = d.data[valueOffset : valueOffset+size]
+		}
+		fields[tag] = f
+	}
+	next := d.order.Uint32(d.data[start+12*n:])
+
+	if _, ok := fields[tagTileWidth]; ok {
+		return nil, 0, fmt.Errorf("%v: tiled image", ErrUnsupported)
+	}
+	page := &Page{
+		Width:           d.uint(fields, tagImageWidth, 0),
+		Height:          d.uint(fields, tagImageLength, 0),
+		BitsPerSample:   d.uint(fields, tagBitsPerSample, 1),
+		SamplesPerPixel: d.uint(fields, tagSamplesPerPixel, 1),
+		Photometric:     d.uint(fields, tagPhotometric, PhotometricWhiteIsZero),
+		Compression:     d.uint(fields, tagCompression, CompressionNone),
+		Predictor:       d.uint(fields, tagPredictor, 1),
+		T4Options:       uint32(d.uint(fields, tagT4Options, 0)),
+		InkSet:          d.uint(fields, tagInkSet, 1),
+		bigEndian:       d.order == binary.BigEndian,
+	}
+	if page.Width <= 0 || page.Height <= 0 {
+		return nil, 0, errors.New("invalid image size")
+	}
+	page.RowsPerStrip = d.uint(fields, tagRowsPerStrip, page.Height)
+	if page.RowsPerStrip <= 0 || page.RowsPerStrip > page.Height {
+		page.RowsPerStrip = page.Height
+	}
+	if d.uint(fields, tagPlanarConfig, 1) != 1 {
+		return nil, 0, fmt.Errorf("%v: planar configuration", ErrUnsupported)
+	}
+	for _, v := range d.uints(fields[tagExtraSamples]) {
+		page.ExtraSamples = append(page.ExtraSamples, int(v))
+	}
+	for _, v := range d.uints(fields[tagColorMap]) {
+		page.ColorMap = append(page.ColorMap, uint16(v))
+	}
+
+	// Resolution in pixels per inch.
+	scale := 1.0
+	switch d.uint(fields, tagResolutionUnit, 2) {
+	case 1:
+		scale = 0
+	case 3:
+		scale = 2.54
+	}
+	page.XResolution = d.rational(fields[tagXResolution]) * scale
+	page.YResolution = d.rational(fields[tagYResolution]) * scale
+
+	// Strips.
+	offsets := d.uints(fields[tagStripOffsets])
+	counts := d.uints(fields[tagStripByteCounts])
+	numStrips := (page.Height + page.RowsPerStrip - 1) / page.RowsPerStrip
+	if len(offsets) < numStrips || len(counts) < numStrips {
+		return nil, 0, errors.New("missing strips")
+	}
+	reverse := d.uint(fields, tagFillOrder, 1) == 2
+	for i := 0; i < numStrips; i++ {
+		off, count := int64(offsets[i]), int64(counts[i])
+		if off+count > int64(len(d.data)) {
+			return nil, 0, errors.New("invalid strip")
+		}
+		strip := d.data[off : off+count]
+		if reverse {
+			reversed := make([]byte, len(strip))
+			for j, b := range strip {
+				reversed[j] = bits.Reverse8(b)
+			}
+			strip = reversed
+		}
+		page.Strips = append(page.Strips, strip)
+	}
+	return page, next, nil
+}
+
+// typeSize returns the size of the values of field type `typ`.
+func typeSize(typ uint16) int64 {
+	switch typ {
+	case typeShort, 8:
+		return 2
+	case typeLong, 9, 11:
+		return 4
+	case typeRational, 10, 12:
+		return 8
+	}
+	return 1
+}
+
+// uints returns the integer values of `f`.
+func (d *decoder) uints(f field) []uint32 {
+	vals := make([]uint32, 0, f.count)
+	for i := 0; i < int(f.count); i++ {
+		switch f.typ {
+		case typeByte:
+			vals = append(vals, uint32(f.value[i]))
+		case typeShort:
+			vals = append(vals, uint32(d.order.Uint16(f.value[2*i:])))
+		case typeLong:
+			vals = append(vals, d.order.Uint32(f.value[4*i:]))
+		default:
+			return nil
+		}
+	}
+	return vals
+}
+
+// uint returns the first integer value of tag `tag` in `fields`, or `def` if it is missing.
+func (d *decoder) uint(fields map[uint16]field, tag uint16, def int) int {
+	vals := d.uints(fields[tag])
+	if len(vals) == 0 {
+		return def
+	}
+	return int(vals[0])
+}
+
+// rational returns the first rational value of `f`, or 0 if it is missing.
+func (d *decoder) rational(f field) float64 {
+	if f.typ != typeRational || f.count == 0 {
+		return 0
+	}
+	num, den := d.order.Uint32(f.value), d.order.Uint32(f.value[4:])
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// IsCCITT returns true if the page is compressed with a CCITT scheme.
+func (p *Page) IsCCITT() bool {
+	switch p.Compression {
+	case CompressionCCITTRLE, CompressionCCITTGroup3, CompressionCCITTGroup4:
+		return true
+	}
+	return false
+}
+
+// StripRows returns the number of rows of strip `i`.
+func (p *Page) StripRows(i int) int {
+	rows := p.Height - i*p.RowsPerStrip
+	if rows > p.RowsPerStrip {
+		rows = p.RowsPerStrip
+	}
+	return rows
+}
+
+// RowBytes returns the number of bytes of the rows of samples of the page.
+func (p *Page) RowBytes() int {
+	return (p.Width*p.SamplesPerPixel*p.BitsPerSample + 7) / 8
+}
+
+// Samples returns the decompressed samples of the page, row by row, with the rows padded to
+// byte boundaries and 16 bit samples in big endian order. The pages compressed with the CCITT
+// schemes are not supported.
+func (p *Page) Samples() ([]byte, error) {
+	if p.IsCCITT() {
+		return nil, fmt.Errorf("%v: CCITT compression", ErrUnsupported)
+	}
+	switch p.BitsPerSample {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("%v: %d bits per sample", ErrUnsupported, p.BitsPerSample)
+	}
+
+	rowBytes := p.RowBytes()
+	data := make([]byte, 0, rowBytes*p.Height)
+	for i, strip := range p.Strips {
+		size := rowBytes * p.StripRows(i)
+		decompressed, err := p.decompress(strip, size)
+		if err != nil {
+			return nil, err
+		}
+		if len(decompressed) < size {
+			// Missing data are left blank.
+			decompressed = append(decompressed, make([]byte, size-len(decompressed))...)
+		}
+		data = append(data, decompressed[:size]...)
+	}
+
+	switch p.Predictor {
+	case 1:
+	case 2:
+		p.undoPredictor(data)
+	default:
+		return nil, fmt.Errorf("%v: predictor %d", ErrUnsupported, p.Predictor)
+	}
+	if p.BitsPerSample == 16 && !p.bigEndian {
+		for i := 0; i+1 < len(data); i += 2 {
+			data[i], data[i+1] = data[i+1], data[i]
+		}
+	}
+	return data, nil
+}
+
+// decompress returns the decompressed data of `strip`, of `size` bytes.
+func (p *Page) decompress(strip []byte, size int) ([]byte, error) {
+	switch p.Compression {
+	case CompressionNone:
+		return strip, nil
+	case CompressionLZW:
+		r := lzw.NewReader(bytes.NewReader(strip), lzw.MSB, 8)
+		defer r.Close()
+		return readAtMost(r, size)
+	case CompressionDeflate, CompressionDeflateOld:
+		r, err := zlib.NewReader(bytes.NewReader(strip))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readAtMost(r, size)
+	case CompressionPackBits:
+		return unpackBits(strip, size), nil
+	}
+	return nil, fmt.Errorf("%v: compression %d", ErrUnsupported, p.Compression)
+}
+
+// readAtMost reads up to `size` bytes of `r`. The data read before an error are returned if the
+// strip is truncated.
+func readAtMost(r io.Reader, size int) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil && len(data) == 0 {
+		return nil, err
+	}
+	return data, nil
+}
+
+// unpackBits returns the up to `size` bytes of the PackBits compressed data `data`.
+func unpackBits(data []byte, size int) []byte {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(data) && len(out) < size; {
+		n := int(int8(data[i]))
+		i++
+		switch {
+		case n >= 0:
+			end := i + n + 1
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		case n != -128:
+			if i < len(data) {
+				out = append(out, bytes.Repeat(data[i:i+1], 1-n)...)
+			}
+			i++
+		}
+	}
+	if len(out) > size {
+		out = out[:size]
+	}
+	return out
+}
+
+// undoPredictor reverts the horizontal differencing of the rows of `data`.
+func (p *Page) undoPredictor(data []byte) {
+	rowBytes := p.RowBytes()
+	spp := p.SamplesPerPixel
+	for y := 0; y+1 <= len(data)/rowBytes; y++ {
+		row := data[y*rowBytes : (y+1)*rowBytes]
+		switch p.BitsPerSample {
+		case 8:
+			for i := spp; i < len(row); i++ {
+				row[i] += row[i-spp]
+			}
+		case 16:
+			var order binary.ByteOrder = binary.LittleEndian
+			if p.bigEndian {
+				order = binary.BigEndian
+			}
+			for i := 2 * spp; i+1 < len(row); i += 2 {
+				order.PutUint16(row[i:], order.Uint16(row[i:])+order.Uint16(row[i-2*spp:]))
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package tiff
+
+import (
+	"bytes"
+	"compress/lzw"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/internal/tiff/tifftest"
+)
+
+func TestDecodeMultiPage(t *testing.T) {
+	gray := []byte{0, 64, 128, 255, 10, 20, 30, 40}
+
+	// RGB with horizontal differencing, compressed with LZW. The code width does not change with so
+	// few codes, so that the early change of TIFF LZW does not matter.
+	rgb := []byte{10, 20, 30, 5, 5, 5, 1, 2, 3, 200, 100, 50}
+	var lzwData bytes.Buffer
+	w := lzw.NewWriter(&lzwData, lzw.MSB, 8)
+	w.Write(rgb)
+	w.Close()
+
+	// Palette, compressed with Deflate.
+	var zlibData bytes.Buffer
+	zw := zlib.NewWriter(&zlibData)
+	zw.Write([]byte{0x1b, 0xe4})
+	zw.Close()
+	colorMap := make([]uint16, 3*4)
+	for i := range colorMap {
+		colorMap[i] = uint16(i) << 8
+	}
+
+	data := tifftest.Encode(binary.BigEndian, []tifftest.Page{
+		{
+			Tags: map[uint16][]uint16{
+				tagImageWidth: {4}, tagImageLength: {2}, tagBitsPerSample: {8},
+				tagPhotometric: {PhotometricBlackIsZero}, tagRowsPerStrip: {1},
+			},
+			Strips: [][]byte{gray[:4], gray[4:]},
+		},
+		{
+			Tags: map[uint16][]uint16{
+				tagImageWidth: {2}, tagImageLength: {2}, tagBitsPerSample: {8, 8, 8},
+				tagSamplesPerPixel: {3}, tagPhotometric: {PhotometricRGB},
+				tagCompression: {CompressionLZW}, tagPredictor: {2},
+			},
+			Strips: [][]byte{lzwData.Bytes()},
+		},
+		{
+			Tags: map[uint16][]uint16{
+				tagImageWidth: {8}, tagImageLength: {1}, tagBitsPerSample: {2},
+				tagPhotometric: {PhotometricPalette}, tagCompression: {CompressionDeflate},
+				tagColorMap: colorMap,
+			},
+			Strips: [][]byte{zlibData.Bytes()},
+		},
+	})
+
+	pages, err := Decode(data)
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+
+	page := pages[0]
+	require.Equal(t, 4, page.Width)
+	require.Equal(t, 2, page.Height)
+	require.Len(t, page.Strips, 2)
+	require.InDelta(t, 365.76, page.XResolution, 1e-9)
+	samples, err := page.Samples()
+	require.NoError(t, err)
+	require.Equal(t, gray, samples)
+
+	samples, err = pages[1].Samples()
+	require.NoError(t, err)
+	require.Equal(t, []byte{10, 20, 30, 15, 25, 35, 1, 2, 3, 201, 102, 53}, samples)
+
+	page = pages[2]
+	require.Equal(t, PhotometricPalette, page.Photometric)
+	require.Equal(t, colorMap, page.ColorMap)
+	samples, err = page.Samples()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x1b, 0xe4}, samples)
+}
+
+func TestDecodeFillOrder(t *testing.T) {
+	// PackBits: 2 literal bytes then a run of 3 bytes.
+	packed := []byte{1, 0x80, 0x01, 0xfe, 0x0f}
+	data := tifftest.Encode(binary.LittleEndian, []tifftest.Page{{
+		Tags: map[uint16][]uint16{
+			tagImageWidth: {40}, tagImageLength: {1}, tagCompression: {CompressionPackBits},
+			tagFillOrder: {2},
+		},
+		Strips: [][]byte{packed},
+	}})
+
+	pages, err := Decode(data)
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	require.Equal(t, PhotometricWhiteIsZero, pages[0].Photometric)
+	// The bits of the strips are reversed to the most significant bit first order.
+	require.Equal(t, []byte{0x80, 0x01, 0x80, 0x7f, 0xf0}, pages[0].Strips[0])
+}
+
+func TestUnpackBits(t *testing.T) {
+	data := []byte{0xfe, 0xaa, 0x02, 0x80, 0x00, 0x2a, 0x80, 0xfd, 0xaa}
+	require.Equal(t, []byte{0xaa, 0xaa, 0xaa, 0x80, 0x00, 0x2a, 0xaa, 0xaa, 0xaa, 0xaa},
+		unpackBits(data, 10))
+	require.Equal(t, []byte{0xaa, 0xaa}, unpackBits(data, 2))
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := Decode([]byte("II*\x00"))
+	require.Error(t, err)
+	_, err = Decode([]byte("XX*\x00\x08\x00\x00\x00"))
+	require.Error(t, err)
+
+	// Tiled images are not supported.
+	data := tifftest.Encode(binary.LittleEndian, []tifftest.Page{{
+		Tags:   map[uint16][]uint16{tagImageWidth: {1}, tagImageLength: {1}, tagTileWidth: {16}},
+		Strips: [][]byte{{0}},
+	}})
+	_, err = Decode(data)
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package tifftest writes the TIFF files used by the tests of the TIFF readers.
+package tifftest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Tags of the strips and of the resolution, added by Encode.
+const (
+	tagStripOffsets    = 273
+	tagStripByteCounts = 279
+	tagXResolution     = 282
+	tagYResolution     = 283
+	tagResolutionUnit  = 296
+)
+
+// Field types.
+const (
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// Page is a page of a test TIFF file: the SHORT values of its tags and its strips.
+type Page struct {
+	Tags   map[uint16][]uint16
+	Strips [][]byte
+}
+
+// Encode returns a TIFF file with the pages `pages` in byte order `order`. The strip offsets and
+// byte counts are added, and the resolution is set to 144 pixels per centimeter.
+func Encode(order binary.ByteOrder, pages []Page) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(0))
+	// nextPos is the position of the offset of the next image file directory.
+	nextPos := 4
+
+	for _, page := range pages {
+		var offsets, counts []uint32
+		for _, strip := range page.Strips {
+			offsets = append(offsets, uint32(buf.Len()))
+			counts = append(counts, uint32(len(strip)))
+			buf.Write(strip)
+		}
+		arrays := map[int]uint32{tagStripOffsets: uint32(buf.Len())}
+		binary.Write(&buf, order, offsets)
+		arrays[tagStripByteCounts] = uint32(buf.Len())
+		binary.Write(&buf, order, counts)
+		resPos := uint32(buf.Len())
+		binary.Write(&buf, order, []uint32{144, 1})
+		for tag, vals := range page.Tags {
+			if len(vals) > 2 {
+				arrays[int(tag)] = uint32(buf.Len())
+				binary.Write(&buf, order, vals)
+			}
+		}
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0)
+		}
+
+		tags := []int{tagStripOffsets, tagStripByteCounts, tagXResolution, tagYResolution,
+			tagResolutionUnit}
+		for tag := range page.Tags {
+			tags = append(tags, int(tag))
+		}
+		sort.Ints(tags)
+
+		order.PutUint32(buf.Bytes()[nextPos:], uint32(buf.Len()))
+		binary.Write(&buf, order, uint16(len(tags)))
+		for _, tag := range tags {
+			binary.Write(&buf, order, uint16(tag))
+			switch tag {
+			case tagStripOffsets, tagStripByteCounts:
+				binary.Write(&buf, order, uint16(typeLong))
+				binary.Write(&buf, order, uint32(len(offsets)))
+				if len(offsets) == 1 && tag == tagStripOffsets {
+					binary.Write(&buf, order, offsets[0])
+				} else if len(offsets) == 1 {
+					binary.Write(&buf, order, counts[0])
+				} else {
+					binary.Write(&buf, order, arrays[tag])
+				}
+			case tagXResolution, tagYResolution:
+				binary.Write(&buf, order, uint16(typeRational))
+				binary.Write(&buf, order, []uint32{1, resPos})
+			case tagResolutionUnit:
+				binary.Write(&buf, order, uint16(typeShort))
+				binary.Write(&buf, order, uint32(1))
+				binary.Write(&buf, order, []uint16{3, 0})
+			default:
+				vals := page.Tags[uint16(tag)]
+				binary.Write(&buf, order, uint16(typeShort))
+				binary.Write(&buf, order, uint32(len(vals)))
+				if len(vals) <= 2 {
+					binary.Write(&buf, order, append(vals, 0, 0)[:2])
+				} else {
+					binary.Write(&buf, order, arrays[tag])
+				}
+			}
+		}
+		nextPos = buf.Len()
+		binary.Write(&buf, order, uint32(0))
+	}
+	return buf.Bytes()
+}
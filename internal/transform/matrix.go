@@ -74,6 +74,17 @@ func (m Matrix) Mult(b Matrix) Matrix {
 	return m
 }
 
+// Inverse returns the inverse of `m`. Returns false if `m` is not invertible.
+func (m Matrix) Inverse() (Matrix, bool) {
+	a, b, c, d, tx, ty := m[0], m[1], m[3], m[4], m[6], m[7]
+	det := a*d - b*c
+	if det == 0 {
+		return Matrix{}, false
+	}
+	ia, ib, ic, id := d/det, -b/det, -c/det, a/det
+	return NewMatrix(ia, ib, ic, id, -(tx*ia + ty*ic), -(tx*ib + ty*id)), true
+}
+
 // Translate appends a translation of `dx`,`dy` to `m`.
 // m.Translate(dx, dy) is equivalent to m.Concat(NewMatrix(1, 0, 0, 1, dx, dy))
 func (m *Matrix) Translate(dx, dy float64) {
@@ -89,8 +100,8 @@ func (m *Matrix) Translation() (float64, float64) {
 
 // Transform returns coordinates `x`,`y` transformed by `m`.
 func (m *Matrix) Transform(x, y float64) (float64, float64) {
-	xp := x*m[0] + y*m[1] + m[6]
-	yp := x*m[3] + y*m[4] + m[7]
+	xp := x*m[0] + y*m[3] + m[6]
+	yp := x*m[1] + y*m[4] + m[7]
 	return xp, yp
 }
 
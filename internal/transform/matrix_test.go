@@ -61,3 +61,46 @@ func makeAngleCase(r, theta float64) angleCase {
 	d := a
 	return angleCase{params{a, b, c, d, 0, 0}, theta}
 }
+
+// TestTransform tests the Matrix.Transform() function.
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		params
+		x, y   float64
+		xp, yp float64
+	}{
+		{params{1, 0, 0, 1, 0, 0}, 3, 4, 3, 4},
+		{params{2, 0, 0, 3, 10, 20}, 3, 4, 16, 32},
+		{params{0, 1, -1, 0, 0, 0}, 3, 4, -4, 3},
+		{params{0, -1, 1, 0, 0, 200}, 3, 4, 4, 197},
+		{params{1, 0, 1, 1, 0, 0}, 3, 4, 7, 4},
+	}
+	for _, test := range tests {
+		p := test.params
+		m := NewMatrix(p.a, p.b, p.c, p.d, p.tx, p.ty)
+		xp, yp := m.Transform(test.x, test.y)
+		if xp != test.xp || yp != test.yp {
+			t.Fatalf("Bad transform: m=%s (%g,%g) expected=(%g,%g) actual=(%g,%g)",
+				m, test.x, test.y, test.xp, test.yp, xp, yp)
+		}
+	}
+}
+
+// TestInverse tests the Matrix.Inverse() function.
+func TestInverse(t *testing.T) {
+	m := NewMatrix(0, 2, -3, 0, 10, 20)
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("Matrix not invertible: m=%s", m)
+	}
+	x, y := m.Transform(3, 4)
+	x, y = inv.Transform(x, y)
+	if math.Abs(x-3) > 1e-9 || math.Abs(y-4) > 1e-9 {
+		t.Fatalf("Bad inverse: m=%s inv=%s (%g,%g)", m, inv, x, y)
+	}
+
+	m = NewMatrix(1, 2, 2, 4, 0, 0)
+	if _, ok := m.Inverse(); ok {
+		t.Fatalf("Singular matrix inverted: m=%s", m)
+	}
+}
@@ -37,6 +37,10 @@ const (
 	ActionTypeURI         PdfActionType = "URI"         // Resolves a uniform resource identifier
 )
 
+// ActionTypeRichMediaExecute is the type of the actions sending a command to a rich media
+// annotation (Adobe Supplement to ISO 32000, extension level 3).
+const ActionTypeRichMediaExecute PdfActionType = "RichMediaExecute"
+
 // PdfAction represents an action in PDF (section 12.6 p. 412).
 type PdfAction struct {
 	context PdfModel
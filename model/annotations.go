@@ -671,6 +671,18 @@ func NewPdfAnnotationWidget() *PdfAnnotationWidget {
 	return annotationWidget
 }
 
+// NewPdfAnnotationFromIndirectObject loads an annotation model from the annotation dictionary
+// in `container`, which is not necessarily part of a document loaded by a PdfReader,
+// e.g. a modified copy of an existing annotation. The annotation is loaded by a reader without
+// document, so the target pages of its destinations are not resolved.
+func NewPdfAnnotationFromIndirectObject(container *core.PdfIndirectObject) (*PdfAnnotation, error) {
+	r := &PdfReader{
+		modelManager: newModelManager(),
+		traversed:    map[core.PdfObject]struct{}{},
+	}
+	return r.newPdfAnnotationFromIndirectObject(container)
+}
+
 // Used for PDF parsing.  Loads a PDF annotation model from a PDF dictionary.
 // Loads the common PDF annotation dictionary, and anything needed for the annotation subtype.
 func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndirectObject) (*PdfAnnotation, error) {
@@ -1075,6 +1087,7 @@ func (r *PdfReader) newPdfAnnotationTextFromDict(d *core.PdfObjectDictionary) (*
 
 func (r *PdfReader) newPdfAnnotationLinkFromDict(d *core.PdfObjectDictionary) (*PdfAnnotationLink, error) {
 	annot := PdfAnnotationLink{}
+	annot.reader = r
 
 	annot.A = d.Get("A")
 	annot.Dest = d.Get("Dest")
@@ -1093,8 +1106,11 @@ func (r *PdfReader) loadAction(obj core.PdfObject) (*PdfAction, error) {
 			return nil, err
 		}
 		return actionObj, nil
+	} else if dict, isDict := core.GetDict(obj); isDict {
+		// Direct action dictionary.
+		return r.newPdfActionFromIndirectObject(core.MakeIndirectObject(dict))
 	} else if !core.IsNullObject(obj) {
-		return nil, errors.New("action should point to an indirect object")
+		return nil, errors.New("action should be a dictionary")
 	}
 	return nil, nil
 }
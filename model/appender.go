@@ -27,6 +27,13 @@ type PdfAppender struct {
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
 
+	pageLabels   *PdfPageLabels
+	nameTrees    map[core.PdfObjectName]*PdfNameTree
+	ocProperties *PdfOCProperties
+
+	outlines        *Outline
+	replaceOutlines bool
+
 	xrefs          core.XrefTable
 	xrefOffset     int64
 	greatestObjNum int
@@ -520,6 +527,39 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// SetPageLabels replaces the page labels of the document in the new revision.
+func (a *PdfAppender) SetPageLabels(labels *PdfPageLabels) {
+	a.pageLabels = labels
+}
+
+// SetNameTree replaces the name tree stored under `key` in the Names dictionary of the
+// catalog in the new revision, e.g. "Dests", "EmbeddedFiles" or "JavaScript". A nil `tree`
+// removes the entry.
+func (a *PdfAppender) SetNameTree(key string, tree *PdfNameTree) error {
+	if key == "" {
+		return errNameTreeKey
+	}
+	if a.nameTrees == nil {
+		a.nameTrees = map[core.PdfObjectName]*PdfNameTree{}
+	}
+	a.nameTrees[core.PdfObjectName(key)] = tree
+	return nil
+}
+
+// SetOptionalContentProperties replaces the optional content properties of the document in
+// the new revision, e.g. to change the default visibility of layers.
+func (a *PdfAppender) SetOptionalContentProperties(ocProperties *PdfOCProperties) {
+	a.ocProperties = ocProperties
+}
+
+// SetOutlines replaces the outlines of the document in the new revision. The outlines
+// loaded with PdfReader.GetOutlines keep their object numbers. Setting nil or an empty
+// outline removes the outlines of the document.
+func (a *PdfAppender) SetOutlines(outline *Outline) {
+	a.outlines = outline
+	a.replaceOutlines = true
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -569,6 +609,39 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		writer.catalog.Set("AcroForm", a.acroForm.ToPdfObject())
 		a.updateObjectsDeep(a.acroForm.ToPdfObject(), nil)
 	}
+	if a.pageLabels != nil {
+		labels := a.pageLabels.ToPdfObject()
+		writer.catalog.Set("PageLabels", labels)
+		a.updateObjectsDeep(labels, nil)
+	}
+	if a.ocProperties != nil {
+		ocProperties := a.ocProperties.ToPdfObject()
+		writer.catalog.Set("OCProperties", ocProperties)
+		a.updateObjectsDeep(ocProperties, nil)
+	}
+	if a.replaceOutlines {
+		if a.outlines == nil || len(a.outlines.Items()) == 0 {
+			writer.catalog.Remove("Outlines")
+		} else {
+			// The outline and items loaded from the document keep their object numbers.
+			outline, replaced := a.outlines.toPdfOutline()
+			for obj, replacement := range replaced {
+				a.replaceObject(obj, replacement)
+			}
+			outlines := outline.ToPdfObject()
+			writer.catalog.Set("Outlines", outlines)
+			a.updateObjectsDeep(outlines, nil)
+		}
+	}
+	if len(a.nameTrees) > 0 {
+		if names := makeNamesDict(writer.catalog.Get("Names"), a.nameTrees); names != nil {
+			namesObj := core.MakeIndirectObject(names)
+			writer.catalog.Set("Names", namesObj)
+			a.updateObjectsDeep(namesObj, nil)
+		} else {
+			writer.catalog.Remove("Names")
+		}
+	}
 
 	a.addNewObject(writer.infoObj)
 	a.addNewObject(writer.root)
@@ -0,0 +1,842 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Portable collections (portfolios), section 12.3.5 p. 370 (PDF32000_2008).
+// Folders are defined in the Adobe Extension Level 3 to ISO 32000-1 and in PDF 2.0 (12.3.5).
+
+// PdfCollectionView specifies how a conforming reader presents a portable collection.
+type PdfCollectionView string
+
+// Collection view modes (Table 155 - View entry).
+const (
+	// CollectionViewDetails shows the collection in details mode, with columns from the schema.
+	CollectionViewDetails PdfCollectionView = "D"
+	// CollectionViewTile shows the collection in tile (icon) mode.
+	CollectionViewTile PdfCollectionView = "T"
+	// CollectionViewHidden hides the collection user interface initially.
+	CollectionViewHidden PdfCollectionView = "H"
+)
+
+// PdfCollectionFieldSubtype specifies the data type of a collection field.
+type PdfCollectionFieldSubtype string
+
+// Collection field subtypes (Table 156 - Entries in a collection field dictionary).
+const (
+	CollectionFieldText           PdfCollectionFieldSubtype = "S"
+	CollectionFieldDate           PdfCollectionFieldSubtype = "D"
+	CollectionFieldNumber         PdfCollectionFieldSubtype = "N"
+	CollectionFieldFileName       PdfCollectionFieldSubtype = "F"
+	CollectionFieldDesc           PdfCollectionFieldSubtype = "Desc"
+	CollectionFieldModDate        PdfCollectionFieldSubtype = "ModDate"
+	CollectionFieldCreationDate   PdfCollectionFieldSubtype = "CreationDate"
+	CollectionFieldSize           PdfCollectionFieldSubtype = "Size"
+	CollectionFieldCompressedSize PdfCollectionFieldSubtype = "CompressedSize"
+)
+
+// PdfCollection represents a collection dictionary (Table 155 - p. 371).
+// The collection dictionary is referred to by the Collection entry of the document catalog
+// and turns the document into a portable collection (portfolio).
+type PdfCollection struct {
+	// Schema describes the columns of the collection. Optional.
+	Schema *PdfCollectionSchema
+
+	// D is the name (in the EmbeddedFiles name tree) of the document which is initially
+	// presented. If empty, the cover sheet (the document itself) is presented.
+	D string
+
+	// View is the initial view mode. Defaults to CollectionViewDetails if empty.
+	View PdfCollectionView
+
+	// Sort specifies the order in which items in the collection appear. Optional.
+	Sort *PdfCollectionSort
+
+	// Folders is the root of the folder hierarchy. Optional.
+	Folders *PdfCollectionFolder
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfCollection returns a new empty collection.
+func NewPdfCollection() *PdfCollection {
+	return &PdfCollection{
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// newPdfCollectionFromObj loads a collection dictionary.
+func newPdfCollectionFromObj(obj core.PdfObject) (*PdfCollection, error) {
+	container, isInd := core.GetIndirect(obj)
+	if !isInd {
+		container = core.MakeIndirectObject(core.TraceToDirectObject(obj))
+	}
+	dict, ok := core.GetDict(container.PdfObject)
+	if !ok {
+		common.Log.Debug("ERROR: Collection not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	c := &PdfCollection{container: container}
+	if obj := dict.Get("Schema"); obj != nil {
+		schema, err := newPdfCollectionSchemaFromObj(obj)
+		if err != nil {
+			return nil, err
+		}
+		c.Schema = schema
+	}
+	if str, ok := core.GetString(dict.Get("D")); ok {
+		c.D = str.Decoded()
+	}
+	if view, ok := core.GetNameVal(dict.Get("View")); ok {
+		c.View = PdfCollectionView(view)
+	}
+	if obj := dict.Get("Sort"); obj != nil {
+		c.Sort = newPdfCollectionSortFromObj(obj)
+	}
+	if obj := dict.Get("Folders"); obj != nil {
+		folders, err := newPdfCollectionFolderFromObj(obj, nil, map[core.PdfObject]struct{}{})
+		if err != nil {
+			return nil, err
+		}
+		c.Folders = folders
+	}
+
+	return c, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (c *PdfCollection) GetContainingPdfObject() core.PdfObject {
+	return c.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (c *PdfCollection) ToPdfObject() core.PdfObject {
+	if c.container == nil {
+		c.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	d, ok := c.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		d = core.MakeDict()
+		c.container.PdfObject = d
+	}
+	d.Clear()
+
+	d.Set("Type", core.MakeName("Collection"))
+	if c.Schema != nil {
+		d.Set("Schema", c.Schema.ToPdfObject())
+	}
+	if c.D != "" {
+		d.Set("D", core.MakeTextString(c.D))
+	}
+	if c.View != "" {
+		d.Set("View", core.MakeName(string(c.View)))
+	}
+	if c.Sort != nil {
+		d.Set("Sort", c.Sort.ToPdfObject())
+	}
+	if c.Folders != nil {
+		d.Set("Folders", c.Folders.ToPdfObject())
+	}
+
+	return c.container
+}
+
+// PdfCollectionField represents a collection field dictionary (Table 156 - p. 372), which
+// describes a single column of the collection schema.
+type PdfCollectionField struct {
+	// Key is the key of the field in the schema and in collection item dictionaries.
+	Key string
+
+	Subtype PdfCollectionFieldSubtype
+	N       string // Textual field name presented to the user.
+	O       *int64 // Relative order of the field in the user interface.
+	V       *bool  // Initial visibility of the field.
+	E       *bool  // Whether the field can be edited.
+}
+
+// NewPdfCollectionField returns a new collection field with key `key`, display name `name`
+// and data type `subtype`.
+func NewPdfCollectionField(key, name string, subtype PdfCollectionFieldSubtype) *PdfCollectionField {
+	return &PdfCollectionField{
+		Key:     key,
+		Subtype: subtype,
+		N:       name,
+	}
+}
+
+// ToPdfObject returns the collection field dictionary.
+func (f *PdfCollectionField) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	d.Set("Type", core.MakeName("CollectionField"))
+	d.Set("Subtype", core.MakeName(string(f.Subtype)))
+	d.Set("N", core.MakeTextString(f.N))
+	if f.O != nil {
+		d.Set("O", core.MakeInteger(*f.O))
+	}
+	if f.V != nil {
+		d.Set("V", core.MakeBool(*f.V))
+	}
+	if f.E != nil {
+		d.Set("E", core.MakeBool(*f.E))
+	}
+	return d
+}
+
+// PdfCollectionSchema represents a collection schema dictionary (Table 154 - p. 372).
+// It maps field keys to the collection fields (columns) of the collection.
+type PdfCollectionSchema struct {
+	fields []*PdfCollectionField
+}
+
+// NewPdfCollectionSchema returns a new schema containing `fields`.
+func NewPdfCollectionSchema(fields ...*PdfCollectionField) *PdfCollectionSchema {
+	schema := &PdfCollectionSchema{}
+	for _, f := range fields {
+		schema.Add(f)
+	}
+	return schema
+}
+
+func newPdfCollectionSchemaFromObj(obj core.PdfObject) (*PdfCollectionSchema, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Collection schema not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	schema := &PdfCollectionSchema{}
+	for _, key := range dict.Keys() {
+		if key == "Type" {
+			continue
+		}
+		fdict, ok := core.GetDict(dict.Get(key))
+		if !ok {
+			common.Log.Debug("Collection field %s not a dictionary - skipping", key)
+			continue
+		}
+
+		field := &PdfCollectionField{Key: string(key)}
+		if subtype, ok := core.GetNameVal(fdict.Get("Subtype")); ok {
+			field.Subtype = PdfCollectionFieldSubtype(subtype)
+		}
+		if str, ok := core.GetString(fdict.Get("N")); ok {
+			field.N = str.Decoded()
+		}
+		if o, err := core.GetNumberAsInt64(fdict.Get("O")); err == nil {
+			field.O = &o
+		}
+		if v, ok := core.GetBoolVal(fdict.Get("V")); ok {
+			field.V = &v
+		}
+		if e, ok := core.GetBoolVal(fdict.Get("E")); ok {
+			field.E = &e
+		}
+		schema.fields = append(schema.fields, field)
+	}
+
+	return schema, nil
+}
+
+// Add adds field `f` to the schema, replacing any field with the same key.
+func (s *PdfCollectionSchema) Add(f *PdfCollectionField) {
+	for i, field := range s.fields {
+		if field.Key == f.Key {
+			s.fields[i] = f
+			return
+		}
+	}
+	s.fields = append(s.fields, f)
+}
+
+// Get returns the field with key `key` or nil if not found.
+func (s *PdfCollectionSchema) Get(key string) *PdfCollectionField {
+	for _, field := range s.fields {
+		if field.Key == key {
+			return field
+		}
+	}
+	return nil
+}
+
+// Fields returns the fields of the schema. Fields which have an order (O) are sorted by it,
+// followed by the remaining fields in the order they were defined.
+func (s *PdfCollectionSchema) Fields() []*PdfCollectionField {
+	fields := make([]*PdfCollectionField, len(s.fields))
+	copy(fields, s.fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		oi, oj := fields[i].O, fields[j].O
+		if oi == nil || oj == nil {
+			return oi != nil && oj == nil
+		}
+		return *oi < *oj
+	})
+	return fields
+}
+
+// ToPdfObject returns the collection schema dictionary.
+func (s *PdfCollectionSchema) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	d.Set("Type", core.MakeName("CollectionSchema"))
+	for _, field := range s.fields {
+		d.Set(core.PdfObjectName(field.Key), field.ToPdfObject())
+	}
+	return d
+}
+
+// PdfCollectionSort represents a collection sort dictionary (Table 158 - p. 373).
+type PdfCollectionSort struct {
+	// S lists the field keys used for sorting, the first key being the primary sort key.
+	S []string
+
+	// A specifies the sort order of the keys in S (true for ascending). If it is shorter
+	// than S, the remaining keys are sorted in ascending order.
+	A []bool
+}
+
+// NewPdfCollectionSort returns a new sort dictionary sorting by `keys` in ascending order.
+func NewPdfCollectionSort(keys ...string) *PdfCollectionSort {
+	return &PdfCollectionSort{S: keys}
+}
+
+func newPdfCollectionSortFromObj(obj core.PdfObject) *PdfCollectionSort {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil
+	}
+
+	s := &PdfCollectionSort{}
+	switch t := core.TraceToDirectObject(dict.Get("S")).(type) {
+	case *core.PdfObjectName:
+		s.S = append(s.S, string(*t))
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			if name, ok := core.GetNameVal(o); ok {
+				s.S = append(s.S, name)
+			}
+		}
+	}
+	switch t := core.TraceToDirectObject(dict.Get("A")).(type) {
+	case *core.PdfObjectBool:
+		s.A = append(s.A, bool(*t))
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			if b, ok := core.GetBoolVal(o); ok {
+				s.A = append(s.A, b)
+			}
+		}
+	}
+	return s
+}
+
+// IsAscending returns true if the i-th sort key is sorted in ascending order.
+func (s *PdfCollectionSort) IsAscending(i int) bool {
+	if i < len(s.A) {
+		return s.A[i]
+	}
+	return true
+}
+
+// ToPdfObject returns the collection sort dictionary.
+func (s *PdfCollectionSort) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	d.Set("Type", core.MakeName("CollectionSort"))
+	if len(s.S) == 1 {
+		d.Set("S", core.MakeName(s.S[0]))
+	} else {
+		keys := core.MakeArray()
+		for _, key := range s.S {
+			keys.Append(core.MakeName(key))
+		}
+		d.Set("S", keys)
+	}
+
+	switch len(s.A) {
+	case 0:
+	case 1:
+		d.Set("A", core.MakeBool(s.A[0]))
+	default:
+		order := core.MakeArray()
+		for _, asc := range s.A {
+			order.Append(core.MakeBool(asc))
+		}
+		d.Set("A", order)
+	}
+	return d
+}
+
+// PdfCollectionItem represents a collection item dictionary (Table 157 - p. 373) which
+// contains the data described by the collection schema for a single embedded file or folder.
+// Values are either text strings, numbers or dates and may have a prefix (collection subitem).
+type PdfCollectionItem struct {
+	keys   []string
+	values map[string]core.PdfObject
+	prefix map[string]string
+}
+
+// NewPdfCollectionItem returns a new empty collection item.
+func NewPdfCollectionItem() *PdfCollectionItem {
+	return &PdfCollectionItem{
+		values: map[string]core.PdfObject{},
+		prefix: map[string]string{},
+	}
+}
+
+// NewPdfCollectionItemFromObj loads a collection item dictionary, e.g. the CI entry of a
+// file specification or folder.
+func NewPdfCollectionItemFromObj(obj core.PdfObject) (*PdfCollectionItem, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Collection item not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	item := NewPdfCollectionItem()
+	for _, key := range dict.Keys() {
+		if key == "Type" {
+			continue
+		}
+		val := core.TraceToDirectObject(dict.Get(key))
+		if sub, ok := val.(*core.PdfObjectDictionary); ok {
+			if p, ok := core.GetString(sub.Get("P")); ok {
+				item.prefix[string(key)] = p.Decoded()
+			}
+			val = core.TraceToDirectObject(sub.Get("D"))
+		}
+		if val == nil {
+			continue
+		}
+		item.set(string(key), val)
+	}
+	return item, nil
+}
+
+func (ci *PdfCollectionItem) set(key string, val core.PdfObject) {
+	if _, has := ci.values[key]; !has {
+		ci.keys = append(ci.keys, key)
+	}
+	ci.values[key] = val
+}
+
+// Keys returns the keys of the item in the order they were set.
+func (ci *PdfCollectionItem) Keys() []string {
+	return ci.keys
+}
+
+// SetText sets the text value of field `key`.
+func (ci *PdfCollectionItem) SetText(key, val string) {
+	ci.set(key, core.MakeTextString(val))
+}
+
+// SetNumber sets the numeric value of field `key`.
+func (ci *PdfCollectionItem) SetNumber(key string, val float64) {
+	if val == float64(int64(val)) {
+		ci.set(key, core.MakeInteger(int64(val)))
+		return
+	}
+	ci.set(key, core.MakeFloat(val))
+}
+
+// SetDate sets the date value of field `key`.
+func (ci *PdfCollectionItem) SetDate(key string, val time.Time) error {
+	date, err := NewPdfDateFromTime(val)
+	if err != nil {
+		return err
+	}
+	ci.set(key, date.ToPdfObject())
+	return nil
+}
+
+// SetPrefix sets a prefix for field `key` which is displayed before the value but which
+// is ignored when sorting.
+func (ci *PdfCollectionItem) SetPrefix(key, prefix string) {
+	ci.prefix[key] = prefix
+}
+
+// Remove removes field `key` from the item.
+func (ci *PdfCollectionItem) Remove(key string) {
+	if _, has := ci.values[key]; !has {
+		return
+	}
+	delete(ci.values, key)
+	delete(ci.prefix, key)
+	for i, k := range ci.keys {
+		if k == key {
+			ci.keys = append(ci.keys[:i], ci.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get returns the raw value of field `key` or nil if not set.
+func (ci *PdfCollectionItem) Get(key string) core.PdfObject {
+	return ci.values[key]
+}
+
+// GetText returns the value of field `key` as text. Numbers are formatted and dates
+// are returned in PDF date format.
+func (ci *PdfCollectionItem) GetText(key string) (string, bool) {
+	switch t := ci.values[key].(type) {
+	case *core.PdfObjectString:
+		return t.Decoded(), true
+	case *core.PdfObjectInteger:
+		return strconv.FormatInt(int64(*t), 10), true
+	case *core.PdfObjectFloat:
+		return strconv.FormatFloat(float64(*t), 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// GetNumber returns the numeric value of field `key`.
+func (ci *PdfCollectionItem) GetNumber(key string) (float64, bool) {
+	val, err := core.GetNumberAsFloat(ci.values[key])
+	if err != nil {
+		return 0, false
+	}
+	return val, true
+}
+
+// GetDate returns the date value of field `key`.
+func (ci *PdfCollectionItem) GetDate(key string) (time.Time, bool) {
+	str, ok := core.GetString(ci.values[key])
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := NewPdfDate(str.Str())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date.ToGoTime(), true
+}
+
+// GetPrefix returns the prefix of field `key`, if any.
+func (ci *PdfCollectionItem) GetPrefix(key string) string {
+	return ci.prefix[key]
+}
+
+// ToPdfObject returns the collection item dictionary.
+func (ci *PdfCollectionItem) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	d.Set("Type", core.MakeName("CollectionItem"))
+	for _, key := range ci.keys {
+		val := ci.values[key]
+		if prefix, has := ci.prefix[key]; has {
+			sub := core.MakeDict()
+			sub.Set("Type", core.MakeName("CollectionSubitem"))
+			sub.Set("D", val)
+			sub.Set("P", core.MakeTextString(prefix))
+			val = sub
+		}
+		d.Set(core.PdfObjectName(key), val)
+	}
+	return d
+}
+
+// PdfCollectionFolder represents a folder dictionary of a portable collection
+// (ISO 32000-2 Table 158). Folders form a tree rooted at the Folders entry of the
+// collection dictionary.
+type PdfCollectionFolder struct {
+	// ID is the unique non-negative identifier of the folder within the collection.
+	ID int64
+
+	// Name is the name of the folder (required).
+	Name string
+
+	Desc         string
+	CreationDate *PdfDate
+	ModDate      *PdfDate
+
+	// CI is the collection item containing the schema data of the folder. Optional.
+	CI *PdfCollectionItem
+
+	Parent   *PdfCollectionFolder
+	Children []*PdfCollectionFolder
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfCollectionFolder returns a new root folder named `name`. The root folder has ID 0.
+// Sub-folders should be created with AddFolder so that IDs remain unique.
+func NewPdfCollectionFolder(name string) *PdfCollectionFolder {
+	return &PdfCollectionFolder{
+		Name:      name,
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+func newPdfCollectionFolderFromObj(obj core.PdfObject, parent *PdfCollectionFolder, visited map[core.PdfObject]struct{}) (*PdfCollectionFolder, error) {
+	container, ok := core.GetIndirect(obj)
+	if !ok {
+		return nil, fmt.Errorf("folder not an indirect object (%T)", obj)
+	}
+	visited[container] = struct{}{}
+
+	dict, ok := core.GetDict(container.PdfObject)
+	if !ok {
+		return nil, errors.New("folder not a dictionary")
+	}
+
+	folder := &PdfCollectionFolder{
+		Parent:    parent,
+		container: container,
+	}
+	id, err := core.GetNumberAsInt64(dict.Get("ID"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid folder ID: %v", err)
+	}
+	folder.ID = id
+	if str, ok := core.GetString(dict.Get("Name")); ok {
+		folder.Name = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("Desc")); ok {
+		folder.Desc = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("CreationDate")); ok {
+		if date, err := NewPdfDate(str.Str()); err == nil {
+			folder.CreationDate = &date
+		}
+	}
+	if str, ok := core.GetString(dict.Get("ModDate")); ok {
+		if date, err := NewPdfDate(str.Str()); err == nil {
+			folder.ModDate = &date
+		}
+	}
+	if obj := dict.Get("CI"); obj != nil {
+		if ci, err := NewPdfCollectionItemFromObj(obj); err == nil {
+			folder.CI = ci
+		}
+	}
+
+	// Children are stored as a linked list via Child and Next.
+	child := core.ResolveReference(dict.Get("Child"))
+	for child != nil && !core.IsNullObject(child) {
+		if _, processed := visited[child]; processed {
+			common.Log.Debug("ERROR: Cyclic folder structure - skipping")
+			break
+		}
+		sub, err := newPdfCollectionFolderFromObj(child, folder, visited)
+		if err != nil {
+			return nil, err
+		}
+		folder.Children = append(folder.Children, sub)
+
+		childDict, _ := core.GetDict(child)
+		child = core.ResolveReference(childDict.Get("Next"))
+	}
+
+	return folder, nil
+}
+
+// root returns the root folder of the hierarchy `f` belongs to.
+func (f *PdfCollectionFolder) root() *PdfCollectionFolder {
+	for f.Parent != nil {
+		f = f.Parent
+	}
+	return f
+}
+
+// maxID returns the greatest folder ID in the subtree rooted at `f`.
+func (f *PdfCollectionFolder) maxID() int64 {
+	id := f.ID
+	for _, child := range f.Children {
+		if cid := child.maxID(); cid > id {
+			id = cid
+		}
+	}
+	return id
+}
+
+// AddFolder creates a sub-folder of `f` named `name`, assigning it an ID which is unique
+// within the folder hierarchy.
+func (f *PdfCollectionFolder) AddFolder(name string) *PdfCollectionFolder {
+	child := NewPdfCollectionFolder(name)
+	child.ID = f.root().maxID() + 1
+	child.Parent = f
+	f.Children = append(f.Children, child)
+	return child
+}
+
+// FindByID returns the folder with identifier `id` in the subtree rooted at `f`, or nil
+// if not found.
+func (f *PdfCollectionFolder) FindByID(id int64) *PdfCollectionFolder {
+	if f.ID == id {
+		return f
+	}
+	for _, child := range f.Children {
+		if found := child.FindByID(id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Path returns the names of the folders from the root (exclusive) down to `f`, separated
+// by "/".
+func (f *PdfCollectionFolder) Path() string {
+	var parts []string
+	for ; f.Parent != nil; f = f.Parent {
+		parts = append([]string{f.Name}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// EmbeddedFileName returns the key under which a file named `name` located in folder `f`
+// is stored in the EmbeddedFiles name tree. Files in the root folder are not prefixed,
+// other files are prefixed with the folder ID in angle brackets, e.g. "<3>exhibit.pdf".
+func (f *PdfCollectionFolder) EmbeddedFileName(name string) string {
+	if f == nil || f.Parent == nil {
+		return name
+	}
+	return fmt.Sprintf("<%d>%s", f.ID, name)
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (f *PdfCollectionFolder) GetContainingPdfObject() core.PdfObject {
+	return f.container
+}
+
+// ToPdfObject implements interface PdfModel. Recursively builds the folder hierarchy.
+func (f *PdfCollectionFolder) ToPdfObject() core.PdfObject {
+	if f.container == nil {
+		f.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	d, ok := f.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		d = core.MakeDict()
+		f.container.PdfObject = d
+	}
+	d.Clear()
+
+	d.Set("Type", core.MakeName("Folder"))
+	d.Set("ID", core.MakeInteger(f.ID))
+	d.Set("Name", core.MakeTextString(f.Name))
+	if f.Desc != "" {
+		d.Set("Desc", core.MakeTextString(f.Desc))
+	}
+	if f.CreationDate != nil {
+		d.Set("CreationDate", f.CreationDate.ToPdfObject())
+	}
+	if f.ModDate != nil {
+		d.Set("ModDate", f.ModDate.ToPdfObject())
+	}
+	if f.CI != nil {
+		d.Set("CI", f.CI.ToPdfObject())
+	}
+	if f.Parent != nil {
+		d.Set("Parent", f.Parent.GetContainingPdfObject())
+	}
+
+	for i, child := range f.Children {
+		child.Parent = f
+		childObj := child.ToPdfObject()
+		if i == 0 {
+			d.Set("Child", childObj)
+		}
+		if i+1 < len(f.Children) {
+			childDict := child.container.PdfObject.(*core.PdfObjectDictionary)
+			childDict.Set("Next", f.Children[i+1].GetContainingPdfObject())
+		}
+	}
+
+	return f.container
+}
+
+// PdfPortfolioFile represents an embedded file of a portable collection.
+type PdfPortfolioFile struct {
+	// Key is the key of the file in the EmbeddedFiles name tree.
+	Key string
+
+	// Name is the name of the file without the folder prefix.
+	Name string
+
+	// Folder is the folder containing the file. It is nil if the collection does not
+	// define folders.
+	Folder *PdfCollectionFolder
+
+	Filespec *PdfFilespec
+
+	// Item contains the collection item (schema data) of the file, if any.
+	Item *PdfCollectionItem
+}
+
+// splitFolderPrefix splits an EmbeddedFiles key of the form "<ID>name" into the folder ID
+// and the file name. The bool flag indicates whether the key had a folder prefix.
+func splitFolderPrefix(key string) (int64, string, bool) {
+	if !strings.HasPrefix(key, "<") {
+		return 0, key, false
+	}
+	end := strings.Index(key, ">")
+	if end < 0 {
+		return 0, key, false
+	}
+	id, err := strconv.ParseInt(key[1:end], 10, 64)
+	if err != nil {
+		return 0, key, false
+	}
+	return id, key[end+1:], true
+}
+
+// GetPortfolioFiles returns the embedded files of the document with their collection
+// item metadata and folder. Files which are not in any sub-folder are placed in the
+// root folder (if the collection defines folders).
+func (r *PdfReader) GetPortfolioFiles() ([]*PdfPortfolioFile, error) {
+	collection, err := r.GetCollection()
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.GetEmbeddedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var portfolioFiles []*PdfPortfolioFile
+	for _, key := range keys {
+		fs := files[key]
+		pf := &PdfPortfolioFile{
+			Key:      key,
+			Name:     key,
+			Filespec: fs,
+		}
+		if collection != nil && collection.Folders != nil {
+			pf.Folder = collection.Folders
+			if id, name, ok := splitFolderPrefix(key); ok {
+				pf.Name = name
+				if folder := collection.Folders.FindByID(id); folder != nil {
+					pf.Folder = folder
+				} else {
+					common.Log.Debug("Folder %d of embedded file %q not found", id, key)
+				}
+			}
+		}
+		if fs.CI != nil {
+			item, err := NewPdfCollectionItemFromObj(fs.CI)
+			if err != nil {
+				return nil, err
+			}
+			pf.Item = item
+		}
+		portfolioFiles = append(portfolioFiles, pf)
+	}
+
+	return portfolioFiles, nil
+}
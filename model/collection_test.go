@@ -0,0 +1,170 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Tests creating a portfolio with a schema, sort order and folders, then reading it back.
+func TestCollectionReadWrite(t *testing.T) {
+	w := NewPdfWriter()
+	page := newTestPage(t, 612, 792, "")
+	require.NoError(t, w.AddPage(page))
+
+	order := int64(1)
+	schema := NewPdfCollectionSchema(
+		NewPdfCollectionField("exhibit", "Exhibit", CollectionFieldText),
+		NewPdfCollectionField("filed", "Filed", CollectionFieldDate),
+		NewPdfCollectionField("name", "File name", CollectionFieldFileName),
+	)
+	schema.Get("exhibit").O = &order
+
+	root := NewPdfCollectionFolder("Exhibits")
+	plaintiff := root.AddFolder("Plaintiff")
+	defendant := root.AddFolder("Defendant")
+	require.Equal(t, int64(1), plaintiff.ID)
+	require.Equal(t, int64(2), defendant.ID)
+
+	collection := NewPdfCollection()
+	collection.Schema = schema
+	collection.View = CollectionViewDetails
+	collection.Sort = &PdfCollectionSort{S: []string{"exhibit", "filed"}, A: []bool{true, false}}
+	collection.Folders = root
+	collection.D = plaintiff.EmbeddedFileName("a.txt")
+	w.SetCollection(collection)
+
+	filed := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	addFile := func(folder *PdfCollectionFolder, name, exhibit string) {
+		fs := NewPdfFilespecFromEmbeddedFile(name, NewPdfEmbeddedFile([]byte("content of "+name), "text/plain"))
+		item := NewPdfCollectionItem()
+		item.SetText("exhibit", exhibit)
+		item.SetPrefix("exhibit", "Ex. ")
+		require.NoError(t, item.SetDate("filed", filed))
+		fs.CI = item.ToPdfObject()
+		require.NoError(t, w.AddEmbeddedFile(folder.EmbeddedFileName(name), fs))
+	}
+	addFile(plaintiff, "a.txt", "A")
+	addFile(defendant, "b.txt", "B")
+	addFile(root, "index.txt", "0")
+
+	reader := writeRead(t, &w)
+
+	c, err := reader.GetCollection()
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, CollectionViewDetails, c.View)
+	require.Equal(t, "<1>a.txt", c.D)
+	require.Equal(t, []string{"exhibit", "filed"}, c.Sort.S)
+	require.False(t, c.Sort.IsAscending(1))
+
+	fields := c.Schema.Fields()
+	require.Len(t, fields, 3)
+	require.Equal(t, "exhibit", fields[0].Key)
+	require.Equal(t, CollectionFieldDate, c.Schema.Get("filed").Subtype)
+
+	require.NotNil(t, c.Folders)
+	require.Equal(t, "Exhibits", c.Folders.Name)
+	require.Len(t, c.Folders.Children, 2)
+	require.Equal(t, "Defendant", c.Folders.Children[1].Path())
+
+	files, err := reader.GetPortfolioFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	byName := map[string]*PdfPortfolioFile{}
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+	require.Equal(t, "Plaintiff", byName["a.txt"].Folder.Name)
+	require.Equal(t, "Defendant", byName["b.txt"].Folder.Name)
+	require.Equal(t, c.Folders, byName["index.txt"].Folder)
+
+	item := byName["b.txt"].Item
+	require.NotNil(t, item)
+	exhibit, ok := item.GetText("exhibit")
+	require.True(t, ok)
+	require.Equal(t, "B", exhibit)
+	require.Equal(t, "Ex. ", item.GetPrefix("exhibit"))
+	date, ok := item.GetDate("filed")
+	require.True(t, ok)
+	require.True(t, filed.Equal(date))
+
+	ef, err := byName["b.txt"].Filespec.GetEmbeddedFile()
+	require.NoError(t, err)
+	require.Equal(t, "content of b.txt", string(ef.Data))
+	require.Equal(t, "text/plain", ef.Subtype)
+	require.Equal(t, "b.txt", byName["b.txt"].Filespec.GetFileName())
+}
+
+// Tests that the Adobe extension level of the folders is merged with the existing extensions.
+func TestCollectionExtensions(t *testing.T) {
+	write := func(extensions *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+		w := NewPdfWriter()
+		page := newTestPage(t, 612, 792, "")
+		require.NoError(t, w.AddPage(page))
+		if extensions != nil {
+			w.catalog.Set("Extensions", extensions)
+		}
+		collection := NewPdfCollection()
+		collection.Folders = NewPdfCollectionFolder("Root")
+		w.SetCollection(collection)
+
+		reader := writeRead(t, &w)
+		trailer, err := reader.GetTrailer()
+		require.NoError(t, err)
+		root, found := core.GetDict(trailer.Get("Root"))
+		require.True(t, found)
+		dict, found := core.GetDict(root.Get("Extensions"))
+		require.True(t, found)
+		return dict
+	}
+	extensionLevel := func(dict *core.PdfObjectDictionary) int {
+		level, _ := core.GetIntVal(dict.Get("ExtensionLevel"))
+		return level
+	}
+
+	// Added to the other developer extensions.
+	other := core.MakeDict()
+	other.Set("BaseVersion", core.MakeName("1.7"))
+	other.Set("ExtensionLevel", core.MakeInteger(1))
+	extensions := core.MakeDict()
+	extensions.Set("XYZ", other)
+	merged := write(extensions)
+	require.NotNil(t, merged.Get("XYZ"))
+	adbe, ok := core.GetDict(merged.Get("ADBE"))
+	require.True(t, ok)
+	require.Equal(t, 3, extensionLevel(adbe))
+	require.Nil(t, extensions.Get("ADBE"))
+
+	// A higher level is kept.
+	adbe = core.MakeDict()
+	adbe.Set("BaseVersion", core.MakeName("1.7"))
+	adbe.Set("ExtensionLevel", core.MakeInteger(8))
+	extensions = core.MakeDict()
+	extensions.Set("ADBE", adbe)
+	adbe, ok = core.GetDict(write(extensions).Get("ADBE"))
+	require.True(t, ok)
+	require.Equal(t, 8, extensionLevel(adbe))
+
+	// A lower level is raised in the array of extensions.
+	adbe = core.MakeDict()
+	adbe.Set("BaseVersion", core.MakeName("1.7"))
+	adbe.Set("ExtensionLevel", core.MakeInteger(1))
+	extensions = core.MakeDict()
+	extensions.Set("ADBE", core.MakeArray(adbe))
+	arr, ok := core.GetArray(write(extensions).Get("ADBE"))
+	require.True(t, ok)
+	require.Equal(t, 1, arr.Len())
+	adbe, ok = core.GetDict(arr.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 3, extensionLevel(adbe))
+}
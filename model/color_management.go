@@ -0,0 +1,262 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/internal/sampling"
+	"github.com/unidoc/unipdf/v3/model/icc"
+)
+
+// ColorConversion holds the settings of the conversion of colors to RGB which are not defined by
+// the color spaces: the ICC profile of DeviceCMYK colors, e.g. the profile of the output intent of
+// a document (PdfOutputIntent.Profile). A nil ColorConversion converts the DeviceCMYK colors with
+// the formula of section 10.3.5 of the PDF reference.
+type ColorConversion struct {
+	cmykProfile   *icc.Profile
+	cmykTransform *icc.Transform
+}
+
+// NewColorConversion returns the color conversion converting DeviceCMYK colors with profile
+// `cmykProfile` and its default rendering intent, or with the formula of section 10.3.5 of the PDF
+// reference if nil.
+func NewColorConversion(cmykProfile *icc.Profile) (*ColorConversion, error) {
+	cc := &ColorConversion{}
+	if cmykProfile == nil {
+		return cc, nil
+	}
+	if cmykProfile.ColorSpace != icc.ColorSpaceCMYK {
+		return nil, errors.New("not a CMYK profile")
+	}
+	transform, err := icc.NewTransform(cmykProfile, icc.SRGB(), cmykProfile.Intent)
+	if err != nil {
+		return nil, err
+	}
+	cc.cmykProfile, cc.cmykTransform = cmykProfile, transform
+	return cc, nil
+}
+
+// DeviceCMYKProfile returns the ICC profile of DeviceCMYK colors, or nil if not set.
+func (cc *ColorConversion) DeviceCMYKProfile() *icc.Profile {
+	if cc == nil {
+		return nil
+	}
+	return cc.cmykProfile
+}
+
+// Colorspace returns color space `cs` converting its colors to RGB with the settings of `cc`: the
+// DeviceCMYK color spaces, including the base, alternate and underlying color spaces of `cs`, are
+// replaced by DeviceCMYK color spaces converting their colors with the profile of `cc`. `cs` is
+// not modified; it is returned if it has no DeviceCMYK color space or `cc` has no profile.
+func (cc *ColorConversion) Colorspace(cs PdfColorspace) PdfColorspace {
+	if cc == nil || cc.cmykTransform == nil {
+		return cs
+	}
+	switch t := cs.(type) {
+	case *PdfColorspaceDeviceCMYK:
+		return &PdfColorspaceDeviceCMYK{transform: cc.cmykTransform}
+	case *PdfColorspaceICCBased:
+		if alternate := cc.Colorspace(t.Alternate); alternate != t.Alternate {
+			c := *t
+			c.Alternate = alternate
+			return &c
+		}
+	case *PdfColorspaceSpecialIndexed:
+		if base := cc.Colorspace(t.Base); base != t.Base {
+			c := *t
+			c.Base = base
+			return &c
+		}
+	case *PdfColorspaceSpecialSeparation:
+		if alternate := cc.Colorspace(t.AlternateSpace); alternate != t.AlternateSpace {
+			c := *t
+			c.AlternateSpace = alternate
+			return &c
+		}
+	case *PdfColorspaceDeviceN:
+		if alternate := cc.Colorspace(t.AlternateSpace); alternate != t.AlternateSpace {
+			c := *t
+			c.AlternateSpace = alternate
+			return &c
+		}
+	case *PdfColorspaceSpecialPattern:
+		if underlying := cc.Colorspace(t.UnderlyingCS); underlying != t.UnderlyingCS {
+			c := *t
+			c.UnderlyingCS = underlying
+			return &c
+		}
+	}
+	return cs
+}
+
+// xyzTransform is the transform of the D50 XYZ colors to sRGB, created once and not modified.
+var xyzTransform = struct {
+	once      sync.Once
+	transform *icc.Transform
+}{}
+
+// xyzToRGB returns the sRGB components of CIE XYZ color (`x`,`y`,`z`) of a CIE-based color space
+// with white point `whitePoint`.
+// NOTE: The colors are adapted from the white point to the white of sRGB with the Bradford
+// transform and encoded with the sRGB transfer curve; the XYZ components were formerly converted
+// with the linear sRGB matrix only, which did not map the white point to white.
+func xyzToRGB(x, y, z float64, whitePoint []float64) (float64, float64, float64) {
+	white := icc.XYZ{0.9505, 1, 1.089}
+	if len(whitePoint) == 3 && whitePoint[0] > 0 && whitePoint[1] > 0 && whitePoint[2] > 0 {
+		copy(white[:], whitePoint)
+	}
+
+	xyzTransform.once.Do(func() {
+		transform, err := icc.NewTransform(icc.NewXYZProfile(icc.D50), icc.SRGB(), icc.RelativeColorimetric)
+		if err != nil {
+			common.Log.Debug("ERROR: XYZ transform: %v", err)
+			return
+		}
+		xyzTransform.transform = transform
+	})
+	if xyzTransform.transform == nil {
+		return 0, 0, 0
+	}
+
+	xyz := icc.Adapt(icc.XYZ{x, y, z}, white, icc.D50)
+	rgb := xyzTransform.transform.Convert(xyz[:])
+	return rgb[0], rgb[1], rgb[2]
+}
+
+// iccBasedMu guards the transforms cached by the ICCBased color spaces.
+var iccBasedMu sync.Mutex
+
+// iccBasedTransform is the transform of the profile of an ICCBased color space to sRGB.
+type iccBasedTransform struct {
+	data      []byte // Profile data the transform was created from.
+	profile   *icc.Profile
+	transform *icc.Transform
+}
+
+// Profile returns the ICC profile of the color space, parsed from its data.
+func (cs *PdfColorspaceICCBased) Profile() (*icc.Profile, error) {
+	return icc.Parse(cs.Data)
+}
+
+// rgbTransform returns the transform of the colors of the profile of the color space to sRGB and
+// the profile, or nil if the profile is invalid or not supported, in which case the alternate
+// color space is used.
+func (cs *PdfColorspaceICCBased) rgbTransform() (*icc.Transform, *icc.Profile) {
+	iccBasedMu.Lock()
+	defer iccBasedMu.Unlock()
+	if t := cs.transform; t != nil && len(t.data) == len(cs.Data) &&
+		(len(cs.Data) == 0 || &t.data[0] == &cs.Data[0]) {
+		return t.transform, t.profile
+	}
+
+	t := &iccBasedTransform{data: cs.Data}
+	cs.transform = t
+	profile, err := icc.Parse(cs.Data)
+	if err == nil && profile.NumComponents() != cs.N {
+		err = errors.New("number of components mismatch")
+	}
+	var transform *icc.Transform
+	if err == nil {
+		transform, err = icc.NewTransform(profile, icc.SRGB(), profile.Intent)
+	}
+	if err != nil {
+		common.Log.Debug("ICCBased profile not used: %v", err)
+		return nil, nil
+	}
+	t.profile, t.transform = profile, transform
+	return transform, profile
+}
+
+// iccInput returns the inputs of the transforms of profile `profile` for color components `vals`
+// of an ICCBased color space: the Lab components are encoded in [0,1] and the other components
+// are in [0,1].
+func iccInput(profile *icc.Profile, vals []float64) []float64 {
+	in := make([]float64, len(vals))
+	for i, v := range vals {
+		if profile.ColorSpace == icc.ColorSpaceLab {
+			if i == 0 {
+				v /= 100
+			} else {
+				v = (v + 128) / 255
+			}
+		}
+		in[i] = math.Min(math.Max(v, 0), 1)
+	}
+	return in
+}
+
+// transformImageToRGB returns the RGB image of image `img` converted with transform `transform`.
+// The samples are mapped to color component values by decode array `decode` and the values to
+// the inputs of the transform by `input`. The RGB image has 8 bits per component, or 16 if `img`
+// has 16.
+func transformImageToRGB(img Image, decode []float64, input func([]float64) []float64,
+	transform *icc.Transform) (Image, error) {
+	n := img.ColorComponents
+	if n <= 0 || n > 4 || len(decode) != 2*n {
+		common.Log.Debug("Invalid decode array (%d) for %d components", len(decode), n)
+		return img, errors.New("invalid decode array")
+	}
+
+	samples := img.GetSamples()
+	maxVal := math.Pow(2, float64(img.BitsPerComponent)) - 1
+	bits := int64(8)
+	if img.BitsPerComponent == 16 {
+		bits = 16
+	}
+	outMax := math.Pow(2, float64(bits)) - 1
+
+	cache := map[uint64][3]uint32{}
+	vals := make([]float64, n)
+	rgbSamples := make([]uint32, 0, 3*(len(samples)/n))
+	for i := 0; i+n <= len(samples); i += n {
+		key, _ := sampling.ColorKey(samples[i : i+n])
+		rgb, ok := cache[key]
+		if !ok {
+			for j := 0; j < n; j++ {
+				vals[j] = interpolate(float64(samples[i+j]), 0, maxVal, decode[2*j], decode[2*j+1])
+			}
+			out := transform.Convert(input(vals))
+			for j := range rgb {
+				rgb[j] = uint32(math.Round(math.Min(math.Max(out[j], 0), 1) * outMax))
+			}
+			if len(cache) < sampling.MaxColorCacheSize {
+				cache[key] = rgb
+			}
+		}
+		rgbSamples = append(rgbSamples, rgb[:]...)
+	}
+
+	rgbImage := img
+	rgbImage.BitsPerComponent = bits
+	rgbImage.ColorComponents = 3
+	rgbImage.SetSamples(rgbSamples)
+	rgbImage.decode = nil
+	return rgbImage, nil
+}
+
+// colorComponents returns the components of device or CIE-based color `color`, and false for
+// other colors.
+func colorComponents(color PdfColor) ([]float64, bool) {
+	switch c := color.(type) {
+	case *PdfColorDeviceGray:
+		return []float64{c.Val()}, true
+	case *PdfColorDeviceRGB:
+		return []float64{c.R(), c.G(), c.B()}, true
+	case *PdfColorDeviceCMYK:
+		return []float64{c.C(), c.M(), c.Y(), c.K()}, true
+	case *PdfColorCalGray:
+		return []float64{c.Val()}, true
+	case *PdfColorCalRGB:
+		return []float64{c.A(), c.B(), c.C()}, true
+	case *PdfColorLab:
+		return []float64{c.L(), c.A(), c.B()}, true
+	}
+	return nil, false
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model/icc"
 )
 
 // PdfColorspace interface defines the common methods of a PDF colorspace.
@@ -549,7 +550,11 @@ func (col *PdfColorDeviceCMYK) ToInteger(bits int) [4]uint32 {
 }
 
 // PdfColorspaceDeviceCMYK represents a CMYK colorspace.
-type PdfColorspaceDeviceCMYK struct{}
+type PdfColorspaceDeviceCMYK struct {
+	// transform converts the colors to sRGB with the ICC profile of DeviceCMYK colors, if set
+	// (see ColorConversion).
+	transform *icc.Transform
+}
 
 // NewPdfColorspaceDeviceCMYK returns a new CMYK colorspace object.
 func NewPdfColorspaceDeviceCMYK() *PdfColorspaceDeviceCMYK {
@@ -640,6 +645,11 @@ func (cs *PdfColorspaceDeviceCMYK) ColorToRGB(color PdfColor) (PdfColor, error)
 	y := cmyk.Y()
 	k := cmyk.K()
 
+	if transform := cs.transform; transform != nil {
+		rgb := transform.Convert([]float64{c, m, y, k})
+		return NewPdfColorDeviceRGB(rgb[0], rgb[1], rgb[2]), nil
+	}
+
 	c = c*(1-k) + k
 	m = m*(1-k) + k
 	y = y*(1-k) + k
@@ -670,6 +680,13 @@ func (cs *PdfColorspaceDeviceCMYK) ImageToRGB(img Image) (Image, error) {
 	}
 	common.Log.Trace("Decode array: %f", decode)
 
+	if transform := cs.transform; transform != nil {
+		identity := func(vals []float64) []float64 {
+			return vals
+		}
+		return transformImageToRGB(img, decode, identity, transform)
+	}
+
 	maxVal := math.Pow(2, float64(img.BitsPerComponent)) - 1
 	common.Log.Trace("MaxVal: %f", maxVal)
 
@@ -934,15 +951,7 @@ func (cs *PdfColorspaceCalGray) ColorToRGB(color PdfColor) (PdfColor, error) {
 	Z := cs.WhitePoint[2] * math.Pow(ANorm, cs.Gamma)
 
 	// X,Y,Z -> rgb
-	// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-	r := 3.240479*X + -1.537150*Y + -0.498535*Z
-	g := -0.969256*X + 1.875992*Y + 0.041556*Z
-	b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-	// Clip.
-	r = math.Min(math.Max(r, 0), 1.0)
-	g = math.Min(math.Max(g, 0), 1.0)
-	b = math.Min(math.Max(b, 0), 1.0)
+	r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 	return NewPdfColorDeviceRGB(r, g, b), nil
 }
@@ -966,15 +975,7 @@ func (cs *PdfColorspaceCalGray) ImageToRGB(img Image) (Image, error) {
 		Z := cs.WhitePoint[2] * math.Pow(ANorm, cs.Gamma)
 
 		// X,Y,Z -> rgb
-		// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-		r := 3.240479*X + -1.537150*Y + -0.498535*Z
-		g := -0.969256*X + 1.875992*Y + 0.041556*Z
-		b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-		// Clip.
-		r = math.Min(math.Max(r, 0), 1.0)
-		g = math.Min(math.Max(g, 0), 1.0)
-		b = math.Min(math.Max(b, 0), 1.0)
+		r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 		// Convert to uint32.
 		R := uint32(r * maxVal)
@@ -1280,15 +1281,7 @@ func (cs *PdfColorspaceCalRGB) ColorToRGB(color PdfColor) (PdfColor, error) {
 	Z := cs.Matrix[2]*math.Pow(aVal, cs.Gamma[0]) + cs.Matrix[5]*math.Pow(bVal, cs.Gamma[1]) + cs.Matrix[8]*math.Pow(cVal, cs.Gamma[2])
 
 	// X, Y, Z -> R, G, B
-	// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-	r := 3.240479*X + -1.537150*Y + -0.498535*Z
-	g := -0.969256*X + 1.875992*Y + 0.041556*Z
-	b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-	// Clip.
-	r = math.Min(math.Max(r, 0), 1.0)
-	g = math.Min(math.Max(g, 0), 1.0)
-	b = math.Min(math.Max(b, 0), 1.0)
+	r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 	return NewPdfColorDeviceRGB(r, g, b), nil
 }
@@ -1315,15 +1308,7 @@ func (cs *PdfColorspaceCalRGB) ImageToRGB(img Image) (Image, error) {
 		Z := cs.Matrix[2]*math.Pow(aVal, cs.Gamma[0]) + cs.Matrix[5]*math.Pow(bVal, cs.Gamma[1]) + cs.Matrix[8]*math.Pow(cVal, cs.Gamma[2])
 
 		// X, Y, Z -> R, G, B
-		// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-		r := 3.240479*X + -1.537150*Y + -0.498535*Z
-		g := -0.969256*X + 1.875992*Y + 0.041556*Z
-		b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-		// Clip.
-		r = math.Min(math.Max(r, 0), 1.0)
-		g = math.Min(math.Max(g, 0), 1.0)
-		b = math.Min(math.Max(b, 0), 1.0)
+		r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 		// Convert to uint32.
 		R := uint32(r * maxVal)
@@ -1613,7 +1598,7 @@ func (cs *PdfColorspaceLab) ColorToRGB(color PdfColor) (PdfColor, error) {
 		if x >= 6.0/29 {
 			return x * x * x
 		}
-		return 108.0 / 841 * (x - 4/29)
+		return 108.0 / 841 * (x - 4.0/29)
 	}
 
 	lab, ok := color.(*PdfColorLab)
@@ -1639,15 +1624,7 @@ func (cs *PdfColorspaceLab) ColorToRGB(color PdfColor) (PdfColor, error) {
 
 	// Convert to RGB.
 	// X, Y, Z -> R, G, B
-	// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-	r := 3.240479*X + -1.537150*Y + -0.498535*Z
-	g := -0.969256*X + 1.875992*Y + 0.041556*Z
-	b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-	// Clip.
-	r = math.Min(math.Max(r, 0), 1.0)
-	g = math.Min(math.Max(g, 0), 1.0)
-	b = math.Min(math.Max(b, 0), 1.0)
+	r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 	return NewPdfColorDeviceRGB(r, g, b), nil
 }
@@ -1658,7 +1635,7 @@ func (cs *PdfColorspaceLab) ImageToRGB(img Image) (Image, error) {
 		if x >= 6.0/29 {
 			return x * x * x
 		}
-		return 108.0 / 841 * (x - 4/29)
+		return 108.0 / 841 * (x - 4.0/29)
 	}
 
 	rgbImage := img
@@ -1701,15 +1678,7 @@ func (cs *PdfColorspaceLab) ImageToRGB(img Image) (Image, error) {
 
 		// Convert to RGB.
 		// X, Y, Z -> R, G, B
-		// http://stackoverflow.com/questions/21576719/how-to-convert-cie-color-space-into-rgb-or-hex-color-code-in-php
-		r := 3.240479*X + -1.537150*Y + -0.498535*Z
-		g := -0.969256*X + 1.875992*Y + 0.041556*Z
-		b := 0.055648*X + -0.204043*Y + 1.057311*Z
-
-		// Clip.
-		r = math.Min(math.Max(r, 0), 1.0)
-		g = math.Min(math.Max(g, 0), 1.0)
-		b = math.Min(math.Max(b, 0), 1.0)
+		r, g, b := xyzToRGB(X, Y, Z, cs.WhitePoint)
 
 		// Convert to uint32.
 		R := uint32(r * maxVal)
@@ -1763,7 +1732,8 @@ func (this *PdfColorICCBased) ToInteger(bits int) []uint32 {
 // A conforming reader shall support ICC.1:2004:10 as required by PDF 1.7, which will enable it
 // to properly render all embedded ICC profiles regardless of the PDF version
 //
-// In the current implementation, we rely on the alternative colormap provided.
+// The colors are converted to RGB with the profile, or with the alternative colormap provided if
+// the profile is invalid or not supported.
 type PdfColorspaceICCBased struct {
 	N         int           // Number of color components (Required). Can be 1,3, or 4.
 	Alternate PdfColorspace // Alternate colorspace for non-conforming readers.
@@ -1775,6 +1745,7 @@ type PdfColorspaceICCBased struct {
 
 	container *core.PdfIndirectObject
 	stream    *core.PdfObjectStream
+	transform *iccBasedTransform // Transform of the colors to sRGB, created on first use.
 }
 
 // GetNumComponents returns the number of color components.
@@ -1989,8 +1960,16 @@ func (cs *PdfColorspaceICCBased) ColorFromPdfObjects(objects []core.PdfObject) (
 	return cs.Alternate.ColorFromPdfObjects(objects)
 }
 
-// ColorToRGB converts a ICCBased color to an RGB color.
+// ColorToRGB converts a ICCBased color to an RGB color with the ICC profile of the colorspace.
+// The alternate colorspace is used if the profile is invalid or not supported.
 func (cs *PdfColorspaceICCBased) ColorToRGB(color PdfColor) (PdfColor, error) {
+	if transform, profile := cs.rgbTransform(); transform != nil {
+		if vals, ok := colorComponents(color); ok && len(vals) == cs.N {
+			rgb := transform.Convert(iccInput(profile, vals))
+			return NewPdfColorDeviceRGB(rgb[0], rgb[1], rgb[2]), nil
+		}
+	}
+
 	if cs.Alternate == nil {
 		common.Log.Debug("ICC Based colorspace missing alternative")
 		if cs.N == 1 {
@@ -2015,8 +1994,31 @@ func (cs *PdfColorspaceICCBased) ColorToRGB(color PdfColor) (PdfColor, error) {
 	return cs.Alternate.ColorToRGB(color)
 }
 
-// ImageToRGB converts ICCBased colorspace image to RGB and returns the result.
+// ImageToRGB converts ICCBased colorspace image to RGB with the ICC profile of the colorspace
+// and returns the result. The alternate colorspace is used if the profile is invalid or not
+// supported.
 func (cs *PdfColorspaceICCBased) ImageToRGB(img Image) (Image, error) {
+	if transform, profile := cs.rgbTransform(); transform != nil && img.ColorComponents == cs.N {
+		decode := img.decode
+		if decode == nil {
+			decode = cs.Range
+		}
+		if len(decode) != 2*cs.N {
+			decode = make([]float64, 2*cs.N)
+			for i := 0; i < cs.N; i++ {
+				decode[2*i+1] = 1
+			}
+		}
+		input := func(vals []float64) []float64 {
+			return iccInput(profile, vals)
+		}
+		rgbImage, err := transformImageToRGB(img, decode, input, transform)
+		if err == nil {
+			return rgbImage, nil
+		}
+		common.Log.Debug("ICC Based image conversion failed: %v - using alternative", err)
+	}
+
 	if cs.Alternate == nil {
 		common.Log.Debug("ICC Based colorspace missing alternative")
 		if cs.N == 1 {
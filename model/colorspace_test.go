@@ -11,6 +11,8 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/unidoc/unipdf/v3/core"
 	"github.com/unidoc/unipdf/v3/internal/testutils"
 )
@@ -174,3 +176,53 @@ endstream endobj
 		t.Fatalf("Incorrect function obj number (got %d)", f.ObjectNumber)
 	}
 }
+
+// Tests that the whites of CIE-based colorspaces are converted to the sRGB white.
+func TestCIEBasedToRGB(t *testing.T) {
+	d65 := []float64{0.9505, 1, 1.089}
+	requireRGB := func(cs PdfColorspace, color PdfColor, expected float64) {
+		rgb, err := cs.ColorToRGB(color)
+		require.NoError(t, err)
+		for _, v := range rgb.(*PdfColorDeviceRGB) {
+			require.InDelta(t, expected, v, 1e-3)
+		}
+	}
+
+	lab := NewPdfColorspaceLab()
+	lab.WhitePoint = d65
+	requireRGB(lab, NewPdfColorLab(100, 0, 0), 1)
+	requireRGB(lab, NewPdfColorLab(50, 0, 0), 0.4663)
+
+	calGray := NewPdfColorspaceCalGray()
+	calGray.WhitePoint = []float64{0.9642, 1, 0.8249}
+	requireRGB(calGray, NewPdfColorCalGray(1), 1)
+
+	calRGB := NewPdfColorspaceCalRGB()
+	calRGB.WhitePoint = d65
+	calRGB.Matrix = []float64{0.4124, 0.2126, 0.0193, 0.3576, 0.7152, 0.1192, 0.1805, 0.0722, 0.9505}
+	requireRGB(calRGB, NewPdfColorCalRGB(1, 1, 1), 1)
+
+	// Neutral colors are adapted to neutral sRGB colors, encoded with the sRGB curve, whatever
+	// the white point.
+	lab.WhitePoint = []float64{0.9642, 1, 0.8249}
+	requireRGB(lab, NewPdfColorLab(100, 0, 0), 1)
+	requireRGB(lab, NewPdfColorLab(50, 0, 0), 0.4663)
+	calGray.Gamma = 1
+	requireRGB(calGray, NewPdfColorCalGray(0.5), 0.7354)
+	calGray.WhitePoint = d65
+	requireRGB(calGray, NewPdfColorCalGray(0.5), 0.7354)
+
+	// The images are converted as the colors.
+	img := Image{Width: 2, Height: 1, BitsPerComponent: 8, ColorComponents: 3,
+		Data: []byte{255, 128, 128, 0, 128, 128}}
+	img.decode = []float64{0, 100, -128, 127, -128, 127}
+	lab.Range = []float64{-128, 127, -128, 127}
+	rgbImg, err := lab.ImageToRGB(img)
+	require.NoError(t, err)
+	require.Equal(t, 3, rgbImg.ColorComponents)
+	samples := rgbImg.GetSamples()
+	require.Len(t, samples, 6)
+	for i, v := range []uint32{255, 255, 255, 0, 0, 0} {
+		require.InDelta(t, v, samples[i], 1, "samples=%v", samples)
+	}
+}
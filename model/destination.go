@@ -0,0 +1,551 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfDestinationType represents the type of the view of an explicit destination
+// (Table 151 - p. 366).
+type PdfDestinationType int
+
+// Destination types.
+const (
+	// PdfDestinationTypeXYZ displays the page with the coordinates (Left, Top) at the upper-left
+	// corner of the window, magnified by the factor Zoom.
+	PdfDestinationTypeXYZ PdfDestinationType = iota
+
+	// PdfDestinationTypeFit displays the page magnified to fit the window.
+	PdfDestinationTypeFit
+
+	// PdfDestinationTypeFitH displays the page with the coordinate Top at the top edge of the
+	// window, magnified to fit the width of the page in the window.
+	PdfDestinationTypeFitH
+
+	// PdfDestinationTypeFitV displays the page with the coordinate Left at the left edge of the
+	// window, magnified to fit the height of the page in the window.
+	PdfDestinationTypeFitV
+
+	// PdfDestinationTypeFitR displays the page magnified to fit the rectangle (Left, Bottom,
+	// Right, Top) in the window.
+	PdfDestinationTypeFitR
+
+	// PdfDestinationTypeFitB, PdfDestinationTypeFitBH and PdfDestinationTypeFitBV are like Fit,
+	// FitH and FitV, fitting the bounding box of the page contents instead of the page.
+	PdfDestinationTypeFitB
+	PdfDestinationTypeFitBH
+	PdfDestinationTypeFitBV
+)
+
+var pdfDestinationTypeNames = map[PdfDestinationType]string{
+	PdfDestinationTypeXYZ:   "XYZ",
+	PdfDestinationTypeFit:   "Fit",
+	PdfDestinationTypeFitH:  "FitH",
+	PdfDestinationTypeFitV:  "FitV",
+	PdfDestinationTypeFitR:  "FitR",
+	PdfDestinationTypeFitB:  "FitB",
+	PdfDestinationTypeFitBH: "FitBH",
+	PdfDestinationTypeFitBV: "FitBV",
+}
+
+// String returns the name of the destination type, as used in explicit destinations.
+func (t PdfDestinationType) String() string {
+	if name, ok := pdfDestinationTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("PdfDestinationType(%d)", int(t))
+}
+
+// PdfDestination represents a destination: a named destination, or an explicit destination
+// specifying a page and a view of the page.
+// See section 12.3.2 "Destinations" (pp. 366-367 PDF32000_2008).
+type PdfDestination struct {
+	// Name is the name of a named destination, which is resolved through the Dests name tree
+	// (see PdfReader.ResolveDestination). The other fields are not used for named destinations.
+	Name string
+
+	// Page is the target page of local explicit destinations.
+	Page *PdfPage
+
+	// PageNum is the target page number (starting from 1) of explicit destinations. It is set
+	// for the local destinations loaded by a reader and specifies the target page of the remote
+	// destinations (of GoToR actions).
+	PageNum int
+
+	// Type is the type of view of the page, which uses the coordinates of the view: Left, Top and
+	// Zoom for XYZ, Top for FitH and FitBH, Left for FitV and FitBV and all the coordinates but
+	// Zoom for FitR. Nil values are written as null: the current value is kept.
+	Type   PdfDestinationType
+	Left   *float64
+	Bottom *float64
+	Right  *float64
+	Top    *float64
+	Zoom   *float64
+
+	// pageObj is the target page object of a loaded destination whose page could not be resolved.
+	pageObj core.PdfObject
+	// remote is true for the destinations in other documents, whose target page is PageNum.
+	remote bool
+	// isName is true if the name of a loaded named destination is a name object rather than a
+	// string (PDF 1.1).
+	isName bool
+}
+
+// NewPdfDestinationNamed returns a named destination.
+func NewPdfDestinationNamed(name string) *PdfDestination {
+	return &PdfDestination{Name: name}
+}
+
+// NewPdfDestinationXYZ returns an explicit destination displaying `page` with the coordinates
+// (`left`, `top`) at the upper-left corner of the window, magnified by the factor `zoom`
+// (0 keeps the current zoom).
+// Returns nil if `page` is nil.
+func NewPdfDestinationXYZ(page *PdfPage, left, top, zoom float64) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{
+		Page: page,
+		Type: PdfDestinationTypeXYZ,
+		Left: &left,
+		Top:  &top,
+		Zoom: &zoom,
+	}
+}
+
+// NewPdfDestinationFit returns an explicit destination displaying `page` magnified to fit the
+// window.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFit(page *PdfPage) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFit}
+}
+
+// NewPdfDestinationFitH returns an explicit destination displaying `page` with the coordinate
+// `top` at the top edge of the window, magnified to fit the width of the page.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitH(page *PdfPage, top float64) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFitH, Top: &top}
+}
+
+// NewPdfDestinationFitV returns an explicit destination displaying `page` with the coordinate
+// `left` at the left edge of the window, magnified to fit the height of the page.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitV(page *PdfPage, left float64) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFitV, Left: &left}
+}
+
+// NewPdfDestinationFitR returns an explicit destination displaying `page` magnified to fit the
+// rectangle `rect` in the window.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitR(page *PdfPage, rect PdfRectangle) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{
+		Page:   page,
+		Type:   PdfDestinationTypeFitR,
+		Left:   &rect.Llx,
+		Bottom: &rect.Lly,
+		Right:  &rect.Urx,
+		Top:    &rect.Ury,
+	}
+}
+
+// NewPdfDestinationFitB returns an explicit destination displaying `page` magnified to fit the
+// bounding box of its contents in the window.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitB(page *PdfPage) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFitB}
+}
+
+// NewPdfDestinationFitBH returns an explicit destination displaying `page` with the coordinate
+// `top` at the top edge of the window, magnified to fit the width of the bounding box of its
+// contents.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitBH(page *PdfPage, top float64) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFitBH, Top: &top}
+}
+
+// NewPdfDestinationFitBV returns an explicit destination displaying `page` with the coordinate
+// `left` at the left edge of the window, magnified to fit the height of the bounding box of its
+// contents.
+// Returns nil if `page` is nil.
+func NewPdfDestinationFitBV(page *PdfPage, left float64) *PdfDestination {
+	if page == nil {
+		return nilPageDestination()
+	}
+	return &PdfDestination{Page: page, Type: PdfDestinationTypeFitBV, Left: &left}
+}
+
+// nilPageDestination logs the creation of a local explicit destination without target page,
+// which is rejected, and returns nil.
+func nilPageDestination() *PdfDestination {
+	common.Log.Debug("ERROR: Destination without target page")
+	return nil
+}
+
+// NewPdfDestinationRemote returns an explicit destination to page `pageNum` (starting from 1) of
+// another document, for GoToR actions, displaying the page magnified to fit the window. The view
+// can be changed by setting the type and the coordinates of the returned destination.
+func NewPdfDestinationRemote(pageNum int) *PdfDestination {
+	return &PdfDestination{PageNum: pageNum, Type: PdfDestinationTypeFit, remote: true}
+}
+
+// IsNamed returns true if `dest` is a named destination.
+func (dest *PdfDestination) IsNamed() bool {
+	return dest.Name != ""
+}
+
+// Validate returns an error if the destination cannot be written: if it is a local explicit
+// destination without target page.
+func (dest *PdfDestination) Validate() error {
+	if dest.IsNamed() || dest.Page != nil || dest.remote || dest.pageObj != nil {
+		return nil
+	}
+	return errors.New("local destination without target page")
+}
+
+// ToPdfObject returns the PDF representation of the destination: a string (or a name if loaded as
+// such) for named destinations and an array for explicit destinations. The target page of the
+// local explicit destinations without target page is written as null (see Validate).
+func (dest *PdfDestination) ToPdfObject() core.PdfObject {
+	if dest.IsNamed() {
+		if dest.isName {
+			return core.MakeName(dest.Name)
+		}
+		return core.MakeString(dest.Name)
+	}
+
+	var page core.PdfObject
+	switch {
+	case dest.Page != nil:
+		page = dest.Page.GetPageAsIndirectObject()
+	case dest.remote:
+		page = core.MakeInteger(int64(dest.PageNum - 1))
+	case dest.pageObj != nil:
+		page = dest.pageObj
+	default:
+		common.Log.Debug("ERROR: Local destination without target page")
+		page = core.MakeNull()
+	}
+	arr := core.MakeArray(page, core.MakeName(dest.Type.String()))
+
+	var coords []*float64
+	switch dest.Type {
+	case PdfDestinationTypeXYZ:
+		coords = []*float64{dest.Left, dest.Top, dest.Zoom}
+	case PdfDestinationTypeFitH, PdfDestinationTypeFitBH:
+		coords = []*float64{dest.Top}
+	case PdfDestinationTypeFitV, PdfDestinationTypeFitBV:
+		coords = []*float64{dest.Left}
+	case PdfDestinationTypeFitR:
+		coords = []*float64{dest.Left, dest.Bottom, dest.Right, dest.Top}
+	}
+	for _, c := range coords {
+		if c == nil {
+			arr.Append(core.MakeNull())
+		} else {
+			arr.Append(core.MakeFloat(*c))
+		}
+	}
+	return arr
+}
+
+// NewPdfDestinationFromObject loads a destination from `obj`: a named destination (string or
+// name), an explicit destination (array) or a destination dictionary (with a D entry). The
+// pages of the explicit destinations are not resolved: only the page numbers of the
+// destinations to page indexes (remote destinations) are set. Use PdfReader.GetDestination to
+// load destinations of a document.
+func NewPdfDestinationFromObject(obj core.PdfObject) (*PdfDestination, error) {
+	return newPdfDestinationFromObject(obj, nil)
+}
+
+// GetDestination loads the destination `obj` of the document (see NewPdfDestinationFromObject),
+// resolving the target pages of the explicit destinations.
+func (r *PdfReader) GetDestination(obj core.PdfObject) (*PdfDestination, error) {
+	return newPdfDestinationFromObject(obj, r)
+}
+
+// ResolveDestination returns the explicit destination of `dest`: the destination named by `dest`
+// in the document for named destinations, `dest` otherwise.
+func (r *PdfReader) ResolveDestination(dest *PdfDestination) (*PdfDestination, error) {
+	if dest == nil || !dest.IsNamed() {
+		return dest, nil
+	}
+	obj := r.namedDestination(dest.Name)
+	if obj == nil {
+		return nil, fmt.Errorf("named destination %q not found", dest.Name)
+	}
+	resolved, err := r.GetDestination(obj)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.IsNamed() {
+		return nil, fmt.Errorf("named destination %q is not an explicit destination", dest.Name)
+	}
+	return resolved, nil
+}
+
+// namedDestination returns the explicit destination of named destination `name`, from the Dests
+// name tree or from the Dests dictionary of the catalog (PDF 1.1), or nil if not found.
+func (r *PdfReader) namedDestination(name string) core.PdfObject {
+	var dest core.PdfObject
+	tree, err := r.GetNameTree("Dests")
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load Dests name tree: %v", err)
+	}
+	if tree != nil {
+		dest, _ = tree.Get(name)
+	}
+	if dest == nil {
+		if dict, ok := core.GetDict(r.catalog.Get("Dests")); ok {
+			dest = dict.Get(core.PdfObjectName(name))
+		}
+	}
+
+	dest = core.ResolveReference(dest)
+	if dict, ok := core.GetDict(dest); ok {
+		dest = core.ResolveReference(dict.Get("D"))
+	}
+	return dest
+}
+
+// newPdfDestinationFromObject loads destination `obj`, resolving the target pages through `r` if
+// not nil.
+func newPdfDestinationFromObject(obj core.PdfObject, r *PdfReader) (*PdfDestination, error) {
+	obj = core.ResolveReference(obj)
+	switch t := obj.(type) {
+	case *core.PdfObjectString:
+		return NewPdfDestinationNamed(t.Str()), nil
+	case *core.PdfObjectName:
+		dest := NewPdfDestinationNamed(string(*t))
+		dest.isName = true
+		return dest, nil
+	case *core.PdfObjectDictionary:
+		// The /D entry of a destination dictionary holds the destination itself, which is not
+		// followed further to avoid looping on dictionaries referring to themselves.
+		d := core.ResolveReference(t.Get("D"))
+		if _, ok := d.(*core.PdfObjectDictionary); ok {
+			return nil, errors.New("invalid destination dictionary")
+		}
+		return newPdfDestinationFromObject(d, r)
+	}
+
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() < 2 {
+		return nil, errors.New("invalid destination")
+	}
+	typeName, ok := core.GetNameVal(arr.Get(1))
+	if !ok {
+		return nil, errors.New("invalid destination type")
+	}
+	dest := &PdfDestination{}
+	found := false
+	for t, name := range pdfDestinationTypeNames {
+		if name == typeName {
+			dest.Type, found = t, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unsupported destination type %s", typeName)
+	}
+
+	// Coordinates, null values are nil.
+	coord := func(i int) *float64 {
+		if i >= arr.Len() {
+			return nil
+		}
+		val, err := core.GetNumberAsFloat(core.ResolveReference(arr.Get(i)))
+		if err != nil {
+			return nil
+		}
+		return &val
+	}
+	switch dest.Type {
+	case PdfDestinationTypeXYZ:
+		dest.Left, dest.Top, dest.Zoom = coord(2), coord(3), coord(4)
+	case PdfDestinationTypeFitH, PdfDestinationTypeFitBH:
+		dest.Top = coord(2)
+	case PdfDestinationTypeFitV, PdfDestinationTypeFitBV:
+		dest.Left = coord(2)
+	case PdfDestinationTypeFitR:
+		dest.Left, dest.Bottom, dest.Right, dest.Top = coord(2), coord(3), coord(4), coord(5)
+	}
+
+	// Target page: a page object, or a page index for remote destinations and the outline
+	// destinations created with NewOutlineDest.
+	page := arr.Get(0)
+	if idx, ok := core.GetIntVal(page); ok {
+		dest.PageNum = idx + 1
+		if r != nil && idx >= 0 && idx < len(r.PageList) {
+			dest.Page = r.PageList[idx]
+		} else {
+			dest.pageObj = page
+		}
+		return dest, nil
+	}
+	if r != nil {
+		if ind, ok := core.GetIndirect(page); ok {
+			if p, pageNum, err := r.PageFromIndirectObject(ind); err == nil {
+				dest.Page, dest.PageNum = p, pageNum
+				return dest, nil
+			}
+		}
+	}
+	dest.pageObj = page
+	return dest, nil
+}
+
+// getGoToDestination returns the destination of GoTo action `obj`, or nil if `obj` is not a GoTo
+// action.
+func getGoToDestination(obj core.PdfObject) core.PdfObject {
+	action, ok := core.GetDict(obj)
+	if !ok {
+		return nil
+	}
+	if name, _ := core.GetNameVal(action.Get("S")); name != "GoTo" {
+		return nil
+	}
+	return action.Get("D")
+}
+
+// GetDestination returns the destination of the outline item: its Dest entry or the
+// destination of its GoTo action. Returns nil if the item has no destination.
+func (item *PdfOutlineItem) GetDestination() (*PdfDestination, error) {
+	obj := item.Dest
+	if obj == nil {
+		obj = getGoToDestination(item.A)
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return newPdfDestinationFromObject(obj, item.reader)
+}
+
+// SetDestination sets the destination of the outline item to `dest`, replacing its action.
+func (item *PdfOutlineItem) SetDestination(dest *PdfDestination) error {
+	obj, err := destinationObject(dest)
+	if err != nil {
+		return err
+	}
+	item.A = nil
+	item.Dest = obj
+	return nil
+}
+
+// GetDestination returns the destination of the link annotation: its Dest entry or the
+// destination of its GoTo action. Returns nil if the link has no destination.
+func (a *PdfAnnotationLink) GetDestination() (*PdfDestination, error) {
+	obj := a.Dest
+	if obj == nil && a.action == nil {
+		obj = getGoToDestination(a.A)
+	}
+	if obj == nil && a.action != nil {
+		if goTo, ok := a.action.GetContext().(*PdfActionGoTo); ok {
+			obj = goTo.D
+		}
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return newPdfDestinationFromObject(obj, a.reader)
+}
+
+// SetDestination sets the destination of the link annotation to `dest`, replacing its action.
+func (a *PdfAnnotationLink) SetDestination(dest *PdfDestination) error {
+	obj, err := destinationObject(dest)
+	if err != nil {
+		return err
+	}
+	a.SetAction(nil)
+	a.Dest = obj
+	return nil
+}
+
+// NewPdfActionGoToDestination returns a GoTo action to destination `dest`.
+func NewPdfActionGoToDestination(dest *PdfDestination) (*PdfActionGoTo, error) {
+	action := NewPdfActionGoTo()
+	if err := action.SetDestination(dest); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// GetDestination returns the destination of the action. The target pages are not resolved
+// (see NewPdfDestinationFromObject).
+func (a *PdfActionGoTo) GetDestination() (*PdfDestination, error) {
+	if a.D == nil {
+		return nil, nil
+	}
+	return NewPdfDestinationFromObject(a.D)
+}
+
+// SetDestination sets the destination of the action to `dest`.
+func (a *PdfActionGoTo) SetDestination(dest *PdfDestination) error {
+	obj, err := destinationObject(dest)
+	if err != nil {
+		return err
+	}
+	a.D = obj
+	return nil
+}
+
+// GetDestination returns the destination in the remote document of the action.
+func (a *PdfActionGoToR) GetDestination() (*PdfDestination, error) {
+	if a.D == nil {
+		return nil, nil
+	}
+	dest, err := NewPdfDestinationFromObject(a.D)
+	if err != nil {
+		return nil, err
+	}
+	dest.remote = !dest.IsNamed()
+	return dest, nil
+}
+
+// SetDestination sets the destination in the remote document of the action to `dest`, a named
+// destination or a remote destination (see NewPdfDestinationRemote).
+func (a *PdfActionGoToR) SetDestination(dest *PdfDestination) error {
+	obj, err := destinationObject(dest)
+	if err != nil {
+		return err
+	}
+	a.D = obj
+	return nil
+}
+
+// destinationObject returns the PDF representation of `dest`, or nil if `dest` is nil.
+// Returns an error if `dest` is not valid (see PdfDestination.Validate).
+func destinationObject(dest *PdfDestination) (core.PdfObject, error) {
+	if dest == nil {
+		return nil, nil
+	}
+	if err := dest.Validate(); err != nil {
+		return nil, err
+	}
+	return dest.ToPdfObject(), nil
+}
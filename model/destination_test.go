@@ -0,0 +1,206 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestPdfDestinationToPdfObject(t *testing.T) {
+	page := NewPdfPage()
+	testcases := []struct {
+		dest     *PdfDestination
+		expected string
+	}{
+		{NewPdfDestinationXYZ(page, 10, 20, 0), "XYZ 10 20 0]"},
+		{&PdfDestination{Page: page, Top: new(float64)}, "XYZ null 0 null]"},
+		{NewPdfDestinationFit(page), "Fit]"},
+		{NewPdfDestinationFitH(page, 20), "FitH 20]"},
+		{NewPdfDestinationFitV(page, 10), "FitV 10]"},
+		{NewPdfDestinationFitR(page, PdfRectangle{Llx: 1, Lly: 2, Urx: 3, Ury: 4}),
+			"FitR 1 2 3 4]"},
+		{NewPdfDestinationFitB(page), "FitB]"},
+		{NewPdfDestinationFitBH(page, 20), "FitBH 20]"},
+		{NewPdfDestinationFitBV(page, 10), "FitBV 10]"},
+	}
+	for _, tc := range testcases {
+		require.NoError(t, tc.dest.Validate())
+		obj := tc.dest.ToPdfObject()
+		arr, ok := core.GetArray(obj)
+		require.True(t, ok)
+		require.Equal(t, page.GetPageAsIndirectObject(), arr.Get(0))
+		require.Contains(t, obj.WriteString(), "/"+tc.expected)
+
+		// Loading does not resolve the page of the destination but keeps it.
+		dest, err := NewPdfDestinationFromObject(obj)
+		require.NoError(t, err)
+		require.Nil(t, dest.Page)
+		loaded := dest.ToPdfObject()
+		require.Equal(t, obj.WriteString(), loaded.WriteString())
+		dest.Page, dest.pageObj = page, nil
+		require.Equal(t, tc.dest, dest)
+	}
+
+	obj := NewPdfDestinationRemote(3).ToPdfObject()
+	require.Equal(t, "[2 /Fit]", obj.WriteString())
+	dest, err := NewPdfDestinationFromObject(obj)
+	require.NoError(t, err)
+	require.Equal(t, 3, dest.PageNum)
+
+	// The local destinations need a target page.
+	require.Error(t, (&PdfDestination{PageNum: 2, Type: PdfDestinationTypeFit}).Validate())
+	require.Nil(t, NewPdfDestinationFit(nil))
+	require.Error(t, NewPdfActionGoTo().SetDestination(&PdfDestination{PageNum: 2}))
+
+	// The named destinations keep the type of their name.
+	obj = NewPdfDestinationNamed("chapter1").ToPdfObject()
+	require.Equal(t, "(chapter1)", obj.WriteString())
+	for _, obj := range []core.PdfObject{obj, core.MakeName("chapter1")} {
+		dest, err = NewPdfDestinationFromObject(obj)
+		require.NoError(t, err)
+		require.True(t, dest.IsNamed())
+		require.Equal(t, "chapter1", dest.Name)
+		written := dest.ToPdfObject()
+		require.Equal(t, obj.WriteString(), written.WriteString())
+	}
+
+	// Destination dictionaries referring to themselves.
+	selfDict := core.MakeDict()
+	selfRef := core.MakeIndirectObject(selfDict)
+	selfDict.Set("D", selfRef)
+
+	for _, obj := range []core.PdfObject{
+		core.MakeInteger(1),
+		core.MakeArray(core.MakeInteger(0)),
+		core.MakeArray(core.MakeInteger(0), core.MakeName("Unknown")),
+		selfRef,
+	} {
+		_, err = NewPdfDestinationFromObject(obj)
+		require.Error(t, err)
+	}
+}
+
+func TestReaderGetDestination(t *testing.T) {
+	r := makeMergeTestDocument(t)
+	var pages []*PdfPage
+	for i := 1; i <= 3; i++ {
+		page, err := r.GetPage(i)
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+
+	// Outline items with page indexes.
+	item := r.GetOutlineTree().First.context.(*PdfOutlineItem)
+	dest, err := item.GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, PdfDestinationTypeXYZ, dest.Type)
+	require.Equal(t, pages[0], dest.Page)
+	require.Equal(t, 1, dest.PageNum)
+	require.Equal(t, 200.0, *dest.Top)
+
+	// Link with an explicit destination.
+	annotations, err := pages[0].GetAnnotations()
+	require.NoError(t, err)
+	link := annotations[0].GetContext().(*PdfAnnotationLink)
+	dest, err = link.GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, PdfDestinationTypeFit, dest.Type)
+	require.Equal(t, pages[2], dest.Page)
+	require.Equal(t, 3, dest.PageNum)
+
+	// Link with a GoTo action to a named destination.
+	annotations, err = pages[1].GetAnnotations()
+	require.NoError(t, err)
+	link = annotations[0].GetContext().(*PdfAnnotationLink)
+	action, err := link.GetAction()
+	require.NoError(t, err)
+	require.NotNil(t, action)
+	dest, err = link.GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, "p2", dest.Name)
+	dest, err = r.ResolveDestination(dest)
+	require.NoError(t, err)
+	require.Equal(t, pages[1], dest.Page)
+	require.Equal(t, 2, dest.PageNum)
+
+	_, err = r.ResolveDestination(NewPdfDestinationNamed("missing"))
+	require.Error(t, err)
+}
+
+func TestSetDestination(t *testing.T) {
+	w := NewPdfWriter()
+	var pages []*PdfPage
+	for i := 0; i < 2; i++ {
+		page := newTestPage(t, 200, 200, "")
+		pages = append(pages, page)
+	}
+
+	link := NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+	link.SetAction(NewPdfActionGoTo().PdfAction)
+	require.NoError(t, link.SetDestination(NewPdfDestinationFitH(pages[1], 150)))
+	require.Nil(t, link.A)
+	pages[0].AddAnnotation(link.PdfAnnotation)
+
+	goToR := NewPdfActionGoToR()
+	goToR.F, _ = NewPdfFilespecFromObj(core.MakeString("other.pdf"))
+	require.NoError(t, goToR.SetDestination(NewPdfDestinationRemote(5)))
+	link = NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{60, 10, 100, 50})
+	link.SetAction(goToR.PdfAction)
+	pages[0].AddAnnotation(link.PdfAnnotation)
+
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+
+	outline := NewOutline()
+	item := NewOutlineItem("Second", OutlineDest{})
+	item.Destination = NewPdfDestinationFitR(pages[1], PdfRectangle{Llx: 10, Lly: 10, Urx: 100, Ury: 100})
+	outline.Add(item)
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+
+	r := writeRead(t, &w)
+	page, err := r.GetPage(2)
+	require.NoError(t, err)
+
+	outlineItem := r.GetOutlineTree().First.context.(*PdfOutlineItem)
+	dest, err := outlineItem.GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, PdfDestinationTypeFitR, dest.Type)
+	require.Equal(t, page, dest.Page)
+	require.Equal(t, 100.0, *dest.Right)
+
+	annotations, err := r.PageList[0].GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	dest, err = annotations[0].GetContext().(*PdfAnnotationLink).GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, PdfDestinationTypeFitH, dest.Type)
+	require.Equal(t, 2, dest.PageNum)
+	require.Equal(t, 150.0, *dest.Top)
+
+	action, err := annotations[1].GetContext().(*PdfAnnotationLink).GetAction()
+	require.NoError(t, err)
+	dest, err = action.GetContext().(*PdfActionGoToR).GetDestination()
+	require.NoError(t, err)
+	require.Nil(t, dest.Page)
+	require.Equal(t, 5, dest.PageNum)
+	dest.PageNum = 6
+	obj := dest.ToPdfObject()
+	require.Equal(t, "[5 /Fit]", obj.WriteString())
+
+	// Replacing the destination of an outline item.
+	require.NoError(t, outlineItem.SetDestination(NewPdfDestinationNamed("end")))
+	require.Error(t, outlineItem.SetDestination(&PdfDestination{PageNum: 1}))
+	dest, err = outlineItem.GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, "end", dest.Name)
+}
@@ -6,6 +6,7 @@
 package model
 
 import (
+	"crypto/md5"
 	"errors"
 
 	"github.com/unidoc/unipdf/v3/common"
@@ -176,3 +177,172 @@ func NewPdfFilespec() *PdfFilespec {
 	action.container = core.MakeIndirectObject(core.MakeDict())
 	return action
 }
+
+// GetEmbeddedFile returns the embedded file referred to by the EF dictionary of the
+// file specification. The UF entry is preferred over F when both are present.
+// Returns nil if the file specification does not contain an embedded file.
+func (f *PdfFilespec) GetEmbeddedFile() (*PdfEmbeddedFile, error) {
+	efDict, ok := core.GetDict(f.EF)
+	if !ok {
+		return nil, nil
+	}
+
+	obj := efDict.Get("UF")
+	if obj == nil {
+		obj = efDict.Get("F")
+	}
+	if obj == nil {
+		return nil, nil
+	}
+
+	return NewPdfEmbeddedFileFromObj(obj)
+}
+
+// GetFileName returns the file name of the file specification, preferring the Unicode UF
+// entry over F.
+func (f *PdfFilespec) GetFileName() string {
+	if str, ok := core.GetString(f.UF); ok {
+		return str.Decoded()
+	}
+	if str, ok := core.GetString(f.F); ok {
+		return str.Decoded()
+	}
+	return ""
+}
+
+// NewPdfFilespecFromEmbeddedFile returns a new file specification which embeds `ef`
+// under file name `name`. The name is set to both the F and UF entries.
+func NewPdfFilespecFromEmbeddedFile(name string, ef *PdfEmbeddedFile) *PdfFilespec {
+	fs := NewPdfFilespec()
+	fs.F = core.MakeString(name)
+	fs.UF = core.MakeTextString(name)
+
+	stream := ef.ToPdfObject()
+	fs.EF = core.MakeDict()
+	fs.EF.(*core.PdfObjectDictionary).Set("F", stream)
+	fs.EF.(*core.PdfObjectDictionary).Set("UF", stream)
+	return fs
+}
+
+// PdfEmbeddedFile represents an embedded file stream (section 7.11.4 p. 104).
+// See Table 45 - Additional entries in an embedded file stream dictionary.
+type PdfEmbeddedFile struct {
+	// Subtype is the MIME media type of the embedded file, e.g. "application/pdf".
+	Subtype string
+
+	// File parameters (Table 46). Zero values are not written.
+	Size         int64
+	CreationDate *PdfDate
+	ModDate      *PdfDate
+	CheckSum     []byte
+
+	// Data is the decoded content of the file.
+	Data []byte
+
+	container *core.PdfObjectStream
+}
+
+// NewPdfEmbeddedFile returns a new embedded file containing `data` of MIME type `mimeType`.
+// The Size and CheckSum parameters are computed from `data`.
+func NewPdfEmbeddedFile(data []byte, mimeType string) *PdfEmbeddedFile {
+	sum := md5.Sum(data)
+	return &PdfEmbeddedFile{
+		Subtype:  mimeType,
+		Size:     int64(len(data)),
+		CheckSum: sum[:],
+		Data:     data,
+	}
+}
+
+// NewPdfEmbeddedFileFromObj loads an embedded file from an embedded file stream object.
+func NewPdfEmbeddedFileFromObj(obj core.PdfObject) (*PdfEmbeddedFile, error) {
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		common.Log.Debug("Embedded file not a stream (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := &PdfEmbeddedFile{
+		Data:      data,
+		Size:      int64(len(data)),
+		container: stream,
+	}
+	if subtype, ok := core.GetNameVal(stream.Get("Subtype")); ok {
+		ef.Subtype = subtype
+	}
+
+	params, ok := core.GetDict(stream.Get("Params"))
+	if !ok {
+		return ef, nil
+	}
+	if size, ok := core.GetIntVal(params.Get("Size")); ok {
+		ef.Size = int64(size)
+	}
+	if str, ok := core.GetString(params.Get("CreationDate")); ok {
+		if date, err := NewPdfDate(str.Str()); err == nil {
+			ef.CreationDate = &date
+		}
+	}
+	if str, ok := core.GetString(params.Get("ModDate")); ok {
+		if date, err := NewPdfDate(str.Str()); err == nil {
+			ef.ModDate = &date
+		}
+	}
+	if sum, ok := core.GetStringBytes(params.Get("CheckSum")); ok {
+		ef.CheckSum = sum
+	}
+
+	return ef, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (ef *PdfEmbeddedFile) GetContainingPdfObject() core.PdfObject {
+	return ef.container
+}
+
+// ToPdfObject implements interface PdfModel. The data is Flate encoded the first time the
+// stream is created.
+func (ef *PdfEmbeddedFile) ToPdfObject() core.PdfObject {
+	if ef.container == nil {
+		stream, err := core.MakeStream(ef.Data, core.NewFlateEncoder())
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to encode embedded file: %v", err)
+			stream, _ = core.MakeStream(ef.Data, nil)
+		}
+		ef.container = stream
+	}
+
+	d := ef.container.PdfObjectDictionary
+	d.Set("Type", core.MakeName("EmbeddedFile"))
+	if ef.Subtype != "" {
+		d.Set("Subtype", core.MakeName(ef.Subtype))
+	} else {
+		d.Remove("Subtype")
+	}
+
+	params := core.MakeDict()
+	if ef.Size > 0 {
+		params.Set("Size", core.MakeInteger(ef.Size))
+	}
+	if ef.CreationDate != nil {
+		params.Set("CreationDate", ef.CreationDate.ToPdfObject())
+	}
+	if ef.ModDate != nil {
+		params.Set("ModDate", ef.ModDate.ToPdfObject())
+	}
+	if len(ef.CheckSum) > 0 {
+		params.Set("CheckSum", core.MakeHexString(string(ef.CheckSum)))
+	}
+	if len(params.Keys()) > 0 {
+		d.Set("Params", params)
+	} else {
+		d.Remove("Params")
+	}
+
+	return ef.container
+}
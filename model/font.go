@@ -843,6 +843,10 @@ type PdfFontDescriptor struct {
 	*fontFile
 	fontFile2 *fonts.TtfType
 
+	// program is the embedded font program providing glyph outlines, loaded on first use.
+	program       *fontProgram
+	programLoaded bool
+
 	// Additional entries for CIDFonts
 	Style  core.PdfObject
 	Lang   core.PdfObject
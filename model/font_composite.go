@@ -231,6 +231,11 @@ type pdfCIDFontType0 struct {
 	// Table 117 – Entries in a CIDFont dictionary (page 269)
 	CIDSystemInfo *core.PdfObjectDictionary // (Required) Dictionary that defines the character
 	// collection of the CIDFont. See Table 116.
+	DW core.PdfObject // (Optional) The default width for glyphs in the CIDFont.
+	W  core.PdfObject // (Optional) The widths for the glyphs in the CIDFont.
+
+	widths       map[textencoding.CharCode]float64
+	defaultWidth float64
 }
 
 // pdfCIDFontType0FromSkeleton returns a pdfCIDFontType0 with its common fields initalized.
@@ -262,7 +267,10 @@ func (font pdfCIDFontType0) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
 
 // GetCharMetrics returns the char metrics for character code `code`.
 func (font pdfCIDFontType0) GetCharMetrics(code textencoding.CharCode) (fonts.CharMetrics, bool) {
-	return fonts.CharMetrics{}, true
+	if w, ok := font.widths[code]; ok {
+		return fonts.CharMetrics{Wx: w}, true
+	}
+	return fonts.CharMetrics{Wx: font.defaultWidth}, true
 }
 
 // ToPdfObject converts the pdfCIDFontType0 to a PDF representation.
@@ -288,6 +296,22 @@ func newPdfCIDFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	}
 	font.CIDSystemInfo = obj
 
+	// Optional attributes.
+	font.DW = d.Get("DW")
+	font.W = d.Get("W")
+
+	widths, err := parseCIDFontWidthsArray(font.W)
+	if err != nil {
+		// The glyphs have the default width.
+		common.Log.Debug("ERROR: Invalid W array: %v. font=%s", err, base)
+	}
+	font.widths = widths
+	if defaultWidth, err := core.GetNumberAsFloat(font.DW); err == nil {
+		font.defaultWidth = defaultWidth
+	} else {
+		font.defaultWidth = 1000.0
+	}
+
 	return font, nil
 }
 
@@ -423,51 +447,11 @@ func newPdfCIDFontType2FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	font.W2 = d.Get("W2")
 	font.CIDToGIDMap = d.Get("CIDToGIDMap")
 
-	if arr2, ok := core.GetArray(font.W); ok {
-		font.widths = make(map[textencoding.CharCode]float64)
-		for i := 0; i < arr2.Len()-1; i++ {
-			obj0 := (*arr2).Get(i)
-			n, ok0 := core.GetIntVal(obj0)
-			if !ok0 {
-				return nil, fmt.Errorf("Bad font W obj0: i=%d %#v", i, obj0)
-			}
-			i++
-			if i > arr2.Len()-1 {
-				return nil, fmt.Errorf("Bad font W array: arr2=%+v", arr2)
-			}
-			obj1 := (*arr2).Get(i)
-			switch obj1.(type) {
-			case *core.PdfObjectArray:
-				arr, _ := core.GetArray(obj1)
-				if widths, err := arr.ToFloat64Array(); err == nil {
-					for j := 0; j < len(widths); j++ {
-						font.widths[textencoding.CharCode(n+j)] = widths[j]
-					}
-				} else {
-					return nil, fmt.Errorf("Bad font W array obj1: i=%d %#v", i, obj1)
-				}
-			case *core.PdfObjectInteger:
-				n1, ok1 := core.GetIntVal(obj1)
-				if !ok1 {
-					return nil, fmt.Errorf("Bad font W int obj1: i=%d %#v", i, obj1)
-				}
-				i++
-				if i > arr2.Len()-1 {
-					return nil, fmt.Errorf("Bad font W array: arr2=%+v", arr2)
-				}
-				obj2 := (*arr2).Get(i)
-				v, err := core.GetNumberAsFloat(obj2)
-				if err != nil {
-					return nil, fmt.Errorf("Bad font W int obj2: i=%d %#v", i, obj2)
-				}
-				for j := n; j <= n1; j++ {
-					font.widths[textencoding.CharCode(j)] = v
-				}
-			default:
-				return nil, fmt.Errorf("Bad font W obj1 type: i=%d %#v", i, obj1)
-			}
-		}
+	widths, err := parseCIDFontWidthsArray(font.W)
+	if err != nil {
+		return nil, err
 	}
+	font.widths = widths
 	if defaultWidth, err := core.GetNumberAsFloat(font.DW); err == nil {
 		font.defaultWidth = defaultWidth
 	} else {
@@ -477,6 +461,58 @@ func newPdfCIDFontType2FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	return font, nil
 }
 
+// parseCIDFontWidthsArray returns the widths of the CIDs in the W array `w` of a CIDFont.
+func parseCIDFontWidthsArray(w core.PdfObject) (map[textencoding.CharCode]float64, error) {
+	arr2, ok := core.GetArray(w)
+	if !ok {
+		return nil, nil
+	}
+	widths := make(map[textencoding.CharCode]float64)
+	for i := 0; i < arr2.Len()-1; i++ {
+		obj0 := (*arr2).Get(i)
+		n, ok0 := core.GetIntVal(obj0)
+		if !ok0 {
+			return nil, fmt.Errorf("Bad font W obj0: i=%d %#v", i, obj0)
+		}
+		i++
+		if i > arr2.Len()-1 {
+			return nil, fmt.Errorf("Bad font W array: arr2=%+v", arr2)
+		}
+		obj1 := (*arr2).Get(i)
+		switch obj1.(type) {
+		case *core.PdfObjectArray:
+			arr, _ := core.GetArray(obj1)
+			if vals, err := arr.ToFloat64Array(); err == nil {
+				for j := 0; j < len(vals); j++ {
+					widths[textencoding.CharCode(n+j)] = vals[j]
+				}
+			} else {
+				return nil, fmt.Errorf("Bad font W array obj1: i=%d %#v", i, obj1)
+			}
+		case *core.PdfObjectInteger:
+			n1, ok1 := core.GetIntVal(obj1)
+			if !ok1 {
+				return nil, fmt.Errorf("Bad font W int obj1: i=%d %#v", i, obj1)
+			}
+			i++
+			if i > arr2.Len()-1 {
+				return nil, fmt.Errorf("Bad font W array: arr2=%+v", arr2)
+			}
+			obj2 := (*arr2).Get(i)
+			v, err := core.GetNumberAsFloat(obj2)
+			if err != nil {
+				return nil, fmt.Errorf("Bad font W int obj2: i=%d %#v", i, obj2)
+			}
+			for j := n; j <= n1; j++ {
+				widths[textencoding.CharCode(j)] = v
+			}
+		default:
+			return nil, fmt.Errorf("Bad font W obj1 type: i=%d %#v", i, obj1)
+		}
+	}
+	return widths, nil
+}
+
 // NewCompositePdfFontFromTTFFile loads a composite font from a TTF font file. Composite fonts can
 // be used to represent unicode fonts which can have multi-byte character codes, representing a wide
 // range of values.
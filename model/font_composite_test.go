@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
 	"github.com/unidoc/unipdf/v3/model/internal/fonts"
 )
 
@@ -59,3 +60,39 @@ func TestCIDWidthArr(t *testing.T) {
 		}
 	}
 }
+
+func TestCIDFontType0Widths(t *testing.T) {
+	newFont := func(w core.PdfObject, dw core.PdfObject) *pdfCIDFontType0 {
+		d := core.MakeDict()
+		d.Set("CIDSystemInfo", core.MakeDict())
+		d.Set("W", w)
+		d.Set("DW", dw)
+		font, err := newPdfCIDFontType0FromPdfObject(d, &fontCommon{subtype: "CIDFontType0"})
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return font
+	}
+	checkWidths := func(font *pdfCIDFontType0, exp map[textencoding.CharCode]float64) {
+		for code, w := range exp {
+			metrics, ok := font.GetCharMetrics(code)
+			if !ok || metrics.Wx != w {
+				t.Fatalf("code=%d: %v %v != %v", code, ok, metrics.Wx, w)
+			}
+		}
+	}
+
+	// Widths of the W array, and DW for the other CIDs.
+	w := core.MakeArray(core.MakeInteger(1), core.MakeArrayFromIntegers([]int{500, 600}),
+		core.MakeInteger(10), core.MakeInteger(12), core.MakeInteger(250))
+	checkWidths(newFont(w, core.MakeInteger(800)), map[textencoding.CharCode]float64{
+		0: 800, 1: 500, 2: 600, 3: 800, 10: 250, 12: 250, 13: 800,
+	})
+
+	// DW defaults to 1000.
+	checkWidths(newFont(w, nil), map[textencoding.CharCode]float64{0: 1000, 1: 500})
+
+	// A malformed W array is ignored.
+	bad := core.MakeArray(core.MakeInteger(1), core.MakeName("X"))
+	checkWidths(newFont(bad, core.MakeInteger(700)), map[textencoding.CharCode]float64{0: 700, 1: 700})
+}
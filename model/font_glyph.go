@@ -0,0 +1,481 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/cmap"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/model/internal/fonts"
+)
+
+// GlyphPath is the outline of a glyph, made of closed contours filled with the nonzero winding
+// number rule.
+type GlyphPath = fonts.GlyphPath
+
+// GlyphSegment is a segment of a glyph outline.
+type GlyphSegment = fonts.GlyphSegment
+
+// GlyphSegmentType is the type of a segment of a glyph outline.
+type GlyphSegmentType = fonts.GlyphSegmentType
+
+// GlyphPoint is a point of a glyph outline.
+type GlyphPoint = fonts.GlyphPoint
+
+// Glyph outline segment types.
+const (
+	GlyphMoveTo = fonts.GlyphMoveTo
+	GlyphLineTo = fonts.GlyphLineTo
+	GlyphQuadTo = fonts.GlyphQuadTo
+	GlyphCubeTo = fonts.GlyphCubeTo
+	GlyphClose  = fonts.GlyphClose
+)
+
+// fontProgram is a font program providing glyph outlines. Exactly one of the programs is set.
+type fontProgram struct {
+	ttf   *fonts.TtfType
+	cff   *fonts.CFFFont
+	type1 *fonts.Type1Font
+
+	// substitute is true for the fonts used in place of fonts that are not embedded.
+	substitute bool
+}
+
+// GetCharGlyph returns the outline of the glyph of character code `code` in glyph space, where
+// 1000 units are 1 unit of text space. Fonts that are not embedded are substituted by similar
+// fonts, scaled to the widths of the font. The returned path is nil if `code` maps to the .notdef
+// glyph, or to no glyph, of an embedded font program, and empty for blank glyphs such as the space.
+// An error is returned if the glyph cannot be loaded, e.g. when there is no font program or the
+// substitute font has no glyph for the character.
+// NOTE: Type 3 fonts are not supported.
+func (font *PdfFont) GetCharGlyph(code textencoding.CharCode) (*GlyphPath, error) {
+	return font.charGlyph(code)
+}
+
+// GetRuneGlyph returns the outline of the glyph of rune `r`, as GetCharGlyph does for the
+// character code encoding `r`.
+func (font *PdfFont) GetRuneGlyph(r rune) (*GlyphPath, error) {
+	encoder := font.Encoder()
+	if encoder == nil {
+		return nil, errors.New("no font encoder")
+	}
+	code, ok := encoder.RuneToCharcode(r)
+	if !ok {
+		return nil, fmt.Errorf("rune %q not encoded", r)
+	}
+	return font.GetCharGlyph(code)
+}
+
+// charGlyph returns the outline of the glyph of character code `code`, or nil if `code` maps to
+// no glyph of the font program.
+func (font *PdfFont) charGlyph(code textencoding.CharCode) (*GlyphPath, error) {
+	switch t := font.context.(type) {
+	case *pdfFontSimple:
+		return font.simpleCharGlyph(t, code)
+	case *pdfFontType0:
+		if t.DescendantFont == nil {
+			return nil, errors.New("no descendant font")
+		}
+		// NOTE: Only the identity mapping of character codes to CIDs is supported.
+		return font.cidGlyph(t.DescendantFont, uint16(code), code)
+	case *pdfCIDFontType0, *pdfCIDFontType2:
+		return font.cidGlyph(font, uint16(code), code)
+	}
+	return nil, ErrFontNotSupported
+}
+
+// simpleCharGlyph returns the outline of the glyph of character code `code` of simple font `t`.
+func (font *PdfFont) simpleCharGlyph(t *pdfFontSimple, code textencoding.CharCode) (*GlyphPath, error) {
+	if code > 0xff {
+		return nil, nil
+	}
+	desc := t.getFontDescriptor()
+	program := desc.getFontProgram(t.basefont)
+	if program == nil {
+		return nil, errors.New("no font program")
+	}
+
+	// The glyph name from the Differences of the font encoding or from the encoding.
+	var name textencoding.GlyphName
+	_, differences, err := t.getFontEncoding()
+	if err != nil {
+		common.Log.Debug("ERROR: Bad font encoding. font=%s err=%v", font, err)
+	}
+	r, hasRune := t.Encoder().CharcodeToRune(code)
+	if glyph, ok := differences[code]; ok {
+		name = glyph
+	} else if hasRune && t.Encoding != nil {
+		name, _ = textencoding.RuneToGlyph(r)
+	}
+	symbolic := t.fontFlags()&fontFlagSymbolic != 0
+
+	switch {
+	case program.substitute:
+		if tu := t.toUnicodeCmap; tu != nil && !hasRune {
+			r, hasRune = tu.CharcodeToUnicode(cmap.CharCode(code))
+		}
+		if !hasRune {
+			return nil, errors.New("no rune for substitute font")
+		}
+		gid, ok := program.ttf.Chars[r]
+		if !ok || gid == 0 {
+			return nil, fmt.Errorf("no substitute glyph for %q", r)
+		}
+		width := -1.0
+		if w, ok := t.charWidths[code]; ok {
+			width = w
+		} else if m, ok := t.fontMetrics[r]; ok {
+			width = m.Wx
+		}
+		return program.substituteGlyph(gid, width)
+
+	case program.ttf != nil:
+		ttf := program.ttf
+		var gid fonts.GID
+		found := false
+		lookup := func(m map[rune]fonts.GID, r rune) {
+			if !found {
+				gid, found = m[r]
+			}
+		}
+		if symbolic && t.Encoding == nil {
+			lookup(ttf.SymbolChars, rune(code))
+			lookup(ttf.SymbolChars, 0xf000+rune(code))
+			lookup(ttf.Chars, rune(code))
+		}
+		if !found && name != "" {
+			for i, glyph := range ttf.GlyphNames {
+				if glyph == name {
+					gid, found = fonts.GID(i), true
+					break
+				}
+			}
+			if r, ok := textencoding.GlyphToRune(name); ok && !found {
+				lookup(ttf.Chars, r)
+			}
+		}
+		if !found && hasRune {
+			lookup(ttf.Chars, r)
+		}
+		lookup(ttf.SymbolChars, 0xf000+rune(code))
+		lookup(ttf.SymbolChars, rune(code))
+		if !found && len(ttf.Chars) == 0 && len(ttf.SymbolChars) == 0 {
+			gid, found = fonts.GID(code), true
+		}
+		if !found || gid == 0 {
+			return nil, nil
+		}
+		return program.glyph(gid, "")
+
+	case program.cff != nil:
+		cff := program.cff
+		if name != "" {
+			if gid, ok := cff.GlyphByName(name); ok {
+				return program.glyph(gid, "")
+			}
+		}
+		if t.Encoding != nil && hasRune {
+			if glyph, ok := textencoding.RuneToGlyph(r); ok {
+				if gid, ok := cff.GlyphByName(glyph); ok {
+					return program.glyph(gid, "")
+				}
+			}
+		}
+		if gid, ok := cff.GlyphByCode(byte(code)); ok {
+			return program.glyph(gid, "")
+		}
+		if hasRune {
+			if glyph, ok := textencoding.RuneToGlyph(r); ok {
+				if gid, ok := cff.GlyphByName(glyph); ok {
+					return program.glyph(gid, "")
+				}
+			}
+		}
+
+	case program.type1 != nil:
+		type1 := program.type1
+		if name != "" && type1.HasGlyph(name) {
+			return program.glyph(0, name)
+		}
+		if t.Encoding == nil || symbolic {
+			if glyph, ok := type1.GlyphNameByCode(byte(code)); ok && type1.HasGlyph(glyph) {
+				return program.glyph(0, glyph)
+			}
+		}
+		if hasRune {
+			if glyph, ok := textencoding.RuneToGlyph(r); ok && type1.HasGlyph(glyph) {
+				return program.glyph(0, glyph)
+			}
+		}
+		if glyph, ok := type1.GlyphNameByCode(byte(code)); ok && type1.HasGlyph(glyph) {
+			return program.glyph(0, glyph)
+		}
+	}
+	return nil, nil
+}
+
+// cidGlyph returns the outline of the glyph with CID `cid` of CIDFont `cidFont`, which is the
+// descendant of `font` (or `font` itself) showing character code `code`.
+func (font *PdfFont) cidGlyph(cidFont *PdfFont, cid uint16, code textencoding.CharCode) (*GlyphPath, error) {
+	base := cidFont.baseFields()
+	program := base.fontDescriptor.getFontProgram(base.basefont)
+	if program == nil {
+		return nil, errors.New("no font program")
+	}
+	if program.substitute {
+		runes := font.CharcodesToUnicode([]textencoding.CharCode{code})
+		if len(runes) != 1 {
+			return nil, errors.New("no rune for substitute font")
+		}
+		gid, ok := program.ttf.Chars[runes[0]]
+		if !ok || gid == 0 {
+			return nil, fmt.Errorf("no substitute glyph for %q", runes[0])
+		}
+		width := -1.0
+		if m, ok := cidFont.GetCharMetrics(code); ok {
+			width = m.Wx
+		}
+		return program.substituteGlyph(gid, width)
+	}
+
+	gid := fonts.GID(cid)
+	switch t := cidFont.context.(type) {
+	case *pdfCIDFontType2:
+		if stream, ok := core.GetStream(t.CIDToGIDMap); ok {
+			data, err := core.DecodeStream(stream)
+			if err != nil {
+				return nil, err
+			}
+			if 2*int(cid)+2 > len(data) {
+				return nil, nil
+			}
+			gid = fonts.GID(binary.BigEndian.Uint16(data[2*int(cid):]))
+		}
+	case *pdfCIDFontType0:
+		if program.cff != nil {
+			var ok bool
+			if gid, ok = program.cff.GlyphByCID(cid); !ok {
+				return nil, nil
+			}
+		}
+	}
+	if program.type1 != nil {
+		return nil, errors.New("Type 1 font program in CIDFont")
+	}
+	if gid == 0 {
+		return nil, nil
+	}
+	return program.glyph(gid, "")
+}
+
+// glyph returns the outline of glyph `gid` (or the glyph named `name` for Type 1 font programs)
+// in glyph space.
+func (program *fontProgram) glyph(gid fonts.GID, name textencoding.GlyphName) (*GlyphPath, error) {
+	var path *GlyphPath
+	var err error
+	var m [6]float64
+	switch {
+	case program.ttf != nil:
+		if path, err = program.ttf.GlyphOutline(gid); err != nil {
+			return nil, err
+		}
+		k := 1000 / float64(program.ttf.UnitsPerEm)
+		m = [6]float64{k, 0, 0, k, 0, 0}
+	case program.cff != nil:
+		if path, err = program.cff.GlyphOutline(gid); err != nil {
+			return nil, err
+		}
+		m = program.cff.FontMatrix
+	case program.type1 != nil:
+		if path, err = program.type1.GlyphOutline(name); err != nil {
+			return nil, err
+		}
+		m = program.type1.FontMatrix
+	default:
+		return nil, errors.New("no font program")
+	}
+	if program.ttf == nil {
+		for i := range m {
+			m[i] *= 1000
+		}
+	}
+	path.Transform(m[0], m[1], m[2], m[3], m[4], m[5])
+	return path, nil
+}
+
+// substituteGlyph returns the outline of glyph `gid` of a substitute font program in glyph
+// space, scaled horizontally to `width` if it is not negative.
+func (program *fontProgram) substituteGlyph(gid fonts.GID, width float64) (*GlyphPath, error) {
+	path, err := program.glyph(gid, "")
+	if err != nil {
+		return nil, err
+	}
+	if int(gid) < len(program.ttf.Widths) && width >= 0 {
+		advance := float64(program.ttf.Widths[gid]) * 1000 / float64(program.ttf.UnitsPerEm)
+		if k := width / advance; advance > 0 && k > 0.25 && k < 4 {
+			path.Transform(k, 0, 0, 1, 0, 0)
+		}
+	}
+	return path, nil
+}
+
+// getFontProgram returns the font program embedded in the font described by `desc`, or a
+// substitute program for font `basefont` if there is none.
+func (desc *PdfFontDescriptor) getFontProgram(basefont string) *fontProgram {
+	if desc == nil {
+		return getSubstituteFontProgram(basefont, 0)
+	}
+	if !desc.programLoaded {
+		desc.programLoaded = true
+		program, err := desc.loadFontProgram()
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load font program. font=%q err=%v", basefont, err)
+		}
+		desc.program = program
+	}
+	if desc.program != nil {
+		return desc.program
+	}
+	return getSubstituteFontProgram(basefont, desc.flags)
+}
+
+// loadFontProgram loads the font program embedded in the font described by `desc`. Returns nil
+// if the font is not embedded.
+func (desc *PdfFontDescriptor) loadFontProgram() (*fontProgram, error) {
+	decode := func(obj core.PdfObject) ([]byte, core.PdfObjectName, error) {
+		stream, ok := core.GetStream(obj)
+		if !ok {
+			return nil, "", core.ErrTypeError
+		}
+		subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+		data, err := core.DecodeStream(stream)
+		return data, core.PdfObjectName(subtype), err
+	}
+
+	switch {
+	case desc.FontFile != nil:
+		data, _, err := decode(desc.FontFile)
+		if err != nil {
+			return nil, err
+		}
+		font, err := fonts.ParseType1(data)
+		if err != nil {
+			return nil, err
+		}
+		return &fontProgram{type1: font}, nil
+
+	case desc.FontFile2 != nil:
+		data, _, err := decode(desc.FontFile2)
+		if err != nil {
+			return nil, err
+		}
+		ttf, err := fonts.TtfParseGlyphs(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &fontProgram{ttf: &ttf}, nil
+
+	case desc.FontFile3 != nil:
+		data, subtype, err := decode(desc.FontFile3)
+		if err != nil {
+			return nil, err
+		}
+		if subtype == "OpenType" {
+			if !bytes.HasPrefix(data, []byte("OTTO")) {
+				ttf, err := fonts.TtfParseGlyphs(bytes.NewReader(data))
+				if err != nil {
+					return nil, err
+				}
+				return &fontProgram{ttf: &ttf}, nil
+			}
+			if data, err = fonts.OpenTypeCFFTable(data); err != nil {
+				return nil, err
+			}
+		}
+		font, err := fonts.ParseCFF(data)
+		if err != nil {
+			return nil, err
+		}
+		return &fontProgram{cff: font}, nil
+	}
+	return nil, nil
+}
+
+// substituteFontPrograms caches the substitute font programs by name.
+var substituteFontPrograms = struct {
+	sync.Mutex
+	programs map[string]*fontProgram
+}{programs: map[string]*fontProgram{}}
+
+// getSubstituteFontProgram returns the font program used in place of font `basefont` with font
+// descriptor flags `flags` when it is not embedded. The Go fonts are used, in monospaced and
+// proportional styles.
+// NOTE: Symbolic fonts such as Symbol and ZapfDingbats are not substituted.
+func getSubstituteFontProgram(basefont string, flags int) *fontProgram {
+	if strings.Contains(basefont, "Symbol") || strings.Contains(basefont, "Dingbats") {
+		return nil
+	}
+	name := strings.ToLower(basefont)
+	mono := flags&fontFlagFixedPitch != 0 || strings.Contains(name, "courier") ||
+		strings.Contains(name, "mono")
+	bold := flags&fontFlagForceBold != 0 || strings.Contains(name, "bold") ||
+		strings.Contains(name, "black") || strings.Contains(name, "heavy")
+	italic := flags&fontFlagItalic != 0 || strings.Contains(name, "italic") ||
+		strings.Contains(name, "oblique")
+
+	var key string
+	var data []byte
+	switch {
+	case mono && bold && italic:
+		key, data = "gomonobolditalic", gomonobolditalic.TTF
+	case mono && bold:
+		key, data = "gomonobold", gomonobold.TTF
+	case mono && italic:
+		key, data = "gomonoitalic", gomonoitalic.TTF
+	case mono:
+		key, data = "gomono", gomono.TTF
+	case bold && italic:
+		key, data = "gobolditalic", gobolditalic.TTF
+	case bold:
+		key, data = "gobold", gobold.TTF
+	case italic:
+		key, data = "goitalic", goitalic.TTF
+	default:
+		key, data = "goregular", goregular.TTF
+	}
+
+	substituteFontPrograms.Lock()
+	defer substituteFontPrograms.Unlock()
+	if program, ok := substituteFontPrograms.programs[key]; ok {
+		return program
+	}
+	var program *fontProgram
+	ttf, err := fonts.TtfParseGlyphs(bytes.NewReader(data))
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load substitute font %q: %v", key, err)
+	} else {
+		program = &fontProgram{ttf: &ttf, substitute: true}
+	}
+	substituteFontPrograms.programs[key] = program
+	return program
+}
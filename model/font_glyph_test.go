@@ -0,0 +1,95 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+)
+
+func TestTrueTypeGlyphs(t *testing.T) {
+	simple, err := NewPdfFontFromTTFFile("./testdata/font/OpenSans-Regular.ttf")
+	require.NoError(t, err)
+	composite, err := NewCompositePdfFontFromTTFFile("./testdata/font/OpenSans-Regular.ttf")
+	require.NoError(t, err)
+
+	path, err := simple.GetRuneGlyph('A')
+	require.NoError(t, err)
+	testutils.RequireBounds(t, path, [4]float64{0, 0, 633, 717}, 1)
+	path2, err := composite.GetRuneGlyph('A')
+	require.NoError(t, err)
+	require.Equal(t, path.Segments, path2.Segments)
+
+	// Space has an empty outline.
+	path, err = simple.GetRuneGlyph(' ')
+	require.NoError(t, err)
+	require.NotNil(t, path)
+	require.Empty(t, path.Segments)
+	_, err = simple.GetRuneGlyph('€' + 1)
+	require.Error(t, err)
+}
+
+func TestSubstituteGlyphs(t *testing.T) {
+	font, err := NewStandard14Font(HelveticaName)
+	require.NoError(t, err)
+	path, err := font.GetRuneGlyph('H')
+	require.NoError(t, err)
+	// The substitute glyph is scaled to the width of Helvetica H (722).
+	_, _, urx, _, ok := path.Bounds()
+	require.True(t, ok)
+	require.True(t, urx > 600 && urx < 722, "urx=%g", urx)
+
+	font, err = NewStandard14Font(SymbolName)
+	require.NoError(t, err)
+	_, err = font.GetRuneGlyph('α')
+	require.Error(t, err)
+}
+
+func TestEmbeddedFontGlyphs(t *testing.T) {
+	testcases := []struct {
+		filename string
+		password string
+		page     int
+		font     core.PdfObjectName
+		code     textencoding.CharCode
+		bounds   [4]float64
+	}{
+		// Type1C font program.
+		{"../core/testdata/invalidstart.pdf", "", 1, "F2", 'A', [4]float64{15, 0, 706, 674}},
+		// Type 1 font programs.
+		{"../core/testdata/issue6010_2.pdf", "æøå", 9, "F33", '1', [4]float64{96, 0, 447, 665}},
+		{"../core/testdata/issue6010_2.pdf", "æøå", 9, "F15", 10, [4]float64{44, 0, 662, 704}},
+	}
+	for _, tc := range testcases {
+		f, err := os.Open(tc.filename)
+		require.NoError(t, err)
+		defer f.Close()
+		r, err := NewPdfReader(f)
+		require.NoError(t, err)
+		if encrypted, _ := r.IsEncrypted(); encrypted {
+			ok, err := r.Decrypt([]byte(tc.password))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		page, err := r.GetPage(tc.page)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName(tc.font)
+		require.True(t, ok)
+		font, err := NewPdfFontFromPdfObject(obj)
+		require.NoError(t, err)
+
+		path, err := font.GetCharGlyph(tc.code)
+		require.NoError(t, err, "font=%s code=%d", font, tc.code)
+		require.NotNil(t, path, "font=%s code=%d", font, tc.code)
+		testutils.RequireBounds(t, path, tc.bounds, 1)
+	}
+}
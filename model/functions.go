@@ -562,11 +562,30 @@ func (f *PdfFunctionType3) Evaluate(x []float64) ([]float64, error) {
 		return nil, errors.New("range check")
 	}
 
-	// Determine which function to use
+	k := len(f.Functions)
+	if k == 0 || len(f.Bounds) != k-1 || len(f.Encode) != 2*k || len(f.Domain) < 2 {
+		common.Log.Debug("ERROR: Invalid stitching function")
+		return nil, errors.New("invalid stitching function")
+	}
 
-	// Encode
+	// Determine which function to use: the subdomains are [Domain0 Bounds0), [Bounds0 Bounds1),
+	// ... [Bounds(k-2) Domain1].
+	v := math.Max(f.Domain[0], math.Min(f.Domain[1], x[0]))
+	i := 0
+	for i < k-1 && v >= f.Bounds[i] {
+		i++
+	}
+	low, high := f.Domain[0], f.Domain[1]
+	if i > 0 {
+		low = f.Bounds[i-1]
+	}
+	if i < k-1 {
+		high = f.Bounds[i]
+	}
 
-	return nil, errors.New("not implemented yet")
+	// Encode the value for the domain of the function.
+	v = interpolate(v, low, high, f.Encode[2*i], f.Encode[2*i+1])
+	return f.Functions[i].Evaluate([]float64{v})
 }
 
 func newPdfFunctionType3FromPdfObject(obj core.PdfObject) (*PdfFunctionType3, error) {
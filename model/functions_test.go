@@ -100,3 +100,38 @@ endobj
 
 	t.Logf("%s", stream.Stream)
 }
+
+func TestType3Function(t *testing.T) {
+	// Gradient from black to red on [0 0.5) and from red to white, reversed, on [0.5 1].
+	fun := &PdfFunctionType3{
+		Domain: []float64{0, 1},
+		Functions: []PdfFunction{
+			&PdfFunctionType2{C0: []float64{0, 0, 0}, C1: []float64{1, 0, 0}, N: 1},
+			&PdfFunctionType2{C0: []float64{1, 0, 0}, C1: []float64{1, 1, 1}, N: 1},
+		},
+		Bounds: []float64{0.5},
+		Encode: []float64{0, 1, 1, 0},
+	}
+
+	testcases := []Type4TestCase{
+		{[]float64{0}, []float64{0, 0, 0}},
+		{[]float64{0.25}, []float64{0.5, 0, 0}},
+		{[]float64{0.5}, []float64{1, 1, 1}},
+		{[]float64{0.75}, []float64{1, 0.5, 0.5}},
+		{[]float64{2}, []float64{1, 0, 0}},
+	}
+	for _, testcase := range testcases {
+		outputs, err := fun.Evaluate(testcase.Inputs)
+		if err != nil {
+			t.Fatalf("Failed: %v", err)
+		}
+		if len(outputs) != len(testcase.Expected) {
+			t.Fatalf("Failed, output length mismatch")
+		}
+		for i := range outputs {
+			if math.Abs(outputs[i]-testcase.Expected[i]) > 0.000001 {
+				t.Fatalf("Failed, output %v and expected %v mismatch", outputs, testcase.Expected)
+			}
+		}
+	}
+}
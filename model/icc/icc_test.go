@@ -0,0 +1,264 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildProfile returns a profile of class `class` with device color space `space`, profile
+// connection space `pcs` and tags `tags`.
+func buildProfile(class Class, space, pcs ColorSpace, tags map[string][]byte) []byte {
+	var sigs []string
+	for sig := range tags {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	data := make([]byte, 132+12*len(sigs))
+	binary.BigEndian.PutUint32(data[8:], 0x04300000)
+	copy(data[12:], class)
+	copy(data[16:], space)
+	copy(data[20:], pcs)
+	copy(data[36:], "acsp")
+	binary.BigEndian.PutUint32(data[128:], uint32(len(sigs)))
+	for i, sig := range sigs {
+		entry := data[132+12*i:]
+		copy(entry, sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(data)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(tags[sig])))
+		data = append(data, tags[sig]...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(data, uint32(len(data)))
+	return data
+}
+
+// appendS15Fixed16 appends the 15.16 fixed point encoding of `v` to `b`.
+func appendS15Fixed16(b []byte, v float64) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(int32(math.Round(v*65536))))
+	return append(b, buf[:]...)
+}
+
+// xyzTag returns an XYZType tag of color `xyz`.
+func xyzTag(xyz XYZ) []byte {
+	b := []byte("XYZ \x00\x00\x00\x00")
+	for _, v := range xyz {
+		b = appendS15Fixed16(b, v)
+	}
+	return b
+}
+
+// gammaTag returns a curveType tag of gamma `gamma`.
+func gammaTag(gamma float64) []byte {
+	b := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01")
+	g := uint16(math.Round(gamma * 256))
+	return append(b, byte(g>>8), byte(g), 0, 0)
+}
+
+// mlucTag returns a multiLocalizedUnicodeType tag of text `text`.
+func mlucTag(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	b := make([]byte, 28, 28+2*len(units))
+	copy(b, "mluc")
+	binary.BigEndian.PutUint32(b[8:], 1)
+	binary.BigEndian.PutUint32(b[12:], 12)
+	copy(b[16:], "enUS")
+	binary.BigEndian.PutUint32(b[20:], uint32(2*len(units)))
+	binary.BigEndian.PutUint32(b[24:], 28)
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+// mft2Tag returns a lut16Type tag with `in` input and `out` output channels, identity curves and
+// a color lookup table of 2 grid points per input whose values are the results of `f` for the
+// grid points.
+func mft2Tag(in, out int, f func(grid []float64) []float64) []byte {
+	b := []byte("mft2\x00\x00\x00\x00")
+	b = append(b, byte(in), byte(out), 2, 0)
+	for _, v := range []float64{1, 0, 0, 0, 1, 0, 0, 0, 1} {
+		b = appendS15Fixed16(b, v)
+	}
+	b = append(b, 0, 2, 0, 2)
+	appendCurves := func(n int) {
+		for i := 0; i < n; i++ {
+			b = append(b, 0, 0, 0xff, 0xff)
+		}
+	}
+	appendCurves(in)
+	for i := 0; i < 1<<uint(in); i++ {
+		grid := make([]float64, in)
+		for j := range grid {
+			grid[j] = float64(i >> uint(in-1-j) & 1)
+		}
+		for _, v := range f(grid) {
+			u := uint16(math.Round(v))
+			b = append(b, byte(u>>8), byte(u))
+		}
+	}
+	appendCurves(out)
+	return b
+}
+
+// identityLUTTag returns a lutAToBType (`sig` "mAB ") or lutBToAType (`sig` "mBA ") tag of 3
+// channels with identity B curves only.
+func identityLUTTag(sig string) []byte {
+	b := make([]byte, 32)
+	copy(b, sig)
+	b[8], b[9] = 3, 3
+	binary.BigEndian.PutUint32(b[12:], 32)
+	for i := 0; i < 3; i++ {
+		b = append(b, "curv\x00\x00\x00\x00\x00\x00\x00\x00"...)
+	}
+	return b
+}
+
+// requireColor checks that `actual` is `expected` within `delta`.
+func requireColor(t *testing.T, expected, actual []float64, delta float64) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		require.InDelta(t, expected[i], actual[i], delta, "component %d of %v", i, actual)
+	}
+}
+
+func TestSRGB(t *testing.T) {
+	xyz := NewXYZProfile(D50)
+	toXYZ, err := NewTransform(SRGB(), xyz, RelativeColorimetric)
+	require.NoError(t, err)
+	requireColor(t, D50[:], toXYZ.Convert([]float64{1, 1, 1}), 1e-3)
+	requireColor(t, []float64{0.4360747, 0.2225045, 0.0139322}, toXYZ.Convert([]float64{1, 0, 0}), 1e-6)
+
+	roundTrip, err := NewTransform(SRGB(), SRGB(), Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{0.2, 0.5, 0.8}, roundTrip.Convert([]float64{0.2, 0.5, 0.8}), 1e-3)
+
+	// The D65 white is adapted to the D50 white.
+	d65, err := NewTransform(NewXYZProfile(XYZ{0.9505, 1, 1.089}), SRGB(), RelativeColorimetric)
+	require.NoError(t, err)
+	requireColor(t, []float64{1, 1, 1}, d65.Convert([]float64{0.9505, 1, 1.089}), 1e-3)
+	requireColor(t, []float64{0, 0, 0}, d65.Convert([]float64{0, 0, 0}), 1e-3)
+}
+
+func TestParseMatrixTRC(t *testing.T) {
+	c := srgb.colorants
+	data := buildProfile(ClassDisplay, ColorSpaceRGB, ColorSpaceXYZ, map[string][]byte{
+		"desc": mlucTag("Linear RGB"),
+		"wtpt": xyzTag(XYZ{0.9505, 1, 1.089}),
+		"rXYZ": xyzTag(c[0]),
+		"gXYZ": xyzTag(c[1]),
+		"bXYZ": xyzTag(c[2]),
+		"rTRC": gammaTag(1),
+		"gTRC": gammaTag(1),
+		"bTRC": gammaTag(1),
+	})
+	p, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, "Linear RGB", p.Description)
+	require.Equal(t, ClassDisplay, p.Class)
+	require.Equal(t, ColorSpaceRGB, p.ColorSpace)
+	require.Equal(t, 3, p.NumComponents())
+	require.True(t, p.HasTag("rTRC"))
+	require.InDelta(t, 1.089, p.MediaWhite[2], 1e-4)
+
+	tr, err := NewTransform(p, SRGB(), RelativeColorimetric)
+	require.NoError(t, err)
+	requireColor(t, []float64{0.7354, 0.7354, 0.7354}, tr.Convert([]float64{0.5, 0.5, 0.5}), 1e-3)
+	requireColor(t, []float64{0.7354, 0, 0}, tr.Convert([]float64{0.5, 0, 0}), 1e-3)
+
+	// Absolute colorimetric colors keep the tint of the media white.
+	tr, err = NewTransform(p, SRGB(), AbsoluteColorimetric)
+	require.NoError(t, err)
+	white := tr.Convert([]float64{1, 1, 1})
+	require.True(t, white[2] > white[0])
+
+	gray, err := Parse(buildProfile(ClassDisplay, ColorSpaceGray, ColorSpaceXYZ, map[string][]byte{
+		"kTRC": gammaTag(1),
+	}))
+	require.NoError(t, err)
+	tr, err = NewTransform(gray, SRGB(), Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{0.7354, 0.7354, 0.7354}, tr.Convert([]float64{0.5}), 1e-3)
+	tr, err = NewTransform(SRGB(), gray, Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{0.5}, tr.Convert([]float64{0.7354, 0.7354, 0.7354}), 1e-3)
+}
+
+func TestParseLUT(t *testing.T) {
+	// CMYK profile where the lightness is reduced by the cyan and black inks, with a colorimetric
+	// intent that ignores the black ink.
+	data := buildProfile(ClassOutput, ColorSpaceCMYK, ColorSpaceLab, map[string][]byte{
+		"A2B0": mft2Tag(4, 3, func(cmyk []float64) []float64 {
+			return []float64{65280 * (1 - cmyk[3]) * (1 - cmyk[0]/2), 32768, 32768}
+		}),
+		"A2B1": mft2Tag(4, 3, func(cmyk []float64) []float64 {
+			return []float64{65280 * (1 - cmyk[0]/2), 32768, 32768}
+		}),
+	})
+	p, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumComponents())
+
+	tr, err := NewTransform(p, SRGB(), Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{1, 1, 1}, tr.Convert([]float64{0, 0, 0, 0}), 1e-3)
+	requireColor(t, []float64{0, 0, 0}, tr.Convert([]float64{0, 0, 0, 1}), 1e-3)
+	// L* = 50.
+	requireColor(t, []float64{0.4663, 0.4663, 0.4663}, tr.Convert([]float64{0, 0, 0, 0.5}), 1e-3)
+	requireColor(t, []float64{0.4663, 0.4663, 0.4663}, tr.Convert([]float64{1, 0, 0, 0}), 1e-3)
+
+	tr, err = NewTransform(p, SRGB(), RelativeColorimetric)
+	require.NoError(t, err)
+	requireColor(t, []float64{1, 1, 1}, tr.Convert([]float64{0, 0, 0, 1}), 1e-3)
+	tr, err = NewTransform(p, SRGB(), Saturation)
+	require.NoError(t, err)
+	requireColor(t, []float64{0, 0, 0}, tr.Convert([]float64{0, 0, 0, 1}), 1e-3)
+
+	// Without B2A tags the profile cannot be a destination.
+	_, err = NewTransform(SRGB(), p, Perceptual)
+	require.Equal(t, ErrUnsupportedProfile, err)
+
+	// Lab profile with lutAToBType and lutBToAType tags.
+	lab, err := Parse(buildProfile(ClassColorSpace, ColorSpaceLab, ColorSpaceLab, map[string][]byte{
+		"A2B0": identityLUTTag("mAB "),
+		"B2A0": identityLUTTag("mBA "),
+	}))
+	require.NoError(t, err)
+	tr, err = NewTransform(lab, SRGB(), Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{1, 1, 1}, tr.Convert([]float64{1, 128.0 / 255, 128.0 / 255}), 1e-3)
+	tr, err = NewTransform(SRGB(), lab, Perceptual)
+	require.NoError(t, err)
+	requireColor(t, []float64{0.5, 128.0 / 255, 128.0 / 255}, tr.Convert([]float64{0.4663, 0.4663, 0.4663}),
+		1e-3)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("not a profile"))
+	require.Equal(t, ErrInvalidProfile, err)
+
+	data := buildProfile(ClassDisplay, ColorSpaceGray, ColorSpaceXYZ, map[string][]byte{
+		"kTRC": gammaTag(1),
+	})
+	binary.BigEndian.PutUint32(data[136:], uint32(len(data)))
+	_, err = Parse(data)
+	require.Equal(t, ErrInvalidProfile, err)
+
+	link, err := Parse(buildProfile(ClassLink, ColorSpaceRGB, ColorSpaceLab, nil))
+	require.NoError(t, err)
+	_, err = NewTransform(link, SRGB(), Perceptual)
+	require.Error(t, err)
+}
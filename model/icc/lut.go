@@ -0,0 +1,424 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// maxCLUTInputs is the maximum number of input channels of the supported color lookup tables.
+const maxCLUTInputs = 8
+
+// stage is a step of a color transform.
+type stage func(in []float64) []float64
+
+// pipeline is a sequence of stages.
+type pipeline []stage
+
+// apply returns the output of the stages of `p` for input `in`.
+func (p pipeline) apply(in []float64) []float64 {
+	for _, s := range p {
+		in = s(in)
+	}
+	return in
+}
+
+// curvesStage returns the stage applying `curves` to the channels.
+func curvesStage(curves []curve) stage {
+	return func(in []float64) []float64 {
+		out := make([]float64, len(curves))
+		for i, c := range curves {
+			if i < len(in) {
+				out[i] = c(in[i])
+			}
+		}
+		return out
+	}
+}
+
+// matrixStage returns the stage multiplying 3 channels by 3×3 matrix `m` (row major) and adding
+// `offset`. The results are clamped to [0,1] if `clamp` is true.
+func matrixStage(m [9]float64, offset [3]float64, clamp bool) stage {
+	return func(in []float64) []float64 {
+		if len(in) < 3 {
+			return in
+		}
+		out := make([]float64, 3)
+		for i := range out {
+			out[i] = m[3*i]*in[0] + m[3*i+1]*in[1] + m[3*i+2]*in[2] + offset[i]
+			if clamp {
+				out[i] = clamp01(out[i])
+			}
+		}
+		return out
+	}
+}
+
+// clut is a multidimensional color lookup table.
+type clut struct {
+	grid    []int     // Number of grid points of each input channel.
+	outputs int       // Number of output channels.
+	values  []float64 // Output values, normalized to [0,1], with the first input varying slowest.
+}
+
+// newCLUT returns the table with `grid` points per input channel and `outputs` output channels
+// read from `b` with `precision` bytes per value.
+func newCLUT(b []byte, grid []int, outputs, precision int) (*clut, error) {
+	if len(grid) == 0 || len(grid) > maxCLUTInputs || outputs <= 0 {
+		return nil, ErrUnsupportedProfile
+	}
+	n := outputs
+	for _, g := range grid {
+		if g < 2 {
+			return nil, ErrInvalidProfile
+		}
+		n *= g
+		if n*precision > len(b) {
+			return nil, ErrInvalidProfile
+		}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		if precision == 1 {
+			values[i] = float64(b[i]) / 255
+		} else {
+			values[i] = float64(binary.BigEndian.Uint16(b[2*i:])) / 65535
+		}
+	}
+	return &clut{grid: grid, outputs: outputs, values: values}, nil
+}
+
+// clutSize returns the size in bytes of the table with `grid` points per input channel and
+// `outputs` output channels with `precision` bytes per value.
+func clutSize(grid []int, outputs, precision int) int {
+	n := outputs * precision
+	for _, g := range grid {
+		n *= g
+	}
+	return n
+}
+
+// lookup returns the output values of the table for input `in`, interpolated multilinearly
+// between the grid points.
+func (t *clut) lookup(in []float64) []float64 {
+	nin := len(t.grid)
+	var base, strides [maxCLUTInputs]int
+	var frac [maxCLUTInputs]float64
+	stride := t.outputs
+	for i := nin - 1; i >= 0; i-- {
+		strides[i] = stride
+		x := 0.0
+		if i < len(in) {
+			x = clamp01(in[i]) * float64(t.grid[i]-1)
+		}
+		b := int(math.Floor(x))
+		if b >= t.grid[i]-1 {
+			b = t.grid[i] - 2
+		}
+		base[i], frac[i] = b, x-float64(b)
+		stride *= t.grid[i]
+	}
+
+	out := make([]float64, t.outputs)
+	for corner := 0; corner < 1<<uint(nin); corner++ {
+		w := 1.0
+		offset := 0
+		for i := 0; i < nin; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				w *= frac[i]
+				offset += (base[i] + 1) * strides[i]
+			} else {
+				w *= 1 - frac[i]
+				offset += base[i] * strides[i]
+			}
+		}
+		if w == 0 {
+			continue
+		}
+		for k := range out {
+			out[k] += w * t.values[offset+k]
+		}
+	}
+	return out
+}
+
+// pcsEncoding is the encoding of PCS colors in LUT-based tags.
+type pcsEncoding int
+
+const (
+	// pcsEncodingV4 is the encoding of PCS colors of lut8Type, lutAToBType and lutBToAType tags:
+	// L* in [0,100] and a*, b* in [-128,127] mapped to [0,1].
+	pcsEncodingV4 pcsEncoding = iota
+	// pcsEncodingLegacy is the 16 bit encoding of PCS Lab colors of lut16Type tags, where 1 is
+	// L* = 100 + 25500/65280.
+	pcsEncodingLegacy
+)
+
+// parseLUT returns the pipeline of normalized values of LUT-based tag `tag` with `in` input and
+// `out` output channels, and the encoding of its PCS colors.
+func parseLUT(tag []byte, in, out int) (pipeline, pcsEncoding, error) {
+	if len(tag) < 32 {
+		return nil, 0, ErrInvalidProfile
+	}
+	switch string(tag[:4]) {
+	case "mft1", "mft2":
+		p, err := parseLUT8Or16(tag, in, out)
+		enc := pcsEncodingV4
+		if string(tag[:4]) == "mft2" {
+			enc = pcsEncodingLegacy
+		}
+		return p, enc, err
+	case "mAB ":
+		p, err := parseLUTAToB(tag, in, out)
+		return p, pcsEncodingV4, err
+	case "mBA ":
+		p, err := parseLUTBToA(tag, in, out)
+		return p, pcsEncodingV4, err
+	}
+	return nil, 0, ErrUnsupportedProfile
+}
+
+// parseLUT8Or16 returns the pipeline of lut8Type or lut16Type tag `tag`: matrix (for XYZ
+// inputs), input curves, color lookup table and output curves.
+func parseLUT8Or16(tag []byte, in, out int) (pipeline, error) {
+	nin, nout, grid := int(tag[8]), int(tag[9]), int(tag[10])
+	if nin != in || nout != out {
+		return nil, ErrInvalidProfile
+	}
+	var m [9]float64
+	for i := range m {
+		m[i] = s15Fixed16(tag[12+4*i:])
+	}
+
+	precision := 1
+	inEntries, outEntries := 256, 256
+	pos := 48
+	if string(tag[:4]) == "mft2" {
+		if len(tag) < 52 {
+			return nil, ErrInvalidProfile
+		}
+		precision = 2
+		inEntries, outEntries = int(binary.BigEndian.Uint16(tag[48:])), int(binary.BigEndian.Uint16(tag[50:]))
+		pos = 52
+		if inEntries < 2 || outEntries < 2 {
+			return nil, ErrInvalidProfile
+		}
+	}
+	readTables := func(n, entries int) ([]curve, error) {
+		if pos+n*entries*precision > len(tag) {
+			return nil, ErrInvalidProfile
+		}
+		curves := make([]curve, n)
+		for i := range curves {
+			table := make([]float64, entries)
+			for j := range table {
+				if precision == 1 {
+					table[j] = float64(tag[pos]) / 255
+				} else {
+					table[j] = float64(binary.BigEndian.Uint16(tag[pos:])) / 65535
+				}
+				pos += precision
+			}
+			curves[i] = tableCurve(table)
+		}
+		return curves, nil
+	}
+
+	inCurves, err := readTables(nin, inEntries)
+	if err != nil {
+		return nil, err
+	}
+	grids := make([]int, nin)
+	for i := range grids {
+		grids[i] = grid
+	}
+	table, err := newCLUT(tag[pos:], grids, nout, precision)
+	if err != nil {
+		return nil, err
+	}
+	pos += clutSize(grids, nout, precision)
+	outCurves, err := readTables(nout, outEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	var p pipeline
+	if nin == 3 && m != [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1} {
+		// The matrix is only used with XYZ inputs, for which it is not the identity.
+		p = append(p, matrixStage(m, [3]float64{}, true))
+	}
+	return append(p, curvesStage(inCurves), table.lookup, curvesStage(outCurves)), nil
+}
+
+// lutElements are the offsets of the elements of lutAToBType and lutBToAType tags.
+type lutElements struct {
+	b, matrix, m, clut, a int
+}
+
+// parseLUTElements returns the offsets of the elements of lutAToBType or lutBToAType tag `tag`
+// with `in` input and `out` output channels.
+func parseLUTElements(tag []byte, in, out int) (lutElements, error) {
+	if int(tag[8]) != in || int(tag[9]) != out {
+		return lutElements{}, ErrInvalidProfile
+	}
+	offset := func(i int) int {
+		return int(binary.BigEndian.Uint32(tag[12+4*i:]))
+	}
+	e := lutElements{b: offset(0), matrix: offset(1), m: offset(2), clut: offset(3), a: offset(4)}
+	for _, o := range []int{e.b, e.matrix, e.m, e.clut, e.a} {
+		if o < 0 || o >= len(tag) {
+			return lutElements{}, ErrInvalidProfile
+		}
+	}
+	return e, nil
+}
+
+// parseCurves returns the `n` curves at offset `offset` of `tag`.
+func parseCurves(tag []byte, offset, n int) ([]curve, error) {
+	curves := make([]curve, n)
+	for i := range curves {
+		if offset >= len(tag) {
+			return nil, ErrInvalidProfile
+		}
+		c, size, err := parseCurve(tag[offset:])
+		if err != nil {
+			return nil, err
+		}
+		curves[i] = c
+		offset += size
+	}
+	return curves, nil
+}
+
+// parseLUTMatrix returns the matrix stage at offset `offset` of `tag`.
+func parseLUTMatrix(tag []byte, offset int) (stage, error) {
+	if offset+48 > len(tag) {
+		return nil, ErrInvalidProfile
+	}
+	var m [9]float64
+	var o [3]float64
+	for i := range m {
+		m[i] = s15Fixed16(tag[offset+4*i:])
+	}
+	for i := range o {
+		o[i] = s15Fixed16(tag[offset+36+4*i:])
+	}
+	return matrixStage(m, o, true), nil
+}
+
+// parseLUTCLUT returns the color lookup table with `in` input and `out` output channels at offset
+// `offset` of `tag`.
+func parseLUTCLUT(tag []byte, offset, in, out int) (*clut, error) {
+	if offset+20 > len(tag) || in > 16 {
+		return nil, ErrInvalidProfile
+	}
+	grid := make([]int, in)
+	for i := range grid {
+		grid[i] = int(tag[offset+i])
+	}
+	precision := int(tag[offset+16])
+	if precision != 1 && precision != 2 {
+		return nil, ErrInvalidProfile
+	}
+	return newCLUT(tag[offset+20:], grid, out, precision)
+}
+
+// parseLUTAToB returns the pipeline of lutAToBType tag `tag`: A curves, color lookup table, M
+// curves, matrix and B curves, where the elements with offset 0 are omitted.
+func parseLUTAToB(tag []byte, in, out int) (pipeline, error) {
+	e, err := parseLUTElements(tag, in, out)
+	if err != nil {
+		return nil, err
+	}
+	if e.b == 0 {
+		return nil, ErrInvalidProfile
+	}
+	var p pipeline
+	if e.a != 0 {
+		curves, err := parseCurves(tag, e.a, in)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, curvesStage(curves))
+	}
+	if e.clut != 0 {
+		table, err := parseLUTCLUT(tag, e.clut, in, out)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, table.lookup)
+	} else if in != out {
+		return nil, ErrInvalidProfile
+	}
+	if e.m != 0 {
+		curves, err := parseCurves(tag, e.m, out)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, curvesStage(curves))
+	}
+	if e.matrix != 0 && out == 3 {
+		m, err := parseLUTMatrix(tag, e.matrix)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, m)
+	}
+	curves, err := parseCurves(tag, e.b, out)
+	if err != nil {
+		return nil, err
+	}
+	return append(p, curvesStage(curves)), nil
+}
+
+// parseLUTBToA returns the pipeline of lutBToAType tag `tag`: B curves, matrix, M curves, color
+// lookup table and A curves, where the elements with offset 0 are omitted.
+func parseLUTBToA(tag []byte, in, out int) (pipeline, error) {
+	e, err := parseLUTElements(tag, in, out)
+	if err != nil {
+		return nil, err
+	}
+	if e.b == 0 {
+		return nil, ErrInvalidProfile
+	}
+	curves, err := parseCurves(tag, e.b, in)
+	if err != nil {
+		return nil, err
+	}
+	p := pipeline{curvesStage(curves)}
+	if e.matrix != 0 && in == 3 {
+		m, err := parseLUTMatrix(tag, e.matrix)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, m)
+	}
+	if e.m != 0 {
+		curves, err := parseCurves(tag, e.m, in)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, curvesStage(curves))
+	}
+	if e.clut != 0 {
+		table, err := parseLUTCLUT(tag, e.clut, in, out)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, table.lookup)
+	} else if in != out {
+		return nil, ErrInvalidProfile
+	}
+	if e.a != 0 {
+		curves, err := parseCurves(tag, e.a, out)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, curvesStage(curves))
+	}
+	return p, nil
+}
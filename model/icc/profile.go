@@ -0,0 +1,429 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package icc parses ICC color profiles (ICC.1:2001-04 v2 and ICC.1:2010 v4) and converts colors
+// between them with rendering intents.
+//
+// Both the matrix/TRC profiles (display RGB and gray profiles) and the LUT-based profiles
+// (lut8Type, lut16Type, lutAToBType and lutBToAType tags, as used by printer CMYK profiles) are
+// supported. The colors are connected in the CIE XYZ profile connection space with the D50
+// illuminant.
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// ColorSpace is the signature of a color space of profiles.
+type ColorSpace string
+
+// Color spaces of profiles.
+const (
+	ColorSpaceXYZ  ColorSpace = "XYZ "
+	ColorSpaceLab  ColorSpace = "Lab "
+	ColorSpaceGray ColorSpace = "GRAY"
+	ColorSpaceRGB  ColorSpace = "RGB "
+	ColorSpaceCMY  ColorSpace = "CMY "
+	ColorSpaceCMYK ColorSpace = "CMYK"
+)
+
+// Class is the signature of a profile class.
+type Class string
+
+// Profile classes.
+const (
+	ClassInput      Class = "scnr"
+	ClassDisplay    Class = "mntr"
+	ClassOutput     Class = "prtr"
+	ClassLink       Class = "link"
+	ClassColorSpace Class = "spac"
+	ClassAbstract   Class = "abst"
+	ClassNamedColor Class = "nmcl"
+)
+
+// Intent is a rendering intent.
+type Intent int
+
+// Rendering intents.
+const (
+	Perceptual Intent = iota
+	RelativeColorimetric
+	Saturation
+	AbsoluteColorimetric
+)
+
+// String returns the name of the rendering intent, as used by the ri operator of PDF.
+func (intent Intent) String() string {
+	switch intent {
+	case Perceptual:
+		return "Perceptual"
+	case RelativeColorimetric:
+		return "RelativeColorimetric"
+	case Saturation:
+		return "Saturation"
+	case AbsoluteColorimetric:
+		return "AbsoluteColorimetric"
+	}
+	return fmt.Sprintf("Intent(%d)", int(intent))
+}
+
+// XYZ is a CIE XYZ color, with Y = 1 for the white.
+type XYZ [3]float64
+
+// D50 is the illuminant of the profile connection space.
+var D50 = XYZ{0.9642, 1, 0.8249}
+
+// Errors returned when parsing profiles.
+var (
+	ErrInvalidProfile     = errors.New("invalid ICC profile")
+	ErrUnsupportedProfile = errors.New("unsupported ICC profile")
+)
+
+// Profile is an ICC color profile.
+type Profile struct {
+	Version     uint32 // Version of the profile format, e.g. 0x04300000 for 4.3.
+	Class       Class
+	ColorSpace  ColorSpace // Color space of the device colors.
+	PCS         ColorSpace // Profile connection space: ColorSpaceXYZ or ColorSpaceLab.
+	Intent      Intent     // Default rendering intent.
+	MediaWhite  XYZ        // Media white point, D50 if not specified.
+	Description string
+
+	tags map[string][]byte
+
+	// Matrix/TRC profiles: the XYZ colorants of the red, green and blue channels, and the tone
+	// reproduction curves of the channels (a single one for gray profiles).
+	colorants *[3]XYZ
+	trc       []curve
+
+	// White point of the device colors of XYZ profiles (NewXYZProfile).
+	white *XYZ
+}
+
+// Parse returns the profile encoded in `data`.
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, ErrInvalidProfile
+	}
+	size := binary.BigEndian.Uint32(data)
+	if int64(size) > int64(len(data)) || size < 132 {
+		return nil, ErrInvalidProfile
+	}
+	data = data[:size]
+
+	p := &Profile{
+		Version:    binary.BigEndian.Uint32(data[8:]),
+		Class:      Class(data[12:16]),
+		ColorSpace: ColorSpace(data[16:20]),
+		PCS:        ColorSpace(data[20:24]),
+		Intent:     Intent(binary.BigEndian.Uint32(data[64:]) & 3),
+		MediaWhite: D50,
+		tags:       map[string][]byte{},
+	}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	if count < 0 || 132+12*count > len(data) {
+		return nil, ErrInvalidProfile
+	}
+	for i := 0; i < count; i++ {
+		entry := data[132+12*i:]
+		sig := string(entry[:4])
+		offset, length := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) || length < 8 {
+			return nil, ErrInvalidProfile
+		}
+		p.tags[sig] = data[offset : offset+length]
+	}
+	if p.PCS != ColorSpaceXYZ && p.PCS != ColorSpaceLab {
+		return nil, ErrUnsupportedProfile
+	}
+	if p.NumComponents() == 0 {
+		return nil, ErrUnsupportedProfile
+	}
+
+	if wtpt, ok := p.tags["wtpt"]; ok {
+		xyz, err := parseXYZ(wtpt)
+		if err != nil {
+			return nil, err
+		}
+		p.MediaWhite = xyz
+	}
+	if desc, ok := p.tags["desc"]; ok {
+		p.Description = parseText(desc)
+	}
+
+	// Matrix/TRC models, used when the profiles have no LUT-based tags.
+	switch p.ColorSpace {
+	case ColorSpaceRGB:
+		var colorants [3]XYZ
+		trc := make([]curve, 3)
+		complete := true
+		for i, c := range []string{"r", "g", "b"} {
+			xyzTag, ok1 := p.tags[c+"XYZ"]
+			trcTag, ok2 := p.tags[c+"TRC"]
+			if !ok1 || !ok2 {
+				complete = false
+				break
+			}
+			xyz, err := parseXYZ(xyzTag)
+			if err != nil {
+				return nil, err
+			}
+			colorants[i] = xyz
+			if trc[i], _, err = parseCurve(trcTag); err != nil {
+				return nil, err
+			}
+		}
+		if complete {
+			p.colorants = &colorants
+			p.trc = trc
+		}
+	case ColorSpaceGray:
+		if tag, ok := p.tags["kTRC"]; ok {
+			c, _, err := parseCurve(tag)
+			if err != nil {
+				return nil, err
+			}
+			p.trc = []curve{c}
+		}
+	}
+	return p, nil
+}
+
+// NumComponents returns the number of components of the device colors of the profile, or 0 if
+// the color space is unknown.
+func (p *Profile) NumComponents() int {
+	switch p.ColorSpace {
+	case ColorSpaceGray:
+		return 1
+	case ColorSpaceCMYK:
+		return 4
+	case ColorSpaceXYZ, ColorSpaceLab, ColorSpaceRGB, ColorSpaceCMY, "Luv ", "YCbr", "Yxy ", "HSV ", "HLS ":
+		return 3
+	}
+	// nCLR color spaces: 2CLR to FCLR.
+	if s := string(p.ColorSpace); len(s) == 4 && s[1:] == "CLR" {
+		if n := strings.IndexByte("0123456789ABCDEF", s[0]); n >= 2 {
+			return n
+		}
+	}
+	return 0
+}
+
+// HasTag returns true if the profile has the tag with signature `sig`, e.g. "A2B0".
+func (p *Profile) HasTag(sig string) bool {
+	_, ok := p.tags[sig]
+	return ok
+}
+
+// s15Fixed16 returns the signed 15.16 fixed point number at the start of `b`.
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// parseXYZ returns the color of XYZType tag `tag`.
+func parseXYZ(tag []byte) (XYZ, error) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return XYZ{}, ErrInvalidProfile
+	}
+	return XYZ{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, nil
+}
+
+// parseText returns the text of textDescriptionType (v2), multiLocalizedUnicodeType (v4) or
+// textType tag `tag`, or an empty string if it is invalid. The first localization of
+// multiLocalizedUnicodeType tags is returned.
+func parseText(tag []byte) string {
+	switch string(tag[:4]) {
+	case "desc":
+		if len(tag) < 12 {
+			return ""
+		}
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if n <= 0 || 12+n > len(tag) {
+			return ""
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00")
+	case "mluc":
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:]) == 0 {
+			return ""
+		}
+		n, offset := binary.BigEndian.Uint32(tag[20:]), binary.BigEndian.Uint32(tag[24:])
+		if uint64(offset)+uint64(n) > uint64(len(tag)) {
+			return ""
+		}
+		units := make([]uint16, n/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[int(offset)+2*i:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	case "text":
+		return strings.TrimRight(string(tag[8:]), "\x00")
+	}
+	return ""
+}
+
+// curve is a one-dimensional transform of values in the range [0,1].
+type curve func(x float64) float64
+
+// identityCurve returns `x`.
+func identityCurve(x float64) float64 {
+	return x
+}
+
+// parseCurve returns the curve of curveType or parametricCurveType element `b` and its size,
+// padded to a multiple of 4 bytes.
+func parseCurve(b []byte) (curve, int, error) {
+	if len(b) < 12 {
+		return nil, 0, ErrInvalidProfile
+	}
+	pad := func(n int) int {
+		return (n + 3) &^ 3
+	}
+	switch string(b[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(b[8:]))
+		if n < 0 || 12+2*n > len(b) {
+			return nil, 0, ErrInvalidProfile
+		}
+		switch n {
+		case 0:
+			return identityCurve, pad(12), nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(b[12:])) / 256
+			return func(x float64) float64 {
+				return math.Pow(clamp01(x), gamma)
+			}, pad(14), nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(b[12+2*i:])) / 65535
+		}
+		return tableCurve(table), pad(12 + 2*n), nil
+	case "para":
+		fn := int(binary.BigEndian.Uint16(b[8:]))
+		counts := []int{1, 3, 4, 5, 7}
+		if fn >= len(counts) || 12+4*counts[fn] > len(b) {
+			return nil, 0, ErrInvalidProfile
+		}
+		var p [7]float64
+		for i := 0; i < counts[fn]; i++ {
+			p[i] = s15Fixed16(b[12+4*i:])
+		}
+		g, a, bb, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		power := func(x float64) float64 {
+			if x <= 0 {
+				return 0
+			}
+			return math.Pow(x, g)
+		}
+		var fnc curve
+		switch fn {
+		case 0:
+			fnc = func(x float64) float64 { return power(x) }
+		case 1:
+			fnc = func(x float64) float64 {
+				if x >= -bb/a {
+					return power(a*x + bb)
+				}
+				return 0
+			}
+		case 2:
+			fnc = func(x float64) float64 {
+				if x >= -bb/a {
+					return power(a*x+bb) + c
+				}
+				return c
+			}
+		case 3:
+			fnc = func(x float64) float64 {
+				if x >= d {
+					return power(a*x + bb)
+				}
+				return c * x
+			}
+		case 4:
+			fnc = func(x float64) float64 {
+				if x >= d {
+					return power(a*x+bb) + e
+				}
+				return c*x + f
+			}
+		}
+		return func(x float64) float64 {
+			return clamp01(fnc(clamp01(x)))
+		}, pad(12 + 4*counts[fn]), nil
+	}
+	return nil, 0, ErrUnsupportedProfile
+}
+
+// tableCurve returns the curve interpolating linearly the values `table` evenly spaced in [0,1].
+func tableCurve(table []float64) curve {
+	n := len(table) - 1
+	return func(x float64) float64 {
+		x = clamp01(x) * float64(n)
+		i := int(x)
+		if i >= n {
+			return table[n]
+		}
+		f := x - float64(i)
+		return table[i] + f*(table[i+1]-table[i])
+	}
+}
+
+// inverseSamples is the number of samples of the inverses of curves.
+const inverseSamples = 4096
+
+// invertCurve returns the inverse of monotonic curve `c`.
+func invertCurve(c curve) curve {
+	ys := make([]float64, inverseSamples+1)
+	for i := range ys {
+		ys[i] = c(float64(i) / inverseSamples)
+	}
+	decreasing := ys[0] > ys[inverseSamples]
+	return func(y float64) float64 {
+		// Binary search of the sample interval containing y.
+		lo, hi := 0, inverseSamples
+		below := func(i int) bool {
+			if decreasing {
+				return ys[i] >= y
+			}
+			return ys[i] <= y
+		}
+		if !below(lo) {
+			return 0
+		}
+		if below(hi) {
+			return 1
+		}
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			if below(mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		f := 0.0
+		if d := ys[hi] - ys[lo]; d != 0 {
+			f = (y - ys[lo]) / d
+		}
+		return (float64(lo) + f) / inverseSamples
+	}
+}
+
+// clamp01 returns `x` clamped to the range [0,1].
+func clamp01(x float64) float64 {
+	if x < 0 || math.IsNaN(x) {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
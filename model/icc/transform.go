@@ -0,0 +1,351 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package icc
+
+import (
+	"fmt"
+	"math"
+)
+
+// bradford is the matrix of the Bradford chromatic adaptation transform, mapping XYZ colors to
+// cone responses.
+var bradford = [9]float64{
+	0.8951, 0.2664, -0.1614,
+	-0.7502, 1.7135, 0.0367,
+	0.0389, -0.0685, 1.0296,
+}
+
+// srgb is the sRGB profile.
+var srgb = &Profile{
+	Version:     0x04300000,
+	Class:       ClassDisplay,
+	ColorSpace:  ColorSpaceRGB,
+	PCS:         ColorSpaceXYZ,
+	Intent:      Perceptual,
+	MediaWhite:  D50,
+	Description: "sRGB IEC61966-2.1",
+	// Colorants of the sRGB primaries adapted to D50.
+	colorants: &[3]XYZ{
+		{0.4360747, 0.2225045, 0.0139322},
+		{0.3850649, 0.7168786, 0.0971045},
+		{0.1430804, 0.0606169, 0.7141733},
+	},
+	trc: []curve{srgbCurve, srgbCurve, srgbCurve},
+}
+
+// srgbCurve is the tone reproduction curve of sRGB.
+func srgbCurve(x float64) float64 {
+	x = clamp01(x)
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+// SRGB returns the sRGB profile (IEC 61966-2-1).
+func SRGB() *Profile {
+	return srgb
+}
+
+// NewXYZProfile returns a profile of the CIE XYZ colors relative to white point `white`, which
+// are adapted to the profile connection space with the Bradford transform. Its device colors are
+// the XYZ components, unnormalized. It connects the CIE-based color spaces of PDF (CalGray,
+// CalRGB and Lab) to profiles.
+func NewXYZProfile(white XYZ) *Profile {
+	return &Profile{
+		Version:    0x04300000,
+		Class:      ClassColorSpace,
+		ColorSpace: ColorSpaceXYZ,
+		PCS:        ColorSpaceXYZ,
+		Intent:     RelativeColorimetric,
+		MediaWhite: white,
+		white:      &white,
+	}
+}
+
+// Transform converts colors between two profiles.
+type Transform struct {
+	src, dst *Profile
+	intent   Intent
+	stages   pipeline
+}
+
+// NewTransform returns the transform of the colors of profile `src` to profile `dst` with
+// rendering intent `intent`. The tags of the intent are used when the profiles have them,
+// otherwise the perceptual tags or the matrix/TRC models (which are colorimetric).
+func NewTransform(src, dst *Profile, intent Intent) (*Transform, error) {
+	if src == nil || dst == nil {
+		return nil, ErrInvalidProfile
+	}
+	toPCS, err := src.pipelineToPCS(intent)
+	if err != nil {
+		return nil, err
+	}
+	fromPCS, err := dst.pipelineFromPCS(intent)
+	if err != nil {
+		return nil, err
+	}
+	stages := append(pipeline{}, toPCS...)
+	if intent == AbsoluteColorimetric {
+		// The colors are relative to the media white points in the PCS.
+		var scale [3]float64
+		for i := range scale {
+			scale[i] = 1
+			if dst.MediaWhite[i] != 0 {
+				scale[i] = src.MediaWhite[i] / dst.MediaWhite[i]
+			}
+		}
+		stages = append(stages, matrixStage([9]float64{scale[0], 0, 0, 0, scale[1], 0, 0, 0, scale[2]},
+			[3]float64{}, false))
+	}
+	stages = append(stages, fromPCS...)
+	return &Transform{src: src, dst: dst, intent: intent, stages: stages}, nil
+}
+
+// Source returns the source profile of the transform.
+func (t *Transform) Source() *Profile {
+	return t.src
+}
+
+// Destination returns the destination profile of the transform.
+func (t *Transform) Destination() *Profile {
+	return t.dst
+}
+
+// Intent returns the rendering intent of the transform.
+func (t *Transform) Intent() Intent {
+	return t.intent
+}
+
+// Convert returns the color of the destination profile for color `in` of the source profile.
+// The components of device colors are in the range [0,1], where Lab colors are encoded with
+// L* = 100 L, a* = 255 a - 128 and b* = 255 b - 128. The components of XYZ profiles
+// (NewXYZProfile) are the XYZ components.
+func (t *Transform) Convert(in []float64) []float64 {
+	n := t.src.NumComponents()
+	vals := make([]float64, n)
+	copy(vals, in)
+	return t.stages.apply(vals)
+}
+
+// lutTag returns the LUT-based tag with signature prefix `prefix` (A2B or B2A) for rendering
+// intent `intent`, or the perceptual tag if the profile does not have it.
+func (p *Profile) lutTag(prefix string, intent Intent) ([]byte, bool) {
+	n := 0
+	switch intent {
+	case RelativeColorimetric, AbsoluteColorimetric:
+		n = 1
+	case Saturation:
+		n = 2
+	}
+	if tag, ok := p.tags[fmt.Sprintf("%s%d", prefix, n)]; ok {
+		return tag, true
+	}
+	tag, ok := p.tags[prefix+"0"]
+	return tag, ok
+}
+
+// pipelineToPCS returns the pipeline converting the device colors of the profile to PCS XYZ
+// colors for rendering intent `intent`.
+func (p *Profile) pipelineToPCS(intent Intent) (pipeline, error) {
+	n := p.NumComponents()
+	if p.white != nil {
+		return pipeline{matrixStage(adaptation(*p.white, D50), [3]float64{}, false)}, nil
+	}
+	if p.Class == ClassLink || p.Class == ClassNamedColor || p.Class == ClassAbstract {
+		return nil, ErrUnsupportedProfile
+	}
+	if tag, ok := p.lutTag("A2B", intent); ok {
+		lut, enc, err := parseLUT(tag, n, 3)
+		if err != nil {
+			return nil, err
+		}
+		return append(lut, p.decodePCS(enc)), nil
+	}
+	switch {
+	case p.ColorSpace == ColorSpaceRGB && p.colorants != nil:
+		c := p.colorants
+		m := [9]float64{c[0][0], c[1][0], c[2][0], c[0][1], c[1][1], c[2][1], c[0][2], c[1][2], c[2][2]}
+		return pipeline{curvesStage(p.trc), matrixStage(m, [3]float64{}, false)}, nil
+	case p.ColorSpace == ColorSpaceGray && len(p.trc) == 1:
+		trc := p.trc[0]
+		return pipeline{func(in []float64) []float64 {
+			y := trc(in[0])
+			return []float64{D50[0] * y, D50[1] * y, D50[2] * y}
+		}}, nil
+	}
+	return nil, ErrUnsupportedProfile
+}
+
+// pipelineFromPCS returns the pipeline converting PCS XYZ colors to the device colors of the
+// profile for rendering intent `intent`.
+func (p *Profile) pipelineFromPCS(intent Intent) (pipeline, error) {
+	n := p.NumComponents()
+	if p.white != nil {
+		return pipeline{matrixStage(adaptation(D50, *p.white), [3]float64{}, false)}, nil
+	}
+	if p.Class == ClassLink || p.Class == ClassNamedColor || p.Class == ClassAbstract {
+		return nil, ErrUnsupportedProfile
+	}
+	if tag, ok := p.lutTag("B2A", intent); ok {
+		lut, enc, err := parseLUT(tag, 3, n)
+		if err != nil {
+			return nil, err
+		}
+		return append(pipeline{p.encodePCS(enc)}, lut...), nil
+	}
+	switch {
+	case p.ColorSpace == ColorSpaceRGB && p.colorants != nil:
+		c := p.colorants
+		m, ok := invert3([9]float64{c[0][0], c[1][0], c[2][0], c[0][1], c[1][1], c[2][1], c[0][2], c[1][2],
+			c[2][2]})
+		if !ok {
+			return nil, ErrInvalidProfile
+		}
+		inverse := make([]curve, 3)
+		for i, c := range p.trc {
+			inverse[i] = invertCurve(c)
+		}
+		return pipeline{matrixStage(m, [3]float64{}, true), curvesStage(inverse)}, nil
+	case p.ColorSpace == ColorSpaceGray && len(p.trc) == 1:
+		inverse := invertCurve(p.trc[0])
+		return pipeline{func(in []float64) []float64 {
+			return []float64{inverse(in[1])}
+		}}, nil
+	}
+	return nil, ErrUnsupportedProfile
+}
+
+// decodePCS returns the stage converting the normalized PCS colors of LUT-based tags with
+// encoding `enc` to XYZ colors.
+func (p *Profile) decodePCS(enc pcsEncoding) stage {
+	if p.PCS == ColorSpaceXYZ {
+		return func(in []float64) []float64 {
+			out := make([]float64, 3)
+			for i := range out {
+				out[i] = in[i] * 65535 / 32768
+			}
+			return out
+		}
+	}
+	scale := 1.0
+	if enc == pcsEncodingLegacy {
+		scale = 65535.0 / 65280
+	}
+	return func(in []float64) []float64 {
+		xyz := labToXYZ([3]float64{in[0] * scale * 100, in[1]*scale*255 - 128, in[2]*scale*255 - 128}, D50)
+		return xyz[:]
+	}
+}
+
+// encodePCS returns the stage converting XYZ colors to the normalized PCS colors of LUT-based
+// tags with encoding `enc`.
+func (p *Profile) encodePCS(enc pcsEncoding) stage {
+	if p.PCS == ColorSpaceXYZ {
+		return func(in []float64) []float64 {
+			out := make([]float64, 3)
+			for i := range out {
+				out[i] = clamp01(in[i] * 32768 / 65535)
+			}
+			return out
+		}
+	}
+	scale := 1.0
+	if enc == pcsEncodingLegacy {
+		scale = 65280.0 / 65535
+	}
+	return func(in []float64) []float64 {
+		lab := xyzToLab(XYZ{in[0], in[1], in[2]}, D50)
+		return []float64{clamp01(lab[0] / 100 * scale), clamp01((lab[1] + 128) / 255 * scale),
+			clamp01((lab[2] + 128) / 255 * scale)}
+	}
+}
+
+// labToXYZ returns the XYZ color of CIE L*a*b* color `lab` relative to white point `white`.
+func labToXYZ(lab [3]float64, white XYZ) XYZ {
+	finv := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return 3 * (6.0 / 29) * (6.0 / 29) * (t - 4.0/29)
+	}
+	fy := (lab[0] + 16) / 116
+	return XYZ{white[0] * finv(fy+lab[1]/500), white[1] * finv(fy), white[2] * finv(fy-lab[2]/200)}
+}
+
+// xyzToLab returns the CIE L*a*b* color of XYZ color `xyz` relative to white point `white`.
+func xyzToLab(xyz, white XYZ) [3]float64 {
+	f := func(t float64) float64 {
+		if t > (6.0/29)*(6.0/29)*(6.0/29) {
+			return math.Cbrt(t)
+		}
+		return t/(3*(6.0/29)*(6.0/29)) + 4.0/29
+	}
+	fx, fy, fz := f(xyz[0]/white[0]), f(xyz[1]/white[1]), f(xyz[2]/white[2])
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// adaptation returns the Bradford chromatic adaptation matrix from white point `src` to white
+// point `dst`.
+func adaptation(src, dst XYZ) [9]float64 {
+	inverse, _ := invert3(bradford)
+	s, d := mulVec3(bradford, src), mulVec3(bradford, dst)
+	var scale [9]float64
+	for i := 0; i < 3; i++ {
+		scale[4*i] = 1
+		if s[i] != 0 {
+			scale[4*i] = d[i] / s[i]
+		}
+	}
+	return mul3(inverse, mul3(scale, bradford))
+}
+
+// Adapt returns the XYZ color `xyz` relative to white point `src` adapted to white point `dst`
+// with the Bradford transform.
+func Adapt(xyz, src, dst XYZ) XYZ {
+	return mulVec3(adaptation(src, dst), xyz)
+}
+
+// mul3 returns the product of 3×3 matrices `a` and `b`.
+func mul3(a, b [9]float64) [9]float64 {
+	var m [9]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[3*i+j] += a[3*i+k] * b[3*k+j]
+			}
+		}
+	}
+	return m
+}
+
+// mulVec3 returns the product of 3×3 matrix `m` and vector `v`.
+func mulVec3(m [9]float64, v XYZ) XYZ {
+	return XYZ{
+		m[0]*v[0] + m[1]*v[1] + m[2]*v[2],
+		m[3]*v[0] + m[4]*v[1] + m[5]*v[2],
+		m[6]*v[0] + m[7]*v[1] + m[8]*v[2],
+	}
+}
+
+// invert3 returns the inverse of 3×3 matrix `m` and false if it is singular.
+func invert3(m [9]float64) ([9]float64, bool) {
+	det := m[0]*(m[4]*m[8]-m[5]*m[7]) - m[1]*(m[3]*m[8]-m[5]*m[6]) + m[2]*(m[3]*m[7]-m[4]*m[6])
+	if det == 0 || math.IsNaN(det) {
+		return [9]float64{}, false
+	}
+	return [9]float64{
+		(m[4]*m[8] - m[5]*m[7]) / det,
+		(m[2]*m[7] - m[1]*m[8]) / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		(m[5]*m[6] - m[3]*m[8]) / det,
+		(m[0]*m[8] - m[2]*m[6]) / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+		(m[3]*m[7] - m[4]*m[6]) / det,
+		(m[1]*m[6] - m[0]*m[7]) / det,
+		(m[0]*m[4] - m[1]*m[3]) / det,
+	}, true
+}
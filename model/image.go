@@ -359,19 +359,27 @@ type DefaultImageHandler struct{}
 
 // NewImageFromGoImage creates a new RGBA unidoc Image from a golang Image.
 // If `goimg` is grayscale (*goimage.Gray) then calls NewGrayImageFromGoImage instead.
+// The alpha samples of translucent images are kept for the soft mask, and the color samples are
+// not premultiplied by alpha: those of translucent *goimage.RGBA images are converted.
 func (ih DefaultImageHandler) NewImageFromGoImage(goimg goimage.Image) (*Image, error) {
 	b := goimg.Bounds()
 
-	var m *goimage.RGBA
+	var m *goimage.NRGBA
 	switch t := goimg.(type) {
 	case *goimage.Gray, *goimage.Gray16:
 		return ih.NewGrayImageFromGoImage(goimg)
-	case *goimage.RGBA:
+	case *goimage.NRGBA:
 		m = t
-	default:
-		// Speed up jpeg encoding by converting to RGBA first.
+	case *goimage.RGBA:
+		// The samples of opaque images are the same in both models.
+		if t.Opaque() {
+			m = &goimage.NRGBA{Pix: t.Pix, Stride: t.Stride, Rect: t.Rect}
+		}
+	}
+	if m == nil {
+		// Speed up jpeg encoding by converting to NRGBA first.
 		// Will not be required once the golang image/jpeg package is optimized.
-		m = goimage.NewRGBA(goimage.Rect(0, 0, b.Dx(), b.Dy()))
+		m = goimage.NewNRGBA(goimage.Rect(0, 0, b.Dx(), b.Dy()))
 		draw.Draw(m, m.Bounds(), goimg, b.Min, draw.Src)
 		b = m.Bounds()
 	}
@@ -448,7 +456,8 @@ func (ih DefaultImageHandler) NewGrayImageFromGoImage(goimg goimage.Image) (*Ima
 }
 
 // Read reads an image and loads into a new Image object with an RGB
-// colormap and 8 bits per component.
+// colormap and 8 bits per component. JPEG, PNG and GIF images are supported, and BMP, TIFF and
+// WebP images if package model/imageformats is imported.
 func (ih DefaultImageHandler) Read(reader io.Reader) (*Image, error) {
 	// Load the image with the native implementation.
 	goimg, _, err := goimage.Decode(reader)
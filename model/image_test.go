@@ -6,12 +6,17 @@
 package model
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"image/draw"
+	"image/png"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/image/bmp"
+
+	_ "github.com/unidoc/unipdf/v3/model/imageformats"
 )
 
 func TestImageResampling(t *testing.T) {
@@ -379,3 +384,54 @@ func BenchmarkImageCopyingDirect(b *testing.B) {
 		}
 	}
 }
+
+func TestImageHandlerRead(t *testing.T) {
+	goimg := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	goimg.Set(0, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 128})
+	goimg.Set(1, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+
+	// The PNG alpha channel is converted to a soft mask, with colors not premultiplied by alpha.
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, goimg))
+	img, err := ImageHandling.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{200, 100, 50, 10, 20, 30}, img.Data)
+	require.True(t, img.hasAlpha)
+	require.Equal(t, []byte{128, 255}, img.alphaData)
+
+	ximg, err := NewXObjectImageFromImage(img, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, ximg.SMask)
+
+	// BMP files.
+	buf.Reset()
+	require.NoError(t, bmp.Encode(&buf, goimg.SubImage(image.Rect(1, 0, 2, 1))))
+	img, err = ImageHandling.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{10, 20, 30}, img.Data)
+	require.False(t, img.hasAlpha)
+}
+
+func TestImageFromGoImageRGBA(t *testing.T) {
+	var ih DefaultImageHandler
+
+	// The samples of opaque images are copied as is.
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	opaque.Set(0, 0, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+	opaque.Set(1, 0, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	img, err := ih.NewImageFromGoImage(opaque)
+	require.NoError(t, err)
+	require.Equal(t, []byte{200, 100, 50, 10, 20, 30}, img.Data)
+	require.False(t, img.hasAlpha)
+
+	// The color samples of translucent images are not premultiplied by alpha.
+	translucent := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	translucent.Set(0, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 128})
+	img, err = ih.NewImageFromGoImage(translucent)
+	require.NoError(t, err)
+	c := color.NRGBAModel.Convert(translucent.At(0, 0)).(color.NRGBA)
+	require.Equal(t, []byte{c.R, c.G, c.B}, img.Data)
+	require.InDelta(t, 200, float64(img.Data[0]), 1)
+	require.True(t, img.hasAlpha)
+	require.Equal(t, []byte{128}, img.alphaData)
+}
@@ -0,0 +1,248 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/ccittfax"
+	"github.com/unidoc/unipdf/v3/internal/tiff"
+)
+
+// TIFFPage is a page of a TIFF file converted to an image XObject.
+type TIFFPage struct {
+	Image *XObjectImage
+
+	// XResolution and YResolution are the resolution of the page in pixels per inch, 0 if not
+	// specified in the TIFF file.
+	XResolution, YResolution float64
+}
+
+// ReadTIFF reads the pages of the (multi-page) TIFF file read from `r` as image XObjects.
+// The bilevel pages compressed with the CCITT Group 3 or Group 4 schemes in a single strip are
+// embedded as is with the CCITTFaxDecode filter. The bilevel pages with several strips are
+// re-encoded with the Group 4 scheme, and the other pages are encoded with the FlateDecode filter.
+// The extra alpha samples are converted to soft masks.
+func ReadTIFF(r io.Reader) ([]*TIFFPage, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := tiff.Decode(data)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid TIFF file: %v", err)
+		return nil, err
+	}
+
+	tiffPages := make([]*TIFFPage, 0, len(pages))
+	for i, page := range pages {
+		var ximg *XObjectImage
+		if page.IsCCITT() {
+			ximg, err = newXObjectImageFromCCITTPage(page)
+		} else {
+			ximg, err = newXObjectImageFromTIFFPage(page)
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: TIFF page %d: %v", i+1, err)
+			return nil, err
+		}
+		tiffPages = append(tiffPages, &TIFFPage{
+			Image:       ximg,
+			XResolution: page.XResolution,
+			YResolution: page.YResolution,
+		})
+	}
+	return tiffPages, nil
+}
+
+// newXObjectImageFromCCITTPage returns the image XObject of TIFF `page` compressed with a CCITT
+// scheme.
+func newXObjectImageFromCCITTPage(page *tiff.Page) (*XObjectImage, error) {
+	if page.BitsPerSample != 1 || page.SamplesPerPixel != 1 {
+		return nil, errors.New("invalid CCITT compressed image")
+	}
+
+	// The CCITT decoders map the runs of white pixels to 0 bits with BlackIs1, the bits of the
+	// BlackIsZero images.
+	enc := &core.CCITTFaxEncoder{
+		Columns:  page.Width,
+		Rows:     page.Height,
+		BlackIs1: page.Photometric == tiff.PhotometricBlackIsZero,
+	}
+	switch page.Compression {
+	case tiff.CompressionCCITTRLE:
+		enc.EncodedByteAlign = true
+	case tiff.CompressionCCITTGroup3:
+		if page.T4Options&tiff.T4Option2D != 0 {
+			enc.K = 1
+		}
+		enc.EncodedByteAlign = page.T4Options&tiff.T4OptionFillBits != 0
+	case tiff.CompressionCCITTGroup4:
+		enc.K = -1
+	}
+
+	var stream []byte
+	if len(page.Strips) == 1 {
+		stream = page.Strips[0]
+	} else {
+		// The strips are separately encoded: the image is re-encoded as a whole.
+		var rows [][]byte
+		for i, strip := range page.Strips {
+			decoder := &ccittfax.Encoder{
+				K:                enc.K,
+				EncodedByteAlign: enc.EncodedByteAlign,
+				Columns:          enc.Columns,
+				Rows:             page.StripRows(i),
+				BlackIs1:         enc.BlackIs1,
+			}
+			stripRows, err := decoder.Decode(strip)
+			if err != nil {
+				return nil, err
+			}
+			if len(stripRows) > decoder.Rows {
+				stripRows = stripRows[:decoder.Rows]
+			}
+			rows = append(rows, stripRows...)
+		}
+		enc.K = -1
+		enc.EncodedByteAlign = false
+		enc.Rows = len(rows)
+		encoder := &ccittfax.Encoder{K: enc.K, Columns: enc.Columns, Rows: enc.Rows, BlackIs1: enc.BlackIs1}
+		stream = encoder.Encode(rows)
+	}
+
+	ximg := NewXObjectImage()
+	width, height, bpc := int64(enc.Columns), int64(enc.Rows), int64(1)
+	ximg.Width = &width
+	ximg.Height = &height
+	ximg.BitsPerComponent = &bpc
+	ximg.ColorSpace = NewPdfColorspaceDeviceGray()
+	ximg.Filter = enc
+	ximg.Stream = stream
+	return ximg, nil
+}
+
+// newXObjectImageFromTIFFPage returns the image XObject of TIFF `page` not compressed with a
+// CCITT scheme.
+func newXObjectImageFromTIFFPage(page *tiff.Page) (*XObjectImage, error) {
+	samples, err := page.Samples()
+	if err != nil {
+		return nil, err
+	}
+
+	bpc := page.BitsPerSample
+	numColors := page.SamplesPerPixel - len(page.ExtraSamples)
+	var cs PdfColorspace
+	var decode []float64
+	switch page.Photometric {
+	case tiff.PhotometricWhiteIsZero, tiff.PhotometricBlackIsZero:
+		if numColors != 1 {
+			return nil, fmt.Errorf("invalid number of gray samples: %d", numColors)
+		}
+		cs = NewPdfColorspaceDeviceGray()
+		if page.Photometric == tiff.PhotometricWhiteIsZero {
+			decode = []float64{1, 0}
+		}
+	case tiff.PhotometricRGB:
+		if numColors != 3 {
+			return nil, fmt.Errorf("invalid number of RGB samples: %d", numColors)
+		}
+		cs = NewPdfColorspaceDeviceRGB()
+	case tiff.PhotometricPalette:
+		if numColors != 1 || bpc > 8 {
+			return nil, errors.New("invalid palette color image")
+		}
+		cs, err = newTIFFPaletteColorspace(page.ColorMap, bpc)
+		if err != nil {
+			return nil, err
+		}
+	case tiff.PhotometricSeparated:
+		if numColors != 4 || page.InkSet != 1 {
+			return nil, errors.New("unsupported separated image: CMYK expected")
+		}
+		cs = NewPdfColorspaceDeviceCMYK()
+	default:
+		return nil, fmt.Errorf("%v: photometric interpretation %d", tiff.ErrUnsupported, page.Photometric)
+	}
+	if numColors < 1 {
+		return nil, errors.New("invalid number of samples")
+	}
+
+	img := &Image{
+		Width:            int64(page.Width),
+		Height:           int64(page.Height),
+		BitsPerComponent: int64(bpc),
+		ColorComponents:  numColors,
+		Data:             samples,
+	}
+	alpha := len(page.ExtraSamples) > 0 && page.ExtraSamples[0] != tiff.ExtraSampleUnspecified
+	if len(page.ExtraSamples) > 0 {
+		if bpc != 8 && bpc != 16 {
+			return nil, fmt.Errorf("%v: extra samples with %d bits per sample", tiff.ErrUnsupported, bpc)
+		}
+		img.Data, img.alphaData = splitTIFFExtraSamples(samples, numColors, page.SamplesPerPixel, bpc/8)
+		img.hasAlpha = alpha
+	}
+
+	ximg, err := NewXObjectImageFromImage(img, cs, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	if decode != nil {
+		ximg.Decode = core.MakeArrayFromFloats(decode)
+	}
+	if alpha && page.ExtraSamples[0] == tiff.ExtraSampleAssociatedAlpha {
+		// The color samples are premultiplied by alpha, i.e. blended with black.
+		if smask, ok := core.GetStream(ximg.SMask); ok {
+			matte := make([]float64, numColors)
+			if decode != nil {
+				matte[0] = 1
+			}
+			smask.Set("Matte", core.MakeArrayFromFloats(matte))
+		}
+	}
+	return ximg, nil
+}
+
+// newTIFFPaletteColorspace returns the Indexed colorspace of TIFF color map `colorMap` of the
+// images with `bpc` bits per sample.
+func newTIFFPaletteColorspace(colorMap []uint16, bpc int) (*PdfColorspaceSpecialIndexed, error) {
+	n := 1 << uint(bpc)
+	if len(colorMap) != 3*n {
+		return nil, errors.New("invalid color map")
+	}
+	lookup := make([]byte, 3*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 3; j++ {
+			lookup[3*i+j] = byte(colorMap[j*n+i] >> 8)
+		}
+	}
+	cs := NewPdfColorspaceSpecialIndexed()
+	cs.Base = NewPdfColorspaceDeviceRGB()
+	cs.HiVal = n - 1
+	cs.Lookup = core.MakeStringFromBytes(lookup)
+	cs.colorLookup = lookup
+	return cs, nil
+}
+
+// splitTIFFExtraSamples splits the samples of `samples`, with `spp` samples of `size` bytes per
+// pixel, into the `numColors` color samples and the first extra sample of each pixel.
+func splitTIFFExtraSamples(samples []byte, numColors, spp, size int) (colors, extra []byte) {
+	numPixels := len(samples) / (spp * size)
+	colors = make([]byte, 0, numPixels*numColors*size)
+	extra = make([]byte, 0, numPixels*size)
+	for i := 0; i < numPixels; i++ {
+		pixel := samples[i*spp*size : (i+1)*spp*size]
+		colors = append(colors, pixel[:numColors*size]...)
+		extra = append(extra, pixel[numColors*size:(numColors+1)*size]...)
+	}
+	return colors, extra
+}
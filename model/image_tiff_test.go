@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/ccittfax"
+	"github.com/unidoc/unipdf/v3/internal/tiff/tifftest"
+)
+
+func TestReadTIFF(t *testing.T) {
+	// 16x2 bilevel image: 8 black and 8 white pixels, then a white row (white is 1).
+	rows := [][]byte{
+		{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1},
+		{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	}
+	g4 := (&ccittfax.Encoder{K: -1, Columns: 16}).Encode(rows)
+	g3Row0 := (&ccittfax.Encoder{K: 0, Columns: 16}).Encode(rows[:1])
+	g3Row1 := (&ccittfax.Encoder{K: 0, Columns: 16}).Encode(rows[1:])
+
+	// Tags: ImageWidth, ImageLength, BitsPerSample, Compression, Photometric, SamplesPerPixel,
+	// RowsPerStrip and ExtraSamples.
+	data := tifftest.Encode(binary.LittleEndian, []tifftest.Page{
+		{
+			Tags:   map[uint16][]uint16{256: {16}, 257: {2}, 259: {4}, 262: {0}, 277: {1}},
+			Strips: [][]byte{g4},
+		},
+		{
+			Tags:   map[uint16][]uint16{256: {16}, 257: {2}, 259: {3}, 262: {0}, 277: {1}, 278: {1}},
+			Strips: [][]byte{g3Row0, g3Row1},
+		},
+		{
+			Tags:   map[uint16][]uint16{256: {2}, 257: {1}, 258: {8}, 262: {2}, 277: {4}, 338: {2}},
+			Strips: [][]byte{{255, 0, 0, 128, 0, 0, 255, 255}},
+		},
+	})
+
+	pages, err := ReadTIFF(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+
+	// The Group 4 strip is embedded as is.
+	ximg := pages[0].Image
+	require.Equal(t, g4, ximg.Stream)
+	enc, ok := ximg.Filter.(*core.CCITTFaxEncoder)
+	require.True(t, ok)
+	require.Equal(t, -1, enc.K)
+	require.Equal(t, 16, enc.Columns)
+	require.Equal(t, 2, enc.Rows)
+	require.False(t, enc.BlackIs1)
+	ximg.ToPdfObject()
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0xff, 0xff, 0xff}, img.Data)
+
+	// The Group 3 strips are re-encoded as a whole with the Group 4 scheme.
+	ximg = pages[1].Image
+	enc, ok = ximg.Filter.(*core.CCITTFaxEncoder)
+	require.True(t, ok)
+	require.Equal(t, -1, enc.K)
+	require.Equal(t, g4, ximg.Stream)
+
+	// RGB with unassociated alpha.
+	ximg = pages[2].Image
+	require.Equal(t, core.StreamEncodingFilterNameFlate, ximg.Filter.GetFilterName())
+	ximg.ToPdfObject()
+	img, err = ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, []byte{255, 0, 0, 0, 0, 255}, img.Data)
+	smask, ok := core.GetStream(ximg.SMask)
+	require.True(t, ok)
+	alpha, err := core.DecodeStream(smask)
+	require.NoError(t, err)
+	require.Equal(t, []byte{128, 255}, alpha)
+	require.Nil(t, smask.Get("Matte"))
+}
+
+func TestReadTIFFInvalid(t *testing.T) {
+	_, err := ReadTIFF(bytes.NewReader([]byte("not a TIFF file")))
+	require.Error(t, err)
+
+	// JPEG compression is not supported.
+	data := tifftest.Encode(binary.LittleEndian, []tifftest.Page{{
+		Tags:   map[uint16][]uint16{256: {1}, 257: {1}, 258: {8}, 259: {7}, 262: {1}, 277: {1}},
+		Strips: [][]byte{{0}},
+	}})
+	_, err = ReadTIFF(bytes.NewReader(data))
+	require.Error(t, err)
+}
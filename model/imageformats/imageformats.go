@@ -0,0 +1,22 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package imageformats registers the BMP, TIFF and WebP decoders of golang.org/x/image with
+// package image, so that the default image handler of package model (model.ImageHandling) and the
+// creator images read files in those formats, in addition to JPEG, PNG and GIF. It is imported
+// for its initialization side effects only:
+//
+//	import _ "github.com/unidoc/unipdf/v3/model/imageformats"
+//
+// Only the first page of multi-page TIFF files is read this way: model.ReadTIFF reads all the
+// pages without this package.
+package imageformats
+
+import (
+	// Imported for initialization side effects.
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
@@ -0,0 +1,1082 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+)
+
+// CFFFont is a Compact Font Format font program, as embedded in FontFile3 streams with subtype
+// Type1C or CIDFontType0C, or in the CFF table of OpenType fonts. Only the information needed
+// to look up glyphs and to interpret their Type 2 charstrings is loaded.
+// See Adobe Technical Notes #5176 (CFF) and #5177 (Type 2 charstrings).
+type CFFFont struct {
+	Name string
+	// FontMatrix maps the glyph space to the text space.
+	FontMatrix [6]float64
+
+	isCID       bool
+	strings     []string
+	charStrings [][]byte
+	globalSubrs [][]byte
+	privates    []cffPrivate
+	fdSelect    []byte   // Font dict index of each glyph (CID-keyed fonts).
+	charset     []uint16 // SID (or CID for CID-keyed fonts) of each glyph.
+	encoding    map[byte]GID
+	nameToGID   map[GlyphName]GID
+	cidToGID    map[uint16]GID
+}
+
+// cffPrivate is the part of a Private DICT needed to interpret charstrings.
+type cffPrivate struct {
+	subrs [][]byte
+}
+
+// Operators of Top and Private DICTs. Escaped operators 12 x are 1200+x.
+const (
+	cffOpCharset     = 15
+	cffOpEncoding    = 16
+	cffOpCharStrings = 17
+	cffOpPrivate     = 18
+	cffOpSubrs       = 19
+	cffOpFontMatrix  = 1207
+	cffOpROS         = 1230
+	cffOpFDArray     = 1236
+	cffOpFDSelect    = 1237
+)
+
+// ParseCFF parses the CFF font program `data`.
+func ParseCFF(data []byte) (*CFFFont, error) {
+	if len(data) < 4 {
+		return nil, errors.New("CFF data too short")
+	}
+	if data[0] != 1 {
+		return nil, fmt.Errorf("unsupported CFF version %d", data[0])
+	}
+	offset := int(data[2])
+
+	names, offset, err := cffIndex(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	topDicts, offset, err := cffIndex(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	strs, offset, err := cffIndex(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	globalSubrs, _, err := cffIndex(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(topDicts) == 0 {
+		return nil, errors.New("no CFF top dict")
+	}
+
+	font := &CFFFont{
+		FontMatrix:  [6]float64{0.001, 0, 0, 0.001, 0, 0},
+		globalSubrs: globalSubrs,
+	}
+	if len(names) > 0 {
+		font.Name = string(names[0])
+	}
+	for _, s := range strs {
+		font.strings = append(font.strings, string(s))
+	}
+
+	top, err := cffDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	if m := top[cffOpFontMatrix]; len(m) == 6 {
+		copy(font.FontMatrix[:], m)
+	}
+	if ops, ok := top[cffOpCharStrings]; !ok || len(ops) != 1 {
+		return nil, errors.New("missing CFF CharStrings")
+	}
+	font.charStrings, _, err = cffIndex(data, int(top[cffOpCharStrings][0]))
+	if err != nil {
+		return nil, err
+	}
+	numGlyphs := len(font.charStrings)
+
+	_, font.isCID = top[cffOpROS]
+	if font.isCID {
+		fdArray, ok := top[cffOpFDArray]
+		if !ok || len(fdArray) != 1 {
+			return nil, errors.New("missing CFF FDArray")
+		}
+		fontDicts, _, err := cffIndex(data, int(fdArray[0]))
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range fontDicts {
+			dict, err := cffDict(fd)
+			if err != nil {
+				return nil, err
+			}
+			private, err := parseCFFPrivate(data, dict[cffOpPrivate])
+			if err != nil {
+				return nil, err
+			}
+			font.privates = append(font.privates, private)
+		}
+		if fdSelect, ok := top[cffOpFDSelect]; ok && len(fdSelect) == 1 {
+			font.fdSelect, err = parseCFFFDSelect(data, int(fdSelect[0]), numGlyphs)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		private, err := parseCFFPrivate(data, top[cffOpPrivate])
+		if err != nil {
+			return nil, err
+		}
+		font.privates = []cffPrivate{private}
+	}
+
+	charsetOffset := 0
+	if ops := top[cffOpCharset]; len(ops) == 1 {
+		charsetOffset = int(ops[0])
+	}
+	font.charset, err = parseCFFCharset(data, charsetOffset, numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	if font.isCID {
+		font.cidToGID = make(map[uint16]GID, numGlyphs)
+		for gid, cid := range font.charset {
+			font.cidToGID[cid] = GID(gid)
+		}
+	} else {
+		font.nameToGID = make(map[GlyphName]GID, numGlyphs)
+		for gid, sid := range font.charset {
+			font.nameToGID[GlyphName(font.sidString(sid))] = GID(gid)
+		}
+		encodingOffset := 0
+		if ops := top[cffOpEncoding]; len(ops) == 1 {
+			encodingOffset = int(ops[0])
+		}
+		font.encoding, err = font.parseEncoding(data, encodingOffset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return font, nil
+}
+
+// OpenTypeCFFTable returns the CFF table of the OpenType font `data`.
+func OpenTypeCFFTable(data []byte) ([]byte, error) {
+	return sfntTable(data, "CFF ")
+}
+
+// String returns a string describing `font`.
+func (font *CFFFont) String() string {
+	return fmt.Sprintf("CFF{%#q glyphs=%d CID=%t}", font.Name, len(font.charStrings), font.isCID)
+}
+
+// IsCID returns true if `font` is a CID-keyed font.
+func (font *CFFFont) IsCID() bool {
+	return font.isCID
+}
+
+// NumGlyphs returns the number of glyphs in `font`.
+func (font *CFFFont) NumGlyphs() int {
+	return len(font.charStrings)
+}
+
+// GlyphByName returns the glyph named `name`.
+func (font *CFFFont) GlyphByName(name GlyphName) (GID, bool) {
+	gid, ok := font.nameToGID[name]
+	return gid, ok
+}
+
+// GlyphByCID returns the glyph with CID `cid`. For fonts that are not CID-keyed, the CID is the
+// glyph index.
+func (font *CFFFont) GlyphByCID(cid uint16) (GID, bool) {
+	if !font.isCID {
+		return GID(cid), int(cid) < len(font.charStrings)
+	}
+	gid, ok := font.cidToGID[cid]
+	return gid, ok
+}
+
+// GlyphByCode returns the glyph of character code `code` in the built-in encoding of `font`.
+func (font *CFFFont) GlyphByCode(code byte) (GID, bool) {
+	gid, ok := font.encoding[code]
+	return gid, ok
+}
+
+// GlyphOutline returns the outline of glyph `gid` in glyph space units.
+func (font *CFFFont) GlyphOutline(gid GID) (*GlyphPath, error) {
+	if int(gid) >= len(font.charStrings) {
+		return nil, fmt.Errorf("glyph %d out of range", gid)
+	}
+	private := font.privates[0]
+	if font.fdSelect != nil && int(gid) < len(font.fdSelect) {
+		if fd := int(font.fdSelect[gid]); fd < len(font.privates) {
+			private = font.privates[fd]
+		}
+	}
+	interp := type2Interpreter{
+		font:        font,
+		path:        &GlyphPath{},
+		globalSubrs: font.globalSubrs,
+		localSubrs:  private.subrs,
+	}
+	if err := interp.run(font.charStrings[gid], 0); err != nil && err != errEndChar {
+		return nil, err
+	}
+	interp.path.Close()
+	return interp.path, nil
+}
+
+// sidString returns the string with identifier `sid`.
+func (font *CFFFont) sidString(sid uint16) string {
+	if int(sid) < len(cffStandardStrings) {
+		return cffStandardStrings[sid]
+	}
+	if i := int(sid) - len(cffStandardStrings); i < len(font.strings) {
+		return font.strings[i]
+	}
+	return ""
+}
+
+// standardEncoder is the encoder of the standard encoding, used for built-in encodings and
+// accented characters.
+var (
+	standardEncoder     textencoding.SimpleEncoder
+	standardEncoderOnce sync.Once
+)
+
+// loadStandardEncoder loads standardEncoder.
+func loadStandardEncoder() {
+	enc, err := textencoding.NewSimpleTextEncoder("StandardEncoding", nil)
+	if err == nil {
+		standardEncoder = enc
+	}
+}
+
+// standardEncodingGlyph returns the glyph of code `code` in the standard encoding.
+func (font *CFFFont) standardEncodingGlyph(code byte) (GID, bool) {
+	name, ok := standardEncodingName(code)
+	if !ok {
+		return 0, false
+	}
+	return font.GlyphByName(name)
+}
+
+// parseEncoding returns the built-in encoding of `font` at `offset`.
+func (font *CFFFont) parseEncoding(data []byte, offset int) (map[byte]GID, error) {
+	encoding := make(map[byte]GID)
+	switch offset {
+	case 0:
+		// Standard encoding.
+		for code := 0; code < 256; code++ {
+			if gid, ok := font.standardEncodingGlyph(byte(code)); ok && gid != 0 {
+				encoding[byte(code)] = gid
+			}
+		}
+		return encoding, nil
+	case 1:
+		// Expert encoding: not supported, the glyphs are looked up by name.
+		return encoding, nil
+	}
+
+	errShort := errors.New("CFF encoding too short")
+	if offset+2 > len(data) {
+		return nil, errShort
+	}
+	format := data[offset]
+	p := offset + 1
+	switch format & 0x7f {
+	case 0:
+		n := int(data[p])
+		p++
+		if p+n > len(data) {
+			return nil, errShort
+		}
+		for i := 0; i < n; i++ {
+			encoding[data[p+i]] = GID(i + 1)
+		}
+		p += n
+	case 1:
+		nRanges := int(data[p])
+		p++
+		if p+2*nRanges > len(data) {
+			return nil, errShort
+		}
+		gid := 1
+		for i := 0; i < nRanges; i++ {
+			first, nLeft := int(data[p]), int(data[p+1])
+			p += 2
+			for code := first; code <= first+nLeft && code < 256; code++ {
+				encoding[byte(code)] = GID(gid)
+				gid++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CFF encoding format %d", format)
+	}
+	if format&0x80 != 0 && p < len(data) {
+		// Supplements: codes of glyphs with several codes.
+		nSups := int(data[p])
+		p++
+		for i := 0; i < nSups && p+3 <= len(data); i++ {
+			code := data[p]
+			sid := binary.BigEndian.Uint16(data[p+1:])
+			p += 3
+			if gid, ok := font.GlyphByName(GlyphName(font.sidString(sid))); ok {
+				encoding[code] = gid
+			}
+		}
+	}
+	return encoding, nil
+}
+
+// parseCFFPrivate parses the Private DICT with size and offset `ops`.
+func parseCFFPrivate(data []byte, ops []float64) (cffPrivate, error) {
+	var private cffPrivate
+	if len(ops) != 2 {
+		return private, nil
+	}
+	size, offset := int(ops[0]), int(ops[1])
+	if size < 0 || offset < 0 || offset+size > len(data) {
+		return private, errors.New("invalid CFF private dict")
+	}
+	dict, err := cffDict(data[offset : offset+size])
+	if err != nil {
+		return private, err
+	}
+	if subrs := dict[cffOpSubrs]; len(subrs) == 1 {
+		private.subrs, _, err = cffIndex(data, offset+int(subrs[0]))
+		if err != nil {
+			return private, err
+		}
+	}
+	return private, nil
+}
+
+// parseCFFCharset returns the SIDs (or CIDs) of the `numGlyphs` glyphs of the charset at
+// `offset`.
+func parseCFFCharset(data []byte, offset int, numGlyphs int) ([]uint16, error) {
+	charset := make([]uint16, numGlyphs)
+	if offset <= 2 {
+		// Predefined charsets. Only ISOAdobe, where the SIDs are the glyph indexes, is supported.
+		for i := range charset {
+			charset[i] = uint16(i)
+		}
+		return charset, nil
+	}
+	if offset >= len(data) {
+		return nil, errors.New("invalid CFF charset offset")
+	}
+	format := data[offset]
+	p := offset + 1
+	errShort := errors.New("CFF charset too short")
+	gid := 1
+	switch format {
+	case 0:
+		for ; gid < numGlyphs; gid++ {
+			if p+2 > len(data) {
+				return nil, errShort
+			}
+			charset[gid] = binary.BigEndian.Uint16(data[p:])
+			p += 2
+		}
+	case 1, 2:
+		for gid < numGlyphs {
+			size := 3
+			if format == 2 {
+				size = 4
+			}
+			if p+size > len(data) {
+				return nil, errShort
+			}
+			first := int(binary.BigEndian.Uint16(data[p:]))
+			nLeft := int(data[p+2])
+			if format == 2 {
+				nLeft = int(binary.BigEndian.Uint16(data[p+2:]))
+			}
+			p += size
+			for i := 0; i <= nLeft && gid < numGlyphs; i++ {
+				charset[gid] = uint16(first + i)
+				gid++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CFF charset format %d", format)
+	}
+	return charset, nil
+}
+
+// parseCFFFDSelect returns the font dict indexes of the `numGlyphs` glyphs of the FDSelect at
+// `offset`.
+func parseCFFFDSelect(data []byte, offset int, numGlyphs int) ([]byte, error) {
+	if offset >= len(data) {
+		return nil, errors.New("invalid CFF FDSelect offset")
+	}
+	fdSelect := make([]byte, numGlyphs)
+	errShort := errors.New("CFF FDSelect too short")
+	switch data[offset] {
+	case 0:
+		if offset+1+numGlyphs > len(data) {
+			return nil, errShort
+		}
+		copy(fdSelect, data[offset+1:])
+	case 3:
+		if offset+3 > len(data) {
+			return nil, errShort
+		}
+		nRanges := int(binary.BigEndian.Uint16(data[offset+1:]))
+		p := offset + 3
+		if p+3*nRanges+2 > len(data) {
+			return nil, errShort
+		}
+		for i := 0; i < nRanges; i++ {
+			first := int(binary.BigEndian.Uint16(data[p:]))
+			fd := data[p+2]
+			next := int(binary.BigEndian.Uint16(data[p+3:]))
+			for gid := first; gid < next && gid < numGlyphs; gid++ {
+				fdSelect[gid] = fd
+			}
+			p += 3
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CFF FDSelect format %d", data[offset])
+	}
+	return fdSelect, nil
+}
+
+// cffIndex returns the objects of the INDEX at `offset` in `data` and the offset following it.
+func cffIndex(data []byte, offset int) ([][]byte, int, error) {
+	errShort := errors.New("CFF INDEX too short")
+	if offset < 0 || offset+2 > len(data) {
+		return nil, 0, errShort
+	}
+	count := int(binary.BigEndian.Uint16(data[offset:]))
+	if count == 0 {
+		return nil, offset + 2, nil
+	}
+	if offset+3 > len(data) {
+		return nil, 0, errShort
+	}
+	offSize := int(data[offset+2])
+	if offSize < 1 || offSize > 4 {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX offset size %d", offSize)
+	}
+	p := offset + 3
+	if p+(count+1)*offSize > len(data) {
+		return nil, 0, errShort
+	}
+	readOffset := func(i int) int {
+		v := 0
+		for _, b := range data[p+i*offSize : p+(i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+	// The offsets are relative to the byte preceding the object data.
+	base := p + (count+1)*offSize - 1
+	objects := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		start, end := base+readOffset(i), base+readOffset(i+1)
+		if start < base+1 || start > end || end > len(data) {
+			return nil, 0, errors.New("invalid CFF INDEX offsets")
+		}
+		objects[i] = data[start:end]
+	}
+	return objects, base + readOffset(count), nil
+}
+
+// cffDict returns the operands of each operator of the DICT `data`.
+func cffDict(data []byte) (map[int][]float64, error) {
+	dict := make(map[int][]float64)
+	var operands []float64
+	for p := 0; p < len(data); {
+		b0 := data[p]
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			p++
+			if b0 == 12 {
+				if p >= len(data) {
+					return nil, errors.New("invalid CFF DICT escape")
+				}
+				op = 1200 + int(data[p])
+				p++
+			}
+			dict[op] = operands
+			operands = nil
+		case b0 == 30:
+			v, n, err := cffReal(data[p+1:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, v)
+			p += 1 + n
+		default:
+			v, n, err := cffDictInt(data[p:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, float64(v))
+			p += n
+		}
+	}
+	return dict, nil
+}
+
+// cffDictInt returns the integer operand at the start of `data` and its size.
+func cffDictInt(data []byte) (int, int, error) {
+	errShort := errors.New("CFF DICT operand too short")
+	b0 := int(data[0])
+	switch {
+	case b0 == 28:
+		if len(data) < 3 {
+			return 0, 0, errShort
+		}
+		return int(int16(binary.BigEndian.Uint16(data[1:]))), 3, nil
+	case b0 == 29:
+		if len(data) < 5 {
+			return 0, 0, errShort
+		}
+		return int(int32(binary.BigEndian.Uint32(data[1:]))), 5, nil
+	case b0 >= 32 && b0 <= 246:
+		return b0 - 139, 1, nil
+	case b0 >= 247 && b0 <= 250:
+		if len(data) < 2 {
+			return 0, 0, errShort
+		}
+		return (b0-247)*256 + int(data[1]) + 108, 2, nil
+	case b0 >= 251 && b0 <= 254:
+		if len(data) < 2 {
+			return 0, 0, errShort
+		}
+		return -(b0-251)*256 - int(data[1]) - 108, 2, nil
+	}
+	return 0, 0, fmt.Errorf("invalid CFF DICT operand %d", b0)
+}
+
+// cffReal returns the real number operand encoded in the nibbles of `data` and its size.
+func cffReal(data []byte) (float64, int, error) {
+	var s []byte
+	for i, b := range data {
+		for _, nibble := range []byte{b >> 4, b & 0xf} {
+			switch {
+			case nibble <= 9:
+				s = append(s, '0'+nibble)
+			case nibble == 0xa:
+				s = append(s, '.')
+			case nibble == 0xb:
+				s = append(s, 'E')
+			case nibble == 0xc:
+				s = append(s, 'E', '-')
+			case nibble == 0xe:
+				s = append(s, '-')
+			case nibble == 0xf:
+				v, err := strconv.ParseFloat(string(s), 64)
+				if err != nil {
+					return 0, 0, err
+				}
+				return v, i + 1, nil
+			}
+		}
+	}
+	return 0, 0, errors.New("unterminated CFF real number")
+}
+
+// errEndChar is returned when the endchar operator terminates a charstring.
+var errEndChar = errors.New("endchar")
+
+// Limits of the Type 2 charstring interpreter.
+const (
+	type2MaxStack     = 513
+	type2MaxRecursion = 10
+)
+
+// type2Interpreter interprets Type 2 charstrings to glyph outlines.
+type type2Interpreter struct {
+	font        *CFFFont
+	path        *GlyphPath
+	globalSubrs [][]byte
+	localSubrs  [][]byte
+
+	stack     []float64
+	transient [32]float64
+	numStems  int
+	seenWidth bool
+	x, y      float64
+}
+
+// subrBias returns the bias of the subroutine numbers for `n` subroutines.
+func subrBias(n int) int {
+	switch {
+	case n < 1240:
+		return 107
+	case n < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// clearWidth removes the optional width argument from the bottom of the stack of the first
+// stack-clearing operator, which has an extra argument if the number of arguments is not
+// `expected` modulo 2 (or not `expected` when `exact` is set).
+func (t *type2Interpreter) clearWidth(expected int, exact bool) {
+	if t.seenWidth {
+		return
+	}
+	t.seenWidth = true
+	n := len(t.stack)
+	if (exact && n > expected) || (!exact && n%2 != expected%2) {
+		t.stack = t.stack[1:]
+	}
+}
+
+func (t *type2Interpreter) moveTo(dx, dy float64) {
+	t.x += dx
+	t.y += dy
+	t.path.MoveTo(t.x, t.y)
+}
+
+func (t *type2Interpreter) lineTo(dx, dy float64) {
+	t.x += dx
+	t.y += dy
+	t.path.LineTo(t.x, t.y)
+}
+
+func (t *type2Interpreter) curveTo(dxa, dya, dxb, dyb, dxc, dyc float64) {
+	x1, y1 := t.x+dxa, t.y+dya
+	x2, y2 := x1+dxb, y1+dyb
+	t.x, t.y = x2+dxc, y2+dyc
+	t.path.CubeTo(x1, y1, x2, y2, t.x, t.y)
+}
+
+// run interprets charstring `code` at subroutine nesting level `depth`.
+func (t *type2Interpreter) run(code []byte, depth int) error {
+	if depth > type2MaxRecursion {
+		return errors.New("charstring subroutines nested too deep")
+	}
+	for p := 0; p < len(code); {
+		b0 := code[p]
+		p++
+
+		// Operands.
+		if b0 >= 32 || b0 == 28 {
+			var v float64
+			switch {
+			case b0 == 28:
+				if p+2 > len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64(int16(binary.BigEndian.Uint16(code[p:])))
+				p += 2
+			case b0 <= 246:
+				v = float64(int(b0) - 139)
+			case b0 <= 250:
+				if p >= len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64((int(b0)-247)*256 + int(code[p]) + 108)
+				p++
+			case b0 <= 254:
+				if p >= len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64(-(int(b0)-251)*256 - int(code[p]) - 108)
+				p++
+			default:
+				if p+4 > len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64(int32(binary.BigEndian.Uint32(code[p:]))) / 65536
+				p += 4
+			}
+			if len(t.stack) >= type2MaxStack {
+				return errors.New("charstring stack overflow")
+			}
+			t.stack = append(t.stack, v)
+			continue
+		}
+
+		s := t.stack
+		switch b0 {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			t.clearWidth(0, false)
+			t.numStems += len(t.stack) / 2
+		case 19, 20: // hintmask, cntrmask
+			// Implicit vstem arguments.
+			t.clearWidth(0, false)
+			t.numStems += len(t.stack) / 2
+			p += (t.numStems + 7) / 8
+		case 21: // rmoveto
+			t.clearWidth(2, true)
+			s = t.stack
+			if len(s) >= 2 {
+				t.moveTo(s[0], s[1])
+			}
+		case 22: // hmoveto
+			t.clearWidth(1, true)
+			s = t.stack
+			if len(s) >= 1 {
+				t.moveTo(s[0], 0)
+			}
+		case 4: // vmoveto
+			t.clearWidth(1, true)
+			s = t.stack
+			if len(s) >= 1 {
+				t.moveTo(0, s[0])
+			}
+		case 5: // rlineto
+			for i := 0; i+1 < len(s); i += 2 {
+				t.lineTo(s[i], s[i+1])
+			}
+		case 6, 7: // hlineto, vlineto
+			horizontal := b0 == 6
+			for _, d := range s {
+				if horizontal {
+					t.lineTo(d, 0)
+				} else {
+					t.lineTo(0, d)
+				}
+				horizontal = !horizontal
+			}
+		case 8: // rrcurveto
+			for i := 0; i+5 < len(s); i += 6 {
+				t.curveTo(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+		case 24: // rcurveline
+			i := 0
+			for ; i+5 < len(s)-2; i += 6 {
+				t.curveTo(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+			if i+1 < len(s) {
+				t.lineTo(s[i], s[i+1])
+			}
+		case 25: // rlinecurve
+			i := 0
+			for ; i+1 < len(s)-6; i += 2 {
+				t.lineTo(s[i], s[i+1])
+			}
+			if i+5 < len(s) {
+				t.curveTo(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+		case 26: // vvcurveto
+			i := 0
+			dx1 := 0.0
+			if len(s)%2 == 1 {
+				dx1 = s[0]
+				i = 1
+			}
+			for ; i+3 < len(s); i += 4 {
+				t.curveTo(dx1, s[i], s[i+1], s[i+2], 0, s[i+3])
+				dx1 = 0
+			}
+		case 27: // hhcurveto
+			i := 0
+			dy1 := 0.0
+			if len(s)%2 == 1 {
+				dy1 = s[0]
+				i = 1
+			}
+			for ; i+3 < len(s); i += 4 {
+				t.curveTo(s[i], dy1, s[i+1], s[i+2], s[i+3], 0)
+				dy1 = 0
+			}
+		case 30, 31: // vhcurveto, hvcurveto
+			horizontal := b0 == 31
+			for i := 0; i+3 < len(s); i += 4 {
+				last := 0.0
+				if len(s)-i == 5 {
+					last = s[i+4]
+				}
+				if horizontal {
+					t.curveTo(s[i], 0, s[i+1], s[i+2], last, s[i+3])
+				} else {
+					t.curveTo(0, s[i], s[i+1], s[i+2], s[i+3], last)
+				}
+				horizontal = !horizontal
+			}
+		case 10, 29: // callsubr, callgsubr
+			if len(s) == 0 {
+				return errors.New("charstring stack underflow")
+			}
+			subrs := t.localSubrs
+			if b0 == 29 {
+				subrs = t.globalSubrs
+			}
+			i := int(s[len(s)-1]) + subrBias(len(subrs))
+			t.stack = s[:len(s)-1]
+			if i < 0 || i >= len(subrs) {
+				return fmt.Errorf("invalid subroutine %d", i)
+			}
+			if err := t.run(subrs[i], depth+1); err != nil {
+				return err
+			}
+			continue
+		case 11: // return
+			return nil
+		case 14: // endchar
+			t.clearWidth(0, false)
+			s = t.stack
+			if len(s) >= 4 {
+				// Accented character (seac).
+				return t.seac(s[len(s)-4], s[len(s)-3], byte(s[len(s)-2]), byte(s[len(s)-1]))
+			}
+			return errEndChar
+		case 12:
+			if p >= len(code) {
+				return errors.New("truncated charstring")
+			}
+			b1 := code[p]
+			p++
+			if err := t.escape(b1); err != nil {
+				return err
+			}
+			continue
+		default:
+			// Reserved operators are ignored.
+		}
+		t.stack = t.stack[:0]
+	}
+	return nil
+}
+
+// escape interprets the escaped operator 12 `op`.
+func (t *type2Interpreter) escape(op byte) error {
+	s := t.stack
+	pop := func(n int) ([]float64, error) {
+		if len(t.stack) < n {
+			return nil, errors.New("charstring stack underflow")
+		}
+		args := append([]float64(nil), t.stack[len(t.stack)-n:]...)
+		t.stack = t.stack[:len(t.stack)-n]
+		return args, nil
+	}
+	push := func(v float64) {
+		t.stack = append(t.stack, v)
+	}
+	boolVal := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	switch op {
+	case 34: // hflex
+		if len(s) >= 7 {
+			y := t.y
+			t.curveTo(s[0], 0, s[1], s[2], s[3], 0)
+			t.curveTo(s[4], 0, s[5], y-t.y, s[6], 0)
+		}
+	case 35: // flex
+		if len(s) >= 12 {
+			t.curveTo(s[0], s[1], s[2], s[3], s[4], s[5])
+			t.curveTo(s[6], s[7], s[8], s[9], s[10], s[11])
+		}
+	case 36: // hflex1
+		if len(s) >= 9 {
+			y := t.y
+			t.curveTo(s[0], s[1], s[2], s[3], s[4], 0)
+			t.curveTo(s[5], 0, s[6], s[7], s[8], y-t.y-s[7])
+		}
+	case 37: // flex1
+		if len(s) >= 11 {
+			dx := s[0] + s[2] + s[4] + s[6] + s[8]
+			dy := s[1] + s[3] + s[5] + s[7] + s[9]
+			x, y := t.x, t.y
+			t.curveTo(s[0], s[1], s[2], s[3], s[4], s[5])
+			t.curveTo(s[6], s[7], s[8], s[9], 0, 0)
+			if math.Abs(dx) > math.Abs(dy) {
+				t.x, t.y = x+dx+s[10], y
+			} else {
+				t.x, t.y = x, y+dy+s[10]
+			}
+			seg := &t.path.Segments[len(t.path.Segments)-1]
+			seg.Points[2] = GlyphPoint{t.x, t.y}
+			t.path.cur = seg.Points[2]
+		}
+	case 0: // dotsection (deprecated)
+	case 3, 4, 5, 9, 10, 11, 12, 14, 15, 18, 20, 21, 22, 23, 24, 26, 27, 28, 29, 30:
+		return t.arithmetic(op, pop, push, boolVal)
+	}
+	t.stack = t.stack[:0]
+	return nil
+}
+
+// arithmetic interprets the arithmetic and storage operator 12 `op`.
+func (t *type2Interpreter) arithmetic(op byte, pop func(int) ([]float64, error), push func(float64),
+	boolVal func(bool) float64) error {
+	nargs := map[byte]int{3: 2, 4: 2, 5: 1, 9: 1, 10: 2, 11: 2, 12: 2, 14: 1, 15: 2, 18: 1,
+		20: 2, 21: 1, 22: 4, 23: 0, 24: 2, 26: 1, 27: 1, 28: 2, 29: 1, 30: 2}[op]
+	a, err := pop(nargs)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case 3: // and
+		push(boolVal(a[0] != 0 && a[1] != 0))
+	case 4: // or
+		push(boolVal(a[0] != 0 || a[1] != 0))
+	case 5: // not
+		push(boolVal(a[0] == 0))
+	case 9: // abs
+		push(math.Abs(a[0]))
+	case 10: // add
+		push(a[0] + a[1])
+	case 11: // sub
+		push(a[0] - a[1])
+	case 12: // div
+		if a[1] == 0 {
+			return errors.New("charstring division by zero")
+		}
+		push(a[0] / a[1])
+	case 14: // neg
+		push(-a[0])
+	case 15: // eq
+		push(boolVal(a[0] == a[1]))
+	case 18: // drop
+	case 20: // put
+		if i := int(a[1]); i >= 0 && i < len(t.transient) {
+			t.transient[i] = a[0]
+		}
+	case 21: // get
+		v := 0.0
+		if i := int(a[0]); i >= 0 && i < len(t.transient) {
+			v = t.transient[i]
+		}
+		push(v)
+	case 22: // ifelse
+		if a[2] <= a[3] {
+			push(a[0])
+		} else {
+			push(a[1])
+		}
+	case 23: // random
+		push(0.5)
+	case 24: // mul
+		push(a[0] * a[1])
+	case 26: // sqrt
+		push(math.Sqrt(math.Abs(a[0])))
+	case 27: // dup
+		push(a[0])
+		push(a[0])
+	case 28: // exch
+		push(a[1])
+		push(a[0])
+	case 29: // index
+		i := int(a[0])
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(t.stack) {
+			return errors.New("charstring stack underflow")
+		}
+		push(t.stack[len(t.stack)-1-i])
+	case 30: // roll
+		n, j := int(a[0]), int(a[1])
+		if n <= 0 || n > len(t.stack) {
+			return errors.New("charstring stack underflow")
+		}
+		elems := t.stack[len(t.stack)-n:]
+		rolled := make([]float64, n)
+		for i := range elems {
+			rolled[((i+j)%n+n)%n] = elems[i]
+		}
+		copy(elems, rolled)
+	}
+	return nil
+}
+
+// seac draws the accented character made of base character `bchar` and accent character
+// `achar` (standard encoding codes) with the accent offset by (`adx`,`ady`).
+func (t *type2Interpreter) seac(adx, ady float64, bchar, achar byte) error {
+	base, ok1 := t.font.standardEncodingGlyph(bchar)
+	accent, ok2 := t.font.standardEncodingGlyph(achar)
+	if !ok1 || !ok2 {
+		return errors.New("seac glyphs not found")
+	}
+	basePath, err := t.font.GlyphOutline(base)
+	if err != nil {
+		return err
+	}
+	accentPath, err := t.font.GlyphOutline(accent)
+	if err != nil {
+		return err
+	}
+	accentPath.Transform(1, 0, 0, 1, adx, ady)
+	t.path.Append(basePath)
+	t.path.Append(accentPath)
+	return errEndChar
+}
+
+// cffStandardStrings are the standard strings of CFF fonts, indexed by SID.
+var cffStandardStrings = []string{
+	".notdef", "space", "exclam", "quotedbl", "numbersign", "dollar", "percent", "ampersand",
+	"quoteright", "parenleft", "parenright", "asterisk", "plus", "comma", "hyphen", "period",
+	"slash", "zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"colon", "semicolon", "less", "equal", "greater", "question", "at", "A", "B", "C", "D", "E",
+	"F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X",
+	"Y", "Z", "bracketleft", "backslash", "bracketright", "asciicircum", "underscore",
+	"quoteleft", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p",
+	"q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "braceleft", "bar", "braceright",
+	"asciitilde", "exclamdown", "cent", "sterling", "fraction", "yen", "florin", "section",
+	"currency", "quotesingle", "quotedblleft", "guillemotleft", "guilsinglleft", "guilsinglright",
+	"fi", "fl", "endash", "dagger", "daggerdbl", "periodcentered", "paragraph", "bullet",
+	"quotesinglbase", "quotedblbase", "quotedblright", "guillemotright", "ellipsis",
+	"perthousand", "questiondown", "grave", "acute", "circumflex", "tilde", "macron", "breve",
+	"dotaccent", "dieresis", "ring", "cedilla", "hungarumlaut", "ogonek", "caron", "emdash", "AE",
+	"ordfeminine", "Lslash", "Oslash", "OE", "ordmasculine", "ae", "dotlessi", "lslash", "oslash",
+	"oe", "germandbls", "onesuperior", "logicalnot", "mu", "trademark", "Eth", "onehalf",
+	"plusminus", "Thorn", "onequarter", "divide", "brokenbar", "degree", "thorn", "threequarters",
+	"twosuperior", "registered", "minus", "eth", "multiply", "threesuperior", "copyright",
+	"Aacute", "Acircumflex", "Adieresis", "Agrave", "Aring", "Atilde", "Ccedilla", "Eacute",
+	"Ecircumflex", "Edieresis", "Egrave", "Iacute", "Icircumflex", "Idieresis", "Igrave",
+	"Ntilde", "Oacute", "Ocircumflex", "Odieresis", "Ograve", "Otilde", "Scaron", "Uacute",
+	"Ucircumflex", "Udieresis", "Ugrave", "Yacute", "Ydieresis", "Zcaron", "aacute",
+	"acircumflex", "adieresis", "agrave", "aring", "atilde", "ccedilla", "eacute", "ecircumflex",
+	"edieresis", "egrave", "iacute", "icircumflex", "idieresis", "igrave", "ntilde", "oacute",
+	"ocircumflex", "odieresis", "ograve", "otilde", "scaron", "uacute", "ucircumflex",
+	"udieresis", "ugrave", "yacute", "ydieresis", "zcaron", "exclamsmall", "Hungarumlautsmall",
+	"dollaroldstyle", "dollarsuperior", "ampersandsmall", "Acutesmall", "parenleftsuperior",
+	"parenrightsuperior", "twodotenleader", "onedotenleader", "zerooldstyle", "oneoldstyle",
+	"twooldstyle", "threeoldstyle", "fouroldstyle", "fiveoldstyle", "sixoldstyle",
+	"sevenoldstyle", "eightoldstyle", "nineoldstyle", "commasuperior", "threequartersemdash",
+	"periodsuperior", "questionsmall", "asuperior", "bsuperior", "centsuperior", "dsuperior",
+	"esuperior", "isuperior", "lsuperior", "msuperior", "nsuperior", "osuperior", "rsuperior",
+	"ssuperior", "tsuperior", "ff", "ffi", "ffl", "parenleftinferior", "parenrightinferior",
+	"Circumflexsmall", "hyphensuperior", "Gravesmall", "Asmall", "Bsmall", "Csmall", "Dsmall",
+	"Esmall", "Fsmall", "Gsmall", "Hsmall", "Ismall", "Jsmall", "Ksmall", "Lsmall", "Msmall",
+	"Nsmall", "Osmall", "Psmall", "Qsmall", "Rsmall", "Ssmall", "Tsmall", "Usmall", "Vsmall",
+	"Wsmall", "Xsmall", "Ysmall", "Zsmall", "colonmonetary", "onefitted", "rupiah", "Tildesmall",
+	"exclamdownsmall", "centoldstyle", "Lslashsmall", "Scaronsmall", "Zcaronsmall",
+	"Dieresissmall", "Brevesmall", "Caronsmall", "Dotaccentsmall", "Macronsmall", "figuredash",
+	"hypheninferior", "Ogoneksmall", "Ringsmall", "Cedillasmall", "questiondownsmall",
+	"oneeighth", "threeeighths", "fiveeighths", "seveneighths", "onethird", "twothirds",
+	"zerosuperior", "foursuperior", "fivesuperior", "sixsuperior", "sevensuperior",
+	"eightsuperior", "ninesuperior", "zeroinferior", "oneinferior", "twoinferior",
+	"threeinferior", "fourinferior", "fiveinferior", "sixinferior", "seveninferior",
+	"eightinferior", "nineinferior", "centinferior", "dollarinferior", "periodinferior",
+	"commainferior", "Agravesmall", "Aacutesmall", "Acircumflexsmall", "Atildesmall",
+	"Adieresissmall", "Aringsmall", "AEsmall", "Ccedillasmall", "Egravesmall", "Eacutesmall",
+	"Ecircumflexsmall", "Edieresissmall", "Igravesmall", "Iacutesmall", "Icircumflexsmall",
+	"Idieresissmall", "Ethsmall", "Ntildesmall", "Ogravesmall", "Oacutesmall", "Ocircumflexsmall",
+	"Otildesmall", "Odieresissmall", "OEsmall", "Oslashsmall", "Ugravesmall", "Uacutesmall",
+	"Ucircumflexsmall", "Udieresissmall", "Yacutesmall", "Thornsmall", "Ydieresissmall",
+	"001.000", "001.001", "001.002", "001.003", "Black", "Bold", "Book", "Light", "Medium",
+	"Regular", "Roman", "Semibold",
+}
@@ -0,0 +1,169 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"fmt"
+	"math"
+)
+
+// GlyphSegmentType is the type of a segment of a glyph outline.
+type GlyphSegmentType int
+
+// Glyph outline segment types.
+const (
+	// GlyphMoveTo starts a new contour at Points[0].
+	GlyphMoveTo GlyphSegmentType = iota
+	// GlyphLineTo adds a line to Points[0].
+	GlyphLineTo
+	// GlyphQuadTo adds a quadratic Bézier curve with control point Points[0] to Points[1].
+	GlyphQuadTo
+	// GlyphCubeTo adds a cubic Bézier curve with control points Points[0] and Points[1] to
+	// Points[2].
+	GlyphCubeTo
+	// GlyphClose closes the current contour.
+	GlyphClose
+)
+
+// GlyphPoint is a point of a glyph outline.
+type GlyphPoint struct {
+	X, Y float64
+}
+
+// GlyphSegment is a segment of a glyph outline. The number of points used depends on the
+// segment type.
+type GlyphSegment struct {
+	Type   GlyphSegmentType
+	Points [3]GlyphPoint
+}
+
+// GlyphPath is a glyph outline, built with the path construction methods.
+type GlyphPath struct {
+	Segments []GlyphSegment
+
+	// Current point and start point of the current contour.
+	cur, start GlyphPoint
+	open       bool
+}
+
+// String returns a string describing `path`.
+func (path *GlyphPath) String() string {
+	return fmt.Sprintf("GLYPH_PATH{segments=%d}", len(path.Segments))
+}
+
+// MoveTo starts a new contour at (`x`,`y`), closing the current one.
+func (path *GlyphPath) MoveTo(x, y float64) {
+	path.Close()
+	p := GlyphPoint{x, y}
+	path.Segments = append(path.Segments, GlyphSegment{Type: GlyphMoveTo, Points: [3]GlyphPoint{p}})
+	path.cur, path.start, path.open = p, p, true
+}
+
+// LineTo adds a line to (`x`,`y`).
+func (path *GlyphPath) LineTo(x, y float64) {
+	path.ensureOpen()
+	p := GlyphPoint{x, y}
+	path.Segments = append(path.Segments, GlyphSegment{Type: GlyphLineTo, Points: [3]GlyphPoint{p}})
+	path.cur = p
+}
+
+// QuadTo adds a quadratic Bézier curve with control point (`cx`,`cy`) to (`x`,`y`).
+func (path *GlyphPath) QuadTo(cx, cy, x, y float64) {
+	path.ensureOpen()
+	p := GlyphPoint{x, y}
+	path.Segments = append(path.Segments, GlyphSegment{Type: GlyphQuadTo,
+		Points: [3]GlyphPoint{{cx, cy}, p}})
+	path.cur = p
+}
+
+// CubeTo adds a cubic Bézier curve with control points (`c1x`,`c1y`) and (`c2x`,`c2y`) to
+// (`x`,`y`).
+func (path *GlyphPath) CubeTo(c1x, c1y, c2x, c2y, x, y float64) {
+	path.ensureOpen()
+	p := GlyphPoint{x, y}
+	path.Segments = append(path.Segments, GlyphSegment{Type: GlyphCubeTo,
+		Points: [3]GlyphPoint{{c1x, c1y}, {c2x, c2y}, p}})
+	path.cur = p
+}
+
+// Close closes the current contour, if any.
+func (path *GlyphPath) Close() {
+	if !path.open {
+		return
+	}
+	path.Segments = append(path.Segments, GlyphSegment{Type: GlyphClose})
+	path.cur, path.open = path.start, false
+}
+
+// CurrentPoint returns the current point of `path`.
+func (path *GlyphPath) CurrentPoint() (float64, float64) {
+	return path.cur.X, path.cur.Y
+}
+
+// ensureOpen starts a contour at the current point if there is no open contour, as for
+// drawing operators following a closed contour without a move.
+func (path *GlyphPath) ensureOpen() {
+	if !path.open {
+		path.MoveTo(path.cur.X, path.cur.Y)
+	}
+}
+
+// Transform transforms the points of `path` by the affine transform [`a` `b` `c` `d` `e` `f`].
+func (path *GlyphPath) Transform(a, b, c, d, e, f float64) {
+	tr := func(p GlyphPoint) GlyphPoint {
+		return GlyphPoint{a*p.X + c*p.Y + e, b*p.X + d*p.Y + f}
+	}
+	for i := range path.Segments {
+		for j := range path.Segments[i].Points {
+			path.Segments[i].Points[j] = tr(path.Segments[i].Points[j])
+		}
+	}
+	path.cur, path.start = tr(path.cur), tr(path.start)
+}
+
+// Append appends the contours of `other` to `path`.
+func (path *GlyphPath) Append(other *GlyphPath) {
+	path.Close()
+	for _, seg := range other.Segments {
+		switch seg.Type {
+		case GlyphMoveTo:
+			path.MoveTo(seg.Points[0].X, seg.Points[0].Y)
+		case GlyphLineTo:
+			path.LineTo(seg.Points[0].X, seg.Points[0].Y)
+		case GlyphQuadTo:
+			path.QuadTo(seg.Points[0].X, seg.Points[0].Y, seg.Points[1].X, seg.Points[1].Y)
+		case GlyphCubeTo:
+			path.CubeTo(seg.Points[0].X, seg.Points[0].Y, seg.Points[1].X, seg.Points[1].Y,
+				seg.Points[2].X, seg.Points[2].Y)
+		case GlyphClose:
+			path.Close()
+		}
+	}
+	path.Close()
+}
+
+// Bounds returns the bounding box of the points of `path` (including the control points).
+// Returns false if `path` is empty.
+func (path *GlyphPath) Bounds() (llx, lly, urx, ury float64, ok bool) {
+	llx, lly = math.Inf(1), math.Inf(1)
+	urx, ury = math.Inf(-1), math.Inf(-1)
+	for _, seg := range path.Segments {
+		n := 1
+		switch seg.Type {
+		case GlyphClose:
+			n = 0
+		case GlyphQuadTo:
+			n = 2
+		case GlyphCubeTo:
+			n = 3
+		}
+		for _, p := range seg.Points[:n] {
+			llx, lly = math.Min(llx, p.X), math.Min(lly, p.Y)
+			urx, ury = math.Max(urx, p.X), math.Max(ury, p.Y)
+		}
+	}
+	return llx, lly, urx, ury, llx <= urx
+}
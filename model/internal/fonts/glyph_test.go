@@ -0,0 +1,90 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/internal/testutils"
+)
+
+// numContours returns the number of contours of `path`.
+func numContours(path *GlyphPath) int {
+	n := 0
+	for _, seg := range path.Segments {
+		if seg.Type == GlyphMoveTo {
+			n++
+		}
+	}
+	return n
+}
+
+func TestTrueTypeGlyphOutline(t *testing.T) {
+	// The glyph outlines are only loaded on request.
+	ttf, err := TtfParseFile(filepath.Join(fontDir, "FreeSans.ttf"))
+	require.NoError(t, err)
+	require.False(t, ttf.HasGlyphOutlines())
+
+	f, err := os.Open(filepath.Join(fontDir, "FreeSans.ttf"))
+	require.NoError(t, err)
+	defer f.Close()
+	ttf, err = TtfParseGlyphs(f)
+	require.NoError(t, err)
+	require.True(t, ttf.HasGlyphOutlines())
+
+	// Simple glyph.
+	path, err := ttf.GlyphOutline(ttf.Chars['o'])
+	require.NoError(t, err)
+	require.Equal(t, 2, numContours(path))
+	testutils.RequireBounds(t, path, [4]float64{36, -23, 510, 539}, 0)
+
+	// Composite glyph: e with dieresis.
+	path, err = ttf.GlyphOutline(ttf.Chars['ё'])
+	require.NoError(t, err)
+	require.Equal(t, 4, numContours(path))
+	testutils.RequireBounds(t, path, [4]float64{40, -23, 513, 716}, 0)
+
+	_, err = ttf.GlyphOutline(GID(ttf.NumGlyphs()))
+	require.Error(t, err)
+}
+
+func TestType2CharString(t *testing.T) {
+	// 20 (width) 100 100 rmoveto 50 hlineto 50 vlineto -50 hlineto endchar
+	code := []byte{159, 239, 239, 21, 189, 6, 189, 7, 89, 6, 14}
+	interp := type2Interpreter{font: &CFFFont{}, path: &GlyphPath{}}
+	require.Equal(t, errEndChar, interp.run(code, 0))
+	require.Equal(t, 1, numContours(interp.path))
+	testutils.RequireBounds(t, interp.path, [4]float64{100, 100, 150, 150}, 0)
+
+	// Local subroutine 0 (biased number -107) drawing the square.
+	interp = type2Interpreter{font: &CFFFont{}, path: &GlyphPath{},
+		localSubrs: [][]byte{{189, 6, 189, 7, 89, 6, 11}}}
+	require.Equal(t, errEndChar, interp.run([]byte{239, 239, 21, 32, 10, 14}, 0))
+	testutils.RequireBounds(t, interp.path, [4]float64{100, 100, 150, 150}, 0)
+}
+
+func TestType1CharString(t *testing.T) {
+	// 0 500 hsbw 100 100 rmoveto 50 0 rlineto 0 50 rlineto closepath endchar
+	code := []byte{139, 248, 136, 13, 239, 239, 21, 189, 139, 5, 139, 189, 5, 9, 14}
+	interp := type1Interpreter{font: &Type1Font{}, path: &GlyphPath{}}
+	require.Equal(t, errEndChar, interp.run(code, 0))
+	require.Equal(t, 1, numContours(interp.path))
+	testutils.RequireBounds(t, interp.path, [4]float64{100, 100, 150, 150}, 0)
+
+	// Encryption round trip.
+	encrypted := make([]byte, 4+len(code))
+	r := uint16(type1CharStringKey)
+	for i, p := range append([]byte{0, 0, 0, 0}, code...) {
+		c := p ^ byte(r>>8)
+		encrypted[i] = c
+		r = (uint16(c)+r)*52845 + 22719
+	}
+	require.Equal(t, code, type1Decrypt(encrypted, type1CharStringKey, 4))
+}
@@ -0,0 +1,385 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Flags of the points of simple glyphs in the glyf table.
+const (
+	glyfOnCurve = 1 << iota
+	glyfXShort
+	glyfYShort
+	glyfRepeat
+	glyfXSameOrPositive
+	glyfYSameOrPositive
+)
+
+// Flags of the components of composite glyphs in the glyf table.
+const (
+	glyfArgsAreWords    = 0x0001
+	glyfArgsAreXYValues = 0x0002
+	glyfHaveScale       = 0x0008
+	glyfMoreComponents  = 0x0020
+	glyfHaveXYScale     = 0x0040
+	glyfHaveTwoByTwo    = 0x0080
+)
+
+// maxCompositeRecursion is the maximum nesting depth of composite glyphs.
+const maxCompositeRecursion = 8
+
+// ParseGlyf loads the glyph outlines data from the "loca" and "glyf" tables.
+func (t *ttfParser) ParseGlyf() error {
+	// indexToLocFormat is at offset 50 in the head table.
+	if err := t.Seek("head"); err != nil {
+		return err
+	}
+	t.Skip(50)
+	longOffsets := t.ReadShort() != 0
+
+	if err := t.Seek("loca"); err != nil {
+		return err
+	}
+	loca := make([]uint32, int(t.numGlyphs)+1)
+	for i := range loca {
+		if longOffsets {
+			loca[i] = t.ReadULong()
+		} else {
+			loca[i] = 2 * uint32(t.ReadUShort())
+		}
+	}
+
+	if err := t.Seek("glyf"); err != nil {
+		return err
+	}
+	length := t.lengths["glyf"]
+	if end := loca[len(loca)-1]; length < end {
+		length = end
+	}
+	glyf := make([]byte, length)
+	n, err := io.ReadFull(t.f, glyf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	t.rec.glyf = glyf[:n]
+	t.rec.loca = loca
+	return nil
+}
+
+// HasGlyphOutlines returns true if `ttf` has TrueType glyph outlines.
+func (ttf *TtfType) HasGlyphOutlines() bool {
+	return len(ttf.loca) > 0
+}
+
+// NumGlyphs returns the number of glyphs in `ttf`.
+func (ttf *TtfType) NumGlyphs() int {
+	if len(ttf.loca) > 0 {
+		return len(ttf.loca) - 1
+	}
+	return len(ttf.Widths)
+}
+
+// GlyphOutline returns the outline of glyph `gid` in font units.
+func (ttf *TtfType) GlyphOutline(gid GID) (*GlyphPath, error) {
+	if !ttf.HasGlyphOutlines() {
+		return nil, errors.New("no glyph outlines")
+	}
+	path := &GlyphPath{}
+	if err := ttf.appendGlyph(path, gid, [6]float64{1, 0, 0, 1, 0, 0}, 0); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// glyphData returns the glyf table data of glyph `gid`.
+func (ttf *TtfType) glyphData(gid GID) ([]byte, error) {
+	if int(gid)+1 >= len(ttf.loca) {
+		return nil, fmt.Errorf("glyph %d out of range", gid)
+	}
+	start, end := ttf.loca[gid], ttf.loca[gid+1]
+	if start > end || end > uint32(len(ttf.glyf)) {
+		return nil, fmt.Errorf("invalid location of glyph %d", gid)
+	}
+	return ttf.glyf[start:end], nil
+}
+
+// appendGlyph appends the contours of glyph `gid` transformed by `m` to `path`.
+func (ttf *TtfType) appendGlyph(path *GlyphPath, gid GID, m [6]float64, depth int) error {
+	data, err := ttf.glyphData(gid)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		// Empty glyph, e.g. space.
+		return nil
+	}
+	if len(data) < 10 {
+		return fmt.Errorf("glyph %d too short", gid)
+	}
+	numContours := int16(binary.BigEndian.Uint16(data))
+	if numContours >= 0 {
+		return appendSimpleGlyph(path, data[10:], int(numContours), m)
+	}
+	if depth >= maxCompositeRecursion {
+		return errors.New("composite glyph nesting too deep")
+	}
+	return ttf.appendCompositeGlyph(path, data[10:], m, depth)
+}
+
+// appendSimpleGlyph appends the contours of the simple glyph description `data` (following the
+// header) with `numContours` contours transformed by `m` to `path`.
+func appendSimpleGlyph(path *GlyphPath, data []byte, numContours int, m [6]float64) error {
+	errShort := errors.New("simple glyph too short")
+	if len(data) < 2*numContours+2 {
+		return errShort
+	}
+	endPts := make([]int, numContours)
+	numPoints := 0
+	for i := range endPts {
+		endPts[i] = int(binary.BigEndian.Uint16(data[2*i:]))
+		if endPts[i] < numPoints-1 {
+			return errors.New("invalid contour end points")
+		}
+		numPoints = endPts[i] + 1
+	}
+	data = data[2*numContours:]
+	instructionLength := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < instructionLength {
+		return errShort
+	}
+	data = data[instructionLength:]
+
+	// Flags.
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if len(data) == 0 {
+			return errShort
+		}
+		flag := data[0]
+		data = data[1:]
+		flags = append(flags, flag)
+		if flag&glyfRepeat != 0 {
+			if len(data) == 0 {
+				return errShort
+			}
+			for n := int(data[0]); n > 0 && len(flags) < numPoints; n-- {
+				flags = append(flags, flag)
+			}
+			data = data[1:]
+		}
+	}
+
+	// Coordinates.
+	readCoords := func(short, same byte) ([]float64, error) {
+		coords := make([]float64, numPoints)
+		v := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if len(data) < 1 {
+					return nil, errShort
+				}
+				d := int(data[0])
+				data = data[1:]
+				if flag&same == 0 {
+					d = -d
+				}
+				v += d
+			case flag&same == 0:
+				if len(data) < 2 {
+					return nil, errShort
+				}
+				v += int(int16(binary.BigEndian.Uint16(data)))
+				data = data[2:]
+			}
+			coords[i] = float64(v)
+		}
+		return coords, nil
+	}
+	xs, err := readCoords(glyfXShort, glyfXSameOrPositive)
+	if err != nil {
+		return err
+	}
+	ys, err := readCoords(glyfYShort, glyfYSameOrPositive)
+	if err != nil {
+		return err
+	}
+
+	type point struct {
+		x, y    float64
+		onCurve bool
+	}
+	start := 0
+	for _, end := range endPts {
+		var pts []point
+		for i := start; i <= end; i++ {
+			x, y := transformPoint(m, xs[i], ys[i])
+			pts = append(pts, point{x, y, flags[i]&glyfOnCurve != 0})
+		}
+		start = end + 1
+		if len(pts) == 0 {
+			continue
+		}
+
+		// Find an on-curve starting point. If there is none, start at the midpoint of the first
+		// two off-curve points.
+		first := -1
+		for i, p := range pts {
+			if p.onCurve {
+				first = i
+				break
+			}
+		}
+		var startPt point
+		if first < 0 {
+			startPt = point{(pts[0].x + pts[1%len(pts)].x) / 2, (pts[0].y + pts[1%len(pts)].y) / 2, true}
+			first = 0
+		} else {
+			startPt = pts[first]
+		}
+		// Rotate the points to end with the starting point.
+		rotated := make([]point, 0, len(pts))
+		rotated = append(rotated, pts[first+1:]...)
+		pts = append(rotated, pts[:first+1]...)
+
+		path.MoveTo(startPt.x, startPt.y)
+		var ctrl *point
+		for i := range pts {
+			p := pts[i]
+			if !p.onCurve {
+				if ctrl != nil {
+					// Implied on-curve point between two off-curve points.
+					mx, my := (ctrl.x+p.x)/2, (ctrl.y+p.y)/2
+					path.QuadTo(ctrl.x, ctrl.y, mx, my)
+				}
+				ctrl = &pts[i]
+				continue
+			}
+			if ctrl != nil {
+				path.QuadTo(ctrl.x, ctrl.y, p.x, p.y)
+				ctrl = nil
+			} else {
+				path.LineTo(p.x, p.y)
+			}
+		}
+		if ctrl != nil {
+			path.QuadTo(ctrl.x, ctrl.y, startPt.x, startPt.y)
+		}
+		path.Close()
+	}
+	return nil
+}
+
+// appendCompositeGlyph appends the components of the composite glyph description `data`
+// (following the header) transformed by `m` to `path`.
+func (ttf *TtfType) appendCompositeGlyph(path *GlyphPath, data []byte, m [6]float64, depth int) error {
+	errShort := errors.New("composite glyph too short")
+	for {
+		if len(data) < 4 {
+			return errShort
+		}
+		flags := binary.BigEndian.Uint16(data)
+		gid := GID(binary.BigEndian.Uint16(data[2:]))
+		data = data[4:]
+
+		var dx, dy float64
+		if flags&glyfArgsAreWords != 0 {
+			if len(data) < 4 {
+				return errShort
+			}
+			dx = float64(int16(binary.BigEndian.Uint16(data)))
+			dy = float64(int16(binary.BigEndian.Uint16(data[2:])))
+			data = data[4:]
+		} else {
+			if len(data) < 2 {
+				return errShort
+			}
+			dx = float64(int8(data[0]))
+			dy = float64(int8(data[1]))
+			data = data[2:]
+		}
+		if flags&glyfArgsAreXYValues == 0 {
+			// The arguments are point numbers to match, which is not supported.
+			dx, dy = 0, 0
+		}
+
+		readF2Dot14 := func() float64 {
+			v := float64(int16(binary.BigEndian.Uint16(data))) / (1 << 14)
+			data = data[2:]
+			return v
+		}
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		switch {
+		case flags&glyfHaveScale != 0:
+			if len(data) < 2 {
+				return errShort
+			}
+			a = readF2Dot14()
+			d = a
+		case flags&glyfHaveXYScale != 0:
+			if len(data) < 4 {
+				return errShort
+			}
+			a = readF2Dot14()
+			d = readF2Dot14()
+		case flags&glyfHaveTwoByTwo != 0:
+			if len(data) < 8 {
+				return errShort
+			}
+			a = readF2Dot14()
+			b = readF2Dot14()
+			c = readF2Dot14()
+			d = readF2Dot14()
+		}
+
+		// The component transform followed by `m`.
+		cm := [6]float64{
+			a*m[0] + b*m[2], a*m[1] + b*m[3],
+			c*m[0] + d*m[2], c*m[1] + d*m[3],
+			dx*m[0] + dy*m[2] + m[4], dx*m[1] + dy*m[3] + m[5],
+		}
+		if err := ttf.appendGlyph(path, gid, cm, depth+1); err != nil {
+			return err
+		}
+		if flags&glyfMoreComponents == 0 {
+			return nil
+		}
+	}
+}
+
+// transformPoint returns (`x`,`y`) transformed by affine transform `m`.
+func transformPoint(m [6]float64, x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// sfntTable returns the table `tag` of the OpenType font `data`.
+func sfntTable(data []byte, tag string) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font data too short")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			break
+		}
+		if string(data[rec:rec+4]) != tag {
+			continue
+		}
+		offset := binary.BigEndian.Uint32(data[rec+8:])
+		length := binary.BigEndian.Uint32(data[rec+12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid %q table", tag)
+		}
+		return data[offset : offset+length], nil
+	}
+	return nil, fmt.Errorf("table %q not found", tag)
+}
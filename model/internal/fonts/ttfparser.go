@@ -107,6 +107,13 @@ type TtfType struct {
 	Chars map[rune]GID
 	// GlyphNames is a list of glyphs from the "post" section of the TrueType file.
 	GlyphNames []GlyphName
+	// SymbolChars maps the character codes of the (3,0) cmap subtable (Windows Symbol) to GIDs.
+	// The codes are usually in the range 0xF000-0xF0FF.
+	SymbolChars map[rune]GID
+
+	// Glyph outlines: the glyf table and the glyph offsets from the loca table.
+	glyf []byte
+	loca []uint32
 }
 
 // MakeToUnicode returns a ToUnicode CMap based on the encoding of `ttf`.
@@ -157,8 +164,12 @@ type ttfParser struct {
 	rec              TtfType
 	f                io.ReadSeeker
 	tables           map[string]uint32
+	lengths          map[string]uint32
 	numberOfHMetrics uint16
 	numGlyphs        uint16
+
+	// outlines is true if the glyph outlines are loaded, which are only needed for rendering.
+	outlines bool
 }
 
 // NewFontFile2FromPdfObject returns a TtfType describing the TrueType font file in PdfObject `obj`.
@@ -200,6 +211,12 @@ func TtfParse(r io.ReadSeeker) (TtfType, error) {
 	return t.Parse()
 }
 
+// TtfParseGlyphs returns a TtfType describing the TrueType font, with its glyph outlines.
+func TtfParseGlyphs(r io.ReadSeeker) (TtfType, error) {
+	t := &ttfParser{f: r, outlines: true}
+	return t.Parse()
+}
+
 // Parse returns a TtfType describing the TrueType font file in io.Reader `t`.f.
 func (t *ttfParser) Parse() (TtfType, error) {
 
@@ -218,6 +235,7 @@ func (t *ttfParser) Parse() (TtfType, error) {
 	numTables := int(t.ReadUShort())
 	t.Skip(3 * 2) // searchRange, entrySelector, rangeShift
 	t.tables = make(map[string]uint32)
+	t.lengths = make(map[string]uint32)
 	var tag string
 	for j := 0; j < numTables; j++ {
 		tag, err = t.ReadStr(4)
@@ -226,8 +244,9 @@ func (t *ttfParser) Parse() (TtfType, error) {
 		}
 		t.Skip(4) // checkSum
 		offset := t.ReadULong()
-		t.Skip(4) // length
+		length := t.ReadULong()
 		t.tables[tag] = offset
+		t.lengths[tag] = length
 	}
 
 	common.Log.Trace(describeTables(t.tables))
@@ -235,6 +254,11 @@ func (t *ttfParser) Parse() (TtfType, error) {
 	if err = t.ParseComponents(); err != nil {
 		return TtfType{}, err
 	}
+	if _, ok := t.tables["glyf"]; ok && t.outlines {
+		if err := t.ParseGlyf(); err != nil {
+			common.Log.Debug("ERROR: Unable to parse glyph outlines: %v", err)
+		}
+	}
 	return t.rec, nil
 }
 
@@ -368,15 +392,35 @@ func (t *ttfParser) ParseHmtx() error {
 
 // parseCmapSubtable31 parses information from an (3,1) subtable (Windows Unicode).
 func (t *ttfParser) parseCmapSubtable31(offset31 int64) error {
+	chars, err := t.parseCmapFormat4(offset31)
+	if err != nil {
+		return err
+	}
+	t.rec.Chars = chars
+	return nil
+}
+
+// parseCmapSubtable30 parses information from an (3,0) subtable (Windows Symbol).
+func (t *ttfParser) parseCmapSubtable30(offset30 int64) error {
+	chars, err := t.parseCmapFormat4(offset30)
+	if err != nil {
+		return err
+	}
+	t.rec.SymbolChars = chars
+	return nil
+}
+
+// parseCmapFormat4 returns the mapping of the format 4 cmap subtable at `offset`.
+func (t *ttfParser) parseCmapFormat4(offset int64) (map[rune]GID, error) {
 	startCount := make([]rune, 0, 8)
 	endCount := make([]rune, 0, 8)
 	idDelta := make([]int16, 0, 8)
 	idRangeOffset := make([]uint16, 0, 8)
-	t.rec.Chars = make(map[rune]GID)
-	t.f.Seek(int64(t.tables["cmap"])+offset31, os.SEEK_SET)
+	chars := make(map[rune]GID)
+	t.f.Seek(int64(t.tables["cmap"])+offset, os.SEEK_SET)
 	format := t.ReadUShort()
 	if format != 4 {
-		return fmt.Errorf("unexpected subtable format: %d", format)
+		return nil, fmt.Errorf("unexpected subtable format: %d", format)
 	}
 	t.Skip(2 * 2) // length, language
 	segCount := int(t.ReadUShort() / 2)
@@ -391,7 +435,7 @@ func (t *ttfParser) parseCmapSubtable31(offset31 int64) error {
 	for j := 0; j < segCount; j++ {
 		idDelta = append(idDelta, t.ReadShort())
 	}
-	offset, _ := t.f.Seek(int64(0), os.SEEK_CUR)
+	rangeOffset, _ := t.f.Seek(int64(0), os.SEEK_CUR)
 	for j := 0; j < segCount; j++ {
 		idRangeOffset = append(idRangeOffset, t.ReadUShort())
 	}
@@ -401,7 +445,7 @@ func (t *ttfParser) parseCmapSubtable31(offset31 int64) error {
 		d := idDelta[j]
 		ro := idRangeOffset[j]
 		if ro > 0 {
-			t.f.Seek(offset+2*int64(j)+int64(ro), os.SEEK_SET)
+			t.f.Seek(rangeOffset+2*int64(j)+int64(ro), os.SEEK_SET)
 		}
 		for c := c1; c <= c2; c++ {
 			if c == 0xFFFF {
@@ -420,11 +464,11 @@ func (t *ttfParser) parseCmapSubtable31(offset31 int64) error {
 				gid -= 65536
 			}
 			if gid > 0 {
-				t.rec.Chars[c] = GID(gid)
+				chars[c] = GID(gid)
 			}
 		}
 	}
-	return nil
+	return chars, nil
 }
 
 // parseCmapSubtable10 parses information from an (1,0) subtable (symbol).
@@ -477,6 +521,7 @@ func (t *ttfParser) ParseCmap() error {
 	t.ReadUShort() // version is ignored.
 	numTables := int(t.ReadUShort())
 	offset10 := int64(0)
+	offset30 := int64(0)
 	offset31 := int64(0)
 	for j := 0; j < numTables; j++ {
 		platformID := t.ReadUShort()
@@ -485,6 +530,9 @@ func (t *ttfParser) ParseCmap() error {
 		if platformID == 3 && encodingID == 1 {
 			// (3,1) subtable. Windows Unicode.
 			offset31 = offset
+		} else if platformID == 3 && encodingID == 0 {
+			// (3,0) subtable. Windows Symbol.
+			offset30 = offset
 		} else if platformID == 1 && encodingID == 0 {
 			offset10 = offset
 		}
@@ -503,8 +551,14 @@ func (t *ttfParser) ParseCmap() error {
 			return err
 		}
 	}
-	if offset31 == 0 && offset10 == 0 {
-		common.Log.Debug("ttfParser.ParseCmap. No 31 or 10 table.")
+	// Symbolic fonts use subtable (3,0).
+	if offset30 != 0 {
+		if err := t.parseCmapSubtable30(offset30); err != nil {
+			common.Log.Debug("ERROR: Unable to parse the (3,0) cmap subtable: %v", err)
+		}
+	}
+	if offset31 == 0 && offset10 == 0 && offset30 == 0 {
+		common.Log.Debug("ttfParser.ParseCmap. No 31, 30 or 10 table.")
 	}
 
 	return nil
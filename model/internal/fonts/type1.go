@@ -0,0 +1,613 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+)
+
+// Type1Font is a Type 1 font program, as embedded in FontFile streams. Only the information
+// needed to look up glyphs and to interpret their charstrings is loaded.
+// See Adobe Type 1 Font Format.
+type Type1Font struct {
+	Name string
+	// FontMatrix maps the glyph space to the text space.
+	FontMatrix [6]float64
+
+	encoding    map[byte]GlyphName
+	charStrings map[GlyphName][]byte
+	subrs       [][]byte
+}
+
+// Keys of the eexec and charstring encryption.
+const (
+	type1EexecKey      = 55665
+	type1CharStringKey = 4330
+)
+
+var (
+	reType1FontName   = regexp.MustCompile(`/FontName\s*/(\S+)`)
+	reType1FontMatrix = regexp.MustCompile(`/FontMatrix\s*\[([^\]]*)\]`)
+	reType1Encoding   = regexp.MustCompile(`dup\s+(\d+)\s*/([^\s/]+)\s+put`)
+	reType1LenIV      = regexp.MustCompile(`/lenIV\s+(-?\d+)`)
+)
+
+// ParseType1 parses the Type 1 font program `data`, made of a cleartext and an eexec encrypted
+// portion, with or without PFB segment headers.
+func ParseType1(data []byte) (*Type1Font, error) {
+	data = stripPFBHeaders(data)
+	i := bytes.Index(data, []byte("eexec"))
+	if i < 0 {
+		return nil, errors.New("eexec section not found")
+	}
+	cleartext, encrypted := data[:i], data[i+len("eexec"):]
+	for len(encrypted) > 0 && isType1Space(encrypted[0]) {
+		encrypted = encrypted[1:]
+	}
+	if isHexData(encrypted) {
+		encrypted = decodeHexData(encrypted)
+	}
+	private := type1Decrypt(encrypted, type1EexecKey, 4)
+
+	font := &Type1Font{
+		FontMatrix:  [6]float64{0.001, 0, 0, 0.001, 0, 0},
+		charStrings: make(map[GlyphName][]byte),
+	}
+	if m := reType1FontName.FindSubmatch(cleartext); m != nil {
+		font.Name = string(m[1])
+	}
+	if m := reType1FontMatrix.FindSubmatch(cleartext); m != nil {
+		fields := bytes.Fields(m[1])
+		if len(fields) == 6 {
+			for i, f := range fields {
+				v, err := strconv.ParseFloat(string(f), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid FontMatrix: %v", err)
+				}
+				font.FontMatrix[i] = v
+			}
+		}
+	}
+	font.parseEncoding(cleartext)
+
+	lenIV := 4
+	if m := reType1LenIV.FindSubmatch(private); m != nil {
+		lenIV, _ = strconv.Atoi(string(m[1]))
+	}
+	if err := font.parsePrivate(private, lenIV); err != nil {
+		return nil, err
+	}
+	if len(font.charStrings) == 0 {
+		return nil, errors.New("no Type 1 charstrings")
+	}
+	return font, nil
+}
+
+// String returns a string describing `font`.
+func (font *Type1Font) String() string {
+	return fmt.Sprintf("TYPE1{%#q glyphs=%d}", font.Name, len(font.charStrings))
+}
+
+// NumGlyphs returns the number of glyphs in `font`.
+func (font *Type1Font) NumGlyphs() int {
+	return len(font.charStrings)
+}
+
+// HasGlyph returns true if `font` has a glyph named `name`.
+func (font *Type1Font) HasGlyph(name GlyphName) bool {
+	_, ok := font.charStrings[name]
+	return ok
+}
+
+// GlyphNameByCode returns the name of the glyph of character code `code` in the built-in
+// encoding of `font`.
+func (font *Type1Font) GlyphNameByCode(code byte) (GlyphName, bool) {
+	name, ok := font.encoding[code]
+	return name, ok
+}
+
+// GlyphOutline returns the outline of the glyph named `name` in glyph space units.
+func (font *Type1Font) GlyphOutline(name GlyphName) (*GlyphPath, error) {
+	code, ok := font.charStrings[name]
+	if !ok {
+		return nil, fmt.Errorf("glyph %q not found", name)
+	}
+	interp := type1Interpreter{font: font, path: &GlyphPath{}}
+	if err := interp.run(code, 0); err != nil && err != errEndChar {
+		return nil, err
+	}
+	interp.path.Close()
+	return interp.path, nil
+}
+
+// parseEncoding loads the built-in encoding from the cleartext portion `clear`.
+func (font *Type1Font) parseEncoding(clear []byte) {
+	font.encoding = make(map[byte]GlyphName)
+	i := bytes.Index(clear, []byte("/Encoding"))
+	if i < 0 {
+		return
+	}
+	clear = clear[i:]
+	if bytes.HasPrefix(bytes.TrimSpace(clear[len("/Encoding"):]), []byte("StandardEncoding")) {
+		for code := 0; code < 256; code++ {
+			if name, ok := standardEncodingName(byte(code)); ok {
+				font.encoding[byte(code)] = name
+			}
+		}
+		return
+	}
+	for _, m := range reType1Encoding.FindAllSubmatch(clear, -1) {
+		code, err := strconv.Atoi(string(m[1]))
+		if err != nil || code > 255 {
+			continue
+		}
+		font.encoding[byte(code)] = GlyphName(m[2])
+	}
+}
+
+// parsePrivate loads the subroutines and the charstrings from the decrypted portion `private`.
+// The charstrings are decrypted and their first `lenIV` bytes are dropped.
+func (font *Type1Font) parsePrivate(private []byte, lenIV int) error {
+	decrypt := func(b []byte) []byte {
+		if lenIV < 0 {
+			return b
+		}
+		return type1Decrypt(b, type1CharStringKey, lenIV)
+	}
+
+	if i := bytes.Index(private, []byte("/Subrs")); i >= 0 {
+		s := type1Scanner{data: private, pos: i + len("/Subrs")}
+		count, err := strconv.Atoi(s.token())
+		if err != nil {
+			return fmt.Errorf("invalid Subrs count: %v", err)
+		}
+		font.subrs = make([][]byte, count)
+		for n := 0; n < count; n++ {
+			if !s.skipTo("dup") {
+				break
+			}
+			index, err := strconv.Atoi(s.token())
+			if err != nil {
+				return fmt.Errorf("invalid subroutine number: %v", err)
+			}
+			b, err := s.binary()
+			if err != nil {
+				return err
+			}
+			if index >= 0 && index < count {
+				font.subrs[index] = decrypt(b)
+			}
+		}
+	}
+
+	i := bytes.Index(private, []byte("/CharStrings"))
+	if i < 0 {
+		return errors.New("CharStrings not found")
+	}
+	s := type1Scanner{data: private, pos: i + len("/CharStrings")}
+	for {
+		tok := s.token()
+		if tok == "" || tok == "end" {
+			break
+		}
+		if tok[0] != '/' {
+			continue
+		}
+		b, err := s.binary()
+		if err != nil {
+			return err
+		}
+		font.charStrings[GlyphName(tok[1:])] = decrypt(b)
+	}
+	return nil
+}
+
+// type1Scanner reads PostScript tokens and binary data from the private portion of a Type 1 font.
+type type1Scanner struct {
+	data []byte
+	pos  int
+}
+
+// token returns the next whitespace-delimited token or "" at the end of data.
+func (s *type1Scanner) token() string {
+	for s.pos < len(s.data) && isType1Space(s.data[s.pos]) {
+		s.pos++
+	}
+	start := s.pos
+	for s.pos < len(s.data) && !isType1Space(s.data[s.pos]) {
+		s.pos++
+	}
+	return string(s.data[start:s.pos])
+}
+
+// skipTo skips past the next token `tok`. Returns false if it is not found.
+func (s *type1Scanner) skipTo(tok string) bool {
+	for {
+		t := s.token()
+		if t == "" {
+			return false
+		}
+		if t == tok {
+			return true
+		}
+	}
+}
+
+// binary reads the binary data written as `length RD <data>`, where RD is the name of the
+// procedure reading it (RD or -|), which is followed by a single space.
+func (s *type1Scanner) binary() ([]byte, error) {
+	length, err := strconv.Atoi(s.token())
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid binary length: %v", err)
+	}
+	s.token()
+	start := s.pos + 1
+	if start+length > len(s.data) {
+		return nil, errors.New("binary data too short")
+	}
+	s.pos = start + length
+	return s.data[start:s.pos], nil
+}
+
+// type1Decrypt returns `data` decrypted with key `r`, without the first `skip` bytes.
+func type1Decrypt(data []byte, r uint16, skip int) []byte {
+	const c1, c2 = 52845, 22719
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ byte(r>>8)
+		r = (uint16(c)+r)*c1 + c2
+	}
+	if skip > len(out) {
+		skip = len(out)
+	}
+	return out[skip:]
+}
+
+// stripPFBHeaders returns `data` without the segment headers of the PFB format, if any.
+func stripPFBHeaders(data []byte) []byte {
+	if len(data) < 6 || data[0] != 0x80 {
+		return data
+	}
+	var out []byte
+	for len(data) >= 6 && data[0] == 0x80 && (data[1] == 1 || data[1] == 2) {
+		length := int(binary.LittleEndian.Uint32(data[2:]))
+		data = data[6:]
+		if length > len(data) {
+			length = len(data)
+		}
+		out = append(out, data[:length]...)
+		data = data[length:]
+	}
+	return out
+}
+
+// isType1Space returns true if `c` is a PostScript whitespace character.
+func isType1Space(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// isHexData returns true if the encrypted portion `data` is hex encoded, which is indicated by
+// its first 4 bytes being hex digits.
+func isHexData(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, c := range data[:4] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeHexData returns the hex encoded `data` decoded, ignoring whitespace.
+func decodeHexData(data []byte) []byte {
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			digits = append(digits, c)
+		} else if !isType1Space(c) {
+			break
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = digits[:len(digits)-1]
+	}
+	out := make([]byte, len(digits)/2)
+	hex.Decode(out, digits)
+	return out
+}
+
+// standardEncodingName returns the name of the glyph of `code` in the standard encoding.
+func standardEncodingName(code byte) (GlyphName, bool) {
+	standardEncoderOnce.Do(loadStandardEncoder)
+	if standardEncoder == nil {
+		return "", false
+	}
+	r, ok := standardEncoder.CharcodeToRune(textencoding.CharCode(code))
+	if !ok {
+		return "", false
+	}
+	return textencoding.RuneToGlyph(r)
+}
+
+// Othersubrs of the flex and hint replacement mechanisms.
+const (
+	type1OtherSubrFlexEnd   = 0
+	type1OtherSubrFlexStart = 1
+	type1OtherSubrFlexPoint = 2
+	type1OtherSubrHints     = 3
+)
+
+// type1Interpreter interprets Type 1 charstrings to glyph outlines.
+type type1Interpreter struct {
+	font *Type1Font
+	path *GlyphPath
+
+	stack    []float64
+	psStack  []float64
+	x, y     float64
+	sbx, sby float64
+	flexing  bool
+	flexPts  []GlyphPoint
+}
+
+func (t *type1Interpreter) moveTo(dx, dy float64) {
+	t.x += dx
+	t.y += dy
+	if !t.flexing {
+		t.path.MoveTo(t.x, t.y)
+	}
+}
+
+func (t *type1Interpreter) lineTo(dx, dy float64) {
+	t.x += dx
+	t.y += dy
+	t.path.LineTo(t.x, t.y)
+}
+
+func (t *type1Interpreter) curveTo(dxa, dya, dxb, dyb, dxc, dyc float64) {
+	x1, y1 := t.x+dxa, t.y+dya
+	x2, y2 := x1+dxb, y1+dyb
+	t.x, t.y = x2+dxc, y2+dyc
+	t.path.CubeTo(x1, y1, x2, y2, t.x, t.y)
+}
+
+// run interprets charstring `code` at subroutine nesting level `depth`.
+func (t *type1Interpreter) run(code []byte, depth int) error {
+	if depth > type2MaxRecursion {
+		return errors.New("charstring subroutines nested too deep")
+	}
+	for p := 0; p < len(code); {
+		b0 := code[p]
+		p++
+
+		// Operands.
+		if b0 >= 32 {
+			var v float64
+			switch {
+			case b0 <= 246:
+				v = float64(int(b0) - 139)
+			case b0 <= 250:
+				if p >= len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64((int(b0)-247)*256 + int(code[p]) + 108)
+				p++
+			case b0 <= 254:
+				if p >= len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64(-(int(b0)-251)*256 - int(code[p]) - 108)
+				p++
+			default:
+				if p+4 > len(code) {
+					return errors.New("truncated charstring")
+				}
+				v = float64(int32(binary.BigEndian.Uint32(code[p:])))
+				p += 4
+			}
+			if len(t.stack) >= type2MaxStack {
+				return errors.New("charstring stack overflow")
+			}
+			t.stack = append(t.stack, v)
+			continue
+		}
+
+		s := t.stack
+		switch b0 {
+		case 1, 3: // hstem, vstem
+		case 4: // vmoveto
+			if len(s) >= 1 {
+				t.moveTo(0, s[0])
+			}
+		case 5: // rlineto
+			if len(s) >= 2 {
+				t.lineTo(s[0], s[1])
+			}
+		case 6: // hlineto
+			if len(s) >= 1 {
+				t.lineTo(s[0], 0)
+			}
+		case 7: // vlineto
+			if len(s) >= 1 {
+				t.lineTo(0, s[0])
+			}
+		case 8: // rrcurveto
+			if len(s) >= 6 {
+				t.curveTo(s[0], s[1], s[2], s[3], s[4], s[5])
+			}
+		case 9: // closepath
+			t.path.Close()
+		case 10: // callsubr
+			if len(s) == 0 {
+				return errors.New("charstring stack underflow")
+			}
+			i := int(s[len(s)-1])
+			t.stack = s[:len(s)-1]
+			if i < 0 || i >= len(t.font.subrs) {
+				return fmt.Errorf("invalid subroutine %d", i)
+			}
+			if err := t.run(t.font.subrs[i], depth+1); err != nil {
+				return err
+			}
+			continue
+		case 11: // return
+			return nil
+		case 13: // hsbw
+			if len(s) >= 2 {
+				t.sbx, t.sby = s[0], 0
+				t.x, t.y = t.sbx, t.sby
+			}
+		case 14: // endchar
+			return errEndChar
+		case 21: // rmoveto
+			if len(s) >= 2 {
+				t.moveTo(s[0], s[1])
+			}
+		case 22: // hmoveto
+			if len(s) >= 1 {
+				t.moveTo(s[0], 0)
+			}
+		case 30: // vhcurveto
+			if len(s) >= 4 {
+				t.curveTo(0, s[0], s[1], s[2], s[3], 0)
+			}
+		case 31: // hvcurveto
+			if len(s) >= 4 {
+				t.curveTo(s[0], 0, s[1], s[2], 0, s[3])
+			}
+		case 12:
+			if p >= len(code) {
+				return errors.New("truncated charstring")
+			}
+			b1 := code[p]
+			p++
+			if err := t.escape(b1); err != nil {
+				return err
+			}
+			continue
+		default:
+			// Reserved operators are ignored.
+		}
+		t.stack = t.stack[:0]
+	}
+	return nil
+}
+
+// escape interprets the escaped operator 12 `op`.
+func (t *type1Interpreter) escape(op byte) error {
+	s := t.stack
+	switch op {
+	case 0, 1, 2: // dotsection, vstem3, hstem3
+	case 6: // seac
+		if len(s) >= 5 {
+			return t.seac(s[0], s[1], s[2], byte(s[3]), byte(s[4]))
+		}
+	case 7: // sbw
+		if len(s) >= 4 {
+			t.sbx, t.sby = s[0], s[1]
+			t.x, t.y = t.sbx, t.sby
+		}
+	case 12: // div
+		if len(s) < 2 {
+			return errors.New("charstring stack underflow")
+		}
+		if s[len(s)-1] == 0 {
+			return errors.New("charstring division by zero")
+		}
+		t.stack = append(s[:len(s)-2], s[len(s)-2]/s[len(s)-1])
+		return nil
+	case 16: // callothersubr
+		if len(s) < 2 {
+			return errors.New("charstring stack underflow")
+		}
+		otherSubr, n := int(s[len(s)-1]), int(s[len(s)-2])
+		s = s[:len(s)-2]
+		if n < 0 || n > len(s) {
+			return errors.New("charstring stack underflow")
+		}
+		args := append([]float64(nil), s[len(s)-n:]...)
+		t.stack = s[:len(s)-n]
+		t.otherSubr(otherSubr, args)
+		return nil
+	case 17: // pop
+		if len(t.psStack) > 0 {
+			v := t.psStack[len(t.psStack)-1]
+			t.psStack = t.psStack[:len(t.psStack)-1]
+			t.stack = append(t.stack, v)
+		}
+		return nil
+	case 33: // setcurrentpoint
+		if len(s) >= 2 {
+			t.x, t.y = s[0], s[1]
+		}
+	}
+	t.stack = t.stack[:0]
+	return nil
+}
+
+// otherSubr runs the othersubr number `n` with arguments `args`.
+func (t *type1Interpreter) otherSubr(n int, args []float64) {
+	switch n {
+	case type1OtherSubrFlexStart:
+		t.flexing = true
+		t.flexPts = t.flexPts[:0]
+	case type1OtherSubrFlexPoint:
+		if t.flexing {
+			t.flexPts = append(t.flexPts, GlyphPoint{t.x, t.y})
+		}
+	case type1OtherSubrFlexEnd:
+		t.flexing = false
+		// The first point is the reference point, followed by the points of two curves.
+		if pts := t.flexPts; len(pts) == 7 {
+			t.path.CubeTo(pts[1].X, pts[1].Y, pts[2].X, pts[2].Y, pts[3].X, pts[3].Y)
+			t.path.CubeTo(pts[4].X, pts[4].Y, pts[5].X, pts[5].Y, pts[6].X, pts[6].Y)
+		}
+		// The end point is returned for setcurrentpoint.
+		if len(args) >= 3 {
+			t.psStack = append(t.psStack, args[2], args[1])
+		}
+	case type1OtherSubrHints:
+		// The subroutine number is returned to be called by callsubr.
+		t.psStack = append(t.psStack, 3)
+	default:
+		for i := len(args) - 1; i >= 0; i-- {
+			t.psStack = append(t.psStack, args[i])
+		}
+	}
+}
+
+// seac draws the accented character made of base character `bchar` and accent character
+// `achar` (standard encoding codes) with the accent left sidebearing `asb` and offset
+// (`adx`,`ady`).
+func (t *type1Interpreter) seac(asb, adx, ady float64, bchar, achar byte) error {
+	baseName, ok1 := standardEncodingName(bchar)
+	accentName, ok2 := standardEncodingName(achar)
+	if !ok1 || !ok2 {
+		return errors.New("seac glyphs not found")
+	}
+	basePath, err := t.font.GlyphOutline(baseName)
+	if err != nil {
+		return err
+	}
+	accentPath, err := t.font.GlyphOutline(accentName)
+	if err != nil {
+		return err
+	}
+	accentPath.Transform(1, 0, 0, 1, adx-asb, ady)
+	t.path.Append(basePath)
+	t.path.Append(accentPath)
+	return errEndChar
+}
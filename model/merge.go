@@ -0,0 +1,698 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfMerger merges several documents into one. Unlike adding the pages of the documents to a
+// PdfWriter, the merger also combines the interactive forms (AcroForm), the outlines and the named
+// destinations of the documents, and keeps the links between the pages working.
+//
+// The merged documents are modified in the process: their pages are reused in the output, so the
+// readers should not be used for other purposes afterwards.
+type PdfMerger struct {
+	// RenameConflictingFields makes the merger rename the form fields whose name is already used
+	// by a field of a previously merged document, so that the fields of the documents remain
+	// independent. The fields are renamed by appending a number to their name.
+	// Otherwise fields with the same name are merged as the kids of a single field holding the
+	// name, and are considered the same field by PDF viewers (sharing the same value).
+	RenameConflictingFields bool
+
+	docs []*mergeDocument
+}
+
+// mergeDocument is a document to be merged.
+type mergeDocument struct {
+	reader *PdfReader
+	title  string
+	pages  []*PdfPage
+}
+
+// NewPdfMerger returns a new PdfMerger.
+func NewPdfMerger() *PdfMerger {
+	return &PdfMerger{}
+}
+
+// AddDocument adds all the pages of the document loaded by `reader`. The outlines of the
+// document are placed under an outline item titled `title`, or the document title if empty.
+func (m *PdfMerger) AddDocument(reader *PdfReader, title string) error {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	pageNums := make([]int, numPages)
+	for i := range pageNums {
+		pageNums[i] = i + 1
+	}
+	return m.AddPages(reader, title, pageNums)
+}
+
+// AddPages adds the pages `pageNums` (starting from 1) of the document loaded by `reader`. The
+// outlines of the document are placed under an outline item titled `title`, or the document title
+// if empty. Links, outline items and named destinations targeting the pages which are not added
+// are removed.
+func (m *PdfMerger) AddPages(reader *PdfReader, title string, pageNums []int) error {
+	if reader == nil {
+		return errors.New("reader cannot be nil")
+	}
+	for _, doc := range m.docs {
+		if doc.reader == reader {
+			return errors.New("document already added")
+		}
+	}
+
+	doc := &mergeDocument{reader: reader, title: title}
+	added := map[int]struct{}{}
+	for _, num := range pageNums {
+		if _, ok := added[num]; ok {
+			return fmt.Errorf("page %d added more than once", num)
+		}
+		added[num] = struct{}{}
+
+		page, err := reader.GetPage(num)
+		if err != nil {
+			return err
+		}
+		doc.pages = append(doc.pages, page)
+	}
+	if doc.title == "" {
+		doc.title = readerTitle(reader)
+	}
+	if doc.title == "" {
+		doc.title = fmt.Sprintf("Document %d", len(m.docs)+1)
+	}
+
+	m.docs = append(m.docs, doc)
+	return nil
+}
+
+// Write merges the documents and writes the result to `w`.
+func (m *PdfMerger) Write(w io.Writer) error {
+	writer := NewPdfWriter()
+	if err := m.AddToWriter(&writer); err != nil {
+		return err
+	}
+	return writer.Write(w)
+}
+
+// AddToWriter merges the documents into `w`: the pages are added to `w`, and the combined form,
+// outlines and named destinations are set on it. This allows setting up the writer (e.g.
+// optimization or encryption) before the merge.
+func (m *PdfMerger) AddToWriter(w *PdfWriter) error {
+	if len(m.docs) == 0 {
+		return errors.New("no documents to merge")
+	}
+
+	ctx := newMergeContext()
+	ctx.renameFields = m.RenameConflictingFields
+	for _, doc := range m.docs {
+		if err := ctx.mergeDocument(w, doc); err != nil {
+			return err
+		}
+	}
+	return ctx.finish(w)
+}
+
+// finish sets the merged form, named destinations and outlines on `w`.
+func (ctx *mergeContext) finish(w *PdfWriter) error {
+	if len(*ctx.form.Fields) > 0 {
+		if err := w.SetForms(ctx.form); err != nil {
+			return err
+		}
+	}
+	if ctx.dests.Len() > 0 {
+		if err := w.SetNameTree("Dests", ctx.dests); err != nil {
+			return err
+		}
+	}
+	if ctx.outline.First != nil {
+		count := countOpenOutlineItems(&ctx.outline.PdfOutlineTreeNode)
+		ctx.outline.Count = &count
+		w.AddOutlineTree(&ctx.outline.PdfOutlineTreeNode)
+	}
+	return nil
+}
+
+// mergeContext holds the state of a merge.
+type mergeContext struct {
+	form     *PdfAcroForm
+	fields   map[string]*PdfField
+	dests    *PdfNameTree
+	outline  *PdfOutline
+	lastItem *PdfOutlineItem
+
+	// Parent fields created to merge the top-level fields with the same name.
+	sharedFields map[*PdfField]struct{}
+
+	// Rename the conflicting form fields and remove the outline items which do not target merged
+	// pages.
+	renameFields  bool
+	pruneOutlines bool
+
+	// State of the document being merged: output page objects by source page object and
+	// new names of the named destinations.
+	pageMap  map[core.PdfObject]*core.PdfIndirectObject
+	destMap  map[string]string
+	pageList []*core.PdfIndirectObject
+}
+
+// newMergeContext returns a new mergeContext.
+func newMergeContext() *mergeContext {
+	return &mergeContext{
+		form:         NewPdfAcroForm(),
+		dests:        NewPdfNameTree(),
+		outline:      NewPdfOutline(),
+		fields:       map[string]*PdfField{},
+		sharedFields: map[*PdfField]struct{}{},
+	}
+}
+
+// mergeDocument merges `doc` into `w`.
+func (ctx *mergeContext) mergeDocument(w *PdfWriter, doc *mergeDocument) error {
+	if err := ctx.mergeDocumentData(doc.reader, doc.pages); err != nil {
+		return err
+	}
+	ctx.mergeOutlines(doc)
+
+	for _, page := range doc.pages {
+		if err := w.AddPage(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeDocumentData merges the named destinations and the form fields of the document loaded by
+// `reader` targeting `pages`, and remaps the links of `pages` to the merged document.
+func (ctx *mergeContext) mergeDocumentData(reader *PdfReader, pages []*PdfPage) error {
+	ctx.pageMap = map[core.PdfObject]*core.PdfIndirectObject{}
+	ctx.destMap = map[string]string{}
+	ctx.pageList = reader.pageList
+	for _, page := range pages {
+		obj := page.GetPageAsIndirectObject()
+		ctx.pageMap[obj] = obj
+	}
+
+	if err := ctx.mergeNamedDestinations(reader); err != nil {
+		return err
+	}
+
+	// Widget annotations of the merged pages, used to only keep the fields on these pages.
+	widgets := map[core.PdfObject]struct{}{}
+	for _, page := range pages {
+		annotations, err := page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		kept := []*PdfAnnotation{}
+		for _, annot := range annotations {
+			switch t := annot.GetContext().(type) {
+			case *PdfAnnotationLink:
+				if !ctx.remapLink(t) {
+					common.Log.Debug("Link to a page not merged - removing")
+					continue
+				}
+			case *PdfAnnotationWidget:
+				widgets[t.container] = struct{}{}
+			}
+			kept = append(kept, annot)
+		}
+		page.SetAnnotations(kept)
+	}
+
+	ctx.mergeForm(reader.AcroForm, widgets)
+	return nil
+}
+
+// mergeNamedDestinations adds the named destinations of the document loaded by `reader`, from the
+// Dests name tree and from the Dests dictionary of the catalog (PDF 1.1).
+func (ctx *mergeContext) mergeNamedDestinations(reader *PdfReader) error {
+	type namedDest struct {
+		name string
+		dest core.PdfObject
+	}
+	var dests []namedDest
+
+	tree, err := reader.GetNameTree("Dests")
+	if err != nil {
+		return err
+	}
+	if tree != nil {
+		tree.Iterate(func(name string, val core.PdfObject) bool {
+			dests = append(dests, namedDest{name, val})
+			return true
+		})
+	}
+	if dict, ok := core.GetDict(reader.catalog.Get("Dests")); ok {
+		for _, key := range dict.Keys() {
+			dests = append(dests, namedDest{string(key), dict.Get(key)})
+		}
+	}
+
+	for _, nd := range dests {
+		if _, ok := ctx.destMap[nd.name]; ok {
+			// Defined in both the name tree and the dictionary.
+			continue
+		}
+		dest := core.ResolveReference(nd.dest)
+		if dict, ok := core.GetDict(dest); ok {
+			dest = core.ResolveReference(dict.Get("D"))
+		}
+		dest, ok := ctx.remapExplicitDest(dest)
+		if !ok {
+			continue
+		}
+
+		name := nd.name
+		for i := 2; ; i++ {
+			if _, exists := ctx.dests.Get(name); !exists {
+				break
+			}
+			name = fmt.Sprintf("%s_%d", nd.name, i)
+		}
+		ctx.destMap[nd.name] = name
+		ctx.dests.Set(name, dest)
+	}
+	return nil
+}
+
+// remapExplicitDest returns the explicit destination `dest` targeting the output page
+// corresponding to its target page. The target page can also be specified by its index, as done
+// by outlines created with NewOutlineDest. Returns false if the target page is not merged.
+func (ctx *mergeContext) remapExplicitDest(dest core.PdfObject) (core.PdfObject, bool) {
+	arr, ok := core.GetArray(dest)
+	if !ok || arr.Len() == 0 {
+		return nil, false
+	}
+	target := core.ResolveReference(arr.Get(0))
+	if idx, ok := core.GetIntVal(target); ok && idx >= 0 && idx < len(ctx.pageList) {
+		target = ctx.pageList[idx]
+	}
+	page, ok := ctx.pageMap[target]
+	if !ok {
+		return nil, false
+	}
+	elems := append([]core.PdfObject{page}, arr.Elements()[1:]...)
+	return core.MakeArray(elems...), true
+}
+
+// remapDest returns the destination `dest` (explicit or named) remapped to the merged document.
+// Returns false if the destination target is not merged.
+func (ctx *mergeContext) remapDest(dest core.PdfObject) (core.PdfObject, bool) {
+	dest = core.ResolveReference(dest)
+	var name string
+	switch t := dest.(type) {
+	case *core.PdfObjectString:
+		name = t.Str()
+	case *core.PdfObjectName:
+		name = string(*t)
+	default:
+		return ctx.remapExplicitDest(dest)
+	}
+	newName, ok := ctx.destMap[name]
+	if !ok {
+		return nil, false
+	}
+	return core.MakeString(newName), true
+}
+
+// remapAction returns the action dictionary `obj` with the destination of GoTo actions remapped
+// to the merged document. The GoTo actions are copied, leaving the actions of the merged document
+// unchanged. Returns false if the destination target is not merged.
+func (ctx *mergeContext) remapAction(obj core.PdfObject) (core.PdfObject, bool) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return obj, true
+	}
+	if s, _ := core.GetNameVal(dict.Get("S")); s != "GoTo" {
+		return obj, true
+	}
+	dest, ok := ctx.remapDest(dict.Get("D"))
+	if !ok {
+		return nil, false
+	}
+	copied := core.MakeDict().Merge(dict)
+	copied.Set("D", dest)
+	return copied, true
+}
+
+// remapLink remaps the destination of link annotation `link` to the merged document.
+// Returns false if the link target is not merged.
+func (ctx *mergeContext) remapLink(link *PdfAnnotationLink) bool {
+	if link.Dest != nil {
+		dest, ok := ctx.remapDest(link.Dest)
+		if !ok {
+			return false
+		}
+		link.Dest = dest
+	}
+	if link.action != nil {
+		if goTo, ok := link.action.GetContext().(*PdfActionGoTo); ok {
+			dest, ok := ctx.remapDest(goTo.D)
+			if !ok {
+				return false
+			}
+			copied := NewPdfActionGoTo()
+			copied.Next = goTo.Next
+			copied.D = dest
+			link.SetAction(copied.PdfAction)
+		}
+	} else if link.A != nil {
+		action, ok := ctx.remapAction(link.A)
+		if !ok {
+			return false
+		}
+		link.A = action
+	}
+	return true
+}
+
+// mergeForm adds the fields of `form` which have widget annotations in `widgets` to the merged
+// form, and merges the form settings.
+func (ctx *mergeContext) mergeForm(form *PdfAcroForm, widgets map[core.PdfObject]struct{}) {
+	if form == nil {
+		return
+	}
+
+	merged := ctx.form
+	if merged.Fields == nil {
+		merged.Fields = &[]*PdfField{}
+	}
+	var formFields []*PdfField
+	if form.Fields != nil {
+		formFields = *form.Fields
+	}
+	// The renamed fields must not take the names of the fields of the previous documents nor
+	// those of the top-level fields of the document.
+	taken := map[string]struct{}{}
+	for _, field := range formFields {
+		if field.T != nil {
+			taken[field.T.Decoded()] = struct{}{}
+		}
+	}
+	fields := map[string]*PdfField{}
+	for _, field := range formFields {
+		if !pruneField(field, widgets) {
+			continue
+		}
+		if field.T == nil {
+			*merged.Fields = append(*merged.Fields, field)
+			continue
+		}
+
+		name := field.T.Decoded()
+		if existing, exists := ctx.fields[name]; exists {
+			if !ctx.renameFields {
+				ctx.shareField(existing, field)
+				continue
+			}
+			newName := name
+			for i := 2; ; i++ {
+				newName = fmt.Sprintf("%s_%d", name, i)
+				_, existsMerged := ctx.fields[newName]
+				_, existsDoc := taken[newName]
+				if !existsMerged && !existsDoc {
+					break
+				}
+			}
+			common.Log.Debug("Renaming conflicting field %s to %s", name, newName)
+			field.T = core.MakeTextString(newName)
+			name = newName
+			taken[name] = struct{}{}
+		}
+		fields[name] = field
+		*merged.Fields = append(*merged.Fields, field)
+	}
+	for name, field := range fields {
+		ctx.fields[name] = field
+	}
+
+	if form.NeedAppearances != nil && bool(*form.NeedAppearances) {
+		merged.NeedAppearances = core.MakeBool(true)
+	}
+	if form.SigFlags != nil {
+		flags := int64(*form.SigFlags)
+		if merged.SigFlags != nil {
+			flags |= int64(*merged.SigFlags)
+		}
+		merged.SigFlags = core.MakeInteger(flags)
+	}
+	if form.CO != nil {
+		if merged.CO == nil {
+			merged.CO = core.MakeArray()
+		}
+		for _, obj := range form.CO.Elements() {
+			if _, ok := widgets[core.ResolveReference(obj)]; ok {
+				merged.CO.Append(obj)
+			}
+		}
+	}
+	if merged.DA == nil {
+		merged.DA = form.DA
+	}
+	if merged.Q == nil {
+		merged.Q = form.Q
+	}
+	if form.DR != nil {
+		if merged.DR == nil {
+			merged.DR = NewPdfPageResources()
+		}
+		mergeResources(merged.DR, form.DR)
+	}
+}
+
+// shareField merges `field` with the top-level merged field `existing` of the same name: both
+// become kids without partial name of a parent field holding the name, and are thus
+// representations of the same field (section 12.7.3.2 p. 434 PDF32000_2008).
+func (ctx *mergeContext) shareField(existing, field *PdfField) {
+	parent := existing
+	if _, ok := ctx.sharedFields[existing]; !ok {
+		parent = NewPdfField()
+		parent.T = existing.T
+		parent.FT = existing.FT
+		parent.Ff = existing.Ff
+		parent.V = existing.V
+		parent.DV = existing.DV
+		ctx.sharedFields[parent] = struct{}{}
+		ctx.fields[existing.T.Decoded()] = parent
+
+		for i, f := range *ctx.form.Fields {
+			if f == existing {
+				(*ctx.form.Fields)[i] = parent
+				break
+			}
+		}
+		adoptField(parent, existing)
+	}
+	adoptField(parent, field)
+}
+
+// adoptField makes `field` a kid of `parent`, removing its partial name.
+func adoptField(parent, field *PdfField) {
+	field.T = nil
+	if dict, ok := core.GetDict(field.container); ok {
+		dict.Remove("T")
+	}
+	field.Parent = parent
+	parent.Kids = append(parent.Kids, field)
+}
+
+// pruneField removes the widget annotations of `field` and its descendants which are not in
+// `widgets`. Returns false if the field does not have any widget annotations left.
+func pruneField(field *PdfField, widgets map[core.PdfObject]struct{}) bool {
+	var kids []*PdfField
+	for _, kid := range field.Kids {
+		if pruneField(kid, widgets) {
+			kids = append(kids, kid)
+		}
+	}
+	var annotations []*PdfAnnotationWidget
+	for _, annot := range field.Annotations {
+		if _, ok := widgets[annot.container]; ok {
+			annotations = append(annotations, annot)
+		}
+	}
+	if len(kids) == len(field.Kids) && len(annotations) == len(field.Annotations) {
+		return len(kids) > 0 || len(annotations) > 0
+	}
+
+	field.Kids = kids
+	field.Annotations = annotations
+	if dict, ok := core.GetDict(field.container); ok && len(kids) == 0 && len(annotations) == 0 {
+		dict.Remove("Kids")
+	}
+	return len(kids) > 0 || len(annotations) > 0
+}
+
+// mergeResources adds the resources of `src` to `dst`. Resources with a name already used in `dst`
+// are not added.
+func mergeResources(dst, src *PdfPageResources) {
+	merge := func(dstObj, srcObj core.PdfObject) core.PdfObject {
+		srcDict, ok := core.GetDict(srcObj)
+		if !ok {
+			return dstObj
+		}
+		dstDict, ok := core.GetDict(dstObj)
+		if !ok {
+			dstDict = core.MakeDict()
+		}
+		for _, key := range srcDict.Keys() {
+			if existing := dstDict.Get(key); existing != nil {
+				if core.ResolveReference(existing) != core.ResolveReference(srcDict.Get(key)) {
+					common.Log.Debug("Form resource %s already defined - skipping", key)
+				}
+				continue
+			}
+			dstDict.Set(key, srcDict.Get(key))
+		}
+		return dstDict
+	}
+	dst.ExtGState = merge(dst.ExtGState, src.ExtGState)
+	dst.ColorSpace = merge(dst.ColorSpace, src.ColorSpace)
+	dst.Pattern = merge(dst.Pattern, src.Pattern)
+	dst.Shading = merge(dst.Shading, src.Shading)
+	dst.XObject = merge(dst.XObject, src.XObject)
+	dst.Font = merge(dst.Font, src.Font)
+	dst.Properties = merge(dst.Properties, src.Properties)
+	if dst.ProcSet == nil {
+		dst.ProcSet = src.ProcSet
+	}
+}
+
+// mergeOutlines adds an outline item for `doc` targeting its first page, with the outline items of
+// the document as children.
+func (ctx *mergeContext) mergeOutlines(doc *mergeDocument) {
+	root := NewPdfOutlineItem()
+	root.Title = core.MakeTextString(doc.title)
+	if len(doc.pages) > 0 {
+		root.Dest = core.MakeArray(doc.pages[0].GetPageAsIndirectObject(), core.MakeName("Fit"))
+	}
+
+	if tree := doc.reader.GetOutlineTree(); tree != nil {
+		ctx.copyOutlineItems(tree.First, &root.PdfOutlineTreeNode, nil)
+	}
+	count := countOpenOutlineItems(&root.PdfOutlineTreeNode)
+	root.Count = &count
+
+	appendOutlineItem(&ctx.outline.PdfOutlineTreeNode, ctx.lastItem, root)
+	ctx.lastItem = root
+}
+
+// copyOutlineItems appends copies of the outline item `node` and its next siblings, with their
+// descendants, to `parent` after its last child `last`. Returns the last child of `parent`.
+func (ctx *mergeContext) copyOutlineItems(node, parent *PdfOutlineTreeNode, last *PdfOutlineItem) *PdfOutlineItem {
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	for node != nil {
+		if _, ok := visited[node]; ok {
+			break
+		}
+		visited[node] = struct{}{}
+		item, ok := node.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+
+		if copied := ctx.copyOutlineItem(item); copied != nil {
+			appendOutlineItem(parent, last, copied)
+			last = copied
+		}
+		node = item.Next
+	}
+	return last
+}
+
+// copyOutlineItem returns a copy of outline item `item` with its descendants. The destinations
+// of the items are remapped to the merged document. When pruning outlines, returns nil if neither
+// the item nor its descendants target merged pages.
+func (ctx *mergeContext) copyOutlineItem(item *PdfOutlineItem) *PdfOutlineItem {
+	copied := NewPdfOutlineItem()
+	copied.Title = item.Title
+	copied.C = item.C
+	copied.F = item.F
+
+	hasTarget := false
+	if item.Dest != nil {
+		if dest, ok := ctx.remapDest(item.Dest); ok {
+			copied.Dest = dest
+			hasTarget = true
+		}
+	}
+	if item.A != nil {
+		if action, ok := ctx.remapAction(item.A); ok {
+			copied.A = action
+			hasTarget = true
+		}
+	}
+	ctx.copyOutlineItems(item.First, &copied.PdfOutlineTreeNode, nil)
+	if ctx.pruneOutlines && !hasTarget && copied.First == nil {
+		return nil
+	}
+
+	// The number of visible descendants may change, as items are pruned.
+	if copied.First != nil {
+		count := countOpenOutlineItems(&copied.PdfOutlineTreeNode)
+		if item.Count != nil && *item.Count < 0 {
+			count = -count
+		}
+		copied.Count = &count
+	}
+	return copied
+}
+
+// appendOutlineItem appends `item` to the children of `parent`, after its last child `last`.
+func appendOutlineItem(parent *PdfOutlineTreeNode, last *PdfOutlineItem, item *PdfOutlineItem) {
+	item.Parent = parent
+	if last == nil {
+		parent.First = &item.PdfOutlineTreeNode
+	} else {
+		last.Next = &item.PdfOutlineTreeNode
+		item.Prev = &last.PdfOutlineTreeNode
+	}
+	parent.Last = &item.PdfOutlineTreeNode
+}
+
+// countOpenOutlineItems returns the number of visible descendants of the outline node `node`,
+// i.e. its children and the visible descendants of its open children (positive Count).
+func countOpenOutlineItems(node *PdfOutlineTreeNode) int64 {
+	var count int64
+	for child := node.First; child != nil; {
+		item, ok := child.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+		count++
+		if item.Count != nil && *item.Count > 0 {
+			count += *item.Count
+		}
+		child = item.Next
+	}
+	return count
+}
+
+// readerTitle returns the title of the document loaded by `reader` from the document information
+// dictionary, or an empty string if not available.
+func readerTitle(reader *PdfReader) string {
+	trailer, err := reader.GetTrailer()
+	if err != nil {
+		return ""
+	}
+	info, ok := core.GetDict(trailer.Get("Info"))
+	if !ok {
+		return ""
+	}
+	title, ok := core.GetString(info.Get("Title"))
+	if !ok {
+		return ""
+	}
+	return title.Decoded()
+}
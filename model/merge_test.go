@@ -0,0 +1,235 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// makeMergeTestDocument returns a 3 page document with an outline, a named destination "p2" to the
+// second page, a link on the first page to the third page and a link on the second page to "p2".
+func makeMergeTestDocument(t *testing.T) *PdfReader {
+	w := NewPdfWriter()
+	var pages []*PdfPage
+	for i := 0; i < 3; i++ {
+		page := newTestPage(t, 200, 200, "")
+		pages = append(pages, page)
+	}
+
+	link := NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+	link.Dest = core.MakeArray(pages[2].GetPageAsIndirectObject(), core.MakeName("Fit"))
+	pages[0].AddAnnotation(link.PdfAnnotation)
+
+	goTo := core.MakeDict()
+	goTo.Set("S", core.MakeName("GoTo"))
+	goTo.Set("D", core.MakeString("p2"))
+	link = NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+	link.A = goTo
+	pages[1].AddAnnotation(link.PdfAnnotation)
+
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+
+	dests := NewPdfNameTree()
+	dests.Set("p2", core.MakeArray(pages[1].GetPageAsIndirectObject(), core.MakeName("Fit")))
+	require.NoError(t, w.SetNameTree("Dests", dests))
+
+	outline := NewOutline()
+	outline.Add(NewOutlineItem("First", NewOutlineDest(0, 0, 200)))
+	outline.Add(NewOutlineItem("Last", NewOutlineDest(2, 0, 200)))
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+
+	return writeRead(t, &w)
+}
+
+// getOutlineTitles returns the titles of the outline items of `node` and its descendants.
+func getOutlineTitles(node *PdfOutlineTreeNode) []string {
+	var titles []string
+	for child := node.First; child != nil; {
+		item := child.context.(*PdfOutlineItem)
+		titles = append(titles, item.Title.Decoded())
+		titles = append(titles, getOutlineTitles(child)...)
+		child = item.Next
+	}
+	return titles
+}
+
+func TestMergeDocuments(t *testing.T) {
+	docB := makeMergeTestDocument(t)
+	page, err := docB.GetPage(2)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	goTo, ok := core.GetDict(annotations[0].GetContext().(*PdfAnnotationLink).A)
+	require.True(t, ok)
+
+	merger := NewPdfMerger()
+	require.NoError(t, merger.AddDocument(makeMergeTestDocument(t), "A"))
+	require.NoError(t, merger.AddPages(docB, "Bücher", []int{2, 1}))
+
+	r := writeRead(t, merger)
+
+	numPages, err := r.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 5, numPages)
+	var pageObjs []core.PdfObject
+	for i := 1; i <= numPages; i++ {
+		page, err := r.GetPage(i)
+		require.NoError(t, err)
+		pageObjs = append(pageObjs, page.GetPageAsIndirectObject())
+	}
+
+	// Named destinations: "p2" of the second document is renamed.
+	dests, err := r.GetNameTree("Dests")
+	require.NoError(t, err)
+	require.Equal(t, []string{"p2", "p2_2"}, dests.Keys())
+	dest, _ := dests.Get("p2_2")
+	arr, ok := core.GetArray(dest)
+	require.True(t, ok)
+	require.Equal(t, pageObjs[3], core.ResolveReference(arr.Get(0)))
+
+	// Links: the link to the third page of the second document is removed, the GoTo action to
+	// the named destination is renamed.
+	getLinks := func(pageNum int) []*PdfAnnotationLink {
+		page, err := r.GetPage(pageNum)
+		require.NoError(t, err)
+		annotations, err := page.GetAnnotations()
+		require.NoError(t, err)
+		var links []*PdfAnnotationLink
+		for _, annot := range annotations {
+			links = append(links, annot.GetContext().(*PdfAnnotationLink))
+		}
+		return links
+	}
+	links := getLinks(1)
+	require.Len(t, links, 1)
+	arr, ok = core.GetArray(links[0].Dest)
+	require.True(t, ok)
+	require.Equal(t, pageObjs[2], core.ResolveReference(arr.Get(0)))
+	require.Len(t, getLinks(5), 0)
+
+	links = getLinks(4)
+	require.Len(t, links, 1)
+	action, ok := core.GetDict(links[0].A)
+	require.True(t, ok)
+	name, ok := core.GetString(action.Get("D"))
+	require.True(t, ok)
+	require.Equal(t, "p2_2", name.Str())
+
+	// The actions of the merged documents are not modified.
+	name, ok = core.GetString(goTo.Get("D"))
+	require.True(t, ok)
+	require.Equal(t, "p2", name.Str())
+
+	// Outlines.
+	outline := r.GetOutlineTree()
+	require.NotNil(t, outline)
+	require.Equal(t, []string{"A", "First", "Last", "Bücher", "First", "Last"}, getOutlineTitles(outline))
+	b := outline.Last.context.(*PdfOutlineItem)
+	arr, ok = core.GetArray(b.Dest)
+	require.True(t, ok)
+	require.Equal(t, pageObjs[3], core.ResolveReference(arr.Get(0)))
+	last := b.Last.context.(*PdfOutlineItem)
+	require.Nil(t, last.Dest)
+}
+
+func TestMergeForms(t *testing.T) {
+	loadForm := func() *PdfReader {
+		f, err := os.Open("./testdata/OoPdfFormExample.pdf")
+		require.NoError(t, err)
+		defer f.Close()
+		r, err := NewPdfReader(f)
+		require.NoError(t, err)
+		require.NotNil(t, r.AcroForm)
+		return r
+	}
+
+	merge := func(rename bool) *PdfReader {
+		merger := NewPdfMerger()
+		merger.RenameConflictingFields = rename
+		require.NoError(t, merger.AddDocument(loadForm(), ""))
+		require.NoError(t, merger.AddDocument(loadForm(), ""))
+
+		r := writeRead(t, merger)
+		require.NotNil(t, r.AcroForm)
+		return r
+	}
+
+	numFields := len(*loadForm().AcroForm.Fields)
+	require.NotZero(t, numFields)
+
+	r := merge(true)
+	require.Len(t, *r.AcroForm.Fields, 2*numFields)
+	names := map[string]struct{}{}
+	for _, field := range *r.AcroForm.Fields {
+		names[field.T.Decoded()] = struct{}{}
+	}
+	require.Len(t, names, 2*numFields)
+	require.NotNil(t, r.AcroForm.DR)
+
+	// Fields with the same name are merged as the kids of a single field.
+	r = merge(false)
+	require.Len(t, *r.AcroForm.Fields, numFields)
+	names = map[string]struct{}{}
+	for _, field := range *r.AcroForm.Fields {
+		names[field.T.Decoded()] = struct{}{}
+		// The kids are loaded as widget annotations, their field entries being merged.
+		require.Len(t, field.Kids, 0)
+		require.Len(t, field.Annotations, 2)
+		for _, widget := range field.Annotations {
+			dict, ok := core.GetDict(widget.container)
+			require.True(t, ok)
+			require.Nil(t, dict.Get("T"))
+		}
+	}
+	require.Len(t, names, numFields)
+}
+
+func TestMergeFormsRenameTaken(t *testing.T) {
+	// makeForm returns a one page document with text fields named `names`.
+	makeForm := func(names ...string) *PdfReader {
+		w := NewPdfWriter()
+		page := newTestPage(t, 200, 200, "")
+		form := NewPdfAcroForm()
+		form.Fields = &[]*PdfField{}
+		for i, name := range names {
+			field := NewPdfField()
+			field.T = core.MakeString(name)
+			field.FT = core.MakeName("Tx")
+			widget := NewPdfAnnotationWidget()
+			y := float64(10 + 30*i)
+			widget.Rect = core.MakeArrayFromFloats([]float64{10, y, 100, y + 20})
+			widget.Parent = field.GetContainingPdfObject()
+			field.Annotations = []*PdfAnnotationWidget{widget}
+			page.AddAnnotation(widget.PdfAnnotation)
+			*form.Fields = append(*form.Fields, field)
+		}
+		require.NoError(t, w.AddPage(page))
+		require.NoError(t, w.SetForms(form))
+		return writeRead(t, &w)
+	}
+
+	merger := NewPdfMerger()
+	merger.RenameConflictingFields = true
+	require.NoError(t, merger.AddDocument(makeForm("x"), ""))
+	require.NoError(t, merger.AddDocument(makeForm("x", "x_2"), ""))
+	r := writeRead(t, merger)
+	require.NotNil(t, r.AcroForm)
+
+	var names []string
+	for _, field := range *r.AcroForm.Fields {
+		names = append(names, field.T.Decoded())
+	}
+	require.Equal(t, []string{"x", "x_3", "x_2"}, names)
+}
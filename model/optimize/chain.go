@@ -32,3 +32,13 @@ func (c *Chain) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 	}
 	return optimizedObjects, nil
 }
+
+// SetTrailer sets the trailer of the document of the optimizers of the chain which need it.
+// It implements interface model.TrailerOptimizer.
+func (c *Chain) SetTrailer(trailer *core.PdfObjectDictionary) {
+	for _, optimizer := range c.optimizers {
+		if optimizer, ok := optimizer.(model.TrailerOptimizer); ok {
+			optimizer.SetTrailer(trailer)
+		}
+	}
+}
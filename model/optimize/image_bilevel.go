@@ -0,0 +1,203 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/ccittfax"
+	"github.com/unidoc/unipdf/v3/internal/jbig2/encoder"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxMidtones is the maximum fraction of the samples of near-bilevel grayscale images that are
+// neither almost black nor almost white, i.e. in [0x40, 0xC0).
+const maxMidtones = 0.01
+
+// BilevelImage optimizes bilevel images by re-encoding them with the CCITT Group 4 (K=-1) or
+// the JBIG2 generic region compression, whichever is smaller. The 1 bit per component images with
+// a single color component are re-encoded losslessly; the near-bilevel 8 bit DeviceGray images
+// are optionally thresholded to bilevel images. Only image XObjects are optimized: the inline
+// images of the content streams are left as is.
+// It implements interface model.Optimizer.
+type BilevelImage struct {
+	// GrayThreshold, in (0, 1), is the threshold of the samples of the near-bilevel grayscale
+	// images converted to bilevel images: the samples below GrayThreshold*255 become black.
+	// The grayscale images are not converted if 0.
+	GrayThreshold float64
+
+	// DryRun disables the replacement of the images: only Estimate is computed.
+	DryRun bool
+
+	// Estimate is the size estimate of the last optimization.
+	Estimate BilevelEstimate
+}
+
+// BilevelEstimate is the size estimate of the optimization of the bilevel images.
+type BilevelEstimate struct {
+	Images        int // Number of images re-encoded.
+	OriginalSize  int // Size of the original image streams.
+	OptimizedSize int // Size of the re-encoded image streams.
+}
+
+// Optimize optimizes PDF objects to decrease PDF size.
+func (b *BilevelImage) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	b.Estimate = BilevelEstimate{}
+	optimizedObjects = make([]core.PdfObject, len(objects))
+	copy(optimizedObjects, objects)
+
+	var images []*core.PdfObjectStream
+	processed := make(map[*core.PdfObjectStream]struct{})
+	softMasks := make(map[*core.PdfObjectStream]struct{})
+	for _, obj := range objects {
+		stream, ok := core.GetStream(obj)
+		if !ok {
+			continue
+		}
+		if _, found := processed[stream]; found {
+			continue
+		}
+		processed[stream] = struct{}{}
+		if subtype, ok := core.GetName(stream.Get("Subtype")); !ok || *subtype != "Image" {
+			continue
+		}
+		images = append(images, stream)
+		if smask, ok := core.GetStream(stream.Get("SMask")); ok {
+			softMasks[smask] = struct{}{}
+		}
+	}
+
+	for _, stream := range images {
+		_, isSoftMask := softMasks[stream]
+		data, width, height, thresholded := b.bilevelData(stream, isSoftMask)
+		if data == nil {
+			continue
+		}
+		encoded, enc := encodeBilevel(data, width, height)
+		if len(encoded) >= len(stream.Stream) {
+			continue
+		}
+		b.Estimate.Images++
+		b.Estimate.OriginalSize += len(stream.Stream)
+		b.Estimate.OptimizedSize += len(encoded)
+		if b.DryRun {
+			continue
+		}
+
+		stream.Stream = encoded
+		stream.Remove("DecodeParms")
+		stream.PdfObjectDictionary.Merge(enc.MakeStreamDict())
+		if thresholded {
+			stream.Set("BitsPerComponent", core.MakeInteger(1))
+		}
+		stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+	}
+	return optimizedObjects, nil
+}
+
+// bilevelData returns the bilevel data of image `stream`, with rows padded to byte boundaries and
+// 1 bits for white, its size and true if its grayscale samples were thresholded. The returned data
+// is nil if the image is not bilevel or cannot be decoded. Soft masks are not thresholded.
+func (b *BilevelImage) bilevelData(stream *core.PdfObjectStream, isSoftMask bool) (
+	data []byte, width, height int, thresholded bool) {
+	width, _ = core.GetIntVal(stream.Get("Width"))
+	height, _ = core.GetIntVal(stream.Get("Height"))
+	if width <= 0 || height <= 0 {
+		return nil, 0, 0, false
+	}
+
+	bpc, numComponents := 1, 1
+	isGray := false
+	if imageMask, _ := core.GetBoolVal(stream.Get("ImageMask")); !imageMask {
+		bpc, _ = core.GetIntVal(stream.Get("BitsPerComponent"))
+		csObj := stream.Get("ColorSpace")
+		if csObj == nil {
+			return nil, 0, 0, false
+		}
+		cs, err := model.NewPdfColorspaceFromPdfObject(csObj)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid image colorspace: %v", err)
+			return nil, 0, 0, false
+		}
+		numComponents = cs.GetNumComponents()
+		_, isGray = cs.(*model.PdfColorspaceDeviceGray)
+	}
+	if numComponents != 1 {
+		return nil, 0, 0, false
+	}
+	threshold := bpc == 8 && isGray && !isSoftMask && b.GrayThreshold > 0 && b.GrayThreshold < 1
+	if _, hasColorKey := core.GetArray(stream.Get("Mask")); hasColorKey {
+		// The color key ranges are not valid for the thresholded samples.
+		threshold = false
+	}
+	if bpc != 1 && !threshold {
+		return nil, 0, 0, false
+	}
+
+	decoded, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode image: %v", err)
+		return nil, 0, 0, false
+	}
+	rowStride := (width + 7) / 8
+	if bpc == 1 {
+		if len(decoded) < rowStride*height {
+			return nil, 0, 0, false
+		}
+		return decoded[:rowStride*height], width, height, false
+	}
+
+	if len(decoded) < width*height {
+		return nil, 0, 0, false
+	}
+	midtones := 0
+	for _, v := range decoded[:width*height] {
+		if v >= 0x40 && v < 0xC0 {
+			midtones++
+		}
+	}
+	if float64(midtones) > maxMidtones*float64(width*height) {
+		return nil, 0, 0, false
+	}
+	level := byte(b.GrayThreshold*255 + 0.5)
+	data = make([]byte, rowStride*height)
+	for y := 0; y < height; y++ {
+		for x, v := range decoded[y*width : (y+1)*width] {
+			if v >= level {
+				data[y*rowStride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return data, width, height, true
+}
+
+// encodeBilevel returns bilevel image `data` of `width` x `height` pixels, with rows padded to byte
+// boundaries and 1 bits for white, encoded with the CCITT Group 4 or JBIG2 compression, whichever
+// is smaller, and the encoder.
+func encodeBilevel(data []byte, width, height int) ([]byte, core.StreamEncoder) {
+	rowStride := (width + 7) / 8
+	pixels := make([][]byte, height)
+	for y := range pixels {
+		row := make([]byte, width)
+		for x := range row {
+			row[x] = (data[y*rowStride+x/8] >> uint(7-x%8)) & 1
+		}
+		pixels[y] = row
+	}
+	ccitt := &core.CCITTFaxEncoder{K: -1, Columns: width, Rows: height}
+	g4 := (&ccittfax.Encoder{K: ccitt.K, Columns: width, Rows: height}).Encode(pixels)
+
+	// The JBIG2 1 bits are black.
+	inverted := make([]byte, len(data))
+	for i, v := range data {
+		inverted[i] = ^v
+	}
+	jbig2, err := encoder.EncodeGeneric(inverted, width, height)
+	if err != nil || len(g4) <= len(jbig2) {
+		return g4, ccitt
+	}
+	return jbig2, core.NewJBIG2Encoder()
+}
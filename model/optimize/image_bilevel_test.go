@@ -0,0 +1,90 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model/optimize"
+)
+
+// makeImageStream returns an uncompressed DeviceGray image stream.
+func makeImageStream(width, height, bpc int, data []byte) *core.PdfObjectStream {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("XObject"))
+	dict.Set("Subtype", core.MakeName("Image"))
+	dict.Set("Width", core.MakeInteger(int64(width)))
+	dict.Set("Height", core.MakeInteger(int64(height)))
+	dict.Set("BitsPerComponent", core.MakeInteger(int64(bpc)))
+	dict.Set("ColorSpace", core.MakeName("DeviceGray"))
+	dict.Set("Length", core.MakeInteger(int64(len(data))))
+	return &core.PdfObjectStream{PdfObjectDictionary: dict, Stream: data}
+}
+
+func TestBilevelImage(t *testing.T) {
+	// 64x64 bilevel image: white with a black square.
+	width, height := 64, 64
+	bilevel := make([]byte, 8*height)
+	gray := make([]byte, width*height)
+	photo := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x >= 16 && x < 32 && y >= 16 && y < 48 {
+				gray[y*width+x] = 0x10
+				continue
+			}
+			bilevel[y*8+x/8] |= 0x80 >> uint(x%8)
+			gray[y*width+x] = 0xF0
+			photo[y*width+x] = byte(4 * x)
+		}
+	}
+	bilevelStream := makeImageStream(width, height, 1, append([]byte(nil), bilevel...))
+	grayStream := makeImageStream(width, height, 8, gray)
+	photoStream := makeImageStream(width, height, 8, photo)
+	objects := []core.PdfObject{bilevelStream, grayStream, photoStream}
+
+	// Dry run.
+	optimizer := &optimize.BilevelImage{GrayThreshold: 0.5, DryRun: true}
+	_, err := optimizer.Optimize(objects)
+	require.NoError(t, err)
+	require.Equal(t, 2, optimizer.Estimate.Images)
+	require.Equal(t, len(bilevel)+len(gray), optimizer.Estimate.OriginalSize)
+	require.True(t, optimizer.Estimate.OptimizedSize < 200, "size: %d", optimizer.Estimate.OptimizedSize)
+	require.Equal(t, bilevel, bilevelStream.Stream)
+
+	optimizer.DryRun = false
+	_, err = optimizer.Optimize(objects)
+	require.NoError(t, err)
+	for _, stream := range []*core.PdfObjectStream{bilevelStream, grayStream} {
+		filter, ok := core.GetName(stream.Get("Filter"))
+		require.True(t, ok)
+		require.Contains(t, []string{core.StreamEncodingFilterNameCCITTFax, core.StreamEncodingFilterNameJBIG2},
+			filter.String())
+		bpc, _ := core.GetIntVal(stream.Get("BitsPerComponent"))
+		require.Equal(t, 1, bpc)
+		length, _ := core.GetIntVal(stream.Get("Length"))
+		require.Equal(t, len(stream.Stream), length)
+
+		decoded, err := core.DecodeStream(stream)
+		require.NoError(t, err)
+		require.Equal(t, bilevel, decoded)
+	}
+
+	// The photo is not near-bilevel.
+	require.Nil(t, photoStream.Get("Filter"))
+	require.Equal(t, photo, photoStream.Stream)
+
+	// Grayscale images are not thresholded by default.
+	grayStream = makeImageStream(width, height, 8, gray)
+	optimizer = &optimize.BilevelImage{}
+	_, err = optimizer.Optimize([]core.PdfObject{grayStream})
+	require.NoError(t, err)
+	require.Equal(t, 0, optimizer.Estimate.Images)
+	require.Equal(t, gray, grayStream.Stream)
+}
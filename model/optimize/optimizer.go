@@ -12,6 +12,10 @@ import (
 // New creates a optimizers chain from options.
 func New(options Options) *Chain {
 	chain := new(Chain)
+	if options.RemoveUnusedObjects {
+		// Removing the unused objects first avoids optimizing them.
+		chain.Append(new(RemoveUnusedObjects))
+	}
 	if options.ImageUpperPPI > 0 {
 		imageOptimizer := new(ImagePPI)
 		imageOptimizer.ImageUpperPPI = options.ImageUpperPPI
@@ -22,6 +26,11 @@ func New(options Options) *Chain {
 		imageOptimizer.ImageQuality = options.ImageQuality
 		chain.Append(imageOptimizer)
 	}
+	if options.BilevelImages {
+		bilevelOptimizer := new(BilevelImage)
+		bilevelOptimizer.GrayThreshold = options.BilevelGrayThreshold
+		chain.Append(bilevelOptimizer)
+	}
 	if options.CombineDuplicateDirectObjects {
 		chain.Append(new(CombineDuplicateDirectObjects))
 	}
@@ -14,4 +14,15 @@ type Options struct {
 	UseObjectStreams                bool
 	CombineIdenticalIndirectObjects bool
 	CompressStreams                 bool
+
+	// RemoveUnusedObjects enables the removal of the resources not used by the content streams
+	// and of the unreachable objects (see RemoveUnusedObjects).
+	RemoveUnusedObjects bool
+
+	// BilevelImages enables the re-encoding of the bilevel images with the CCITT Group 4 or JBIG2
+	// compression (see BilevelImage).
+	BilevelImages bool
+	// BilevelGrayThreshold, if in (0, 1), converts the near-bilevel grayscale images to bilevel
+	// images with this threshold when BilevelImages is set.
+	BilevelGrayThreshold float64
 }
@@ -0,0 +1,516 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"bytes"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// resourceCategories are the categories of named resources pruned from the resource dictionaries.
+var resourceCategories = []core.PdfObjectName{
+	"ExtGState", "ColorSpace", "Pattern", "Shading", "XObject", "Font", "Properties",
+}
+
+// RemoveUnusedObjects removes the resources not used by the content streams from the resource
+// dictionaries, and then the objects which are not reachable from the trailer.
+// The content streams of the pages, of their annotation appearances, and of the form XObjects,
+// tiling patterns, soft mask groups and Type 3 fonts they use are scanned. The resource
+// dictionaries of the content streams which cannot be parsed are kept as is, and so are the
+// default resources of the interactive form (AcroForm DR) and the property lists of the optional
+// content groups of the document (OCProperties) or of the annotations.
+// Nothing is removed unless the trailer is set (see model.TrailerOptimizer): the trailer is not
+// set when the objects are not the whole document, e.g. in the incremental updates of
+// model.PdfAppender.
+// It implements interface model.Optimizer.
+type RemoveUnusedObjects struct {
+	trailer *core.PdfObjectDictionary
+}
+
+// SetTrailer sets the trailer of the document, whose entries are the roots of the reachable
+// objects, for the next optimization.
+// It implements interface model.TrailerOptimizer.
+func (r *RemoveUnusedObjects) SetTrailer(trailer *core.PdfObjectDictionary) {
+	r.trailer = trailer
+}
+
+// Optimize optimizes PDF objects to decrease PDF size.
+func (r *RemoveUnusedObjects) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	trailer := r.trailer
+	r.trailer = nil
+	if trailer == nil {
+		optimizedObjects = make([]core.PdfObject, len(objects))
+		copy(optimizedObjects, objects)
+		return optimizedObjects, nil
+	}
+	catalog, _ := core.GetDict(trailer.Get("Root"))
+	pruneResources(objects, catalog)
+
+	reachable := make(map[core.PdfObject]struct{})
+	markReachable(trailer, reachable)
+	for _, obj := range objects {
+		keep := false
+		switch t := obj.(type) {
+		case *core.PdfObjectStreams:
+			for _, elem := range t.Elements() {
+				if _, found := reachable[elem]; found {
+					keep = true
+					break
+				}
+			}
+		default:
+			_, keep = reachable[obj]
+		}
+		if keep {
+			optimizedObjects = append(optimizedObjects, obj)
+		}
+	}
+	return optimizedObjects, nil
+}
+
+// markReachable adds `obj` and the objects reachable from it to `reachable`.
+func markReachable(obj core.PdfObject, reachable map[core.PdfObject]struct{}) {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		if resolved := t.Resolve(); resolved != nil && resolved != obj {
+			markReachable(resolved, reachable)
+		}
+	case *core.PdfIndirectObject:
+		if _, found := reachable[t]; found {
+			return
+		}
+		reachable[t] = struct{}{}
+		markReachable(t.PdfObject, reachable)
+	case *core.PdfObjectStream:
+		if _, found := reachable[t]; found {
+			return
+		}
+		reachable[t] = struct{}{}
+		markReachable(t.PdfObjectDictionary, reachable)
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			markReachable(t.Get(key), reachable)
+		}
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			markReachable(elem, reachable)
+		}
+	}
+}
+
+// categoryUsage is the usage of the resources of a category dictionary (e.g. a Font dictionary),
+// which may be shared by several resource dictionaries.
+type categoryUsage struct {
+	category core.PdfObjectName
+	// used are the names of the resources used through any of the resource dictionaries.
+	used map[core.PdfObjectName]struct{}
+	// keep is true if the resources must be kept, e.g. because a content stream using them cannot
+	// be parsed.
+	keep bool
+	// parents are the resource dictionaries holding the category dictionary.
+	parents map[*core.PdfObjectDictionary]struct{}
+}
+
+// resourceScanner finds the resources used by content streams.
+type resourceScanner struct {
+	// usage is the usage of the category dictionaries of the scanned resource dictionaries.
+	usage map[*core.PdfObjectDictionary]*categoryUsage
+	// registered are the resource dictionaries whose category dictionaries are in usage.
+	registered map[*core.PdfObjectDictionary]struct{}
+	// scanned are the content streams scanned with each resource dictionary.
+	scanned map[core.PdfObject]map[*core.PdfObjectDictionary]struct{}
+	// kept are the dictionaries which are not pruned: the default resources of the interactive
+	// form and their categories.
+	kept map[*core.PdfObjectDictionary]struct{}
+	// ocgs are the optional content groups and membership dictionaries of the document and of
+	// the annotations, whose property list resources are kept.
+	ocgs map[*core.PdfObjectDictionary]struct{}
+}
+
+// pruneResources removes the unused resources from the resource dictionaries of the content
+// streams reachable from the pages of `objects`, keeping those reachable from document catalog
+// `catalog` through the interactive form and the optional content properties.
+// The category dictionaries may be shared by several resource dictionaries, so they are only
+// pruned once all the content streams are scanned, keeping the resources used through any of
+// them.
+func pruneResources(objects []core.PdfObject, catalog *core.PdfObjectDictionary) {
+	s := &resourceScanner{
+		usage:      make(map[*core.PdfObjectDictionary]*categoryUsage),
+		registered: make(map[*core.PdfObjectDictionary]struct{}),
+		scanned:    make(map[core.PdfObject]map[*core.PdfObjectDictionary]struct{}),
+		kept:       make(map[*core.PdfObjectDictionary]struct{}),
+		ocgs:       make(map[*core.PdfObjectDictionary]struct{}),
+	}
+	s.markCatalog(catalog)
+	for _, obj := range objects {
+		page, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		if name, ok := core.GetName(page.Get("Type")); !ok || *name != "Page" {
+			continue
+		}
+		resources := inheritedResources(page)
+		if resources == nil {
+			continue
+		}
+		s.scanPage(page, resources)
+	}
+
+	for dict, usage := range s.usage {
+		if _, kept := s.kept[dict]; usage.keep || kept {
+			continue
+		}
+		for _, name := range dict.Keys() {
+			if _, used := usage.used[name]; used {
+				continue
+			}
+			if ocg, ok := core.GetDict(dict.Get(name)); ok && usage.category == "Properties" {
+				if _, found := s.ocgs[ocg]; found {
+					continue
+				}
+			}
+			common.Log.Trace("Removing unused resource %s %s", usage.category, name)
+			dict.Remove(name)
+		}
+		if len(dict.Keys()) > 0 {
+			continue
+		}
+		for resources := range usage.parents {
+			if _, kept := s.kept[resources]; !kept {
+				resources.Remove(usage.category)
+			}
+		}
+	}
+}
+
+// markCatalog marks the default resources of the interactive form of document catalog `catalog`
+// as kept, and adds the optional content groups of the document to the kept property lists.
+func (s *resourceScanner) markCatalog(catalog *core.PdfObjectDictionary) {
+	if catalog == nil {
+		return
+	}
+	if acroForm, ok := core.GetDict(catalog.Get("AcroForm")); ok {
+		if dr, ok := core.GetDict(acroForm.Get("DR")); ok {
+			s.kept[dr] = struct{}{}
+			for _, category := range resourceCategories {
+				if dict, ok := core.GetDict(dr.Get(category)); ok {
+					s.kept[dict] = struct{}{}
+				}
+			}
+		}
+	}
+	if ocProperties, ok := core.GetDict(catalog.Get("OCProperties")); ok {
+		if ocgs, ok := core.GetArray(ocProperties.Get("OCGs")); ok {
+			for _, ocg := range ocgs.Elements() {
+				s.markOC(ocg)
+			}
+		}
+	}
+}
+
+// markOC adds optional content group or membership dictionary `obj`, and the groups of the
+// membership dictionary, to the kept property lists.
+func (s *resourceScanner) markOC(obj core.PdfObject) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if _, found := s.ocgs[dict]; found {
+		return
+	}
+	s.ocgs[dict] = struct{}{}
+	switch t := core.TraceToDirectObject(dict.Get("OCGs")).(type) {
+	case *core.PdfObjectDictionary:
+		s.markOC(t)
+	case *core.PdfObjectArray:
+		for _, ocg := range t.Elements() {
+			s.markOC(ocg)
+		}
+	}
+}
+
+// inheritedResources returns the resource dictionary of `page`, inherited from its ancestors in
+// the page tree if not set.
+func inheritedResources(page *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	visited := make(map[*core.PdfObjectDictionary]struct{})
+	for node := page; node != nil; {
+		if _, found := visited[node]; found {
+			break
+		}
+		visited[node] = struct{}{}
+		if resources, ok := core.GetDict(node.Get("Resources")); ok {
+			return resources
+		}
+		node, _ = core.GetDict(node.Get("Parent"))
+	}
+	return nil
+}
+
+// scanPage scans the contents of `page` with resource dictionary `resources`, and the appearance
+// streams of its annotations.
+func (s *resourceScanner) scanPage(page, resources *core.PdfObjectDictionary) {
+	var contents []*core.PdfObjectStream
+	switch t := core.TraceToDirectObject(page.Get("Contents")).(type) {
+	case *core.PdfObjectStream:
+		contents = append(contents, t)
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			if stream, ok := core.GetStream(elem); ok {
+				contents = append(contents, stream)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	for _, stream := range contents {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode page content stream: %v", err)
+			s.keep(resources)
+			return
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	s.scanContent(buf.Bytes(), resources)
+
+	annots, ok := core.GetArray(page.Get("Annots"))
+	if !ok {
+		return
+	}
+	for _, obj := range annots.Elements() {
+		annot, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		s.markOC(annot.Get("OC"))
+		ap, ok := core.GetDict(annot.Get("AP"))
+		if !ok {
+			continue
+		}
+		for _, key := range ap.Keys() {
+			// The appearances are streams or dictionaries of streams by appearance state.
+			appearance := ap.Get(key)
+			if states, ok := core.GetDict(appearance); ok {
+				for _, state := range states.Keys() {
+					s.scanForm(states.Get(state), nil)
+				}
+				continue
+			}
+			s.scanForm(appearance, nil)
+		}
+	}
+}
+
+// register adds the category dictionaries of `resources` to the scanned ones.
+func (s *resourceScanner) register(resources *core.PdfObjectDictionary) {
+	if _, found := s.registered[resources]; found {
+		return
+	}
+	s.registered[resources] = struct{}{}
+	for _, category := range resourceCategories {
+		dict, ok := core.GetDict(resources.Get(category))
+		if !ok {
+			continue
+		}
+		usage, ok := s.usage[dict]
+		if !ok {
+			usage = &categoryUsage{
+				category: category,
+				used:     make(map[core.PdfObjectName]struct{}),
+				parents:  make(map[*core.PdfObjectDictionary]struct{}),
+			}
+			s.usage[dict] = usage
+		}
+		usage.parents[resources] = struct{}{}
+	}
+}
+
+// keep marks all the resources of `resources` as kept.
+func (s *resourceScanner) keep(resources *core.PdfObjectDictionary) {
+	s.register(resources)
+	for _, category := range resourceCategories {
+		if dict, ok := core.GetDict(resources.Get(category)); ok {
+			s.usage[dict].keep = true
+		}
+	}
+}
+
+// useResource marks the resource named `name` of `category` of `resources` as used and returns
+// it, nil if not found.
+func (s *resourceScanner) useResource(resources *core.PdfObjectDictionary, category,
+	name core.PdfObjectName) core.PdfObject {
+	s.register(resources)
+	dict, ok := core.GetDict(resources.Get(category))
+	if !ok {
+		return nil
+	}
+	s.usage[dict].used[name] = struct{}{}
+	return dict.Get(name)
+}
+
+// scanForm scans the content stream of the form XObject, tiling pattern or Type 3 glyph `obj`
+// with its resource dictionary, or `resources` if it has none.
+func (s *resourceScanner) scanForm(obj core.PdfObject, resources *core.PdfObjectDictionary) {
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		return
+	}
+	if own, ok := core.GetDict(stream.Get("Resources")); ok {
+		resources = own
+	}
+	if resources == nil {
+		return
+	}
+
+	scanned, ok := s.scanned[stream]
+	if !ok {
+		scanned = make(map[*core.PdfObjectDictionary]struct{})
+		s.scanned[stream] = scanned
+	}
+	if _, found := scanned[resources]; found {
+		return
+	}
+	scanned[resources] = struct{}{}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode content stream: %v", err)
+		s.keep(resources)
+		return
+	}
+	s.scanContent(data, resources)
+}
+
+// scanContent marks the resources of `resources` used by content stream `data` as used, and scans
+// the content streams of the resources used.
+func (s *resourceScanner) scanContent(data []byte, resources *core.PdfObjectDictionary) {
+	s.register(resources)
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse content stream: %v", err)
+		s.keep(resources)
+		return
+	}
+
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tf":
+			if len(op.Params) == 2 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					s.scanFont(s.useResource(resources, "Font", *name), resources)
+				}
+			}
+		case "Do":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					xobj := s.useResource(resources, "XObject", *name)
+					if stream, ok := core.GetStream(xobj); ok {
+						if subtype, ok := core.GetName(stream.Get("Subtype")); ok && *subtype == "Form" {
+							s.scanForm(stream, resources)
+						}
+					}
+				}
+			}
+		case "gs":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					s.scanExtGState(s.useResource(resources, "ExtGState", *name), resources)
+				}
+			}
+		case "cs", "CS":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					s.useResource(resources, "ColorSpace", *name)
+				}
+			}
+		case "scn", "SCN":
+			if len(op.Params) > 0 {
+				if name, ok := core.GetName(op.Params[len(op.Params)-1]); ok {
+					pattern := s.useResource(resources, "Pattern", *name)
+					if stream, ok := core.GetStream(pattern); ok {
+						// Tiling pattern.
+						s.scanForm(stream, resources)
+					}
+				}
+			}
+		case "sh":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					s.useResource(resources, "Shading", *name)
+				}
+			}
+		case "BDC", "DP":
+			if len(op.Params) == 2 {
+				if name, ok := core.GetName(op.Params[1]); ok {
+					s.useResource(resources, "Properties", *name)
+				}
+			}
+		case "BI":
+			if len(op.Params) != 1 {
+				continue
+			}
+			if img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage); ok {
+				s.useColorSpaceNames(img.ColorSpace, resources)
+			}
+		}
+	}
+}
+
+// useColorSpaceNames marks the names of inline image colorspace `obj`, e.g. the base of an Indexed
+// colorspace, as used colorspace resources of `resources`.
+func (s *resourceScanner) useColorSpaceNames(obj core.PdfObject, resources *core.PdfObjectDictionary) {
+	switch t := obj.(type) {
+	case *core.PdfObjectName:
+		s.useResource(resources, "ColorSpace", *t)
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			if name, ok := elem.(*core.PdfObjectName); ok {
+				s.useResource(resources, "ColorSpace", *name)
+			}
+		}
+	}
+}
+
+// scanFont scans the glyph procedures of `font` if it is a Type 3 font, with its resource
+// dictionary or `resources` if it has none.
+func (s *resourceScanner) scanFont(font core.PdfObject, resources *core.PdfObjectDictionary) {
+	dict, ok := core.GetDict(font)
+	if !ok {
+		return
+	}
+	if subtype, ok := core.GetName(dict.Get("Subtype")); !ok || *subtype != "Type3" {
+		return
+	}
+	if own, ok := core.GetDict(dict.Get("Resources")); ok {
+		resources = own
+	}
+	charProcs, ok := core.GetDict(dict.Get("CharProcs"))
+	if !ok {
+		return
+	}
+	for _, name := range charProcs.Keys() {
+		s.scanForm(charProcs.Get(name), resources)
+	}
+}
+
+// scanExtGState scans the font and the soft mask group of graphics state parameter dictionary
+// `obj`.
+func (s *resourceScanner) scanExtGState(obj core.PdfObject, resources *core.PdfObjectDictionary) {
+	gs, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if font, ok := core.GetArray(gs.Get("Font")); ok && font.Len() == 2 {
+		s.scanFont(font.Get(0), resources)
+	}
+	if smask, ok := core.GetDict(gs.Get("SMask")); ok {
+		s.scanForm(smask.Get("G"), resources)
+	}
+}
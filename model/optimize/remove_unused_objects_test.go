@@ -0,0 +1,184 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/optimize"
+)
+
+// resourceNames returns the names of the resources of `category` of `resources`.
+func resourceNames(resources *core.PdfObjectDictionary, category core.PdfObjectName) []string {
+	var names []string
+	if dict, ok := core.GetDict(resources.Get(category)); ok {
+		for _, name := range dict.Keys() {
+			names = append(names, name.String())
+		}
+	}
+	return names
+}
+
+func TestRemoveUnusedObjects(t *testing.T) {
+	helvetica, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	courier, err := model.NewStandard14Font(model.CourierName)
+	require.NoError(t, err)
+
+	page := pdftest.NewPage(t, 200, 200, "/GS0 gs BT /F1 10 Tf (Text) Tj ET /Fm0 Do")
+	require.NoError(t, page.Resources.SetFontByName("F2", courier.ToPdfObject()))
+	gs := core.MakeDict()
+	gs.Set("CA", core.MakeFloat(0.5))
+	require.NoError(t, page.Resources.AddExtGState("GS0", core.MakeIndirectObject(gs)))
+	require.NoError(t, page.Resources.AddExtGState("GS1", core.MakeIndirectObject(gs)))
+
+	// Unused image.
+	unused, err := core.MakeStream([]byte("UNUSEDIMAGEDATA"), nil)
+	require.NoError(t, err)
+	unused.Set("Subtype", core.MakeName("Image"))
+	require.NoError(t, page.Resources.SetXObjectByName("Im0", unused))
+
+	// Form with its own resources.
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 200, 200})
+	form.Resources = model.NewPdfPageResources()
+	require.NoError(t, form.Resources.SetFontByName("F1", helvetica.ToPdfObject()))
+	require.NoError(t, form.Resources.SetFontByName("F3", courier.ToPdfObject()))
+	require.NoError(t, form.SetContentStream([]byte("BT /F1 12 Tf (Form) Tj ET"), core.NewRawEncoder()))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm0", form))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	w.SetOptimizer(optimize.New(optimize.Options{RemoveUnusedObjects: true}))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.False(t, bytes.Contains(buf.Bytes(), []byte("UNUSEDIMAGEDATA")))
+	require.False(t, bytes.Contains(buf.Bytes(), []byte("Courier")))
+
+	outPage, err := pdftest.Read(t, buf.Bytes()).GetPage(1)
+	require.NoError(t, err)
+	resources, ok := core.GetDict(outPage.Resources.ToPdfObject())
+	require.True(t, ok)
+	// The unlicensed copies add a watermark with its own font.
+	fonts := resourceNames(resources, "Font")
+	require.Contains(t, fonts, "F1")
+	require.NotContains(t, fonts, "F2")
+	require.Equal(t, []string{"GS0"}, resourceNames(resources, "ExtGState"))
+	require.Equal(t, []string{"Fm0"}, resourceNames(resources, "XObject"))
+
+	xobj, xtype := outPage.Resources.GetXObjectByName("Fm0")
+	require.Equal(t, model.XObjectTypeForm, xtype)
+	formResources, ok := core.GetDict(xobj.Get("Resources"))
+	require.True(t, ok)
+	require.Equal(t, []string{"F1"}, resourceNames(formResources, "Font"))
+}
+
+func TestRemoveUnusedObjectsSharedCategory(t *testing.T) {
+	// The pages share one font dictionary and use different fonts of it.
+	fonts := core.MakeDict()
+	for name, fontName := range map[core.PdfObjectName]model.StdFontName{
+		"F1": model.HelveticaName, "F2": model.CourierName, "F3": model.TimesRomanName,
+	} {
+		font, err := model.NewStandard14Font(fontName)
+		require.NoError(t, err)
+		fonts.Set(name, font.ToPdfObject())
+	}
+	shared := core.MakeIndirectObject(fonts)
+
+	page1 := pdftest.NewPage(t, 200, 200, "BT /F1 10 Tf (One) Tj ET")
+	page1.Resources.Font = shared
+	page2 := pdftest.NewPage(t, 200, 200, "BT /F2 10 Tf (Two) Tj ET")
+	page2.Resources.Font = shared
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page1))
+	require.NoError(t, w.AddPage(page2))
+	w.SetOptimizer(optimize.New(optimize.Options{RemoveUnusedObjects: true}))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.False(t, bytes.Contains(buf.Bytes(), []byte("Times-Roman")))
+
+	r := pdftest.Read(t, buf.Bytes())
+	for i := 1; i <= 2; i++ {
+		outPage, err := r.GetPage(i)
+		require.NoError(t, err)
+		resources, ok := core.GetDict(outPage.Resources.ToPdfObject())
+		require.True(t, ok)
+		names := resourceNames(resources, "Font")
+		require.Contains(t, names, "F1")
+		require.Contains(t, names, "F2")
+		require.NotContains(t, names, "F3")
+	}
+}
+
+func TestRemoveUnusedObjectsKeptResources(t *testing.T) {
+	// The page fonts are the fonts of the default resources of the interactive form, and the
+	// property list of an optional content group of the document is kept though not used.
+	page := pdftest.NewPage(t, 200, 200, "BT (Text) Tj ET")
+	ocg := model.NewPdfOCG("Layer")
+	require.NoError(t, page.Resources.SetPropertiesByName("OC0", ocg.ToPdfObject()))
+	require.NoError(t, page.Resources.SetPropertiesByName("MC0", core.MakeDict()))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	ocProperties := model.NewPdfOCProperties()
+	ocProperties.AddOCG(ocg, true)
+	require.NoError(t, w.SetOptionalContentProperties(ocProperties))
+	form := model.NewPdfAcroForm()
+	form.DR = model.NewPdfPageResources()
+	form.DR.Font = page.Resources.Font
+	require.NoError(t, w.SetForms(form))
+	w.SetOptimizer(optimize.New(optimize.Options{RemoveUnusedObjects: true}))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	outPage, err := pdftest.Read(t, buf.Bytes()).GetPage(1)
+	require.NoError(t, err)
+	resources, ok := core.GetDict(outPage.Resources.ToPdfObject())
+	require.True(t, ok)
+	require.Contains(t, resourceNames(resources, "Font"), "F1")
+	require.Equal(t, []string{"OC0"}, resourceNames(resources, "Properties"))
+}
+
+func TestRemoveUnusedObjectsWithoutTrailer(t *testing.T) {
+	// The resources are not pruned without the trailer, e.g. in the incremental updates.
+	page := core.MakeDict()
+	page.Set("Type", core.MakeName("Page"))
+	resources := core.MakeDict()
+	fonts := core.MakeDict()
+	fonts.Set("F1", core.MakeDict())
+	resources.Set("Font", fonts)
+	page.Set("Resources", resources)
+	_, err := (&optimize.RemoveUnusedObjects{}).Optimize([]core.PdfObject{core.MakeIndirectObject(page)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"F1"}, resourceNames(resources, "Font"))
+
+	// The unreachable objects are only removed if the trailer is set.
+	obj := core.MakeIndirectObject(core.MakeDict())
+	objects, err := (&optimize.RemoveUnusedObjects{}).Optimize([]core.PdfObject{obj})
+	require.NoError(t, err)
+	require.Equal(t, []core.PdfObject{obj}, objects)
+
+	opt := &optimize.RemoveUnusedObjects{}
+	trailer := core.MakeDict()
+	root := core.MakeIndirectObject(core.MakeDict())
+	trailer.Set("Root", root)
+	opt.SetTrailer(trailer)
+	objects, err = opt.Optimize([]core.PdfObject{root, obj})
+	require.NoError(t, err)
+	require.Equal(t, []core.PdfObject{root}, objects)
+
+	// The trailer is only used by the next optimization.
+	objects, err = opt.Optimize([]core.PdfObject{root, obj})
+	require.NoError(t, err)
+	require.Equal(t, []core.PdfObject{root, obj}, objects)
+}
@@ -16,3 +16,12 @@ import (
 type Optimizer interface {
 	Optimize(objects []core.PdfObject) ([]core.PdfObject, error)
 }
+
+// TrailerOptimizer is an Optimizer which also needs the trailer of the document, with the Root,
+// Info and Encrypt entries, e.g. to find the objects which are not reachable from it.
+// The trailer is set by PdfWriter before Optimize is called, except in append mode where the
+// objects to optimize are not the whole document.
+type TrailerOptimizer interface {
+	Optimizer
+	SetTrailer(trailer *core.PdfObjectDictionary)
+}
@@ -0,0 +1,899 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Optional content (layers), section 8.11 p. 216 (PDF32000_2008).
+
+// PdfOCState represents the state of optional content groups (ON/OFF).
+type PdfOCState string
+
+// Optional content states.
+const (
+	OCStateOn  PdfOCState = "ON"
+	OCStateOff PdfOCState = "OFF"
+	// OCStateUnchanged is only valid as the BaseState of an optional content configuration
+	// which is not the default one.
+	OCStateUnchanged PdfOCState = "Unchanged"
+)
+
+// PdfOCVisibilityPolicy is the visibility policy of an optional content membership dictionary
+// (Table 99 - P entry).
+type PdfOCVisibilityPolicy string
+
+// Optional content membership visibility policies.
+const (
+	OCPolicyAllOn  PdfOCVisibilityPolicy = "AllOn"
+	OCPolicyAnyOn  PdfOCVisibilityPolicy = "AnyOn"
+	OCPolicyAnyOff PdfOCVisibilityPolicy = "AnyOff"
+	OCPolicyAllOff PdfOCVisibilityPolicy = "AllOff"
+)
+
+// PdfOCVisibilityOperator is the operator of a visibility expression (section 8.11.2.2).
+type PdfOCVisibilityOperator string
+
+// Visibility expression operators.
+const (
+	OCOperatorAnd PdfOCVisibilityOperator = "And"
+	OCOperatorOr  PdfOCVisibilityOperator = "Or"
+	OCOperatorNot PdfOCVisibilityOperator = "Not"
+)
+
+// PdfOptionalContent is implemented by the objects which can control the visibility of content:
+// optional content groups (PdfOCG) and membership dictionaries (PdfOCMD).
+// They are referenced by the OC entry of XObjects and annotations and by marked content
+// sequences with the OC tag.
+type PdfOptionalContent interface {
+	PdfModel
+
+	// IsVisible returns true if the content is visible when the state of each optional
+	// content group is given by `visible`.
+	IsVisible(visible func(ocg *PdfOCG) bool) bool
+}
+
+// PdfOCG represents an optional content group dictionary (Table 98 - p. 219).
+// An optional content group is typically presented to the user as a layer.
+type PdfOCG struct {
+	Name string
+
+	// Intent is the intended use of the group, e.g. "View" (default) or "Design".
+	Intent []string
+
+	// Usage describes the nature of the content of the group. Optional.
+	Usage *PdfOCUsage
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfOCG returns a new optional content group named `name`.
+func NewPdfOCG(name string) *PdfOCG {
+	return &PdfOCG{
+		Name:      name,
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// newPdfOCGFromObj loads an optional content group from `obj`.
+func newPdfOCGFromObj(obj core.PdfObject) (*PdfOCG, error) {
+	container, isInd := core.GetIndirect(obj)
+	if !isInd {
+		container = core.MakeIndirectObject(core.TraceToDirectObject(obj))
+	}
+	dict, ok := core.GetDict(container.PdfObject)
+	if !ok {
+		common.Log.Debug("ERROR: OCG not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	ocg := &PdfOCG{container: container}
+	if str, ok := core.GetString(dict.Get("Name")); ok {
+		ocg.Name = str.Decoded()
+	}
+	ocg.Intent = loadNames(dict.Get("Intent"))
+	if usageDict, ok := core.GetDict(dict.Get("Usage")); ok {
+		ocg.Usage = newPdfOCUsageFromDict(usageDict)
+	}
+	return ocg, nil
+}
+
+// IsVisible returns the state of the group as given by `visible`.
+// Implements the PdfOptionalContent interface.
+func (ocg *PdfOCG) IsVisible(visible func(ocg *PdfOCG) bool) bool {
+	return visible(ocg)
+}
+
+// GetContainingPdfObject returns the container of the group (indirect object).
+func (ocg *PdfOCG) GetContainingPdfObject() core.PdfObject {
+	return ocg.container
+}
+
+// ToPdfObject returns the optional content group dictionary in an indirect object.
+func (ocg *PdfOCG) ToPdfObject() core.PdfObject {
+	dict, ok := core.GetDict(ocg.container.PdfObject)
+	if !ok {
+		dict = core.MakeDict()
+		ocg.container.PdfObject = dict
+	}
+	dict.Set("Type", core.MakeName("OCG"))
+	dict.Set("Name", core.MakeTextString(ocg.Name))
+	dict.Remove("Intent")
+	if len(ocg.Intent) == 1 {
+		dict.Set("Intent", core.MakeName(ocg.Intent[0]))
+	} else if len(ocg.Intent) > 1 {
+		dict.Set("Intent", makeNameArray(ocg.Intent))
+	}
+	dict.Remove("Usage")
+	if ocg.Usage != nil {
+		dict.Set("Usage", ocg.Usage.ToPdfObject())
+	}
+	return ocg.container
+}
+
+// PdfOCUsage represents an optional content usage dictionary (Table 102 - p. 225), which
+// specifies how the content of a group is used when viewing, printing or exporting.
+// Entries which are not represented by the fields are preserved.
+type PdfOCUsage struct {
+	// ViewState indicates whether the group is visible when the document is opened.
+	ViewState PdfOCState
+
+	// PrintState indicates whether the group is printed and PrintSubtype the kind of content,
+	// e.g. "Trapping", "PrintersMarks" or "Watermark".
+	PrintState   PdfOCState
+	PrintSubtype string
+
+	// ExportState indicates whether the group is included when the document is exported
+	// to a format which does not support optional content.
+	ExportState PdfOCState
+
+	primitive *core.PdfObjectDictionary
+}
+
+// NewPdfOCUsage returns a new empty usage dictionary.
+func NewPdfOCUsage() *PdfOCUsage {
+	return &PdfOCUsage{primitive: core.MakeDict()}
+}
+
+func newPdfOCUsageFromDict(dict *core.PdfObjectDictionary) *PdfOCUsage {
+	u := &PdfOCUsage{primitive: dict}
+	if d, ok := core.GetDict(dict.Get("View")); ok {
+		if state, ok := core.GetNameVal(d.Get("ViewState")); ok {
+			u.ViewState = PdfOCState(state)
+		}
+	}
+	if d, ok := core.GetDict(dict.Get("Print")); ok {
+		if state, ok := core.GetNameVal(d.Get("PrintState")); ok {
+			u.PrintState = PdfOCState(state)
+		}
+		if subtype, ok := core.GetNameVal(d.Get("Subtype")); ok {
+			u.PrintSubtype = subtype
+		}
+	}
+	if d, ok := core.GetDict(dict.Get("Export")); ok {
+		if state, ok := core.GetNameVal(d.Get("ExportState")); ok {
+			u.ExportState = PdfOCState(state)
+		}
+	}
+	return u
+}
+
+// ToPdfObject returns the usage dictionary.
+func (u *PdfOCUsage) ToPdfObject() core.PdfObject {
+	if u.primitive == nil {
+		u.primitive = core.MakeDict()
+	}
+	d := u.primitive
+
+	d.Remove("View")
+	if u.ViewState != "" {
+		view := core.MakeDict()
+		view.Set("ViewState", core.MakeName(string(u.ViewState)))
+		d.Set("View", view)
+	}
+	d.Remove("Print")
+	if u.PrintState != "" || u.PrintSubtype != "" {
+		printDict := core.MakeDict()
+		if u.PrintSubtype != "" {
+			printDict.Set("Subtype", core.MakeName(u.PrintSubtype))
+		}
+		if u.PrintState != "" {
+			printDict.Set("PrintState", core.MakeName(string(u.PrintState)))
+		}
+		d.Set("Print", printDict)
+	}
+	d.Remove("Export")
+	if u.ExportState != "" {
+		export := core.MakeDict()
+		export.Set("ExportState", core.MakeName(string(u.ExportState)))
+		d.Set("Export", export)
+	}
+	return d
+}
+
+// PdfOCMD represents an optional content membership dictionary (Table 99 - p. 220).
+// The visibility of the content is determined by the visibility expression VE if set,
+// otherwise by applying the policy P to the groups OCGs.
+type PdfOCMD struct {
+	OCGs []*PdfOCG
+	P    PdfOCVisibilityPolicy
+	VE   *PdfOCVisibilityExpression
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfOCMD returns a new membership dictionary with policy `policy` applying to `ocgs`.
+func NewPdfOCMD(policy PdfOCVisibilityPolicy, ocgs ...*PdfOCG) *PdfOCMD {
+	return &PdfOCMD{
+		OCGs:      ocgs,
+		P:         policy,
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// IsVisible returns true if the content is visible when the state of each optional content
+// group is given by `visible`.
+// Implements the PdfOptionalContent interface.
+func (md *PdfOCMD) IsVisible(visible func(ocg *PdfOCG) bool) bool {
+	if md.VE != nil {
+		return md.VE.Evaluate(visible)
+	}
+	if len(md.OCGs) == 0 {
+		return true
+	}
+
+	var numOn int
+	for _, ocg := range md.OCGs {
+		if visible(ocg) {
+			numOn++
+		}
+	}
+	switch md.P {
+	case OCPolicyAllOn:
+		return numOn == len(md.OCGs)
+	case OCPolicyAnyOff:
+		return numOn < len(md.OCGs)
+	case OCPolicyAllOff:
+		return numOn == 0
+	}
+	return numOn > 0
+}
+
+// GetContainingPdfObject returns the container of the membership dictionary (indirect object).
+func (md *PdfOCMD) GetContainingPdfObject() core.PdfObject {
+	return md.container
+}
+
+// ToPdfObject returns the membership dictionary in an indirect object.
+func (md *PdfOCMD) ToPdfObject() core.PdfObject {
+	dict, ok := core.GetDict(md.container.PdfObject)
+	if !ok {
+		dict = core.MakeDict()
+		md.container.PdfObject = dict
+	}
+	dict.Set("Type", core.MakeName("OCMD"))
+	dict.Remove("OCGs")
+	if len(md.OCGs) == 1 {
+		dict.Set("OCGs", md.OCGs[0].ToPdfObject())
+	} else if len(md.OCGs) > 1 {
+		dict.Set("OCGs", ocgsToArray(md.OCGs))
+	}
+	dict.Remove("P")
+	if md.P != "" {
+		dict.Set("P", core.MakeName(string(md.P)))
+	}
+	dict.Remove("VE")
+	if md.VE != nil {
+		dict.Set("VE", md.VE.ToPdfObject())
+	}
+	return md.container
+}
+
+// PdfOCVisibilityExpression represents a visibility expression (section 8.11.2.2 p. 221).
+// An expression is either an optional content group (OCG set) or an operator applied to
+// sub-expressions. The Not operator takes a single operand.
+type PdfOCVisibilityExpression struct {
+	Operator PdfOCVisibilityOperator
+	Operands []*PdfOCVisibilityExpression
+	OCG      *PdfOCG
+}
+
+// NewOCVisibilityExpressionOCG returns an expression which evaluates to the state of `ocg`.
+func NewOCVisibilityExpressionOCG(ocg *PdfOCG) *PdfOCVisibilityExpression {
+	return &PdfOCVisibilityExpression{OCG: ocg}
+}
+
+// NewOCVisibilityExpressionAnd returns the conjunction of `operands`.
+func NewOCVisibilityExpressionAnd(operands ...*PdfOCVisibilityExpression) *PdfOCVisibilityExpression {
+	return &PdfOCVisibilityExpression{Operator: OCOperatorAnd, Operands: operands}
+}
+
+// NewOCVisibilityExpressionOr returns the disjunction of `operands`.
+func NewOCVisibilityExpressionOr(operands ...*PdfOCVisibilityExpression) *PdfOCVisibilityExpression {
+	return &PdfOCVisibilityExpression{Operator: OCOperatorOr, Operands: operands}
+}
+
+// NewOCVisibilityExpressionNot returns the negation of `operand`.
+func NewOCVisibilityExpressionNot(operand *PdfOCVisibilityExpression) *PdfOCVisibilityExpression {
+	return &PdfOCVisibilityExpression{Operator: OCOperatorNot, Operands: []*PdfOCVisibilityExpression{operand}}
+}
+
+// Evaluate returns the value of the expression when the state of each optional content
+// group is given by `visible`.
+func (ve *PdfOCVisibilityExpression) Evaluate(visible func(ocg *PdfOCG) bool) bool {
+	if ve.OCG != nil {
+		return visible(ve.OCG)
+	}
+	switch ve.Operator {
+	case OCOperatorNot:
+		if len(ve.Operands) == 0 {
+			return true
+		}
+		return !ve.Operands[0].Evaluate(visible)
+	case OCOperatorOr:
+		for _, op := range ve.Operands {
+			if op.Evaluate(visible) {
+				return true
+			}
+		}
+		return len(ve.Operands) == 0
+	}
+	for _, op := range ve.Operands {
+		if !op.Evaluate(visible) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToPdfObject returns the visibility expression object: either an OCG or an array with
+// the operator name followed by the operands.
+func (ve *PdfOCVisibilityExpression) ToPdfObject() core.PdfObject {
+	if ve.OCG != nil {
+		return ve.OCG.ToPdfObject()
+	}
+	arr := core.MakeArray(core.MakeName(string(ve.Operator)))
+	for _, op := range ve.Operands {
+		arr.Append(op.ToPdfObject())
+	}
+	return arr
+}
+
+// PdfOCOrderItem is an entry of the Order array of an optional content configuration,
+// which defines how groups are presented in the user interface. An item is either a group
+// (optionally with nested children) or a collection of items with an optional label which
+// cannot be toggled.
+type PdfOCOrderItem struct {
+	OCG      *PdfOCG
+	Label    string
+	Children []*PdfOCOrderItem
+}
+
+// PdfOCUsageApplication represents a usage application dictionary (Table 103 - p. 227),
+// which specifies how usage entries are applied to set the state of groups automatically.
+type PdfOCUsageApplication struct {
+	// Event is "View", "Print" or "Export".
+	Event string
+	OCGs  []*PdfOCG
+	// Category lists the usage entries to consult, e.g. "Print" or "View".
+	Category []string
+}
+
+// PdfOCConfig represents an optional content configuration dictionary (Table 101 - p. 223).
+type PdfOCConfig struct {
+	Name    string
+	Creator string
+
+	// BaseState is the initial state of all groups (ON by default). Groups listed in ON
+	// and OFF override it.
+	BaseState PdfOCState
+	ON        []*PdfOCG
+	OFF       []*PdfOCG
+
+	Intent []string
+	AS     []*PdfOCUsageApplication
+
+	// Order specifies the presentation of the groups in the user interface.
+	Order []*PdfOCOrderItem
+	// ListMode is "AllPages" (default) or "VisiblePages".
+	ListMode string
+	// RBGroups are radio button groups: at most one group of each set is visible at a time.
+	RBGroups [][]*PdfOCG
+	// Locked groups cannot be toggled by the user.
+	Locked []*PdfOCG
+
+	container *core.PdfObjectDictionary
+}
+
+// NewPdfOCConfig returns a new optional content configuration.
+func NewPdfOCConfig() *PdfOCConfig {
+	return &PdfOCConfig{container: core.MakeDict()}
+}
+
+// IsVisible returns true if `ocg` is visible in the configuration.
+func (c *PdfOCConfig) IsVisible(ocg *PdfOCG) bool {
+	if containsOCG(c.ON, ocg) {
+		return true
+	}
+	if containsOCG(c.OFF, ocg) {
+		return false
+	}
+	return c.BaseState != OCStateOff
+}
+
+// SetVisible sets the state of `ocg` in the configuration. When a group is made visible,
+// the other groups of its radio button groups are hidden.
+func (c *PdfOCConfig) SetVisible(ocg *PdfOCG, visible bool) {
+	c.ON = removeOCG(c.ON, ocg)
+	c.OFF = removeOCG(c.OFF, ocg)
+	if !visible {
+		c.OFF = append(c.OFF, ocg)
+		return
+	}
+	c.ON = append(c.ON, ocg)
+
+	for _, group := range c.RBGroups {
+		if !containsOCG(group, ocg) {
+			continue
+		}
+		for _, other := range group {
+			if other != ocg && c.IsVisible(other) {
+				c.SetVisible(other, false)
+			}
+		}
+	}
+}
+
+// ToPdfObject returns the configuration dictionary.
+func (c *PdfOCConfig) ToPdfObject() core.PdfObject {
+	if c.container == nil {
+		c.container = core.MakeDict()
+	}
+	d := c.container
+
+	setOptional := func(key core.PdfObjectName, obj core.PdfObject, isSet bool) {
+		d.Remove(key)
+		if isSet {
+			d.Set(key, obj)
+		}
+	}
+	setOptional("Name", core.MakeTextString(c.Name), c.Name != "")
+	setOptional("Creator", core.MakeTextString(c.Creator), c.Creator != "")
+	setOptional("BaseState", core.MakeName(string(c.BaseState)), c.BaseState != "")
+	setOptional("ON", ocgsToArray(c.ON), len(c.ON) > 0)
+	setOptional("OFF", ocgsToArray(c.OFF), len(c.OFF) > 0)
+	setOptional("Intent", makeNameArray(c.Intent), len(c.Intent) > 0)
+
+	d.Remove("AS")
+	if len(c.AS) > 0 {
+		as := core.MakeArray()
+		for _, app := range c.AS {
+			appDict := core.MakeDict()
+			appDict.Set("Event", core.MakeName(app.Event))
+			appDict.Set("OCGs", ocgsToArray(app.OCGs))
+			appDict.Set("Category", makeNameArray(app.Category))
+			as.Append(appDict)
+		}
+		d.Set("AS", as)
+	}
+
+	setOptional("Order", orderItemsToArray(c.Order), len(c.Order) > 0)
+	setOptional("ListMode", core.MakeName(c.ListMode), c.ListMode != "")
+
+	d.Remove("RBGroups")
+	if len(c.RBGroups) > 0 {
+		groups := core.MakeArray()
+		for _, group := range c.RBGroups {
+			groups.Append(ocgsToArray(group))
+		}
+		d.Set("RBGroups", groups)
+	}
+	setOptional("Locked", ocgsToArray(c.Locked), len(c.Locked) > 0)
+	return d
+}
+
+// PdfOCProperties represents the optional content properties dictionary (Table 100 - p. 222)
+// referred to by the OCProperties entry of the document catalog.
+type PdfOCProperties struct {
+	// OCGs lists all the optional content groups of the document.
+	OCGs []*PdfOCG
+	// D is the default configuration, applied when the document is opened.
+	D *PdfOCConfig
+	// Configs are alternate configurations. Optional.
+	Configs []*PdfOCConfig
+
+	// Loaded groups by primitive object.
+	ocgMap    map[core.PdfObject]*PdfOCG
+	container *core.PdfObjectDictionary
+}
+
+// NewPdfOCProperties returns new optional content properties with an empty default
+// configuration.
+func NewPdfOCProperties() *PdfOCProperties {
+	return &PdfOCProperties{
+		D:         NewPdfOCConfig(),
+		ocgMap:    map[core.PdfObject]*PdfOCG{},
+		container: core.MakeDict(),
+	}
+}
+
+// newPdfOCPropertiesFromObj loads the optional content properties from `obj`.
+func newPdfOCPropertiesFromObj(obj core.PdfObject) (*PdfOCProperties, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: OCProperties not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	p := NewPdfOCProperties()
+	p.container = dict
+	if arr, ok := core.GetArray(dict.Get("OCGs")); ok {
+		p.OCGs = p.loadOCGs(arr)
+	}
+	if d, ok := core.GetDict(dict.Get("D")); ok {
+		p.D = p.loadConfig(d)
+	}
+	if arr, ok := core.GetArray(dict.Get("Configs")); ok {
+		for _, obj := range arr.Elements() {
+			if d, ok := core.GetDict(obj); ok {
+				p.Configs = append(p.Configs, p.loadConfig(d))
+			}
+		}
+	}
+	return p, nil
+}
+
+// AddOCG adds `ocg` to the groups of the document with initial state `visible` in the
+// default configuration and appends it to the presentation order.
+func (p *PdfOCProperties) AddOCG(ocg *PdfOCG, visible bool) {
+	for _, o := range p.OCGs {
+		if o == ocg {
+			return
+		}
+	}
+	p.OCGs = append(p.OCGs, ocg)
+	p.ocgMap[ocg.GetContainingPdfObject()] = ocg
+	if p.D == nil {
+		p.D = NewPdfOCConfig()
+	}
+	p.D.Order = append(p.D.Order, &PdfOCOrderItem{OCG: ocg})
+	if !visible || p.D.BaseState == OCStateOff {
+		p.D.SetVisible(ocg, visible)
+	}
+}
+
+// GetOCGByName returns the first optional content group named `name` or nil if not found.
+func (p *PdfOCProperties) GetOCGByName(name string) *PdfOCG {
+	for _, ocg := range p.OCGs {
+		if ocg.Name == name {
+			return ocg
+		}
+	}
+	return nil
+}
+
+// IsVisible returns true if `ocg` is visible in the default configuration.
+func (p *PdfOCProperties) IsVisible(ocg *PdfOCG) bool {
+	if p.D == nil {
+		return true
+	}
+	return p.D.IsVisible(ocg)
+}
+
+// SetVisible sets the state of `ocg` in the default configuration, i.e. whether the group
+// is visible when the document is opened.
+func (p *PdfOCProperties) SetVisible(ocg *PdfOCG, visible bool) {
+	if p.D == nil {
+		p.D = NewPdfOCConfig()
+	}
+	p.D.SetVisible(ocg, visible)
+}
+
+// IsContentVisible returns true if content controlled by `oc` is visible in the default
+// configuration. Content which is not optional (nil `oc`) is always visible.
+func (p *PdfOCProperties) IsContentVisible(oc PdfOptionalContent) bool {
+	if oc == nil {
+		return true
+	}
+	return oc.IsVisible(p.IsVisible)
+}
+
+// GetOptionalContent loads the optional content group or membership dictionary `obj`,
+// e.g. the OC entry of an XObject or an entry of the Properties resource dictionary.
+// Groups belonging to the document are returned as the instances in OCGs.
+func (p *PdfOCProperties) GetOptionalContent(obj core.PdfObject) (PdfOptionalContent, error) {
+	obj = core.ResolveReference(obj)
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	switch typ, _ := core.GetNameVal(dict.Get("Type")); typ {
+	case "OCG":
+		return p.loadOCG(obj)
+	case "OCMD":
+		return p.loadOCMD(obj)
+	}
+	return nil, errors.New("not an optional content group or membership dictionary")
+}
+
+// ToPdfObject returns the optional content properties dictionary.
+func (p *PdfOCProperties) ToPdfObject() core.PdfObject {
+	if p.container == nil {
+		p.container = core.MakeDict()
+	}
+	d := p.container
+	d.Set("OCGs", ocgsToArray(p.OCGs))
+	if p.D == nil {
+		p.D = NewPdfOCConfig()
+	}
+	d.Set("D", p.D.ToPdfObject())
+	d.Remove("Configs")
+	if len(p.Configs) > 0 {
+		configs := core.MakeArray()
+		for _, c := range p.Configs {
+			configs.Append(c.ToPdfObject())
+		}
+		d.Set("Configs", configs)
+	}
+	return d
+}
+
+// loadOCG returns the group represented by `obj`, loading it on first access.
+func (p *PdfOCProperties) loadOCG(obj core.PdfObject) (*PdfOCG, error) {
+	obj = core.ResolveReference(obj)
+	if ocg, ok := p.ocgMap[obj]; ok {
+		return ocg, nil
+	}
+	ocg, err := newPdfOCGFromObj(obj)
+	if err != nil {
+		return nil, err
+	}
+	p.ocgMap[obj] = ocg
+	p.ocgMap[ocg.container] = ocg
+	return ocg, nil
+}
+
+// loadOCGs loads the groups in `arr`, skipping invalid entries.
+func (p *PdfOCProperties) loadOCGs(arr *core.PdfObjectArray) []*PdfOCG {
+	var ocgs []*PdfOCG
+	for _, obj := range arr.Elements() {
+		ocg, err := p.loadOCG(obj)
+		if err != nil {
+			common.Log.Debug("Invalid OCG - skipping: %v", err)
+			continue
+		}
+		ocgs = append(ocgs, ocg)
+	}
+	return ocgs
+}
+
+func (p *PdfOCProperties) loadOCMD(obj core.PdfObject) (*PdfOCMD, error) {
+	container, isInd := core.GetIndirect(obj)
+	if !isInd {
+		container = core.MakeIndirectObject(core.TraceToDirectObject(obj))
+	}
+	dict, ok := core.GetDict(container.PdfObject)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+
+	md := &PdfOCMD{container: container}
+	switch t := core.ResolveReference(dict.Get("OCGs")).(type) {
+	case *core.PdfObjectArray:
+		md.OCGs = p.loadOCGs(t)
+	case nil:
+	default:
+		ocg, err := p.loadOCG(t)
+		if err != nil {
+			return nil, err
+		}
+		md.OCGs = []*PdfOCG{ocg}
+	}
+	if policy, ok := core.GetNameVal(dict.Get("P")); ok {
+		md.P = PdfOCVisibilityPolicy(policy)
+	}
+	if obj := dict.Get("VE"); obj != nil {
+		ve, err := p.loadVisibilityExpression(obj, 0)
+		if err != nil {
+			return nil, err
+		}
+		md.VE = ve
+	}
+	return md, nil
+}
+
+func (p *PdfOCProperties) loadVisibilityExpression(obj core.PdfObject, depth int) (*PdfOCVisibilityExpression, error) {
+	if depth > maxTreeDepth {
+		return nil, errors.New("visibility expression too deep")
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		ocg, err := p.loadOCG(obj)
+		if err != nil {
+			return nil, err
+		}
+		return NewOCVisibilityExpressionOCG(ocg), nil
+	}
+	if arr.Len() == 0 {
+		return nil, errors.New("empty visibility expression")
+	}
+	op, ok := core.GetNameVal(arr.Get(0))
+	if !ok {
+		return nil, errors.New("missing visibility expression operator")
+	}
+
+	ve := &PdfOCVisibilityExpression{Operator: PdfOCVisibilityOperator(op)}
+	for _, elem := range arr.Elements()[1:] {
+		operand, err := p.loadVisibilityExpression(elem, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		ve.Operands = append(ve.Operands, operand)
+	}
+	return ve, nil
+}
+
+func (p *PdfOCProperties) loadConfig(dict *core.PdfObjectDictionary) *PdfOCConfig {
+	c := &PdfOCConfig{container: dict}
+	if str, ok := core.GetString(dict.Get("Name")); ok {
+		c.Name = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("Creator")); ok {
+		c.Creator = str.Decoded()
+	}
+	if state, ok := core.GetNameVal(dict.Get("BaseState")); ok {
+		c.BaseState = PdfOCState(state)
+	}
+	if arr, ok := core.GetArray(dict.Get("ON")); ok {
+		c.ON = p.loadOCGs(arr)
+	}
+	if arr, ok := core.GetArray(dict.Get("OFF")); ok {
+		c.OFF = p.loadOCGs(arr)
+	}
+	c.Intent = loadNames(dict.Get("Intent"))
+	if arr, ok := core.GetArray(dict.Get("AS")); ok {
+		for _, obj := range arr.Elements() {
+			appDict, ok := core.GetDict(obj)
+			if !ok {
+				continue
+			}
+			app := &PdfOCUsageApplication{Category: loadNames(appDict.Get("Category"))}
+			app.Event, _ = core.GetNameVal(appDict.Get("Event"))
+			if ocgs, ok := core.GetArray(appDict.Get("OCGs")); ok {
+				app.OCGs = p.loadOCGs(ocgs)
+			}
+			c.AS = append(c.AS, app)
+		}
+	}
+	if arr, ok := core.GetArray(dict.Get("Order")); ok {
+		c.Order = p.loadOrderItems(arr, 0)
+	}
+	c.ListMode, _ = core.GetNameVal(dict.Get("ListMode"))
+	if arr, ok := core.GetArray(dict.Get("RBGroups")); ok {
+		for _, obj := range arr.Elements() {
+			if group, ok := core.GetArray(obj); ok {
+				c.RBGroups = append(c.RBGroups, p.loadOCGs(group))
+			}
+		}
+	}
+	if arr, ok := core.GetArray(dict.Get("Locked")); ok {
+		c.Locked = p.loadOCGs(arr)
+	}
+	return c
+}
+
+// loadOrderItems loads the items of an Order array. An array following a group contains
+// the children of the group, otherwise it is a collection optionally starting with a label.
+func (p *PdfOCProperties) loadOrderItems(arr *core.PdfObjectArray, depth int) []*PdfOCOrderItem {
+	if depth > maxTreeDepth {
+		common.Log.Debug("ERROR: OC order too deep - truncating")
+		return nil
+	}
+
+	var items []*PdfOCOrderItem
+	for _, obj := range arr.Elements() {
+		sub, isArr := core.GetArray(obj)
+		if !isArr {
+			ocg, err := p.loadOCG(obj)
+			if err != nil {
+				common.Log.Debug("Invalid OC order entry - skipping: %v", err)
+				continue
+			}
+			items = append(items, &PdfOCOrderItem{OCG: ocg})
+			continue
+		}
+
+		if n := len(items); n > 0 && items[n-1].OCG != nil && items[n-1].Children == nil {
+			items[n-1].Children = p.loadOrderItems(sub, depth+1)
+			continue
+		}
+		item := &PdfOCOrderItem{}
+		elems := sub.Elements()
+		if len(elems) > 0 {
+			if str, ok := core.GetString(elems[0]); ok {
+				item.Label = str.Decoded()
+				elems = elems[1:]
+			}
+		}
+		item.Children = p.loadOrderItems(core.MakeArray(elems...), depth+1)
+		items = append(items, item)
+	}
+	return items
+}
+
+func orderItemsToArray(items []*PdfOCOrderItem) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, item := range items {
+		if item.OCG != nil {
+			arr.Append(item.OCG.ToPdfObject())
+			if len(item.Children) > 0 {
+				arr.Append(orderItemsToArray(item.Children))
+			}
+			continue
+		}
+		sub := orderItemsToArray(item.Children)
+		if item.Label != "" {
+			sub = core.MakeArray(append([]core.PdfObject{core.MakeTextString(item.Label)}, sub.Elements()...)...)
+		}
+		arr.Append(sub)
+	}
+	return arr
+}
+
+func ocgsToArray(ocgs []*PdfOCG) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, ocg := range ocgs {
+		arr.Append(ocg.ToPdfObject())
+	}
+	return arr
+}
+
+func containsOCG(ocgs []*PdfOCG, ocg *PdfOCG) bool {
+	for _, o := range ocgs {
+		if o == ocg {
+			return true
+		}
+	}
+	return false
+}
+
+func removeOCG(ocgs []*PdfOCG, ocg *PdfOCG) []*PdfOCG {
+	var res []*PdfOCG
+	for _, o := range ocgs {
+		if o != ocg {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// loadNames returns the names of `obj`, which is either a name or an array of names.
+func loadNames(obj core.PdfObject) []string {
+	if name, ok := core.GetNameVal(obj); ok {
+		return []string{name}
+	}
+	var names []string
+	if arr, ok := core.GetArray(obj); ok {
+		for _, elem := range arr.Elements() {
+			if name, ok := core.GetNameVal(elem); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func makeNameArray(names []string) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, name := range names {
+		arr.Append(core.MakeName(name))
+	}
+	return arr
+}
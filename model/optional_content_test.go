@@ -0,0 +1,146 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestOCPropertiesLoad(t *testing.T) {
+	rawText := `
+1 0 obj
+<<
+/OCGs [2 0 R 3 0 R 4 0 R]
+/D <<
+	/Name (Default)
+	/BaseState /OFF
+	/ON [2 0 R]
+	/Order [2 0 R [3 0 R] [(Languages) 4 0 R]]
+	/RBGroups [[3 0 R 4 0 R]]
+	/Locked [2 0 R]
+	/AS [<< /Event /Print /OCGs [3 0 R] /Category [/Print] >>]
+>>
+>>
+endobj
+2 0 obj
+<< /Type /OCG /Name (Frame) >>
+endobj
+3 0 obj
+<< /Type /OCG /Name (English) /Intent [/View /Design] /Usage << /Print << /PrintState /ON >> /Language << /Lang (en) >> >> >>
+endobj
+4 0 obj
+<< /Type /OCG /Name (French) >>
+endobj
+5 0 obj
+<< /Type /OCMD /VE [/And 2 0 R [/Not 4 0 R]] >>
+endobj
+`
+	r := NewReaderForText(rawText)
+	require.NoError(t, r.ParseIndObjSeries())
+
+	obj, err := r.parser.LookupByNumber(1)
+	require.NoError(t, err)
+	require.NoError(t, core.ResolveReferencesDeep(obj, nil))
+
+	p, err := newPdfOCPropertiesFromObj(obj)
+	require.NoError(t, err)
+	require.Len(t, p.OCGs, 3)
+	frame, english, french := p.OCGs[0], p.OCGs[1], p.OCGs[2]
+	require.Equal(t, "Frame", frame.Name)
+	require.Equal(t, []string{"View", "Design"}, english.Intent)
+	require.Equal(t, OCStateOn, english.Usage.PrintState)
+
+	d := p.D
+	require.Equal(t, "Default", d.Name)
+	require.Equal(t, OCStateOff, d.BaseState)
+	require.Equal(t, []*PdfOCG{frame}, d.Locked)
+	require.Len(t, d.AS, 1)
+	require.Equal(t, "Print", d.AS[0].Event)
+	require.Equal(t, []*PdfOCG{english}, d.AS[0].OCGs)
+
+	// Order: English is nested under Frame, French is in a labeled collection.
+	require.Len(t, d.Order, 2)
+	require.Equal(t, frame, d.Order[0].OCG)
+	require.Equal(t, english, d.Order[0].Children[0].OCG)
+	require.Equal(t, "Languages", d.Order[1].Label)
+	require.Equal(t, french, d.Order[1].Children[0].OCG)
+
+	require.True(t, p.IsVisible(frame))
+	require.False(t, p.IsVisible(english))
+	require.False(t, p.IsVisible(french))
+
+	// Radio button groups: showing French hides English.
+	p.SetVisible(english, true)
+	require.True(t, p.IsVisible(english))
+	p.SetVisible(french, true)
+	require.True(t, p.IsVisible(french))
+	require.False(t, p.IsVisible(english))
+
+	// Membership dictionary with a visibility expression.
+	mdObj, err := r.parser.LookupByNumber(5)
+	require.NoError(t, err)
+	oc, err := p.GetOptionalContent(mdObj)
+	require.NoError(t, err)
+	md, ok := oc.(*PdfOCMD)
+	require.True(t, ok)
+	require.Equal(t, frame, md.VE.Operands[0].OCG)
+	require.False(t, p.IsContentVisible(md))
+	p.SetVisible(english, true)
+	require.True(t, p.IsContentVisible(md))
+
+	// Round trip.
+	loaded, err := newPdfOCPropertiesFromObj(p.ToPdfObject())
+	require.NoError(t, err)
+	require.Len(t, loaded.D.Order, 2)
+	require.Equal(t, "Languages", loaded.D.Order[1].Label)
+	require.Len(t, loaded.D.RBGroups, 1)
+	require.True(t, loaded.IsVisible(loaded.GetOCGByName("English")))
+	require.False(t, loaded.IsVisible(loaded.GetOCGByName("French")))
+
+	// Unknown usage entries are preserved.
+	usage, ok := core.GetDict(english.Usage.ToPdfObject())
+	require.True(t, ok)
+	require.NotNil(t, usage.Get("Language"))
+}
+
+func TestOCMDVisibility(t *testing.T) {
+	a, b := NewPdfOCG("A"), NewPdfOCG("B")
+	state := map[*PdfOCG]bool{a: true, b: false}
+	visible := func(ocg *PdfOCG) bool { return state[ocg] }
+
+	testcases := []struct {
+		policy   PdfOCVisibilityPolicy
+		expected bool
+	}{
+		{OCPolicyAllOn, false},
+		{OCPolicyAnyOn, true},
+		{OCPolicyAnyOff, true},
+		{OCPolicyAllOff, false},
+		{"", true},
+	}
+	for _, tcase := range testcases {
+		md := NewPdfOCMD(tcase.policy, a, b)
+		require.Equal(t, tcase.expected, md.IsVisible(visible), tcase.policy)
+	}
+
+	md := NewPdfOCMD(OCPolicyAnyOn, a, b)
+	md.VE = NewOCVisibilityExpressionOr(
+		NewOCVisibilityExpressionNot(NewOCVisibilityExpressionOCG(a)),
+		NewOCVisibilityExpressionOCG(b),
+	)
+	require.False(t, md.IsVisible(visible))
+	state[b] = true
+	require.True(t, md.IsVisible(visible))
+
+	dict, ok := core.GetDict(md.ToPdfObject())
+	require.True(t, ok)
+	ve, ok := core.GetArray(dict.Get("VE"))
+	require.True(t, ok)
+	require.Equal(t, 3, ve.Len())
+}
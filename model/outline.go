@@ -6,6 +6,7 @@
 package model
 
 import (
+	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
 )
 
@@ -39,9 +40,13 @@ func (od OutlineDest) ToPdfObject() core.PdfObject {
 }
 
 // Outline represents a PDF outline dictionary (Table 152 - p. 376).
-// Currently, the Outline object can only be used to construct PDF outlines.
+// The Outline object can be used to construct PDF outlines or to edit the
+// outlines of a document (see PdfReader.GetOutlines).
 type Outline struct {
 	items []*OutlineItem
+
+	// primitive is the outline dictionary of a loaded outline.
+	primitive *core.PdfIndirectObject
 }
 
 // NewOutline returns a new outline instance.
@@ -65,6 +70,14 @@ func (o *Outline) Insert(index uint, item *OutlineItem) {
 	o.items = append(o.items[:index], append([]*OutlineItem{item}, o.items[index:]...)...)
 }
 
+// Remove removes the top level outline item at the specified index and
+// returns it. Returns nil if the index is out of range.
+func (o *Outline) Remove(index uint) *OutlineItem {
+	var item *OutlineItem
+	o.items, item = removeOutlineItem(o.items, index)
+	return item
+}
+
 // Items returns all children outline items.
 func (o *Outline) Items() []*OutlineItem {
 	return o.items
@@ -73,15 +86,40 @@ func (o *Outline) Items() []*OutlineItem {
 // ToPdfOutline returns a low level PdfOutline object, based on the current
 // instance.
 func (o *Outline) ToPdfOutline() *PdfOutline {
+	outline, _ := o.toPdfOutline()
+	return outline
+}
+
+// outlineReplacements maps the objects of a loaded outline and of its items to the new objects
+// replacing them in a serialized outline. The loaded objects are left unchanged and are replaced
+// once: the items inserted several times get new objects.
+type outlineReplacements map[*core.PdfIndirectObject]*core.PdfIndirectObject
+
+// add registers `replacement` as the replacement of loaded object `obj`, if not nil and not
+// already replaced.
+func (replaced outlineReplacements) add(obj, replacement *core.PdfIndirectObject) {
+	if obj == nil {
+		return
+	}
+	if _, ok := replaced[obj]; !ok {
+		replaced[obj] = replacement
+	}
+}
+
+// toPdfOutline returns the PdfOutline of the outline and the replacements of the loaded objects.
+func (o *Outline) toPdfOutline() (*PdfOutline, outlineReplacements) {
 	// Create outline.
 	outline := NewPdfOutline()
+	replaced := outlineReplacements{}
+	replaced.add(o.primitive, outline.primitive)
 
 	// Create outline items.
 	var outlineItems []*PdfOutlineItem
+	var lenVisible int64
 	var prev *PdfOutlineItem
 
 	for _, item := range o.items {
-		outlineItem, _ := item.ToPdfOutlineItem()
+		outlineItem, lenChildren := item.toPdfOutlineItem(replaced)
 		outlineItem.Parent = &outline.PdfOutlineTreeNode
 
 		if prev != nil {
@@ -90,18 +128,22 @@ func (o *Outline) ToPdfOutline() *PdfOutline {
 		}
 
 		outlineItems = append(outlineItems, outlineItem)
+		lenVisible++
+		if !item.Closed {
+			lenVisible += lenChildren
+		}
 		prev = outlineItem
 	}
 
 	// Add outline linked list properties.
-	lenOutlineItems := int64(len(outlineItems))
+	lenOutlineItems := len(outlineItems)
 	if lenOutlineItems > 0 {
 		outline.First = &outlineItems[0].PdfOutlineTreeNode
 		outline.Last = &outlineItems[lenOutlineItems-1].PdfOutlineTreeNode
-		outline.Count = &lenOutlineItems
+		outline.Count = &lenVisible
 	}
 
-	return outline
+	return outline, replaced
 }
 
 // ToPdfObject returns a PDF object representation of the outline.
@@ -114,7 +156,31 @@ type OutlineItem struct {
 	Title string
 	Dest  OutlineDest
 
+	// Destination is the destination of the item, if specified, instead of Dest.
+	Destination *PdfDestination
+
+	// Action is the action performed when the item is activated. Dest is not
+	// used for items with an action.
+	Action *PdfAction
+
+	// Color is the color of the title (black if not specified).
+	Color *PdfColorDeviceRGB
+
+	// Italic and Bold specify the style of the title.
+	Italic bool
+	Bold   bool
+
+	// Closed specifies whether the children of the item are hidden.
+	Closed bool
+
 	items []*OutlineItem
+
+	// primitive is the outline item dictionary of a loaded item.
+	primitive *core.PdfIndirectObject
+
+	// noDest is true for the loaded items which have no valid destination: they are written
+	// without destination unless Dest is changed.
+	noDest bool
 }
 
 // NewOutlineItem returns a new outline item instance.
@@ -141,26 +207,71 @@ func (oi *OutlineItem) Insert(index uint, item *OutlineItem) {
 	oi.items = append(oi.items[:index], append([]*OutlineItem{item}, oi.items[index:]...)...)
 }
 
+// Remove removes the child outline item at the specified index and returns it.
+// Returns nil if the index is out of range.
+func (oi *OutlineItem) Remove(index uint) *OutlineItem {
+	var item *OutlineItem
+	oi.items, item = removeOutlineItem(oi.items, index)
+	return item
+}
+
 // Items returns all children outline items.
 func (oi *OutlineItem) Items() []*OutlineItem {
 	return oi.items
 }
 
 // ToPdfOutlineItem returns a low level PdfOutlineItem object,
-// based on the current instance.
+// based on the current instance, and the number of its descendants
+// which are visible when the item is open.
 func (oi *OutlineItem) ToPdfOutlineItem() (*PdfOutlineItem, int64) {
+	return oi.toPdfOutlineItem(outlineReplacements{})
+}
+
+// toPdfOutlineItem returns the PdfOutlineItem of the item and the number of its visible
+// descendants, registering the replacements of the loaded objects in `replaced`.
+func (oi *OutlineItem) toPdfOutlineItem(replaced outlineReplacements) (*PdfOutlineItem, int64) {
 	// Create outline item.
 	currItem := NewPdfOutlineItem()
-	currItem.Title = core.MakeString(oi.Title)
-	currItem.Dest = oi.Dest.ToPdfObject()
+	replaced.add(oi.primitive, currItem.primitive)
+	currItem.Title = core.MakeTextString(oi.Title)
+	switch {
+	case oi.Destination != nil:
+		dest, err := destinationObject(oi.Destination)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid destination of outline item %q: %v - skipping", oi.Title, err)
+		}
+		currItem.Dest = dest
+	case oi.Action == nil && !(oi.noDest && oi.Dest == OutlineDest{}):
+		currItem.Dest = oi.Dest.ToPdfObject()
+	}
+	if oi.Action != nil {
+		if ctx := oi.Action.GetContext(); ctx != nil {
+			currItem.A = ctx.ToPdfObject()
+		} else {
+			currItem.A = oi.Action.GetContainingPdfObject()
+		}
+	}
+	if oi.Color != nil {
+		currItem.C = core.MakeArrayFromFloats([]float64{oi.Color.R(), oi.Color.G(), oi.Color.B()})
+	}
+	var flags int64
+	if oi.Italic {
+		flags |= 1
+	}
+	if oi.Bold {
+		flags |= 2
+	}
+	if flags != 0 {
+		currItem.F = core.MakeInteger(flags)
+	}
 
 	// Create outline items.
 	var outlineItems []*PdfOutlineItem
-	var lenDescendants int64
+	var lenVisible int64
 	var prev *PdfOutlineItem
 
 	for _, item := range oi.items {
-		outlineItem, lenChildren := item.ToPdfOutlineItem()
+		outlineItem, lenChildren := item.toPdfOutlineItem(replaced)
 		outlineItem.Parent = &currItem.PdfOutlineTreeNode
 
 		if prev != nil {
@@ -169,21 +280,27 @@ func (oi *OutlineItem) ToPdfOutlineItem() (*PdfOutlineItem, int64) {
 		}
 
 		outlineItems = append(outlineItems, outlineItem)
-		lenDescendants += lenChildren
+		lenVisible++
+		if !item.Closed {
+			lenVisible += lenChildren
+		}
 		prev = outlineItem
 	}
 
-	// Add outline item linked list properties.
+	// Add outline item linked list properties. The count is negative
+	// for closed items.
 	lenOutlineItems := len(outlineItems)
-	lenDescendants += int64(lenOutlineItems)
-
 	if lenOutlineItems > 0 {
 		currItem.First = &outlineItems[0].PdfOutlineTreeNode
 		currItem.Last = &outlineItems[lenOutlineItems-1].PdfOutlineTreeNode
-		currItem.Count = &lenDescendants
+		count := lenVisible
+		if oi.Closed {
+			count = -count
+		}
+		currItem.Count = &count
 	}
 
-	return currItem, lenDescendants
+	return currItem, lenVisible
 }
 
 // ToPdfObject returns a PDF object representation of the outline item.
@@ -191,3 +308,100 @@ func (oi *OutlineItem) ToPdfObject() core.PdfObject {
 	outlineItem, _ := oi.ToPdfOutlineItem()
 	return outlineItem.ToPdfObject()
 }
+
+// removeOutlineItem removes the item at `index` from `items`. Returns the
+// resulting items and the removed item, or nil if the index is out of range.
+func removeOutlineItem(items []*OutlineItem, index uint) ([]*OutlineItem, *OutlineItem) {
+	if index >= uint(len(items)) {
+		return items, nil
+	}
+	item := items[index]
+	return append(items[:index], items[index+1:]...), item
+}
+
+// GetOutlines returns the outlines of the document as an Outline, which can
+// be edited and written back, either to a new document (PdfWriter.AddOutlineTree)
+// or in an incremental update (PdfAppender.SetOutlines). Returns an empty
+// outline if the document has no outlines.
+func (r *PdfReader) GetOutlines() (*Outline, error) {
+	outline := NewOutline()
+	if r.outlineTree == nil {
+		return outline, nil
+	}
+	if o, ok := r.outlineTree.context.(*PdfOutline); ok {
+		outline.primitive = o.container
+	}
+
+	items, err := r.newOutlineItems(r.outlineTree, map[*PdfOutlineTreeNode]struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	outline.items = items
+	return outline, nil
+}
+
+// newOutlineItems returns the outline items of the children of `node`.
+func (r *PdfReader) newOutlineItems(node *PdfOutlineTreeNode,
+	visited map[*PdfOutlineTreeNode]struct{}) ([]*OutlineItem, error) {
+	var items []*OutlineItem
+	for child := node.First; child != nil; {
+		if _, ok := visited[child]; ok {
+			break
+		}
+		visited[child] = struct{}{}
+
+		pdfItem, ok := child.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+		item, err := r.newOutlineItem(pdfItem)
+		if err != nil {
+			return nil, err
+		}
+		if item.items, err = r.newOutlineItems(child, visited); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		child = pdfItem.Next
+	}
+	return items, nil
+}
+
+// newOutlineItem returns the outline item of `pdfItem`, without its children.
+func (r *PdfReader) newOutlineItem(pdfItem *PdfOutlineItem) (*OutlineItem, error) {
+	item := &OutlineItem{primitive: pdfItem.container}
+	if pdfItem.Title != nil {
+		item.Title = pdfItem.Title.Decoded()
+	}
+
+	if pdfItem.Dest != nil {
+		dest, err := r.GetDestination(pdfItem.Dest)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid outline item destination: %v", err)
+		} else {
+			item.Destination = dest
+		}
+	}
+	if pdfItem.A != nil {
+		action, err := r.loadAction(pdfItem.A)
+		if err != nil {
+			return nil, err
+		}
+		item.Action = action
+	}
+	item.noDest = item.Destination == nil && item.Action == nil
+
+	if arr, ok := core.GetArray(pdfItem.C); ok && arr.Len() == 3 {
+		if vals, err := arr.ToFloat64Array(); err == nil {
+			item.Color = NewPdfColorDeviceRGB(vals[0], vals[1], vals[2])
+		}
+	}
+	if flags, ok := core.GetIntVal(pdfItem.F); ok {
+		item.Italic = flags&1 != 0
+		item.Bold = flags&2 != 0
+	}
+	if pdfItem.Count != nil {
+		item.Closed = *pdfItem.Count < 0
+	}
+	return item, nil
+}
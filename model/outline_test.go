@@ -0,0 +1,201 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestOutlineCount(t *testing.T) {
+	// 1
+	//   1.1 (closed)
+	//     1.1.1
+	//     1.1.2
+	//   1.2
+	// 2
+	outline := NewOutline()
+	item1 := NewOutlineItem("1", NewOutlineDest(0, 0, 0))
+	item11 := NewOutlineItem("1.1", NewOutlineDest(0, 0, 0))
+	item11.Closed = true
+	item11.Add(NewOutlineItem("1.1.1", NewOutlineDest(0, 0, 0)))
+	item11.Add(NewOutlineItem("1.1.2", NewOutlineDest(0, 0, 0)))
+	item1.Add(item11)
+	item1.Add(NewOutlineItem("1.2", NewOutlineDest(0, 0, 0)))
+	outline.Add(item1)
+	outline.Add(NewOutlineItem("2", NewOutlineDest(0, 0, 0)))
+
+	pdfOutline := outline.ToPdfOutline()
+	require.Equal(t, int64(4), *pdfOutline.Count)
+	pdfItem1 := pdfOutline.First.context.(*PdfOutlineItem)
+	require.Equal(t, int64(2), *pdfItem1.Count)
+	pdfItem11 := pdfItem1.First.context.(*PdfOutlineItem)
+	require.Equal(t, int64(-2), *pdfItem11.Count)
+	require.Nil(t, pdfOutline.Last.context.(*PdfOutlineItem).Count)
+
+	item11.Closed = false
+	require.Equal(t, int64(6), *outline.ToPdfOutline().Count)
+
+	require.Equal(t, item11, item1.Remove(0))
+	require.Nil(t, item1.Remove(1))
+	require.Equal(t, int64(3), *outline.ToPdfOutline().Count)
+}
+
+func TestEditOutlines(t *testing.T) {
+	w := NewPdfWriter()
+	var pages []*PdfPage
+	for i := 0; i < 2; i++ {
+		page := newTestPage(t, 200, 200, "")
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page)
+	}
+
+	uri := NewPdfActionURI()
+	uri.URI = core.MakeString("https://example.com")
+	outline := NewOutline()
+	item := NewOutlineItem("Chapter", OutlineDest{})
+	item.Destination = NewPdfDestinationFit(pages[0])
+	item.Color = NewPdfColorDeviceRGB(1, 0, 0)
+	item.Bold = true
+	item.Closed = true
+	item.Add(NewOutlineItem("Section", NewOutlineDest(1, 0, 200)))
+	outline.Add(item)
+	link := NewOutlineItem("Link", OutlineDest{})
+	link.Action = uri.PdfAction
+	outline.Add(link)
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+
+	r := writeRead(t, &w)
+
+	// Loading.
+	outline, err := r.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Items(), 2)
+	item = outline.Items()[0]
+	require.Equal(t, "Chapter", item.Title)
+	require.Equal(t, PdfDestinationTypeFit, item.Destination.Type)
+	require.Equal(t, 1, item.Destination.PageNum)
+	require.Equal(t, []float64{1, 0, 0}, []float64{item.Color.R(), item.Color.G(), item.Color.B()})
+	require.True(t, item.Bold)
+	require.False(t, item.Italic)
+	require.True(t, item.Closed)
+	require.Len(t, item.Items(), 1)
+	require.Equal(t, 2, item.Items()[0].Destination.PageNum)
+	link = outline.Items()[1]
+	require.Nil(t, link.Destination)
+	require.IsType(t, &PdfActionURI{}, link.Action.GetContext())
+	itemObj := item.primitive
+
+	// Editing and incremental update.
+	item.Title = "Chapter 1 – Introduction"
+	item.Italic = true
+	item.Closed = false
+	item.Add(NewOutlineItem("Conclusion", OutlineDest{}))
+	item.Items()[1].Destination = NewPdfDestinationFitH(r.PageList[1], 100)
+	outline.Insert(0, NewOutlineItem("Cover", NewOutlineDest(0, 0, 200)))
+
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.SetOutlines(outline)
+	r = writeRead(t, appender)
+	require.Equal(t, []string{"Cover", "Chapter 1 – Introduction", "Section", "Conclusion", "Link"},
+		getOutlineTitles(r.GetOutlineTree()))
+	pdfOutline := r.GetOutlineTree().context.(*PdfOutline)
+	require.Equal(t, int64(5), *pdfOutline.Count)
+	pdfItem := pdfOutline.First.context.(*PdfOutlineItem).Next.context.(*PdfOutlineItem)
+	require.Equal(t, int64(2), *pdfItem.Count)
+	require.Equal(t, core.MakeInteger(3), pdfItem.F)
+	require.Equal(t, itemObj.ObjectNumber, pdfItem.container.ObjectNumber)
+
+	outline, err = r.GetOutlines()
+	require.NoError(t, err)
+	dest := outline.Items()[1].Items()[1].Destination
+	require.Equal(t, PdfDestinationTypeFitH, dest.Type)
+	require.Equal(t, 2, dest.PageNum)
+	action := outline.Items()[2].Action.GetContext().(*PdfActionURI)
+	require.Equal(t, "https://example.com", action.URI.(*core.PdfObjectString).Str())
+
+	// Removing the outlines.
+	appender, err = NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.SetOutlines(nil)
+	r = writeRead(t, appender)
+	outline, err = r.GetOutlines()
+	require.NoError(t, err)
+	require.Empty(t, outline.Items())
+}
+
+func TestOutlineInsertLoadedItemTwice(t *testing.T) {
+	w := NewPdfWriter()
+	page := newTestPage(t, 200, 200, "")
+	require.NoError(t, w.AddPage(page))
+	outline := NewOutline()
+	outline.Add(NewOutlineItem("Chapter", NewOutlineDest(0, 0, 200)))
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+	r := writeRead(t, &w)
+
+	// The loaded item is inserted twice: the serialization does not change the loaded objects and
+	// gives the second copy its own object.
+	outline, err := r.GetOutlines()
+	require.NoError(t, err)
+	item := outline.Items()[0]
+	loaded := item.primitive.PdfObject
+	outline.Add(item)
+	pdfOutline := outline.ToPdfOutline()
+	require.Equal(t, loaded, item.primitive.PdfObject)
+	first := pdfOutline.First.context.(*PdfOutlineItem)
+	last := pdfOutline.Last.context.(*PdfOutlineItem)
+	require.True(t, first.primitive != last.primitive)
+
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.SetOutlines(outline)
+	r = writeRead(t, appender)
+	require.Equal(t, []string{"Chapter", "Chapter"}, getOutlineTitles(r.GetOutlineTree()))
+	pdfOutline = r.GetOutlineTree().context.(*PdfOutline)
+	require.Equal(t, int64(2), *pdfOutline.Count)
+	first = pdfOutline.First.context.(*PdfOutlineItem)
+	last = pdfOutline.Last.context.(*PdfOutlineItem)
+	require.Equal(t, item.primitive.ObjectNumber, first.container.ObjectNumber)
+	require.NotEqual(t, first.container.ObjectNumber, last.container.ObjectNumber)
+}
+
+func TestOutlineLoadedItemWithoutDest(t *testing.T) {
+	w := NewPdfWriter()
+	page := newTestPage(t, 200, 200, "")
+	require.NoError(t, w.AddPage(page))
+	item := NewPdfOutlineItem()
+	item.Title = core.MakeString("Heading")
+	outline := NewPdfOutline()
+	item.Parent = &outline.PdfOutlineTreeNode
+	outline.First = &item.PdfOutlineTreeNode
+	outline.Last = &item.PdfOutlineTreeNode
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+	r := writeRead(t, &w)
+
+	// The item without destination is written back without destination.
+	loaded, err := r.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, loaded.Items(), 1)
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.SetOutlines(loaded)
+	r = writeRead(t, appender)
+	pdfItem := r.GetOutlineTree().First.context.(*PdfOutlineItem)
+	require.Equal(t, "Heading", pdfItem.Title.Decoded())
+	require.Nil(t, pdfItem.Dest)
+	require.Nil(t, pdfItem.A)
+
+	// Setting Dest gives it a destination.
+	loaded, err = r.GetOutlines()
+	require.NoError(t, err)
+	loaded.Items()[0].Dest = NewOutlineDest(0, 0, 100)
+	pdfItem = loaded.ToPdfOutline().First.context.(*PdfOutlineItem)
+	require.NotNil(t, pdfItem.Dest)
+}
@@ -27,6 +27,8 @@ type PdfOutline struct {
 	Count  *int64
 
 	primitive *core.PdfIndirectObject
+	// container is the outline dictionary object the outline was loaded from.
+	container *core.PdfIndirectObject
 }
 
 // PdfOutlineItem represents an outline item dictionary (Table 153 - pp. 376 - 377).
@@ -44,6 +46,9 @@ type PdfOutlineItem struct {
 	F      core.PdfObject
 
 	primitive *core.PdfIndirectObject
+	// container is the outline item dictionary object the item was loaded from.
+	container *core.PdfIndirectObject
+	reader    *PdfReader
 }
 
 // NewPdfOutline returns an initialized PdfOutline.
@@ -96,6 +101,7 @@ func newPdfOutlineFromIndirectObject(container *core.PdfIndirectObject) (*PdfOut
 	}
 
 	outline := NewPdfOutline()
+	outline.container = container
 
 	if obj := dict.Get("Type"); obj != nil {
 		typeVal, ok := obj.(*core.PdfObjectName)
@@ -129,6 +135,8 @@ func (r *PdfReader) newPdfOutlineItemFromIndirectObject(container *core.PdfIndir
 	}
 
 	item := NewPdfOutlineItem()
+	item.container = container
+	item.reader = r
 
 	// Title (required).
 	obj := dict.Get("Title")
@@ -0,0 +1,158 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model/icc"
+)
+
+// Output intent subtypes (section 14.11.5 "Output Intents" p. 633 PDF32000_2008).
+const (
+	OutputIntentPDFX  = "GTS_PDFX"
+	OutputIntentPDFA1 = "GTS_PDFA1"
+	OutputIntentISO   = "ISO_PDFE1"
+)
+
+// PdfOutputIntent represents an output intent dictionary (Table 365 - p. 634), which describes
+// the color characteristics of the output device the document is intended for, e.g. the print
+// condition. The profile of CMYK intents can be used for converting DeviceCMYK colors to RGB with
+// NewColorConversion.
+type PdfOutputIntent struct {
+	S                         string // Subtype, e.g. OutputIntentPDFX.
+	OutputCondition           string
+	OutputConditionIdentifier string // Identifier of the output condition, e.g. "FOGRA39".
+	RegistryName              string
+	Info                      string
+
+	// DestOutputProfile is the ICC profile of the output condition. It is required unless
+	// OutputConditionIdentifier identifies a standard condition of the registry.
+	DestOutputProfile []byte
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfOutputIntent returns an output intent of subtype `subtype` with the output condition
+// `identifier` and ICC profile `profile`.
+func NewPdfOutputIntent(subtype, identifier string, profile []byte) *PdfOutputIntent {
+	return &PdfOutputIntent{
+		S:                         subtype,
+		OutputConditionIdentifier: identifier,
+		DestOutputProfile:         profile,
+	}
+}
+
+// Profile returns the parsed destination output profile of the output intent.
+func (oi *PdfOutputIntent) Profile() (*icc.Profile, error) {
+	if len(oi.DestOutputProfile) == 0 {
+		return nil, errors.New("output intent without profile")
+	}
+	return icc.Parse(oi.DestOutputProfile)
+}
+
+// newPdfOutputIntentFromObj loads the output intent from dictionary `obj`.
+func newPdfOutputIntentFromObj(obj core.PdfObject) (*PdfOutputIntent, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+
+	oi := &PdfOutputIntent{}
+	if indObj, ok := obj.(*core.PdfIndirectObject); ok {
+		oi.container = indObj
+	}
+	if name, ok := core.GetNameVal(dict.Get("S")); ok {
+		oi.S = name
+	}
+	text := func(key core.PdfObjectName) string {
+		if str, ok := core.GetString(dict.Get(key)); ok {
+			return str.Decoded()
+		}
+		return ""
+	}
+	oi.OutputCondition = text("OutputCondition")
+	oi.OutputConditionIdentifier = text("OutputConditionIdentifier")
+	oi.RegistryName = text("RegistryName")
+	oi.Info = text("Info")
+
+	if stream, ok := core.GetStream(dict.Get("DestOutputProfile")); ok {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		oi.DestOutputProfile = data
+	}
+	return oi, nil
+}
+
+// ToPdfObject returns the output intent as a PDF dictionary, with the profile in a stream.
+func (oi *PdfOutputIntent) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("OutputIntent"))
+	dict.Set("S", core.MakeName(oi.S))
+	setText := func(key core.PdfObjectName, value string) {
+		if value != "" {
+			dict.Set(key, core.MakeString(value))
+		}
+	}
+	setText("OutputCondition", oi.OutputCondition)
+	setText("OutputConditionIdentifier", oi.OutputConditionIdentifier)
+	setText("RegistryName", oi.RegistryName)
+	setText("Info", oi.Info)
+
+	if len(oi.DestOutputProfile) > 0 {
+		stream, err := core.MakeStream(oi.DestOutputProfile, core.NewFlateEncoder())
+		if err != nil {
+			common.Log.Debug("ERROR: output profile stream: %v", err)
+		} else {
+			if profile, err := icc.Parse(oi.DestOutputProfile); err == nil {
+				stream.Set("N", core.MakeInteger(int64(profile.NumComponents())))
+			}
+			dict.Set("DestOutputProfile", stream)
+		}
+	}
+
+	if oi.container != nil {
+		oi.container.PdfObject = dict
+		return oi.container
+	}
+	return dict
+}
+
+// GetOutputIntents returns the output intents of the document, or nil if it has none.
+func (r *PdfReader) GetOutputIntents() ([]*PdfOutputIntent, error) {
+	obj := core.ResolveReference(r.catalog.Get("OutputIntents"))
+	array, ok := core.GetArray(obj)
+	if !ok {
+		return nil, nil
+	}
+
+	if !r.isLazy {
+		err := r.traverseObjectData(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var intents []*PdfOutputIntent
+	for _, obj := range array.Elements() {
+		oi, err := newPdfOutputIntentFromObj(core.ResolveReference(obj))
+		if err != nil {
+			common.Log.Debug("Invalid output intent: %v", err)
+			continue
+		}
+		intents = append(intents, oi)
+	}
+	return intents, nil
+}
+
+// AddOutputIntent adds output intent `intent` to the output document.
+func (w *PdfWriter) AddOutputIntent(intent *PdfOutputIntent) {
+	w.outputIntents = append(w.outputIntents, intent)
+}
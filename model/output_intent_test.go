@@ -0,0 +1,119 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/model/icc"
+)
+
+// linearGrayProfile returns an ICC gray profile with a linear tone reproduction curve.
+func linearGrayProfile() []byte {
+	data := make([]byte, 160)
+	binary.BigEndian.PutUint32(data, 160)
+	binary.BigEndian.PutUint32(data[8:], 0x04300000)
+	copy(data[12:], "mntrGRAYXYZ ")
+	copy(data[36:], "acsp")
+	binary.BigEndian.PutUint32(data[128:], 1)
+	copy(data[132:], "kTRC")
+	binary.BigEndian.PutUint32(data[136:], 144)
+	binary.BigEndian.PutUint32(data[140:], 14)
+	copy(data[144:], "curv\x00\x00\x00\x00\x00\x00\x00\x01\x01\x00")
+	return data
+}
+
+func TestOutputIntentReadWrite(t *testing.T) {
+	w := NewPdfWriter()
+	page := newTestPage(t, 612, 792, "")
+	require.NoError(t, w.AddPage(page))
+
+	intent := NewPdfOutputIntent(OutputIntentPDFA1, "Linear gray", linearGrayProfile())
+	intent.Info = "Test profile"
+	w.AddOutputIntent(intent)
+
+	reader := writeRead(t, &w)
+	intents, err := reader.GetOutputIntents()
+	require.NoError(t, err)
+	require.Len(t, intents, 1)
+	require.Equal(t, OutputIntentPDFA1, intents[0].S)
+	require.Equal(t, "Linear gray", intents[0].OutputConditionIdentifier)
+	require.Equal(t, "Test profile", intents[0].Info)
+	require.Equal(t, linearGrayProfile(), intents[0].DestOutputProfile)
+
+	profile, err := intents[0].Profile()
+	require.NoError(t, err)
+	require.Equal(t, icc.ColorSpaceGray, profile.ColorSpace)
+
+	// Only CMYK profiles apply to DeviceCMYK colors.
+	_, err = NewColorConversion(profile)
+	require.Error(t, err)
+	cc, err := NewColorConversion(nil)
+	require.NoError(t, err)
+	require.Nil(t, cc.DeviceCMYKProfile())
+}
+
+func TestColorConversion(t *testing.T) {
+	// The DeviceCMYK colors are converted with the formula without profile.
+	indexed := NewPdfColorspaceSpecialIndexed()
+	indexed.Base = NewPdfColorspaceDeviceCMYK()
+	var cc *ColorConversion
+	require.True(t, cc.Colorspace(indexed) == PdfColorspace(indexed))
+	color, err := indexed.Base.ColorToRGB(NewPdfColorDeviceCMYK(0.5, 0, 0, 0))
+	require.NoError(t, err)
+	require.Equal(t, NewPdfColorDeviceRGB(0.5, 1, 1), color)
+
+	// The transform of the linear gray profile converts the cyan component only.
+	profile, err := icc.Parse(linearGrayProfile())
+	require.NoError(t, err)
+	transform, err := icc.NewTransform(profile, icc.SRGB(), icc.RelativeColorimetric)
+	require.NoError(t, err)
+	cc = &ColorConversion{cmykTransform: transform}
+	converted, ok := cc.Colorspace(indexed).(*PdfColorspaceSpecialIndexed)
+	require.True(t, ok)
+	require.True(t, converted != indexed)
+	require.Nil(t, indexed.Base.(*PdfColorspaceDeviceCMYK).transform)
+	color, err = converted.Base.ColorToRGB(NewPdfColorDeviceCMYK(0.5, 0, 0, 0))
+	require.NoError(t, err)
+	rgb := color.(*PdfColorDeviceRGB)
+	require.InDelta(t, 0.7354, rgb.R(), 1e-3)
+	require.InDelta(t, 0.7354, rgb.B(), 1e-3)
+
+	// Color spaces without DeviceCMYK are not copied.
+	gray := NewPdfColorspaceDeviceGray()
+	require.True(t, cc.Colorspace(gray) == PdfColorspace(gray))
+}
+
+func TestICCBasedToRGB(t *testing.T) {
+	cs, err := NewPdfColorspaceICCBased(1)
+	require.NoError(t, err)
+	cs.Data = linearGrayProfile()
+	cs.Range = []float64{0, 1}
+
+	// The linear gray is encoded with the sRGB curve.
+	color, err := cs.ColorToRGB(NewPdfColorDeviceGray(0.5))
+	require.NoError(t, err)
+	rgb, ok := color.(*PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.InDelta(t, 0.7354, rgb.R(), 1e-3)
+	require.InDelta(t, 0.7354, rgb.B(), 1e-3)
+
+	img := Image{Width: 3, Height: 1, BitsPerComponent: 8, ColorComponents: 1, Data: []byte{0, 128, 255}}
+	rgbImg, err := cs.ImageToRGB(img)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), rgbImg.BitsPerComponent)
+	require.Equal(t, 3, rgbImg.ColorComponents)
+	require.Equal(t, []byte{0, 0, 0, 188, 188, 188, 255, 255, 255}, rgbImg.Data)
+
+	// Invalid profiles fall back to the alternate colorspace.
+	cs.Data = []byte("invalid")
+	color, err = cs.ColorToRGB(NewPdfColorDeviceGray(0.5))
+	require.NoError(t, err)
+	require.Equal(t, NewPdfColorDeviceRGB(0.5, 0.5, 0.5), color)
+}
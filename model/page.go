@@ -279,6 +279,16 @@ func (r *PdfReader) newPdfPageFromDict(p *core.PdfObjectDictionary) (*PdfPage, e
 	return page, nil
 }
 
+// GetOptionalContentProperties returns the optional content properties (layers) of the
+// document containing `page`, or nil if the page was not loaded from a document with
+// optional content.
+func (page *PdfPage) GetOptionalContentProperties() (*PdfOCProperties, error) {
+	if page.reader == nil {
+		return nil, nil
+	}
+	return page.reader.GetOptionalContentProperties()
+}
+
 // GetAnnotations returns the list of page annotations for `page`. If not loaded attempts to load the
 // annotations, otherwise returns the loaded list.
 func (page *PdfPage) GetAnnotations() ([]*PdfAnnotation, error) {
@@ -0,0 +1,480 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// PdfPageBox identifies one of the page boundaries (14.11.2 - Page Boundaries).
+type PdfPageBox string
+
+// Page boundaries.
+const (
+	PageBoxMedia PdfPageBox = "MediaBox"
+	PageBoxCrop  PdfPageBox = "CropBox"
+	PageBoxBleed PdfPageBox = "BleedBox"
+	PageBoxTrim  PdfPageBox = "TrimBox"
+	PageBoxArt   PdfPageBox = "ArtBox"
+)
+
+// PaperSize represents the size of a sheet of paper in points.
+type PaperSize struct {
+	Width  float64
+	Height float64
+}
+
+// Common paper sizes (portrait).
+var (
+	PaperSizeA3     = PaperSize{297 * 72 / 25.4, 420 * 72 / 25.4}
+	PaperSizeA4     = PaperSize{210 * 72 / 25.4, 297 * 72 / 25.4}
+	PaperSizeA5     = PaperSize{148 * 72 / 25.4, 210 * 72 / 25.4}
+	PaperSizeLetter = PaperSize{8.5 * 72, 11 * 72}
+	PaperSizeLegal  = PaperSize{8.5 * 72, 14 * 72}
+)
+
+// PageResizeMode specifies how PdfPage.Resize scales the page contents.
+type PageResizeMode int
+
+// Page resize modes.
+const (
+	// PageResizeFit scales the contents uniformly so that they fit the new size, centered.
+	PageResizeFit PageResizeMode = iota
+
+	// PageResizeStretch scales the contents independently in each direction so that they fill
+	// the new size.
+	PageResizeStretch
+)
+
+// box returns a pointer to the field of `p` for page boundary `box`.
+func (p *PdfPage) box(box PdfPageBox) (**PdfRectangle, error) {
+	switch box {
+	case PageBoxMedia:
+		return &p.MediaBox, nil
+	case PageBoxCrop:
+		return &p.CropBox, nil
+	case PageBoxBleed:
+		return &p.BleedBox, nil
+	case PageBoxTrim:
+		return &p.TrimBox, nil
+	case PageBoxArt:
+		return &p.ArtBox, nil
+	}
+	return nil, fmt.Errorf("invalid page box %s", box)
+}
+
+// GetBox returns the effective page boundary `box`, accounting for inheritance from the parent
+// page tree nodes (MediaBox and CropBox) and the default values: the crop box defaults to the media
+// box, and the bleed, trim and art boxes default to the crop box. The boxes other than the media
+// box are reduced to their intersection with the media box.
+func (p *PdfPage) GetBox(box PdfPageBox) (*PdfRectangle, error) {
+	field, err := p.box(box)
+	if err != nil {
+		return nil, err
+	}
+	if box == PageBoxMedia {
+		return p.GetMediaBox()
+	}
+
+	mediaBox, err := p.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	rect := *field
+	if rect == nil && box == PageBoxCrop {
+		rect, err = p.getInheritedRectangle(PageBoxCrop)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rect == nil {
+		if box == PageBoxCrop {
+			return mediaBox, nil
+		}
+		return p.GetBox(PageBoxCrop)
+	}
+
+	clipped := rect.Normalized()
+	clipped = clipped.Intersect(mediaBox.Normalized())
+	return &clipped, nil
+}
+
+// SetBox sets page boundary `box` of the page to `rect`. Setting nil removes the boundary from the
+// page, which then falls back to the inherited or default value (see GetBox).
+func (p *PdfPage) SetBox(box PdfPageBox, rect *PdfRectangle) error {
+	field, err := p.box(box)
+	if err != nil {
+		return err
+	}
+	if rect != nil {
+		r := *rect
+		rect = &r
+	}
+	*field = rect
+	return nil
+}
+
+// GetCropBox returns the effective crop box of the page, i.e. the visible region of the page.
+// See GetBox.
+func (p *PdfPage) GetCropBox() (*PdfRectangle, error) {
+	return p.GetBox(PageBoxCrop)
+}
+
+// GetRotate returns the effective clockwise rotation of the page in degrees, accounting for
+// inheritance from the parent page tree nodes. The returned value is one of 0, 90, 180, 270.
+func (p *PdfPage) GetRotate() (int64, error) {
+	rotate := p.Rotate
+	if rotate == nil {
+		obj, err := p.getInheritedAttribute("Rotate")
+		if err != nil {
+			return 0, err
+		}
+		if obj != nil {
+			val, ok := core.GetIntVal(obj)
+			if !ok {
+				return 0, errors.New("invalid page Rotate object")
+			}
+			r := int64(val)
+			rotate = &r
+		}
+	}
+	if rotate == nil {
+		return 0, nil
+	}
+	if *rotate%90 != 0 {
+		common.Log.Debug("ERROR: Page rotation %d not a multiple of 90 - ignoring", *rotate)
+		return 0, nil
+	}
+	return (*rotate%360 + 360) % 360, nil
+}
+
+// getInheritedAttribute returns the value of inheritable page attribute `name` from the parent
+// page tree nodes of the page, or nil if not defined.
+func (p *PdfPage) getInheritedAttribute(name core.PdfObjectName) (core.PdfObject, error) {
+	node := p.Parent
+	for node != nil {
+		dict, ok := core.GetDict(node)
+		if !ok {
+			return nil, errors.New("invalid parent objects dictionary")
+		}
+		if obj := dict.Get(name); obj != nil {
+			return obj, nil
+		}
+		node = dict.Get("Parent")
+	}
+	return nil, nil
+}
+
+// getInheritedRectangle returns the rectangle of inheritable page boundary `box` from the parent
+// page tree nodes of the page, or nil if not defined.
+func (p *PdfPage) getInheritedRectangle(box PdfPageBox) (*PdfRectangle, error) {
+	obj, err := p.getInheritedAttribute(core.PdfObjectName(box))
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s", box)
+	}
+	return NewPdfRectangle(*arr)
+}
+
+// Scale scales the page by `sx` horizontally and `sy` vertically: the contents, the page
+// boundaries and the annotations are scaled.
+func (p *PdfPage) Scale(sx, sy float64) error {
+	if sx <= 0 || sy <= 0 {
+		return errors.New("invalid scale factors")
+	}
+	return p.transform(transform.NewMatrix(sx, 0, 0, sy, 0, 0), nil)
+}
+
+// Resize scales the page to the size `width` x `height`, as displayed (i.e. with the page
+// rotation applied), with the scaling specified by `mode`. The visible region of the page (crop
+// box) is mapped to the new media box, and the page contents outside of it are clipped.
+// The annotations are scaled along with the contents.
+func (p *PdfPage) Resize(width, height float64, mode PageResizeMode) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("invalid page size")
+	}
+	cropBox, err := p.GetCropBox()
+	if err != nil {
+		return err
+	}
+	rotate, err := p.GetRotate()
+	if err != nil {
+		return err
+	}
+	if rotate%180 != 0 {
+		width, height = height, width
+	}
+	if cropBox.Width() == 0 || cropBox.Height() == 0 {
+		return errors.New("empty page crop box")
+	}
+
+	sx := width / cropBox.Width()
+	sy := height / cropBox.Height()
+	var tx, ty float64
+	if mode == PageResizeFit {
+		sx = math.Min(sx, sy)
+		sy = sx
+		tx = (width - cropBox.Width()*sx) / 2
+		ty = (height - cropBox.Height()*sy) / 2
+	}
+	m := transform.NewMatrix(sx, 0, 0, sy, tx-cropBox.Llx*sx, ty-cropBox.Lly*sy)
+
+	clip := cropBox.Transform(m)
+	if err := p.transform(m, &clip); err != nil {
+		return err
+	}
+	p.MediaBox = &PdfRectangle{Urx: width, Ury: height}
+	p.CropBox = nil
+	return p.overrideInherited()
+}
+
+// NormalizeRotation bakes the rotation of the page (Rotate entry) into the page contents, so that
+// the page displays the same way with a rotation of 0. The page boundaries and annotations are
+// rotated along with the contents.
+func (p *PdfPage) NormalizeRotation() error {
+	rotate, err := p.GetRotate()
+	if err != nil {
+		return err
+	}
+	if rotate == 0 {
+		if p.Rotate != nil {
+			r := int64(0)
+			p.Rotate = &r
+		}
+		return nil
+	}
+	mediaBox, err := p.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	// Rotate clockwise about the media box, which is moved to the origin.
+	w, h := mediaBox.Width(), mediaBox.Height()
+	var m transform.Matrix
+	switch rotate {
+	case 90:
+		m = transform.NewMatrix(0, -1, 1, 0, 0, w)
+	case 180:
+		m = transform.NewMatrix(-1, 0, 0, -1, w, h)
+	case 270:
+		m = transform.NewMatrix(0, 1, -1, 0, h, 0)
+	}
+	m = m.Mult(transform.TranslationMatrix(-mediaBox.Llx, -mediaBox.Lly))
+
+	if err := p.transform(m, nil); err != nil {
+		return err
+	}
+	r := int64(0)
+	p.Rotate = &r
+	return nil
+}
+
+// transform applies transform `m` to the contents, page boundaries and annotations of the page.
+// The contents are clipped to `clip` if not nil.
+func (p *PdfPage) transform(m transform.Matrix, clip *PdfRectangle) error {
+	// Page boundaries, including the inherited ones which are set on the page.
+	mediaBox, err := p.GetMediaBox()
+	if err != nil {
+		return err
+	}
+	rect := mediaBox.Transform(m)
+	p.MediaBox = &rect
+
+	cropBox := p.CropBox
+	if cropBox == nil {
+		if cropBox, err = p.getInheritedRectangle(PageBoxCrop); err != nil {
+			return err
+		}
+	}
+	if cropBox != nil {
+		rect := cropBox.Transform(m)
+		p.CropBox = &rect
+	}
+	for _, box := range []**PdfRectangle{&p.BleedBox, &p.TrimBox, &p.ArtBox} {
+		if *box != nil {
+			rect := (*box).Transform(m)
+			*box = &rect
+		}
+	}
+
+	// Contents.
+	cstreams, err := p.GetContentStreams()
+	if err != nil {
+		return err
+	}
+	if len(cstreams) > 0 {
+		prefix := "q\n"
+		if clip != nil {
+			prefix += fmt.Sprintf("%.4f %.4f %.4f %.4f re W n\n",
+				clip.Llx, clip.Lly, clip.Width(), clip.Height())
+		}
+		prefix += fmt.Sprintf("%.6f %.6f %.6f %.6f %.4f %.4f cm\n", m[0], m[1], m[3], m[4], m[6], m[7])
+		cstreams[0] = prefix + cstreams[0]
+		cstreams[len(cstreams)-1] += "\nQ"
+		if err := p.SetContentStreams(cstreams, core.NewFlateEncoder()); err != nil {
+			return err
+		}
+	}
+
+	// Annotations.
+	annotations, err := p.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	appearances := map[*core.PdfObjectStream]struct{}{}
+	for _, annot := range annotations {
+		annot.Transform(m)
+		rotateAppearances(annot, m, appearances)
+	}
+
+	return p.overrideInherited()
+}
+
+// overrideInherited sets the inheritable attributes which are inherited from the parent page
+// tree nodes explicitly on the page, so that they are not inherited anymore, as they are no longer
+// valid after the page geometry has been changed.
+func (p *PdfPage) overrideInherited() error {
+	if p.CropBox == nil {
+		inherited, err := p.getInheritedRectangle(PageBoxCrop)
+		if err != nil {
+			return err
+		}
+		if inherited != nil {
+			mediaBox := *p.MediaBox
+			p.CropBox = &mediaBox
+		}
+	}
+	if p.Rotate == nil {
+		rotate, err := p.getInheritedAttribute("Rotate")
+		if err != nil {
+			return err
+		}
+		if rotate != nil {
+			r, err := p.GetRotate()
+			if err != nil {
+				return err
+			}
+			p.Rotate = &r
+		}
+	}
+	return nil
+}
+
+// Transform transforms the geometry of the annotation by `m`: its rectangle and the point
+// coordinates of its QuadPoints, Vertices, L, CL and InkList entries. The appearance streams are
+// left unchanged.
+func (annot *PdfAnnotation) Transform(m transform.Matrix) {
+	if rect, ok := GetPdfRectangle(annot.Rect); ok {
+		rect = rect.Transform(m)
+		annot.Rect = rect.ToPdfObject()
+	}
+
+	points := func(obj core.PdfObject) core.PdfObject {
+		if arr, ok := transformPointArray(obj, m); ok {
+			return arr
+		}
+		return obj
+	}
+	switch t := annot.GetContext().(type) {
+	case *PdfAnnotationLink:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationFreeText:
+		t.CL = points(t.CL)
+	case *PdfAnnotationLine:
+		t.L = points(t.L)
+	case *PdfAnnotationPolygon:
+		t.Vertices = points(t.Vertices)
+	case *PdfAnnotationPolyLine:
+		t.Vertices = points(t.Vertices)
+	case *PdfAnnotationHighlight:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationUnderline:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationSquiggly:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationStrikeOut:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationRedact:
+		t.QuadPoints = points(t.QuadPoints)
+	case *PdfAnnotationInk:
+		if inkList, ok := core.GetArray(t.InkList); ok {
+			paths := core.MakeArray()
+			for _, path := range inkList.Elements() {
+				paths.Append(points(path))
+			}
+			t.InkList = paths
+		}
+	}
+}
+
+// rotateAppearances applies the rotation of `m` to the appearance streams of `annot`.
+// `appearances` holds the appearance streams which are already processed.
+func rotateAppearances(annot *PdfAnnotation, m transform.Matrix, appearances map[*core.PdfObjectStream]struct{}) {
+	// The appearance streams are scaled to the annotation rectangle by the viewers, but the
+	// rotation needs to be applied to them.
+	if m[1] == 0 && m[3] == 0 {
+		return
+	}
+	apDict, ok := core.GetDict(annot.AP)
+	if !ok {
+		return
+	}
+	rotation := transform.NewMatrix(m[0], m[1], m[3], m[4], 0, 0)
+	for _, key := range apDict.Keys() {
+		var streams []*core.PdfObjectStream
+		if stream, ok := core.GetStream(apDict.Get(key)); ok {
+			streams = append(streams, stream)
+		} else if states, ok := core.GetDict(apDict.Get(key)); ok {
+			for _, state := range states.Keys() {
+				if stream, ok := core.GetStream(states.Get(state)); ok {
+					streams = append(streams, stream)
+				}
+			}
+		}
+		for _, stream := range streams {
+			if _, processed := appearances[stream]; processed {
+				continue
+			}
+			appearances[stream] = struct{}{}
+
+			formMatrix := transform.IdentityMatrix()
+			if arr, ok := core.GetArray(stream.Get("Matrix")); ok {
+				if vals, err := arr.ToFloat64Array(); err == nil && len(vals) == 6 {
+					formMatrix = transform.NewMatrix(vals[0], vals[1], vals[2], vals[3], vals[4], vals[5])
+				}
+			}
+			formMatrix = rotation.Mult(formMatrix)
+			stream.Set("Matrix", core.MakeArrayFromFloats([]float64{
+				formMatrix[0], formMatrix[1], formMatrix[3], formMatrix[4], formMatrix[6], formMatrix[7],
+			}))
+		}
+	}
+}
+
+// transformPointArray returns the array of point coordinates `obj` transformed by `m`.
+func transformPointArray(obj core.PdfObject, m transform.Matrix) (*core.PdfObjectArray, bool) {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil, false
+	}
+	vals, err := arr.ToFloat64Array()
+	if err != nil || len(vals)%2 != 0 {
+		return nil, false
+	}
+	for i := 0; i < len(vals); i += 2 {
+		vals[i], vals[i+1] = m.Transform(vals[i], vals[i+1])
+	}
+	return core.MakeArrayFromFloats(vals), true
+}
@@ -0,0 +1,141 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// newGeometryTestPage returns a page of size 200x100 with a parent node defining a crop box and
+// a rotation, and a highlight annotation.
+func newGeometryTestPage(t *testing.T) (*PdfPage, *PdfAnnotationHighlight) {
+	parent := core.MakeDict()
+	parent.Set("Type", core.MakeName("Pages"))
+	parent.Set("CropBox", core.MakeArrayFromFloats([]float64{10, 10, 190, 90}))
+	parent.Set("Rotate", core.MakeInteger(90))
+
+	page := newTestPage(t, 200, 100, "0 0 1 rg 20 20 50 50 re f")
+	page.Parent = core.MakeIndirectObject(parent)
+	page.TrimBox = &PdfRectangle{Llx: -10, Lly: 20, Urx: 180, Ury: 80}
+
+	highlight := NewPdfAnnotationHighlight()
+	highlight.Rect = core.MakeArrayFromFloats([]float64{20, 20, 70, 30})
+	highlight.QuadPoints = core.MakeArrayFromFloats([]float64{20, 30, 70, 30, 20, 20, 70, 20})
+	page.AddAnnotation(highlight.PdfAnnotation)
+	return page, highlight
+}
+
+// requireFloats checks that `obj` is an array of numbers equal to `expected`.
+func requireFloats(t *testing.T, expected []float64, obj core.PdfObject) {
+	arr, ok := core.GetArray(obj)
+	require.True(t, ok)
+	vals, err := arr.ToFloat64Array()
+	require.NoError(t, err)
+	require.InDeltaSlice(t, expected, vals, 1e-6)
+}
+
+func TestPageBoxes(t *testing.T) {
+	page, _ := newGeometryTestPage(t)
+
+	box, err := page.GetCropBox()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{10, 10, 190, 90}, *box)
+
+	// Defaults to the crop box.
+	box, err = page.GetBox(PageBoxArt)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{10, 10, 190, 90}, *box)
+
+	// Clipped to the media box.
+	box, err = page.GetBox(PageBoxTrim)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{0, 20, 180, 80}, *box)
+
+	require.NoError(t, page.SetBox(PageBoxCrop, &PdfRectangle{0, 0, 100, 100}))
+	box, err = page.GetCropBox()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{0, 0, 100, 100}, *box)
+	require.Error(t, page.SetBox("Box", nil))
+
+	rotate, err := page.GetRotate()
+	require.NoError(t, err)
+	require.Equal(t, int64(90), rotate)
+}
+
+func TestPageResize(t *testing.T) {
+	page, highlight := newGeometryTestPage(t)
+
+	// The page is displayed rotated: the displayed size is 80x180, fit to 160x400.
+	require.NoError(t, page.Resize(160, 400, PageResizeFit))
+	require.Equal(t, PdfRectangle{Urx: 400, Ury: 160}, *page.MediaBox)
+	require.Equal(t, PdfRectangle{Urx: 400, Ury: 160}, *page.CropBox)
+	require.Equal(t, int64(90), *page.Rotate)
+
+	// Scale 2, crop box moved to (20, 0).
+	requireFloats(t, []float64{40, 20, 140, 40}, highlight.Rect)
+	requireFloats(t, []float64{40, 40, 140, 40, 40, 20, 140, 20}, highlight.QuadPoints)
+	require.Equal(t, PdfRectangle{-20, 20, 360, 140}, *page.TrimBox)
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(content,
+		"q\n20.0000 0.0000 360.0000 160.0000 re W n\n2.000000 0.000000 0.000000 2.000000 0.0000 -20.0000 cm\n"))
+	require.True(t, strings.HasSuffix(content, "\nQ"))
+
+	page, _ = newGeometryTestPage(t)
+	require.NoError(t, page.Scale(2, 3))
+	require.Equal(t, PdfRectangle{Urx: 400, Ury: 300}, *page.MediaBox)
+	require.Equal(t, PdfRectangle{20, 30, 380, 270}, *page.CropBox)
+	require.Error(t, page.Scale(0, 1))
+}
+
+func TestPageNormalizeRotation(t *testing.T) {
+	page, highlight := newGeometryTestPage(t)
+	require.NoError(t, page.NormalizeRotation())
+
+	require.Equal(t, int64(0), *page.Rotate)
+	require.Equal(t, PdfRectangle{Urx: 100, Ury: 200}, *page.MediaBox)
+	require.Equal(t, PdfRectangle{10, 10, 90, 190}, *page.CropBox)
+
+	// The top left corner of the page is now at the top right corner.
+	requireFloats(t, []float64{20, 130, 30, 180}, highlight.Rect)
+	requireFloats(t, []float64{30, 180, 30, 130, 20, 180, 20, 130}, highlight.QuadPoints)
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "0.000000 -1.000000 1.000000 0.000000 0.0000 200.0000 cm")
+
+	rotate, err := page.GetRotate()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), rotate)
+}
+
+func TestPdfRectangleGeometry(t *testing.T) {
+	rect, ok := GetPdfRectangle(core.MakeArrayFromFloats([]float64{100, 50, 0, 0}))
+	require.True(t, ok)
+	_, ok = GetPdfRectangle(core.MakeArrayFromFloats([]float64{0, 0, 1}))
+	require.False(t, ok)
+
+	rect = rect.Normalized()
+	require.Equal(t, PdfRectangle{Urx: 100, Ury: 50}, rect)
+	// Rotation by 90 degrees counterclockwise.
+	require.Equal(t, PdfRectangle{Llx: -50, Urx: 0, Ury: 100},
+		rect.Transform(transform.NewMatrix(0, 1, -1, 0, 0, 0)))
+
+	other := PdfRectangle{Llx: 80, Lly: 40, Urx: 200, Ury: 200}
+	require.True(t, rect.Overlaps(other))
+	require.Equal(t, PdfRectangle{Llx: 80, Lly: 40, Urx: 100, Ury: 50}, rect.Intersect(other))
+	other = PdfRectangle{Llx: 100, Urx: 200, Ury: 200}
+	require.False(t, rect.Overlaps(other))
+	empty := rect.Intersect(other)
+	require.Equal(t, 0.0, empty.Width())
+}
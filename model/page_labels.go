@@ -0,0 +1,334 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfPageLabelStyle represents the numbering style of a page label range
+// (section 12.4.2 "Page Labels" p. 383).
+type PdfPageLabelStyle string
+
+// Page label numbering styles (Table 159 - Entries in a page label dictionary).
+const (
+	// PageLabelStyleNone produces labels consisting of the prefix only.
+	PageLabelStyleNone PdfPageLabelStyle = ""
+	// PageLabelStyleDecimal produces decimal arabic numerals (1, 2, 3...).
+	PageLabelStyleDecimal PdfPageLabelStyle = "D"
+	// PageLabelStyleUpperRoman produces uppercase roman numerals (I, II, III...).
+	PageLabelStyleUpperRoman PdfPageLabelStyle = "R"
+	// PageLabelStyleLowerRoman produces lowercase roman numerals (i, ii, iii...).
+	PageLabelStyleLowerRoman PdfPageLabelStyle = "r"
+	// PageLabelStyleUpperLetters produces uppercase letters (A to Z, then AA to ZZ...).
+	PageLabelStyleUpperLetters PdfPageLabelStyle = "A"
+	// PageLabelStyleLowerLetters produces lowercase letters (a to z, then aa to zz...).
+	PageLabelStyleLowerLetters PdfPageLabelStyle = "a"
+)
+
+// PdfPageLabel represents a page label dictionary (Table 159 - p. 385), which defines
+// the labeling of a range of pages.
+type PdfPageLabel struct {
+	Style  PdfPageLabelStyle
+	Prefix string
+
+	// Start is the value of the numeric portion of the first label in the range.
+	// Values lower than 1 are treated as 1.
+	Start int
+}
+
+// Format returns the label of the page at offset `offset` (0-based) within the range.
+func (l PdfPageLabel) Format(offset int) string {
+	start := l.Start
+	if start < 1 {
+		start = 1
+	}
+	n := start + offset
+
+	var num string
+	switch l.Style {
+	case PageLabelStyleDecimal:
+		num = strconv.Itoa(n)
+	case PageLabelStyleUpperRoman:
+		num = strings.ToUpper(formatRoman(n))
+	case PageLabelStyleLowerRoman:
+		num = formatRoman(n)
+	case PageLabelStyleUpperLetters:
+		num = strings.ToUpper(formatLetters(n))
+	case PageLabelStyleLowerLetters:
+		num = formatLetters(n)
+	}
+	return l.Prefix + num
+}
+
+// parse returns the offset within the range of the page labeled `label`.
+// The bool flag is false if the label cannot belong to the range.
+func (l PdfPageLabel) parse(label string) (int, bool) {
+	if !strings.HasPrefix(label, l.Prefix) {
+		return 0, false
+	}
+	num := label[len(l.Prefix):]
+	start := l.Start
+	if start < 1 {
+		start = 1
+	}
+
+	var n int
+	switch l.Style {
+	case PageLabelStyleNone:
+		if num != "" {
+			return 0, false
+		}
+		return 0, true
+	case PageLabelStyleDecimal:
+		val, err := strconv.Atoi(num)
+		if err != nil || strconv.Itoa(val) != num {
+			return 0, false
+		}
+		n = val
+	case PageLabelStyleUpperRoman, PageLabelStyleLowerRoman:
+		n = parseRoman(strings.ToLower(num))
+	case PageLabelStyleUpperLetters, PageLabelStyleLowerLetters:
+		n = parseLetters(strings.ToLower(num))
+	}
+	if n < start || l.Format(n-start) != label {
+		return 0, false
+	}
+	return n - start, true
+}
+
+// ToPdfObject returns the page label dictionary.
+func (l PdfPageLabel) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	d.Set("Type", core.MakeName("PageLabel"))
+	if l.Style != PageLabelStyleNone {
+		d.Set("S", core.MakeName(string(l.Style)))
+	}
+	if l.Prefix != "" {
+		d.Set("P", core.MakeTextString(l.Prefix))
+	}
+	if l.Start > 1 {
+		d.Set("St", core.MakeInteger(int64(l.Start)))
+	}
+	return d
+}
+
+// newPdfPageLabelFromObj loads a page label dictionary.
+func newPdfPageLabelFromObj(obj core.PdfObject) (PdfPageLabel, error) {
+	var l PdfPageLabel
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return l, core.ErrTypeError
+	}
+	if style, ok := core.GetNameVal(dict.Get("S")); ok {
+		l.Style = PdfPageLabelStyle(style)
+	}
+	if str, ok := core.GetString(dict.Get("P")); ok {
+		l.Prefix = str.Decoded()
+	}
+	if start, err := core.GetNumberAsInt64(dict.Get("St")); err == nil {
+		l.Start = int(start)
+	}
+	return l, nil
+}
+
+// PdfPageLabels represents the page labels of a document, i.e. the PageLabels number tree
+// of the document catalog. Each page label range starts at a page and extends to the start
+// of the following range. Pages preceding the first range have decimal labels.
+type PdfPageLabels struct {
+	ranges map[int]PdfPageLabel // Page index (0-based) -> label range.
+}
+
+// NewPdfPageLabels returns a new empty set of page labels.
+func NewPdfPageLabels() *PdfPageLabels {
+	return &PdfPageLabels{ranges: map[int]PdfPageLabel{}}
+}
+
+// newPdfPageLabelsFromObj loads the page labels from the PageLabels number tree `obj`.
+func newPdfPageLabelsFromObj(obj core.PdfObject) (*PdfPageLabels, error) {
+	tree, err := NewPdfNumberTreeFromObj(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := NewPdfPageLabels()
+	tree.Iterate(func(idx int64, val core.PdfObject) bool {
+		label, err := newPdfPageLabelFromObj(val)
+		if err != nil {
+			common.Log.Debug("Invalid page label for page index %d - skipping", idx)
+			return true
+		}
+		labels.ranges[int(idx)] = label
+		return true
+	})
+	return labels, nil
+}
+
+// Set starts a new page label range at page `pageNum` (1-based). The range extends up to
+// the page preceding the next range.
+func (pl *PdfPageLabels) Set(pageNum int, label PdfPageLabel) error {
+	if pageNum < 1 {
+		return errors.New("page numbering must start at 1")
+	}
+	pl.ranges[pageNum-1] = label
+	return nil
+}
+
+// Remove removes the page label range starting at page `pageNum` (1-based).
+func (pl *PdfPageLabels) Remove(pageNum int) {
+	delete(pl.ranges, pageNum-1)
+}
+
+// RangeStarts returns the page numbers (1-based) at which page label ranges start,
+// in increasing order.
+func (pl *PdfPageLabels) RangeStarts() []int {
+	var starts []int
+	for idx := range pl.ranges {
+		starts = append(starts, idx+1)
+	}
+	sort.Ints(starts)
+	return starts
+}
+
+// GetRange returns the page label range starting at page `pageNum` (1-based).
+func (pl *PdfPageLabels) GetRange(pageNum int) (PdfPageLabel, bool) {
+	label, ok := pl.ranges[pageNum-1]
+	return label, ok
+}
+
+// rangeFor returns the range containing the page with index `idx` and the index of its
+// first page. The bool flag is false if the page precedes all ranges.
+func (pl *PdfPageLabels) rangeFor(idx int) (PdfPageLabel, int, bool) {
+	first := -1
+	for start := range pl.ranges {
+		if start <= idx && start > first {
+			first = start
+		}
+	}
+	if first < 0 {
+		return PdfPageLabel{}, 0, false
+	}
+	return pl.ranges[first], first, true
+}
+
+// GetLabel returns the label of page `pageNum` (1-based).
+func (pl *PdfPageLabels) GetLabel(pageNum int) string {
+	idx := pageNum - 1
+	label, first, ok := pl.rangeFor(idx)
+	if !ok {
+		return strconv.Itoa(pageNum)
+	}
+	return label.Format(idx - first)
+}
+
+// GetPageNumber returns the number (1-based) of the first page labeled `label` in a
+// document with `numPages` pages.
+func (pl *PdfPageLabels) GetPageNumber(label string, numPages int) (int, error) {
+	starts := pl.RangeStarts()
+	for i, start := range starts {
+		end := numPages
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+		if start > numPages {
+			break
+		}
+		offset, ok := pl.ranges[start-1].parse(label)
+		if ok && start+offset <= end {
+			return start + offset, nil
+		}
+	}
+
+	// Pages preceding the first range have decimal labels.
+	if n, err := strconv.Atoi(label); err == nil && n >= 1 && n <= numPages {
+		if len(starts) == 0 || n < starts[0] {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("page label %q not found", label)
+}
+
+// ToNumberTree returns the PageLabels number tree, mapping the page index (0-based) of
+// the first page of each range to its page label dictionary.
+func (pl *PdfPageLabels) ToNumberTree() *PdfNumberTree {
+	tree := NewPdfNumberTree()
+	for idx, label := range pl.ranges {
+		tree.Set(int64(idx), label.ToPdfObject())
+	}
+	return tree
+}
+
+// ToPdfObject returns the root of the PageLabels number tree.
+func (pl *PdfPageLabels) ToPdfObject() core.PdfObject {
+	return pl.ToNumberTree().ToPdfObject()
+}
+
+var romanNumerals = []struct {
+	value int
+	str   string
+}{
+	{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"},
+	{100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"},
+	{10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+}
+
+// formatRoman returns the lowercase roman numeral for `n`.
+func formatRoman(n int) string {
+	var b strings.Builder
+	for _, rn := range romanNumerals {
+		for n >= rn.value {
+			b.WriteString(rn.str)
+			n -= rn.value
+		}
+	}
+	return b.String()
+}
+
+// parseRoman returns the value of the lowercase roman numeral `s` or 0 if `s` is
+// not a valid numeral.
+func parseRoman(s string) int {
+	n := 0
+	for _, rn := range romanNumerals {
+		for strings.HasPrefix(s, rn.str) {
+			n += rn.value
+			s = s[len(rn.str):]
+		}
+	}
+	if s != "" {
+		return 0
+	}
+	return n
+}
+
+// formatLetters returns the lowercase letter label for `n`: a to z for the first 26
+// values, aa to zz for the next 26 and so on.
+func formatLetters(n int) string {
+	if n < 1 {
+		return ""
+	}
+	letter := byte('a' + (n-1)%26)
+	return strings.Repeat(string(letter), (n-1)/26+1)
+}
+
+// parseLetters returns the value of the lowercase letter label `s` or 0 if invalid.
+func parseLetters(s string) int {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return 0
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] != s[0] {
+			return 0
+		}
+	}
+	return (len(s)-1)*26 + int(s[0]-'a') + 1
+}
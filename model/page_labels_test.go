@@ -0,0 +1,87 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageLabelFormat(t *testing.T) {
+	testcases := []struct {
+		label    PdfPageLabel
+		offset   int
+		expected string
+	}{
+		{PdfPageLabel{Style: PageLabelStyleDecimal}, 0, "1"},
+		{PdfPageLabel{Style: PageLabelStyleDecimal, Start: 10}, 2, "12"},
+		{PdfPageLabel{Style: PageLabelStyleLowerRoman}, 3, "iv"},
+		{PdfPageLabel{Style: PageLabelStyleUpperRoman}, 1993, "MCMXCIV"},
+		{PdfPageLabel{Style: PageLabelStyleUpperLetters}, 0, "A"},
+		{PdfPageLabel{Style: PageLabelStyleLowerLetters}, 27, "bb"},
+		{PdfPageLabel{Style: PageLabelStyleDecimal, Prefix: "A-"}, 2, "A-3"},
+		{PdfPageLabel{Prefix: "Cover"}, 0, "Cover"},
+	}
+
+	for _, tcase := range testcases {
+		label := tcase.label.Format(tcase.offset)
+		require.Equal(t, tcase.expected, label)
+
+		offset, ok := tcase.label.parse(label)
+		require.True(t, ok, label)
+		if tcase.label.Style != PageLabelStyleNone {
+			require.Equal(t, tcase.offset, offset)
+		}
+	}
+}
+
+func TestPageLabelsReadWrite(t *testing.T) {
+	labels := NewPdfPageLabels()
+	require.NoError(t, labels.Set(1, PdfPageLabel{Style: PageLabelStyleLowerRoman}))
+	require.NoError(t, labels.Set(4, PdfPageLabel{Style: PageLabelStyleDecimal}))
+	require.NoError(t, labels.Set(7, PdfPageLabel{Style: PageLabelStyleDecimal, Prefix: "A-", Start: 1}))
+
+	w := NewPdfWriter()
+	for i := 0; i < 8; i++ {
+		page := newTestPage(t, 612, 792, "")
+		require.NoError(t, w.AddPage(page))
+	}
+	w.SetPageLabels(labels)
+
+	reader := writeRead(t, &w)
+
+	expected := []string{"i", "ii", "iii", "1", "2", "3", "A-1", "A-2"}
+	for i, exp := range expected {
+		label, err := reader.GetPageLabel(i + 1)
+		require.NoError(t, err)
+		require.Equal(t, exp, label)
+
+		pageNum, err := reader.GetPageNumberByLabel(exp)
+		require.NoError(t, err)
+		require.Equal(t, i+1, pageNum)
+	}
+
+	_, err := reader.GetPageNumberByLabel("iv")
+	require.Error(t, err)
+	_, err = reader.GetPageNumberByLabel("A-3")
+	require.Error(t, err)
+
+	read, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 4, 7}, read.RangeStarts())
+
+	// Update the labels incrementally.
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, read.Set(4, PdfPageLabel{Style: PageLabelStyleUpperLetters}))
+	appender.SetPageLabels(read)
+
+	reader = writeRead(t, appender)
+	label, err := reader.GetPageLabel(5)
+	require.NoError(t, err)
+	require.Equal(t, "B", label)
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/unidoc/unipdf/v3/common"
@@ -29,6 +30,7 @@ type PdfReader struct {
 	catalog        *core.PdfObjectDictionary
 	outlineTree    *PdfOutlineTreeNode
 	AcroForm       *PdfAcroForm
+	ocProperties   *PdfOCProperties
 
 	modelManager *modelManager
 
@@ -672,6 +674,26 @@ func (r *PdfReader) GetOCProperties() (core.PdfObject, error) {
 	return obj, nil
 }
 
+// GetOptionalContentProperties returns the optional content properties (layers) of the
+// document or nil if the document has no optional content. The properties are loaded once
+// and the same instance is returned on subsequent calls, so that changes to the visibility
+// of the groups apply to the processing of the page contents.
+func (r *PdfReader) GetOptionalContentProperties() (*PdfOCProperties, error) {
+	if r.ocProperties != nil {
+		return r.ocProperties, nil
+	}
+	obj, err := r.GetOCProperties()
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	ocProperties, err := newPdfOCPropertiesFromObj(obj)
+	if err != nil {
+		return nil, err
+	}
+	r.ocProperties = ocProperties
+	return ocProperties, nil
+}
+
 // GetNamedDestinations returns the Names entry in the PDF catalog.
 // See section 12.3.2.3 "Named Destinations" (p. 367 PDF32000_2008).
 func (r *PdfReader) GetNamedDestinations() (core.PdfObject, error) {
@@ -691,6 +713,127 @@ func (r *PdfReader) GetNamedDestinations() (core.PdfObject, error) {
 	return obj, nil
 }
 
+// GetCollection returns the collection dictionary of the document (portable collection /
+// portfolio), or nil if the document is not a portable collection.
+// See section 12.3.5 "Collections" (p. 370 PDF32000_2008).
+func (r *PdfReader) GetCollection() (*PdfCollection, error) {
+	obj := core.ResolveReference(r.catalog.Get("Collection"))
+	if obj == nil || core.IsNullObject(core.TraceToDirectObject(obj)) {
+		return nil, nil
+	}
+
+	if !r.isLazy {
+		err := r.traverseObjectData(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newPdfCollectionFromObj(obj)
+}
+
+// GetNameTree returns the name tree stored under `key` in the Names dictionary of the
+// catalog, e.g. "Dests", "EmbeddedFiles", "JavaScript" or "AP". Returns nil if the document
+// does not have such a name tree.
+// See section 7.7.4 "Name Dictionary" (p. 80 PDF32000_2008).
+func (r *PdfReader) GetNameTree(key string) (*PdfNameTree, error) {
+	names, ok := core.GetDict(r.catalog.Get("Names"))
+	if !ok {
+		return nil, nil
+	}
+	obj := core.ResolveReference(names.Get(core.PdfObjectName(key)))
+	if obj == nil || core.IsNullObject(core.TraceToDirectObject(obj)) {
+		return nil, nil
+	}
+
+	if !r.isLazy {
+		err := r.traverseObjectData(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPdfNameTreeFromObj(obj)
+}
+
+// GetEmbeddedFiles returns the file specifications in the EmbeddedFiles name tree of the
+// document, keyed by name. See section 7.11.4 "Embedded File Streams" (p. 104 PDF32000_2008).
+func (r *PdfReader) GetEmbeddedFiles() (map[string]*PdfFilespec, error) {
+	tree, err := r.GetNameTree("EmbeddedFiles")
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	files := map[string]*PdfFilespec{}
+	tree.Iterate(func(name string, val core.PdfObject) bool {
+		fs, err := NewPdfFilespecFromObj(core.ResolveReference(val))
+		if err != nil {
+			common.Log.Debug("Invalid embedded file %q: %v - skipping", name, err)
+			return true
+		}
+		files[textStringFromKey(name)] = fs
+		return true
+	})
+	return files, nil
+}
+
+// GetPageLabels returns the page labels of the document, loaded from the PageLabels number
+// tree of the catalog. Returns nil if the document does not define page labels.
+// See section 12.4.2 "Page Labels" (p. 383 PDF32000_2008).
+func (r *PdfReader) GetPageLabels() (*PdfPageLabels, error) {
+	obj := core.ResolveReference(r.catalog.Get("PageLabels"))
+	if obj == nil || core.IsNullObject(core.TraceToDirectObject(obj)) {
+		return nil, nil
+	}
+
+	if !r.isLazy {
+		err := r.traverseObjectData(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newPdfPageLabelsFromObj(obj)
+}
+
+// GetPageLabel returns the label of page `pageNum` (1-based), e.g. "iv" or "A-3".
+// If the document does not define page labels, the decimal page number is returned.
+func (r *PdfReader) GetPageLabel(pageNum int) (string, error) {
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return "", err
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return "", fmt.Errorf("invalid page number %d", pageNum)
+	}
+
+	labels, err := r.GetPageLabels()
+	if err != nil {
+		return "", err
+	}
+	if labels == nil {
+		return strconv.Itoa(pageNum), nil
+	}
+	return labels.GetLabel(pageNum), nil
+}
+
+// GetPageNumberByLabel returns the number (1-based) of the first page labeled `label`.
+func (r *PdfReader) GetPageNumberByLabel(label string) (int, error) {
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return 0, err
+	}
+
+	labels, err := r.GetPageLabels()
+	if err != nil {
+		return 0, err
+	}
+	if labels == nil {
+		labels = NewPdfPageLabels()
+	}
+	return labels.GetPageNumber(label, numPages)
+}
+
 // Inspect inspects the object types, subtypes and content in the PDF file returning a map of
 // object type to number of instances of each.
 func (r *PdfReader) Inspect() (map[string]int, error) {
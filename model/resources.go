@@ -281,6 +281,42 @@ func (r *PdfPageResources) SetFontByName(keyName core.PdfObjectName, obj core.Pd
 	return nil
 }
 
+// GetPropertiesByName returns the property list (e.g. an optional content group or membership
+// dictionary referenced by a marked content sequence) specified by keyName. Returns a bool value
+// indicating whether or not the entry was found.
+func (r *PdfPageResources) GetPropertiesByName(keyName core.PdfObjectName) (core.PdfObject, bool) {
+	if r.Properties == nil {
+		return nil, false
+	}
+
+	propDict, has := core.TraceToDirectObject(r.Properties).(*core.PdfObjectDictionary)
+	if !has {
+		common.Log.Debug("ERROR: Properties not a dictionary! (got %T)", core.TraceToDirectObject(r.Properties))
+		return nil, false
+	}
+	if obj := propDict.Get(keyName); obj != nil {
+		return obj, true
+	}
+
+	return nil, false
+}
+
+// SetPropertiesByName sets the property list specified by keyName to the given object.
+func (r *PdfPageResources) SetPropertiesByName(keyName core.PdfObjectName, obj core.PdfObject) error {
+	if r.Properties == nil {
+		r.Properties = core.MakeDict()
+	}
+
+	propDict, has := core.TraceToDirectObject(r.Properties).(*core.PdfObjectDictionary)
+	if !has {
+		common.Log.Debug("ERROR: Properties not a dictionary! (got %T)", core.TraceToDirectObject(r.Properties))
+		return core.ErrTypeError
+	}
+
+	propDict.Set(keyName, obj)
+	return nil
+}
+
 // GetColorspaceByName returns the colorspace with the specified name from the page resources.
 func (r *PdfPageResources) GetColorspaceByName(keyName core.PdfObjectName) (PdfColorspace, bool) {
 	colorspace, err := r.GetColorspaces()
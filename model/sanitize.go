@@ -0,0 +1,524 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// SanitizeKind represents the kinds of active content removed by PdfSanitizer.
+type SanitizeKind string
+
+// Kinds of removed content.
+const (
+	// SanitizeJavaScript is document level JavaScript and JavaScript actions.
+	SanitizeJavaScript SanitizeKind = "JavaScript"
+
+	// SanitizeOpenAction is the action performed when the document is opened.
+	SanitizeOpenAction SanitizeKind = "OpenAction"
+
+	// SanitizeAdditionalActions are the actions triggered by events (AA entries) of the
+	// document, pages, annotations and form fields.
+	SanitizeAdditionalActions SanitizeKind = "AdditionalActions"
+
+	// SanitizeAction is the actions launching applications, importing or submitting data,
+	// playing media or going to embedded documents.
+	SanitizeAction SanitizeKind = "Action"
+
+	// SanitizeURI is the URI actions whose URI is not allowed.
+	SanitizeURI SanitizeKind = "URI"
+
+	// SanitizeEmbeddedFile is embedded files.
+	SanitizeEmbeddedFile SanitizeKind = "EmbeddedFile"
+
+	// SanitizeAnnotation is multimedia, 3D and file attachment annotations.
+	SanitizeAnnotation SanitizeKind = "Annotation"
+
+	// SanitizeXFA is XFA forms.
+	SanitizeXFA SanitizeKind = "XFA"
+)
+
+// SanitizeRemoval describes content removed by PdfSanitizer.
+type SanitizeRemoval struct {
+	Kind SanitizeKind
+
+	// Location is where the content was found, e.g. "catalog", "page 2", `field "name"` or
+	// `outline item "title"`.
+	Location string
+
+	// Detail describes the removed content, e.g. the type of the action, the URI or the name
+	// of the embedded file.
+	Detail string
+}
+
+// String returns a description of the removal.
+func (r SanitizeRemoval) String() string {
+	if r.Detail == "" {
+		return fmt.Sprintf("%s: %s", r.Location, r.Kind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", r.Location, r.Kind, r.Detail)
+}
+
+// PdfSanitizer removes the active content of a document, which is then written as a new document.
+// The following content is removed:
+//   - document level JavaScript and JavaScript actions,
+//   - the open action of the document and the actions triggered by events (AA entries),
+//   - Launch, ImportData, SubmitForm, Rendition, RichMediaExecute, Movie, Sound and GoToE actions,
+//   - URI actions, unless allowed by AllowedURIs,
+//   - embedded files,
+//   - RichMedia, Movie, Sound, Screen, 3D and FileAttachment annotations,
+//   - XFA forms.
+//
+// A removed action is replaced by the action following it in its sequence of actions, if
+// single; otherwise, the actions following it are removed with it.
+// The document loaded by the reader is modified by the sanitizer.
+type PdfSanitizer struct {
+	// AllowedURIs are the prefixes of the URIs kept in URI actions, e.g. "https://example.com/".
+	// The prefixes are not case sensitive. All of the URI actions are removed if empty.
+	AllowedURIs []string
+
+	reader   *PdfReader
+	removed  []SanitizeRemoval
+	visited  map[core.PdfObject]struct{}
+	reported map[core.PdfObject]struct{}
+}
+
+// NewPdfSanitizer returns a new sanitizer of the document loaded by `reader`.
+func NewPdfSanitizer(reader *PdfReader) *PdfSanitizer {
+	return &PdfSanitizer{reader: reader}
+}
+
+// Write writes the sanitized document to `w` and returns the removed content.
+func (s *PdfSanitizer) Write(w io.Writer) ([]SanitizeRemoval, error) {
+	writer := NewPdfWriter()
+	removed, err := s.AddToWriter(&writer)
+	if err != nil {
+		return nil, err
+	}
+	return removed, writer.Write(w)
+}
+
+// AddToWriter adds the sanitized document to `w`: the pages are added to `w`, and the form,
+// outlines and the other entries of the document catalog are set on it. Returns the removed
+// content.
+func (s *PdfSanitizer) AddToWriter(w *PdfWriter) ([]SanitizeRemoval, error) {
+	r := s.reader
+	s.removed = nil
+	s.visited = map[core.PdfObject]struct{}{}
+	s.reported = map[core.PdfObject]struct{}{}
+
+	for i, page := range r.PageList {
+		if err := s.sanitizePage(page, fmt.Sprintf("page %d", i+1)); err != nil {
+			return nil, err
+		}
+	}
+	if r.AcroForm != nil {
+		s.sanitizeForm(r.AcroForm)
+	}
+	if r.outlineTree != nil {
+		s.sanitizeOutlines(r.outlineTree)
+	}
+	s.sanitizeCatalog(r.catalog)
+
+	for _, page := range r.PageList {
+		if err := w.AddPage(page); err != nil {
+			return nil, err
+		}
+	}
+	if r.AcroForm != nil {
+		if err := w.SetForms(r.AcroForm); err != nil {
+			return nil, err
+		}
+	}
+	if r.outlineTree != nil && r.outlineTree.First != nil {
+		w.AddOutlineTree(r.outlineTree)
+	}
+
+	// Remaining entries of the catalog.
+	for _, key := range r.catalog.Keys() {
+		switch key {
+		case "Type", "Pages", "Outlines", "AcroForm", "Version":
+			continue
+		}
+		obj := r.catalog.Get(key)
+		s.walk(obj, "catalog")
+		w.catalog.Set(key, obj)
+		if err := w.addObjects(core.ResolveReference(obj)); err != nil {
+			return nil, err
+		}
+	}
+	return s.removed, nil
+}
+
+// report records the removal of `obj`, reported once.
+func (s *PdfSanitizer) report(obj core.PdfObject, kind SanitizeKind, location, detail string) {
+	if obj != nil {
+		if _, ok := s.reported[obj]; ok {
+			return
+		}
+		s.reported[obj] = struct{}{}
+	}
+	common.Log.Debug("Sanitizer: removing %s at %s (%s)", kind, location, detail)
+	s.removed = append(s.removed, SanitizeRemoval{Kind: kind, Location: location, Detail: detail})
+}
+
+// sanitizePage removes the additional actions and the active annotations of `page`, and the
+// active actions of its other annotations.
+func (s *PdfSanitizer) sanitizePage(page *PdfPage, location string) error {
+	if page.AA != nil {
+		s.sanitizeAdditionalActions(page.AA, location, "")
+		page.AA = nil
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		return nil
+	}
+	kept := make([]*PdfAnnotation, 0, len(annotations))
+	for _, annot := range annotations {
+		if subtype, ok := activeAnnotationSubtype(annot); ok {
+			s.report(annot.GetContainingPdfObject(), SanitizeAnnotation, location, subtype)
+			if attachment, ok := annot.GetContext().(*PdfAnnotationFileAttachment); ok {
+				if fs, ok := core.GetDict(attachment.FS); ok {
+					s.removeEmbeddedFile(fs, location)
+				}
+			}
+			// Clear the annotation, which may still be referenced, e.g. by the structure tree.
+			if ind, ok := annot.GetContainingPdfObject().(*core.PdfIndirectObject); ok {
+				ind.PdfObject = core.MakeNull()
+			}
+			continue
+		}
+		switch t := annot.GetContext().(type) {
+		case *PdfAnnotationLink:
+			t.A = s.sanitizeAction(t.A, location)
+			t.action = nil
+		case *PdfAnnotationWidget:
+			s.sanitizeWidget(t, location)
+		}
+		kept = append(kept, annot)
+	}
+	page.SetAnnotations(kept)
+	return nil
+}
+
+// activeAnnotationSubtype returns the subtype of `annot` if it is a multimedia, 3D or file
+// attachment annotation.
+func activeAnnotationSubtype(annot *PdfAnnotation) (string, bool) {
+	switch annot.GetContext().(type) {
+	case *PdfAnnotationRichMedia:
+		return "RichMedia", true
+	case *PdfAnnotationMovie:
+		return "Movie", true
+	case *PdfAnnotationSound:
+		return "Sound", true
+	case *PdfAnnotationScreen:
+		return "Screen", true
+	case *PdfAnnotation3D:
+		return "3D", true
+	case *PdfAnnotationFileAttachment:
+		return "FileAttachment", true
+	}
+	return "", false
+}
+
+// sanitizeWidget removes the additional actions and the active actions of `widget`.
+func (s *PdfSanitizer) sanitizeWidget(widget *PdfAnnotationWidget, location string) {
+	if widget.AA != nil {
+		s.sanitizeAdditionalActions(widget.AA, location, "widget")
+		widget.AA = nil
+	}
+	widget.A = s.sanitizeAction(widget.A, location)
+}
+
+// sanitizeForm removes the XFA form and the actions of the fields of `form`.
+func (s *PdfSanitizer) sanitizeForm(form *PdfAcroForm) {
+	if form.XFA != nil {
+		s.report(nil, SanitizeXFA, "form", "")
+		form.XFA = nil
+		removeEntry(form.container, "XFA")
+	}
+	for _, field := range form.AllFields() {
+		name, _ := field.FullName()
+		location := fmt.Sprintf("field %q", name)
+		if field.AA != nil {
+			s.sanitizeAdditionalActions(field.AA, location, "")
+			field.AA = nil
+			removeEntry(field.container, "AA")
+		}
+		for _, widget := range field.Annotations {
+			s.sanitizeWidget(widget, location)
+		}
+	}
+}
+
+// sanitizeOutlines removes the active actions of the outline items of `node` and its
+// descendants.
+func (s *PdfSanitizer) sanitizeOutlines(node *PdfOutlineTreeNode) {
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	var sanitize func(node *PdfOutlineTreeNode)
+	sanitize = func(node *PdfOutlineTreeNode) {
+		for child := node.First; child != nil; {
+			if _, ok := visited[child]; ok {
+				return
+			}
+			visited[child] = struct{}{}
+			item, ok := child.context.(*PdfOutlineItem)
+			if !ok {
+				return
+			}
+			var title string
+			if item.Title != nil {
+				title = item.Title.Decoded()
+			}
+			item.A = s.sanitizeAction(item.A, fmt.Sprintf("outline item %q", title))
+			sanitize(child)
+			child = item.Next
+		}
+	}
+	sanitize(node)
+}
+
+// sanitizeCatalog removes the open action, the additional actions, the document JavaScript,
+// the embedded files and the portable collection of the document catalog.
+func (s *PdfSanitizer) sanitizeCatalog(catalog *core.PdfObjectDictionary) {
+	const location = "catalog"
+	if obj := catalog.Get("OpenAction"); obj != nil {
+		if action, ok := core.GetDict(obj); ok {
+			actionType, _ := core.GetNameVal(action.Get("S"))
+			s.report(core.ResolveReference(obj), SanitizeOpenAction, location, actionType)
+			catalog.Remove("OpenAction")
+		}
+	}
+	if obj := catalog.Get("AA"); obj != nil {
+		s.sanitizeAdditionalActions(obj, location, "")
+		catalog.Remove("AA")
+	}
+
+	if names, ok := core.GetDict(catalog.Get("Names")); ok {
+		if tree, err := s.reader.GetNameTree("JavaScript"); err == nil && tree != nil {
+			for _, key := range tree.Keys() {
+				s.report(nil, SanitizeJavaScript, location, key)
+			}
+		}
+		if tree, err := s.reader.GetNameTree("EmbeddedFiles"); err == nil && tree != nil {
+			for _, key := range tree.Keys() {
+				s.report(nil, SanitizeEmbeddedFile, location, key)
+			}
+		}
+		names.Remove("JavaScript")
+		names.Remove("EmbeddedFiles")
+	}
+	if catalog.Get("Collection") != nil {
+		s.report(nil, SanitizeEmbeddedFile, location, "portable collection")
+		catalog.Remove("Collection")
+	}
+	catalog.Remove("NeedsRendering")
+}
+
+// walk removes the active content of the objects which are not represented by models: the
+// additional actions, the active actions and the embedded files of the dictionaries of `obj`
+// and its descendants.
+func (s *PdfSanitizer) walk(obj core.PdfObject, location string) {
+	obj = core.ResolveReference(obj)
+	if _, ok := s.visited[obj]; ok || obj == nil {
+		return
+	}
+	s.visited[obj] = struct{}{}
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		s.walk(t.PdfObject, location)
+	case *core.PdfObjectStream:
+		s.walk(t.PdfObjectDictionary, location)
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			s.walk(elem, location)
+		}
+	case *core.PdfObjectDictionary:
+		if kind, detail, remove := s.isActiveAction(t); remove {
+			// Action which is not in an action entry.
+			s.report(t, kind, location, detail)
+			t.Clear()
+			return
+		}
+		if aa := t.Get("AA"); aa != nil {
+			s.sanitizeAdditionalActions(aa, location, "")
+			t.Remove("AA")
+		}
+		if a := t.Get("A"); a != nil {
+			if action := s.sanitizeAction(a, location); action != nil {
+				t.Set("A", action)
+			} else {
+				t.Remove("A")
+			}
+		}
+		s.removeEmbeddedFile(t, location)
+		for _, key := range t.Keys() {
+			if key != "Parent" {
+				s.walk(t.Get(key), location)
+			}
+		}
+	}
+}
+
+// sanitizeAdditionalActions records the removal of the additional actions `aa` and sanitizes the
+// actions triggered by its events, which are cleared if active, e.g. the JavaScript run when a
+// page is opened, as they may still be referenced by other objects.
+func (s *PdfSanitizer) sanitizeAdditionalActions(aa core.PdfObject, location, detail string) {
+	s.report(core.ResolveReference(aa), SanitizeAdditionalActions, location, detail)
+	dict, ok := core.GetDict(aa)
+	if !ok {
+		return
+	}
+	for _, key := range dict.Keys() {
+		s.sanitizeAction(dict.Get(key), location)
+	}
+}
+
+// removeEntry removes `key` from the dictionary of `container`, for the models which do not clear
+// their dictionaries when serialized.
+func removeEntry(container core.PdfObject, key core.PdfObjectName) {
+	if dict, ok := core.GetDict(container); ok {
+		dict.Remove(key)
+	}
+}
+
+// removeEmbeddedFile removes the embedded file of file specification `fs`, which may be shared.
+func (s *PdfSanitizer) removeEmbeddedFile(fs *core.PdfObjectDictionary, location string) {
+	if ef := fs.Get("EF"); ef != nil {
+		s.report(core.ResolveReference(ef), SanitizeEmbeddedFile, location, fileSpecName(fs))
+		fs.Remove("EF")
+	}
+}
+
+// sanitizeAction returns the action `obj` without the active actions of its sequence of
+// actions, or nil if the action is removed.
+func (s *PdfSanitizer) sanitizeAction(obj core.PdfObject, location string) core.PdfObject {
+	action, ok := core.GetDict(obj)
+	if !ok {
+		return obj
+	}
+	if _, ok := s.visited[action]; ok {
+		// Sanitized already, or loop in the sequence of actions.
+		if _, removed := s.reported[action]; removed {
+			return nil
+		}
+		return obj
+	}
+	s.visited[action] = struct{}{}
+
+	next := s.sanitizeNext(action.Get("Next"), location)
+	if kind, detail, remove := s.isActiveAction(action); remove {
+		s.report(action, kind, location, detail)
+		// Clear the action, which may still be referenced, and replace it by the action
+		// following it, if single.
+		action.Clear()
+		if len(next) == 1 {
+			return next[0]
+		}
+		return nil
+	}
+
+	switch len(next) {
+	case 0:
+		action.Remove("Next")
+	case 1:
+		if _, isArray := core.GetArray(action.Get("Next")); !isArray {
+			action.Set("Next", next[0])
+			break
+		}
+		fallthrough
+	default:
+		action.Set("Next", core.MakeArray(next...))
+	}
+	for _, key := range action.Keys() {
+		if key != "Next" {
+			s.walk(action.Get(key), location)
+		}
+	}
+	return obj
+}
+
+// sanitizeNext returns the sanitized actions of the sequence of actions `next` (Next entry of
+// an action) which are kept.
+func (s *PdfSanitizer) sanitizeNext(next core.PdfObject, location string) []core.PdfObject {
+	var actions []core.PdfObject
+	if arr, ok := core.GetArray(next); ok {
+		actions = arr.Elements()
+	} else if next != nil {
+		actions = []core.PdfObject{next}
+	}
+
+	var kept []core.PdfObject
+	for _, action := range actions {
+		if action = s.sanitizeAction(action, location); action != nil {
+			kept = append(kept, action)
+		}
+	}
+	return kept
+}
+
+// isActiveAction returns whether `action` is removed, with the kind and the description of the
+// removal.
+func (s *PdfSanitizer) isActiveAction(action *core.PdfObjectDictionary) (SanitizeKind, string, bool) {
+	actionType, _ := core.GetNameVal(action.Get("S"))
+	switch PdfActionType(actionType) {
+	case ActionTypeJavaScript:
+		return SanitizeJavaScript, "JavaScript action", true
+	case ActionTypeLaunch, ActionTypeImportData, ActionTypeSubmitForm:
+		if name := fileSpecName(action.Get("F")); name != "" {
+			return SanitizeAction, actionType + " " + name, true
+		}
+		return SanitizeAction, actionType, true
+	case ActionTypeRendition, ActionTypeMovie, ActionTypeSound, ActionTypeGoToE, ActionTypeRichMediaExecute:
+		return SanitizeAction, actionType, true
+	case ActionTypeURI:
+		uri, _ := core.GetStringVal(action.Get("URI"))
+		if s.isAllowedURI(uri) {
+			return "", "", false
+		}
+		return SanitizeURI, uri, true
+	}
+	return "", "", false
+}
+
+// isAllowedURI returns true if `uri` starts with one of the allowed prefixes.
+func (s *PdfSanitizer) isAllowedURI(uri string) bool {
+	uri = strings.ToLower(strings.TrimSpace(uri))
+	for _, prefix := range s.AllowedURIs {
+		if prefix != "" && strings.HasPrefix(uri, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSpecName returns the file name of file specification `obj`.
+func fileSpecName(obj core.PdfObject) string {
+	obj = core.ResolveReference(obj)
+	if name, ok := core.GetStringVal(obj); ok {
+		return name
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return ""
+	}
+	for _, key := range []core.PdfObjectName{"UF", "F", "DOS", "Mac", "Unix"} {
+		if name, ok := core.GetString(dict.Get(key)); ok {
+			return name.Decoded()
+		}
+	}
+	return ""
+}
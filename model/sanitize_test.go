@@ -0,0 +1,183 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// makeSanitizeTestDocument returns a document with active content of each kind.
+func makeSanitizeTestDocument(t *testing.T) *PdfReader {
+	js := func(script string) core.PdfObject {
+		action := NewPdfActionJavaScript()
+		action.JS = core.MakeString(script)
+		return action.ToPdfObject()
+	}
+	uri := func(uri string) core.PdfObject {
+		action := NewPdfActionURI()
+		action.URI = core.MakeString(uri)
+		return action.ToPdfObject()
+	}
+	fileSpec := func(name string) *PdfFilespec {
+		fs := NewPdfFilespec()
+		fs.F = core.MakeString(name)
+		return fs
+	}
+	addLink := func(page *PdfPage, action core.PdfObject) {
+		link := NewPdfAnnotationLink()
+		link.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+		link.A = action
+		page.AddAnnotation(link.PdfAnnotation)
+	}
+
+	w := NewPdfWriter()
+	page := newTestPage(t, 200, 200, "")
+	page.AA = core.MakeDict()
+	page.AA.(*core.PdfObjectDictionary).Set("O", js("app.alert('page')"))
+
+	// Links: allowed and external URIs, a sequence of actions and a Launch action.
+	addLink(page, uri("https://EXAMPLE.com/docs"))
+	addLink(page, uri("https://evil.com/"))
+	goTo := NewPdfActionGoTo()
+	goTo.D = core.MakeArray(page.GetPageAsIndirectObject(), core.MakeName("Fit"))
+	goTo.Next = js("app.alert('next')")
+	addLink(page, goTo.ToPdfObject())
+	launch := NewPdfActionLaunch()
+	launch.F = fileSpec("calc.exe")
+	addLink(page, launch.ToPdfObject())
+
+	// Active annotations.
+	attachment := NewPdfAnnotationFileAttachment()
+	attachment.Rect = core.MakeArrayFromFloats([]float64{60, 10, 80, 30})
+	attachment.FS = NewPdfFilespecFromEmbeddedFile("payload.exe", NewPdfEmbeddedFile([]byte("MZ"), "")).ToPdfObject()
+	page.AddAnnotation(attachment.PdfAnnotation)
+	richMedia := NewPdfAnnotationRichMedia()
+	richMedia.Rect = core.MakeArrayFromFloats([]float64{60, 40, 80, 60})
+	page.AddAnnotation(richMedia.PdfAnnotation)
+
+	// Form: submit button with additional actions and XFA.
+	submit := NewPdfActionSubmitForm()
+	submit.F = fileSpec("https://evil.com/submit")
+	field := NewPdfField()
+	field.T = core.MakeString("submit")
+	field.FT = core.MakeName("Btn")
+	field.AA = core.MakeDict()
+	field.AA.(*core.PdfObjectDictionary).Set("K", js("event.value = 1"))
+	widget := NewPdfAnnotationWidget()
+	widget.Rect = core.MakeArrayFromFloats([]float64{100, 10, 150, 30})
+	widget.A = submit.ToPdfObject()
+	widget.Parent = field.GetContainingPdfObject()
+	field.Annotations = []*PdfAnnotationWidget{widget}
+	page.AddAnnotation(widget.PdfAnnotation)
+	form := NewPdfAcroForm()
+	form.Fields = &[]*PdfField{field}
+	xfa, err := core.MakeStream([]byte("<template/>"), nil)
+	require.NoError(t, err)
+	form.XFA = core.MakeArray(core.MakeString("template"), xfa)
+	require.NoError(t, w.AddPage(page))
+	require.NoError(t, w.SetForms(form))
+
+	// Outline item running JavaScript.
+	item := NewPdfOutlineItem()
+	item.Title = core.MakeString("Run")
+	item.A = js("app.alert('outline')")
+	outline := NewPdfOutline()
+	item.Parent = &outline.PdfOutlineTreeNode
+	outline.First = &item.PdfOutlineTreeNode
+	outline.Last = &item.PdfOutlineTreeNode
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+
+	// Document JavaScript, open action, additional actions and embedded files.
+	scripts := NewPdfNameTree()
+	scripts.Set("init", js("app.alert('init')"))
+	require.NoError(t, w.SetNameTree("JavaScript", scripts))
+	require.NoError(t, w.AddEmbeddedFile("data.bin",
+		NewPdfFilespecFromEmbeddedFile("data.bin", NewPdfEmbeddedFile([]byte("data"), ""))))
+	aa := core.MakeDict()
+	aa.Set("WC", js("app.alert('close')"))
+	for key, obj := range map[core.PdfObjectName]core.PdfObject{
+		"OpenAction": js("app.alert('open')"),
+		"AA":         aa,
+		"Lang":       core.MakeString("en"),
+	} {
+		w.catalog.Set(key, obj)
+		require.NoError(t, w.addObjects(obj))
+	}
+
+	return writeRead(t, &w)
+}
+
+func TestSanitize(t *testing.T) {
+	sanitizer := NewPdfSanitizer(makeSanitizeTestDocument(t))
+	sanitizer.AllowedURIs = []string{"https://example.com/"}
+	var buf bytes.Buffer
+	removed, err := sanitizer.Write(&buf)
+	require.NoError(t, err)
+
+	var descriptions []string
+	for _, removal := range removed {
+		descriptions = append(descriptions, removal.String())
+	}
+	sort.Strings(descriptions)
+	require.Equal(t, []string{
+		`catalog: AdditionalActions`,
+		`catalog: EmbeddedFile (data.bin)`,
+		`catalog: JavaScript (JavaScript action)`,
+		`catalog: JavaScript (init)`,
+		`catalog: OpenAction (JavaScript)`,
+		`field "submit": AdditionalActions`,
+		`field "submit": JavaScript (JavaScript action)`,
+		`form: XFA`,
+		`outline item "Run": JavaScript (JavaScript action)`,
+		`page 1: Action (Launch calc.exe)`,
+		`page 1: Action (SubmitForm https://evil.com/submit)`,
+		`page 1: AdditionalActions`,
+		`page 1: Annotation (FileAttachment)`,
+		`page 1: Annotation (RichMedia)`,
+		`page 1: EmbeddedFile (payload.exe)`,
+		`page 1: JavaScript (JavaScript action)`, // Page open action.
+		`page 1: JavaScript (JavaScript action)`, // Next action of the GoTo link.
+		`page 1: URI (https://evil.com/)`,
+	}, descriptions)
+
+	data := buf.Bytes()
+	for _, active := range []string{"/JavaScript", "/JS", "/Launch", "/SubmitForm", "/EmbeddedFile", "/XFA",
+		"/RichMedia", "/FileAttachment", "/OpenAction", "/AA", "evil.com", "payload.exe"} {
+		require.NotContains(t, string(data), active)
+	}
+
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	page, err := r.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 5)
+
+	// The allowed URI and the GoTo action are kept.
+	action, err := annotations[0].GetContext().(*PdfAnnotationLink).GetAction()
+	require.NoError(t, err)
+	require.Equal(t, "https://EXAMPLE.com/docs", action.GetContext().(*PdfActionURI).URI.(*core.PdfObjectString).Str())
+	for _, i := range []int{1, 3} {
+		link := annotations[i].GetContext().(*PdfAnnotationLink)
+		require.Nil(t, link.A)
+	}
+	dest, err := annotations[2].GetContext().(*PdfAnnotationLink).GetDestination()
+	require.NoError(t, err)
+	require.Equal(t, 1, dest.PageNum)
+
+	require.NotNil(t, r.AcroForm)
+	require.Len(t, r.AcroForm.AllFields(), 1)
+	require.Equal(t, []string{"Run"}, getOutlineTitles(r.GetOutlineTree()))
+	lang, _ := core.GetStringVal(r.catalog.Get("Lang"))
+	require.Equal(t, "en", lang)
+}
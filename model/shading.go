@@ -551,7 +551,7 @@ func newPdfShadingType4FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 		common.Log.Debug("BitsPerFlag not an integer (got %T)", obj)
 		return nil, core.ErrTypeError
 	}
-	shading.BitsPerComponent = integer
+	shading.BitsPerFlag = integer
 
 	// Decode (required).
 	obj = dict.Get("Decode")
@@ -566,15 +566,19 @@ func newPdfShadingType4FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 	}
 	shading.Decode = arr
 
-	// Function (required).
-	obj = dict.Get("Function")
-	if obj == nil {
-		common.Log.Debug("Required attribute missing:  Function")
-		return nil, ErrRequiredAttributeMissing
-	}
-	shading.Function = []PdfFunction{}
-	if array, is := obj.(*core.PdfObjectArray); is {
-		for _, obj := range array.Elements() {
+	// Function (optional).
+	if obj := dict.Get("Function"); obj != nil {
+		shading.Function = []PdfFunction{}
+		if array, is := obj.(*core.PdfObjectArray); is {
+			for _, obj := range array.Elements() {
+				function, err := newPdfFunctionFromPdfObject(obj)
+				if err != nil {
+					common.Log.Debug("Error parsing function: %v", err)
+					return nil, err
+				}
+				shading.Function = append(shading.Function, function)
+			}
+		} else {
 			function, err := newPdfFunctionFromPdfObject(obj)
 			if err != nil {
 				common.Log.Debug("Error parsing function: %v", err)
@@ -582,13 +586,6 @@ func newPdfShadingType4FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 			}
 			shading.Function = append(shading.Function, function)
 		}
-	} else {
-		function, err := newPdfFunctionFromPdfObject(obj)
-		if err != nil {
-			common.Log.Debug("Error parsing function: %v", err)
-			return nil, err
-		}
-		shading.Function = append(shading.Function, function)
 	}
 
 	return &shading, nil
@@ -653,7 +650,6 @@ func newPdfShadingType5FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 
 	// Function (optional).
 	if obj := dict.Get("Function"); obj != nil {
-		// Function (required).
 		shading.Function = []PdfFunction{}
 		if array, is := obj.(*core.PdfObjectArray); is {
 			for _, obj := range array.Elements() {
@@ -719,7 +715,7 @@ func newPdfShadingType6FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 		common.Log.Debug("BitsPerFlag not an integer (got %T)", obj)
 		return nil, core.ErrTypeError
 	}
-	shading.BitsPerComponent = integer
+	shading.BitsPerFlag = integer
 
 	// Decode (required).
 	obj = dict.Get("Decode")
@@ -801,7 +797,7 @@ func newPdfShadingType7FromDictionary(dict *core.PdfObjectDictionary) (*PdfShadi
 		common.Log.Debug("BitsPerFlag not an integer (got %T)", obj)
 		return nil, core.ErrTypeError
 	}
-	shading.BitsPerComponent = integer
+	shading.BitsPerFlag = integer
 
 	// Decode (required).
 	obj = dict.Get("Decode")
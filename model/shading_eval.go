@@ -0,0 +1,868 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/jbig2/reader"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// patchSteps is the number of subdivisions of the sides of Coons and tensor-product patches
+// into triangles.
+const patchSteps = 16
+
+// ShadingEvaluator evaluates the colors painted by a shading at the points of the shading space.
+// The streams of mesh shadings (types 4 to 7) are decoded when the evaluator is created and their
+// patches are divided into triangles.
+type ShadingEvaluator struct {
+	shading *PdfShading
+	funcs   []PdfFunction
+	ncomps  int
+	ranges  []float64 // Ranges of the color components.
+	bbox    *PdfRectangle
+
+	// values returns the input values of the functions, or the color components of shadings
+	// without functions, at a point of the shading space and false if the point is not painted.
+	values func(x, y float64) ([]float64, bool)
+}
+
+// NewShadingEvaluator returns an evaluator of the colors painted by `shading`.
+func NewShadingEvaluator(shading *PdfShading) (*ShadingEvaluator, error) {
+	if shading == nil || shading.ColorSpace == nil {
+		return nil, errors.New("shading colorspace missing")
+	}
+	e := &ShadingEvaluator{
+		shading: shading,
+		ncomps:  shading.ColorSpace.GetNumComponents(),
+	}
+	e.ranges = shading.ColorSpace.DecodeArray()
+	if len(e.ranges) != 2*e.ncomps {
+		e.ranges = make([]float64, 2*e.ncomps)
+		for i := 0; i < e.ncomps; i++ {
+			e.ranges[2*i+1] = 1
+		}
+	}
+	if shading.BBox != nil {
+		bbox := *shading.BBox
+		bbox.Llx, bbox.Urx = math.Min(bbox.Llx, bbox.Urx), math.Max(bbox.Llx, bbox.Urx)
+		bbox.Lly, bbox.Ury = math.Min(bbox.Lly, bbox.Ury), math.Max(bbox.Lly, bbox.Ury)
+		e.bbox = &bbox
+	}
+
+	var err error
+	switch t := shading.GetContext().(type) {
+	case *PdfShadingType1:
+		e.funcs = t.Function
+		err = e.initFunctionBased(t)
+	case *PdfShadingType2:
+		e.funcs = t.Function
+		err = e.initAxial(t)
+	case *PdfShadingType3:
+		e.funcs = t.Function
+		err = e.initRadial(t)
+	case *PdfShadingType4:
+		e.funcs = t.Function
+		err = e.initMesh(t.BitsPerCoordinate, t.BitsPerComponent, t.BitsPerFlag, t.Decode, 0)
+	case *PdfShadingType5:
+		e.funcs = t.Function
+		if t.VerticesPerRow == nil {
+			err = errors.New("vertices per row missing")
+		} else {
+			err = e.initMesh(t.BitsPerCoordinate, t.BitsPerComponent, nil, t.Decode, int(*t.VerticesPerRow))
+		}
+	case *PdfShadingType6:
+		e.funcs = t.Function
+		err = e.initMesh(t.BitsPerCoordinate, t.BitsPerComponent, t.BitsPerFlag, t.Decode, 0)
+	case *PdfShadingType7:
+		e.funcs = t.Function
+		err = e.initMesh(t.BitsPerCoordinate, t.BitsPerComponent, t.BitsPerFlag, t.Decode, 0)
+	default:
+		err = errors.New("unsupported shading type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Evaluate returns the color components, in the colorspace of the shading, painted at point
+// (`x`,`y`) of the shading space, and false if the shading does not paint the point: the point
+// is outside of the bounding box, of the domain of the shading, of the (possibly extended)
+// gradient or of the mesh. The background color is not used, see Background.
+func (e *ShadingEvaluator) Evaluate(x, y float64) ([]float64, bool) {
+	if e.bbox != nil && (x < e.bbox.Llx || x > e.bbox.Urx || y < e.bbox.Lly || y > e.bbox.Ury) {
+		return nil, false
+	}
+	in, ok := e.values(x, y)
+	if !ok {
+		return nil, false
+	}
+	return e.components(in)
+}
+
+// ColorAt returns the color painted at point (`x`,`y`) of the shading space, and false if the
+// shading does not paint the point.
+func (e *ShadingEvaluator) ColorAt(x, y float64) (PdfColor, bool) {
+	vals, ok := e.Evaluate(x, y)
+	if !ok {
+		return nil, false
+	}
+	color, err := e.shading.ColorSpace.ColorFromFloats(vals)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid shading color %v: %v", vals, err)
+		return nil, false
+	}
+	return color, true
+}
+
+// Background returns the color components of the background of the shading, painted outside of
+// the shading by shading patterns, and false if the shading has no background.
+func (e *ShadingEvaluator) Background() ([]float64, bool) {
+	if e.shading.Background == nil {
+		return nil, false
+	}
+	vals, err := e.shading.Background.ToFloat64Array()
+	if err != nil || len(vals) < e.ncomps {
+		return nil, false
+	}
+	return e.clamp(vals[:e.ncomps]), true
+}
+
+// Rasterize returns an image of the colors painted by the shading in `region` of the shading
+// space, sampled at the centers of `width`×`height` pixels, with the first row at the top of
+// the region. The image is in the colorspace of the shading with 8 bits per component. The
+// pixels not painted by the shading have the background color if `background` is true and the
+// shading has one, and are transparent otherwise.
+func (e *ShadingEvaluator) Rasterize(region PdfRectangle, width, height int, background bool) (*Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid image size")
+	}
+	if region.Width() == 0 || region.Height() == 0 {
+		return nil, errors.New("empty region")
+	}
+	var bg []float64
+	if background {
+		bg, _ = e.Background()
+	}
+	decode := e.ranges
+	n := e.ncomps
+	data := make([]byte, width*height*n)
+	alpha := make([]byte, width*height)
+	hasAlpha := false
+	dx, dy := (region.Urx-region.Llx)/float64(width), (region.Ury-region.Lly)/float64(height)
+	for j := 0; j < height; j++ {
+		y := region.Ury - (float64(j)+0.5)*dy
+		for i := 0; i < width; i++ {
+			x := region.Llx + (float64(i)+0.5)*dx
+			vals, ok := e.Evaluate(x, y)
+			if !ok {
+				vals = bg
+			}
+			k := j*width + i
+			if vals == nil {
+				hasAlpha = true
+				continue
+			}
+			alpha[k] = 255
+			for c := 0; c < n; c++ {
+				v := 0.0
+				if span := decode[2*c+1] - decode[2*c]; span != 0 {
+					v = (vals[c] - decode[2*c]) / span
+				}
+				data[k*n+c] = byte(math.Round(255 * math.Max(0, math.Min(1, v))))
+			}
+		}
+	}
+
+	img := &Image{
+		Width:            int64(width),
+		Height:           int64(height),
+		BitsPerComponent: 8,
+		ColorComponents:  n,
+		Data:             data,
+		decode:           append([]float64{}, decode...),
+	}
+	if hasAlpha {
+		img.alphaData = alpha
+		img.hasAlpha = true
+	}
+	return img, nil
+}
+
+// components returns the clamped color components of the shading for function input values, or
+// color components, `in`.
+func (e *ShadingEvaluator) components(in []float64) ([]float64, bool) {
+	var vals []float64
+	switch len(e.funcs) {
+	case 0:
+		vals = in
+	case 1:
+		out, err := e.funcs[0].Evaluate(in)
+		if err != nil {
+			return nil, false
+		}
+		vals = out
+	default:
+		// One function per color component.
+		vals = make([]float64, 0, len(e.funcs))
+		for _, f := range e.funcs {
+			out, err := f.Evaluate(in)
+			if err != nil || len(out) == 0 {
+				return nil, false
+			}
+			vals = append(vals, out[0])
+		}
+	}
+	if len(vals) < e.ncomps {
+		return nil, false
+	}
+	return e.clamp(vals[:e.ncomps]), true
+}
+
+// clamp returns a copy of color components `vals` clamped to the ranges of the colorspace.
+func (e *ShadingEvaluator) clamp(vals []float64) []float64 {
+	out := make([]float64, len(vals))
+	for i, v := range vals {
+		out[i] = math.Max(e.ranges[2*i], math.Min(e.ranges[2*i+1], v))
+	}
+	return out
+}
+
+// initFunctionBased sets up the evaluation of function-based shading `t`.
+func (e *ShadingEvaluator) initFunctionBased(t *PdfShadingType1) error {
+	if len(t.Function) == 0 {
+		return errors.New("shading function missing")
+	}
+	domain := []float64{0, 1, 0, 1}
+	if t.Domain != nil {
+		if d, err := t.Domain.ToFloat64Array(); err == nil && len(d) == 4 {
+			domain = d
+		}
+	}
+	matrix := transform.IdentityMatrix()
+	if t.Matrix != nil {
+		m, err := t.Matrix.ToFloat64Array()
+		if err != nil || len(m) != 6 {
+			return errors.New("invalid shading matrix")
+		}
+		matrix = transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5])
+	}
+	inverse, ok := matrix.Inverse()
+	if !ok {
+		return errors.New("shading matrix not invertible")
+	}
+	e.values = func(x, y float64) ([]float64, bool) {
+		u, v := inverse.Transform(x, y)
+		if u < domain[0] || u > domain[1] || v < domain[2] || v > domain[3] {
+			return nil, false
+		}
+		return []float64{u, v}, true
+	}
+	return nil
+}
+
+// initAxial sets up the evaluation of axial shading `t`.
+func (e *ShadingEvaluator) initAxial(t *PdfShadingType2) error {
+	coords, domain, extend, err := t.GradientParameters()
+	if err != nil {
+		return err
+	}
+	x0, y0, x1, y1 := coords[0], coords[1], coords[2], coords[3]
+	dx, dy := x1-x0, y1-y0
+	den := dx*dx + dy*dy
+	e.values = func(x, y float64) ([]float64, bool) {
+		s := 0.0
+		if den != 0 {
+			s = ((x-x0)*dx + (y-y0)*dy) / den
+		}
+		if (s < 0 && !extend[0]) || (s > 1 && !extend[1]) {
+			return nil, false
+		}
+		s = math.Max(0, math.Min(1, s))
+		return []float64{domain[0] + s*(domain[1]-domain[0])}, true
+	}
+	return nil
+}
+
+// initRadial sets up the evaluation of radial shading `t`.
+func (e *ShadingEvaluator) initRadial(t *PdfShadingType3) error {
+	coords, domain, extend, err := t.GradientParameters()
+	if err != nil {
+		return err
+	}
+	e.values = func(x, y float64) ([]float64, bool) {
+		s, ok := RadialShadingParameter(coords, x, y, extend)
+		if !ok {
+			return nil, false
+		}
+		s = math.Max(0, math.Min(1, s))
+		return []float64{domain[0] + s*(domain[1]-domain[0])}, true
+	}
+	return nil
+}
+
+// GradientParameters returns the coordinates (x0 y0 x1 y1), the domain and the extend flags of
+// the axial shading, with their default values if not specified.
+func (s *PdfShadingType2) GradientParameters() ([]float64, [2]float64, [2]bool, error) {
+	return gradientParameters(s.Coords, s.Domain, s.Extend, 4)
+}
+
+// GradientParameters returns the coordinates (x0 y0 r0 x1 y1 r1), the domain and the extend flags
+// of the radial shading, with their default values if not specified.
+func (s *PdfShadingType3) GradientParameters() ([]float64, [2]float64, [2]bool, error) {
+	return gradientParameters(s.Coords, s.Domain, s.Extend, 6)
+}
+
+// gradientParameters returns the `n` coordinates, the domain and the extend flags of an axial or
+// radial shading.
+func gradientParameters(coordsArr, domainArr, extendArr *core.PdfObjectArray, n int) ([]float64,
+	[2]float64, [2]bool, error) {
+	domain := [2]float64{0, 1}
+	var extend [2]bool
+	if coordsArr == nil {
+		return nil, domain, extend, errors.New("shading coordinates missing")
+	}
+	coords, err := coordsArr.ToFloat64Array()
+	if err != nil {
+		return nil, domain, extend, err
+	}
+	if len(coords) != n {
+		return nil, domain, extend, errors.New("invalid shading coordinates")
+	}
+	if domainArr != nil {
+		if d, err := domainArr.ToFloat64Array(); err == nil && len(d) == 2 {
+			domain = [2]float64{d[0], d[1]}
+		}
+	}
+	if extendArr != nil && extendArr.Len() == 2 {
+		for i := range extend {
+			if b, ok := core.GetBoolVal(extendArr.Get(i)); ok {
+				extend[i] = b
+			}
+		}
+	}
+	return coords, domain, extend, nil
+}
+
+// RadialShadingParameter returns the parameter of the circle, of a radial shading with
+// coordinates `coords` (x0 y0 r0 x1 y1 r1), passing through point (`x`,`y`). The circles are
+// interpolated between the start (0) and end (1) circles and extended as specified by `extend`;
+// the largest parameter is used when several circles pass through the point. It returns false if
+// no circle passes through the point.
+func RadialShadingParameter(coords []float64, x, y float64, extend [2]bool) (float64, bool) {
+	x0, y0, r0, x1, y1, r1 := coords[0], coords[1], coords[2], coords[3], coords[4], coords[5]
+	cdx, cdy, dr := x1-x0, y1-y0, r1-r0
+	pdx, pdy := x-x0, y-y0
+	a := cdx*cdx + cdy*cdy - dr*dr
+	b := pdx*cdx + pdy*cdy + r0*dr
+	c := pdx*pdx + pdy*pdy - r0*r0
+
+	valid := func(s float64) bool {
+		if r0+s*dr < 0 {
+			return false
+		}
+		return (s >= 0 || extend[0]) && (s <= 1 || extend[1])
+	}
+	if math.Abs(a) < 1e-9 {
+		if b == 0 {
+			return 0, false
+		}
+		s := c / (2 * b)
+		return s, valid(s)
+	}
+	disc := b*b - a*c
+	if disc < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(disc)
+	s1, s2 := (b+sq)/a, (b-sq)/a
+	if s1 < s2 {
+		s1, s2 = s2, s1
+	}
+	if valid(s1) {
+		return s1, true
+	}
+	if valid(s2) {
+		return s2, true
+	}
+	return 0, false
+}
+
+// initMesh sets up the evaluation of a mesh shading by decoding its stream with the bits per
+// coordinate, component and flag (nil for lattice-form meshes) and the decode array of the
+// shading. `verticesPerRow` is the number of vertices per row of lattice-form meshes.
+func (e *ShadingEvaluator) initMesh(bitsPerCoord, bitsPerComp, bitsPerFlag *core.PdfObjectInteger,
+	decodeArr *core.PdfObjectArray, verticesPerRow int) error {
+	if bitsPerCoord == nil || bitsPerComp == nil || decodeArr == nil {
+		return errors.New("mesh parameters missing")
+	}
+	stream, ok := core.GetStream(e.shading.container)
+	if !ok {
+		return errors.New("mesh shading stream missing")
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return err
+	}
+	decode, err := decodeArr.ToFloat64Array()
+	if err != nil {
+		return err
+	}
+
+	// The vertices have a parametric value when the shading has functions.
+	nvals := e.ncomps
+	if len(e.funcs) > 0 {
+		nvals = 1
+	}
+	if len(decode) < 4+2*nvals {
+		return errors.New("invalid mesh decode array")
+	}
+	mr := &meshReader{
+		r:            reader.New(data),
+		bitsPerCoord: byte(*bitsPerCoord),
+		bitsPerComp:  byte(*bitsPerComp),
+		decode:       decode,
+		nvals:        nvals,
+	}
+	if bitsPerFlag != nil {
+		mr.bitsPerFlag = byte(*bitsPerFlag)
+	}
+	if mr.bitsPerCoord < 1 || mr.bitsPerCoord > 32 || mr.bitsPerComp < 1 || mr.bitsPerComp > 16 ||
+		mr.bitsPerFlag > 8 {
+		return errors.New("invalid mesh bits")
+	}
+
+	var triangles []meshTriangle
+	switch *e.shading.ShadingType {
+	case 4:
+		triangles = mr.freeFormTriangles()
+	case 5:
+		if verticesPerRow < 2 {
+			return errors.New("invalid vertices per row")
+		}
+		triangles = mr.latticeTriangles(verticesPerRow)
+	case 6:
+		triangles = mr.patchTriangles(false)
+	case 7:
+		triangles = mr.patchTriangles(true)
+	}
+	mesh := newMeshIndex(triangles)
+	e.values = mesh.values
+	return nil
+}
+
+// meshVertex is a vertex of a mesh shading with its parametric value or color components.
+type meshVertex struct {
+	x, y float64
+	vals []float64
+}
+
+// meshTriangle is a triangle of a mesh shading, with the colors interpolated between its vertices.
+type meshTriangle struct {
+	v   [3]meshVertex
+	den float64 // Denominator of the barycentric coordinates.
+}
+
+// newMeshTriangle returns the triangle with vertices `a`, `b` and `c` and false if it is
+// degenerate.
+func newMeshTriangle(a, b, c meshVertex) (meshTriangle, bool) {
+	den := (b.y-c.y)*(a.x-c.x) + (c.x-b.x)*(a.y-c.y)
+	if den == 0 || math.IsNaN(den) || math.IsInf(den, 0) {
+		return meshTriangle{}, false
+	}
+	return meshTriangle{v: [3]meshVertex{a, b, c}, den: den}, true
+}
+
+// values returns the values interpolated at point (`x`,`y`) and false if the point is outside of
+// the triangle.
+func (t *meshTriangle) values(x, y float64) ([]float64, bool) {
+	const eps = 1e-9
+	a, b, c := t.v[0], t.v[1], t.v[2]
+	l0 := ((b.y-c.y)*(x-c.x) + (c.x-b.x)*(y-c.y)) / t.den
+	l1 := ((c.y-a.y)*(x-c.x) + (a.x-c.x)*(y-c.y)) / t.den
+	l2 := 1 - l0 - l1
+	if l0 < -eps || l1 < -eps || l2 < -eps {
+		return nil, false
+	}
+	vals := make([]float64, len(a.vals))
+	for i := range vals {
+		vals[i] = l0*a.vals[i] + l1*b.vals[i] + l2*c.vals[i]
+	}
+	return vals, true
+}
+
+// meshReader reads the vertices of mesh shading streams.
+type meshReader struct {
+	r            *reader.Reader
+	bitsPerCoord byte
+	bitsPerComp  byte
+	bitsPerFlag  byte
+	decode       []float64
+	nvals        int
+}
+
+// readValue returns the next value of `bits` bits mapped to range [`min`,`max`].
+func (mr *meshReader) readValue(bits byte, min, max float64) (float64, error) {
+	u, err := mr.r.ReadBits(bits)
+	if err != nil {
+		return 0, err
+	}
+	return min + float64(u)*(max-min)/float64(uint64(1)<<bits-1), nil
+}
+
+// readFlag returns the next edge flag.
+func (mr *meshReader) readFlag() (int, error) {
+	u, err := mr.r.ReadBits(mr.bitsPerFlag)
+	return int(u), err
+}
+
+// readPoint returns the next point.
+func (mr *meshReader) readPoint() (x, y float64, err error) {
+	if x, err = mr.readValue(mr.bitsPerCoord, mr.decode[0], mr.decode[1]); err != nil {
+		return 0, 0, err
+	}
+	if y, err = mr.readValue(mr.bitsPerCoord, mr.decode[2], mr.decode[3]); err != nil {
+		return 0, 0, err
+	}
+	return x, y, nil
+}
+
+// readValues returns the next parametric value or color components.
+func (mr *meshReader) readValues() ([]float64, error) {
+	vals := make([]float64, mr.nvals)
+	for i := range vals {
+		v, err := mr.readValue(mr.bitsPerComp, mr.decode[4+2*i], mr.decode[5+2*i])
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// readVertex returns the next vertex.
+func (mr *meshReader) readVertex() (meshVertex, error) {
+	x, y, err := mr.readPoint()
+	if err != nil {
+		return meshVertex{}, err
+	}
+	vals, err := mr.readValues()
+	if err != nil {
+		return meshVertex{}, err
+	}
+	return meshVertex{x: x, y: y, vals: vals}, nil
+}
+
+// freeFormTriangles returns the triangles of a free-form Gouraud-shaded triangle mesh (type 4).
+// Each vertex starts with an edge flag: 0 starts a new triangle, 1 and 2 form a triangle with
+// respectively the last two vertices and the first and last vertices of the previous triangle.
+// The vertices start at byte boundaries.
+func (mr *meshReader) freeFormTriangles() []meshTriangle {
+	var triangles []meshTriangle
+	var tri [3]meshVertex
+	n := 0 // Number of vertices of the triangle being started.
+	for {
+		flag, err := mr.readFlag()
+		if err != nil {
+			break
+		}
+		v, err := mr.readVertex()
+		if err != nil {
+			break
+		}
+		mr.r.Align()
+		switch {
+		case n < 3:
+			tri[n] = v
+			n++
+		case flag == 0:
+			tri[0] = v
+			n = 1
+		case flag == 1:
+			tri = [3]meshVertex{tri[1], tri[2], v}
+		case flag == 2:
+			tri = [3]meshVertex{tri[0], tri[2], v}
+		default:
+			common.Log.Debug("ERROR: Invalid mesh edge flag %d", flag)
+			return triangles
+		}
+		if n == 3 {
+			if t, ok := newMeshTriangle(tri[0], tri[1], tri[2]); ok {
+				triangles = append(triangles, t)
+			}
+		}
+	}
+	return triangles
+}
+
+// latticeTriangles returns the triangles of a lattice-form Gouraud-shaded triangle mesh (type 5)
+// with `perRow` vertices per row.
+func (mr *meshReader) latticeTriangles(perRow int) []meshTriangle {
+	var triangles []meshTriangle
+	var prev []meshVertex
+	for {
+		row := make([]meshVertex, perRow)
+		for i := range row {
+			v, err := mr.readVertex()
+			if err != nil {
+				return triangles
+			}
+			row[i] = v
+		}
+		for i := 0; prev != nil && i+1 < perRow; i++ {
+			if t, ok := newMeshTriangle(prev[i], prev[i+1], row[i]); ok {
+				triangles = append(triangles, t)
+			}
+			if t, ok := newMeshTriangle(prev[i+1], row[i+1], row[i]); ok {
+				triangles = append(triangles, t)
+			}
+		}
+		prev = row
+	}
+}
+
+// patchOrder is the order of the control points p[i][j] of tensor-product patches in the mesh
+// streams: the 12 points of the boundary, which are those of Coons patches, then the 4 internal
+// points.
+var patchOrder = [16][2]int{
+	{0, 0}, {0, 1}, {0, 2}, {0, 3}, {1, 3}, {2, 3}, {3, 3}, {3, 2},
+	{3, 1}, {3, 0}, {2, 0}, {1, 0}, {1, 1}, {1, 2}, {2, 2}, {2, 1},
+}
+
+// patchTriangles returns the triangles of a Coons patch mesh (type 6) or of a tensor-product
+// patch mesh (type 7) if `tensor` is true. Patches with an edge flag other than 0 share an edge,
+// and the colors of its corners, with the previous patch. The patches start at byte boundaries.
+func (mr *meshReader) patchTriangles(tensor bool) []meshTriangle {
+	npoints := 12
+	if tensor {
+		npoints = 16
+	}
+	var triangles []meshTriangle
+	var points, prevPoints [16][2]float64
+	var colors, prevColors [4][]float64
+	first := true
+	for {
+		flag, err := mr.readFlag()
+		if err != nil {
+			break
+		}
+		// Stream indices of the points and colors of the shared edge of the previous patch.
+		var shared [4]int
+		var sharedColors [2]int
+		start, startColor := 0, 0
+		switch flag {
+		case 0:
+		case 1:
+			shared, sharedColors = [4]int{3, 4, 5, 6}, [2]int{1, 2}
+		case 2:
+			shared, sharedColors = [4]int{6, 7, 8, 9}, [2]int{2, 3}
+		case 3:
+			shared, sharedColors = [4]int{9, 10, 11, 0}, [2]int{3, 0}
+		default:
+			common.Log.Debug("ERROR: Invalid mesh edge flag %d", flag)
+			return triangles
+		}
+		if flag != 0 {
+			if first {
+				common.Log.Debug("ERROR: Mesh patch edge flag %d without previous patch", flag)
+				return triangles
+			}
+			for i, k := range shared {
+				points[i] = prevPoints[k]
+			}
+			for i, k := range sharedColors {
+				colors[i] = prevColors[k]
+			}
+			start, startColor = 4, 2
+		}
+		for i := start; i < npoints; i++ {
+			x, y, err := mr.readPoint()
+			if err != nil {
+				return triangles
+			}
+			points[i] = [2]float64{x, y}
+		}
+		for i := startColor; i < 4; i++ {
+			vals, err := mr.readValues()
+			if err != nil {
+				return triangles
+			}
+			colors[i] = vals
+		}
+		mr.r.Align()
+
+		triangles = append(triangles, newPatch(points, tensor).triangles(colors)...)
+		prevPoints, prevColors = points, colors
+		first = false
+	}
+	return triangles
+}
+
+// patch is a tensor-product patch with control points p[i][j], where i follows the u direction
+// and j the v direction.
+type patch [4][4][2]float64
+
+// newPatch returns the patch with control points `points` in stream order. The internal points of
+// Coons patches (`tensor` false) are computed from the boundary points.
+func newPatch(points [16][2]float64, tensor bool) *patch {
+	var p patch
+	for k, ij := range patchOrder {
+		p[ij[0]][ij[1]] = points[k]
+	}
+	if tensor {
+		return &p
+	}
+	for c := 0; c < 2; c++ {
+		at := func(i, j int) float64 { return p[i][j][c] }
+		p[1][1][c] = (-4*at(0, 0) + 6*(at(0, 1)+at(1, 0)) - 2*(at(0, 3)+at(3, 0)) +
+			3*(at(3, 1)+at(1, 3)) - at(3, 3)) / 9
+		p[1][2][c] = (-4*at(0, 3) + 6*(at(0, 2)+at(1, 3)) - 2*(at(0, 0)+at(3, 3)) +
+			3*(at(3, 2)+at(1, 0)) - at(3, 0)) / 9
+		p[2][1][c] = (-4*at(3, 0) + 6*(at(3, 1)+at(2, 0)) - 2*(at(3, 3)+at(0, 0)) +
+			3*(at(0, 1)+at(2, 3)) - at(0, 3)) / 9
+		p[2][2][c] = (-4*at(3, 3) + 6*(at(3, 2)+at(2, 3)) - 2*(at(3, 0)+at(0, 3)) +
+			3*(at(0, 2)+at(2, 0)) - at(0, 0)) / 9
+	}
+	return &p
+}
+
+// point returns the point of the patch at parameters (`u`,`v`).
+func (p *patch) point(u, v float64) (float64, float64) {
+	bernstein := func(t float64) [4]float64 {
+		s := 1 - t
+		return [4]float64{s * s * s, 3 * t * s * s, 3 * t * t * s, t * t * t}
+	}
+	bu, bv := bernstein(u), bernstein(v)
+	var x, y float64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			w := bu[i] * bv[j]
+			x += w * p[i][j][0]
+			y += w * p[i][j][1]
+		}
+	}
+	return x, y
+}
+
+// triangles returns the patch divided into triangles with the colors `colors` of its corners
+// (u,v) = (0,0), (0,1), (1,1) and (1,0) interpolated bilinearly. The triangles of larger
+// parameters are last so that they are painted over the others where the patch folds.
+func (p *patch) triangles(colors [4][]float64) []meshTriangle {
+	var grid [patchSteps + 1][patchSteps + 1]meshVertex
+	for i := 0; i <= patchSteps; i++ {
+		u := float64(i) / patchSteps
+		for j := 0; j <= patchSteps; j++ {
+			v := float64(j) / patchSteps
+			x, y := p.point(u, v)
+			vals := make([]float64, len(colors[0]))
+			for k := range vals {
+				vals[k] = (1-u)*(1-v)*colors[0][k] + (1-u)*v*colors[1][k] + u*v*colors[2][k] +
+					u*(1-v)*colors[3][k]
+			}
+			grid[i][j] = meshVertex{x: x, y: y, vals: vals}
+		}
+	}
+	triangles := make([]meshTriangle, 0, 2*patchSteps*patchSteps)
+	for i := 0; i < patchSteps; i++ {
+		for j := 0; j < patchSteps; j++ {
+			if t, ok := newMeshTriangle(grid[i][j], grid[i+1][j], grid[i][j+1]); ok {
+				triangles = append(triangles, t)
+			}
+			if t, ok := newMeshTriangle(grid[i+1][j], grid[i+1][j+1], grid[i][j+1]); ok {
+				triangles = append(triangles, t)
+			}
+		}
+	}
+	return triangles
+}
+
+// meshIndex finds the triangles of a mesh containing points with a uniform grid of cells listing
+// the triangles whose bounding boxes overlap them.
+type meshIndex struct {
+	triangles  []meshTriangle
+	bbox       PdfRectangle
+	cols, rows int
+	cells      [][]int
+}
+
+// newMeshIndex returns the index of mesh `triangles`.
+func newMeshIndex(triangles []meshTriangle) *meshIndex {
+	m := &meshIndex{triangles: triangles}
+	if len(triangles) == 0 {
+		return m
+	}
+	bounds := func(t *meshTriangle) PdfRectangle {
+		r := PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+		for _, v := range t.v {
+			r.Llx, r.Lly = math.Min(r.Llx, v.x), math.Min(r.Lly, v.y)
+			r.Urx, r.Ury = math.Max(r.Urx, v.x), math.Max(r.Ury, v.y)
+		}
+		return r
+	}
+	m.bbox = bounds(&triangles[0])
+	for i := range triangles {
+		r := bounds(&triangles[i])
+		m.bbox.Llx, m.bbox.Lly = math.Min(m.bbox.Llx, r.Llx), math.Min(m.bbox.Lly, r.Lly)
+		m.bbox.Urx, m.bbox.Ury = math.Max(m.bbox.Urx, r.Urx), math.Max(m.bbox.Ury, r.Ury)
+	}
+	side := int(math.Max(1, math.Min(256, math.Sqrt(float64(len(triangles))))))
+	m.cols, m.rows = side, side
+	m.cells = make([][]int, m.cols*m.rows)
+	for i := range triangles {
+		r := bounds(&triangles[i])
+		c0, r0 := m.cell(r.Llx, r.Lly)
+		c1, r1 := m.cell(r.Urx, r.Ury)
+		for row := r0; row <= r1; row++ {
+			for col := c0; col <= c1; col++ {
+				k := row*m.cols + col
+				m.cells[k] = append(m.cells[k], i)
+			}
+		}
+	}
+	return m
+}
+
+// cell returns the column and row of the cell containing point (`x`,`y`), clamped to the grid.
+func (m *meshIndex) cell(x, y float64) (int, int) {
+	col, row := 0, 0
+	if w := m.bbox.Width(); w > 0 {
+		col = int((x - m.bbox.Llx) / w * float64(m.cols))
+	}
+	if h := m.bbox.Height(); h > 0 {
+		row = int((y - m.bbox.Lly) / h * float64(m.rows))
+	}
+	clamp := func(v, n int) int {
+		if v < 0 {
+			return 0
+		}
+		if v >= n {
+			return n - 1
+		}
+		return v
+	}
+	return clamp(col, m.cols), clamp(row, m.rows)
+}
+
+// values returns the values interpolated at point (`x`,`y`) in the last triangle of the mesh
+// containing it, and false if no triangle contains the point.
+func (m *meshIndex) values(x, y float64) ([]float64, bool) {
+	if len(m.triangles) == 0 || x < m.bbox.Llx || x > m.bbox.Urx || y < m.bbox.Lly || y > m.bbox.Ury {
+		return nil, false
+	}
+	col, row := m.cell(x, y)
+	cell := m.cells[row*m.cols+col]
+	for i := len(cell) - 1; i >= 0; i-- {
+		if vals, ok := m.triangles[cell[i]].values(x, y); ok {
+			return vals, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,161 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// newMeshTestShading returns a DeviceRGB mesh shading of type `shadingType` with 8 bit
+// coordinates, components and flags in the range [0,255], and the mesh stream `data` where color
+// components are scaled to 255.
+func newMeshTestShading(t *testing.T, shadingType int, data []byte) *PdfShading {
+	stream, err := core.MakeStream(data, core.NewRawEncoder())
+	require.NoError(t, err)
+	stream.Set("ShadingType", core.MakeInteger(int64(shadingType)))
+	stream.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	stream.Set("BitsPerCoordinate", core.MakeInteger(8))
+	stream.Set("BitsPerComponent", core.MakeInteger(8))
+	if shadingType == 5 {
+		stream.Set("VerticesPerRow", core.MakeInteger(2))
+	} else {
+		stream.Set("BitsPerFlag", core.MakeInteger(8))
+	}
+	stream.Set("Decode", core.MakeArrayFromFloats([]float64{0, 255, 0, 255, 0, 1, 0, 1, 0, 1}))
+
+	shading, err := newPdfShadingFromPdfObject(stream)
+	require.NoError(t, err)
+	return shading
+}
+
+// requireShadingColor checks that `e` paints color `expected` at (`x`,`y`).
+func requireShadingColor(t *testing.T, e *ShadingEvaluator, x, y float64, expected []float64) {
+	vals, ok := e.Evaluate(x, y)
+	require.True(t, ok, "point (%g,%g)", x, y)
+	require.Len(t, vals, len(expected))
+	for i := range expected {
+		require.InDelta(t, expected[i], vals[i], 0.01, "point (%g,%g) component %d", x, y, i)
+	}
+}
+
+func TestShadingEvaluatorAxial(t *testing.T) {
+	function := &PdfFunctionType2{
+		Domain: []float64{0, 1},
+		C0:     []float64{1, 0, 0},
+		C1:     []float64{0, 0, 1},
+		N:      1,
+	}
+	dict := core.MakeDict()
+	dict.Set("ShadingType", core.MakeInteger(2))
+	dict.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	dict.Set("Coords", core.MakeArrayFromFloats([]float64{0, 0, 100, 0}))
+	dict.Set("Function", function.ToPdfObject())
+	shading, err := newPdfShadingFromPdfObject(dict)
+	require.NoError(t, err)
+
+	e, err := NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, 50, 5, []float64{0.5, 0, 0.5})
+	_, ok := e.Evaluate(-1, 0)
+	require.False(t, ok)
+
+	color, ok := e.ColorAt(25, 0)
+	require.True(t, ok)
+	rgb, ok := color.(*PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.InDelta(t, 0.75, rgb.R(), 1e-9)
+
+	// The pixels outside of the gradient are transparent.
+	img, err := e.Rasterize(PdfRectangle{Llx: -50, Urx: 150, Ury: 10}, 4, 1, false)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 191, 0, 64, 64, 0, 191, 0, 0, 0}, img.Data)
+	require.Equal(t, []byte{0, 255, 255, 0}, img.alphaData)
+
+	// Extended gradient with a background.
+	shading.GetContext().(*PdfShadingType2).Extend = core.MakeArray(core.MakeBool(true), core.MakeBool(false))
+	shading.Background = core.MakeArrayFromFloats([]float64{0, 1, 0})
+	e, err = NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, -10, 0, []float64{1, 0, 0})
+	img, err = e.Rasterize(PdfRectangle{Llx: -50, Urx: 150, Ury: 10}, 4, 1, true)
+	require.NoError(t, err)
+	require.Equal(t, []byte{255, 0, 0, 191, 0, 64, 64, 0, 191, 0, 255, 0}, img.Data)
+	require.Nil(t, img.alphaData)
+}
+
+func TestShadingEvaluatorTriangleMeshes(t *testing.T) {
+	// Free-form mesh: a triangle and a triangle sharing its last two vertices.
+	shading := newMeshTestShading(t, 4, []byte{
+		0, 0, 0, 255, 0, 0,
+		0, 100, 0, 0, 255, 0,
+		0, 0, 100, 0, 0, 255,
+		1, 100, 100, 255, 255, 255,
+	})
+	mesh, ok := shading.GetContext().(*PdfShadingType4)
+	require.True(t, ok)
+	require.Equal(t, int64(8), int64(*mesh.BitsPerFlag))
+
+	e, err := NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, 0, 0, []float64{1, 0, 0})
+	requireShadingColor(t, e, 20, 30, []float64{0.5, 0.2, 0.3})
+	requireShadingColor(t, e, 80, 90, []float64{0.7, 0.8, 0.9})
+	_, ok = e.Evaluate(101, 50)
+	require.False(t, ok)
+
+	// Lattice mesh of 2 rows of 2 vertices.
+	shading = newMeshTestShading(t, 5, []byte{
+		0, 0, 0, 0, 0,
+		100, 0, 255, 0, 0,
+		0, 100, 0, 255, 0,
+		100, 100, 255, 255, 0,
+	})
+	e, err = NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, 50, 50, []float64{0.5, 0.5, 0})
+	requireShadingColor(t, e, 90, 20, []float64{0.9, 0.2, 0})
+	requireShadingColor(t, e, 20, 90, []float64{0.2, 0.9, 0})
+}
+
+func TestShadingEvaluatorPatchMeshes(t *testing.T) {
+	// Coons patch with straight sides over square (0,0)-(90,90) and a patch sharing its top side
+	// over square (0,90)-(90,180).
+	data := []byte{
+		0,
+		0, 0, 0, 30, 0, 60, 0, 90, 30, 90, 60, 90, 90, 90, 90, 60, 90, 30, 90, 0, 60, 0, 30, 0,
+		255, 0, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0,
+		1,
+		90, 120, 90, 150, 90, 180, 60, 180, 30, 180, 0, 180, 0, 150, 0, 120,
+		255, 255, 255, 0, 0, 0,
+	}
+	shading := newMeshTestShading(t, 6, data)
+	e, err := NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, 45, 45, []float64{0.25, 0.25, 0.25})
+	requireShadingColor(t, e, 30, 60, []float64{2.0 / 9, 4.0 / 9, 2.0 / 9})
+	requireShadingColor(t, e, 45, 180, []float64{0.5, 0.5, 0.5})
+	requireShadingColor(t, e, 0, 135, []float64{0, 0.5, 0})
+	_, ok := e.Evaluate(100, 100)
+	require.False(t, ok)
+
+	// The same patches as tensor-product patches with the internal points of the Coons patches.
+	var tensor []byte
+	tensor = append(tensor, data[:25]...)
+	tensor = append(tensor, 30, 30, 30, 60, 60, 60, 60, 30)
+	tensor = append(tensor, data[25:38]...)
+	tensor = append(tensor, data[38:54]...)
+	tensor = append(tensor, 30, 120, 60, 120, 60, 150, 30, 150)
+	tensor = append(tensor, data[54:]...)
+	shading = newMeshTestShading(t, 7, tensor)
+	e, err = NewShadingEvaluator(shading)
+	require.NoError(t, err)
+	requireShadingColor(t, e, 30, 60, []float64{2.0 / 9, 4.0 / 9, 2.0 / 9})
+	requireShadingColor(t, e, 45, 180, []float64{0.5, 0.5, 0.5})
+}
@@ -0,0 +1,476 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfSplitter splits a document into several documents, by top-level outline item, by page ranges
+// or by size. Each part only keeps the resources, form fields, outline items and named
+// destinations relevant to its pages, and the links to pages of other parts are removed.
+type PdfSplitter struct {
+	// MaxSize is the maximum size in bytes of the parts. Parts exceeding it are split further into
+	// continuation parts. A part containing a single page is never split, even if it exceeds
+	// MaxSize. Zero means no limit.
+	MaxSize int64
+
+	reader    *PdfReader
+	numPages  int
+	pageIndex map[core.PdfObject]int
+}
+
+// PdfSplitPart is a document produced by PdfSplitter.
+type PdfSplitPart struct {
+	// Title of the part: the title of the outline item, the page range expression or the document
+	// title. Continuation parts have a "(n)" suffix.
+	Title string
+
+	// Pages contains the page numbers (1-based) of the source document in the part.
+	Pages []int
+
+	// Data is the serialized document.
+	Data []byte
+}
+
+// NewPdfSplitter returns a new PdfSplitter splitting the document loaded by `reader`.
+func NewPdfSplitter(reader *PdfReader) (*PdfSplitter, error) {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	if numPages == 0 {
+		return nil, errors.New("document has no pages")
+	}
+
+	pageIndex := map[core.PdfObject]int{}
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		pageIndex[page.GetPageAsIndirectObject()] = i
+	}
+
+	return &PdfSplitter{
+		reader:    reader,
+		numPages:  numPages,
+		pageIndex: pageIndex,
+	}, nil
+}
+
+// SplitByOutline splits the document by top-level outline item. Each part starts at the target page
+// of an outline item and ends before the target page of the next one, and contains the outline
+// item with its descendants. Outline items targeting the same page are kept in the same part.
+// The pages before the first target page are added to the first part. If the document does not
+// have outlines, it is split by MaxSize only.
+func (s *PdfSplitter) SplitByOutline() ([]*PdfSplitPart, error) {
+	type section struct {
+		start int
+		items []*PdfOutlineItem
+	}
+	var sections []*section
+	var current *section
+	for _, item := range s.outlineItems() {
+		start, ok := s.outlineItemPage(item)
+		if !ok {
+			// Keep the items without target with the previous item.
+			if current == nil {
+				current = &section{start: 1}
+				sections = append(sections, current)
+			}
+			current.items = append(current.items, item)
+			continue
+		}
+		current = &section{start: start, items: []*PdfOutlineItem{item}}
+		sections = append(sections, current)
+	}
+	if len(sections) == 0 {
+		return s.SplitBySize(s.MaxSize)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].start < sections[j].start
+	})
+	var merged []*section
+	for _, sec := range sections {
+		if len(merged) > 0 && merged[len(merged)-1].start == sec.start {
+			last := merged[len(merged)-1]
+			last.items = append(last.items, sec.items...)
+			continue
+		}
+		merged = append(merged, sec)
+	}
+	merged[0].start = 1
+
+	var parts []*PdfSplitPart
+	for i, sec := range merged {
+		end := s.numPages
+		if i+1 < len(merged) {
+			end = merged[i+1].start - 1
+		}
+		var pageNums []int
+		for n := sec.start; n <= end; n++ {
+			pageNums = append(pageNums, n)
+		}
+		title := ""
+		if sec.items[0].Title != nil {
+			title = sec.items[0].Title.Decoded()
+		}
+		secParts, err := s.split(title, pageNums, sec.items, s.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, secParts...)
+	}
+	return parts, nil
+}
+
+// SplitByPageRanges splits the document into one part per page range expression of `ranges`.
+// An expression is a comma separated list of page numbers and ranges, e.g. "1-3,5,8-" where "8-"
+// means from page 8 to the last page and "-3" from the first page to page 3. The parts are
+// titled with their expressions.
+func (s *PdfSplitter) SplitByPageRanges(ranges ...string) ([]*PdfSplitPart, error) {
+	if len(ranges) == 0 {
+		return nil, errors.New("no page ranges")
+	}
+
+	var parts []*PdfSplitPart
+	for _, expr := range ranges {
+		pageNums, err := parsePageRanges(expr, s.numPages)
+		if err != nil {
+			return nil, err
+		}
+		rangeParts, err := s.split(expr, pageNums, s.outlineItems(), s.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rangeParts...)
+	}
+	return parts, nil
+}
+
+// SplitBySize splits the document into parts of at most `maxSize` bytes. The parts are titled
+// with the document title.
+func (s *PdfSplitter) SplitBySize(maxSize int64) ([]*PdfSplitPart, error) {
+	pageNums := make([]int, s.numPages)
+	for i := range pageNums {
+		pageNums[i] = i + 1
+	}
+	return s.split(readerTitle(s.reader), pageNums, s.outlineItems(), maxSize)
+}
+
+// split writes the pages `pageNums` with the outline items `items` to parts of at most `maxSize`
+// bytes (no limit if zero). The parts have the title `title`, numbered if there are several.
+func (s *PdfSplitter) split(title string, pageNums []int, items []*PdfOutlineItem, maxSize int64) ([]*PdfSplitPart, error) {
+	var parts []*PdfSplitPart
+	for len(pageNums) > 0 {
+		n := len(pageNums)
+		data, err := s.writePart(pageNums, items)
+		if err != nil {
+			return nil, err
+		}
+
+		if maxSize > 0 && int64(len(data)) > maxSize && n > 1 {
+			// Find the largest number of pages fitting in maxSize, assuming the size grows with the
+			// number of pages.
+			n, data = 1, nil
+			lo, hi := 1, len(pageNums)-1
+			for lo <= hi {
+				mid := (lo + hi) / 2
+				midData, err := s.writePart(pageNums[:mid], items)
+				if err != nil {
+					return nil, err
+				}
+				if int64(len(midData)) <= maxSize {
+					n, data = mid, midData
+					lo = mid + 1
+				} else {
+					hi = mid - 1
+				}
+			}
+			if data == nil {
+				common.Log.Debug("Page %d exceeds the maximum size", pageNums[0])
+				if data, err = s.writePart(pageNums[:1], items); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		parts = append(parts, &PdfSplitPart{
+			Title: title,
+			Pages: pageNums[:n],
+			Data:  data,
+		})
+		pageNums = pageNums[n:]
+	}
+
+	if len(parts) > 1 {
+		for i, part := range parts {
+			part.Title = strings.TrimSpace(fmt.Sprintf("%s (%d)", title, i+1))
+		}
+	}
+	return parts, nil
+}
+
+// writePart returns a document containing the pages `pageNums`, the descendants of the outline
+// items `items` targeting these pages, and the form fields and named destinations of the pages.
+func (s *PdfSplitter) writePart(pageNums []int, items []*PdfOutlineItem) ([]byte, error) {
+	var pages []*PdfPage
+	for _, n := range pageNums {
+		page, err := s.reader.GetPage(n)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+
+	// The pages and form fields are modified by the writer and the merge, restore them afterwards
+	// so that they can be used for the next parts.
+	restore, err := s.saveState(pages)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	w := NewPdfWriter()
+	version := s.reader.PdfVersion()
+	w.SetVersion(version.Major, version.Minor)
+
+	ctx := newMergeContext()
+	ctx.pruneOutlines = true
+	if err := ctx.mergeDocumentData(s.reader, pages); err != nil {
+		return nil, err
+	}
+	var last *PdfOutlineItem
+	for _, item := range items {
+		if copied := ctx.copyOutlineItem(item); copied != nil {
+			appendOutlineItem(&ctx.outline.PdfOutlineTreeNode, last, copied)
+			last = copied
+		}
+	}
+
+	for _, page := range pages {
+		if err := w.AddPage(page); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.finish(&w); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// saveState saves the annotations and contents of `pages` and the kids and widget annotations of
+// the form fields of the document. Returns a function restoring them.
+func (s *PdfSplitter) saveState(pages []*PdfPage) (func(), error) {
+	type pageState struct {
+		page        *PdfPage
+		annotations []*PdfAnnotation
+		contents    core.PdfObject
+		contentsArr *core.PdfObjectArray
+		elements    []core.PdfObject
+	}
+	type fieldState struct {
+		field       *PdfField
+		kids        []*PdfField
+		annotations []*PdfAnnotationWidget
+	}
+
+	var pageStates []pageState
+	for _, page := range pages {
+		annotations, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		state := pageState{
+			page:        page,
+			annotations: annotations,
+			contents:    page.Contents,
+		}
+		if arr, ok := core.GetArray(page.Contents); ok {
+			state.contentsArr = arr
+			state.elements = append([]core.PdfObject{}, arr.Elements()...)
+		}
+		pageStates = append(pageStates, state)
+	}
+
+	var fieldStates []fieldState
+	for _, field := range s.reader.AcroForm.AllFields() {
+		fieldStates = append(fieldStates, fieldState{
+			field:       field,
+			kids:        field.Kids,
+			annotations: field.Annotations,
+		})
+	}
+
+	return func() {
+		for _, state := range pageStates {
+			state.page.SetAnnotations(state.annotations)
+			state.page.Contents = state.contents
+			if state.contentsArr != nil {
+				state.contentsArr.Clear()
+				state.contentsArr.Append(state.elements...)
+			}
+		}
+		for _, state := range fieldStates {
+			state.field.Kids = state.kids
+			state.field.Annotations = state.annotations
+		}
+	}, nil
+}
+
+// outlineItems returns the top-level outline items of the document.
+func (s *PdfSplitter) outlineItems() []*PdfOutlineItem {
+	tree := s.reader.GetOutlineTree()
+	if tree == nil {
+		return nil
+	}
+	var items []*PdfOutlineItem
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	for node := tree.First; node != nil; {
+		if _, ok := visited[node]; ok {
+			break
+		}
+		visited[node] = struct{}{}
+		item, ok := node.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+		items = append(items, item)
+		node = item.Next
+	}
+	return items
+}
+
+// outlineItemPage returns the number of the page targeted by outline item `item`, or by its first
+// descendant with a target if it does not have one. Returns false if no target page is found.
+func (s *PdfSplitter) outlineItemPage(item *PdfOutlineItem) (int, bool) {
+	if item.Dest != nil {
+		if n, ok := s.destPage(item.Dest); ok {
+			return n, true
+		}
+	}
+	if action, ok := core.GetDict(item.A); ok {
+		if name, _ := core.GetNameVal(action.Get("S")); name == "GoTo" {
+			if n, ok := s.destPage(action.Get("D")); ok {
+				return n, true
+			}
+		}
+	}
+
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	for node := item.First; node != nil; {
+		if _, ok := visited[node]; ok {
+			break
+		}
+		visited[node] = struct{}{}
+		child, ok := node.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+		if n, ok := s.outlineItemPage(child); ok {
+			return n, true
+		}
+		node = child.Next
+	}
+	return 0, false
+}
+
+// destPage returns the number of the page targeted by destination `dest` (explicit or named).
+func (s *PdfSplitter) destPage(dest core.PdfObject) (int, bool) {
+	dest = core.ResolveReference(dest)
+	var name string
+	switch t := dest.(type) {
+	case *core.PdfObjectString:
+		name = t.Str()
+	case *core.PdfObjectName:
+		name = string(*t)
+	}
+	if name != "" {
+		dest = s.reader.namedDestination(name)
+	}
+
+	arr, ok := core.GetArray(dest)
+	if !ok || arr.Len() == 0 {
+		return 0, false
+	}
+	target := core.ResolveReference(arr.Get(0))
+	if idx, ok := core.GetIntVal(target); ok {
+		// Page index, as in the outlines created with NewOutlineDest.
+		return idx + 1, idx >= 0 && idx < s.numPages
+	}
+	n, ok := s.pageIndex[target]
+	return n, ok
+}
+
+// parsePageRanges returns the page numbers of page range expression `expr` (see
+// SplitByPageRanges) for a document of `numPages` pages.
+func parsePageRanges(expr string, numPages int) ([]int, error) {
+	parsePage := func(s string, def int) (int, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid page number %q", s)
+		}
+		if n < 1 || n > numPages {
+			return 0, fmt.Errorf("page number %d out of range", n)
+		}
+		return n, nil
+	}
+
+	var pageNums []int
+	seen := map[int]struct{}{}
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, fmt.Errorf("invalid page range expression %q", expr)
+		}
+
+		var from, to int
+		var err error
+		if i := strings.Index(item, "-"); i >= 0 {
+			if from, err = parsePage(item[:i], 1); err != nil {
+				return nil, err
+			}
+			if to, err = parsePage(item[i+1:], numPages); err != nil {
+				return nil, err
+			}
+		} else {
+			if from, err = parsePage(item, 0); err != nil {
+				return nil, err
+			}
+			to = from
+		}
+		if from > to {
+			return nil, fmt.Errorf("invalid page range %q", item)
+		}
+		for n := from; n <= to; n++ {
+			if _, ok := seen[n]; ok {
+				return nil, fmt.Errorf("page %d listed more than once", n)
+			}
+			seen[n] = struct{}{}
+			pageNums = append(pageNums, n)
+		}
+	}
+	return pageNums, nil
+}
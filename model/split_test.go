@@ -0,0 +1,105 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitByOutline(t *testing.T) {
+	reader := makeMergeTestDocument(t)
+	splitter, err := NewPdfSplitter(reader)
+	require.NoError(t, err)
+	parts, err := splitter.SplitByOutline()
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, "First", parts[0].Title)
+	require.Equal(t, []int{1, 2}, parts[0].Pages)
+	require.Equal(t, "Last", parts[1].Title)
+	require.Equal(t, []int{3}, parts[1].Pages)
+
+	r, err := NewPdfReader(bytes.NewReader(parts[0].Data))
+	require.NoError(t, err)
+	numPages, err := r.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 2, numPages)
+	require.Equal(t, []string{"First"}, getOutlineTitles(r.GetOutlineTree()))
+	dests, err := r.GetNameTree("Dests")
+	require.NoError(t, err)
+	require.Equal(t, []string{"p2"}, dests.Keys())
+
+	// The link to the third page is removed, the link to "p2" is kept.
+	page, err := r.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 0)
+	page, err = r.GetPage(2)
+	require.NoError(t, err)
+	annotations, err = page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+
+	r, err = NewPdfReader(bytes.NewReader(parts[1].Data))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Last"}, getOutlineTitles(r.GetOutlineTree()))
+	dests, err = r.GetNameTree("Dests")
+	require.NoError(t, err)
+	require.Nil(t, dests)
+
+	// The source document is unchanged.
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	annotations, err = page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+}
+
+func TestSplitByPageRanges(t *testing.T) {
+	splitter, err := NewPdfSplitter(makeMergeTestDocument(t))
+	require.NoError(t, err)
+	parts, err := splitter.SplitByPageRanges("2-", "-1,3")
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, "2-", parts[0].Title)
+	require.Equal(t, []int{2, 3}, parts[0].Pages)
+	require.Equal(t, []int{1, 3}, parts[1].Pages)
+
+	r, err := NewPdfReader(bytes.NewReader(parts[1].Data))
+	require.NoError(t, err)
+	require.Equal(t, []string{"First", "Last"}, getOutlineTitles(r.GetOutlineTree()))
+
+	for _, expr := range []string{"0", "4", "2-1", "1,1", "a", "1,"} {
+		_, err = splitter.SplitByPageRanges(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	splitter, err := NewPdfSplitter(makeMergeTestDocument(t))
+	require.NoError(t, err)
+	parts, err := splitter.SplitBySize(0)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	require.Equal(t, []int{1, 2, 3}, parts[0].Pages)
+
+	// Pages exceeding the maximum size are written alone.
+	parts, err = splitter.SplitBySize(1)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	require.Equal(t, "(2)", parts[1].Title)
+
+	// Outline parts are split further.
+	splitter.MaxSize = 1
+	parts, err = splitter.SplitByOutline()
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	require.Equal(t, "First (2)", parts[1].Title)
+	require.Equal(t, []int{2}, parts[1].Pages)
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
 )
 
 // PdfRectangle is a definition of a rectangle.
@@ -80,6 +81,64 @@ func (rect *PdfRectangle) ToPdfObject() core.PdfObject {
 	)
 }
 
+// GetPdfRectangle returns the rectangle represented by the array `obj`, which may be a reference.
+// The bool flag indicates whether `obj` is a valid rectangle.
+func GetPdfRectangle(obj core.PdfObject) (PdfRectangle, bool) {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return PdfRectangle{}, false
+	}
+	rect, err := NewPdfRectangle(*arr)
+	if err != nil {
+		return PdfRectangle{}, false
+	}
+	return *rect, true
+}
+
+// Normalized returns `rect` with its lower left corner before its upper right corner.
+func (rect *PdfRectangle) Normalized() PdfRectangle {
+	return PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx),
+		Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx),
+		Ury: math.Max(rect.Lly, rect.Ury),
+	}
+}
+
+// Transform returns the bounding box of `rect` transformed by `m`.
+func (rect *PdfRectangle) Transform(m transform.Matrix) PdfRectangle {
+	xs := [4]float64{rect.Llx, rect.Urx, rect.Urx, rect.Llx}
+	ys := [4]float64{rect.Lly, rect.Lly, rect.Ury, rect.Ury}
+	res := PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for i := range xs {
+		x, y := m.Transform(xs[i], ys[i])
+		res.Llx = math.Min(res.Llx, x)
+		res.Lly = math.Min(res.Lly, y)
+		res.Urx = math.Max(res.Urx, x)
+		res.Ury = math.Max(res.Ury, y)
+	}
+	return res
+}
+
+// Intersect returns the intersection of normalized rectangles `rect` and `other`, which is empty
+// if they do not overlap.
+func (rect *PdfRectangle) Intersect(other PdfRectangle) PdfRectangle {
+	res := PdfRectangle{
+		Llx: math.Max(rect.Llx, other.Llx),
+		Lly: math.Max(rect.Lly, other.Lly),
+		Urx: math.Min(rect.Urx, other.Urx),
+		Ury: math.Min(rect.Ury, other.Ury),
+	}
+	res.Urx = math.Max(res.Urx, res.Llx)
+	res.Ury = math.Max(res.Ury, res.Lly)
+	return res
+}
+
+// Overlaps returns true if the interiors of normalized rectangles `rect` and `other` overlap.
+func (rect *PdfRectangle) Overlaps(other PdfRectangle) bool {
+	return rect.Llx < other.Urx && other.Llx < rect.Urx && rect.Lly < other.Ury && other.Lly < rect.Ury
+}
+
 // PdfDate represents a date, which is a PDF string of the form:
 // (D:YYYYMMDDHHmmSSOHH'mm)
 type PdfDate struct {
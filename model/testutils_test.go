@@ -0,0 +1,37 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// The document fixtures shared by the tests of this package. The tests of the other packages use
+// those of package internal/testutils/pdftest, which cannot be imported here.
+
+// newTestPage returns a page of size `width`x`height` with content stream `content`.
+func newTestPage(t *testing.T, width, height float64, content string) *PdfPage {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: width, Ury: height}
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+	return page
+}
+
+// writeRead writes the document of `w` (a writer, an appender or a merger) and returns the
+// reader of the written document.
+func writeRead(t *testing.T, w interface{ Write(io.Writer) error }) *PdfReader {
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return r
+}
@@ -0,0 +1,550 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Name trees (section 7.9.6 p. 88) and number trees (section 7.9.7 p. 90).
+//
+// Both tree types map sorted keys to values. The root and intermediate nodes have a Kids array
+// of child nodes, each child carrying the Limits (smallest and greatest key) of its subtree.
+// Leaf nodes hold the key/value pairs in a Names (name tree) or Nums (number tree) array.
+//
+// Loaded trees are searched in place, following the Limits of the Kids. Once a tree is
+// modified, its entries are held in a sorted slice and a balanced tree is generated on output.
+
+// treeMaxNodeSize is the maximum number of entries of a leaf, or kids of an intermediate
+// node, of generated trees.
+const treeMaxNodeSize = 32
+
+// maxTreeDepth limits the depth of loaded trees, protecting against cyclic structures.
+const maxTreeDepth = 32
+
+// treeKey is a name tree (str) or number tree (num) key.
+type treeKey struct {
+	str string
+	num int64
+}
+
+type treeEntry struct {
+	key treeKey
+	val core.PdfObject
+}
+
+// pdfTree implements the functionality shared by name and number trees.
+type pdfTree struct {
+	isNum bool
+
+	// root is the root node of a loaded tree. It is nil once the tree has been modified.
+	root *core.PdfObjectDictionary
+
+	// entries are the sorted entries of a new or modified tree.
+	entries []treeEntry
+
+	container *core.PdfIndirectObject
+}
+
+// arrayKey returns "Nums" for number trees and "Names" for name trees.
+func (t *pdfTree) arrayKey() core.PdfObjectName {
+	if t.isNum {
+		return "Nums"
+	}
+	return "Names"
+}
+
+func (t *pdfTree) compare(a, b treeKey) int {
+	if t.isNum {
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case a.str < b.str:
+		return -1
+	case a.str > b.str:
+		return 1
+	}
+	return 0
+}
+
+// parseKey reads a key object. Name tree keys are strings, number tree keys integers.
+func (t *pdfTree) parseKey(obj core.PdfObject) (treeKey, bool) {
+	if t.isNum {
+		num, err := core.GetNumberAsInt64(core.TraceToDirectObject(obj))
+		if err != nil {
+			return treeKey{}, false
+		}
+		return treeKey{num: num}, true
+	}
+	str, ok := core.GetString(obj)
+	if !ok {
+		return treeKey{}, false
+	}
+	return treeKey{str: str.Str()}, true
+}
+
+func (t *pdfTree) makeKey(key treeKey) core.PdfObject {
+	if t.isNum {
+		return core.MakeInteger(key.num)
+	}
+	return core.MakeString(key.str)
+}
+
+// load sets the root of the tree to the node `obj`.
+func (t *pdfTree) load(obj core.PdfObject) error {
+	if ind, ok := core.GetIndirect(obj); ok {
+		t.container = ind
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Tree root not a dictionary (%T)", obj)
+		return core.ErrTypeError
+	}
+	t.root = dict
+	return nil
+}
+
+// limits returns the Limits of node `dict`.
+func (t *pdfTree) limits(dict *core.PdfObjectDictionary) (treeKey, treeKey, bool) {
+	arr, ok := core.GetArray(dict.Get("Limits"))
+	if !ok || arr.Len() != 2 {
+		return treeKey{}, treeKey{}, false
+	}
+	lo, ok1 := t.parseKey(arr.Get(0))
+	hi, ok2 := t.parseKey(arr.Get(1))
+	return lo, hi, ok1 && ok2
+}
+
+// get looks up `key`. In loaded trees the Kids are binary searched by their Limits.
+func (t *pdfTree) get(key treeKey) (core.PdfObject, bool) {
+	if t.root == nil {
+		i := sort.Search(len(t.entries), func(i int) bool {
+			return t.compare(t.entries[i].key, key) >= 0
+		})
+		if i < len(t.entries) && t.compare(t.entries[i].key, key) == 0 {
+			return t.entries[i].val, true
+		}
+		return nil, false
+	}
+	return t.lookup(t.root, key, 0)
+}
+
+func (t *pdfTree) lookup(node *core.PdfObjectDictionary, key treeKey, depth int) (core.PdfObject, bool) {
+	if depth > maxTreeDepth {
+		common.Log.Debug("ERROR: Tree depth exceeded - possibly cyclic")
+		return nil, false
+	}
+
+	if arr, ok := core.GetArray(node.Get(t.arrayKey())); ok {
+		n := arr.Len() / 2
+		i := sort.Search(n, func(i int) bool {
+			k, ok := t.parseKey(arr.Get(2 * i))
+			return !ok || t.compare(k, key) >= 0
+		})
+		if i < n {
+			if k, ok := t.parseKey(arr.Get(2 * i)); ok && t.compare(k, key) == 0 {
+				return arr.Get(2*i + 1), true
+			}
+		}
+		// Fall back to a linear scan in case the leaf is not sorted properly.
+		for i := 0; i < n; i++ {
+			if k, ok := t.parseKey(arr.Get(2 * i)); ok && t.compare(k, key) == 0 {
+				return arr.Get(2*i + 1), true
+			}
+		}
+	}
+
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		return nil, false
+	}
+	elements := kids.Elements()
+	i := sort.Search(len(elements), func(i int) bool {
+		kid, ok := core.GetDict(elements[i])
+		if !ok {
+			return false
+		}
+		_, hi, ok := t.limits(kid)
+		return !ok || t.compare(hi, key) >= 0
+	})
+	if i < len(elements) {
+		if kid, ok := core.GetDict(elements[i]); ok {
+			lo, _, hasLimits := t.limits(kid)
+			if !hasLimits || t.compare(lo, key) <= 0 {
+				if val, found := t.lookup(kid, key, depth+1); found || hasLimits {
+					return val, found
+				}
+			}
+		}
+	}
+
+	// Kids without valid Limits: search all kids.
+	for _, elem := range elements {
+		kid, ok := core.GetDict(elem)
+		if !ok {
+			continue
+		}
+		if _, _, hasLimits := t.limits(kid); hasLimits {
+			continue
+		}
+		if val, found := t.lookup(kid, key, depth+1); found {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// iterate calls `f` for each entry in key order until `f` returns false.
+func (t *pdfTree) iterate(f func(key treeKey, val core.PdfObject) bool) {
+	if t.root == nil {
+		for _, e := range t.entries {
+			if !f(e.key, e.val) {
+				return
+			}
+		}
+		return
+	}
+	t.walk(t.root, f, 0, map[*core.PdfObjectDictionary]struct{}{})
+}
+
+func (t *pdfTree) walk(node *core.PdfObjectDictionary, f func(key treeKey, val core.PdfObject) bool,
+	depth int, visited map[*core.PdfObjectDictionary]struct{}) bool {
+	if _, ok := visited[node]; ok || depth > maxTreeDepth {
+		common.Log.Debug("ERROR: Cyclic tree structure - skipping node")
+		return true
+	}
+	visited[node] = struct{}{}
+
+	if kids, ok := core.GetArray(node.Get("Kids")); ok {
+		for _, elem := range kids.Elements() {
+			kid, ok := core.GetDict(elem)
+			if !ok {
+				continue
+			}
+			if !t.walk(kid, f, depth+1, visited) {
+				return false
+			}
+		}
+	}
+	if arr, ok := core.GetArray(node.Get(t.arrayKey())); ok {
+		for i := 0; i+1 < arr.Len(); i += 2 {
+			key, ok := t.parseKey(arr.Get(i))
+			if !ok {
+				common.Log.Debug("Invalid tree key (%T) - skipping", arr.Get(i))
+				continue
+			}
+			if !f(key, arr.Get(i+1)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// materialize loads all entries of a loaded tree into a sorted slice, so that the tree
+// can be modified.
+func (t *pdfTree) materialize() {
+	if t.root == nil {
+		return
+	}
+	var entries []treeEntry
+	t.iterate(func(key treeKey, val core.PdfObject) bool {
+		entries = append(entries, treeEntry{key: key, val: val})
+		return true
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return t.compare(entries[i].key, entries[j].key) < 0
+	})
+
+	// Remove duplicates, keeping the last occurrence.
+	var unique []treeEntry
+	for _, e := range entries {
+		if n := len(unique); n > 0 && t.compare(unique[n-1].key, e.key) == 0 {
+			unique[n-1] = e
+			continue
+		}
+		unique = append(unique, e)
+	}
+
+	t.entries = unique
+	t.root = nil
+}
+
+func (t *pdfTree) set(key treeKey, val core.PdfObject) {
+	t.materialize()
+	i := sort.Search(len(t.entries), func(i int) bool {
+		return t.compare(t.entries[i].key, key) >= 0
+	})
+	if i < len(t.entries) && t.compare(t.entries[i].key, key) == 0 {
+		t.entries[i].val = val
+		return
+	}
+	t.entries = append(t.entries, treeEntry{})
+	copy(t.entries[i+1:], t.entries[i:])
+	t.entries[i] = treeEntry{key: key, val: val}
+}
+
+func (t *pdfTree) remove(key treeKey) bool {
+	if _, found := t.get(key); !found {
+		return false
+	}
+	t.materialize()
+	i := sort.Search(len(t.entries), func(i int) bool {
+		return t.compare(t.entries[i].key, key) >= 0
+	})
+	t.entries = append(t.entries[:i], t.entries[i+1:]...)
+	return true
+}
+
+func (t *pdfTree) len() int {
+	if t.root == nil {
+		return len(t.entries)
+	}
+	n := 0
+	t.iterate(func(treeKey, core.PdfObject) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// toPdfObject returns the root node of the tree. Loaded trees which have not been modified
+// are returned as is, otherwise a balanced tree is generated.
+func (t *pdfTree) toPdfObject() core.PdfObject {
+	if t.container == nil {
+		t.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	if t.root != nil {
+		if t.container.PdfObject != t.root {
+			t.container.PdfObject = t.root
+		}
+		return t.container
+	}
+
+	type node struct {
+		obj    *core.PdfIndirectObject
+		lo, hi treeKey
+	}
+
+	// Leaves.
+	var nodes []node
+	for start := 0; start < len(t.entries); start += treeMaxNodeSize {
+		end := start + treeMaxNodeSize
+		if end > len(t.entries) {
+			end = len(t.entries)
+		}
+		arr := core.MakeArray()
+		for _, e := range t.entries[start:end] {
+			arr.Append(t.makeKey(e.key), e.val)
+		}
+		dict := core.MakeDict()
+		dict.Set(t.arrayKey(), arr)
+		nodes = append(nodes, node{
+			obj: core.MakeIndirectObject(dict),
+			lo:  t.entries[start].key,
+			hi:  t.entries[end-1].key,
+		})
+	}
+
+	// Intermediate levels.
+	for len(nodes) > 1 {
+		var parents []node
+		for start := 0; start < len(nodes); start += treeMaxNodeSize {
+			end := start + treeMaxNodeSize
+			if end > len(nodes) {
+				end = len(nodes)
+			}
+			kids := core.MakeArray()
+			for _, n := range nodes[start:end] {
+				n.obj.PdfObject.(*core.PdfObjectDictionary).Set("Limits",
+					core.MakeArray(t.makeKey(n.lo), t.makeKey(n.hi)))
+				kids.Append(n.obj)
+			}
+			dict := core.MakeDict()
+			dict.Set("Kids", kids)
+			parents = append(parents, node{
+				obj: core.MakeIndirectObject(dict),
+				lo:  nodes[start].lo,
+				hi:  nodes[end-1].hi,
+			})
+		}
+		nodes = parents
+	}
+
+	// The root node has no Limits.
+	root := core.MakeDict()
+	if len(nodes) == 0 {
+		root.Set(t.arrayKey(), core.MakeArray())
+	} else {
+		root.Merge(nodes[0].obj.PdfObject.(*core.PdfObjectDictionary))
+	}
+	t.container.PdfObject = root
+	return t.container
+}
+
+// PdfNameTree represents a name tree (section 7.9.6 p. 88), which maps string keys to
+// values. Name trees are used by the Names dictionary of the document catalog, e.g. for
+// named destinations (Dests), embedded files (EmbeddedFiles) and document level
+// JavaScript (JavaScript). The keys are the bytes of the PDF strings, i.e. text strings are
+// encoded in PDFDocEncoding or UTF-16BE.
+type PdfNameTree struct {
+	tree pdfTree
+}
+
+// NewPdfNameTree returns a new empty name tree.
+func NewPdfNameTree() *PdfNameTree {
+	return &PdfNameTree{}
+}
+
+// NewPdfNameTreeFromObj loads a name tree from its root node `obj`.
+func NewPdfNameTreeFromObj(obj core.PdfObject) (*PdfNameTree, error) {
+	t := &PdfNameTree{}
+	if err := t.tree.load(obj); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Get returns the value associated with `name`. The bool flag indicates whether the name
+// was found.
+func (t *PdfNameTree) Get(name string) (core.PdfObject, bool) {
+	return t.tree.get(treeKey{str: name})
+}
+
+// Set associates `val` with `name`, replacing any previous value.
+func (t *PdfNameTree) Set(name string, val core.PdfObject) {
+	t.tree.set(treeKey{str: name}, val)
+}
+
+// Remove removes `name` from the tree. Returns false if the name was not found.
+func (t *PdfNameTree) Remove(name string) bool {
+	return t.tree.remove(treeKey{str: name})
+}
+
+// Len returns the number of entries in the tree.
+func (t *PdfNameTree) Len() int {
+	return t.tree.len()
+}
+
+// Keys returns the names in the tree, in tree order.
+func (t *PdfNameTree) Keys() []string {
+	var keys []string
+	t.tree.iterate(func(key treeKey, _ core.PdfObject) bool {
+		keys = append(keys, key.str)
+		return true
+	})
+	return keys
+}
+
+// Iterate calls `f` for each entry of the tree, in tree order, until `f` returns false.
+func (t *PdfNameTree) Iterate(f func(name string, val core.PdfObject) bool) {
+	t.tree.iterate(func(key treeKey, val core.PdfObject) bool {
+		return f(key.str, val)
+	})
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (t *PdfNameTree) GetContainingPdfObject() core.PdfObject {
+	return t.tree.container
+}
+
+// ToPdfObject implements interface PdfModel. Returns the root node of the tree.
+func (t *PdfNameTree) ToPdfObject() core.PdfObject {
+	return t.tree.toPdfObject()
+}
+
+// textStringKey returns the name tree key of text string `text`.
+func textStringKey(text string) string {
+	return core.MakeTextString(text).Str()
+}
+
+// textStringFromKey returns the text string of name tree key `key`. It is the inverse of
+// textStringKey.
+func textStringFromKey(key string) string {
+	return core.MakeString(key).Decoded()
+}
+
+// PdfNumberTree represents a number tree (section 7.9.7 p. 90), which maps integer keys to
+// values. Number trees are used e.g. for page labels (PageLabels) and the structure parent
+// tree (ParentTree).
+type PdfNumberTree struct {
+	tree pdfTree
+}
+
+// NewPdfNumberTree returns a new empty number tree.
+func NewPdfNumberTree() *PdfNumberTree {
+	return &PdfNumberTree{tree: pdfTree{isNum: true}}
+}
+
+// NewPdfNumberTreeFromObj loads a number tree from its root node `obj`.
+func NewPdfNumberTreeFromObj(obj core.PdfObject) (*PdfNumberTree, error) {
+	t := NewPdfNumberTree()
+	if err := t.tree.load(obj); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Get returns the value associated with `key`. The bool flag indicates whether the key
+// was found.
+func (t *PdfNumberTree) Get(key int64) (core.PdfObject, bool) {
+	return t.tree.get(treeKey{num: key})
+}
+
+// Set associates `val` with `key`, replacing any previous value.
+func (t *PdfNumberTree) Set(key int64, val core.PdfObject) {
+	t.tree.set(treeKey{num: key}, val)
+}
+
+// Remove removes `key` from the tree. Returns false if the key was not found.
+func (t *PdfNumberTree) Remove(key int64) bool {
+	return t.tree.remove(treeKey{num: key})
+}
+
+// Len returns the number of entries in the tree.
+func (t *PdfNumberTree) Len() int {
+	return t.tree.len()
+}
+
+// Keys returns the keys in the tree, in tree order.
+func (t *PdfNumberTree) Keys() []int64 {
+	var keys []int64
+	t.tree.iterate(func(key treeKey, _ core.PdfObject) bool {
+		keys = append(keys, key.num)
+		return true
+	})
+	return keys
+}
+
+// Iterate calls `f` for each entry of the tree, in tree order, until `f` returns false.
+func (t *PdfNumberTree) Iterate(f func(key int64, val core.PdfObject) bool) {
+	t.tree.iterate(func(key treeKey, val core.PdfObject) bool {
+		return f(key.num, val)
+	})
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (t *PdfNumberTree) GetContainingPdfObject() core.PdfObject {
+	return t.tree.container
+}
+
+// ToPdfObject implements interface PdfModel. Returns the root node of the tree.
+func (t *PdfNumberTree) ToPdfObject() core.PdfObject {
+	return t.tree.toPdfObject()
+}
+
+// errNameTreeKey is returned when setting a name tree with an invalid catalog Names key.
+var errNameTreeKey = errors.New("invalid name tree key")
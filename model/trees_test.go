@@ -0,0 +1,189 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Tests lookup in a loaded name tree with intermediate nodes and Limits.
+func TestNameTreeLoad(t *testing.T) {
+	rawText := `
+1 0 obj
+<< /Kids [2 0 R 3 0 R] >>
+endobj
+2 0 obj
+<< /Limits [(Apple) (Cherry)] /Names [(Apple) 1 (Banana) 2 (Cherry) 3] >>
+endobj
+3 0 obj
+<< /Limits [(Date) (Fig)] /Kids [4 0 R] >>
+endobj
+4 0 obj
+<< /Limits [(Date) (Fig)] /Names [(Date) 4 (Elderberry) 5 (Fig) 6] >>
+endobj
+`
+	r := NewReaderForText(rawText)
+	require.NoError(t, r.ParseIndObjSeries())
+
+	obj, err := r.parser.LookupByNumber(1)
+	require.NoError(t, err)
+	require.NoError(t, core.ResolveReferencesDeep(obj, nil))
+
+	tree, err := NewPdfNameTreeFromObj(obj)
+	require.NoError(t, err)
+
+	for i, name := range []string{"Apple", "Banana", "Cherry", "Date", "Elderberry", "Fig"} {
+		val, found := tree.Get(name)
+		require.True(t, found, name)
+		require.Equal(t, fmt.Sprintf("%d", i+1), val.WriteString())
+	}
+	_, found := tree.Get("Coconut")
+	require.False(t, found)
+	_, found = tree.Get("Zucchini")
+	require.False(t, found)
+
+	require.Equal(t, []string{"Apple", "Banana", "Cherry", "Date", "Elderberry", "Fig"}, tree.Keys())
+
+	// Modify the loaded tree.
+	require.True(t, tree.Remove("Banana"))
+	require.False(t, tree.Remove("Banana"))
+	tree.Set("Coconut", core.MakeInteger(7))
+	require.Equal(t, []string{"Apple", "Cherry", "Coconut", "Date", "Elderberry", "Fig"}, tree.Keys())
+
+	val, found := tree.Get("Coconut")
+	require.True(t, found)
+	require.Equal(t, "7", val.WriteString())
+}
+
+// Tests that large trees are written balanced and can be read back.
+func TestNameTreeBalanced(t *testing.T) {
+	tree := NewPdfNameTree()
+	n := 2000
+	for i := n - 1; i >= 0; i-- {
+		tree.Set(fmt.Sprintf("name%05d", i), core.MakeInteger(int64(i)))
+	}
+	require.Equal(t, n, tree.Len())
+
+	root, ok := core.GetDict(tree.ToPdfObject())
+	require.True(t, ok)
+	require.Nil(t, root.Get("Limits"))
+	require.Nil(t, root.Get("Names"))
+
+	// Check all leaves are at the same depth and respect the node size.
+	var leafDepths []int
+	var check func(dict *core.PdfObjectDictionary, depth int)
+	check = func(dict *core.PdfObjectDictionary, depth int) {
+		if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+			require.True(t, kids.Len() <= treeMaxNodeSize)
+			for _, kid := range kids.Elements() {
+				kidDict, ok := core.GetDict(kid)
+				require.True(t, ok)
+				require.NotNil(t, kidDict.Get("Limits"))
+				check(kidDict, depth+1)
+			}
+			return
+		}
+		names, ok := core.GetArray(dict.Get("Names"))
+		require.True(t, ok)
+		require.True(t, names.Len() <= 2*treeMaxNodeSize)
+		leafDepths = append(leafDepths, depth)
+	}
+	check(root, 0)
+	for _, depth := range leafDepths {
+		require.Equal(t, leafDepths[0], depth)
+	}
+
+	// Round trip through the writer as the JavaScript name tree.
+	w := NewPdfWriter()
+	page := newTestPage(t, 612, 792, "")
+	require.NoError(t, w.AddPage(page))
+	require.NoError(t, w.SetNameTree("JavaScript", tree))
+
+	reader := writeRead(t, &w)
+	loaded, err := reader.GetNameTree("JavaScript")
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	require.Equal(t, n, loaded.Len())
+
+	for _, i := range []int{0, 31, 32, 999, 1024, n - 1} {
+		val, found := loaded.Get(fmt.Sprintf("name%05d", i))
+		require.True(t, found)
+		require.Equal(t, fmt.Sprintf("%d", i), val.WriteString())
+	}
+	_, found := loaded.Get("name99999")
+	require.False(t, found)
+}
+
+func TestNumberTree(t *testing.T) {
+	tree := NewPdfNumberTree()
+	for _, key := range []int64{50, 10, 30, 20, 40} {
+		tree.Set(key, core.MakeInteger(key*2))
+	}
+	require.Equal(t, []int64{10, 20, 30, 40, 50}, tree.Keys())
+	require.True(t, tree.Remove(30))
+
+	var keys []int64
+	tree.Iterate(func(key int64, val core.PdfObject) bool {
+		keys = append(keys, key)
+		return key < 20
+	})
+	require.Equal(t, []int64{10, 20}, keys)
+
+	loaded, err := NewPdfNumberTreeFromObj(tree.ToPdfObject())
+	require.NoError(t, err)
+	val, found := loaded.Get(40)
+	require.True(t, found)
+	require.Equal(t, "80", val.WriteString())
+	_, found = loaded.Get(30)
+	require.False(t, found)
+}
+
+// Tests that the keys of embedded files with non-Latin-1 names round trip, and that a nil name
+// tree removes the entry from the Names dictionary with both the writer and the appender.
+func TestNameTreeTextKeys(t *testing.T) {
+	names := []string{"a.txt", "文件.txt", "файл😀.txt"}
+	w := NewPdfWriter()
+	page := newTestPage(t, 612, 792, "")
+	require.NoError(t, w.AddPage(page))
+	for _, name := range names {
+		fs := NewPdfFilespecFromEmbeddedFile(name, NewPdfEmbeddedFile([]byte(name), "text/plain"))
+		require.NoError(t, w.AddEmbeddedFile(name, fs))
+	}
+	// A nil tree removes the tree set before.
+	require.NoError(t, w.SetNameTree("JavaScript", NewPdfNameTree()))
+	require.NoError(t, w.SetNameTree("JavaScript", nil))
+
+	reader := writeRead(t, &w)
+	files, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, len(names))
+	tree, err := reader.GetNameTree("EmbeddedFiles")
+	require.NoError(t, err)
+	for _, name := range names {
+		fs, ok := files[name]
+		require.True(t, ok, name)
+		require.Equal(t, name, fs.GetFileName())
+		_, found := tree.Get(textStringKey(name))
+		require.True(t, found)
+	}
+	tree, err = reader.GetNameTree("JavaScript")
+	require.NoError(t, err)
+	require.Nil(t, tree)
+
+	// Removal of the only name tree with the appender.
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, appender.SetNameTree("EmbeddedFiles", nil))
+	reader = writeRead(t, appender)
+	files, err = reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Empty(t, files)
+	require.Nil(t, reader.catalog.Get("Names"))
+}
@@ -167,6 +167,16 @@ type PdfWriter struct {
 	// Forms.
 	acroForm *PdfAcroForm
 
+	// Portable collections.
+	collection *PdfCollection
+
+	// Name trees of the Names dictionary of the catalog.
+	nameTrees map[core.PdfObjectName]*PdfNameTree
+
+	pageLabels *PdfPageLabels
+
+	outputIntents []*PdfOutputIntent
+
 	optimizer              Optimizer
 	crossReferenceMap      map[int]crossReference
 	writeOffset            int64 // used by PdfAppender
@@ -409,6 +419,17 @@ func (w *PdfWriter) SetOCProperties(ocProperties core.PdfObject) error {
 	return nil
 }
 
+// SetOptionalContentProperties sets the optional content properties (layers) of the document.
+func (w *PdfWriter) SetOptionalContentProperties(ocProperties *PdfOCProperties) error {
+	if ocProperties == nil {
+		return nil
+	}
+	if w.majorVersion == 1 && w.minorVersion < 5 {
+		w.SetVersion(1, 5)
+	}
+	return w.SetOCProperties(ocProperties.ToPdfObject())
+}
+
 // SetNamedDestinations sets the Names entry in the PDF catalog.
 // See section 12.3.2.3 "Named Destinations" (p. 367 PDF32000_2008).
 func (w *PdfWriter) SetNamedDestinations(names core.PdfObject) error {
@@ -421,6 +442,147 @@ func (w *PdfWriter) SetNamedDestinations(names core.PdfObject) error {
 	return w.addObjects(names)
 }
 
+// SetCollection turns the output document into a portable collection (portfolio) described
+// by `collection`. The files of the collection are added with AddEmbeddedFile.
+// The PDF version is raised to 1.7 if needed.
+// See section 12.3.5 "Collections" (p. 370 PDF32000_2008).
+func (w *PdfWriter) SetCollection(collection *PdfCollection) {
+	w.collection = collection
+	if collection != nil && (w.majorVersion == 1 && w.minorVersion < 7) {
+		w.SetVersion(1, 7)
+	}
+}
+
+// AddEmbeddedFile adds the file specification `fs` to the EmbeddedFiles name tree of the
+// output document under key `name`. For files located in a collection folder, the key is
+// obtained with PdfCollectionFolder.EmbeddedFileName.
+func (w *PdfWriter) AddEmbeddedFile(name string, fs *PdfFilespec) error {
+	if fs == nil {
+		return errors.New("file specification cannot be nil")
+	}
+	tree := w.nameTrees["EmbeddedFiles"]
+	if tree == nil {
+		tree = NewPdfNameTree()
+		if err := w.SetNameTree("EmbeddedFiles", tree); err != nil {
+			return err
+		}
+	}
+	tree.Set(textStringKey(name), fs.ToPdfObject())
+	return nil
+}
+
+// SetNameTree sets the name tree stored under `key` in the Names dictionary of the catalog,
+// e.g. "Dests", "EmbeddedFiles" or "JavaScript". A nil `tree` removes the entry. The name trees
+// take precedence over the entries of a Names dictionary set with SetNamedDestinations.
+// See section 7.7.4 "Name Dictionary" (p. 80 PDF32000_2008).
+func (w *PdfWriter) SetNameTree(key string, tree *PdfNameTree) error {
+	if key == "" {
+		return errNameTreeKey
+	}
+	if w.nameTrees == nil {
+		w.nameTrees = map[core.PdfObjectName]*PdfNameTree{}
+	}
+	w.nameTrees[core.PdfObjectName(key)] = tree
+	return nil
+}
+
+// setAdobeExtensionLevel sets the level of the Adobe extensions to PDF 1.7 in the Extensions
+// dictionary of the catalog to `level`, unless an equal or higher level is already set. The other
+// extensions are kept.
+func (w *PdfWriter) setAdobeExtensionLevel(level int) {
+	// Copy the existing dictionaries, as they may belong to another document.
+	extensions := core.MakeDict()
+	if dict, ok := core.GetDict(w.catalog.Get("Extensions")); ok {
+		extensions.Merge(dict)
+	}
+	w.catalog.Set("Extensions", extensions)
+
+	// The ADBE entry is an extension dictionary or an array of extension dictionaries.
+	adbe := core.MakeDict()
+	switch t := core.TraceToDirectObject(extensions.Get("ADBE")).(type) {
+	case *core.PdfObjectDictionary:
+		adbe.Merge(t)
+		extensions.Set("ADBE", adbe)
+	case *core.PdfObjectArray:
+		arr := core.MakeArray()
+		found := false
+		for _, elem := range t.Elements() {
+			if dict, ok := core.GetDict(elem); ok && !found {
+				if base, _ := core.GetNameVal(dict.Get("BaseVersion")); base == "1.7" {
+					adbe.Merge(dict)
+					elem, found = adbe, true
+				}
+			}
+			arr.Append(elem)
+		}
+		if !found {
+			arr.Append(adbe)
+		}
+		extensions.Set("ADBE", arr)
+	default:
+		extensions.Set("ADBE", adbe)
+	}
+
+	if base, _ := core.GetNameVal(adbe.Get("BaseVersion")); base > "1.7" {
+		// The extensions to later versions include the level.
+		return
+	}
+	if current, ok := core.GetIntVal(adbe.Get("ExtensionLevel")); ok && current >= level {
+		return
+	}
+	adbe.Set("BaseVersion", core.MakeName("1.7"))
+	adbe.Set("ExtensionLevel", core.MakeInteger(int64(level)))
+}
+
+// writeNameTrees sets the name trees in the Names dictionary of the catalog.
+func (w *PdfWriter) writeNameTrees() error {
+	if len(w.nameTrees) == 0 {
+		return nil
+	}
+	names := makeNamesDict(w.catalog.Get("Names"), w.nameTrees)
+	if names == nil {
+		w.catalog.Remove("Names")
+		return nil
+	}
+	namesObj := core.MakeIndirectObject(names)
+	w.catalog.Set("Names", namesObj)
+	return w.addObjects(namesObj)
+}
+
+// makeNamesDict returns a copy of the Names dictionary `namesObj`, as it may belong to another
+// document, with the name trees of `trees` set. The entries of the nil trees are removed.
+// Returns nil if the resulting dictionary is empty.
+func makeNamesDict(namesObj core.PdfObject, trees map[core.PdfObjectName]*PdfNameTree) *core.PdfObjectDictionary {
+	names := core.MakeDict()
+	if dict, ok := core.GetDict(namesObj); ok {
+		names.Merge(dict)
+	}
+
+	keys := make([]string, 0, len(trees))
+	for key := range trees {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := core.PdfObjectName(k)
+		if tree := trees[key]; tree != nil {
+			names.Set(key, tree.ToPdfObject())
+		} else {
+			names.Remove(key)
+		}
+	}
+	if len(names.Keys()) == 0 {
+		return nil
+	}
+	return names
+}
+
+// SetPageLabels sets the page labels of the output document.
+// See section 12.4.2 "Page Labels" (p. 383 PDF32000_2008).
+func (w *PdfWriter) SetPageLabels(labels *PdfPageLabels) {
+	w.pageLabels = labels
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (w *PdfWriter) SetOptimizer(optimizer Optimizer) {
 	w.optimizer = optimizer
@@ -959,6 +1121,46 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// Page labels.
+	if w.pageLabels != nil {
+		labels := w.pageLabels.ToPdfObject()
+		w.catalog.Set("PageLabels", labels)
+		err := w.addObjects(labels)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Output intents.
+	if len(w.outputIntents) > 0 {
+		intents := core.MakeArray()
+		for _, intent := range w.outputIntents {
+			intents.Append(intent.ToPdfObject())
+		}
+		w.catalog.Set("OutputIntents", intents)
+		err := w.addObjects(intents)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Name trees and portable collection.
+	if err := w.writeNameTrees(); err != nil {
+		return err
+	}
+	if w.collection != nil {
+		collection := w.collection.ToPdfObject()
+		w.catalog.Set("Collection", collection)
+		if w.collection.Folders != nil {
+			// Folders are defined in Adobe extension level 3.
+			w.setAdobeExtensionLevel(3)
+		}
+		err := w.addObjects(collection)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Check pending objects prior to write.
 	for pendingObj, pendingObjDicts := range w.pendingObjects {
 		if !w.hasObject(pendingObj) {
@@ -984,6 +1186,15 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	w.copyObjects()
 
 	if w.optimizer != nil {
+		if optimizer, ok := w.optimizer.(TrailerOptimizer); ok && !w.appendMode {
+			trailer := core.MakeDict()
+			trailer.Set("Root", w.root)
+			trailer.Set("Info", w.infoObj)
+			if w.encryptObj != nil {
+				trailer.Set("Encrypt", w.encryptObj)
+			}
+			optimizer.SetTrailer(trailer)
+		}
 		var err error
 		w.objects, err = w.optimizer.Optimize(w.objects)
 		if err != nil {
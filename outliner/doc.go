@@ -0,0 +1,18 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package outliner converts the text of pages to outlines: the text objects of the page content
+// streams are replaced with paths filled, stroked or used as clipping paths as the glyphs were,
+// so that the pages look the same without using the fonts, and the text can no longer be
+// extracted. This is needed e.g. for printing fonts that cannot be licensed for embedding.
+//
+// The glyph outlines are read from the embedded TrueType, CFF and Type 1 font programs, and from
+// substitute fonts for the fonts that are not embedded. The text matrix, the text state
+// parameters (character and word spacing, horizontal scaling, leading and rise) and the text
+// rendering mode are applied to the glyphs.
+//
+// The form XObjects, the tiling patterns and the annotation appearances used by the pages are
+// converted in place. The text objects using Type 3 fonts are kept as text.
+package outliner
@@ -0,0 +1,338 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outliner
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/pdfutil"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// OutlinePage replaces the text of `page` with the outlines of the glyphs, in the content streams
+// of the page and the form XObjects, tiling patterns and annotation appearances it uses.
+func OutlinePage(page *model.PdfPage) error {
+	if page.Resources == nil {
+		page.Resources = model.NewPdfPageResources()
+	}
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+
+	o := newOutliner()
+	ops, err := o.outlineContent(contents, page.Resources)
+	if err != nil {
+		return err
+	}
+	err = page.SetContentStreams([]string{string(ops.Bytes())}, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	for _, annot := range annotations {
+		if err := o.outlineAnnotation(annot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutlineDocument replaces the text of the pages of `reader` with the outlines of the glyphs
+// (see OutlinePage).
+func OutlineDocument(reader *model.PdfReader) error {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return err
+		}
+		if err := OutlinePage(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// glyphKey identifies a glyph of a font.
+type glyphKey struct {
+	font *model.PdfFont
+	code textencoding.CharCode
+}
+
+// glyphOutline is the outline of a glyph, or the error loading it.
+type glyphOutline struct {
+	path *model.GlyphPath
+	err  error
+}
+
+// outliner converts the text of content streams to outlines.
+type outliner struct {
+	fonts   textstate.FontCache
+	glyphs  map[glyphKey]glyphOutline
+	visited pdfutil.ObjectSet
+}
+
+// newOutliner returns a new outliner.
+func newOutliner() *outliner {
+	return &outliner{
+		fonts:   textstate.FontCache{},
+		glyphs:  map[glyphKey]glyphOutline{},
+		visited: pdfutil.ObjectSet{},
+	}
+}
+
+// outlineContent returns the operations of content stream `content`, drawn with resources
+// `resources`, with the text objects replaced with the outlines of their glyphs.
+func (o *outliner) outlineContent(content string, resources *model.PdfPageResources) (
+	*contentstream.ContentStreamOperations, error) {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var out contentstream.ContentStreamOperations
+	ts := textstate.New()
+	var stack []textstate.State
+	var text *textObject
+	for _, op := range *ops {
+		if text != nil {
+			if op.Operand == "BT" {
+				// Nested text objects are invalid: end the current one.
+				out = append(out, text.end()...)
+				ts = *text.ts
+			} else {
+				if err := o.outlineTextOperation(text, op, resources); err != nil {
+					return nil, err
+				}
+				if op.Operand == "ET" {
+					out = append(out, text.end()...)
+					ts = *text.ts
+					text = nil
+				}
+				continue
+			}
+		}
+
+		switch op.Operand {
+		case "BT":
+			text = newTextObject(op, ts)
+			continue
+		case "q":
+			stack = append(stack, ts)
+		case "Q":
+			if len(stack) > 0 {
+				ts = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "Tc", "Tw", "Tz", "TL", "Ts", "Tr", "Tf":
+			ts.Set(op, func(name core.PdfObjectName) *model.PdfFont {
+				return o.fonts.Get(resources, name)
+			})
+		case "gs":
+			o.setExtGState(op, resources, &ts)
+		case "Do":
+			if err := o.outlineXObject(op, resources); err != nil {
+				return nil, err
+			}
+		case "scn", "SCN":
+			if err := o.outlinePattern(op, resources); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, op)
+	}
+	if text != nil {
+		// Unterminated text object.
+		out = append(out, text.ops...)
+	}
+	return &out, nil
+}
+
+// outlineTextOperation adds operation `op` of text object `text`, drawn with resources
+// `resources`.
+func (o *outliner) outlineTextOperation(text *textObject, op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) error {
+	text.ops = append(text.ops, op)
+	getFont := func(name core.PdfObjectName) *model.PdfFont {
+		return o.fonts.Get(resources, name)
+	}
+	switch op.Operand {
+	case "ET":
+		return nil
+	case "gs":
+		o.setExtGState(op, resources, text.ts)
+	case "scn", "SCN":
+		if err := o.outlinePattern(op, resources); err != nil {
+			return err
+		}
+	}
+	args, show := text.handle(op, getFont)
+	if show {
+		text.showText(args, o.glyph)
+	}
+	return nil
+}
+
+// glyph returns the outline of the glyph of `font` for `code`, or nil if `code` maps to no glyph
+// of the font program. An error is returned if the glyph cannot be loaded.
+func (o *outliner) glyph(font *model.PdfFont, code textencoding.CharCode) (*model.GlyphPath, error) {
+	key := glyphKey{font, code}
+	glyph, ok := o.glyphs[key]
+	if !ok {
+		glyph.path, glyph.err = font.GetCharGlyph(code)
+		o.glyphs[key] = glyph
+	}
+	return glyph.path, glyph.err
+}
+
+// setExtGState updates `ts` with the font of the graphics state parameter dictionary set by `op`.
+func (o *outliner) setExtGState(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	ts *textstate.State) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	obj, ok := resources.GetExtGState(*name)
+	if !ok {
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	ts.SetExtGStateFont(dict, o.fonts)
+}
+
+// outlineXObject converts the form XObject drawn by Do operation `op` in place.
+func (o *outliner) outlineXObject(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) error {
+	if len(op.Params) != 1 || resources == nil {
+		return nil
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return nil
+	}
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil || xtype != model.XObjectTypeForm {
+		return nil
+	}
+	return o.outlineForm(stream, resources)
+}
+
+// outlineForm converts the content stream of form XObject `stream` in place, drawn with
+// resources `resources` if the form has none.
+func (o *outliner) outlineForm(stream *core.PdfObjectStream, resources *model.PdfPageResources) error {
+	if o.visited.Visit(stream) {
+		return nil
+	}
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid form XObject: %v - not converted", err)
+		return nil
+	}
+	content, err := xform.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form XObject: %v - not converted", err)
+		return nil
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+	if formResources == nil {
+		formResources = model.NewPdfPageResources()
+	}
+
+	ops, err := o.outlineContent(string(content), formResources)
+	if err != nil {
+		return err
+	}
+	return pdfutil.SetStreamData(stream, ops.Bytes())
+}
+
+// outlinePattern converts the content stream of the tiling pattern set by color operation `op`
+// in place.
+func (o *outliner) outlinePattern(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) error {
+	if len(op.Params) == 0 || resources == nil {
+		return nil
+	}
+	name, ok := core.GetName(op.Params[len(op.Params)-1])
+	if !ok {
+		return nil
+	}
+	pattern, ok := resources.GetPatternByName(*name)
+	if !ok || !pattern.IsTiling() || o.visited.Visit(pattern.GetContainingPdfObject()) {
+		return nil
+	}
+
+	tiling := pattern.GetAsTilingPattern()
+	stream, ok := core.GetStream(pattern.GetContainingPdfObject())
+	if !ok {
+		return nil
+	}
+	content, err := tiling.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode tiling pattern: %v - not converted", err)
+		return nil
+	}
+	patternResources := tiling.Resources
+	if patternResources == nil {
+		patternResources = resources
+	}
+	ops, err := o.outlineContent(string(content), patternResources)
+	if err != nil {
+		return err
+	}
+	return pdfutil.SetStreamData(stream, ops.Bytes())
+}
+
+// outlineAnnotation converts the appearance streams of `annot` in place.
+func (o *outliner) outlineAnnotation(annot *model.PdfAnnotation) error {
+	dict, ok := core.GetDict(annot.GetContainingPdfObject())
+	if !ok {
+		return nil
+	}
+	ap, ok := core.GetDict(dict.Get("AP"))
+	if !ok {
+		return nil
+	}
+	for _, key := range []core.PdfObjectName{"N", "R", "D"} {
+		obj := ap.Get(key)
+		if stream, ok := core.GetStream(obj); ok {
+			if err := o.outlineForm(stream, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		// Appearances of the states of the annotation.
+		states, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		for _, state := range states.Keys() {
+			if stream, ok := core.GetStream(states.Get(state)); ok {
+				if err := o.outlineForm(stream, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
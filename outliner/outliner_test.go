@@ -0,0 +1,164 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outliner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// pageOperations returns the operations of the content streams of `page`.
+func pageOperations(t *testing.T, page *model.PdfPage) contentstream.ContentStreamOperations {
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	require.NoError(t, err)
+	return *ops
+}
+
+// operands returns the operands of `ops`.
+func operands(ops contentstream.ContentStreamOperations) []string {
+	var names []string
+	for _, op := range ops {
+		names = append(names, op.Operand)
+	}
+	return names
+}
+
+// pathBounds returns the bounding box of the points of the path operations of `ops`.
+func pathBounds(t *testing.T, ops contentstream.ContentStreamOperations) model.PdfRectangle {
+	bounds := model.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for _, op := range ops {
+		if op.Operand != "m" && op.Operand != "l" && op.Operand != "c" {
+			continue
+		}
+		f, err := core.GetNumbersAsFloat(op.Params)
+		require.NoError(t, err)
+		for i := 0; i+1 < len(f); i += 2 {
+			bounds.Llx = math.Min(bounds.Llx, f[i])
+			bounds.Lly = math.Min(bounds.Lly, f[i+1])
+			bounds.Urx = math.Max(bounds.Urx, f[i])
+			bounds.Ury = math.Max(bounds.Ury, f[i+1])
+		}
+	}
+	return bounds
+}
+
+func TestOutlinePage(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 200, "BT /F1 10 Tf 2 0 0 2 100 50 Tm 1 0 0 rg (HI) Tj ET\n/Fm0 Do\n")
+
+	// Form XObject with text.
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 200, 200})
+	form.Resources = page.Resources
+	require.NoError(t, form.SetContentStream([]byte("BT /F1 12 Tf 10 10 Td (Form) Tj ET"), core.NewRawEncoder()))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm0", form))
+
+	require.NoError(t, OutlinePage(page))
+
+	ops := pageOperations(t, page)
+	names := operands(ops)
+	require.NotContains(t, names, "BT")
+	require.NotContains(t, names, "Tj")
+	require.Equal(t, []string{"Tf", "rg", "m"}, names[:3])
+	fill := -1
+	for i, name := range names {
+		if name == "f" {
+			fill = i
+			break
+		}
+	}
+	require.True(t, fill > 0)
+
+	// "HI" is 1000 units wide in Helvetica, shown at 20 units per text space unit.
+	bounds := pathBounds(t, ops[:fill])
+	require.InDelta(t, 100, bounds.Llx, 2)
+	require.InDelta(t, 120, bounds.Urx, 2)
+	require.InDelta(t, 50, bounds.Lly, 0.1)
+	require.True(t, bounds.Ury > 60 && bounds.Ury < 66, "bounds: %v", bounds)
+
+	// The form is converted in place.
+	formContent, err := form.GetContentStream()
+	require.NoError(t, err)
+	formOps, err := contentstream.NewContentStreamParser(string(formContent)).Parse()
+	require.NoError(t, err)
+	require.NotContains(t, operands(*formOps), "Tj")
+	require.Contains(t, operands(*formOps), "f")
+
+	// No text can be extracted.
+	ex, err := extractor.New(page)
+	require.NoError(t, err)
+	text, err := ex.ExtractText()
+	require.NoError(t, err)
+	require.NotContains(t, text, "HI")
+	require.NotContains(t, text, "Form")
+}
+
+func TestOutlineTextState(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 200, "BT /F1 10 Tf 7 Tr 50 Tz 5 Ts 10 0 Td (H) Tj ET\n0 0 100 100 re f\n")
+	require.NoError(t, OutlinePage(page))
+
+	ops := pageOperations(t, page)
+	names := operands(ops)
+	require.Equal(t, []string{"Tf", "Tr", "Tz", "Ts", "m"}, names[:5])
+	require.Equal(t, []string{"W", "n", "re", "f"}, names[len(names)-4:])
+	require.NotContains(t, names[:len(names)-1], "f")
+
+	// "H" is 722 units wide, scaled horizontally by 50% and raised by 5.
+	bounds := pathBounds(t, ops)
+	require.InDelta(t, 10, bounds.Llx, 1)
+	require.InDelta(t, 13.61, bounds.Urx, 1)
+	require.InDelta(t, 5, bounds.Lly, 0.1)
+}
+
+func TestOutlineType3Kept(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 200, "BT /T3 10 Tf (a) Tj ET\nBT /F1 10 Tf (a) Tj ET\n")
+
+	glyph, err := core.MakeStream([]byte("1000 0 d0 0 0 1000 1000 re f"), nil)
+	require.NoError(t, err)
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("Type3"))
+	font.Set("FontBBox", core.MakeArrayFromFloats([]float64{0, 0, 1000, 1000}))
+	font.Set("FontMatrix", core.MakeArrayFromFloats([]float64{0.001, 0, 0, 0.001, 0, 0}))
+	charProcs := core.MakeDict()
+	charProcs.Set("a", glyph)
+	font.Set("CharProcs", charProcs)
+	encoding := core.MakeDict()
+	encoding.Set("Differences", core.MakeArray(core.MakeInteger(97), core.MakeName("a")))
+	font.Set("Encoding", encoding)
+	font.Set("FirstChar", core.MakeInteger(97))
+	font.Set("LastChar", core.MakeInteger(97))
+	font.Set("Widths", core.MakeArrayFromIntegers([]int{1000}))
+	require.NoError(t, page.Resources.SetFontByName("T3", font))
+
+	require.NoError(t, OutlinePage(page))
+
+	names := operands(pageOperations(t, page))
+	require.Equal(t, []string{"BT", "Tf", "Tj", "ET", "Tf", "m"}, names[:6])
+}
+
+func TestOutlineMissingGlyphKept(t *testing.T) {
+	// The substitute font of Symbol has no glyph for α (code 0x61), so the text object is kept
+	// while the blank glyph of the space does not prevent the outlining.
+	page := pdftest.NewPage(t, 200, 200, "BT /S1 10 Tf (a) Tj ET\nBT /F1 10 Tf ( ) Tj ET\n")
+	font, err := model.NewStandard14Font(model.SymbolName)
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetFontByName("S1", font.ToPdfObject()))
+
+	require.NoError(t, OutlinePage(page))
+
+	names := operands(pageOperations(t, page))
+	require.Equal(t, []string{"BT", "Tf", "Tj", "ET", "Tf"}, names)
+}
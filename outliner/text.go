@@ -0,0 +1,185 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outliner
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// textObject is a text object being converted to outlines.
+type textObject struct {
+	ts   *textstate.State
+	text textstate.Object
+
+	// ops are the operations of the text object, kept if it cannot be converted.
+	ops []*contentstream.ContentStreamOperation
+	// outlined are the operations replacing the text object and clip the path accumulated by
+	// the clipping text rendering modes.
+	outlined []*contentstream.ContentStreamOperation
+	clip     []*contentstream.ContentStreamOperation
+	// keep is true if the text object is kept as text.
+	keep bool
+}
+
+// newTextObject returns the text object begun by BT operation `op` with text state `ts`.
+func newTextObject(op *contentstream.ContentStreamOperation, ts textstate.State) *textObject {
+	to := &textObject{
+		ts:  &ts,
+		ops: []*contentstream.ContentStreamOperation{op},
+	}
+	to.text.Begin()
+	return to
+}
+
+// handle updates the text state with the operation `op` of the text object, loading the fonts
+// with `getFont`, and adds the operations replacing it, except for the glyphs. It returns the
+// text operands to show (strings and positioning adjustments as for TJ) and true if `op` shows
+// text.
+func (to *textObject) handle(op *contentstream.ContentStreamOperation,
+	getFont func(name core.PdfObjectName) *model.PdfFont) ([]core.PdfObject, bool) {
+	ts := to.ts
+	args, show := to.text.Handle(op, ts, getFont)
+	switch op.Operand {
+	case "Tc", "Tw", "Tz", "TL", "Ts", "Tr", "Tf":
+		// The text state operations are also valid outside text objects.
+		to.outlined = append(to.outlined, op)
+	case "TD":
+		to.outlined = append(to.outlined, &contentstream.ContentStreamOperation{
+			Operand: "TL",
+			Params:  []core.PdfObject{core.MakeFloat(ts.Leading)},
+		})
+	case `"`:
+		to.outlined = append(to.outlined,
+			&contentstream.ContentStreamOperation{
+				Operand: "Tw",
+				Params:  []core.PdfObject{core.MakeFloat(ts.WordSpacing)},
+			},
+			&contentstream.ContentStreamOperation{
+				Operand: "Tc",
+				Params:  []core.PdfObject{core.MakeFloat(ts.CharSpacing)},
+			})
+	case "Td", "Tm", "T*", "Tj", "TJ", "'":
+	default:
+		// General graphics state, color and marked content operations.
+		to.outlined = append(to.outlined, op)
+	}
+	return args, show
+}
+
+// showText adds the outlines of the glyphs of the text operands `args` (strings and positioning
+// adjustments as for TJ), painted with the text rendering mode. The outlines are returned by
+// `glyph`. The text object is kept as text if the font is not set, is a Type 3 font or has glyphs
+// that cannot be loaded.
+func (to *textObject) showText(args []core.PdfObject,
+	glyph func(font *model.PdfFont, code textencoding.CharCode) (*model.GlyphPath, error)) {
+	ts := to.ts
+	font := ts.Font
+	if font == nil {
+		common.Log.Debug("ERROR: No font set - text kept")
+		to.keep = true
+		return
+	}
+	if font.Subtype() == "Type3" {
+		common.Log.Debug("Type 3 font %s - text kept", font)
+		to.keep = true
+		return
+	}
+	cc := contentstream.NewContentCreator()
+	to.text.Show(args, ts, func(g textstate.Glyph) {
+		// The glyphs without outline, e.g. those of the codes mapped to .notdef, are not
+		// painted.
+		path, err := glyph(font, g.Code)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to get glyph. code=0x%04x font=%s err=%v - text kept",
+				g.Code, font, err)
+			to.keep = true
+			return
+		}
+		if path != nil {
+			addGlyphPath(cc, path, g.Matrix)
+		}
+	}, nil)
+	if to.keep {
+		return
+	}
+
+	path := *cc.Operations()
+	if len(path) == 0 {
+		return
+	}
+	fill, stroke, clip := ts.PaintModes()
+	var paint string
+	switch {
+	case fill && stroke:
+		paint = "B"
+	case fill:
+		paint = "f"
+	case stroke:
+		paint = "S"
+	}
+	if paint != "" {
+		to.outlined = append(to.outlined, path...)
+		to.outlined = append(to.outlined, &contentstream.ContentStreamOperation{Operand: paint})
+	}
+	if clip {
+		to.clip = append(to.clip, path...)
+	}
+}
+
+// end returns the operations replacing the text object: the outlines of the glyphs followed by
+// the clipping path accumulated by the clipping text rendering modes, or the operations of the
+// text object if it is kept as text.
+func (to *textObject) end() []*contentstream.ContentStreamOperation {
+	if to.keep {
+		return to.ops
+	}
+	ops := to.outlined
+	if len(to.clip) > 0 {
+		ops = append(ops, to.clip...)
+		ops = append(ops,
+			&contentstream.ContentStreamOperation{Operand: "W"},
+			&contentstream.ContentStreamOperation{Operand: "n"})
+	}
+	return ops
+}
+
+// addGlyphPath adds the path of glyph outline `glyph` transformed by the glyph space to user
+// space matrix `m` to `cc`. The quadratic Bézier curves are converted to cubic ones.
+func addGlyphPath(cc *contentstream.ContentCreator, glyph *model.GlyphPath, m transform.Matrix) {
+	var cur, start model.GlyphPoint
+	for _, seg := range glyph.Segments {
+		switch seg.Type {
+		case model.GlyphMoveTo:
+			cur, start = seg.Points[0], seg.Points[0]
+			cc.Add_m(m.Transform(cur.X, cur.Y))
+		case model.GlyphLineTo:
+			cur = seg.Points[0]
+			cc.Add_l(m.Transform(cur.X, cur.Y))
+		case model.GlyphQuadTo:
+			q, p := seg.Points[0], seg.Points[1]
+			x1, y1 := m.Transform(cur.X+2*(q.X-cur.X)/3, cur.Y+2*(q.Y-cur.Y)/3)
+			x2, y2 := m.Transform(p.X+2*(q.X-p.X)/3, p.Y+2*(q.Y-p.Y)/3)
+			x3, y3 := m.Transform(p.X, p.Y)
+			cc.Add_c(x1, y1, x2, y2, x3, y3)
+			cur = p
+		case model.GlyphCubeTo:
+			x1, y1 := m.Transform(seg.Points[0].X, seg.Points[0].Y)
+			x2, y2 := m.Transform(seg.Points[1].X, seg.Points[1].Y)
+			x3, y3 := m.Transform(seg.Points[2].X, seg.Points[2].Y)
+			cc.Add_c(x1, y1, x2, y2, x3, y3)
+			cur = seg.Points[2]
+		case model.GlyphClose:
+			cc.Add_h()
+			cur = start
+		}
+	}
+}
@@ -0,0 +1,1010 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// exclusionExtent is the half size of the rectangle, in device space, used to clip out the
+// redaction regions from the content partially overlapping them.
+const exclusionExtent = 1e7
+
+// imageSpace is the unit square, which the CTM maps onto the images.
+var imageSpace = model.PdfRectangle{Urx: 1, Ury: 1}
+
+// region is a redaction region in the default user space of the page.
+type region struct {
+	rect  model.PdfRectangle
+	index int // Index of the redaction.
+}
+
+// contentRedactor removes the content inside redaction regions from content streams.
+type contentRedactor struct {
+	regions []region
+	fonts   textstate.FontCache
+
+	// Text removed from each redaction.
+	texts []*textCollector
+}
+
+// newContentRedactor returns a contentRedactor for `redactions`.
+func newContentRedactor(redactions []*Redaction) *contentRedactor {
+	r := &contentRedactor{
+		fonts: textstate.FontCache{},
+	}
+	for i, redaction := range redactions {
+		for _, rect := range redaction.Regions {
+			r.regions = append(r.regions, region{rect: rect.Normalized(), index: i})
+		}
+		r.texts = append(r.texts, &textCollector{})
+	}
+	return r
+}
+
+// graphicsState is the part of the graphics state needed to locate the content.
+type graphicsState struct {
+	ctm       transform.Matrix
+	lineWidth float64
+	text      textstate.State
+}
+
+// redact returns the operations `ops` drawn with resources `resources` and initial CTM `ctm`
+// without the content inside the redaction regions. Returns false if nothing was removed.
+func (r *contentRedactor) redact(ops *contentstream.ContentStreamOperations, resources *model.PdfPageResources,
+	ctm transform.Matrix) (*contentstream.ContentStreamOperations, bool, error) {
+	gs := graphicsState{ctm: ctm, lineWidth: 1, text: textstate.New()}
+	var stack []graphicsState
+	var text textstate.Object
+	var path pathBuilder
+	var clip *contentstream.ContentStreamOperation
+	var marked []*contentstream.ContentStreamOperation
+	var out contentstream.ContentStreamOperations
+	changed := false
+	// Operations drawing the redacted copies of the XObjects, by name of the original XObjects.
+	replaced := map[core.PdfObjectName][]*contentstream.ContentStreamOperation{}
+
+	getFont := func(name core.PdfObjectName) *model.PdfFont {
+		return r.getFont(resources, name)
+	}
+	showText := func(op *contentstream.ContentStreamOperation, args []core.PdfObject,
+		prefix ...*contentstream.ContentStreamOperation) {
+		kept, removed := r.showText(args, &gs, &text)
+		if !removed {
+			out = append(out, op)
+			return
+		}
+		changed = true
+		// The replacement text of the enclosing marked content sequences could contain the
+		// removed text.
+		for _, mc := range marked {
+			if mc.Operand != "BDC" || len(mc.Params) != 2 {
+				continue
+			}
+			if dict, ok := mc.Params[1].(*core.PdfObjectDictionary); ok {
+				dict.Remove("ActualText")
+				dict.Remove("Alt")
+				dict.Remove("E")
+			}
+		}
+		out = append(out, prefix...)
+		out = append(out, &contentstream.ContentStreamOperation{
+			Operand: "TJ",
+			Params:  []core.PdfObject{core.MakeArray(kept...)},
+		})
+	}
+
+	for _, op := range *ops {
+		switch op.Operand {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil && len(f) == 6 {
+				gs.ctm.Concat(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+			}
+		case "w":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil && len(f) == 1 {
+				gs.lineWidth = f[0]
+			}
+		case "gs":
+			r.setExtGState(op, resources, &gs)
+
+		// Text.
+		case "BT":
+			text.Begin()
+		case "Tc", "Tw", "Tz", "TL", "Ts", "Tr", "Tf", "Td", "TD", "Tm", "T*", "Tj", "TJ", "'", `"`:
+			args, show := text.Handle(op, &gs.text, getFont)
+			if !show {
+				break
+			}
+			// The replaced operations also move to the next line and set the spacings.
+			switch op.Operand {
+			case "'":
+				showText(op, args, &contentstream.ContentStreamOperation{Operand: "T*"})
+			case `"`:
+				showText(op, args,
+					&contentstream.ContentStreamOperation{Operand: "Tw", Params: op.Params[:1]},
+					&contentstream.ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]},
+					&contentstream.ContentStreamOperation{Operand: "T*"})
+			default:
+				showText(op, args)
+			}
+			continue
+
+		// Paths.
+		case "m", "l", "c", "v", "y", "h", "re":
+			path.add(op, gs.ctm)
+			continue
+		case "W", "W*":
+			clip = op
+			continue
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			paintOps, removed := r.paintPath(&path, clip, op, &gs)
+			out = append(out, paintOps...)
+			changed = changed || removed
+			path = pathBuilder{}
+			clip = nil
+			continue
+		case "sh":
+			// The shading fills the clipping region: clip out all the regions.
+			out = append(out, &contentstream.ContentStreamOperation{Operand: "q"})
+			out = append(out, r.exclusionClip(r.regions, gs.ctm)...)
+			out = append(out, op, &contentstream.ContentStreamOperation{Operand: "Q"})
+			changed = true
+			continue
+
+		// XObjects and inline images.
+		case "Do":
+			ops, removed, err := r.redactXObject(op, resources, gs.ctm)
+			if err != nil {
+				return nil, false, err
+			}
+			if removed {
+				name, _ := core.GetName(op.Params[0])
+				replaced[*name] = append(replaced[*name], ops...)
+			}
+			out = append(out, ops...)
+			changed = changed || removed
+			continue
+		case "BI":
+			ops, removed, err := r.redactInlineImage(op, resources, gs.ctm)
+			if err != nil {
+				return nil, false, err
+			}
+			out = append(out, ops...)
+			changed = changed || removed
+			continue
+
+		// Marked content.
+		case "BMC", "BDC":
+			marked = append(marked, op)
+		case "EMC":
+			if len(marked) > 0 {
+				marked = marked[:len(marked)-1]
+			}
+		}
+		out = append(out, op)
+	}
+	// Unterminated path.
+	out = append(out, path.ops()...)
+
+	if err := replaceXObjects(out, resources, replaced); err != nil {
+		return nil, false, err
+	}
+	return &out, changed, nil
+}
+
+// replaceXObjects removes the XObjects of `resources` replaced by the redacted copies drawn by
+// the operations `replaced`, by name of the original XObjects, which are not used anymore by the
+// operations `ops`, so that their content is not kept in the document. An XObject replaced by
+// a single copy is replaced under the same name.
+func replaceXObjects(ops contentstream.ContentStreamOperations, resources *model.PdfPageResources,
+	replaced map[core.PdfObjectName][]*contentstream.ContentStreamOperation) error {
+	if len(replaced) == 0 {
+		return nil
+	}
+	used := map[core.PdfObjectName]struct{}{}
+	for _, op := range ops {
+		if op.Operand != "Do" || len(op.Params) != 1 {
+			continue
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			used[*name] = struct{}{}
+		}
+	}
+	xobjects, ok := core.GetDict(resources.XObject)
+	if !ok {
+		return nil
+	}
+
+	for name, copies := range replaced {
+		if _, ok := used[name]; ok {
+			continue
+		}
+		xobjects.Remove(name)
+		if len(copies) != 1 {
+			continue
+		}
+		newName, ok := core.GetName(copies[0].Params[0])
+		if !ok {
+			continue
+		}
+		stream, _ := resources.GetXObjectByName(*newName)
+		if stream == nil {
+			continue
+		}
+		xobjects.Remove(*newName)
+		if err := resources.SetXObjectByName(name, stream); err != nil {
+			return err
+		}
+		copies[0].Params[0] = core.MakeName(string(name))
+	}
+	return nil
+}
+
+// getFont returns the font named `name` in `resources`. Returns the default font if it is not
+// found or cannot be loaded, so that the text can still be located.
+func (r *contentRedactor) getFont(resources *model.PdfPageResources, name core.PdfObjectName) *model.PdfFont {
+	if font := r.fonts.Get(resources, name); font != nil {
+		return font
+	}
+	common.Log.Debug("ERROR: Font %s not available - using default font", name)
+	return model.DefaultFont()
+}
+
+// setExtGState updates `gs` with the line width and font of the graphics state parameter
+// dictionary set by `op`.
+func (r *contentRedactor) setExtGState(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	gs *graphicsState) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	obj, ok := resources.GetExtGState(*name)
+	if !ok {
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if lw, err := core.GetNumberAsFloat(core.ResolveReference(dict.Get("LW"))); err == nil {
+		gs.lineWidth = lw
+	}
+	if gs.text.SetExtGStateFont(dict, r.fonts) && gs.text.Font == nil {
+		gs.text.Font = model.DefaultFont()
+	}
+}
+
+// showText shows the text operands `args` (strings and positioning adjustments as for TJ) with
+// graphics state `gs` in text object `text`, removing the glyphs inside the redaction regions.
+// Returns the TJ operands of the remaining text, where the removed glyphs are replaced with
+// adjustments so that the following glyphs keep their position, and true if glyphs were removed.
+func (r *contentRedactor) showText(args []core.PdfObject, gs *graphicsState, text *textstate.Object) (
+	[]core.PdfObject, bool) {
+	ts := gs.text
+	if ts.Font == nil {
+		common.Log.Debug("ERROR: No font set - using default font")
+		ts.Font = model.DefaultFont()
+	}
+	fs := ts.FontSize
+	th := ts.Scaling / 100
+	ascent, descent := fontExtent(ts.Font)
+
+	var out []core.PdfObject
+	var kept []byte
+	adjust := 0.0
+	removed := false
+	addAdjust := func(n float64) {
+		if len(kept) > 0 {
+			out = append(out, core.MakeString(string(kept)))
+			kept = nil
+		}
+		adjust += n
+	}
+	addBytes := func(b []byte) {
+		if adjust != 0 {
+			out = append(out, core.MakeFloat(adjust))
+			adjust = 0
+		}
+		kept = append(kept, b...)
+	}
+
+	text.Show(args, &ts, func(g textstate.Glyph) {
+		// Trm maps the glyph space to the device space.
+		trm := gs.ctm.Mult(g.Matrix)
+		cx, cy := trm.Transform(g.Width/2, (ascent+descent)/2)
+		idx := r.regionAt(cx, cy)
+		if idx < 0 {
+			addBytes(g.Data)
+			return
+		}
+		removed = true
+		x0, y0 := trm.Transform(0, 0)
+		x1, y1 := trm.Transform(g.Width, 0)
+		str := string(ts.Font.CharcodesToUnicode([]textencoding.CharCode{g.Code}))
+		// The glyph space unit is a thousandth of the font size.
+		r.texts[idx].add(str, x0, y0, x1, y1, 1000*trm.ScalingFactorY())
+		if fs != 0 && th != 0 {
+			addAdjust(-g.Advance / th / fs * 1000)
+		}
+	}, addAdjust)
+
+	if len(kept) > 0 {
+		out = append(out, core.MakeString(string(kept)))
+	}
+	if adjust != 0 {
+		out = append(out, core.MakeFloat(adjust))
+	}
+	return out, removed
+}
+
+// fontExtent returns the ascent and descent of `font` in glyph space units.
+func fontExtent(font *model.PdfFont) (float64, float64) {
+	if desc := font.FontDescriptor(); desc != nil {
+		ascent, err1 := core.GetNumberAsFloat(core.ResolveReference(desc.Ascent))
+		descent, err2 := core.GetNumberAsFloat(core.ResolveReference(desc.Descent))
+		if err1 == nil && err2 == nil && ascent > descent {
+			return ascent, descent
+		}
+	}
+	return 750, -250
+}
+
+// textCollector collects the text removed from a redaction.
+type textCollector struct {
+	buf  strings.Builder
+	x, y float64
+}
+
+// add adds `text` drawn from (`x0`,`y0`) to (`x1`,`y1`) in device space with height `height`.
+// A space is inserted if the text does not follow the previous text.
+func (tc *textCollector) add(text string, x0, y0, x1, y1, height float64) {
+	if tc.buf.Len() > 0 && math.Hypot(x0-tc.x, y0-tc.y) > 0.2*height {
+		tc.buf.WriteByte(' ')
+	}
+	tc.buf.WriteString(text)
+	tc.x, tc.y = x1, y1
+}
+
+// String returns the collected text with its white spaces collapsed.
+func (tc *textCollector) String() string {
+	return strings.Join(strings.Fields(tc.buf.String()), " ")
+}
+
+// subpath is a subpath of the current path.
+type subpath struct {
+	ops  []*contentstream.ContentStreamOperation
+	bbox model.PdfRectangle // In device space.
+}
+
+// pathBuilder collects the subpaths of the current path.
+type pathBuilder struct {
+	subpaths []*subpath
+	closed   bool // The last subpath is a rectangle: the next segment starts a new subpath.
+}
+
+// add adds the path construction operation `op` drawn with CTM `ctm`.
+func (p *pathBuilder) add(op *contentstream.ContentStreamOperation, ctm transform.Matrix) {
+	f, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		f = nil
+	}
+	var points []float64
+	switch op.Operand {
+	case "re":
+		if len(f) == 4 {
+			x, y, w, h := f[0], f[1], f[2], f[3]
+			points = []float64{x, y, x + w, y, x + w, y + h, x, y + h}
+		}
+	default:
+		points = f[:len(f)/2*2]
+	}
+
+	if op.Operand == "m" || op.Operand == "re" || len(p.subpaths) == 0 || p.closed {
+		p.subpaths = append(p.subpaths, &subpath{
+			bbox: model.PdfRectangle{
+				Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1),
+			},
+		})
+	}
+	p.closed = op.Operand == "re"
+
+	sp := p.subpaths[len(p.subpaths)-1]
+	sp.ops = append(sp.ops, op)
+	for i := 0; i+1 < len(points); i += 2 {
+		x, y := ctm.Transform(points[i], points[i+1])
+		sp.bbox.Llx = math.Min(sp.bbox.Llx, x)
+		sp.bbox.Lly = math.Min(sp.bbox.Lly, y)
+		sp.bbox.Urx = math.Max(sp.bbox.Urx, x)
+		sp.bbox.Ury = math.Max(sp.bbox.Ury, y)
+	}
+}
+
+// ops returns the path construction operations of the path.
+func (p *pathBuilder) ops() []*contentstream.ContentStreamOperation {
+	var ops []*contentstream.ContentStreamOperation
+	for _, sp := range p.subpaths {
+		ops = append(ops, sp.ops...)
+	}
+	return ops
+}
+
+// paintPath returns the operations painting `path` with painting operation `op` and clipping
+// operation `clip` (nil if not clipping), without the subpaths inside the redaction regions. The
+// regions overlapping the remaining subpaths are clipped out. Returns true if the painting was
+// changed.
+func (r *contentRedactor) paintPath(path *pathBuilder, clip, op *contentstream.ContentStreamOperation,
+	gs *graphicsState) ([]*contentstream.ContentStreamOperation, bool) {
+	original := path.ops()
+	if clip != nil {
+		original = append(original, clip)
+	}
+	original = append(original, op)
+	if op.Operand == "n" {
+		return original, false
+	}
+
+	// Half the line width for stroked paths.
+	extent := 0.0
+	switch op.Operand {
+	case "S", "s", "B", "B*", "b", "b*":
+		extent = gs.lineWidth / 2 * math.Max(gs.ctm.ScalingFactorX(), gs.ctm.ScalingFactorY())
+	}
+
+	var kept []*subpath
+	var bbox *model.PdfRectangle
+	removed := false
+	for _, sp := range path.subpaths {
+		b := sp.bbox
+		if b.Llx > b.Urx {
+			// No points.
+			kept = append(kept, sp)
+			continue
+		}
+		b = model.PdfRectangle{Llx: b.Llx - extent, Lly: b.Lly - extent, Urx: b.Urx + extent, Ury: b.Ury + extent}
+		if r.isRedacted(b) {
+			removed = true
+			continue
+		}
+		kept = append(kept, sp)
+		if bbox == nil {
+			bbox = &b
+		} else {
+			*bbox = unionRects(*bbox, b)
+		}
+	}
+	var overlapping []region
+	if bbox != nil {
+		overlapping = r.overlappingRegions(*bbox)
+	}
+	if !removed && len(overlapping) == 0 {
+		return original, false
+	}
+
+	var ops []*contentstream.ContentStreamOperation
+	if len(kept) > 0 {
+		exclusion := r.exclusionClip(overlapping, gs.ctm)
+		if len(overlapping) > 0 && exclusion == nil {
+			// Not invertible CTM: nothing is drawn.
+			kept = nil
+		}
+		if len(kept) > 0 {
+			ops = append(ops, &contentstream.ContentStreamOperation{Operand: "q"})
+			ops = append(ops, exclusion...)
+			for _, sp := range kept {
+				ops = append(ops, sp.ops...)
+			}
+			ops = append(ops, op, &contentstream.ContentStreamOperation{Operand: "Q"})
+		}
+	}
+	if clip != nil {
+		// Keep the clipping path unchanged, without painting it.
+		ops = append(ops, path.ops()...)
+		ops = append(ops, clip, &contentstream.ContentStreamOperation{Operand: "n"})
+	}
+	return ops, true
+}
+
+// exclusionClip returns the operations clipping out `regions` from the clipping path, in the
+// user space defined by `ctm`. Returns nil if `ctm` is not invertible.
+func (r *contentRedactor) exclusionClip(regions []region, ctm transform.Matrix) []*contentstream.ContentStreamOperation {
+	inv, ok := ctm.Inverse()
+	if !ok {
+		return nil
+	}
+	polygon := func(rect model.PdfRectangle) []*contentstream.ContentStreamOperation {
+		corners := []float64{rect.Llx, rect.Lly, rect.Urx, rect.Lly, rect.Urx, rect.Ury, rect.Llx, rect.Ury}
+		var ops []*contentstream.ContentStreamOperation
+		for i := 0; i < len(corners); i += 2 {
+			x, y := inv.Transform(corners[i], corners[i+1])
+			operand := "l"
+			if i == 0 {
+				operand = "m"
+			}
+			ops = append(ops, &contentstream.ContentStreamOperation{
+				Operand: operand,
+				Params:  []core.PdfObject{core.MakeFloat(x), core.MakeFloat(y)},
+			})
+		}
+		return append(ops, &contentstream.ContentStreamOperation{Operand: "h"})
+	}
+
+	outer := model.PdfRectangle{
+		Llx: -exclusionExtent, Lly: -exclusionExtent, Urx: exclusionExtent, Ury: exclusionExtent,
+	}
+	var ops []*contentstream.ContentStreamOperation
+	for _, reg := range regions {
+		ops = append(ops, polygon(outer)...)
+		ops = append(ops, polygon(reg.rect)...)
+		ops = append(ops,
+			&contentstream.ContentStreamOperation{Operand: "W*"},
+			&contentstream.ContentStreamOperation{Operand: "n"})
+	}
+	return ops
+}
+
+// redactXObject returns the operations replacing the XObject painting operation `op` drawn
+// with resources `resources` and CTM `ctm`. The XObjects inside a redaction region are removed
+// and the ones overlapping a region are replaced with redacted copies, added to `resources` under
+// a new name, so that other uses of the XObjects are not affected. Returns true if the operation
+// was changed.
+func (r *contentRedactor) redactXObject(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	ctm transform.Matrix) ([]*contentstream.ContentStreamOperation, bool, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	if len(op.Params) != 1 || resources == nil {
+		return keep, false, nil
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return keep, false, nil
+	}
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil {
+		return keep, false, nil
+	}
+
+	var redacted *core.PdfObjectStream
+	var drop bool
+	var err error
+	switch xtype {
+	case model.XObjectTypeImage:
+		redacted, drop, err = r.redactImage(stream, ctm)
+	case model.XObjectTypeForm:
+		redacted, drop, err = r.redactForm(stream, resources, ctm)
+	default:
+		return keep, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if drop {
+		return nil, true, nil
+	}
+	if redacted == nil {
+		return keep, false, nil
+	}
+
+	newName := resources.GenerateXObjectName()
+	if err := resources.SetXObjectByName(newName, redacted); err != nil {
+		return nil, false, err
+	}
+	return []*contentstream.ContentStreamOperation{{
+		Operand: "Do",
+		Params:  []core.PdfObject{core.MakeName(string(newName))},
+	}}, true, nil
+}
+
+// redactForm returns a redacted copy of form XObject `stream` drawn with CTM `ctm`, nil if it is
+// not affected by the redactions, or true if it must be removed.
+func (r *contentRedactor) redactForm(stream *core.PdfObjectStream, resources *model.PdfPageResources,
+	ctm transform.Matrix) (*core.PdfObjectStream, bool, error) {
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid form XObject: %v - removing", err)
+		return nil, true, nil
+	}
+	formCTM := ctm
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		if f, err := arr.ToFloat64Array(); err == nil && len(f) == 6 {
+			formCTM = ctm.Mult(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	}
+	if bbox, ok := model.GetPdfRectangle(xform.BBox); ok {
+		devBBox := bbox.Transform(formCTM)
+		if r.isRedacted(devBBox) {
+			return nil, true, nil
+		}
+		if len(r.overlappingRegions(devBBox)) == 0 {
+			return nil, false, nil
+		}
+	}
+
+	content, err := xform.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form XObject: %v - removing", err)
+		return nil, true, nil
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse form XObject: %v - removing", err)
+		return nil, true, nil
+	}
+	// The replacement XObjects are added to a copy of the resources, which can be shared.
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+	formResources, err = copyResources(formResources)
+	if err != nil {
+		return nil, false, err
+	}
+	redacted, changed, err := r.redact(ops, formResources, formCTM)
+	if err != nil || !changed {
+		return nil, false, err
+	}
+
+	copied, err := copyStream(stream, redacted.Bytes())
+	if err != nil {
+		return nil, false, err
+	}
+	copied.Set("Resources", formResources.ToPdfObject())
+	return copied, false, nil
+}
+
+// redactImage returns a copy of image XObject `stream` drawn with CTM `ctm` with the pixels
+// inside the redaction regions erased, in the image and in its soft mask, nil if it is not
+// affected by the redactions, or true if it must be removed. The images which cannot be decoded
+// are removed.
+func (r *contentRedactor) redactImage(stream *core.PdfObjectStream, ctm transform.Matrix) (
+	*core.PdfObjectStream, bool, error) {
+	devBBox := imageSpace.Transform(ctm)
+	if len(r.overlappingRegions(devBBox)) == 0 {
+		return nil, false, nil
+	}
+	if r.isRedacted(devBBox) {
+		return nil, true, nil
+	}
+
+	img, erased, err := r.eraseImage(stream, ctm)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to erase image: %v - removing", err)
+		return nil, true, nil
+	}
+	// The soft mask, drawn over the same area, can show the shape of the erased content.
+	smask, hasMask := core.GetStream(stream.Get("SMask"))
+	var mask *model.Image
+	maskErased := false
+	if hasMask {
+		if mask, maskErased, err = r.eraseImage(smask, ctm); err != nil {
+			common.Log.Debug("ERROR: Unable to erase soft mask: %v - removing", err)
+			return nil, true, nil
+		}
+	}
+	if !erased && !maskErased {
+		return nil, false, nil
+	}
+
+	copied, err := copyStream(stream, img.Data)
+	if err != nil {
+		return nil, false, err
+	}
+	if maskErased {
+		copiedMask, err := copyStream(smask, mask.Data)
+		if err != nil {
+			return nil, false, err
+		}
+		copied.Set("SMask", copiedMask)
+	}
+	return copied, false, nil
+}
+
+// eraseImage returns the decoded samples of image XObject `stream` drawn with CTM `ctm` with the
+// pixels inside the redaction regions erased. Returns true if pixels were erased.
+func (r *contentRedactor) eraseImage(stream *core.PdfObjectStream, ctm transform.Matrix) (
+	*model.Image, bool, error) {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return nil, false, err
+	}
+	isMask := false
+	if b, ok := core.GetBool(ximg.ImageMask); ok && bool(*b) {
+		isMask = true
+		if ximg.BitsPerComponent == nil {
+			bpc := int64(1)
+			ximg.BitsPerComponent = &bpc
+		}
+	}
+	img, err := ximg.ToImage()
+	if err != nil {
+		return nil, false, err
+	}
+
+	values := erasedSamples(isMask, ximg.Decode, img.ColorComponents, int(img.BitsPerComponent))
+	erased, err := r.eraseSamples(img, ctm, values)
+	if err != nil {
+		return nil, false, err
+	}
+	return img, erased, nil
+}
+
+// redactInlineImage returns the operations replacing the inline image operation `op` drawn
+// with resources `resources` and CTM `ctm`. The images overlapping a redaction region are
+// replaced with image XObjects with the pixels inside the region erased. Returns true if the
+// operation was changed.
+func (r *contentRedactor) redactInlineImage(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	ctm transform.Matrix) ([]*contentstream.ContentStreamOperation, bool, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	if len(op.Params) != 1 {
+		return keep, false, nil
+	}
+	inline, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
+	if !ok {
+		return keep, false, nil
+	}
+	devBBox := imageSpace.Transform(ctm)
+	if len(r.overlappingRegions(devBBox)) == 0 {
+		return keep, false, nil
+	}
+	if r.isRedacted(devBBox) || resources == nil {
+		return nil, true, nil
+	}
+
+	isMask, err := inline.IsMask()
+	if err != nil {
+		return nil, true, nil
+	}
+	img, err := inline.ToImage(resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode inline image: %v - removing", err)
+		return nil, true, nil
+	}
+	var cs model.PdfColorspace
+	if !isMask {
+		if cs, err = inline.GetColorSpace(resources); err != nil {
+			common.Log.Debug("ERROR: Unsupported inline image colorspace: %v - removing", err)
+			return nil, true, nil
+		}
+	}
+	values := erasedSamples(isMask, inline.Decode, img.ColorComponents, int(img.BitsPerComponent))
+	erased, err := r.eraseSamples(img, ctm, values)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to erase image samples: %v - removing", err)
+		return nil, true, nil
+	}
+	if !erased {
+		return keep, false, nil
+	}
+
+	stream, err := core.MakeStream(img.Data, core.NewFlateEncoder())
+	if err != nil {
+		return nil, false, err
+	}
+	stream.Set("Type", core.MakeName("XObject"))
+	stream.Set("Subtype", core.MakeName("Image"))
+	stream.Set("Width", core.MakeInteger(img.Width))
+	stream.Set("Height", core.MakeInteger(img.Height))
+	stream.Set("BitsPerComponent", core.MakeInteger(img.BitsPerComponent))
+	if isMask {
+		stream.Set("ImageMask", core.MakeBool(true))
+	} else {
+		stream.Set("ColorSpace", cs.ToPdfObject())
+	}
+	if inline.Decode != nil {
+		stream.Set("Decode", inline.Decode)
+	}
+	if inline.Interpolate != nil {
+		stream.Set("Interpolate", inline.Interpolate)
+	}
+
+	name := resources.GenerateXObjectName()
+	if err := resources.SetXObjectByName(name, stream); err != nil {
+		return nil, false, err
+	}
+	return []*contentstream.ContentStreamOperation{{
+		Operand: "Do",
+		Params:  []core.PdfObject{core.MakeName(string(name))},
+	}}, true, nil
+}
+
+// erasedSamples returns the sample values of the erased pixels of an image with `comps` color
+// components of `bpc` bits and decode array `decode`: not painted for stencil masks (`isMask`),
+// otherwise the samples decoded to the minimum of each color component, so that the erased color
+// does not depend on the decode array (section 8.9.5.2 p. 115 PDF32000_2008).
+func erasedSamples(isMask bool, decode core.PdfObject, comps, bpc int) []uint32 {
+	var d []float64
+	if arr, ok := core.GetArray(decode); ok {
+		d, _ = arr.ToFloat64Array()
+	}
+	inverted := func(c int) bool {
+		return len(d) >= 2*(c+1) && d[2*c] > d[2*c+1]
+	}
+	maxSample := uint32(1)<<uint(bpc) - 1
+
+	if isMask {
+		// Inverted mask: samples of 1 are painted.
+		if inverted(0) {
+			return []uint32{0}
+		}
+		return []uint32{1}
+	}
+	values := make([]uint32, comps)
+	for c := range values {
+		if inverted(c) {
+			values[c] = maxSample
+		}
+	}
+	return values
+}
+
+// eraseSamples sets the samples of the pixels of `img`, drawn with CTM `ctm`, whose center is
+// inside a redaction region to `values`, one per color component. Returns true if pixels were
+// erased.
+func (r *contentRedactor) eraseSamples(img *model.Image, ctm transform.Matrix, values []uint32) (bool, error) {
+	inv, ok := ctm.Inverse()
+	if !ok {
+		return false, nil
+	}
+	w, h := int(img.Width), int(img.Height)
+	comps := img.ColorComponents
+	bpc := int(img.BitsPerComponent)
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return false, errors.New("unsupported bits per component")
+	}
+	stride := (w*comps*bpc + 7) / 8
+	if w <= 0 || h <= 0 || len(img.Data) < stride*h {
+		return false, errors.New("invalid image data size")
+	}
+
+	erased := false
+	for _, reg := range r.regions {
+		// Range of pixels covered by the region. The image space is the unit square, with the
+		// first row of pixels at the top.
+		box := reg.rect.Transform(inv)
+		x0 := clampInt(int(math.Floor(box.Llx*float64(w))), 0, w)
+		x1 := clampInt(int(math.Ceil(box.Urx*float64(w))), 0, w)
+		y0 := clampInt(int(math.Floor((1-box.Ury)*float64(h))), 0, h)
+		y1 := clampInt(int(math.Ceil((1-box.Lly)*float64(h))), 0, h)
+
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				dx, dy := ctm.Transform((float64(x)+0.5)/float64(w), 1-(float64(y)+0.5)/float64(h))
+				if !containsPoint(reg.rect, dx, dy) {
+					continue
+				}
+				for c := 0; c < comps; c++ {
+					setSample(img.Data, y*stride*8+(x*comps+c)*bpc, bpc, values[c])
+				}
+				erased = true
+			}
+		}
+	}
+	return erased, nil
+}
+
+// setSample sets the sample of `bpc` bits at bit offset `offset` of `data` to `value`.
+func setSample(data []byte, offset, bpc int, value uint32) {
+	i := offset / 8
+	switch bpc {
+	case 8:
+		data[i] = byte(value)
+	case 16:
+		data[i] = byte(value >> 8)
+		data[i+1] = byte(value)
+	default:
+		shift := uint(8 - bpc - offset%8)
+		mask := byte(1<<uint(bpc)-1) << shift
+		data[i] = data[i]&^mask | byte(value)<<shift&mask
+	}
+}
+
+// copyResources returns a copy of `resources` whose XObjects can be changed without changing
+// `resources`.
+func copyResources(resources *model.PdfPageResources) (*model.PdfPageResources, error) {
+	dict := core.MakeDict()
+	if src, ok := core.GetDict(resources.ToPdfObject()); ok {
+		dict.Merge(src)
+	}
+	if xobjects, ok := core.GetDict(dict.Get("XObject")); ok {
+		dict.Set("XObject", core.MakeDict().Merge(xobjects))
+	}
+	return model.NewPdfPageResourcesFromDict(dict)
+}
+
+// copyStream returns a copy of `stream` with content `data`, flate encoded.
+func copyStream(stream *core.PdfObjectStream, data []byte) (*core.PdfObjectStream, error) {
+	copied, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range stream.Keys() {
+		switch key {
+		case "Filter", "DecodeParms", "Length", "DL":
+			continue
+		}
+		copied.Set(key, stream.Get(key))
+	}
+	return copied, nil
+}
+
+// regionAt returns the index of the redaction of the region containing the point (`x`,`y`), or
+// -1 if the point is not redacted.
+func (r *contentRedactor) regionAt(x, y float64) int {
+	for _, reg := range r.regions {
+		if containsPoint(reg.rect, x, y) {
+			return reg.index
+		}
+	}
+	return -1
+}
+
+// isRedacted returns true if `rect` is inside a redaction region.
+func (r *contentRedactor) isRedacted(rect model.PdfRectangle) bool {
+	for _, reg := range r.regions {
+		if rect.Llx >= reg.rect.Llx && rect.Urx <= reg.rect.Urx &&
+			rect.Lly >= reg.rect.Lly && rect.Ury <= reg.rect.Ury {
+			return true
+		}
+	}
+	return false
+}
+
+// overlappingRegions returns the redaction regions overlapping `rect`.
+func (r *contentRedactor) overlappingRegions(rect model.PdfRectangle) []region {
+	var regions []region
+	for _, reg := range r.regions {
+		if rect.Overlaps(reg.rect) {
+			regions = append(regions, reg)
+		}
+	}
+	return regions
+}
+
+// containsPoint returns true if the point (`x`,`y`) is inside `rect`.
+func containsPoint(rect model.PdfRectangle, x, y float64) bool {
+	return x >= rect.Llx && x <= rect.Urx && y >= rect.Lly && y <= rect.Ury
+}
+
+// unionRects returns the bounding box of `a` and `b`.
+func unionRects(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx),
+		Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx),
+		Ury: math.Max(a.Ury, b.Ury),
+	}
+}
+
+// clampInt returns `v` clamped to [`min`, `max`].
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,18 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package redactor applies redactions to pages: the content in the redacted regions is removed
+// from the page content streams and the form XObjects they use, rather than just being covered.
+// Text glyphs are removed, vector paths inside the regions are dropped (the parts of the paths
+// overlapping the regions are clipped out) and the image pixels inside the regions are erased.
+// The regions are then filled and overlay text or appearances are drawn over them.
+//
+// The redactions are specified with Redact annotations (ApplyRedactions) or directly with
+// rectangles (RedactPage). The removed text is returned so that it can also be removed from the
+// rest of the document with ScrubText, e.g. from the document information or outlines.
+//
+// The redacted pages must be written to a new document with a model.PdfWriter: an incremental
+// update (model.PdfAppender) keeps the original content in the file.
+package redactor
@@ -0,0 +1,438 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TextAlignment specifies the alignment of the overlay text in the redacted regions.
+type TextAlignment int
+
+// Overlay text alignments, matching the values of the Q entry of Redact annotations.
+const (
+	TextAlignmentLeft TextAlignment = iota
+	TextAlignmentCenter
+	TextAlignmentRight
+)
+
+// Redaction specifies regions of a page to redact and how to mark them once the content is
+// removed.
+type Redaction struct {
+	// Regions to redact in the default user space of the page.
+	Regions []model.PdfRectangle
+
+	// FillColor is the color the regions are filled with: 1 (gray), 3 (RGB) or 4 (CMYK)
+	// components. The regions are not filled if nil.
+	FillColor []float64
+
+	// OverlayText is drawn in each region, repeated to fill the region if RepeatText is set.
+	OverlayText string
+	RepeatText  bool
+
+	// TextColor is the color of the overlay text (black if nil), FontSize its size (fitted in
+	// the regions if 0) and TextAlignment its alignment.
+	TextColor     []float64
+	FontSize      float64
+	TextAlignment TextAlignment
+
+	// Overlay is a form XObject drawn over the bounding box of the regions instead of filling
+	// them and drawing the overlay text.
+	Overlay *core.PdfObjectStream
+}
+
+// RedactPage applies `redactions` to `page`: the text, paths and image pixels inside the
+// regions are removed from the content streams of the page and the form XObjects they use, and
+// the regions are marked as specified by the redactions. The removed text is also removed from
+// the annotations and the metadata of the page.
+// Returns the text removed by each redaction.
+func RedactPage(page *model.PdfPage, redactions []*Redaction) ([]string, error) {
+	if page.Resources == nil {
+		page.Resources = model.NewPdfPageResources()
+	}
+	// The redacted XObjects replace the original ones in a copy of the resources, which can be
+	// shared with other pages.
+	resources, err := copyResources(page.Resources)
+	if err != nil {
+		return nil, err
+	}
+	page.Resources = resources
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	r := newContentRedactor(redactions)
+	redacted, _, err := r.redact(ops, page.Resources, transform.IdentityMatrix())
+	if err != nil {
+		return nil, err
+	}
+	redacted.WrapIfNeeded()
+
+	cc := contentstream.NewContentCreator()
+	for _, redaction := range redactions {
+		if err := drawOverlay(cc, page.Resources, redaction); err != nil {
+			return nil, err
+		}
+	}
+	*redacted = append(*redacted, *cc.Operations()...)
+	err = page.SetContentStreams([]string{string(redacted.Bytes())}, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(redactions))
+	for i, tc := range r.texts {
+		texts[i] = tc.String()
+	}
+	if err := scrubPage(page, texts); err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// ApplyRedactions applies the Redact annotations of `page` (see RedactPage) and removes them,
+// with their pop-up annotations and replies. The regions to redact are the quadrilaterals of
+// the annotations (QuadPoints), or their rectangles (Rect), which are marked with the interior
+// color (IC), and the overlay text (OverlayText, Repeat, DA, Q) or appearance (RO).
+// Returns the text removed by each annotation.
+func ApplyRedactions(page *model.PdfPage) ([]string, error) {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	var redactions []*Redaction
+	removed := map[core.PdfObject]struct{}{}
+	for _, annot := range annotations {
+		redact, ok := annot.GetContext().(*model.PdfAnnotationRedact)
+		if !ok {
+			continue
+		}
+		removed[annot.GetContainingPdfObject()] = struct{}{}
+		if redaction := newRedaction(redact); redaction != nil {
+			redactions = append(redactions, redaction)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	// Remove the pop-up annotations and the replies of the removed annotations.
+	kept := annotations
+	for {
+		var next []*model.PdfAnnotation
+		for _, annot := range kept {
+			if _, ok := removed[annot.GetContainingPdfObject()]; ok {
+				continue
+			}
+			if isAttachedTo(annot, removed) {
+				removed[annot.GetContainingPdfObject()] = struct{}{}
+				continue
+			}
+			next = append(next, annot)
+		}
+		if len(next) == len(kept) {
+			break
+		}
+		kept = next
+	}
+	if kept == nil {
+		kept = []*model.PdfAnnotation{}
+	}
+	page.SetAnnotations(kept)
+
+	return RedactPage(page, redactions)
+}
+
+// isAttachedTo returns true if `annot` is a pop-up annotation or a reply of an annotation of
+// `annotations`.
+func isAttachedTo(annot *model.PdfAnnotation, annotations map[core.PdfObject]struct{}) bool {
+	// The dictionary is rebuilt as the model may have been modified since it was loaded.
+	dict, ok := core.GetDict(annotationToPdfObject(annot))
+	if !ok {
+		return false
+	}
+	for _, key := range []core.PdfObjectName{"Parent", "IRT"} {
+		if obj, ok := dict.Get(key).(*core.PdfObjectReference); ok {
+			if _, ok := annotations[obj.Resolve()]; ok {
+				return true
+			}
+			continue
+		}
+		if obj := dict.Get(key); obj != nil {
+			if _, ok := annotations[obj]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// annotationToPdfObject returns the dictionary of `annot` rebuilt from its model, including the
+// entries specific to its subtype.
+func annotationToPdfObject(annot *model.PdfAnnotation) core.PdfObject {
+	if ctx := annot.GetContext(); ctx != nil {
+		return ctx.ToPdfObject()
+	}
+	return annot.ToPdfObject()
+}
+
+// newRedaction returns the Redaction specified by Redact annotation `annot`, or nil if the
+// annotation does not define any region.
+func newRedaction(annot *model.PdfAnnotationRedact) *Redaction {
+	redaction := &Redaction{}
+	if arr, ok := core.GetArray(annot.QuadPoints); ok {
+		points, err := arr.ToFloat64Array()
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid QuadPoints: %v", err)
+		}
+		for i := 0; i+8 <= len(points); i += 8 {
+			quad := model.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+			for j := i; j < i+8; j += 2 {
+				quad = unionRects(quad, model.PdfRectangle{
+					Llx: points[j], Lly: points[j+1], Urx: points[j], Ury: points[j+1],
+				})
+			}
+			redaction.Regions = append(redaction.Regions, quad)
+		}
+	}
+	if len(redaction.Regions) == 0 {
+		rect, ok := model.GetPdfRectangle(annot.Rect)
+		if !ok {
+			common.Log.Debug("ERROR: Redact annotation without region - ignoring")
+			return nil
+		}
+		redaction.Regions = []model.PdfRectangle{rect}
+	}
+
+	if arr, ok := core.GetArray(annot.IC); ok {
+		if color, err := arr.ToFloat64Array(); err == nil && len(color) > 0 {
+			redaction.FillColor = color
+		}
+	}
+	if text, ok := core.GetString(annot.OverlayText); ok {
+		redaction.OverlayText = text.Decoded()
+	}
+	if repeat, ok := core.GetBool(annot.Repeat); ok {
+		redaction.RepeatText = bool(*repeat)
+	}
+	if q, ok := core.GetIntVal(annot.Q); ok {
+		redaction.TextAlignment = TextAlignment(q)
+	}
+	if da, ok := core.GetString(annot.DA); ok {
+		redaction.FontSize, redaction.TextColor = parseDA(da.Str())
+	}
+	if stream, ok := core.GetStream(annot.RO); ok {
+		redaction.Overlay = stream
+	}
+	return redaction
+}
+
+// parseDA returns the font size and the fill color set by default appearance string `da`.
+func parseDA(da string) (float64, []float64) {
+	ops, err := contentstream.NewContentStreamParser(da).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid DA: %v", err)
+		return 0, nil
+	}
+	var size float64
+	var color []float64
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tf":
+			if len(op.Params) == 2 {
+				size, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "g", "rg", "k":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil {
+				color = f
+			}
+		}
+	}
+	return size, color
+}
+
+// drawOverlay adds the operations marking the regions of `redaction` to `cc`, using resources
+// `resources`.
+func drawOverlay(cc *contentstream.ContentCreator, resources *model.PdfPageResources, redaction *Redaction) error {
+	if len(redaction.Regions) == 0 {
+		return nil
+	}
+
+	if redaction.Overlay != nil {
+		bbox := redaction.Regions[0].Normalized()
+		for _, rect := range redaction.Regions[1:] {
+			bbox = unionRects(bbox, rect.Normalized())
+		}
+		formBBox, ok := model.GetPdfRectangle(redaction.Overlay.Get("BBox"))
+		if !ok {
+			common.Log.Debug("ERROR: Overlay without BBox - ignoring")
+			return nil
+		}
+		matrix := transform.IdentityMatrix()
+		if arr, ok := core.GetArray(redaction.Overlay.Get("Matrix")); ok {
+			if f, err := arr.ToFloat64Array(); err == nil && len(f) == 6 {
+				matrix = transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5])
+			}
+		}
+		formBBox = formBBox.Transform(matrix)
+		if formBBox.Width() == 0 || formBBox.Height() == 0 {
+			return nil
+		}
+
+		name := resources.GenerateXObjectName()
+		if err := resources.SetXObjectByName(name, redaction.Overlay); err != nil {
+			return err
+		}
+		sx := bbox.Width() / formBBox.Width()
+		sy := bbox.Height() / formBBox.Height()
+		cc.Add_q().
+			Add_cm(sx, 0, 0, sy, bbox.Llx-sx*formBBox.Llx, bbox.Lly-sy*formBBox.Lly).
+			Add_Do(name).
+			Add_Q()
+		return nil
+	}
+
+	if redaction.FillColor != nil {
+		cc.Add_q()
+		addColor(cc, redaction.FillColor)
+		for _, rect := range redaction.Regions {
+			rect = rect.Normalized()
+			cc.Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height())
+		}
+		cc.Add_f().Add_Q()
+	}
+
+	if redaction.OverlayText != "" {
+		font, err := model.NewStandard14Font(model.HelveticaName)
+		if err != nil {
+			return err
+		}
+		fontName := generateFontName(resources)
+		if err := resources.SetFontByName(fontName, font.ToPdfObject()); err != nil {
+			return err
+		}
+		for _, rect := range redaction.Regions {
+			drawOverlayText(cc, font, fontName, rect.Normalized(), redaction)
+		}
+	}
+	return nil
+}
+
+// drawOverlayText adds the operations drawing the overlay text of `redaction` in `rect` with
+// `font` named `fontName` to `cc`.
+func drawOverlayText(cc *contentstream.ContentCreator, font *model.PdfFont, fontName core.PdfObjectName,
+	rect model.PdfRectangle, redaction *Redaction) {
+	// Width of text at font size 1.
+	textWidth := func(text string) float64 {
+		w := 0.0
+		for _, r := range text {
+			if m, ok := font.GetRuneMetrics(r); ok {
+				w += m.Wx / 1000
+			}
+		}
+		return w
+	}
+
+	text := redaction.OverlayText
+	width := textWidth(text)
+	fontSize := redaction.FontSize
+	if fontSize <= 0 {
+		fontSize = math.Min(12, 0.8*rect.Height())
+		if width > 0 {
+			fontSize = math.Min(fontSize, rect.Width()/width)
+		}
+	}
+	if fontSize <= 0 {
+		return
+	}
+	lineHeight := 1.2 * fontSize
+
+	var lines []string
+	if redaction.RepeatText {
+		spaceWidth := textWidth(" ")
+		n := 1
+		if width > 0 {
+			n = int((rect.Width()/fontSize + spaceWidth) / (width + spaceWidth))
+		}
+		if n < 1 {
+			n = 1
+		}
+		line := strings.TrimSpace(strings.Repeat(text+" ", n))
+		numLines := int(rect.Height() / lineHeight)
+		if numLines < 1 {
+			numLines = 1
+		}
+		for i := 0; i < numLines; i++ {
+			lines = append(lines, line)
+		}
+	} else {
+		lines = []string{text}
+	}
+
+	// Center the lines vertically, using an approximate cap height of 0.7.
+	top := rect.Lly + (rect.Height()+float64(len(lines)-1)*lineHeight+0.7*fontSize)/2
+
+	cc.Add_q().
+		Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).
+		Add_W().
+		Add_n().
+		Add_BT()
+	addColor(cc, redaction.TextColor)
+	cc.Add_Tf(fontName, fontSize)
+	for i, line := range lines {
+		w := textWidth(line) * fontSize
+		x := rect.Llx
+		switch redaction.TextAlignment {
+		case TextAlignmentCenter:
+			x += (rect.Width() - w) / 2
+		case TextAlignmentRight:
+			x = rect.Urx - w
+		}
+		y := top - float64(i)*lineHeight - 0.7*fontSize
+		cc.Add_Tm(1, 0, 0, 1, x, y).
+			Add_Tj(*core.MakeString(string(font.Encoder().Encode(line))))
+	}
+	cc.Add_ET().Add_Q()
+}
+
+// addColor adds the operation setting the fill color to `color` to `cc`. The color is black if
+// `color` is nil.
+func addColor(cc *contentstream.ContentCreator, color []float64) {
+	switch len(color) {
+	case 1:
+		cc.Add_g(color[0])
+	case 3:
+		cc.Add_rg(color[0], color[1], color[2])
+	case 4:
+		cc.Add_k(color[0], color[1], color[2], color[3])
+	default:
+		cc.Add_g(0)
+	}
+}
+
+// generateFontName returns an unused font name of `resources`.
+func generateFontName(resources *model.PdfPageResources) core.PdfObjectName {
+	for i := 1; ; i++ {
+		name := core.PdfObjectName(fmt.Sprintf("Redact%d", i))
+		if !resources.HasFontByName(name) {
+			return name
+		}
+	}
+}
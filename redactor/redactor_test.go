@@ -0,0 +1,318 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makeRedactTestPage returns a page showing "Public Secret" at (10, 100), a filled square at
+// (120, 20), a bar at (10, 10) and a 10x10 gray image with an opaque soft mask over
+// (100, 100)-(200, 200), with a Redact annotation over "Secret", the square and the lower left
+// quarter of the image, a pop-up of the Redact annotation and a text annotation mentioning the
+// secret.
+func makeRedactTestPage(t *testing.T) *model.PdfPage {
+	content := "BT /F1 12 Tf 10 100 Td (Public Secret) Tj ET\n" +
+		"0 0 1 rg 120 20 30 30 re f\n" +
+		"10 10 100 10 re f\n" +
+		"q 100 0 0 100 100 100 cm /Im1 Do Q\n"
+	page := pdftest.NewPage(t, 200, 200, content)
+
+	data := bytes.Repeat([]byte{0x80}, 100)
+	img := &model.Image{Width: 10, Height: 10, BitsPerComponent: 8, ColorComponents: 1, Data: data}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	mask := &model.Image{Width: 10, Height: 10, BitsPerComponent: 8, ColorComponents: 1,
+		Data: bytes.Repeat([]byte{0xFF}, 100)}
+	smask, err := model.NewXObjectImageFromImage(mask, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	ximg.SMask = smask.ToPdfObject()
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im1", ximg))
+
+	redact := model.NewPdfAnnotationRedact()
+	redact.Rect = core.MakeArrayFromFloats([]float64{45, 15, 155, 155})
+	redact.QuadPoints = core.MakeArrayFromFloats([]float64{
+		45, 115, 85, 115, 45, 95, 85, 95,
+		115, 55, 155, 55, 115, 15, 155, 15,
+		100, 150, 150, 150, 100, 100, 150, 100,
+	})
+	redact.IC = core.MakeArrayFromFloats([]float64{0})
+	redact.OverlayText = core.MakeString("REDACTED")
+	page.AddAnnotation(redact.PdfAnnotation)
+
+	popup := model.NewPdfAnnotationPopup()
+	popup.Rect = core.MakeArrayFromFloats([]float64{150, 150, 200, 200})
+	popup.Parent = redact.ToPdfObject()
+	page.AddAnnotation(popup.PdfAnnotation)
+
+	note := model.NewPdfAnnotationText()
+	note.Rect = core.MakeArrayFromFloats([]float64{0, 180, 20, 200})
+	note.Contents = core.MakeString("Mentions Secret here")
+	page.AddAnnotation(note.PdfAnnotation)
+	return page
+}
+
+func TestApplyRedactions(t *testing.T) {
+	page := makeRedactTestPage(t)
+	original, _ := page.Resources.GetXObjectByName("Im1")
+	originalMask, ok := core.GetStream(original.Get("SMask"))
+	require.True(t, ok)
+	texts, err := ApplyRedactions(page)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Secret"}, texts)
+
+	// The original image and its soft mask are replaced.
+	doc := pdftest.Write(t, page)
+	require.NotContains(t, string(doc), "Secret")
+	require.False(t, bytes.Contains(doc, original.Stream))
+	require.False(t, bytes.Contains(doc, originalMask.Stream))
+	page, err = pdftest.Read(t, doc).GetPage(1)
+	require.NoError(t, err)
+
+	// Only the text annotation remains, without the redacted text.
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	note, ok := annotations[0].GetContext().(*model.PdfAnnotationText)
+	require.True(t, ok)
+	contents, ok := core.GetString(note.Contents)
+	require.True(t, ok)
+	require.Equal(t, "Mentions  here", contents.Decoded())
+
+	data, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(data).Parse()
+	require.NoError(t, err)
+
+	var shown []string
+	var rects [][]float64
+	var images []core.PdfObjectName
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tj", "TJ":
+			for _, param := range op.Params {
+				if arr, ok := core.GetArray(param); ok {
+					for _, elem := range arr.Elements() {
+						if str, ok := core.GetString(elem); ok {
+							shown = append(shown, str.Str())
+						}
+					}
+				} else if str, ok := core.GetString(param); ok {
+					shown = append(shown, str.Str())
+				}
+			}
+		case "re":
+			f, err := core.GetNumbersAsFloat(op.Params)
+			require.NoError(t, err)
+			rects = append(rects, f)
+		case "Do":
+			name, ok := core.GetName(op.Params[0])
+			require.True(t, ok)
+			images = append(images, *name)
+		}
+	}
+
+	text := strings.Join(shown, "")
+	require.Contains(t, text, "Public")
+	require.NotContains(t, text, "Secret")
+	require.Contains(t, text, "REDACTED")
+	require.Contains(t, rects, []float64{10, 10, 100, 10})
+	require.NotContains(t, rects, []float64{120, 20, 30, 30})
+	require.Contains(t, rects, []float64{115, 15, 40, 40})
+
+	// The pixels of the lower left quarter of the image and of its soft mask are erased.
+	require.Equal(t, []core.PdfObjectName{"Im1"}, images)
+	xobjects, ok := core.GetDict(page.Resources.XObject)
+	require.True(t, ok)
+	require.Equal(t, []core.PdfObjectName{"Im1"}, xobjects.Keys())
+	stream, xtype := page.Resources.GetXObjectByName("Im1")
+	require.Equal(t, model.XObjectTypeImage, xtype)
+	smask, ok := core.GetStream(stream.Get("SMask"))
+	require.True(t, ok)
+	for _, tc := range []struct {
+		stream *core.PdfObjectStream
+		value  byte
+	}{{stream, 0x80}, {smask, 0xFF}} {
+		ximg, err := model.NewXObjectImageFromStream(tc.stream)
+		require.NoError(t, err)
+		img, err := ximg.ToImage()
+		require.NoError(t, err)
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				expected := tc.value
+				if x < 5 && y >= 5 {
+					expected = 0
+				}
+				require.Equal(t, expected, img.Data[y*10+x], "pixel (%d, %d)", x, y)
+			}
+		}
+	}
+}
+
+func TestRedactPageRemovedImage(t *testing.T) {
+	page := pdftest.NewPage(t, 100, 100, "q 50 0 0 50 0 0 cm /Im1 Do Q")
+	img := &model.Image{Width: 1, Height: 1, BitsPerComponent: 8, ColorComponents: 1, Data: []byte{1}}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im1", ximg))
+
+	// The image inside the region is removed with its resource.
+	redaction := &Redaction{Regions: []model.PdfRectangle{{Llx: 0, Lly: 0, Urx: 60, Ury: 60}}}
+	_, err = RedactPage(page, []*Redaction{redaction})
+	require.NoError(t, err)
+	data, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, data, "Do")
+	require.False(t, page.Resources.HasXObjectByName("Im1"))
+}
+
+func TestRedactPageForm(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 200, "q 1 0 0 1 100 100 cm /Fm1 Do Q /Fm1 Do")
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	require.NoError(t, form.SetContentStream([]byte("0 0 10 10 re f 50 50 10 10 re f"), core.NewRawEncoder()))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm1", form))
+
+	// Only the form drawn at (100, 100) is redacted: the other use keeps the original content.
+	redaction := &Redaction{Regions: []model.PdfRectangle{{Llx: 95, Lly: 95, Urx: 115, Ury: 115}}}
+	_, err := RedactPage(page, []*Redaction{redaction})
+	require.NoError(t, err)
+
+	data, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(data).Parse()
+	require.NoError(t, err)
+	var names []core.PdfObjectName
+	for _, op := range *ops {
+		if op.Operand == "Do" {
+			name, _ := core.GetName(op.Params[0])
+			names = append(names, *name)
+		}
+	}
+	require.Len(t, names, 2)
+	require.NotEqual(t, names[0], names[1])
+	require.Equal(t, core.PdfObjectName("Fm1"), names[1])
+
+	getFormContent := func(name core.PdfObjectName) string {
+		form, err := page.Resources.GetXObjectFormByName(name)
+		require.NoError(t, err)
+		data, err := form.GetContentStream()
+		require.NoError(t, err)
+		return string(data)
+	}
+	require.NotContains(t, getFormContent(names[0]), "0 0 10 10 re")
+	require.Contains(t, getFormContent(names[0]), "50 50 10 10 re")
+	require.Contains(t, getFormContent(names[1]), "0 0 10 10 re")
+}
+
+func TestRedactFormSharedResources(t *testing.T) {
+	page := pdftest.NewPage(t, 200, 200, "q 1 0 0 1 100 100 cm /Fm1 Do Q /Fm1 Do")
+	img := &model.Image{Width: 2, Height: 2, BitsPerComponent: 8, ColorComponents: 1, Data: []byte{1, 2, 3, 4}}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	form.Resources = model.NewPdfPageResources()
+	require.NoError(t, form.Resources.SetXObjectImageByName("Im1", ximg))
+	require.NoError(t, form.SetContentStream([]byte("q 100 0 0 100 0 0 cm /Im1 Do Q"), core.NewRawEncoder()))
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm1", form))
+
+	// The redacted copy of the image is added to a copy of the form resources.
+	redaction := &Redaction{Regions: []model.PdfRectangle{{Llx: 100, Lly: 100, Urx: 150, Ury: 150}}}
+	_, err = RedactPage(page, []*Redaction{redaction})
+	require.NoError(t, err)
+	xobjects, ok := core.GetDict(form.Resources.XObject)
+	require.True(t, ok)
+	require.Equal(t, []core.PdfObjectName{"Im1"}, xobjects.Keys())
+}
+
+func TestRedactImageDecode(t *testing.T) {
+	page := pdftest.NewPage(t, 100, 100, "q 100 0 0 100 0 0 cm /Im1 Do Q")
+
+	// The samples of the inverted image are erased to the maximum value, decoded to black.
+	img := &model.Image{Width: 2, Height: 1, BitsPerComponent: 8, ColorComponents: 1, Data: []byte{0x40, 0x40}}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+	ximg.Decode = core.MakeArrayFromFloats([]float64{1, 0})
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im1", ximg))
+
+	redaction := &Redaction{Regions: []model.PdfRectangle{{Llx: 0, Lly: 0, Urx: 50, Ury: 100}}}
+	_, err = RedactPage(page, []*Redaction{redaction})
+	require.NoError(t, err)
+
+	data, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(data).Parse()
+	require.NoError(t, err)
+	var name *core.PdfObjectName
+	for _, op := range *ops {
+		if op.Operand == "Do" {
+			name, _ = core.GetName(op.Params[0])
+		}
+	}
+	require.NotNil(t, name)
+	stream, xtype := page.Resources.GetXObjectByName(*name)
+	require.Equal(t, model.XObjectTypeImage, xtype)
+	redacted, err := model.NewXObjectImageFromStream(stream)
+	require.NoError(t, err)
+	out, err := redacted.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xFF, 0x40}, out.Data)
+}
+
+func TestScrubText(t *testing.T) {
+	info := core.MakeDict()
+	info.Set("Title", core.MakeString("Report on Project Falcon"))
+	info.Set("Subject", core.MakeEncodedString("Project Falcon budget", true))
+	info.Set("Type", core.MakeName("Falcon"))
+
+	xmp := "<x:xmpmeta><dc:title alt=\"Project Falcon\">Project Falcon &amp; more</dc:title>" +
+		"<!-- Project Falcon --></x:xmpmeta>"
+	metadata, err := core.MakeStream([]byte(xmp), core.NewRawEncoder())
+	require.NoError(t, err)
+	metadata.Set("Type", core.MakeName("Metadata"))
+	catalog := core.MakeDict()
+	catalog.Set("Metadata", metadata)
+	catalog.Set("Info", core.MakeIndirectObject(info))
+
+	require.True(t, ScrubText(catalog, []string{"Project Falcon"}))
+	title, _ := core.GetString(info.Get("Title"))
+	require.Equal(t, "Report on ", title.Decoded())
+	subject, _ := core.GetString(info.Get("Subject"))
+	require.Equal(t, " budget", subject.Decoded())
+	require.Equal(t, []byte{0xFE, 0xFF}, subject.Bytes()[:2])
+	require.Equal(t, core.MakeName("Falcon"), info.Get("Type"))
+
+	data, err := core.DecodeStream(metadata)
+	require.NoError(t, err)
+	require.Equal(t, "<x:xmpmeta><dc:title alt=\"\"> &amp; more</dc:title><!--  --></x:xmpmeta>", string(data))
+
+	require.False(t, ScrubText(catalog, []string{"Project Falcon"}))
+}
+
+func TestScrubTextWordBoundaries(t *testing.T) {
+	info := core.MakeDict()
+	info.Set("Title", core.MakeString("The other quarterly report"))
+	info.Set("Subject", core.MakeString("Send the money to Bob, see the attached theory"))
+	info.Set("Keywords", core.MakeString("Send the money to Bobby"))
+
+	require.True(t, ScrubText(info, []string{"Send the money to Bob", "the"}))
+	title, _ := core.GetString(info.Get("Title"))
+	require.Equal(t, "The other quarterly report", title.Decoded())
+	subject, _ := core.GetString(info.Get("Subject"))
+	require.Equal(t, ", see  attached theory", subject.Decoded())
+	keywords, _ := core.GetString(info.Get("Keywords"))
+	require.Equal(t, "Send  money to Bobby", keywords.Decoded())
+}
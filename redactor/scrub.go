@@ -0,0 +1,363 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/pdfutil"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// scrubbedKeys are the keys of the dictionary entries holding text which is scrubbed: document
+// information, annotation and form field texts and alternate descriptions.
+var scrubbedKeys = map[core.PdfObjectName]struct{}{
+	"Title": {}, "Author": {}, "Subject": {}, "Keywords": {}, "Creator": {}, "Producer": {},
+	"Contents": {}, "T": {}, "TU": {}, "TM": {}, "Subj": {}, "RC": {}, "V": {}, "DV": {},
+	"Alt": {}, "ActualText": {}, "E": {},
+}
+
+// ScrubText removes `texts` from the text strings of `obj` and the objects it refers to (except
+// parents), e.g. from the document information dictionary, outline item titles, annotation and
+// field values and XMP metadata streams. The texts are only removed where they occur as whole
+// words. Returns true if any text was removed.
+func ScrubText(obj core.PdfObject, texts []string) bool {
+	replacer := newScrubReplacer(texts)
+	if replacer == nil {
+		return false
+	}
+	s := &scrubber{replacer: replacer, visited: pdfutil.ObjectSet{}}
+	return s.scrub(obj, "")
+}
+
+// scrubReplacer removes phrases where they occur on word boundaries.
+type scrubReplacer struct {
+	// phrases are the removed phrases, longest first.
+	phrases []string
+}
+
+// newScrubReplacer returns a replacer removing `texts` of 3 runes or more, or nil if there is
+// nothing to remove.
+func newScrubReplacer(texts []string) *scrubReplacer {
+	seen := map[string]struct{}{}
+	var phrases []string
+	addPhrase := func(s string) {
+		s = strings.TrimSpace(s)
+		if utf8.RuneCountInString(s) < 3 {
+			return
+		}
+		if _, ok := seen[s]; ok {
+			return
+		}
+		seen[s] = struct{}{}
+		phrases = append(phrases, s)
+	}
+	for _, text := range texts {
+		addPhrase(text)
+	}
+	if len(phrases) == 0 {
+		return nil
+	}
+
+	// Also remove the XML escaped forms of the phrases for XMP metadata.
+	for _, phrase := range phrases {
+		if escaped := html.EscapeString(phrase); escaped != phrase {
+			addPhrase(escaped)
+		}
+	}
+	sort.SliceStable(phrases, func(i, j int) bool {
+		return len(phrases[i]) > len(phrases[j])
+	})
+	return &scrubReplacer{phrases: phrases}
+}
+
+// Replace returns `s` without the phrases of `r`.
+func (r *scrubReplacer) Replace(s string) string {
+	for _, phrase := range r.phrases {
+		s = removePhrase(s, phrase)
+	}
+	return s
+}
+
+// removePhrase returns `s` without the occurrences of `phrase` which are not part of a longer
+// word, e.g. "the" is removed from "see the report" but not from "other".
+func removePhrase(s, phrase string) string {
+	var sb strings.Builder
+	last := 0
+	for start := 0; start < len(s); {
+		i := strings.Index(s[start:], phrase)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + len(phrase)
+		if !isWordBoundary(s, i) || !isWordBoundary(s, end) {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			start = i + size
+			continue
+		}
+		sb.WriteString(s[last:i])
+		last = end
+		start = end
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// isWordBoundary returns true if byte offset `i` of `s` does not split a word, i.e. if the runes
+// before and after it are not both letters or digits.
+func isWordBoundary(s string, i int) bool {
+	if i <= 0 || i >= len(s) {
+		return true
+	}
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i:])
+	return !isWordRune(before) || !isWordRune(after)
+}
+
+// isWordRune returns true if `r` is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// scrubber removes text from objects.
+type scrubber struct {
+	replacer *scrubReplacer
+	visited  pdfutil.ObjectSet
+}
+
+// scrub removes text from `obj`, which is the value of dictionary entry `key` if not empty.
+// Returns true if any text was removed.
+func (s *scrubber) scrub(obj core.PdfObject, key core.PdfObjectName) bool {
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		obj = ref.Resolve()
+	}
+	if obj == nil {
+		return false
+	}
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if s.visited.Visit(t) {
+			return false
+		}
+		return s.scrub(t.PdfObject, key)
+	case *core.PdfObjectDictionary:
+		if s.visited.Visit(t) {
+			return false
+		}
+		return s.scrubDict(t)
+	case *core.PdfObjectArray:
+		changed := false
+		for i, elem := range t.Elements() {
+			if str, ok := elem.(*core.PdfObjectString); ok {
+				if _, ok := scrubbedKeys[key]; ok {
+					if scrubbed, ok := s.scrubString(str); ok {
+						t.Set(i, scrubbed)
+						changed = true
+					}
+				}
+				continue
+			}
+			if s.scrub(elem, key) {
+				changed = true
+			}
+		}
+		return changed
+	case *core.PdfObjectStream:
+		if s.visited.Visit(t) {
+			return false
+		}
+		if key == "Metadata" || isMetadataStream(t) {
+			return s.scrubMetadata(t)
+		}
+		return s.scrubDict(t.PdfObjectDictionary)
+	}
+	return false
+}
+
+// scrubDict removes text from the entries of `dict`. Returns true if any text was removed.
+func (s *scrubber) scrubDict(dict *core.PdfObjectDictionary) bool {
+	if dict == nil {
+		return false
+	}
+	changed := false
+	for _, key := range dict.Keys() {
+		if key == "Parent" || key == "P" {
+			continue
+		}
+		val := dict.Get(key)
+		if str, ok := val.(*core.PdfObjectString); ok {
+			if _, ok := scrubbedKeys[key]; ok {
+				if scrubbed, ok := s.scrubString(str); ok {
+					dict.Set(key, scrubbed)
+					changed = true
+				}
+			}
+			continue
+		}
+		if s.scrub(val, key) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// scrubString returns `str` without the scrubbed text, and true if any text was removed.
+func (s *scrubber) scrubString(str *core.PdfObjectString) (*core.PdfObjectString, bool) {
+	decoded := str.Decoded()
+	scrubbed := s.replacer.Replace(decoded)
+	if scrubbed == decoded {
+		return str, false
+	}
+	b := str.Bytes()
+	isUTF16 := len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF
+	return core.MakeEncodedString(scrubbed, isUTF16), true
+}
+
+// scrubMetadata removes text from XMP metadata stream `stream`: from the character data, the
+// attribute values, the comments and the CDATA sections, leaving the markup intact.
+// Returns true if any text was removed.
+func (s *scrubber) scrubMetadata(stream *core.PdfObjectStream) bool {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode metadata stream: %v", err)
+		return false
+	}
+	scrubbed := scrubXML(string(data), s.replacer)
+	if scrubbed == string(data) {
+		return false
+	}
+
+	if err := pdfutil.SetStreamData(stream, []byte(scrubbed)); err != nil {
+		common.Log.Debug("ERROR: Unable to encode metadata stream: %v", err)
+		return false
+	}
+	return true
+}
+
+// scrubXML returns `data` with the text replaced by `replacer` outside of the tag and attribute
+// names.
+func scrubXML(data string, replacer *scrubReplacer) string {
+	var sb strings.Builder
+	for len(data) > 0 {
+		i := strings.IndexByte(data, '<')
+		if i < 0 {
+			sb.WriteString(replacer.Replace(data))
+			break
+		}
+		sb.WriteString(replacer.Replace(data[:i]))
+		data = data[i:]
+
+		var end string
+		switch {
+		case strings.HasPrefix(data, "<!--"):
+			end = "-->"
+		case strings.HasPrefix(data, "<![CDATA["):
+			end = "]]>"
+		case strings.HasPrefix(data, "<?"):
+			end = "?>"
+		default:
+			end = ">"
+		}
+		j := strings.Index(data, end)
+		if j < 0 {
+			j = len(data)
+		} else {
+			j += len(end)
+		}
+		tag := data[:j]
+		data = data[j:]
+
+		switch end {
+		case "-->":
+			sb.WriteString("<!--" + replacer.Replace(strings.TrimSuffix(tag[4:], end)) + end)
+		case "]]>":
+			sb.WriteString("<![CDATA[" + replacer.Replace(strings.TrimSuffix(tag[9:], end)) + end)
+		case "?>":
+			sb.WriteString(tag)
+		default:
+			sb.WriteString(scrubAttributes(tag, replacer))
+		}
+	}
+	return sb.String()
+}
+
+// scrubAttributes returns `tag` with the text of its quoted attribute values replaced by
+// `replacer`.
+func scrubAttributes(tag string, replacer *scrubReplacer) string {
+	var sb strings.Builder
+	for len(tag) > 0 {
+		i := strings.IndexAny(tag, `"'`)
+		if i < 0 {
+			sb.WriteString(tag)
+			break
+		}
+		sb.WriteString(tag[:i+1])
+		quote := tag[i]
+		tag = tag[i+1:]
+		j := strings.IndexByte(tag, quote)
+		if j < 0 {
+			sb.WriteString(tag)
+			break
+		}
+		sb.WriteString(replacer.Replace(tag[:j]))
+		sb.WriteByte(quote)
+		tag = tag[j+1:]
+	}
+	return sb.String()
+}
+
+// isMetadataStream returns true if `stream` is a metadata stream.
+func isMetadataStream(stream *core.PdfObjectStream) bool {
+	name, ok := core.GetName(stream.Get("Type"))
+	return ok && *name == "Metadata"
+}
+
+// scrubPage removes `texts` from the metadata and the annotations of `page`.
+func scrubPage(page *model.PdfPage, texts []string) error {
+	replacer := newScrubReplacer(texts)
+	if replacer == nil {
+		return nil
+	}
+	s := &scrubber{replacer: replacer, visited: pdfutil.ObjectSet{}}
+	if page.Metadata != nil {
+		s.scrub(page.Metadata, "Metadata")
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i, annot := range annotations {
+		// The annotation models rebuild their dictionaries from their fields, so the text is
+		// removed from the dictionaries which are then loaded back.
+		container, ok := annotationToPdfObject(annot).(*core.PdfIndirectObject)
+		if !ok || !s.scrub(container, "") {
+			continue
+		}
+		scrubbed, err := model.NewPdfAnnotationFromIndirectObject(container)
+		if err != nil {
+			return err
+		}
+		annotations[i] = scrubbed
+		changed = true
+	}
+	if changed {
+		page.SetAnnotations(annotations)
+	}
+	return nil
+}
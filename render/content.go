@@ -0,0 +1,571 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// flatness is the maximum distance, in device pixels, between curves and the polylines
+// approximating them.
+const flatness = 0.2
+
+// graphicsState is the part of the graphics state used for rendering. The colors are tracked by
+// the content stream processor and converted to paints when they are set.
+type graphicsState struct {
+	ctm  transform.Matrix // Maps the user space to the device space (pixels).
+	clip *image.Alpha     // Clipping mask, nil if there is no clipping.
+
+	fill, stroke           paint
+	fillAlpha, strokeAlpha float64
+
+	lineWidth  float64
+	lineCap    int
+	lineJoin   int
+	miterLimit float64
+	dash       []float64
+	dashPhase  float64
+
+	text textstate.State
+}
+
+// newGraphicsState returns the initial graphics state of a page with CTM `ctm`.
+func newGraphicsState(ctm transform.Matrix) graphicsState {
+	black := paint{}
+	return graphicsState{
+		ctm:         ctm,
+		fill:        black,
+		stroke:      black,
+		fillAlpha:   1,
+		strokeAlpha: 1,
+		lineWidth:   1,
+		miterLimit:  10,
+		text:        textstate.New(),
+	}
+}
+
+// subpath is a subpath of a path in user space, with the curves flattened.
+type subpath struct {
+	points []point
+	closed bool
+}
+
+// path is the current path, in user space.
+type path struct {
+	subpaths []subpath
+	cur      point
+}
+
+// moveTo starts a new subpath at `p`.
+func (p *path) moveTo(pt point) {
+	// A trailing lone move is replaced.
+	if n := len(p.subpaths); n > 0 && len(p.subpaths[n-1].points) == 1 && !p.subpaths[n-1].closed {
+		p.subpaths = p.subpaths[:n-1]
+	}
+	p.subpaths = append(p.subpaths, subpath{points: []point{pt}})
+	p.cur = pt
+}
+
+// lineTo adds a line to `pt`.
+func (p *path) lineTo(pt point) {
+	n := len(p.subpaths)
+	if n == 0 || p.subpaths[n-1].closed {
+		// Start a new subpath at the current point.
+		p.subpaths = append(p.subpaths, subpath{points: []point{p.cur}})
+		n++
+	}
+	p.subpaths[n-1].points = append(p.subpaths[n-1].points, pt)
+	p.cur = pt
+}
+
+// cubeTo adds a cubic Bézier curve with control points `c1` and `c2` to `pt`, approximated
+// with lines within tolerance `tol`.
+func (p *path) cubeTo(c1, c2, pt point, tol float64) {
+	p0 := p.cur
+	dd := math.Max(math.Hypot(p0.x-2*c1.x+c2.x, p0.y-2*c1.y+c2.y),
+		math.Hypot(c1.x-2*c2.x+pt.x, c1.y-2*c2.y+pt.y))
+	n := segmentCount(0.75*dd, tol)
+	for i := 1; i < n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		p.lineTo(point{a*p0.x + b*c1.x + c*c2.x + d*pt.x, a*p0.y + b*c1.y + c*c2.y + d*pt.y})
+	}
+	p.lineTo(pt)
+}
+
+// quadTo adds a quadratic Bézier curve with control point `c` to `pt`, approximated with lines
+// within tolerance `tol`.
+func (p *path) quadTo(c, pt point, tol float64) {
+	p0 := p.cur
+	dd := math.Hypot(p0.x-2*c.x+pt.x, p0.y-2*c.y+pt.y)
+	n := segmentCount(dd/4, tol)
+	for i := 1; i < n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		a, b, d := u*u, 2*u*t, t*t
+		p.lineTo(point{a*p0.x + b*c.x + d*pt.x, a*p0.y + b*c.y + d*pt.y})
+	}
+	p.lineTo(pt)
+}
+
+// close closes the current subpath.
+func (p *path) close() {
+	n := len(p.subpaths)
+	if n == 0 || p.subpaths[n-1].closed {
+		return
+	}
+	p.subpaths[n-1].closed = true
+	p.cur = p.subpaths[n-1].points[0]
+}
+
+// append adds the subpaths of `other` transformed by `m` to `p`.
+func (p *path) append(other path, m transform.Matrix) {
+	for _, sp := range other.subpaths {
+		pts := make([]point, len(sp.points))
+		for i, pt := range sp.points {
+			pts[i].x, pts[i].y = m.Transform(pt.x, pt.y)
+		}
+		p.subpaths = append(p.subpaths, subpath{points: pts, closed: sp.closed})
+	}
+	if n := len(p.subpaths); n > 0 {
+		last := p.subpaths[n-1]
+		p.cur = last.points[len(last.points)-1]
+		if last.closed {
+			p.cur = last.points[0]
+		}
+	}
+}
+
+// polygons returns the subpaths of `p` transformed by `m`, implicitly closed.
+func (p *path) polygons(m transform.Matrix) []polygon {
+	polys := make([]polygon, 0, len(p.subpaths))
+	for _, sp := range p.subpaths {
+		polys = append(polys, transformPolygon(sp.points, m))
+	}
+	return polys
+}
+
+// segmentCount returns the number of lines approximating a curve with error `err` for a
+// single line within tolerance `tol`, where the error decreases with the square of the number
+// of lines.
+func segmentCount(err, tol float64) int {
+	if tol <= 0 || err <= tol {
+		return 1
+	}
+	n := int(math.Ceil(math.Sqrt(err / tol)))
+	if n > 500 {
+		n = 500
+	}
+	return n
+}
+
+// transformPolygon returns `pts` transformed by `m`.
+func transformPolygon(pts []point, m transform.Matrix) polygon {
+	poly := make(polygon, len(pts))
+	for i, pt := range pts {
+		poly[i].x, poly[i].y = m.Transform(pt.x, pt.y)
+	}
+	return poly
+}
+
+// userTolerance returns the flatness tolerance in the user space of CTM `ctm`.
+func userTolerance(ctm transform.Matrix) float64 {
+	s := matrixScale(ctm)
+	if s == 0 {
+		return flatness
+	}
+	return flatness / s
+}
+
+// matrixScale returns the mean scaling factor of `m`.
+func matrixScale(m transform.Matrix) float64 {
+	return math.Sqrt(math.Abs(m[0]*m[4] - m[1]*m[3]))
+}
+
+// streamRenderer renders a content stream.
+type streamRenderer struct {
+	r         *renderer
+	proc      *contentstream.ContentStreamProcessor
+	resources *model.PdfPageResources
+	depth     int              // Nesting depth of Form XObjects and patterns.
+	base      transform.Matrix // Initial CTM of the content stream.
+
+	gs    graphicsState
+	stack []graphicsState
+	path  path
+	clip  bool // Clipping path operator pending.
+	rule  fillRule
+
+	// Text object state.
+	text     textstate.Object
+	textClip []polygon
+	clipText bool
+}
+
+// renderContent renders the operations `ops` with resources `resources` and initial graphics
+// state `gs`. `depth` is the nesting depth of the content stream.
+func (r *renderer) renderContent(ops contentstream.ContentStreamOperations, resources *model.PdfPageResources,
+	gs graphicsState, depth int) {
+	if depth > maxNesting {
+		common.Log.Debug("ERROR: Content nesting too deep - skipping")
+		return
+	}
+	sr := &streamRenderer{
+		r:         r,
+		proc:      contentstream.NewContentStreamProcessor(ops),
+		resources: resources,
+		depth:     depth,
+		base:      gs.ctm,
+		gs:        gs,
+	}
+	sr.proc.SetOptionalContent(r.ocProperties, nil)
+	sr.proc.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, pgs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			sr.handle(op, pgs, resources)
+			return nil
+		})
+	if err := sr.proc.Process(resources); err != nil {
+		common.Log.Debug("ERROR: Unable to process content stream: %v", err)
+	}
+}
+
+// handle renders operation `op`, with the colors of the processor graphics state `pgs`.
+func (sr *streamRenderer) handle(op *contentstream.ContentStreamOperation, pgs contentstream.GraphicsState,
+	resources *model.PdfPageResources) {
+	visible := sr.proc.IsContentVisible()
+	gs := &sr.gs
+	switch op.Operand {
+	case "q":
+		sr.stack = append(sr.stack, sr.gs)
+	case "Q":
+		if n := len(sr.stack); n > 0 {
+			sr.gs = sr.stack[n-1]
+			sr.stack = sr.stack[:n-1]
+		}
+	case "cm":
+		if f, ok := textstate.FloatParams(op, 6); ok {
+			gs.ctm.Concat(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	case "w":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineWidth = f[0]
+		}
+	case "J":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineCap = int(f[0])
+		}
+	case "j":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineJoin = int(f[0])
+		}
+	case "M":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.miterLimit = f[0]
+		}
+	case "d":
+		if len(op.Params) == 2 {
+			gs.dash, gs.dashPhase = getDash(op.Params[0], op.Params[1])
+		}
+	case "gs":
+		setExtGState(gs, op, resources, sr.r.fonts)
+
+	// Colors.
+	case "g", "rg", "k", "cs", "sc", "scn":
+		gs.fill = makePaint(pgs.ColorspaceNonStroking, pgs.ColorNonStroking, resources, sr.base)
+	case "G", "RG", "K", "CS", "SC", "SCN":
+		gs.stroke = makePaint(pgs.ColorspaceStroking, pgs.ColorStroking, resources, sr.base)
+
+	// Path construction.
+	case "m":
+		if f, ok := textstate.FloatParams(op, 2); ok {
+			sr.path.moveTo(point{f[0], f[1]})
+		}
+	case "l":
+		if f, ok := textstate.FloatParams(op, 2); ok {
+			sr.path.lineTo(point{f[0], f[1]})
+		}
+	case "c":
+		if f, ok := textstate.FloatParams(op, 6); ok {
+			sr.path.cubeTo(point{f[0], f[1]}, point{f[2], f[3]}, point{f[4], f[5]}, userTolerance(gs.ctm))
+		}
+	case "v":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			sr.path.cubeTo(sr.path.cur, point{f[0], f[1]}, point{f[2], f[3]}, userTolerance(gs.ctm))
+		}
+	case "y":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			sr.path.cubeTo(point{f[0], f[1]}, point{f[2], f[3]}, point{f[2], f[3]}, userTolerance(gs.ctm))
+		}
+	case "h":
+		sr.path.close()
+	case "re":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			x, y, w, h := f[0], f[1], f[2], f[3]
+			sr.path.moveTo(point{x, y})
+			sr.path.lineTo(point{x + w, y})
+			sr.path.lineTo(point{x + w, y + h})
+			sr.path.lineTo(point{x, y + h})
+			sr.path.close()
+		}
+
+	// Path painting and clipping.
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+		sr.paintPath(op.Operand, visible)
+	case "W":
+		sr.clip, sr.rule = true, nonZero
+	case "W*":
+		sr.clip, sr.rule = true, evenOdd
+
+	// Text.
+	case "BT":
+		sr.text.Begin()
+		sr.textClip, sr.clipText = nil, false
+	case "ET":
+		if sr.clipText {
+			gs.clip = intersectClip(gs.clip, sr.textClip, nonZero, sr.r.width, sr.r.height)
+			sr.textClip, sr.clipText = nil, false
+		}
+	case "Tc", "Tw", "Tz", "TL", "Tf", "Tr", "Ts", "Td", "TD", "Tm", "T*", "Tj", "TJ", "'", `"`:
+		sr.handleText(op, resources, visible)
+
+	// XObjects, inline images and shadings.
+	case "Do":
+		if visible {
+			sr.drawXObject(op, resources)
+		}
+	case "BI":
+		if visible {
+			sr.drawInlineImage(op, resources)
+		}
+	case "sh":
+		if visible {
+			sr.drawShading(op, resources)
+		}
+	}
+}
+
+// paintPath paints the current path with painting operator `operand`, if `visible`, and
+// intersects the clipping path with it if a clipping operator is pending.
+func (sr *streamRenderer) paintPath(operand string, visible bool) {
+	switch operand {
+	case "s", "b", "b*":
+		sr.path.close()
+	}
+	if visible {
+		switch operand {
+		case "f", "F":
+			sr.fillPath(nonZero)
+		case "f*":
+			sr.fillPath(evenOdd)
+		case "B", "b":
+			sr.fillPath(nonZero)
+			sr.strokePath()
+		case "B*", "b*":
+			sr.fillPath(evenOdd)
+			sr.strokePath()
+		case "S", "s":
+			sr.strokePath()
+		}
+	}
+	if sr.clip {
+		sr.gs.clip = intersectClip(sr.gs.clip, sr.path.polygons(sr.gs.ctm), sr.rule, sr.r.width, sr.r.height)
+		sr.clip = false
+	}
+	sr.path = path{}
+}
+
+// fillPath fills the current path with the fill paint and rule `rule`.
+func (sr *streamRenderer) fillPath(rule fillRule) {
+	sr.r.fillPolygons(sr.path.polygons(sr.gs.ctm), rule, sr.paintSource(sr.gs.fill), sr.gs.fillAlpha, sr.gs.clip)
+}
+
+// strokePath strokes the current path with the stroke paint.
+func (sr *streamRenderer) strokePath() {
+	sr.r.fillPolygons(sr.strokePolygons(sr.path), nonZero, sr.paintSource(sr.gs.stroke), sr.gs.strokeAlpha,
+		sr.gs.clip)
+}
+
+// strokePolygons returns the device space polygons covering the stroke of `p` with the line
+// parameters of the graphics state.
+func (sr *streamRenderer) strokePolygons(p path) []polygon {
+	gs := &sr.gs
+	style := strokeStyle{
+		width:      gs.lineWidth,
+		cap:        gs.lineCap,
+		join:       gs.lineJoin,
+		miterLimit: gs.miterLimit,
+		dash:       gs.dash,
+		dashPhase:  gs.dashPhase,
+	}
+	// Lines are at least one pixel wide.
+	if s := matrixScale(gs.ctm); s > 0 && style.width*s < 1 {
+		style.width = 1 / s
+	}
+	polys := strokePolygons(p.subpaths, style, userTolerance(gs.ctm))
+	for i, poly := range polys {
+		polys[i] = transformPolygon(poly, gs.ctm)
+	}
+	return polys
+}
+
+// setExtGState sets the parameters of the graphics state parameter dictionary set by `op` in
+// `gs`, loading the fonts with `fonts`.
+func setExtGState(gs *graphicsState, op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	fonts textstate.FontCache) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	obj, ok := resources.GetExtGState(*name)
+	if !ok {
+		common.Log.Debug("ERROR: ExtGState %s not found", *name)
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	number := func(key core.PdfObjectName) (float64, bool) {
+		v, err := core.GetNumberAsFloat(core.ResolveReference(dict.Get(key)))
+		return v, err == nil
+	}
+	if v, ok := number("LW"); ok {
+		gs.lineWidth = v
+	}
+	if v, ok := number("LC"); ok {
+		gs.lineCap = int(v)
+	}
+	if v, ok := number("LJ"); ok {
+		gs.lineJoin = int(v)
+	}
+	if v, ok := number("ML"); ok {
+		gs.miterLimit = v
+	}
+	if v, ok := number("CA"); ok {
+		gs.strokeAlpha = math.Max(0, math.Min(1, v))
+	}
+	if v, ok := number("ca"); ok {
+		gs.fillAlpha = math.Max(0, math.Min(1, v))
+	}
+	if arr, ok := core.GetArray(dict.Get("D")); ok && arr.Len() == 2 {
+		gs.dash, gs.dashPhase = getDash(arr.Get(0), arr.Get(1))
+	}
+	gs.text.SetExtGStateFont(dict, fonts)
+	if smask := dict.Get("SMask"); smask != nil {
+		if name, ok := core.GetNameVal(smask); !ok || name != "None" {
+			common.Log.Debug("Soft masks of the graphics state not supported - ignoring")
+		}
+	}
+}
+
+// drawXObject draws the XObject referenced by the Do operation `op`.
+func (sr *streamRenderer) drawXObject(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil {
+		common.Log.Debug("ERROR: XObject %s not found", *name)
+		return
+	}
+	if !sr.r.isVisible(stream.Get("OC")) {
+		return
+	}
+	switch xtype {
+	case model.XObjectTypeImage:
+		sr.drawImageXObject(stream)
+	case model.XObjectTypeForm:
+		form, err := model.NewXObjectFormFromStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load form %s: %v", *name, err)
+			return
+		}
+		sr.r.drawForm(form, sr.gs, resources, sr.depth)
+	}
+}
+
+// drawForm draws Form XObject `form` with graphics state `gs`. `resources` are the resources
+// of the parent content stream, used if the form has none.
+func (r *renderer) drawForm(form *model.XObjectForm, gs graphicsState, resources *model.PdfPageResources,
+	depth int) {
+	if m, ok := getMatrix(form.Matrix); ok {
+		gs.ctm = gs.ctm.Mult(m)
+	}
+	if bbox, ok := getRectangle(form.BBox); ok {
+		rect := path{}
+		rect.moveTo(point{bbox.Llx, bbox.Lly})
+		rect.lineTo(point{bbox.Urx, bbox.Lly})
+		rect.lineTo(point{bbox.Urx, bbox.Ury})
+		rect.lineTo(point{bbox.Llx, bbox.Ury})
+		gs.clip = intersectClip(gs.clip, rect.polygons(gs.ctm), nonZero, r.width, r.height)
+	}
+	content, err := form.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form content: %v", err)
+		return
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse form content: %v", err)
+		if ops == nil {
+			return
+		}
+	}
+	if form.Resources != nil {
+		resources = form.Resources
+	}
+	r.renderContent(*ops, resources, gs, depth+1)
+}
+
+// getDash returns the dash pattern with array `arrObj` and phase `phaseObj`.
+func getDash(arrObj, phaseObj core.PdfObject) ([]float64, float64) {
+	arr, ok := core.GetArray(arrObj)
+	if !ok {
+		return nil, 0
+	}
+	dash, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil, 0
+	}
+	phase, _ := core.GetNumberAsFloat(core.ResolveReference(phaseObj))
+	return dash, phase
+}
+
+// getMatrix returns the matrix of the array of 6 numbers `obj`.
+func getMatrix(obj core.PdfObject) (transform.Matrix, bool) {
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() != 6 {
+		return transform.Matrix{}, false
+	}
+	f, err := arr.ToFloat64Array()
+	if err != nil {
+		return transform.Matrix{}, false
+	}
+	return transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]), true
+}
+
+// getRectangle returns the normalized rectangle of the array of 4 numbers `obj`.
+func getRectangle(obj core.PdfObject) (model.PdfRectangle, bool) {
+	rect, ok := model.GetPdfRectangle(obj)
+	return rect.Normalized(), ok
+}
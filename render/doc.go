@@ -0,0 +1,27 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package render rasterizes pages to images and exports them as SVG. The page content streams are interpreted with a
+// contentstream.ContentStreamProcessor and drawn with an anti-aliasing scanline rasterizer:
+// paths are filled with the nonzero winding number or even-odd rules and stroked with the line
+// width, caps, joins, miter limit and dash pattern of the graphics state, and clipping paths are
+// kept as coverage masks.
+//
+// Text is drawn with the glyph outlines of the embedded TrueType, CFF and Type 1 font programs
+// (see model.PdfFont.GetCharGlyph), or of substitute fonts for the fonts which are not embedded.
+// Type 3 fonts are not supported. Images are drawn with their stencil masks, explicit masks, color
+// key masks and soft masks, and axial, radial and function-based shadings are painted with the
+// sh operator or as shading patterns. Tiling patterns, Form XObjects, optional content and the
+// appearance streams of the annotations are also supported.
+//
+// Transparency is limited to the constant alpha of the graphics state (CA and ca): blend modes,
+// soft masks of the graphics state and transparency groups are not supported.
+//
+// WritePageSVG converts the same content to SVG elements: paths keep their curves, clipping paths
+// are clipPath elements, images are PNG (or JPEG) data URIs and axial and radial shadings are
+// gradients, while the shadings with no SVG equivalent are rasterized. Text is written as text
+// elements positioned glyph by glyph, using the embedded TrueType and OpenType font programs or
+// the installed fonts closest to the PDF fonts, or optionally as glyph outlines.
+package render
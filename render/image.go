@@ -0,0 +1,334 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// rasterImage is an image decoded for rendering.
+type rasterImage struct {
+	width, height int
+	rgb           []byte // 3 bytes per pixel, nil for stencil masks painted with the fill paint.
+	alpha         []byte // 1 byte per pixel, nil for opaque images.
+}
+
+// drawImageXObject draws the image XObject `stream` in the unit square of the user space.
+func (sr *streamRenderer) drawImageXObject(stream *core.PdfObjectStream) {
+	img, err := loadXObjectImage(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load image: %v", err)
+		return
+	}
+	sr.drawImage(img)
+}
+
+// drawInlineImage draws the inline image of the BI operation `op` in the unit square of the
+// user space.
+func (sr *streamRenderer) drawInlineImage(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) {
+	if len(op.Params) != 1 {
+		return
+	}
+	inline, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
+	if !ok {
+		return
+	}
+	img, err := loadInlineImage(inline, resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load inline image: %v", err)
+		return
+	}
+	sr.drawImage(img)
+}
+
+// drawImage draws `img` in the unit square of the user space, with nearest neighbor sampling.
+func (sr *streamRenderer) drawImage(img *rasterImage) {
+	gs := &sr.gs
+	inverse, ok := gs.ctm.Inverse()
+	if !ok {
+		return
+	}
+
+	// Device space bounding box of the unit square.
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, c := range []point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x, y := gs.ctm.Transform(c.x, c.y)
+		x0, y0 = math.Min(x0, x), math.Min(y0, y)
+		x1, y1 = math.Max(x1, x), math.Max(y1, y)
+	}
+	r := sr.r
+	ix0, iy0 := int(math.Max(math.Floor(x0), 0)), int(math.Max(math.Floor(y0), 0))
+	ix1, iy1 := int(math.Min(math.Ceil(x1), float64(r.width))), int(math.Min(math.Ceil(y1), float64(r.height)))
+
+	var src paintSource
+	if img.rgb == nil {
+		src = sr.paintSource(gs.fill)
+	}
+	alpha := float32(gs.fillAlpha)
+	for y := iy0; y < iy1; y++ {
+		for x := ix0; x < ix1; x++ {
+			u, v := inverse.Transform(float64(x)+0.5, float64(y)+0.5)
+			if u < 0 || u >= 1 || v < 0 || v >= 1 {
+				continue
+			}
+			// The first image row is at the top of the unit square.
+			i := int((1-v)*float64(img.height))*img.width + int(u*float64(img.width))
+			if i < 0 || i >= img.width*img.height {
+				continue
+			}
+			k := alpha * clipAlpha(gs.clip, x, y)
+			if img.alpha != nil {
+				k *= float32(img.alpha[i]) / 255
+			}
+			if k <= 0 {
+				continue
+			}
+			var c rgba
+			if img.rgb != nil {
+				c = rgba{float32(img.rgb[3*i]) / 255, float32(img.rgb[3*i+1]) / 255, float32(img.rgb[3*i+2]) / 255, 1}
+			} else {
+				c = src.colorAt(x, y)
+			}
+			r.blend(x, y, c, k)
+		}
+	}
+}
+
+// loadXObjectImage decodes the image XObject `stream` with its masks.
+func loadXObjectImage(stream *core.PdfObjectStream) (*rasterImage, error) {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if ximg.Width == nil || ximg.Height == nil {
+		return nil, errors.New("image dimensions missing")
+	}
+	width, height := int(*ximg.Width), int(*ximg.Height)
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid image dimensions")
+	}
+
+	if isMask, _ := core.GetBoolVal(ximg.ImageMask); isMask {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return &rasterImage{
+			width:  width,
+			height: height,
+			alpha:  stencilAlpha(data, width, height, ximg.Decode),
+		}, nil
+	}
+
+	if ximg.BitsPerComponent == nil {
+		return nil, errors.New("bits per component missing")
+	}
+	mimg, err := ximg.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	img, err := convertImage(mimg, ximg.ColorSpace)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mask := core.TraceToDirectObject(ximg.Mask).(type) {
+	case *core.PdfObjectStream:
+		// Explicit mask: an image mask painting the image where it would paint the fill color.
+		if m, err := loadXObjectImage(mask); err == nil && m.rgb == nil {
+			img.alpha = resampleAlpha(m.alpha, m.width, m.height, width, height)
+		} else {
+			common.Log.Debug("ERROR: Invalid image mask: %v", err)
+		}
+	case *core.PdfObjectArray:
+		// Color key masking.
+		if ranges, err := mask.ToIntegerArray(); err == nil {
+			img.alpha = colorKeyAlpha(mimg, ranges)
+		}
+	}
+
+	if smask, ok := core.GetStream(ximg.SMask); ok {
+		alpha, w, h, err := loadSoftMask(smask)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid soft mask: %v", err)
+		} else {
+			img.alpha = resampleAlpha(alpha, w, h, width, height)
+		}
+	}
+	return img, nil
+}
+
+// loadInlineImage decodes the inline image `inline` with resources `resources`.
+func loadInlineImage(inline *contentstream.ContentStreamInlineImage, resources *model.PdfPageResources) (
+	*rasterImage, error) {
+	mimg, err := inline.ToImage(resources)
+	if err != nil {
+		return nil, err
+	}
+	width, height := int(mimg.Width), int(mimg.Height)
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid image dimensions")
+	}
+	if isMask, _ := inline.IsMask(); isMask {
+		return &rasterImage{
+			width:  width,
+			height: height,
+			alpha:  stencilAlpha(mimg.Data, width, height, inline.Decode),
+		}, nil
+	}
+	cs, err := inline.GetColorSpace(resources)
+	if err != nil {
+		return nil, err
+	}
+	return convertImage(mimg, cs)
+}
+
+// loadSoftMask returns the alpha values and dimensions of the soft mask image `stream`.
+func loadSoftMask(stream *core.PdfObjectStream) ([]byte, int, int, error) {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if ximg.BitsPerComponent == nil || ximg.Width == nil || ximg.Height == nil {
+		return nil, 0, 0, errors.New("soft mask parameters missing")
+	}
+	ximg.ColorSpace = model.NewPdfColorspaceDeviceGray()
+	mimg, err := ximg.ToImage()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	unpadRows(mimg)
+	samples := mimg.GetSamples()
+	width, height := int(mimg.Width), int(mimg.Height)
+	if len(samples) < width*height {
+		return nil, 0, 0, errors.New("soft mask data too short")
+	}
+	maxVal := float64(uint32(1)<<uint32(mimg.BitsPerComponent) - 1)
+	alpha := make([]byte, width*height)
+	for i := range alpha {
+		alpha[i] = byte(float64(samples[i])*255/maxVal + 0.5)
+	}
+	return alpha, width, height, nil
+}
+
+// convertImage returns `img` in colorspace `cs` converted to RGB.
+func convertImage(img *model.Image, cs model.PdfColorspace) (*rasterImage, error) {
+	if cs == nil {
+		return nil, errors.New("image colorspace missing")
+	}
+	unpadRows(img)
+	rgbImg, err := cs.ImageToRGB(*img)
+	if err != nil {
+		return nil, err
+	}
+	width, height := int(img.Width), int(img.Height)
+	samples := rgbImg.GetSamples()
+	if rgbImg.ColorComponents != 3 || len(samples) < 3*width*height {
+		return nil, errors.New("image data too short")
+	}
+	maxVal := float64(uint32(1)<<uint32(rgbImg.BitsPerComponent) - 1)
+	rgb := make([]byte, 3*width*height)
+	for i := range rgb {
+		rgb[i] = byte(float64(samples[i])*255/maxVal + 0.5)
+	}
+	return &rasterImage{width: width, height: height, rgb: rgb}, nil
+}
+
+// unpadRows removes the padding bits at the end of the rows of `img` so that the samples are
+// contiguous, as expected by the model.Image methods.
+func unpadRows(img *model.Image) {
+	rowBits := int(img.Width) * img.ColorComponents * int(img.BitsPerComponent)
+	if rowBits%8 == 0 {
+		return
+	}
+	stride := (rowBits + 7) / 8
+	height := int(img.Height)
+	data := make([]byte, (rowBits*height+7)/8)
+	for y := 0; y < height && (y+1)*stride <= len(img.Data); y++ {
+		row := img.Data[y*stride:]
+		for b := 0; b < rowBits; b++ {
+			if row[b/8]&(0x80>>uint(b%8)) != 0 {
+				out := y*rowBits + b
+				data[out/8] |= 0x80 >> uint(out%8)
+			}
+		}
+	}
+	img.Data = data
+}
+
+// stencilAlpha returns the alpha values of the stencil mask `data` (1 bit per pixel, padded
+// rows) of `width`×`height` pixels with decode array `decode`: the pixels with value 0 (1 if the
+// decode array is [1 0]) are painted.
+func stencilAlpha(data []byte, width, height int, decode core.PdfObject) []byte {
+	paintBit := byte(0)
+	if arr, ok := core.GetArray(decode); ok && arr.Len() == 2 {
+		if d, err := arr.ToFloat64Array(); err == nil && d[0] > d[1] {
+			paintBit = 1
+		}
+	}
+	stride := (width + 7) / 8
+	alpha := make([]byte, width*height)
+	for y := 0; y < height && (y+1)*stride <= len(data); y++ {
+		row := data[y*stride:]
+		for x := 0; x < width; x++ {
+			if (row[x/8]>>uint(7-x%8))&1 == paintBit {
+				alpha[y*width+x] = 255
+			}
+		}
+	}
+	return alpha
+}
+
+// colorKeyAlpha returns the alpha values of `img` (with contiguous samples) masked with color key
+// ranges `ranges` (min and max sample values of each component): the pixels with all components
+// within the ranges are not painted.
+func colorKeyAlpha(img *model.Image, ranges []int) []byte {
+	n := img.ColorComponents
+	if len(ranges) != 2*n {
+		return nil
+	}
+	samples := img.GetSamples()
+	count := int(img.Width * img.Height)
+	if len(samples) < n*count {
+		return nil
+	}
+	alpha := make([]byte, count)
+	for i := range alpha {
+		alpha[i] = 0
+		for c := 0; c < n; c++ {
+			v := int(samples[i*n+c])
+			if v < ranges[2*c] || v > ranges[2*c+1] {
+				alpha[i] = 255
+				break
+			}
+		}
+	}
+	return alpha
+}
+
+// resampleAlpha returns the alpha values `alpha` of a `w`×`h` image resampled to `width`×`height`
+// pixels with nearest neighbor sampling.
+func resampleAlpha(alpha []byte, w, h, width, height int) []byte {
+	if w == width && h == height {
+		return alpha
+	}
+	out := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		sy := y * h / height
+		for x := 0; x < width; x++ {
+			out[y*width+x] = alpha[sy*w+x*w/width]
+		}
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// subScanlines is the number of sample lines per pixel row used for vertical anti-aliasing.
+// The horizontal coverage of the sample lines is computed exactly.
+const subScanlines = 5
+
+// maxCoordinate limits the device coordinates of the rasterized polygons.
+const maxCoordinate = 1e7
+
+// point is a point in user space or device space.
+type point struct {
+	x, y float64
+}
+
+// polygon is a closed polygon in device space.
+type polygon []point
+
+// fillRule is the rule deciding which points are inside a path.
+type fillRule int
+
+// Fill rules.
+const (
+	nonZero fillRule = iota
+	evenOdd
+)
+
+// edge is a non-horizontal edge of a polygon with y0 < y1. `dir` is the winding direction.
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+// crossing is the crossing of an edge with a sample line.
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// coverageFunc is called with the coverage of the pixels [x0,x1) of row y, in cov[x0:x1].
+type coverageFunc func(y, x0, x1 int, cov []float32)
+
+// rasterize computes the coverage of the pixels of a width×height image by `polys` filled
+// with rule `rule` and calls `fn` for each row with covered pixels.
+func rasterize(polys []polygon, rule fillRule, width, height int, fn coverageFunc) {
+	var edges []edge
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		n := len(poly)
+		for i := 0; i < n; i++ {
+			p, q := clampPoint(poly[i]), clampPoint(poly[(i+1)%n])
+			if p.y == q.y || math.IsNaN(p.x+p.y+q.x+q.y) {
+				continue
+			}
+			e := edge{p.x, p.y, q.x, q.y, 1}
+			if p.y > q.y {
+				e = edge{q.x, q.y, p.x, p.y, -1}
+			}
+			edges = append(edges, e)
+			ymin, ymax = math.Min(ymin, e.y0), math.Max(ymax, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	y0 := int(math.Max(math.Floor(ymin), 0))
+	y1 := int(math.Min(math.Ceil(ymax), float64(height)))
+	cov := make([]float32, width+2)
+	acc := make([]float32, width+2)
+	var active []edge
+	var crossings []crossing
+	next := 0
+	const weight = 1.0 / subScanlines
+
+	for y := y0; y < y1; y++ {
+		// Update the active edges.
+		k := 0
+		for _, e := range active {
+			if e.y1 > float64(y) {
+				active[k] = e
+				k++
+			}
+		}
+		active = active[:k]
+		for next < len(edges) && edges[next].y0 < float64(y+1) {
+			if edges[next].y1 > float64(y) {
+				active = append(active, edges[next])
+			}
+			next++
+		}
+		if len(active) == 0 {
+			continue
+		}
+
+		minX, maxX := width+1, -1
+		addSpan := func(xa, xb float64) {
+			xa, xb = math.Max(xa, 0), math.Min(xb, float64(width))
+			if xb <= xa {
+				return
+			}
+			ia, ib := int(xa), int(xb)
+			if ia == ib {
+				cov[ia] += float32(weight * (xb - xa))
+			} else {
+				cov[ia] += float32(weight * (float64(ia+1) - xa))
+				acc[ia+1] += weight
+				acc[ib] -= weight
+				cov[ib] += float32(weight * (xb - float64(ib)))
+			}
+			if ia < minX {
+				minX = ia
+			}
+			if ib > maxX {
+				maxX = ib
+			}
+		}
+
+		for s := 0; s < subScanlines; s++ {
+			sy := float64(y) + (float64(s)+0.5)/subScanlines
+			crossings = crossings[:0]
+			for _, e := range active {
+				if e.y0 <= sy && sy < e.y1 {
+					x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+					crossings = append(crossings, crossing{x, e.dir})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding, start := 0, 0.0
+			for _, c := range crossings {
+				wasInside := isInside(winding, rule)
+				winding += c.dir
+				if inside := isInside(winding, rule); inside && !wasInside {
+					start = c.x
+				} else if !inside && wasInside {
+					addSpan(start, c.x)
+				}
+			}
+		}
+		if maxX < 0 {
+			continue
+		}
+
+		hi := maxX
+		if hi > width-1 {
+			hi = width - 1
+		}
+		sum := float32(0)
+		for x := minX; x <= hi; x++ {
+			sum += acc[x]
+			c := cov[x] + sum
+			if c > 1 {
+				c = 1
+			} else if c < 0 {
+				c = 0
+			}
+			cov[x] = c
+		}
+		if minX <= hi {
+			fn(y, minX, hi+1, cov)
+		}
+		for x := minX; x <= maxX+1 && x < len(cov); x++ {
+			cov[x], acc[x] = 0, 0
+		}
+	}
+}
+
+// isInside returns true if a point with winding number `winding` is inside a path filled with
+// rule `rule`.
+func isInside(winding int, rule fillRule) bool {
+	if rule == evenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// clampPoint limits the coordinates of `p` to the range allowed for rasterization.
+func clampPoint(p point) point {
+	clamp := func(v float64) float64 {
+		return math.Max(-maxCoordinate, math.Min(maxCoordinate, v))
+	}
+	return point{clamp(p.x), clamp(p.y)}
+}
+
+// intersectClip returns the clip mask `clip` (nil for no clipping) of a width×height image
+// intersected with `polys` filled with rule `rule`.
+func intersectClip(clip *image.Alpha, polys []polygon, rule fillRule, width, height int) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	rasterize(polys, rule, width, height, func(y, x0, x1 int, cov []float32) {
+		row := mask.Pix[y*mask.Stride:]
+		for x := x0; x < x1; x++ {
+			a := cov[x]
+			if clip != nil {
+				a *= float32(clip.Pix[y*clip.Stride+x]) / 255
+			}
+			row[x] = uint8(a*255 + 0.5)
+		}
+	})
+	return mask
+}
+
+// clipAlpha returns the alpha of pixel (`x`,`y`) in clip mask `clip` in the range [0,1].
+func clipAlpha(clip *image.Alpha, x, y int) float32 {
+	if clip == nil {
+		return 1
+	}
+	return float32(clip.Pix[y*clip.Stride+x]) / 255
+}
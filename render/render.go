@@ -0,0 +1,327 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxNesting is the maximum nesting depth of Form XObjects and tiling patterns.
+const maxNesting = 16
+
+// maxTileSize is the maximum width and height in pixels of the rendered cells of tiling patterns.
+const maxTileSize = 2048
+
+// Annotation flags hiding annotations on screen (section 12.5.3 p. 385).
+const (
+	annotationFlagHidden = 1 << 1
+	annotationFlagNoView = 1 << 5
+)
+
+// Options define the options of page rendering.
+type Options struct {
+	// DPI is the resolution of the rendered images in pixels per inch.
+	DPI float64
+
+	// Annotations specifies whether the appearance streams of the annotations are drawn.
+	Annotations bool
+
+	// Background is the color of the images before the page is drawn. The images are
+	// transparent where nothing is drawn if nil.
+	Background color.Color
+}
+
+// NewOptions returns the default rendering options: 72 DPI (one pixel per point), with the
+// annotations on a white background.
+func NewOptions() *Options {
+	return &Options{
+		DPI:         72,
+		Annotations: true,
+		Background:  color.White,
+	}
+}
+
+// RenderPage renders `page` to an image with options `opts` (the default options if nil).
+// The image shows the crop box of the page, rotated as specified by the Rotate entry of the page.
+func RenderPage(page *model.PdfPage, opts *Options) (*image.RGBA, error) {
+	if opts == nil {
+		opts = NewOptions()
+	}
+	if opts.DPI <= 0 {
+		return nil, errors.New("invalid resolution")
+	}
+	box, err := page.GetCropBox()
+	if err != nil {
+		return nil, err
+	}
+	rotate, err := page.GetRotate()
+	if err != nil {
+		return nil, err
+	}
+
+	scale := opts.DPI / 72
+	width := int(math.Max(math.Ceil(box.Width()*scale-0.01), 1))
+	height := int(math.Max(math.Ceil(box.Height()*scale-0.01), 1))
+	device := deviceMatrix(*box, rotate, scale, float64(width), float64(height))
+	if rotate == 90 || rotate == 270 {
+		width, height = height, width
+	}
+
+	r := newRenderer(width, height)
+	if opts.Background != nil {
+		draw.Draw(r.canvas, r.canvas.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+	r.ocProperties = getOCProperties(page)
+	ops, err := parsePageContent(page)
+	if err != nil {
+		return nil, err
+	}
+	r.renderContent(*ops, page.Resources, newGraphicsState(device), 0)
+
+	if opts.Annotations {
+		if err := r.renderAnnotations(page, device); err != nil {
+			return nil, err
+		}
+	}
+	return r.canvas, nil
+}
+
+// deviceMatrix returns the matrix mapping the default user space of a page with crop box `box`
+// and rotation `rotate` to a device space with the y axis down, `scale` device units per point,
+// where the unrotated page is `width`×`height` device units.
+func deviceMatrix(box model.PdfRectangle, rotate int64, scale, width, height float64) transform.Matrix {
+	llx, ury := math.Min(box.Llx, box.Urx), math.Max(box.Lly, box.Ury)
+	device := transform.NewMatrix(scale, 0, 0, -scale, -llx*scale, ury*scale)
+	switch rotate {
+	case 90:
+		device = transform.NewMatrix(0, 1, -1, 0, height, 0).Mult(device)
+	case 180:
+		device = transform.NewMatrix(-1, 0, 0, -1, width, height).Mult(device)
+	case 270:
+		device = transform.NewMatrix(0, -1, 1, 0, 0, width).Mult(device)
+	}
+	return device
+}
+
+// getOCProperties returns the optional content properties of the document of `page`, or nil if
+// it has none.
+func getOCProperties(page *model.PdfPage) *model.PdfOCProperties {
+	ocProperties, err := page.GetOptionalContentProperties()
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid optional content properties - ignoring: %v", err)
+		return nil
+	}
+	return ocProperties
+}
+
+// parsePageContent returns the operations of the content streams of `page`. The operations
+// parsed before an error are returned if the content is partly invalid.
+func parsePageContent(page *model.PdfPage) (*contentstream.ContentStreamOperations, error) {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		if ops == nil {
+			return nil, err
+		}
+		common.Log.Debug("ERROR: Unable to parse the whole page content - rendering the start: %v", err)
+	}
+	return ops, nil
+}
+
+// renderer draws content streams on an image.
+type renderer struct {
+	canvas        *image.RGBA
+	width, height int
+	ocProperties  *model.PdfOCProperties
+
+	// Caches shared with the renderers of tiling pattern cells.
+	fonts  textstate.FontCache
+	glyphs map[glyphKey]*model.GlyphPath
+	tiles  map[tileKey]*image.RGBA
+}
+
+// newRenderer returns a renderer drawing on a new transparent `width`×`height` image.
+func newRenderer(width, height int) *renderer {
+	return &renderer{
+		canvas: image.NewRGBA(image.Rect(0, 0, width, height)),
+		width:  width,
+		height: height,
+		fonts:  textstate.FontCache{},
+		glyphs: map[glyphKey]*model.GlyphPath{},
+		tiles:  map[tileKey]*image.RGBA{},
+	}
+}
+
+// newSubRenderer returns a renderer drawing on a new transparent `width`×`height` image with the
+// caches and optional content configuration of `r`.
+func (r *renderer) newSubRenderer(width, height int) *renderer {
+	sub := newRenderer(width, height)
+	sub.ocProperties = r.ocProperties
+	sub.fonts, sub.glyphs, sub.tiles = r.fonts, r.glyphs, r.tiles
+	return sub
+}
+
+// fillPolygons fills `polys` with rule `rule` and the colors of `src`, with constant alpha
+// `alpha`, through clipping mask `clip`.
+func (r *renderer) fillPolygons(polys []polygon, rule fillRule, src paintSource, alpha float64, clip *image.Alpha) {
+	a := float32(alpha)
+	rasterize(polys, rule, r.width, r.height, func(y, x0, x1 int, cov []float32) {
+		for x := x0; x < x1; x++ {
+			if k := cov[x] * a * clipAlpha(clip, x, y); k > 0 {
+				r.blend(x, y, src.colorAt(x, y), k)
+			}
+		}
+	})
+}
+
+// blend composites color `c` with opacity `k` over pixel (`x`,`y`).
+func (r *renderer) blend(x, y int, c rgba, k float32) {
+	i := r.canvas.PixOffset(x, y)
+	pix := r.canvas.Pix[i : i+4 : i+4]
+	sa := c[3] * k
+	for j := range pix {
+		v := c[j]*k*255 + float32(pix[j])*(1-sa)
+		if v > 255 {
+			v = 255
+		} else if v < 0 {
+			v = 0
+		}
+		pix[j] = uint8(v + 0.5)
+	}
+}
+
+// isVisible returns true if the content controlled by optional content group or membership
+// dictionary `oc` is visible.
+func (r *renderer) isVisible(oc core.PdfObject) bool {
+	return isContentVisible(r.ocProperties, oc)
+}
+
+// isContentVisible returns true if the content controlled by optional content group or
+// membership dictionary `oc` is visible with optional content properties `ocProperties`.
+func isContentVisible(ocProperties *model.PdfOCProperties, oc core.PdfObject) bool {
+	if oc == nil || ocProperties == nil {
+		return true
+	}
+	content, err := ocProperties.GetOptionalContent(oc)
+	if err != nil {
+		common.Log.Debug("Invalid optional content - assuming visible: %v", err)
+		return true
+	}
+	return ocProperties.IsContentVisible(content)
+}
+
+// renderAnnotations draws the appearance streams of the annotations of `page` visible on screen,
+// where `device` maps the default user space of the page to the device space.
+func (r *renderer) renderAnnotations(page *model.PdfPage, device transform.Matrix) error {
+	appearances, err := getAppearances(page, r.ocProperties)
+	if err != nil {
+		return err
+	}
+	for _, ap := range appearances {
+		r.drawForm(ap.form, newGraphicsState(device.Mult(ap.matrix)), page.Resources, 0)
+	}
+	return nil
+}
+
+// appearance is an annotation appearance stream placed on a page.
+type appearance struct {
+	form   *model.XObjectForm
+	matrix transform.Matrix // Maps the form space (transformed by the form Matrix) to the page space.
+}
+
+// getAppearances returns the appearances of the annotations of `page` visible on screen with
+// optional content properties `ocProperties`.
+func getAppearances(page *model.PdfPage, ocProperties *model.PdfOCProperties) ([]appearance, error) {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	var appearances []appearance
+	for _, annot := range annotations {
+		if flags, ok := core.GetIntVal(annot.F); ok && flags&(annotationFlagHidden|annotationFlagNoView) != 0 {
+			continue
+		}
+		if !isContentVisible(ocProperties, annot.OC) {
+			continue
+		}
+		form, rect := annotationAppearance(annot)
+		if form == nil {
+			continue
+		}
+		// The appearance BBox, transformed by its Matrix, is mapped to the annotation Rect.
+		bbox, ok := getRectangle(form.BBox)
+		if !ok {
+			continue
+		}
+		matrix := transform.IdentityMatrix()
+		if m, ok := getMatrix(form.Matrix); ok {
+			matrix = m
+		}
+		x0, y0 := math.Inf(1), math.Inf(1)
+		x1, y1 := math.Inf(-1), math.Inf(-1)
+		for _, c := range []point{{bbox.Llx, bbox.Lly}, {bbox.Urx, bbox.Lly}, {bbox.Llx, bbox.Ury}, {bbox.Urx, bbox.Ury}} {
+			x, y := matrix.Transform(c.x, c.y)
+			x0, y0 = math.Min(x0, x), math.Min(y0, y)
+			x1, y1 = math.Max(x1, x), math.Max(y1, y)
+		}
+		if x1-x0 == 0 || y1-y0 == 0 {
+			continue
+		}
+		sx, sy := rect.Width()/(x1-x0), rect.Height()/(y1-y0)
+		appearances = append(appearances, appearance{
+			form:   form,
+			matrix: transform.NewMatrix(sx, 0, 0, sy, rect.Llx-x0*sx, rect.Lly-y0*sy),
+		})
+	}
+	return appearances, nil
+}
+
+// annotationAppearance returns the normal appearance of `annot` in its current appearance state
+// and its normalized rectangle, or nil if it has none.
+func annotationAppearance(annot *model.PdfAnnotation) (*model.XObjectForm, model.PdfRectangle) {
+	rect, ok := getRectangle(annot.Rect)
+	if !ok {
+		return nil, rect
+	}
+	ap, ok := core.GetDict(annot.AP)
+	if !ok {
+		return nil, rect
+	}
+	var stream *core.PdfObjectStream
+	switch n := core.TraceToDirectObject(ap.Get("N")).(type) {
+	case *core.PdfObjectStream:
+		stream = n
+	case *core.PdfObjectDictionary:
+		state, ok := core.GetName(annot.AS)
+		if !ok {
+			return nil, rect
+		}
+		stream, _ = core.GetStream(n.Get(*state))
+	}
+	if stream == nil {
+		return nil, rect
+	}
+	form, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid annotation appearance: %v", err)
+		return nil, rect
+	}
+	return form, rect
+}
@@ -0,0 +1,120 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makeRenderTestPage returns a 200×100 page with a filled rectangle, a stroked line, a clipped
+// fill, text and an image.
+func makeRenderTestPage(t *testing.T) *model.PdfPage {
+	content := `1 0 0 rg 10 10 50 30 re f
+0 0 1 RG 4 w 10 80 m 100 80 l S
+q 70 10 20 20 re W n 0 1 0 rg 60 0 40 40 re f Q
+0 g BT /F1 40 Tf 100 50 Td (H) Tj ET
+q 40 0 0 40 150 10 cm /Im1 Do Q
+`
+	page := pdftest.NewPage(t, 200, 100, content)
+
+	// 2×2 image: red, green (top row), blue, white (bottom row).
+	img := &model.Image{
+		Width:            2,
+		Height:           2,
+		BitsPerComponent: 8,
+		ColorComponents:  3,
+		Data:             []byte{255, 0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255},
+	}
+	ximg, err := model.NewXObjectImageFromImage(img, model.NewPdfColorspaceDeviceRGB(), core.NewRawEncoder())
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetXObjectByName("Im1", ximg.ToPdfObject().(*core.PdfObjectStream)))
+	return page
+}
+
+func TestRenderPage(t *testing.T) {
+	page := makeRenderTestPage(t)
+	img, err := RenderPage(page, nil)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 200, 100), img.Bounds())
+
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	for _, c := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{30, 75, red},    // Filled rectangle.
+		{9, 75, white},   // Outside of the rectangle.
+		{50, 20, blue},   // Stroked line.
+		{50, 23, white},  // Outside of the line width.
+		{80, 80, green},  // Inside of the clipping path.
+		{65, 80, white},  // Outside of the clipping path.
+		{105, 40, black}, // Stem of the H glyph.
+		{115, 28, white}, // Counter of the H glyph.
+		{160, 60, red},   // Image samples.
+		{180, 60, green},
+		{160, 80, blue},
+		{180, 80, white},
+	} {
+		require.Equal(t, c.color, img.RGBAAt(c.x, c.y), "pixel (%d,%d)", c.x, c.y)
+	}
+
+	// Resolution and rotation.
+	rotate := int64(90)
+	page.Rotate = &rotate
+	img, err = RenderPage(page, &Options{DPI: 144})
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 200, 400), img.Bounds())
+	require.Equal(t, red, img.RGBAAt(50, 60))
+	require.Equal(t, color.RGBA{}, img.RGBAAt(50, 10))
+}
+
+func TestRenderMeshShading(t *testing.T) {
+	// Free-form triangle mesh with red, green, blue and white corners covering the page.
+	mesh, err := core.MakeStream([]byte{
+		0, 0, 0, 255, 0, 0,
+		0, 100, 0, 0, 255, 0,
+		0, 0, 100, 0, 0, 255,
+		1, 100, 100, 255, 255, 255,
+	}, core.NewRawEncoder())
+	require.NoError(t, err)
+	mesh.Set("ShadingType", core.MakeInteger(4))
+	mesh.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	mesh.Set("BitsPerCoordinate", core.MakeInteger(8))
+	mesh.Set("BitsPerComponent", core.MakeInteger(8))
+	mesh.Set("BitsPerFlag", core.MakeInteger(8))
+	mesh.Set("Decode", core.MakeArrayFromFloats([]float64{0, 255, 0, 255, 0, 1, 0, 1, 0, 1}))
+
+	page := pdftest.NewPage(t, 100, 100, "/Sh1 sh")
+	require.NoError(t, page.Resources.SetShadingByName("Sh1", mesh))
+
+	img, err := RenderPage(page, nil)
+	require.NoError(t, err)
+	for _, c := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{10, 79, color.RGBA{176, 27, 52, 255}},   // Point (10.5,20.5) of the first triangle.
+		{79, 10, color.RGBA{176, 203, 228, 255}}, // Point (79.5,89.5) of the second triangle.
+	} {
+		got := img.RGBAAt(c.x, c.y)
+		for i, v := range []uint8{got.R, got.G, got.B, got.A} {
+			expected := []uint8{c.color.R, c.color.G, c.color.B, c.color.A}[i]
+			require.InDelta(t, expected, v, 2, "pixel (%d,%d)", c.x, c.y)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"errors"
+	"image"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// shadingSteps is the number of precomputed colors of axial and radial shadings.
+const shadingSteps = 256
+
+// rgba is a color with premultiplied alpha, with components in the range [0,1].
+type rgba [4]float32
+
+// paintSource provides the colors painted at the device pixels.
+type paintSource interface {
+	// colorAt returns the color painted at pixel (`x`,`y`).
+	colorAt(x, y int) rgba
+}
+
+// solidSource is a paint source painting a single color.
+type solidSource rgba
+
+// colorAt returns the color of `s`.
+func (s solidSource) colorAt(x, y int) rgba {
+	return rgba(s)
+}
+
+// newSolidSource returns an opaque paint source of color `rgb`.
+func newSolidSource(rgb [3]float64) solidSource {
+	return solidSource{float32(rgb[0]), float32(rgb[1]), float32(rgb[2]), 1}
+}
+
+// paint is a fill or stroke paint: a color or a pattern.
+type paint struct {
+	rgb     [3]float64        // Color, or color of uncolored tiling patterns.
+	pattern *model.PdfPattern // Pattern, nil for colors.
+	base    transform.Matrix  // Default coordinate space of the pattern parent content stream.
+}
+
+// makePaint returns the paint of color `col` in colorspace `cs`, where `base` is the initial CTM
+// of the content stream.
+func makePaint(cs model.PdfColorspace, col model.PdfColor, resources *model.PdfPageResources,
+	base transform.Matrix) paint {
+	if cs == nil || col == nil {
+		return paint{}
+	}
+	patternCS, ok := cs.(*model.PdfColorspaceSpecialPattern)
+	if !ok {
+		rgb, _ := colorToRGB(cs, col)
+		return paint{rgb: rgb}
+	}
+
+	patternColor, ok := col.(*model.PdfColorPattern)
+	if !ok || resources == nil {
+		return paint{}
+	}
+	pattern, found := resources.GetPatternByName(patternColor.PatternName)
+	if !found {
+		common.Log.Debug("ERROR: Pattern %s not found", patternColor.PatternName)
+		return paint{}
+	}
+	p := paint{pattern: pattern, base: base}
+	if patternCS.UnderlyingCS != nil && patternColor.Color != nil {
+		p.rgb, _ = colorToRGB(patternCS.UnderlyingCS, patternColor.Color)
+	}
+	return p
+}
+
+// paintSource returns the paint source painting `p`.
+func (sr *streamRenderer) paintSource(p paint) paintSource {
+	if p.pattern == nil {
+		return newSolidSource(p.rgb)
+	}
+	if p.pattern.IsShading() {
+		sp := p.pattern.GetAsShadingPattern()
+		m := p.base
+		if sp.Matrix != nil {
+			if pm, ok := getMatrix(sp.Matrix); ok {
+				m = m.Mult(pm)
+			}
+		}
+		if sp.Shading == nil {
+			return solidSource{}
+		}
+		src, err := newShadingSource(sp.Shading, m, true)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to paint shading pattern: %v", err)
+			return solidSource{}
+		}
+		return src
+	}
+	if p.pattern.IsTiling() {
+		tp := p.pattern.GetAsTilingPattern()
+		m := p.base
+		if tp.Matrix != nil {
+			if pm, ok := getMatrix(tp.Matrix); ok {
+				m = m.Mult(pm)
+			}
+		}
+		src, err := sr.r.newTileSource(tp, m, p.rgb, sr.depth)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to paint tiling pattern: %v", err)
+			return solidSource{}
+		}
+		return src
+	}
+	return solidSource{}
+}
+
+// drawShading paints the shading of the sh operation `op` over the clipping region.
+func (sr *streamRenderer) drawShading(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	shading, found := resources.GetShadingByName(*name)
+	if !found {
+		common.Log.Debug("ERROR: Shading %s not found", *name)
+		return
+	}
+	src, err := newShadingSource(shading, sr.gs.ctm, false)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to paint shading %s: %v", *name, err)
+		return
+	}
+	w, h := float64(sr.r.width), float64(sr.r.height)
+	page := polygon{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	sr.r.fillPolygons([]polygon{page}, nonZero, src, sr.gs.fillAlpha, sr.gs.clip)
+}
+
+// shadingSource is a paint source painting a shading.
+type shadingSource struct {
+	inverse    transform.Matrix // Maps the device space to the shading space.
+	bbox       *model.PdfRectangle
+	background *rgba
+	color      func(x, y float64) (rgba, bool)
+}
+
+// newShadingSource returns a paint source painting `shading` where `m` maps the shading space
+// to the device space. The background color of the shading is painted outside of the shading
+// if `background` is true (shading patterns).
+func newShadingSource(shading *model.PdfShading, m transform.Matrix, background bool) (*shadingSource, error) {
+	inverse, ok := m.Inverse()
+	if !ok {
+		return nil, errors.New("shading space not invertible")
+	}
+	cs := shading.ColorSpace
+	if cs == nil {
+		return nil, errors.New("shading colorspace missing")
+	}
+	src := &shadingSource{inverse: inverse}
+	if shading.BBox != nil {
+		bbox := shading.BBox.Normalized()
+		src.bbox = &bbox
+	}
+	if background && shading.Background != nil {
+		vals, err := shading.Background.ToFloat64Array()
+		if err == nil {
+			if c, ok := componentsToRGBA(cs, vals); ok {
+				src.background = &c
+			}
+		}
+	}
+
+	// Axial and radial shadings use precomputed colors.
+	switch t := shading.GetContext().(type) {
+	case *model.PdfShadingType2:
+		coords, domain, extend, err := t.GradientParameters()
+		if err != nil {
+			return nil, err
+		}
+		lut := shadingLUT(cs, t.Function, domain)
+		x0, y0, x1, y1 := coords[0], coords[1], coords[2], coords[3]
+		dx, dy := x1-x0, y1-y0
+		den := dx*dx + dy*dy
+		src.color = func(x, y float64) (rgba, bool) {
+			s := 0.0
+			if den != 0 {
+				s = ((x-x0)*dx + (y-y0)*dy) / den
+			}
+			return lookupShading(lut, s, extend)
+		}
+	case *model.PdfShadingType3:
+		coords, domain, extend, err := t.GradientParameters()
+		if err != nil {
+			return nil, err
+		}
+		lut := shadingLUT(cs, t.Function, domain)
+		src.color = func(x, y float64) (rgba, bool) {
+			s, ok := model.RadialShadingParameter(coords, x, y, extend)
+			if !ok {
+				return rgba{}, false
+			}
+			return lookupShading(lut, s, extend)
+		}
+	default:
+		// Function-based and mesh shadings.
+		e, err := model.NewShadingEvaluator(shading)
+		if err != nil {
+			return nil, err
+		}
+		src.color = func(x, y float64) (rgba, bool) {
+			vals, ok := e.Evaluate(x, y)
+			if !ok {
+				return rgba{}, false
+			}
+			return componentsToRGBA(cs, vals)
+		}
+	}
+	return src, nil
+}
+
+// colorAt returns the color of the shading at pixel (`x`,`y`).
+func (s *shadingSource) colorAt(x, y int) rgba {
+	sx, sy := s.inverse.Transform(float64(x)+0.5, float64(y)+0.5)
+	if s.bbox != nil && (sx < s.bbox.Llx || sx > s.bbox.Urx || sy < s.bbox.Lly || sy > s.bbox.Ury) {
+		return rgba{}
+	}
+	if c, ok := s.color(sx, sy); ok {
+		return c
+	}
+	if s.background != nil {
+		return *s.background
+	}
+	return rgba{}
+}
+
+// shadingLUT returns the colors of the shading with functions `funcs` in colorspace `cs` at
+// shadingSteps parameter values evenly spaced in `domain`.
+func shadingLUT(cs model.PdfColorspace, funcs []model.PdfFunction, domain [2]float64) []rgba {
+	lut := make([]rgba, shadingSteps)
+	for i := range lut {
+		t := domain[0] + (domain[1]-domain[0])*float64(i)/(shadingSteps-1)
+		lut[i], _ = evaluateShading(cs, funcs, []float64{t})
+	}
+	return lut
+}
+
+// lookupShading returns the color of parameter `s` (in the range [0,1] within the shading) of
+// shading colors `lut`, where the shading is extended before 0 and after 1 as specified by
+// `extend`.
+func lookupShading(lut []rgba, s float64, extend [2]bool) (rgba, bool) {
+	if s < 0 {
+		if !extend[0] {
+			return rgba{}, false
+		}
+		s = 0
+	} else if s > 1 {
+		if !extend[1] {
+			return rgba{}, false
+		}
+		s = 1
+	}
+	return lut[int(s*(shadingSteps-1)+0.5)], true
+}
+
+// evaluateShading returns the color of the shading with functions `funcs` in colorspace `cs`
+// for input values `in`.
+func evaluateShading(cs model.PdfColorspace, funcs []model.PdfFunction, in []float64) (rgba, bool) {
+	var vals []float64
+	switch len(funcs) {
+	case 0:
+		vals = in
+	case 1:
+		out, err := funcs[0].Evaluate(in)
+		if err != nil {
+			return rgba{}, false
+		}
+		vals = out
+	default:
+		// One function per color component.
+		for _, f := range funcs {
+			out, err := f.Evaluate(in)
+			if err != nil || len(out) == 0 {
+				return rgba{}, false
+			}
+			vals = append(vals, out[0])
+		}
+	}
+	return componentsToRGBA(cs, vals)
+}
+
+// componentsToRGBA returns the opaque color of components `vals` in colorspace `cs`.
+func componentsToRGBA(cs model.PdfColorspace, vals []float64) (rgba, bool) {
+	n := cs.GetNumComponents()
+	if len(vals) < n {
+		return rgba{}, false
+	}
+	vals = append([]float64{}, vals[:n]...)
+	if decode := cs.DecodeArray(); len(decode) == 2*n {
+		for i := range vals {
+			vals[i] = math.Max(decode[2*i], math.Min(decode[2*i+1], vals[i]))
+		}
+	}
+	col, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		return rgba{}, false
+	}
+	rgb, ok := colorToRGB(cs, col)
+	if !ok {
+		return rgba{}, false
+	}
+	return rgba{float32(rgb[0]), float32(rgb[1]), float32(rgb[2]), 1}, true
+}
+
+// colorToRGB returns the RGB components of color `col` in colorspace `cs`.
+func colorToRGB(cs model.PdfColorspace, col model.PdfColor) ([3]float64, bool) {
+	rgbColor, err := cs.ColorToRGB(col)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to convert color %v to RGB: %v", col, err)
+		return [3]float64{}, false
+	}
+	rgb, ok := rgbColor.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return [3]float64{}, false
+	}
+	clamp := func(v float64) float64 {
+		return math.Max(0, math.Min(1, v))
+	}
+	return [3]float64{clamp(rgb.R()), clamp(rgb.G()), clamp(rgb.B())}, true
+}
+
+// tileKey identifies a rendered tiling pattern cell.
+type tileKey struct {
+	pattern core.PdfObject
+	matrix  transform.Matrix
+}
+
+// tileSource is a paint source painting a tiling pattern.
+type tileSource struct {
+	inverse      transform.Matrix // Maps the device space to the pattern space.
+	toTile       transform.Matrix // Maps the pattern space to the pixels of `tile`.
+	tile         *image.RGBA      // Rendered pattern cell.
+	bbox         model.PdfRectangle
+	xstep, ystep float64
+
+	// Color of uncolored tiling patterns, nil for colored ones.
+	color *rgba
+}
+
+// newTileSource returns a paint source painting tiling pattern `tp` with color `rgb` if it is
+// uncolored, where `m` maps the pattern space to the device space.
+func (r *renderer) newTileSource(tp *model.PdfTilingPattern, m transform.Matrix, rgb [3]float64,
+	depth int) (*tileSource, error) {
+	if tp.BBox == nil || tp.XStep == nil || tp.YStep == nil {
+		return nil, errors.New("tiling pattern parameters missing")
+	}
+	bbox := tp.BBox.Normalized()
+	if bbox.Width() == 0 || bbox.Height() == 0 {
+		return nil, errors.New("invalid tiling pattern bbox")
+	}
+	xstep, ystep := math.Abs(float64(*tp.XStep)), math.Abs(float64(*tp.YStep))
+	if xstep == 0 || ystep == 0 {
+		return nil, errors.New("invalid tiling pattern steps")
+	}
+	inverse, ok := m.Inverse()
+	if !ok {
+		return nil, errors.New("pattern space not invertible")
+	}
+
+	// Render the cell at the device resolution.
+	sx, sy := m.ScalingFactorX(), m.ScalingFactorY()
+	w, h := math.Ceil(bbox.Width()*sx), math.Ceil(bbox.Height()*sy)
+	if w > maxTileSize {
+		sx *= maxTileSize / w
+		w = maxTileSize
+	}
+	if h > maxTileSize {
+		sy *= maxTileSize / h
+		h = maxTileSize
+	}
+	w, h = math.Max(w, 1), math.Max(h, 1)
+	toTile := transform.NewMatrix(sx, 0, 0, -sy, -bbox.Llx*sx, bbox.Ury*sy)
+
+	src := &tileSource{
+		inverse: inverse,
+		toTile:  toTile,
+		bbox:    bbox,
+		xstep:   xstep,
+		ystep:   ystep,
+	}
+	if !tp.IsColored() {
+		c := rgba{float32(rgb[0]), float32(rgb[1]), float32(rgb[2]), 1}
+		src.color = &c
+	}
+
+	key := tileKey{tp.GetContainingPdfObject(), toTile}
+	if tile, ok := r.tiles[key]; ok {
+		src.tile = tile
+		return src, nil
+	}
+	content, err := tp.GetContentStream()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	sub := r.newSubRenderer(int(w), int(h))
+	sub.renderContent(*ops, tp.Resources, newGraphicsState(toTile), depth+1)
+	r.tiles[key] = sub.canvas
+	src.tile = sub.canvas
+	return src, nil
+}
+
+// colorAt returns the color of the pattern at pixel (`x`,`y`).
+func (s *tileSource) colorAt(x, y int) rgba {
+	px, py := s.inverse.Transform(float64(x)+0.5, float64(y)+0.5)
+	i0 := math.Ceil((px - s.bbox.Urx) / s.xstep)
+	i1 := math.Floor((px - s.bbox.Llx) / s.xstep)
+	j0 := math.Ceil((py - s.bbox.Ury) / s.ystep)
+	j1 := math.Floor((py - s.bbox.Lly) / s.ystep)
+	// Only the cells drawn last matter when they overlap.
+	i0, j0 = math.Max(i0, i1-1), math.Max(j0, j1-1)
+
+	var out rgba
+	bounds := s.tile.Bounds()
+	for j := j0; j <= j1; j++ {
+		for i := i0; i <= i1; i++ {
+			tx, ty := s.toTile.Transform(px-i*s.xstep, py-j*s.ystep)
+			ix, iy := int(math.Floor(tx)), int(math.Floor(ty))
+			if ix < 0 || iy < 0 || ix >= bounds.Dx() || iy >= bounds.Dy() {
+				continue
+			}
+			pix := s.tile.Pix[s.tile.PixOffset(ix, iy):]
+			c := rgba{float32(pix[0]) / 255, float32(pix[1]) / 255, float32(pix[2]) / 255, float32(pix[3]) / 255}
+			if s.color != nil {
+				a := c[3]
+				c = rgba{s.color[0] * a, s.color[1] * a, s.color[2] * a, a}
+			}
+			for k := range out {
+				out[k] = c[k] + out[k]*(1-c[3])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,292 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"math"
+)
+
+// Line cap styles (section 8.4.3.3 p. 125).
+const (
+	capButt = iota
+	capRound
+	capSquare
+)
+
+// Line join styles (section 8.4.3.4 p. 126).
+const (
+	joinMiter = iota
+	joinRound
+	joinBevel
+)
+
+// strokeStyle holds the line parameters of the graphics state used for stroking, in user space.
+type strokeStyle struct {
+	width      float64
+	cap        int
+	join       int
+	miterLimit float64
+	dash       []float64
+	dashPhase  float64
+}
+
+// strokePolygons returns the polygons, in user space, covering the stroke of `subpaths` with
+// style `style`. Curves are approximated within tolerance `tol`. The polygons all have the same
+// orientation so that they must be filled with the nonzero winding number rule.
+func strokePolygons(subpaths []subpath, style strokeStyle, tol float64) []polygon {
+	subpaths = dashSubpaths(subpaths, style.dash, style.dashPhase)
+	hw := style.width / 2
+	var polys []polygon
+	add := func(poly polygon) {
+		if signedArea(poly) < 0 {
+			for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+				poly[i], poly[j] = poly[j], poly[i]
+			}
+		}
+		polys = append(polys, poly)
+	}
+
+	for _, sp := range subpaths {
+		if len(sp.points) < 2 {
+			// A lone move does not paint anything.
+			continue
+		}
+		pts := dedupPoints(sp.points)
+		closed := sp.closed
+		if closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+			pts = pts[:len(pts)-1]
+		}
+		if len(pts) == 1 {
+			// Zero length subpath: only round and square caps are painted.
+			p := pts[0]
+			switch style.cap {
+			case capRound:
+				add(circlePolygon(p, hw, tol))
+			case capSquare:
+				add(polygon{{p.x - hw, p.y - hw}, {p.x + hw, p.y - hw}, {p.x + hw, p.y + hw}, {p.x - hw, p.y + hw}})
+			}
+			continue
+		}
+		if len(pts) == 2 {
+			closed = false
+		}
+
+		n := len(pts)
+		numSegments := n - 1
+		if closed {
+			numSegments = n
+		}
+		for i := 0; i < numSegments; i++ {
+			p, q := pts[i], pts[(i+1)%n]
+			nx, ny := normal(p, q, hw)
+			add(polygon{{p.x + nx, p.y + ny}, {q.x + nx, q.y + ny}, {q.x - nx, q.y - ny}, {p.x - nx, p.y - ny}})
+		}
+
+		// Joins.
+		for i := 0; i < n; i++ {
+			if !closed && (i == 0 || i == n-1) {
+				continue
+			}
+			prev, p, next := pts[(i+n-1)%n], pts[i], pts[(i+1)%n]
+			if join := joinPolygon(prev, p, next, hw, style, tol); join != nil {
+				add(join)
+			}
+		}
+
+		// Caps.
+		if !closed {
+			for _, end := range [][2]point{{pts[1], pts[0]}, {pts[n-2], pts[n-1]}} {
+				from, p := end[0], end[1]
+				switch style.cap {
+				case capRound:
+					add(circlePolygon(p, hw, tol))
+				case capSquare:
+					dx, dy := direction(from, p)
+					nx, ny := -dy*hw, dx*hw
+					ex, ey := p.x+dx*hw, p.y+dy*hw
+					add(polygon{{p.x + nx, p.y + ny}, {ex + nx, ey + ny}, {ex - nx, ey - ny}, {p.x - nx, p.y - ny}})
+				}
+			}
+		}
+	}
+	return polys
+}
+
+// joinPolygon returns the polygon filling the join at `p` of the segments from `prev` and to
+// `next` with half width `hw`, or nil if there is nothing to fill.
+func joinPolygon(prev, p, next point, hw float64, style strokeStyle, tol float64) polygon {
+	d0x, d0y := direction(prev, p)
+	d1x, d1y := direction(p, next)
+	cross := d0x*d1y - d0y*d1x
+	dot := d0x*d1x + d0y*d1y
+	if math.Abs(cross) < 1e-12 && dot > 0 {
+		// Collinear segments.
+		return nil
+	}
+	if style.join == joinRound {
+		return circlePolygon(p, hw, tol)
+	}
+
+	// The join is on the outer side of the turn.
+	side := 1.0
+	if cross > 0 {
+		side = -1
+	}
+	n0x, n0y := -d0y*hw*side, d0x*hw*side
+	n1x, n1y := -d1y*hw*side, d1x*hw*side
+	a := point{p.x + n0x, p.y + n0y}
+	b := point{p.x + n1x, p.y + n1y}
+
+	if style.join == joinMiter {
+		// The miter length divided by the line width is 1/sin(θ/2) where θ is the angle
+		// between the segments, i.e. 1/cos(φ/2) where φ is the turning angle.
+		cosHalf := math.Sqrt(math.Max((1+dot)/2, 0))
+		if cosHalf > 1e-9 && 1/cosHalf <= style.miterLimit {
+			mx, my := n0x+n1x, n0y+n1y
+			l := math.Hypot(mx, my)
+			if l > 0 {
+				m := hw / cosHalf / l
+				tip := point{p.x + mx*m, p.y + my*m}
+				return polygon{p, a, tip, b}
+			}
+		}
+	}
+	return polygon{p, a, b}
+}
+
+// dashSubpaths returns the pieces of `subpaths` painted with dash pattern `dash` and phase
+// `phase`. Returns `subpaths` if the dash pattern is empty (solid line).
+func dashSubpaths(subpaths []subpath, dash []float64, phase float64) []subpath {
+	total := 0.0
+	for _, d := range dash {
+		if d < 0 {
+			return subpaths
+		}
+		total += d
+	}
+	if len(dash) == 0 || total <= 0 {
+		return subpaths
+	}
+	if len(dash)%2 == 1 {
+		dash = append(append([]float64{}, dash...), dash...)
+		total *= 2
+	}
+
+	// Initial position in the dash pattern.
+	phase = math.Mod(phase, total)
+	if phase < 0 {
+		phase += total
+	}
+	index0 := 0
+	for phase > 0 && phase >= dash[index0] {
+		phase -= dash[index0]
+		index0 = (index0 + 1) % len(dash)
+	}
+	remaining0 := dash[index0] - phase
+
+	var pieces []subpath
+	for _, sp := range subpaths {
+		pts := sp.points
+		if len(pts) < 2 {
+			continue
+		}
+		if sp.closed {
+			pts = append(append([]point{}, pts...), pts[0])
+		}
+		index, remaining := index0, remaining0
+		on := index%2 == 0
+		var piece []point
+		if on {
+			piece = []point{pts[0]}
+		}
+		for i := 0; i+1 < len(pts); i++ {
+			p, q := pts[i], pts[i+1]
+			length := math.Hypot(q.x-p.x, q.y-p.y)
+			t := 0.0
+			for length-t > remaining {
+				t += remaining
+				s := t / length
+				split := point{p.x + (q.x-p.x)*s, p.y + (q.y-p.y)*s}
+				if on {
+					pieces = append(pieces, subpath{points: append(piece, split)})
+					piece = nil
+				} else {
+					piece = []point{split}
+				}
+				on = !on
+				index = (index + 1) % len(dash)
+				remaining = dash[index]
+			}
+			remaining -= length - t
+			if on {
+				piece = append(piece, q)
+			}
+		}
+		if on && len(piece) > 0 {
+			pieces = append(pieces, subpath{points: piece})
+		}
+	}
+	return pieces
+}
+
+// circlePolygon returns a polygon approximating the circle of center `c` and radius `r` within
+// tolerance `tol`.
+func circlePolygon(c point, r, tol float64) polygon {
+	n := 8
+	if r > tol {
+		n = int(math.Ceil(math.Pi / math.Acos(1-tol/r)))
+	}
+	if n < 8 {
+		n = 8
+	} else if n > 256 {
+		n = 256
+	}
+	poly := make(polygon, n)
+	for i := range poly {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		poly[i] = point{c.x + r*math.Cos(a), c.y + r*math.Sin(a)}
+	}
+	return poly
+}
+
+// direction returns the unit vector from `p` to `q`.
+func direction(p, q point) (float64, float64) {
+	dx, dy := q.x-p.x, q.y-p.y
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return 1, 0
+	}
+	return dx / l, dy / l
+}
+
+// normal returns the left normal of the segment from `p` to `q` with length `hw`.
+func normal(p, q point, hw float64) (float64, float64) {
+	dx, dy := direction(p, q)
+	return -dy * hw, dx * hw
+}
+
+// dedupPoints returns `pts` without the consecutive duplicate points.
+func dedupPoints(pts []point) []point {
+	out := make([]point, 0, len(pts))
+	for _, p := range pts {
+		if len(out) > 0 && math.Abs(p.x-out[len(out)-1].x) < 1e-9 && math.Abs(p.y-out[len(out)-1].y) < 1e-9 {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// signedArea returns the signed area of `poly`, positive for counterclockwise polygons in a
+// y-up coordinate system.
+func signedArea(poly polygon) float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.x*q.y - q.x*p.y
+	}
+	return area / 2
+}
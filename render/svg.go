@@ -0,0 +1,685 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// SVGOptions define the options of SVG export.
+type SVGOptions struct {
+	// Annotations specifies whether the appearance streams of the annotations are drawn.
+	Annotations bool
+
+	// TextAsPaths specifies whether text is drawn with the outlines of the glyphs instead of text
+	// elements. The outlines look the same in all viewers but the text cannot be selected.
+	TextAsPaths bool
+
+	// EmbedFonts specifies whether the TrueType and OpenType font programs embedded in the PDF
+	// are embedded in the SVG for the text elements. Otherwise the text elements use the
+	// installed fonts closest to the PDF fonts.
+	// The visible text in subset and symbolic fonts with such programs is drawn with the outlines
+	// of the glyphs, as the character maps of these fonts may not map the text to the glyphs.
+	EmbedFonts bool
+
+	// ShadingDPI is the resolution in pixels per inch of the images replacing the shadings
+	// which have no SVG equivalent (function-based shadings, and radial shadings whose start
+	// circle is not inside the end circle).
+	ShadingDPI float64
+
+	// Background is the color of the page drawn below the content. The page is transparent if nil.
+	Background color.Color
+}
+
+// NewSVGOptions returns the default SVG export options: text elements with the embedded fonts,
+// annotations, shadings rasterized at 150 DPI, on a white background.
+func NewSVGOptions() *SVGOptions {
+	return &SVGOptions{
+		Annotations: true,
+		EmbedFonts:  true,
+		ShadingDPI:  150,
+		Background:  color.White,
+	}
+}
+
+// WritePageSVG writes `page` as an SVG image to `w` with options `opts` (the default options if
+// nil). The image shows the crop box of the page, rotated as specified by the Rotate entry of the
+// page, with one SVG user unit per point.
+func WritePageSVG(w io.Writer, page *model.PdfPage, opts *SVGOptions) error {
+	if opts == nil {
+		opts = NewSVGOptions()
+	}
+	if opts.ShadingDPI <= 0 {
+		return errors.New("invalid shading resolution")
+	}
+	box, err := page.GetCropBox()
+	if err != nil {
+		return err
+	}
+	rotate, err := page.GetRotate()
+	if err != nil {
+		return err
+	}
+	width, height := box.Width(), box.Height()
+	device := deviceMatrix(*box, rotate, 1, width, height)
+	if rotate == 90 || rotate == 270 {
+		width, height = height, width
+	}
+
+	sw := newSVGWriter(opts)
+	sw.ocProperties = getOCProperties(page)
+	ops, err := parsePageContent(page)
+	if err != nil {
+		return err
+	}
+	body := &svgBody{}
+	if opts.Background != nil {
+		body.write("", fmt.Sprintf(`<rect width="%s" height="%s" fill="%s"/>`, svgNumber(width),
+			svgNumber(height), svgColor(colorToFloats(opts.Background))))
+	}
+	bounds := model.PdfRectangle{Urx: width, Ury: height}
+	sw.writeContent(*ops, page.Resources, newGraphicsState(device), "", body, bounds, nil, 0)
+	if opts.Annotations {
+		appearances, err := getAppearances(page, sw.ocProperties)
+		if err != nil {
+			return err
+		}
+		for _, ap := range appearances {
+			sw.writeForm(ap.form, newGraphicsState(device.Mult(ap.matrix)), "", body, page.Resources, bounds,
+				nil, 0)
+		}
+	}
+	body.close()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" `+
+		`version="1.1" width="%spt" height="%spt" viewBox="0 0 %s %s">`+"\n",
+		svgNumber(width), svgNumber(height), svgNumber(width), svgNumber(height))
+	if sw.fontFaces.Len() > 0 || sw.defs.Len() > 0 {
+		out.WriteString("<defs>\n")
+		if sw.fontFaces.Len() > 0 {
+			out.WriteString("<style type=\"text/css\"><![CDATA[\n")
+			out.Write(sw.fontFaces.Bytes())
+			out.WriteString("]]></style>\n")
+		}
+		out.Write(sw.defs.Bytes())
+		out.WriteString("</defs>\n")
+	}
+	out.Write(body.buf.Bytes())
+	out.WriteString("</svg>\n")
+	return out.Flush()
+}
+
+// svgWriter converts content streams to SVG elements.
+type svgWriter struct {
+	opts         *SVGOptions
+	ocProperties *model.PdfOCProperties
+
+	defs      bytes.Buffer // Definitions of the referenced elements.
+	fontFaces bytes.Buffer // Style sheet of the embedded fonts.
+	lastID    int
+
+	fonts    textstate.FontCache
+	glyphs   map[glyphKey]*model.GlyphPath
+	families map[*model.PdfFont]string // Font family attributes.
+	outlined map[*model.PdfFont]bool   // Fonts drawn with the glyph outlines.
+	images   map[svgImageKey]string    // Identifiers of the image elements.
+	cells    map[svgCellKey]string     // Identifiers of the tiling pattern cells.
+	servers  map[svgServerKey]string   // Identifiers of the paint servers.
+	embedded map[core.PdfObject]string // Font families of the embedded font programs.
+}
+
+// newSVGWriter returns an SVG writer with options `opts`.
+func newSVGWriter(opts *SVGOptions) *svgWriter {
+	return &svgWriter{
+		opts:     opts,
+		fonts:    textstate.FontCache{},
+		glyphs:   map[glyphKey]*model.GlyphPath{},
+		families: map[*model.PdfFont]string{},
+		outlined: map[*model.PdfFont]bool{},
+		images:   map[svgImageKey]string{},
+		cells:    map[svgCellKey]string{},
+		servers:  map[svgServerKey]string{},
+		embedded: map[core.PdfObject]string{},
+	}
+}
+
+// newID returns a new element identifier starting with `prefix`.
+func (w *svgWriter) newID(prefix string) string {
+	w.lastID++
+	return prefix + strconv.Itoa(w.lastID)
+}
+
+// addClip defines a clipping path intersecting the clipping path `clip` (none if empty) with the
+// region of `element` and returns its identifier.
+func (w *svgWriter) addClip(clip, element string) string {
+	id := w.newID("c")
+	fmt.Fprintf(&w.defs, `<clipPath id="%s"%s>%s</clipPath>`+"\n", id, clipAttr(clip), element)
+	return id
+}
+
+// svgBody is a sequence of SVG elements, grouped by clipping path.
+type svgBody struct {
+	buf  bytes.Buffer
+	clip string // Clipping path of the open group, empty if there is none.
+}
+
+// write adds `element` clipped by clipping path `clip` (none if empty).
+func (b *svgBody) write(clip, element string) {
+	if clip != b.clip {
+		b.close()
+		if clip != "" {
+			fmt.Fprintf(&b.buf, `<g clip-path="url(#%s)">`+"\n", clip)
+		}
+		b.clip = clip
+	}
+	b.buf.WriteString(element)
+	b.buf.WriteByte('\n')
+}
+
+// close closes the open group.
+func (b *svgBody) close() {
+	if b.clip != "" {
+		b.buf.WriteString("</g>\n")
+		b.clip = ""
+	}
+}
+
+// svgState is the graphics state of SVG output with its clipping path.
+type svgState struct {
+	gs   graphicsState
+	clip string
+}
+
+// svgStreamWriter converts a content stream to SVG elements.
+type svgStreamWriter struct {
+	w         *svgWriter
+	body      *svgBody
+	proc      *contentstream.ContentStreamProcessor
+	resources *model.PdfPageResources
+	depth     int                // Nesting depth of Form XObjects and patterns.
+	base      transform.Matrix   // Initial CTM of the content stream.
+	bounds    model.PdfRectangle // Device space region drawn.
+	color     *[3]float64        // Color of uncolored tiling pattern cells, nil otherwise.
+
+	gs       graphicsState
+	clipPath string // Clipping path, empty if there is none.
+	stack    []svgState
+	path     svgPath
+	clip     bool // Clipping path operator pending.
+	rule     fillRule
+
+	// Text object state.
+	text     textstate.Object
+	textClip []string
+	clipText bool
+}
+
+// writeContent writes the SVG elements of the operations `ops` with resources `resources`,
+// initial graphics state `gs` and clipping path `clip` to `body`. `bounds` is the region of the
+// device space drawn and `color` the color of uncolored tiling pattern cells (nil otherwise).
+// `depth` is the nesting depth of the content stream.
+func (w *svgWriter) writeContent(ops contentstream.ContentStreamOperations, resources *model.PdfPageResources,
+	gs graphicsState, clip string, body *svgBody, bounds model.PdfRectangle, color *[3]float64, depth int) {
+	if depth > maxNesting {
+		common.Log.Debug("ERROR: Content nesting too deep - skipping")
+		return
+	}
+	if color != nil {
+		gs.fill, gs.stroke = paint{rgb: *color}, paint{rgb: *color}
+	}
+	ssw := &svgStreamWriter{
+		w:         w,
+		body:      body,
+		proc:      contentstream.NewContentStreamProcessor(ops),
+		resources: resources,
+		depth:     depth,
+		base:      gs.ctm,
+		bounds:    bounds,
+		color:     color,
+		gs:        gs,
+		clipPath:  clip,
+	}
+	ssw.proc.SetOptionalContent(w.ocProperties, nil)
+	ssw.proc.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, pgs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			ssw.handle(op, pgs, resources)
+			return nil
+		})
+	if err := ssw.proc.Process(resources); err != nil {
+		common.Log.Debug("ERROR: Unable to process content stream: %v", err)
+	}
+}
+
+// handle writes the SVG elements of operation `op`, with the colors of the processor graphics
+// state `pgs`.
+func (ssw *svgStreamWriter) handle(op *contentstream.ContentStreamOperation, pgs contentstream.GraphicsState,
+	resources *model.PdfPageResources) {
+	visible := ssw.proc.IsContentVisible()
+	gs := &ssw.gs
+	switch op.Operand {
+	case "q":
+		ssw.stack = append(ssw.stack, svgState{ssw.gs, ssw.clipPath})
+	case "Q":
+		if n := len(ssw.stack); n > 0 {
+			ssw.gs, ssw.clipPath = ssw.stack[n-1].gs, ssw.stack[n-1].clip
+			ssw.stack = ssw.stack[:n-1]
+		}
+	case "cm":
+		if f, ok := textstate.FloatParams(op, 6); ok {
+			gs.ctm.Concat(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	case "w":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineWidth = f[0]
+		}
+	case "J":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineCap = int(f[0])
+		}
+	case "j":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.lineJoin = int(f[0])
+		}
+	case "M":
+		if f, ok := textstate.FloatParams(op, 1); ok {
+			gs.miterLimit = f[0]
+		}
+	case "d":
+		if len(op.Params) == 2 {
+			gs.dash, gs.dashPhase = getDash(op.Params[0], op.Params[1])
+		}
+	case "gs":
+		setExtGState(gs, op, resources, ssw.w.fonts)
+
+	// Colors. The colors of uncolored tiling pattern cells are those of the pattern.
+	case "g", "rg", "k", "cs", "sc", "scn":
+		if ssw.color == nil {
+			gs.fill = makePaint(pgs.ColorspaceNonStroking, pgs.ColorNonStroking, resources, ssw.base)
+		}
+	case "G", "RG", "K", "CS", "SC", "SCN":
+		if ssw.color == nil {
+			gs.stroke = makePaint(pgs.ColorspaceStroking, pgs.ColorStroking, resources, ssw.base)
+		}
+
+	// Path construction.
+	case "m":
+		if f, ok := textstate.FloatParams(op, 2); ok {
+			ssw.path.moveTo(point{f[0], f[1]})
+		}
+	case "l":
+		if f, ok := textstate.FloatParams(op, 2); ok {
+			ssw.path.lineTo(point{f[0], f[1]})
+		}
+	case "c":
+		if f, ok := textstate.FloatParams(op, 6); ok {
+			ssw.path.cubeTo(point{f[0], f[1]}, point{f[2], f[3]}, point{f[4], f[5]})
+		}
+	case "v":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			ssw.path.cubeTo(ssw.path.cur, point{f[0], f[1]}, point{f[2], f[3]})
+		}
+	case "y":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			ssw.path.cubeTo(point{f[0], f[1]}, point{f[2], f[3]}, point{f[2], f[3]})
+		}
+	case "h":
+		ssw.path.close()
+	case "re":
+		if f, ok := textstate.FloatParams(op, 4); ok {
+			ssw.path.rect(f[0], f[1], f[2], f[3])
+		}
+
+	// Path painting and clipping.
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+		ssw.paintPath(op.Operand, visible)
+	case "W":
+		ssw.clip, ssw.rule = true, nonZero
+	case "W*":
+		ssw.clip, ssw.rule = true, evenOdd
+
+	// Text.
+	case "BT":
+		ssw.text.Begin()
+		ssw.textClip, ssw.clipText = nil, false
+	case "ET":
+		if ssw.clipText {
+			ssw.clipPath = ssw.w.addClip(ssw.clipPath, strings.Join(ssw.textClip, ""))
+			ssw.textClip, ssw.clipText = nil, false
+		}
+	case "Tc", "Tw", "Tz", "TL", "Tf", "Tr", "Ts", "Td", "TD", "Tm", "T*", "Tj", "TJ", "'", `"`:
+		args, show := ssw.text.Handle(op, &gs.text, func(name core.PdfObjectName) *model.PdfFont {
+			return ssw.w.fonts.Get(resources, name)
+		})
+		if show {
+			ssw.showText(args, visible)
+		}
+
+	// XObjects, inline images and shadings.
+	case "Do":
+		if visible {
+			ssw.drawXObject(op, resources)
+		}
+	case "BI":
+		if visible {
+			ssw.drawInlineImage(op, resources)
+		}
+	case "sh":
+		if visible {
+			ssw.drawShading(op, resources)
+		}
+	}
+}
+
+// paintPath writes the current path painted with painting operator `operand`, if `visible`, and
+// intersects the clipping path with it if a clipping operator is pending.
+func (ssw *svgStreamWriter) paintPath(operand string, visible bool) {
+	switch operand {
+	case "s", "b", "b*":
+		ssw.path.close()
+	}
+	d := ssw.path.String()
+	if visible && d != "" {
+		var attrs string
+		switch operand {
+		case "f", "F":
+			attrs = ssw.fillAttrs(nonZero)
+		case "f*":
+			attrs = ssw.fillAttrs(evenOdd)
+		case "B", "b":
+			attrs = ssw.fillAttrs(nonZero) + ssw.strokeAttrs(1)
+		case "B*", "b*":
+			attrs = ssw.fillAttrs(evenOdd) + ssw.strokeAttrs(1)
+		case "S", "s":
+			attrs = ` fill="none"` + ssw.strokeAttrs(1)
+		}
+		if attrs != "" {
+			ssw.body.write(ssw.clipPath, fmt.Sprintf(`<path d="%s"%s%s/>`, d, transformAttr(ssw.gs.ctm), attrs))
+		}
+	}
+	if ssw.clip {
+		rule := ""
+		if ssw.rule == evenOdd {
+			rule = ` clip-rule="evenodd"`
+		}
+		ssw.clipPath = ssw.w.addClip(ssw.clipPath, fmt.Sprintf(`<path d="%s"%s%s/>`, d, transformAttr(ssw.gs.ctm), rule))
+		ssw.clip = false
+	}
+	ssw.path = svgPath{}
+}
+
+// fillAttrs returns the attributes filling elements with the fill paint and rule `rule`.
+func (ssw *svgStreamWriter) fillAttrs(rule fillRule) string {
+	gs := &ssw.gs
+	attrs := fmt.Sprintf(` fill="%s"`, ssw.paintValue(gs.fill))
+	if rule == evenOdd {
+		attrs += ` fill-rule="evenodd"`
+	}
+	if gs.fillAlpha < 1 {
+		attrs += fmt.Sprintf(` fill-opacity="%s"`, svgNumber(gs.fillAlpha))
+	}
+	return attrs
+}
+
+// strokeAttrs returns the attributes stroking elements with the stroke paint and the line
+// parameters of the graphics state, with the lengths multiplied by `scale` (the user space unit
+// in units of the element space).
+func (ssw *svgStreamWriter) strokeAttrs(scale float64) string {
+	gs := &ssw.gs
+	var b strings.Builder
+	fmt.Fprintf(&b, ` stroke="%s"`, ssw.paintValue(gs.stroke))
+	if gs.strokeAlpha < 1 {
+		fmt.Fprintf(&b, ` stroke-opacity="%s"`, svgNumber(gs.strokeAlpha))
+	}
+	if gs.lineWidth > 0 {
+		fmt.Fprintf(&b, ` stroke-width="%s"`, svgNumber(gs.lineWidth*scale))
+	} else {
+		// A width of 0 denotes the thinnest line that can be rendered.
+		b.WriteString(` stroke-width="1" vector-effect="non-scaling-stroke"`)
+	}
+	switch gs.lineCap {
+	case capRound:
+		b.WriteString(` stroke-linecap="round"`)
+	case capSquare:
+		b.WriteString(` stroke-linecap="square"`)
+	}
+	switch gs.lineJoin {
+	case joinRound:
+		b.WriteString(` stroke-linejoin="round"`)
+	case joinBevel:
+		b.WriteString(` stroke-linejoin="bevel"`)
+	default:
+		if gs.miterLimit >= 1 && gs.miterLimit != 4 {
+			fmt.Fprintf(&b, ` stroke-miterlimit="%s"`, svgNumber(gs.miterLimit))
+		}
+	}
+	if dash := svgDash(gs.dash, scale); dash != "" {
+		fmt.Fprintf(&b, ` stroke-dasharray="%s"`, dash)
+		if gs.dashPhase != 0 {
+			fmt.Fprintf(&b, ` stroke-dashoffset="%s"`, svgNumber(gs.dashPhase*scale))
+		}
+	}
+	return b.String()
+}
+
+// svgDash returns the value of the stroke-dasharray attribute of dash array `dash` with the
+// lengths multiplied by `scale`, or an empty string for solid lines.
+func svgDash(dash []float64, scale float64) string {
+	total := 0.0
+	for _, d := range dash {
+		if d < 0 {
+			return ""
+		}
+		total += d
+	}
+	if total == 0 {
+		return ""
+	}
+	parts := make([]string, len(dash))
+	for i, d := range dash {
+		parts[i] = svgNumber(d * scale)
+	}
+	return strings.Join(parts, " ")
+}
+
+// drawXObject writes the XObject referenced by the Do operation `op`.
+func (ssw *svgStreamWriter) drawXObject(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil {
+		common.Log.Debug("ERROR: XObject %s not found", *name)
+		return
+	}
+	if !isContentVisible(ssw.w.ocProperties, stream.Get("OC")) {
+		return
+	}
+	switch xtype {
+	case model.XObjectTypeImage:
+		ssw.drawImageXObject(stream)
+	case model.XObjectTypeForm:
+		form, err := model.NewXObjectFormFromStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load form %s: %v", *name, err)
+			return
+		}
+		ssw.w.writeForm(form, ssw.gs, ssw.clipPath, ssw.body, resources, ssw.bounds, ssw.color, ssw.depth)
+	}
+}
+
+// writeForm writes the SVG elements of Form XObject `form` with graphics state `gs` and clipping
+// path `clip` to `body`. `resources` are the resources of the parent content stream, used if the
+// form has none.
+func (w *svgWriter) writeForm(form *model.XObjectForm, gs graphicsState, clip string, body *svgBody,
+	resources *model.PdfPageResources, bounds model.PdfRectangle, color *[3]float64, depth int) {
+	if m, ok := getMatrix(form.Matrix); ok {
+		gs.ctm = gs.ctm.Mult(m)
+	}
+	if bbox, ok := getRectangle(form.BBox); ok {
+		var rect svgPath
+		rect.rect(bbox.Llx, bbox.Lly, bbox.Width(), bbox.Height())
+		clip = w.addClip(clip, fmt.Sprintf(`<path d="%s"%s/>`, rect.String(), transformAttr(gs.ctm)))
+	}
+	content, err := form.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form content: %v", err)
+		return
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse form content: %v", err)
+		if ops == nil {
+			return
+		}
+	}
+	if form.Resources != nil {
+		resources = form.Resources
+	}
+	w.writeContent(*ops, resources, gs, clip, body, bounds, color, depth+1)
+}
+
+// svgPath is the path data of an SVG path element, in user space.
+type svgPath struct {
+	d          strings.Builder
+	cur, start point
+	open       bool // A subpath has been started.
+}
+
+// moveTo starts a new subpath at `pt`.
+func (p *svgPath) moveTo(pt point) {
+	p.command('M', pt)
+	p.cur, p.start, p.open = pt, pt, true
+}
+
+// lineTo adds a line to `pt`.
+func (p *svgPath) lineTo(pt point) {
+	if !p.open {
+		p.moveTo(p.cur)
+	}
+	p.command('L', pt)
+	p.cur = pt
+}
+
+// cubeTo adds a cubic Bézier curve with control points `c1` and `c2` to `pt`.
+func (p *svgPath) cubeTo(c1, c2, pt point) {
+	if !p.open {
+		p.moveTo(p.cur)
+	}
+	p.command('C', c1, c2, pt)
+	p.cur = pt
+}
+
+// quadTo adds a quadratic Bézier curve with control point `c` to `pt`.
+func (p *svgPath) quadTo(c, pt point) {
+	if !p.open {
+		p.moveTo(p.cur)
+	}
+	p.command('Q', c, pt)
+	p.cur = pt
+}
+
+// rect adds a closed rectangle with lower left corner (`x`,`y`) and size `w`×`h`.
+func (p *svgPath) rect(x, y, w, h float64) {
+	p.moveTo(point{x, y})
+	p.lineTo(point{x + w, y})
+	p.lineTo(point{x + w, y + h})
+	p.lineTo(point{x, y + h})
+	p.close()
+}
+
+// close closes the current subpath.
+func (p *svgPath) close() {
+	if !p.open {
+		return
+	}
+	p.d.WriteString("Z")
+	p.cur, p.open = p.start, false
+}
+
+// command writes path command `cmd` with points `pts`.
+func (p *svgPath) command(cmd byte, pts ...point) {
+	p.d.WriteByte(cmd)
+	for i, pt := range pts {
+		if i > 0 {
+			p.d.WriteByte(' ')
+		}
+		p.d.WriteString(svgNumber(pt.x))
+		p.d.WriteByte(' ')
+		p.d.WriteString(svgNumber(pt.y))
+	}
+}
+
+// String returns the path data of `p`.
+func (p *svgPath) String() string {
+	return p.d.String()
+}
+
+// svgNumber returns the SVG representation of `v`.
+func svgNumber(v float64) string {
+	if math.Abs(v) < 1e-9 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'g', 7, 64)
+}
+
+// svgMatrix returns the SVG representation of `m`.
+func svgMatrix(m transform.Matrix) string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)", svgNumber(m[0]), svgNumber(m[1]), svgNumber(m[3]),
+		svgNumber(m[4]), svgNumber(m[6]), svgNumber(m[7]))
+}
+
+// transformAttr returns the transform attribute of an element with matrix `m`.
+func transformAttr(m transform.Matrix) string {
+	if m == transform.IdentityMatrix() {
+		return ""
+	}
+	return fmt.Sprintf(` transform="%s"`, svgMatrix(m))
+}
+
+// clipAttr returns the clip-path attribute of an element clipped by clipping path `clip`, or an
+// empty string if `clip` is empty.
+func clipAttr(clip string) string {
+	if clip == "" {
+		return ""
+	}
+	return fmt.Sprintf(` clip-path="url(#%s)"`, clip)
+}
+
+// svgColor returns the SVG representation of color `rgb`.
+func svgColor(rgb [3]float64) string {
+	c := func(v float64) int {
+		return int(math.Max(0, math.Min(1, v))*255 + 0.5)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c(rgb[0]), c(rgb[1]), c(rgb[2]))
+}
+
+// colorToFloats returns the RGB components of `c`, in the range [0,1].
+func colorToFloats(c color.Color) [3]float64 {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+	return [3]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff}
+}
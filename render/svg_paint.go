@@ -0,0 +1,555 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// gradientSamples is the number of colors sampled along SVG gradients, reduced to the stops
+// needed to interpolate them.
+const gradientSamples = 65
+
+// svgServerKey identifies a paint server (gradient or pattern element).
+type svgServerKey struct {
+	object     interface{} // Shading or pattern.
+	matrix     transform.Matrix
+	rgb        [3]float64
+	background bool
+}
+
+// svgCellKey identifies a tiling pattern cell, with its color if the pattern is uncolored.
+type svgCellKey struct {
+	object core.PdfObject
+	rgb    [3]float64
+}
+
+// svgImageKey identifies an image element, with its color if the image is a stencil mask.
+type svgImageKey struct {
+	stream *core.PdfObjectStream
+	rgb    [3]float64
+}
+
+// svgRaster is a shading rasterized in an image element.
+type svgRaster struct {
+	id     string
+	region model.PdfRectangle // Device space region of the image.
+}
+
+// paintValue returns the value of the fill or stroke attribute painting `p` on elements with
+// the CTM of the graphics state.
+func (ssw *svgStreamWriter) paintValue(p paint) string {
+	if p.pattern == nil {
+		return svgColor(p.rgb)
+	}
+	var id string
+	var err error
+	switch {
+	case p.pattern.IsShading():
+		sp := p.pattern.GetAsShadingPattern()
+		if sp.Shading == nil {
+			return "none"
+		}
+		m := p.base
+		if pm, ok := getMatrix(sp.Matrix); ok {
+			m = m.Mult(pm)
+		}
+		id, err = ssw.w.shadingServer(sp.Shading, m, ssw.gs.ctm, true, ssw.bounds)
+	case p.pattern.IsTiling():
+		tp := p.pattern.GetAsTilingPattern()
+		m := p.base
+		if pm, ok := getMatrix(tp.Matrix); ok {
+			m = m.Mult(pm)
+		}
+		id, err = ssw.tilingServer(tp, m, p.rgb)
+	default:
+		return "none"
+	}
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to paint pattern: %v", err)
+		return "none"
+	}
+	return fmt.Sprintf("url(#%s)", id)
+}
+
+// drawShading writes the shading of the sh operation `op` painted over the clipping region.
+func (ssw *svgStreamWriter) drawShading(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	shading, found := resources.GetShadingByName(*name)
+	if !found {
+		common.Log.Debug("ERROR: Shading %s not found", *name)
+		return
+	}
+	gs := &ssw.gs
+	inverse, ok := gs.ctm.Inverse()
+	if !ok {
+		return
+	}
+	// The shading is painted over the drawn region, or its bounding box.
+	var p svgPath
+	if shading.BBox != nil {
+		bbox := shading.BBox.Normalized()
+		p.rect(bbox.Llx, bbox.Lly, bbox.Width(), bbox.Height())
+	} else {
+		b := ssw.bounds
+		for i, c := range []point{{b.Llx, b.Lly}, {b.Urx, b.Lly}, {b.Urx, b.Ury}, {b.Llx, b.Ury}} {
+			x, y := inverse.Transform(c.x, c.y)
+			if i == 0 {
+				p.moveTo(point{x, y})
+			} else {
+				p.lineTo(point{x, y})
+			}
+		}
+		p.close()
+	}
+	id, err := ssw.w.shadingServer(shading, gs.ctm, gs.ctm, false, ssw.bounds)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to paint shading %s: %v", *name, err)
+		return
+	}
+	opacity := ""
+	if gs.fillAlpha < 1 {
+		opacity = fmt.Sprintf(` fill-opacity="%s"`, svgNumber(gs.fillAlpha))
+	}
+	ssw.body.write(ssw.clipPath, fmt.Sprintf(`<path d="%s"%s fill="url(#%s)"%s/>`, p.String(),
+		transformAttr(gs.ctm), id, opacity))
+}
+
+// shadingServer returns the identifier of a paint server painting `shading` on elements with CTM
+// `ctm`, where `m` maps the shading space to the device space. The background color of the
+// shading is painted outside of the shading if `background` is true (shading patterns).
+// `bounds` is the device space region drawn.
+func (w *svgWriter) shadingServer(shading *model.PdfShading, m, ctm transform.Matrix, background bool,
+	bounds model.PdfRectangle) (string, error) {
+	inverse, ok := ctm.Inverse()
+	if !ok {
+		return "", errors.New("user space not invertible")
+	}
+	key := svgServerKey{object: shading, matrix: inverse.Mult(m), background: background}
+	if id, ok := w.servers[key]; ok {
+		return id, nil
+	}
+	cs := shading.ColorSpace
+	if cs == nil {
+		return "", errors.New("shading colorspace missing")
+	}
+	var bg *rgba
+	if background && shading.Background != nil {
+		if vals, err := shading.Background.ToFloat64Array(); err == nil {
+			if c, ok := componentsToRGBA(cs, vals); ok {
+				bg = &c
+			}
+		}
+	}
+
+	// Axial shadings and radial shadings with the start circle inside the end circle are SVG
+	// gradients, the other shadings are rasterized.
+	id := w.newID("g")
+	var gradient string
+	switch t := shading.GetContext().(type) {
+	case *model.PdfShadingType2:
+		coords, domain, extend, err := t.GradientParameters()
+		if err != nil {
+			return "", err
+		}
+		if coords[0] != coords[2] || coords[1] != coords[3] {
+			gradient = fmt.Sprintf(`<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" `+
+				`x2="%s" y2="%s"%s>%s</linearGradient>`, id, svgNumber(coords[0]), svgNumber(coords[1]),
+				svgNumber(coords[2]), svgNumber(coords[3]), gradientTransformAttr(key.matrix),
+				gradientStops(cs, t.Function, domain, extend, bg))
+		}
+	case *model.PdfShadingType3:
+		coords, domain, extend, err := t.GradientParameters()
+		if err != nil {
+			return "", err
+		}
+		x0, y0, r0, x1, y1, r1 := coords[0], coords[1], coords[2], coords[3], coords[4], coords[5]
+		if r0 >= 0 && r1 > 0 && math.Hypot(x1-x0, y1-y0)+r0 <= r1*(1+1e-9) {
+			gradient = fmt.Sprintf(`<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" `+
+				`r="%s" fx="%s" fy="%s" fr="%s"%s>%s</radialGradient>`, id, svgNumber(x1), svgNumber(y1),
+				svgNumber(r1), svgNumber(x0), svgNumber(y0), svgNumber(r0), gradientTransformAttr(key.matrix),
+				gradientStops(cs, t.Function, domain, extend, bg))
+		}
+	}
+	if gradient != "" {
+		w.defs.WriteString(gradient + "\n")
+		w.servers[key] = id
+		return id, nil
+	}
+
+	raster, err := w.rasterizeShading(shading, m, background, bounds)
+	if err != nil {
+		return "", err
+	}
+	// The pattern tile covers the drawn region so that the image is not repeated.
+	id = w.newID("p")
+	fmt.Fprintf(&w.defs, `<pattern id="%s" patternUnits="userSpaceOnUse" x="%s" y="%s" width="%s" height="%s" `+
+		`patternTransform="%s"><use xlink:href="#%s"%s/></pattern>`+"\n", id, svgNumber(bounds.Llx),
+		svgNumber(bounds.Lly), svgNumber(bounds.Width()), svgNumber(bounds.Height()), svgMatrix(inverse),
+		raster.id, transformAttr(transform.NewMatrix(raster.region.Width(), 0, 0, raster.region.Height(),
+			raster.region.Llx, raster.region.Lly)))
+	w.servers[key] = id
+	return id, nil
+}
+
+// gradientTransformAttr returns the gradientTransform attribute of a gradient with matrix `m`.
+func gradientTransformAttr(m transform.Matrix) string {
+	if m == transform.IdentityMatrix() {
+		return ""
+	}
+	return fmt.Sprintf(` gradientTransform="%s"`, svgMatrix(m))
+}
+
+// gradientStops returns the stop elements of an SVG gradient with the colors of the shading with
+// functions `funcs` in colorspace `cs` over `domain`. The gradient is extended before the start
+// and after the end as specified by `extend`, with color `background` (transparent if nil)
+// otherwise.
+func gradientStops(cs model.PdfColorspace, funcs []model.PdfFunction, domain [2]float64, extend [2]bool,
+	background *rgba) string {
+	samples := make([]rgba, gradientSamples)
+	for i := range samples {
+		t := domain[0] + (domain[1]-domain[0])*float64(i)/(gradientSamples-1)
+		samples[i], _ = evaluateShading(cs, funcs, []float64{t})
+	}
+
+	// Keep the samples which cannot be linearly interpolated from their neighbors.
+	interpolated := func(i, j int) bool {
+		for k := i + 1; k < j; k++ {
+			f := float32(k-i) / float32(j-i)
+			for c := range samples[k] {
+				if math.Abs(float64(samples[i][c]+(samples[j][c]-samples[i][c])*f-samples[k][c])) > 1.0/512 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	keep := []int{0}
+	for j := 2; j < gradientSamples; j++ {
+		if !interpolated(keep[len(keep)-1], j) {
+			keep = append(keep, j-1)
+		}
+	}
+	keep = append(keep, gradientSamples-1)
+
+	var b strings.Builder
+	stop := func(offset float64, c *rgba) {
+		if c == nil || c[3] == 0 {
+			fmt.Fprintf(&b, `<stop offset="%s" stop-opacity="0"/>`, svgNumber(offset))
+			return
+		}
+		fmt.Fprintf(&b, `<stop offset="%s" stop-color="%s"/>`, svgNumber(offset),
+			svgColor([3]float64{float64(c[0] / c[3]), float64(c[1] / c[3]), float64(c[2] / c[3])}))
+	}
+	// The first and last stops are repeated with the colors painted outside of the gradient.
+	if !extend[0] {
+		stop(0, background)
+	}
+	for _, i := range keep {
+		stop(float64(i)/(gradientSamples-1), &samples[i])
+	}
+	if !extend[1] {
+		stop(1, background)
+	}
+	return b.String()
+}
+
+// rasterizeShading returns `shading` rasterized in the device space region `bounds`, where `m`
+// maps the shading space to the device space. The background color of the shading is painted
+// outside of the shading if `background` is true.
+func (w *svgWriter) rasterizeShading(shading *model.PdfShading, m transform.Matrix, background bool,
+	bounds model.PdfRectangle) (*svgRaster, error) {
+	src, err := newShadingSource(shading, m, background)
+	if err != nil {
+		return nil, err
+	}
+
+	// The image covers the drawn region within the bounding box of the shading.
+	region := bounds
+	intersect := func(rect model.PdfRectangle, m transform.Matrix) {
+		x0, y0 := math.Inf(1), math.Inf(1)
+		x1, y1 := math.Inf(-1), math.Inf(-1)
+		for _, c := range []point{{rect.Llx, rect.Lly}, {rect.Urx, rect.Lly}, {rect.Llx, rect.Ury}, {rect.Urx, rect.Ury}} {
+			x, y := m.Transform(c.x, c.y)
+			x0, y0 = math.Min(x0, x), math.Min(y0, y)
+			x1, y1 = math.Max(x1, x), math.Max(y1, y)
+		}
+		region.Llx, region.Lly = math.Max(region.Llx, x0), math.Max(region.Lly, y0)
+		region.Urx, region.Ury = math.Min(region.Urx, x1), math.Min(region.Ury, y1)
+	}
+	if shading.BBox != nil {
+		intersect(shading.BBox.Normalized(), m)
+	}
+	if t, ok := shading.GetContext().(*model.PdfShadingType1); ok && !background {
+		domain := model.PdfRectangle{Urx: 1, Ury: 1}
+		if t.Domain != nil {
+			if d, err := t.Domain.ToFloat64Array(); err == nil && len(d) == 4 {
+				domain = model.PdfRectangle{Llx: d[0], Urx: d[1], Lly: d[2], Ury: d[3]}
+			}
+		}
+		matrix := transform.IdentityMatrix()
+		if mat, ok := getMatrix(t.Matrix); ok {
+			matrix = mat
+		}
+		intersect(domain, m.Mult(matrix))
+	}
+	if region.Width() <= 0 || region.Height() <= 0 {
+		return nil, errors.New("shading outside of the drawn region")
+	}
+
+	scale := w.opts.ShadingDPI / 72
+	width := int(math.Min(math.Max(math.Ceil(region.Width()*scale), 1), maxTileSize))
+	height := int(math.Min(math.Max(math.Ceil(region.Height()*scale), 1), maxTileSize))
+	sx, sy := float64(width)/region.Width(), float64(height)/region.Height()
+	src.inverse = src.inverse.Mult(transform.NewMatrix(1/sx, 0, 0, 1/sy, region.Llx, region.Lly))
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := src.colorAt(x, y)
+			if c[3] == 0 {
+				continue
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(c[0]/c[3]*255 + 0.5),
+				G: uint8(c[1]/c[3]*255 + 0.5),
+				B: uint8(c[2]/c[3]*255 + 0.5),
+				A: uint8(c[3]*255 + 0.5),
+			})
+		}
+	}
+	id, err := w.addImage(img)
+	if err != nil {
+		return nil, err
+	}
+	return &svgRaster{id: id, region: region}, nil
+}
+
+// tilingServer returns the identifier of a pattern element painting tiling pattern `tp` with
+// color `rgb` if it is uncolored, on elements with the CTM of the graphics state, where `m` maps
+// the pattern space to the device space.
+func (ssw *svgStreamWriter) tilingServer(tp *model.PdfTilingPattern, m transform.Matrix, rgb [3]float64) (
+	string, error) {
+	w := ssw.w
+	if tp.BBox == nil || tp.XStep == nil || tp.YStep == nil {
+		return "", errors.New("tiling pattern parameters missing")
+	}
+	bbox := tp.BBox.Normalized()
+	xstep, ystep := math.Abs(float64(*tp.XStep)), math.Abs(float64(*tp.YStep))
+	if bbox.Width() == 0 || bbox.Height() == 0 || xstep == 0 || ystep == 0 {
+		return "", errors.New("invalid tiling pattern cell")
+	}
+	inverse, ok := ssw.gs.ctm.Inverse()
+	if !ok {
+		return "", errors.New("user space not invertible")
+	}
+	if tp.IsColored() {
+		rgb = [3]float64{}
+	}
+	key := svgServerKey{object: tp.GetContainingPdfObject(), matrix: inverse.Mult(m), rgb: rgb}
+	if id, ok := w.servers[key]; ok {
+		return id, nil
+	}
+	cell, err := w.tilingCell(tp, bbox, rgb, ssw.depth)
+	if err != nil {
+		return "", err
+	}
+	id := w.newID("p")
+	fmt.Fprintf(&w.defs, `<pattern id="%s" patternUnits="userSpaceOnUse" x="%s" y="%s" width="%s" height="%s" `+
+		`patternTransform="%s"><use xlink:href="#%s"/></pattern>`+"\n", id, svgNumber(bbox.Llx),
+		svgNumber(bbox.Lly), svgNumber(xstep), svgNumber(ystep), svgMatrix(key.matrix), cell)
+	w.servers[key] = id
+	return id, nil
+}
+
+// tilingCell returns the identifier of a group element with the cell of tiling pattern `tp`
+// with bounding box `bbox`, in pattern space, painted with color `rgb` if it is uncolored.
+func (w *svgWriter) tilingCell(tp *model.PdfTilingPattern, bbox model.PdfRectangle, rgb [3]float64,
+	depth int) (string, error) {
+	key := svgCellKey{tp.GetContainingPdfObject(), rgb}
+	if id, ok := w.cells[key]; ok {
+		return id, nil
+	}
+	content, err := tp.GetContentStream()
+	if err != nil {
+		return "", err
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		return "", err
+	}
+	var color *[3]float64
+	if !tp.IsColored() {
+		color = &rgb
+	}
+	var rect svgPath
+	rect.rect(bbox.Llx, bbox.Lly, bbox.Width(), bbox.Height())
+	clip := w.addClip("", fmt.Sprintf(`<path d="%s"/>`, rect.String()))
+	body := &svgBody{}
+	w.writeContent(*ops, tp.Resources, newGraphicsState(transform.IdentityMatrix()), clip, body, bbox, color,
+		depth+1)
+	body.close()
+
+	id := w.newID("t")
+	fmt.Fprintf(&w.defs, "<g id=\"%s\">\n%s</g>\n", id, body.buf.Bytes())
+	w.cells[key] = id
+	return id, nil
+}
+
+// drawImageXObject writes the image XObject `stream` in the unit square of the user space.
+func (ssw *svgStreamWriter) drawImageXObject(stream *core.PdfObjectStream) {
+	key := svgImageKey{stream: stream}
+	if isMask, _ := core.GetBoolVal(stream.Get("ImageMask")); isMask {
+		key.rgb = ssw.stencilColor()
+	}
+	id, ok := ssw.w.images[key]
+	if !ok {
+		var err error
+		if data, ok := jpegData(stream); ok {
+			id = ssw.w.addImageData("image/jpeg", data)
+		} else {
+			var img *rasterImage
+			img, err = loadXObjectImage(stream)
+			if err == nil {
+				id, err = ssw.w.addImage(img.toImage(key.rgb))
+			}
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load image: %v", err)
+			return
+		}
+		ssw.w.images[key] = id
+	}
+	ssw.useImage(id)
+}
+
+// drawInlineImage writes the inline image of the BI operation `op` in the unit square of the
+// user space.
+func (ssw *svgStreamWriter) drawInlineImage(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) {
+	if len(op.Params) != 1 {
+		return
+	}
+	inline, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
+	if !ok {
+		return
+	}
+	img, err := loadInlineImage(inline, resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load inline image: %v", err)
+		return
+	}
+	id, err := ssw.w.addImage(img.toImage(ssw.stencilColor()))
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to encode inline image: %v", err)
+		return
+	}
+	ssw.useImage(id)
+}
+
+// stencilColor returns the color painting stencil masks: the fill color.
+func (ssw *svgStreamWriter) stencilColor() [3]float64 {
+	if ssw.gs.fill.pattern != nil {
+		common.Log.Debug("Stencil masks painted with patterns not supported - using the pattern color")
+	}
+	return ssw.gs.fill.rgb
+}
+
+// useImage writes a reference to the image element `id`, drawn in the unit square of the user
+// space with the first row at the top.
+func (ssw *svgStreamWriter) useImage(id string) {
+	gs := &ssw.gs
+	opacity := ""
+	if gs.fillAlpha < 1 {
+		opacity = fmt.Sprintf(` opacity="%s"`, svgNumber(gs.fillAlpha))
+	}
+	flip := transform.NewMatrix(1, 0, 0, -1, 0, 1)
+	ssw.body.write(ssw.clipPath, fmt.Sprintf(`<use xlink:href="#%s"%s%s/>`, id, transformAttr(gs.ctm.Mult(flip)),
+		opacity))
+}
+
+// addImage defines an image element with `img` encoded as PNG, in the unit square, and returns
+// its identifier.
+func (w *svgWriter) addImage(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return w.addImageData("image/png", buf.Bytes()), nil
+}
+
+// addImageData defines an image element with image file `data` of media type `mediaType`, in
+// the unit square, and returns its identifier.
+func (w *svgWriter) addImageData(mediaType string, data []byte) string {
+	id := w.newID("i")
+	fmt.Fprintf(&w.defs, `<image id="%s" width="1" height="1" preserveAspectRatio="none" `+
+		`xlink:href="data:%s;base64,%s"/>`+"\n", id, mediaType, base64.StdEncoding.EncodeToString(data))
+	return id
+}
+
+// toImage returns `img` as an image, with stencil masks painted with color `rgb`.
+func (img *rasterImage) toImage(rgb [3]float64) image.Image {
+	out := image.NewNRGBA(image.Rect(0, 0, img.width, img.height))
+	stencil := [3]uint8{}
+	for i, v := range rgb {
+		stencil[i] = uint8(math.Max(0, math.Min(1, v))*255 + 0.5)
+	}
+	for i := 0; i < img.width*img.height; i++ {
+		pix := out.Pix[4*i : 4*i+4]
+		if img.rgb != nil {
+			copy(pix, img.rgb[3*i:3*i+3])
+		} else {
+			copy(pix, stencil[:])
+		}
+		pix[3] = 255
+		if img.alpha != nil {
+			pix[3] = img.alpha[i]
+		}
+	}
+	return out
+}
+
+// jpegData returns the JPEG data of the image XObject `stream` if it can be used as is: an
+// unmasked DCT encoded image in the DeviceGray or DeviceRGB colorspace.
+func jpegData(stream *core.PdfObjectStream) ([]byte, bool) {
+	dict := stream.PdfObjectDictionary
+	filter := core.TraceToDirectObject(dict.Get("Filter"))
+	if arr, ok := filter.(*core.PdfObjectArray); ok && arr.Len() == 1 {
+		filter = core.TraceToDirectObject(arr.Get(0))
+	}
+	if name, ok := core.GetNameVal(filter); !ok || (name != core.StreamEncodingFilterNameDCT && name != "DCT") {
+		return nil, false
+	}
+	if cs, ok := core.GetNameVal(dict.Get("ColorSpace")); !ok || (cs != "DeviceGray" && cs != "DeviceRGB") {
+		return nil, false
+	}
+	for _, key := range []core.PdfObjectName{"Mask", "SMask", "Decode", "ImageMask"} {
+		if dict.Get(key) != nil {
+			return nil, false
+		}
+	}
+	return stream.Stream, true
+}
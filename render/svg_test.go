@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/testutils/pdftest"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// svgElements returns the names of the elements of the SVG document `data`, checking that it is
+// well formed.
+func svgElements(t *testing.T, data []byte) map[string]int {
+	elements := map[string]int{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if start, ok := tok.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+	return elements
+}
+
+func TestWritePageSVG(t *testing.T) {
+	page := makeRenderTestPage(t)
+	var buf bytes.Buffer
+	require.NoError(t, WritePageSVG(&buf, page, nil))
+	out := buf.String()
+
+	elements := svgElements(t, buf.Bytes())
+	require.Equal(t, 1, elements["svg"])
+	require.Equal(t, 1, elements["clipPath"])
+	require.Equal(t, 1, elements["text"])
+	require.Equal(t, 1, elements["image"])
+	require.True(t, strings.Contains(out, `width="200pt" height="100pt" viewBox="0 0 200 100"`))
+
+	// The page is flipped to the SVG coordinate system, with the y axis down.
+	require.True(t, strings.Contains(out, `<path d="M10 10L60 10L60 40L10 40Z" transform="matrix(1 0 0 -1 0 100)" fill="#ff0000"/>`), out)
+	require.True(t, strings.Contains(out, `fill="none" stroke="#0000ff" stroke-width="4"`), out)
+	require.True(t, strings.Contains(out, `>H</text>`), out)
+	require.True(t, strings.Contains(out, `font-family="'Helvetica', 'Arial', sans-serif"`), out)
+	require.True(t, strings.Contains(out, `xlink:href="data:image/png;base64,`), out)
+
+	// Text drawn with the glyph outlines.
+	opts := NewSVGOptions()
+	opts.TextAsPaths = true
+	buf.Reset()
+	require.NoError(t, WritePageSVG(&buf, page, opts))
+	elements = svgElements(t, buf.Bytes())
+	require.Equal(t, 0, elements["text"])
+	require.Equal(t, 5, elements["path"])
+}
+
+func TestWritePageSVGEmbeddedFonts(t *testing.T) {
+	writeSVG := func(baseFont, content string) (string, map[string]int) {
+		font, err := model.NewPdfFontFromTTFFile("../model/testdata/font/OpenSans-Regular.ttf")
+		require.NoError(t, err)
+		fontObj := font.ToPdfObject()
+		dict, ok := core.GetDict(fontObj)
+		require.True(t, ok)
+		dict.Set("BaseFont", core.MakeName(baseFont))
+
+		page := pdftest.NewPage(t, 200, 100, content)
+		require.NoError(t, page.Resources.SetFontByName("F1", fontObj))
+		var buf bytes.Buffer
+		require.NoError(t, WritePageSVG(&buf, page, nil))
+		return buf.String(), svgElements(t, buf.Bytes())
+	}
+
+	// The font program is embedded for the text elements.
+	out, elements := writeSVG("OpenSans", "BT /F1 40 Tf 10 50 Td (H) Tj ET")
+	require.Equal(t, 1, elements["text"])
+	require.True(t, strings.Contains(out, "@font-face"), out)
+
+	// The text in subset fonts is drawn with the glyph outlines, unless invisible.
+	out, elements = writeSVG("ABCDEF+OpenSans", "BT /F1 40 Tf 10 50 Td (H) Tj ET")
+	require.Equal(t, 0, elements["text"])
+	require.Equal(t, 1, elements["path"])
+	require.False(t, strings.Contains(out, "@font-face"), out)
+	_, elements = writeSVG("ABCDEF+OpenSans", "BT 3 Tr /F1 40 Tf 10 50 Td (H) Tj ET")
+	require.Equal(t, 1, elements["text"])
+}
@@ -0,0 +1,349 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Font descriptor flags (section 9.8.2 p. 283).
+const (
+	fontFlagFixedPitch = 1 << 0
+	fontFlagSerif      = 1 << 1
+	fontFlagSymbolic   = 1 << 2
+	fontFlagItalic     = 1 << 6
+	fontFlagForceBold  = 1 << 18
+)
+
+// showText writes the text operands `args` with the text rendering mode, if `visible`.
+func (ssw *svgStreamWriter) showText(args []core.PdfObject, visible bool) {
+	gs := &ssw.gs
+	if gs.text.Font == nil {
+		return
+	}
+	fill, stroke, clip := gs.text.PaintModes()
+	fill, stroke = fill && visible, stroke && visible
+	if clip {
+		ssw.clipText = true
+	}
+	if ssw.w.opts.TextAsPaths || (fill || stroke) && ssw.w.isOutlinedFont(gs.text.Font) {
+		ssw.showTextPaths(args, fill, stroke, clip)
+		return
+	}
+
+	// The glyphs are positioned in the text space at the first glyph, scaled horizontally, with the
+	// y axis down so that the text is upright.
+	font := gs.text.Font
+	var textMatrix, inverse transform.Matrix
+	valid := false
+	var text strings.Builder
+	var xs []string
+	first := true
+	ssw.text.Show(args, &gs.text, func(g textstate.Glyph) {
+		if first {
+			sign := 1.0
+			if gs.text.FontSize < 0 {
+				sign = -1
+			}
+			textMatrix = ssw.text.Tm.Mult(transform.NewMatrix(gs.text.Scaling/100*sign, 0, 0, -sign, 0, gs.text.Rise))
+			inverse, valid = textMatrix.Inverse()
+			valid = valid && gs.text.FontSize != 0
+			first = false
+		}
+		if !valid {
+			return
+		}
+		x, _ := inverse.Transform(g.Matrix.Transform(0, 0))
+		runes := font.CharcodesToUnicode([]textencoding.CharCode{g.Code})
+		advance := g.Width / 1000 * math.Abs(gs.text.FontSize)
+		for i, r := range runes {
+			if r < 0x20 || r == textencoding.MissingCodeRune {
+				continue
+			}
+			// The characters of a glyph, such as ligatures, share its advance.
+			xs = append(xs, svgNumber(x+advance*float64(i)/float64(len(runes))))
+			xml.EscapeText(&text, []byte(string(r)))
+		}
+	}, nil)
+	if len(xs) == 0 {
+		return
+	}
+
+	ctm := gs.ctm.Mult(textMatrix)
+	element := fmt.Sprintf(`<text xml:space="preserve"%s x="%s" font-size="%s"%s`, transformAttr(ctm),
+		strings.Join(xs, " "), svgNumber(math.Abs(gs.text.FontSize)), ssw.w.fontAttrs(font))
+	if clip {
+		ssw.textClip = append(ssw.textClip, fmt.Sprintf(`%s>%s</text>`, element, text.String()))
+	}
+	if !visible {
+		return
+	}
+	var attrs string
+	switch {
+	case fill && stroke:
+		attrs = ssw.fillAttrs(nonZero) + ssw.strokeAttrs(1/matrixScale(textMatrix))
+	case fill:
+		attrs = ssw.fillAttrs(nonZero)
+	case stroke:
+		attrs = ` fill="none"` + ssw.strokeAttrs(1/matrixScale(textMatrix))
+	default:
+		// Invisible text, such as the text of scanned pages, can be selected.
+		attrs = ` fill-opacity="0"`
+	}
+	ssw.body.write(ssw.clipPath, fmt.Sprintf(`%s%s>%s</text>`, element, attrs, text.String()))
+}
+
+// showTextPaths writes the outlines of the glyphs of the text operands `args`, filled if `fill`,
+// stroked if `stroke` and added to the text clipping path if `clip`.
+func (ssw *svgStreamWriter) showTextPaths(args []core.PdfObject, fill, stroke, clip bool) {
+	gs := &ssw.gs
+	var p svgPath
+	ssw.text.Show(args, &gs.text, func(g textstate.Glyph) {
+		if !fill && !stroke && !clip {
+			return
+		}
+		key := glyphKey{gs.text.Font, g.Code}
+		glyph, ok := ssw.w.glyphs[key]
+		if !ok {
+			var err error
+			if glyph, err = gs.text.Font.GetCharGlyph(g.Code); err != nil {
+				common.Log.Debug("ERROR: Unable to get glyph. code=0x%04x font=%s err=%v", g.Code,
+					gs.text.Font, err)
+			}
+			ssw.w.glyphs[key] = glyph
+		}
+		if glyph == nil {
+			return
+		}
+		for _, seg := range glyph.Segments {
+			pt := func(i int) point {
+				x, y := g.Matrix.Transform(seg.Points[i].X, seg.Points[i].Y)
+				return point{x, y}
+			}
+			switch seg.Type {
+			case model.GlyphMoveTo:
+				p.moveTo(pt(0))
+			case model.GlyphLineTo:
+				p.lineTo(pt(0))
+			case model.GlyphQuadTo:
+				p.quadTo(pt(0), pt(1))
+			case model.GlyphCubeTo:
+				p.cubeTo(pt(0), pt(1), pt(2))
+			case model.GlyphClose:
+				p.close()
+			}
+		}
+	}, nil)
+	d := p.String()
+	if d == "" {
+		return
+	}
+	if fill || stroke {
+		attrs := ` fill="none"`
+		if fill {
+			attrs = ssw.fillAttrs(nonZero)
+		}
+		if stroke {
+			attrs += ssw.strokeAttrs(1)
+		}
+		ssw.body.write(ssw.clipPath, fmt.Sprintf(`<path d="%s"%s%s/>`, d, transformAttr(gs.ctm), attrs))
+	}
+	if clip {
+		ssw.textClip = append(ssw.textClip, fmt.Sprintf(`<path d="%s"%s/>`, d, transformAttr(gs.ctm)))
+	}
+}
+
+// fontAttrs returns the font family, weight and style attributes of text elements in `font`.
+// The font family lists the font program embedded in the SVG, if any, the closest installed
+// fonts and a generic family.
+func (w *svgWriter) fontAttrs(font *model.PdfFont) string {
+	if attrs, ok := w.families[font]; ok {
+		return attrs
+	}
+	name := font.BaseFont()
+	if isSubsetName(name) {
+		name = name[7:]
+	}
+	family := name
+	if i := strings.IndexAny(family, "-,"); i > 0 {
+		family = family[:i]
+	}
+
+	descriptor := w.fontDescriptor(font)
+	flags := 0
+	weight := 0.0
+	if descriptor != nil {
+		if f, ok := core.GetIntVal(descriptor.Get("Flags")); ok {
+			flags = f
+		}
+		weight, _ = core.GetNumberAsFloat(core.ResolveReference(descriptor.Get("FontWeight")))
+	}
+	lower := strings.ToLower(name)
+	bold := flags&fontFlagForceBold != 0 || weight >= 600 || strings.Contains(lower, "bold") ||
+		strings.Contains(lower, "black") || strings.Contains(lower, "heavy")
+	italic := flags&fontFlagItalic != 0 || strings.Contains(lower, "italic") ||
+		strings.Contains(lower, "oblique")
+
+	var families []string
+	if w.opts.EmbedFonts && descriptor != nil {
+		if embedded := w.embedFont(descriptor); embedded != "" {
+			families = append(families, embedded)
+		}
+	}
+	switch {
+	case strings.HasPrefix(family, "Helvetica") || strings.HasPrefix(family, "Arial"):
+		families = append(families, "Helvetica", "Arial", "sans-serif")
+	case strings.HasPrefix(family, "Times"):
+		families = append(families, "Times New Roman", "Times", "serif")
+	case strings.HasPrefix(family, "Courier"):
+		families = append(families, "Courier New", "Courier", "monospace")
+	case family == "Symbol" || family == "ZapfDingbats":
+		families = append(families, family)
+	default:
+		generic := "sans-serif"
+		if flags&fontFlagFixedPitch != 0 {
+			generic = "monospace"
+		} else if flags&fontFlagSerif != 0 {
+			generic = "serif"
+		}
+		if family != "" {
+			families = append(families, family)
+		}
+		families = append(families, generic)
+	}
+	for i, f := range families {
+		if f != "serif" && f != "sans-serif" && f != "monospace" {
+			families[i] = "'" + strings.Map(func(r rune) rune {
+				if strings.ContainsRune(`'"<>&\`, r) || r < 0x20 {
+					return -1
+				}
+				return r
+			}, f) + "'"
+		}
+	}
+
+	attrs := fmt.Sprintf(` font-family="%s"`, strings.Join(families, ", "))
+	if bold {
+		attrs += ` font-weight="bold"`
+	}
+	if italic {
+		attrs += ` font-style="italic"`
+	}
+	w.families[font] = attrs
+	return attrs
+}
+
+// isOutlinedFont returns true if the glyphs of `font` are drawn with their outlines when the
+// font programs are embedded: for the subset and symbolic fonts with a font program which can be
+// embedded, whose text may not map to the glyphs through the character maps of the program.
+func (w *svgWriter) isOutlinedFont(font *model.PdfFont) bool {
+	if !w.opts.EmbedFonts {
+		return false
+	}
+	if outlined, ok := w.outlined[font]; ok {
+		return outlined
+	}
+	outlined := false
+	if descriptor := w.fontDescriptor(font); descriptor != nil {
+		if _, _, _, ok := embeddableFontProgram(descriptor); ok {
+			flags, _ := core.GetIntVal(descriptor.Get("Flags"))
+			outlined = isSubsetName(font.BaseFont()) || flags&fontFlagSymbolic != 0
+		}
+	}
+	w.outlined[font] = outlined
+	return outlined
+}
+
+// isSubsetName returns true if font name `name` has the tag of subset fonts: 6 uppercase
+// letters followed by '+'.
+func isSubsetName(name string) bool {
+	if len(name) < 7 || name[6] != '+' {
+		return false
+	}
+	for _, c := range name[:6] {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// fontDescriptor returns the font descriptor dictionary of `font` (of its descendant font for
+// composite fonts), or nil if it has none.
+func (w *svgWriter) fontDescriptor(font *model.PdfFont) *core.PdfObjectDictionary {
+	var dict *core.PdfObjectDictionary
+	for obj, f := range w.fonts {
+		if f == font {
+			dict, _ = core.GetDict(obj)
+			break
+		}
+	}
+	if dict == nil {
+		return nil
+	}
+	if subtype, _ := core.GetNameVal(dict.Get("Subtype")); subtype == "Type0" {
+		arr, ok := core.GetArray(dict.Get("DescendantFonts"))
+		if !ok || arr.Len() == 0 {
+			return nil
+		}
+		if dict, ok = core.GetDict(arr.Get(0)); !ok {
+			return nil
+		}
+	}
+	descriptor, _ := core.GetDict(dict.Get("FontDescriptor"))
+	return descriptor
+}
+
+// embedFont adds a font face with the TrueType or OpenType font program of font descriptor
+// `descriptor` to the style sheet and returns its font family, or an empty string if the font
+// has no such program.
+func (w *svgWriter) embedFont(descriptor *core.PdfObjectDictionary) string {
+	stream, mediaType, format, ok := embeddableFontProgram(descriptor)
+	if !ok {
+		return ""
+	}
+	if family, ok := w.embedded[stream]; ok {
+		return family
+	}
+	family := ""
+	if data, err := core.DecodeStream(stream); err != nil {
+		common.Log.Debug("ERROR: Unable to decode font program: %v", err)
+	} else {
+		family = w.newID("f")
+		fmt.Fprintf(&w.fontFaces, "@font-face { font-family: '%s'; src: url(data:%s;base64,%s) format('%s'); }\n",
+			family, mediaType, base64.StdEncoding.EncodeToString(data), format)
+	}
+	w.embedded[stream] = family
+	return family
+}
+
+// embeddableFontProgram returns the TrueType or OpenType font program of font descriptor
+// `descriptor` with its media type and its CSS font format. The bool return flag is false if the
+// font has no such program.
+func embeddableFontProgram(descriptor *core.PdfObjectDictionary) (*core.PdfObjectStream, string, string, bool) {
+	if stream, ok := core.GetStream(descriptor.Get("FontFile2")); ok {
+		return stream, "font/ttf", "truetype", true
+	}
+	stream, ok := core.GetStream(descriptor.Get("FontFile3"))
+	if !ok {
+		return nil, "", "", false
+	}
+	if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "OpenType" {
+		return nil, "", "", false
+	}
+	return stream, "font/otf", "opentype", true
+}
@@ -0,0 +1,106 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/textstate"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// glyphKey identifies a glyph of a font.
+type glyphKey struct {
+	font *model.PdfFont
+	code textencoding.CharCode
+}
+
+// handleText renders the text operation `op`, painting the glyphs if `visible`.
+func (sr *streamRenderer) handleText(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources,
+	visible bool) {
+	args, show := sr.text.Handle(op, &sr.gs.text, func(name core.PdfObjectName) *model.PdfFont {
+		return sr.r.fonts.Get(resources, name)
+	})
+	if show {
+		sr.showText(args, visible)
+	}
+}
+
+// showText shows the text operands `args`, painting the glyphs with the text rendering mode if
+// `visible`.
+func (sr *streamRenderer) showText(args []core.PdfObject, visible bool) {
+	gs := &sr.gs
+	if gs.text.Font == nil {
+		return
+	}
+	fill, stroke, clip := gs.text.PaintModes()
+	fill, stroke = fill && visible, stroke && visible
+	if clip {
+		sr.clipText = true
+	}
+
+	sr.text.Show(args, &gs.text, func(g textstate.Glyph) {
+		if !fill && !stroke && !clip {
+			return
+		}
+		glyph, ok := sr.glyphPath(gs.text.Font, g.Code, gs.ctm.Mult(g.Matrix))
+		if !ok {
+			return
+		}
+		var p path
+		p.append(glyph, g.Matrix)
+		if fill {
+			sr.r.fillPolygons(p.polygons(gs.ctm), nonZero, sr.paintSource(gs.fill), gs.fillAlpha, gs.clip)
+		}
+		if stroke {
+			sr.r.fillPolygons(sr.strokePolygons(p), nonZero, sr.paintSource(gs.stroke), gs.strokeAlpha, gs.clip)
+		}
+		if clip {
+			sr.textClip = append(sr.textClip, p.polygons(gs.ctm)...)
+		}
+	}, nil)
+}
+
+// glyphPath returns the outline of the glyph of `font` for `code`, in glyph space, flattened
+// for glyph to device space matrix `m`.
+func (sr *streamRenderer) glyphPath(font *model.PdfFont, code textencoding.CharCode, m transform.Matrix) (path, bool) {
+	key := glyphKey{font, code}
+	glyph, ok := sr.r.glyphs[key]
+	if !ok {
+		var err error
+		if glyph, err = font.GetCharGlyph(code); err != nil {
+			common.Log.Debug("ERROR: Unable to get glyph. code=0x%04x font=%s err=%v", code, font, err)
+		}
+		sr.r.glyphs[key] = glyph
+	}
+	if glyph == nil {
+		return path{}, false
+	}
+
+	tol := userTolerance(m)
+	var p path
+	for _, seg := range glyph.Segments {
+		pt := func(i int) point {
+			return point{seg.Points[i].X, seg.Points[i].Y}
+		}
+		switch seg.Type {
+		case model.GlyphMoveTo:
+			p.moveTo(pt(0))
+		case model.GlyphLineTo:
+			p.lineTo(pt(0))
+		case model.GlyphQuadTo:
+			p.quadTo(pt(0), pt(1), tol)
+		case model.GlyphCubeTo:
+			p.cubeTo(pt(0), pt(1), pt(2), tol)
+		case model.GlyphClose:
+			p.close()
+		}
+	}
+	return p, true
+}